@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/humbkr/albaplayer-server/internal/alba"
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	analyzeLoudnessCmd.Flags().BoolVar(&force, "force", false, "Analyze again the tracks already analyzed")
+	analyzeCmd.AddCommand(analyzeLoudnessCmd)
+	rootCmd.AddCommand(analyzeCmd)
+}
+
+var force bool
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Analyze media library audio",
+	Long:  `Analyze the audio of the media library tracks.`,
+}
+
+var analyzeLoudnessCmd = &cobra.Command{
+	Use:   "loudness",
+	Short: "Analyze tracks loudness",
+	Long: `Measure the EBU R128 loudness and true peak of the tracks and albums, for the tracks without ReplayGain tags.
+Only MP3 and FLAC files can be analyzed. An interrupted analysis resumes from where it stopped.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		libraryInteractor := alba.InitApp()
+
+		fmt.Println("Analyzing tracks loudness, this can take a long time...")
+
+		err := libraryInteractor.AnalyzeLoudness(force, func(progress business.LoudnessAnalysisProgress) {
+			if progress.Running && progress.TracksTotal > 0 {
+				fmt.Printf("\r%v/%v tracks, %v/%v albums", progress.TracksDone, progress.TracksTotal, progress.AlbumsDone, progress.AlbumsTotal)
+			}
+		})
+		fmt.Println()
+		if err != nil {
+			fmt.Println("Analysis failed: " + err.Error())
+			os.Exit(1)
+		}
+
+		progress := libraryInteractor.GetLoudnessAnalysisProgress()
+		if progress.Failed > 0 {
+			fmt.Printf("%v tracks could not be decoded.\n", progress.Failed)
+		}
+		fmt.Println("Analysis finished.")
+		os.Exit(0)
+	},
+}
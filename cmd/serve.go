@@ -7,6 +7,7 @@ import (
 	"mime"
 	"net/http"
 	"path/filepath"
+	"time"
 
 	gqlHandler "github.com/graphql-go/handler"
 	"github.com/humbkr/albaplayer-server/internal/alba"
@@ -45,6 +46,9 @@ var serveCmd = &cobra.Command{
 		// Make the server handle cross-domain requests.
 		mux.Handle("/graphql", graphQLHandler)
 
+		// Serve the GraphQL subscriptions over WebSocket.
+		mux.Handle("/subscriptions", interfaces.NewSubscriptionHandler(graphQLInteractor))
+
 		// Serve media files streaming endpoint.
 		// Makes the server handle cross-domain requests.
 		mediaFilesHandler := interfaces.NewMediaStreamHandler(&libraryInteractor)
@@ -55,6 +59,32 @@ var serveCmd = &cobra.Command{
 		coverFilesHandler := interfaces.NewCoverStreamHandler(&libraryInteractor)
 		mux.Handle("/covers/", http.StripPrefix("/covers/", coverFilesHandler))
 
+		// Serve tracks waveforms endpoint.
+		waveformHandler := interfaces.NewWaveformHandler(&libraryInteractor)
+		mux.Handle("/waveform/", http.StripPrefix("/waveform/", waveformHandler))
+
+		// Serve albums and artists download endpoint.
+		downloadHandler := interfaces.NewDownloadHandler(&libraryInteractor)
+		mux.Handle("/download/", http.StripPrefix("/download/", downloadHandler))
+
+		// Serve HLS streaming endpoint.
+		hlsHandler := interfaces.NewHlsHandler(&libraryInteractor)
+		mux.Handle("/hls/", http.StripPrefix("/hls/", hlsHandler))
+
+		// Serve public share links endpoint.
+		shareHandler := interfaces.NewShareHandler(&libraryInteractor)
+		mux.Handle("/share/", http.StripPrefix("/share/", shareHandler))
+
+		// Send the plays to the scrobbling services in the background.
+		go interfaces.RunScrobbleQueue(&libraryInteractor)
+
+		// Close the idle parties in the background, so their listeners are released.
+		go func() {
+			for range time.Tick(time.Minute) {
+				libraryInteractor.CloseIdleParties()
+			}
+		}()
+
 		// Serve SPA.
 		fileServer := http.FileServer(pkger.Dir("/web"))
 		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -5,11 +5,14 @@ go 1.12
 require (
 	github.com/dhowden/tag v0.0.0-20181104225729-a9f04c2798ca
 	github.com/go-gorp/gorp v2.0.0+incompatible
+	github.com/gorilla/websocket v1.2.0
 	github.com/graphql-go/graphql v0.7.2
 	github.com/graphql-go/handler v0.1.0
 	github.com/graphql-go/relay v0.0.0-20171208134043-54350098cfe5 // indirect
+	github.com/hajimehoshi/go-mp3 v0.3.4
 	github.com/markbates/pkger v0.17.1
 	github.com/mattn/go-sqlite3 v1.12.0
+	github.com/mewkiz/flac v1.0.10
 	github.com/mnmtanish/go-graphiql v0.0.0-20160921055525-cef5a61bd62b
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/natefinch/lumberjack v0.0.0-20170531160350-a96e63847dc3
@@ -20,5 +23,6 @@ require (
 	github.com/spf13/cobra v0.0.5
 	github.com/spf13/viper v1.3.2
 	github.com/stretchr/testify v1.4.0
+	golang.org/x/text v0.7.0
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0 // indirect
 )
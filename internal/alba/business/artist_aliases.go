@@ -0,0 +1,87 @@
+package business
+
+import (
+	"errors"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+/*
+This file exposes the merge of artists appearing under several names.
+
+Merged artists are deleted, their albums, tracks and credits being given to the target artist, and their names are
+kept as aliases of the target artist so the library updates map them onto it. A merge can be undone from its alias.
+ */
+
+// Merges artists into a target artist.
+//
+// Returns the target artist.
+func (interactor *LibraryInteractor) MergeArtists(sourceIds []int, targetId int) (domain.Artist, error) {
+	if len(sourceIds) == 0 {
+		return domain.Artist{}, errors.New("cannot merge artists: no artist to merge")
+	}
+	target, err := interactor.ArtistRepository.Get(targetId)
+	if err != nil {
+		return domain.Artist{}, errors.New("cannot merge artists: invalid target artist ID")
+	}
+
+	known := make(map[int]bool)
+	for _, sourceId := range sourceIds {
+		if sourceId == targetId || known[sourceId] {
+			return target, errors.New("cannot merge artists: an artist is given several times")
+		}
+		known[sourceId] = true
+
+		source, errSource := interactor.ArtistRepository.Get(sourceId)
+		if errSource != nil {
+			return target, errors.New("cannot merge artists: invalid artist ID")
+		}
+		// The library updates need it to find the compilations.
+		if source.Name == LibraryDefaultCompilationArtist {
+			return target, errors.New("cannot merge artists: " + LibraryDefaultCompilationArtist + " cannot be merged")
+		}
+	}
+
+	// Don't move entities while the library is updating them.
+	if interactor.LibraryIsUpdating {
+		return target, errors.New("cannot merge artists: library currently updating")
+	}
+	interactor.mutex.Lock()
+	defer interactor.mutex.Unlock()
+
+	if _, err = interactor.ArtistAliasRepository.Merge(sourceIds, targetId); err != nil {
+		return target, errors.New("cannot merge artists: " + err.Error())
+	}
+
+	return interactor.ArtistRepository.Get(targetId)
+}
+
+// Gets all the artist aliases, or the aliases of an artist if artistId is not 0.
+//
+// If no aliases found, returns an empty collection.
+func (interactor *LibraryInteractor) GetArtistAliases(artistId int) (domain.ArtistAliases, error) {
+	return interactor.ArtistAliasRepository.GetAll(artistId)
+}
+
+// Undoes the merge which created an alias.
+//
+// Returns the restored artist.
+func (interactor *LibraryInteractor) UndoArtistMerge(aliasId int) (domain.Artist, error) {
+	alias, err := interactor.ArtistAliasRepository.Get(aliasId)
+	if err != nil {
+		return domain.Artist{}, errors.New("cannot undo artist merge: invalid alias ID")
+	}
+
+	if interactor.LibraryIsUpdating {
+		return domain.Artist{}, errors.New("cannot undo artist merge: library currently updating")
+	}
+	interactor.mutex.Lock()
+	defer interactor.mutex.Unlock()
+
+	artist, err := interactor.ArtistAliasRepository.Unmerge(&alias)
+	if err != nil {
+		return artist, errors.New("cannot undo artist merge: " + err.Error())
+	}
+
+	return interactor.ArtistRepository.Get(artist.Id)
+}
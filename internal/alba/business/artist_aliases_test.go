@@ -0,0 +1,71 @@
+package business
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ArtistAliasesTestSuite struct {
+	suite.Suite
+	Library *LibraryInteractor
+}
+
+// Go testing framework entry point.
+func TestArtistAliasesTestSuite(t *testing.T) {
+	suite.Run(t, new(ArtistAliasesTestSuite))
+}
+
+func (suite *ArtistAliasesTestSuite) SetupTest() {
+	suite.Library = createMockLibraryInteractor()
+}
+
+func (suite *ArtistAliasesTestSuite) TestMergeArtists() {
+	artist, err := suite.Library.MergeArtists([]int{3, 4}, 2)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, artist.Id)
+	assert.Equal(suite.T(), []int{3, 4}, suite.Library.ArtistAliasRepository.(*ArtistAliasRepositoryMock).Merged)
+
+	// Invalid merges.
+	_, err = suite.Library.MergeArtists([]int{}, 2)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.MergeArtists([]int{3}, 99)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.MergeArtists([]int{3, 99}, 2)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.MergeArtists([]int{3, 2}, 2)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.MergeArtists([]int{3, 3}, 2)
+	assert.NotNil(suite.T(), err)
+	assert.Len(suite.T(), suite.Library.ArtistAliasRepository.(*ArtistAliasRepositoryMock).Merged, 2)
+
+	// Artists are not moved during a library update.
+	suite.Library.LibraryIsUpdating = true
+	_, err = suite.Library.MergeArtists([]int{5}, 2)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *ArtistAliasesTestSuite) TestGetArtistAliases() {
+	aliases, err := suite.Library.GetArtistAliases(0)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), aliases, 1)
+
+	aliases, err = suite.Library.GetArtistAliases(2)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), aliases, 1)
+	assert.Equal(suite.T(), "Artist #3", aliases[0].Name)
+
+	aliases, err = suite.Library.GetArtistAliases(3)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), aliases)
+}
+
+func (suite *ArtistAliasesTestSuite) TestUndoArtistMerge() {
+	artist, err := suite.Library.UndoArtistMerge(1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 3, artist.Id)
+
+	_, err = suite.Library.UndoArtistMerge(99)
+	assert.NotNil(suite.T(), err)
+}
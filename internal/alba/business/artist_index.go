@@ -0,0 +1,113 @@
+package business
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"golang.org/x/text/unicode/norm"
+)
+
+/*
+This file exposes the artists and albums sort names and the artists alphabetical index.
+ */
+
+// Index letter of the artists whose sort name doesn't start with a letter.
+const ArtistIndexOtherLetter = "#"
+
+// Artists whose sort name starts with the same letter.
+type ArtistIndexGroup struct {
+	Letter string
+	Artists domain.Artists
+}
+
+/*
+Computes the sort name of an artist or an album.
+
+Accents are removed, and a leading article is moved to the end of the name: "The Beatles" becomes "Beatles, The".
+Articles are matched regardless of their case, and must be followed by a whitespace unless they end with an
+apostrophe, like "L'".
+*/
+func SortName(name string, articles []string) string {
+	name = strings.Join(strings.Fields(FoldAccents(name)), " ")
+
+	for _, article := range articles {
+		article = FoldAccents(article)
+		if article == "" || len(name) <= len(article) || !strings.EqualFold(name[:len(article)], article) {
+			continue
+		}
+
+		rest := name[len(article):]
+		if !strings.HasSuffix(article, "'") {
+			if rest[0] != ' ' {
+				continue
+			}
+			rest = strings.TrimLeft(rest, " ")
+		}
+		if rest != "" {
+			return rest + ", " + name[:len(article)]
+		}
+	}
+
+	return name
+}
+
+// Removes the diacritics from a string: "Björk" becomes "Bjork".
+func FoldAccents(s string) string {
+	var folded strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if !unicode.Is(unicode.Mn, r) {
+			folded.WriteRune(r)
+		}
+	}
+
+	return norm.NFC.String(folded.String())
+}
+
+/*
+Groups artists by the first letter of their sort name.
+
+Groups are ordered alphabetically, artists whose sort name doesn't start with a letter from A to Z come first under
+"#". Artists without sort name are indexed on their name.
+*/
+func BuildArtistIndex(artists domain.Artists, articles []string) (index []ArtistIndexGroup) {
+	sortNames := make(map[int]string)
+	for _, artist := range artists {
+		sortName := artist.SortName
+		if sortName == "" {
+			sortName = SortName(artist.Name, articles)
+		}
+		sortNames[artist.Id] = strings.ToLower(FoldAccents(sortName))
+	}
+
+	sorted := make(domain.Artists, len(artists))
+	copy(sorted, artists)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sortNames[sorted[i].Id] < sortNames[sorted[j].Id]
+	})
+
+	groups := make(map[string]int)
+	for _, artist := range sorted {
+		letter := ArtistIndexOtherLetter
+		if sortName := sortNames[artist.Id]; sortName != "" && sortName[0] >= 'a' && sortName[0] <= 'z' {
+			letter = strings.ToUpper(sortName[:1])
+		}
+
+		if _, ok := groups[letter]; !ok {
+			groups[letter] = len(index)
+			index = append(index, ArtistIndexGroup{Letter: letter})
+		}
+		index[groups[letter]].Artists = append(index[groups[letter]].Artists, artist)
+	}
+
+	// Keep "#" first, whatever the characters it stands for.
+	sort.SliceStable(index, func(i, j int) bool {
+		if index[i].Letter == ArtistIndexOtherLetter || index[j].Letter == ArtistIndexOtherLetter {
+			return index[i].Letter == ArtistIndexOtherLetter && index[j].Letter != ArtistIndexOtherLetter
+		}
+		return index[i].Letter < index[j].Letter
+	})
+
+	return
+}
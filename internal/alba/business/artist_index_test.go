@@ -0,0 +1,74 @@
+package business
+
+import (
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ArtistIndexTestSuite struct {
+	suite.Suite
+	Articles []string
+}
+
+// Go testing framework entry point.
+func TestArtistIndexTestSuite(t *testing.T) {
+	suite.Run(t, new(ArtistIndexTestSuite))
+}
+
+func (suite *ArtistIndexTestSuite) SetupSuite() {
+	suite.Articles = []string{"The", "Les", "L'", "Die"}
+}
+
+func (suite *ArtistIndexTestSuite) TestSortName() {
+	assert.Equal(suite.T(), "Beatles, The", SortName("The Beatles", suite.Articles))
+	assert.Equal(suite.T(), "Beatles, the", SortName("the   Beatles", suite.Articles))
+	assert.Equal(suite.T(), "Innocents, Les", SortName("Les Innocents", suite.Articles))
+	assert.Equal(suite.T(), "Arzte, Die", SortName("Die Ärzte", suite.Articles))
+	assert.Equal(suite.T(), "Imperatrice, L'", SortName("L'Impératrice", suite.Articles))
+
+	// Articles must be separate words.
+	assert.Equal(suite.T(), "Theory of a Deadman", SortName("Theory of a Deadman", suite.Articles))
+	// Names made of an article only are kept.
+	assert.Equal(suite.T(), "The", SortName("The", suite.Articles))
+	assert.Equal(suite.T(), "Bjork", SortName("Björk", nil))
+}
+
+func (suite *ArtistIndexTestSuite) TestFoldAccents() {
+	assert.Equal(suite.T(), "Bjork", FoldAccents("Björk"))
+	assert.Equal(suite.T(), "Beyonce", FoldAccents("Beyoncé"))
+	assert.Equal(suite.T(), "Sigur Ros", FoldAccents("Sigur Rós"))
+}
+
+func (suite *ArtistIndexTestSuite) TestBuildArtistIndex() {
+	artists := domain.Artists{
+		{Id: 1, Name: "The Beatles"},
+		{Id: 2, Name: "Björk"},
+		{Id: 3, Name: "Air"},
+		{Id: 4, Name: "2Pac"},
+		{Id: 5, Name: "Ángel Parra"},
+		{Id: 6, Name: "Beck", SortName: "Beck"},
+		{Id: 7, Name: "...And You Will Know Us by the Trail of Dead"},
+	}
+
+	index := BuildArtistIndex(artists, suite.Articles)
+	assert.Len(suite.T(), index, 3)
+
+	assert.Equal(suite.T(), ArtistIndexOtherLetter, index[0].Letter)
+	assert.Len(suite.T(), index[0].Artists, 2)
+
+	assert.Equal(suite.T(), "A", index[1].Letter)
+	assert.Len(suite.T(), index[1].Artists, 2)
+	assert.Equal(suite.T(), "Air", index[1].Artists[0].Name)
+	assert.Equal(suite.T(), "Ángel Parra", index[1].Artists[1].Name)
+
+	assert.Equal(suite.T(), "B", index[2].Letter)
+	assert.Len(suite.T(), index[2].Artists, 3)
+	assert.Equal(suite.T(), "The Beatles", index[2].Artists[0].Name)
+	assert.Equal(suite.T(), "Beck", index[2].Artists[1].Name)
+	assert.Equal(suite.T(), "Björk", index[2].Artists[2].Name)
+
+	assert.Empty(suite.T(), BuildArtistIndex(domain.Artists{}, suite.Articles))
+}
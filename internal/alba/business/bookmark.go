@@ -0,0 +1,123 @@
+package business
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/spf13/viper"
+)
+
+/*
+This file exposes the bookmarks: playback positions of the users in long tracks like audiobooks, DJ mixes or lectures,
+so their playback can be resumed.
+
+A user has at most one bookmark per track. Bookmarks of the resumable tracks, see IsResumable(), are also updated
+when the play queue is saved, and removed once the track has been played until its end.
+*/
+
+// Positions this close to the end of a track mean the track has been played until its end.
+const bookmarkEndMargin = 10000
+
+/*
+Tells whether the playback position of a track must be remembered.
+
+Tracks lasting at least Bookmarks.MinDuration minutes are resumable, as well as the tracks of the Bookmarks.Genres
+genres and the tracks in the Bookmarks.Folders folders, which are relative to the library path unless absolute.
+Tracks whose duration couldn't be read when scanning have a duration of 0, so they only match the genres and folders.
+*/
+func (interactor *LibraryInteractor) IsResumable(track domain.Track) bool {
+	minDuration := viper.GetInt("Bookmarks.MinDuration")
+	if minDuration > 0 && track.Duration >= minDuration*60 {
+		return true
+	}
+
+	for _, folder := range viper.GetStringSlice("Bookmarks.Folders") {
+		if !filepath.IsAbs(folder) {
+			folder = filepath.Join(viper.GetString("Library.Path"), folder)
+		}
+		folder = filepath.Clean(folder) + string(filepath.Separator)
+		if strings.HasPrefix(filepath.Clean(track.Path), folder) {
+			return true
+		}
+	}
+
+	resumableGenres := viper.GetStringSlice("Bookmarks.Genres")
+	if len(resumableGenres) == 0 {
+		return false
+	}
+	genres, _ := interactor.GenreRepository.GetGenresForTrack(track.Id)
+	for _, genre := range genres {
+		for _, resumableGenre := range resumableGenres {
+			if strings.EqualFold(genre.Name, resumableGenre) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Gets the bookmark of a user for a track.
+func (interactor *LibraryInteractor) GetBookmark(user string, trackId int) (domain.Bookmark, error) {
+	return interactor.BookmarkRepository.Get(user, trackId)
+}
+
+// Gets all the bookmarks of a user, the most recently modified first.
+func (interactor *LibraryInteractor) GetBookmarks(user string) (domain.Bookmarks, error) {
+	return interactor.BookmarkRepository.GetAll(user)
+}
+
+/*
+Saves the playback position of a user in a track, in milliseconds.
+
+The bookmark is removed if the position is at the end of the track, in which case an empty bookmark is returned.
+*/
+func (interactor *LibraryInteractor) SaveBookmark(user string, trackId int, position int) (domain.Bookmark, error) {
+	if position < 0 {
+		return domain.Bookmark{}, errors.New("negative position")
+	}
+	track, err := interactor.TrackRepository.Get(trackId)
+	if err != nil {
+		return domain.Bookmark{}, errors.New("invalid track ID")
+	}
+
+	return interactor.saveBookmark(user, track, position)
+}
+
+// Deletes the bookmark of a user for a track. Returns the deleted bookmark.
+func (interactor *LibraryInteractor) DeleteBookmark(user string, trackId int) (domain.Bookmark, error) {
+	bookmark, err := interactor.BookmarkRepository.Get(user, trackId)
+	if err != nil {
+		return domain.Bookmark{}, err
+	}
+
+	return bookmark, interactor.BookmarkRepository.Delete(&bookmark)
+}
+
+// Saves or removes the bookmark of a user for a track.
+func (interactor *LibraryInteractor) saveBookmark(user string, track domain.Track, position int) (domain.Bookmark, error) {
+	bookmark, err := interactor.BookmarkRepository.Get(user, track.Id)
+	if err != nil {
+		bookmark = domain.Bookmark{User: user, TrackId: track.Id}
+	}
+
+	// Without a duration, the bookmark stays until deleted.
+	if track.Duration > 0 && position >= track.Duration*1000-bookmarkEndMargin {
+		if bookmark.Id != 0 {
+			if err := interactor.BookmarkRepository.Delete(&bookmark); err != nil {
+				return domain.Bookmark{}, err
+			}
+		}
+
+		return domain.Bookmark{}, nil
+	}
+
+	bookmark.Position = position
+	if err := interactor.BookmarkRepository.Save(&bookmark); err != nil {
+		return domain.Bookmark{}, err
+	}
+
+	return bookmark, nil
+}
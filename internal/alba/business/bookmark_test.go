@@ -0,0 +1,137 @@
+package business
+
+import (
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type BookmarkTestSuite struct {
+	suite.Suite
+	Library *LibraryInteractor
+}
+
+// Go testing framework entry point.
+func TestBookmarkTestSuite(t *testing.T) {
+	suite.Run(t, new(BookmarkTestSuite))
+}
+
+func (suite *BookmarkTestSuite) SetupTest() {
+	suite.Library = createMockLibraryInteractor()
+	viper.Set("Library.Path", "/music")
+	viper.Set("Bookmarks.MinDuration", 20)
+	viper.Set("Bookmarks.Genres", []string{})
+	viper.Set("Bookmarks.Folders", []string{})
+}
+
+func (suite *BookmarkTestSuite) TearDownTest() {
+	viper.Set("Library.Path", nil)
+	viper.Set("Bookmarks.MinDuration", nil)
+	viper.Set("Bookmarks.Genres", nil)
+	viper.Set("Bookmarks.Folders", nil)
+}
+
+func (suite *BookmarkTestSuite) TestIsResumable() {
+	track := domain.Track{Id: 2, Path: "/music/Mixes/Mix.mp3", Duration: 1199}
+	assert.False(suite.T(), suite.Library.IsResumable(track))
+
+	// Long tracks.
+	track.Duration = 1200
+	assert.True(suite.T(), suite.Library.IsResumable(track))
+	viper.Set("Bookmarks.MinDuration", 0)
+	assert.False(suite.T(), suite.Library.IsResumable(track))
+
+	// Tracks in the folders, relative to the library path or absolute.
+	viper.Set("Bookmarks.Folders", []string{"Mix"})
+	assert.False(suite.T(), suite.Library.IsResumable(track))
+	viper.Set("Bookmarks.Folders", []string{"Mixes"})
+	assert.True(suite.T(), suite.Library.IsResumable(track))
+	viper.Set("Bookmarks.Folders", []string{"/music/Mixes/"})
+	assert.True(suite.T(), suite.Library.IsResumable(track))
+	viper.Set("Bookmarks.Folders", []string{})
+
+	// Tracks of the genres, whatever their case. Only track 1 has genres.
+	viper.Set("Bookmarks.Genres", []string{"genre #2"})
+	assert.False(suite.T(), suite.Library.IsResumable(track))
+	track.Id = 1
+	assert.True(suite.T(), suite.Library.IsResumable(track))
+}
+
+func (suite *BookmarkTestSuite) TestSaveBookmark() {
+	bookmark, err := suite.Library.SaveBookmark("", 1, 60000)
+	assert.Nil(suite.T(), err)
+	assert.NotZero(suite.T(), bookmark.Id)
+	assert.Equal(suite.T(), 1, bookmark.TrackId)
+	assert.Equal(suite.T(), 60000, bookmark.Position)
+
+	// Saving again updates the bookmark.
+	saved, err := suite.Library.SaveBookmark("", 1, 90000)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), bookmark.Id, saved.Id)
+	bookmark, err = suite.Library.GetBookmark("", 1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 90000, bookmark.Position)
+
+	// Bookmarks are per user.
+	_, err = suite.Library.GetBookmark("other", 1)
+	assert.NotNil(suite.T(), err)
+	_, _ = suite.Library.SaveBookmark("other", 2, 1000)
+	bookmarks, _ := suite.Library.GetBookmarks("")
+	assert.Len(suite.T(), bookmarks, 1)
+
+	// The bookmark is removed once the track has been played until its end (200 seconds).
+	bookmark, err = suite.Library.SaveBookmark("", 1, 195000)
+	assert.Nil(suite.T(), err)
+	assert.Zero(suite.T(), bookmark.Id)
+	_, err = suite.Library.GetBookmark("", 1)
+	assert.NotNil(suite.T(), err)
+
+	_, err = suite.Library.SaveBookmark("", 1, -1)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.SaveBookmark("", 99, 1000)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *BookmarkTestSuite) TestDeleteBookmark() {
+	_, _ = suite.Library.SaveBookmark("", 1, 60000)
+
+	bookmark, err := suite.Library.DeleteBookmark("", 1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 60000, bookmark.Position)
+	_, err = suite.Library.GetBookmark("", 1)
+	assert.NotNil(suite.T(), err)
+
+	_, err = suite.Library.DeleteBookmark("", 1)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *BookmarkTestSuite) TestPlayQueueBookmarks() {
+	// Tracks aren't long enough to be resumable.
+	_, err := suite.Library.SavePlayQueue("", "desktop", []int{1, 2}, 1, 60000)
+	assert.Nil(suite.T(), err)
+	_, err = suite.Library.GetBookmark("", 2)
+	assert.NotNil(suite.T(), err)
+
+	// The position in the current track is bookmarked.
+	viper.Set("Bookmarks.MinDuration", 3)
+	_, err = suite.Library.SavePlayQueue("", "desktop", []int{1, 2}, 1, 60000)
+	assert.Nil(suite.T(), err)
+	bookmark, err := suite.Library.GetBookmark("", 2)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 60000, bookmark.Position)
+
+	// Starting the track again doesn't lose the bookmark.
+	_, err = suite.Library.SavePlayQueue("", "desktop", []int{1, 2}, 1, 0)
+	assert.Nil(suite.T(), err)
+	bookmark, _ = suite.Library.GetBookmark("", 2)
+	assert.Equal(suite.T(), 60000, bookmark.Position)
+
+	// Playing the track until its end removes the bookmark.
+	_, err = suite.Library.SavePlayQueue("", "desktop", []int{1, 2}, 1, 199000)
+	assert.Nil(suite.T(), err)
+	_, err = suite.Library.GetBookmark("", 2)
+	assert.NotNil(suite.T(), err)
+}
@@ -12,6 +12,7 @@ type ClientSettings struct {
 	LibraryPath string
 	CoversPreferredSource string
 	DisableLibraryConfiguration bool
+	WriteTags bool
 }
 
 type ClientSettingsInteractor struct {}
@@ -22,6 +23,7 @@ func (si *ClientSettingsInteractor) GetSettings() ClientSettings {
 	settings.DisableLibraryConfiguration = viper.GetBool("ClientSettings.DisableLibraryConfiguration")
 	settings.LibraryPath = viper.GetString("Library.Path")
 	settings.CoversPreferredSource = viper.GetString("Covers.PreferredSource")
+	settings.WriteTags = viper.GetBool("Library.WriteTags")
 
 	return settings
 }
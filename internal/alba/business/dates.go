@@ -0,0 +1,103 @@
+package business
+
+import (
+	"regexp"
+	"strconv"
+)
+
+/*
+This file exposes the handling of the release dates read from the media files.
+
+Dates are stored as "YYYY", "YYYY-MM" or "YYYY-MM-DD", depending on what the tags provide, so they can be compared as
+strings.
+*/
+
+// Matches the dates found in tags, like "2001", "2001-05", "2001-05-14", "2001/05/14", "2001-05-14T12:00:00" or
+// "20010514".
+var tagDateRegexp = regexp.MustCompile(`^\s*(\d{4})(?:[-/.]?(\d{2})(?:[-/.]?(\d{2}))?)?(?:[T\s].*)?$`)
+
+// Separates the first and last years of an album whose tracks have been released in different years.
+const YearSpanSeparator = "–"
+
+// Formats a date read from a tag as "YYYY", "YYYY-MM" or "YYYY-MM-DD".
+//
+// Invalid months and days are dropped. Returns an empty string if the tag doesn't hold a date.
+func NormalizeDate(value string) string {
+	matches := tagDateRegexp.FindStringSubmatch(value)
+	if matches == nil || matches[1] == "0000" {
+		return ""
+	}
+
+	date := matches[1]
+	if month, _ := strconv.Atoi(matches[2]); month >= 1 && month <= 12 {
+		date += "-" + matches[2]
+		if day, _ := strconv.Atoi(matches[3]); day >= 1 && day <= 31 {
+			date += "-" + matches[3]
+		}
+	}
+
+	return date
+}
+
+// Gets the year of a normalised date.
+func DateYear(date string) string {
+	if len(date) < 4 {
+		return ""
+	}
+
+	return date[0:4]
+}
+
+// Computes the year of an album from the dates of its tracks: "1994", or "1994–2001" if the tracks have been released
+// in different years.
+//
+// Returns an empty string if no track has a date.
+func YearSpan(dates []string) string {
+	first := DateYear(EarliestDate(dates))
+	last := DateYear(LatestDate(dates))
+	if first == last {
+		return first
+	}
+
+	return first + YearSpanSeparator + last
+}
+
+// Gets the earliest of normalised dates, the most precise one if several dates are in the same period.
+//
+// Returns an empty string if there is no date.
+func EarliestDate(dates []string) (earliest string) {
+	for _, date := range dates {
+		if date == "" {
+			continue
+		}
+		if earliest == "" {
+			earliest = date
+			continue
+		}
+
+		// Compare the dates on their common precision.
+		n := len(date)
+		if len(earliest) < n {
+			n = len(earliest)
+		}
+		if date[0:n] < earliest[0:n] || date[0:n] == earliest[0:n] && len(date) > len(earliest) {
+			earliest = date
+		}
+	}
+
+	return
+}
+
+// Gets the latest of normalised dates, the most precise one if several dates are in the same period.
+//
+// Returns an empty string if there is no date.
+func LatestDate(dates []string) (latest string) {
+	for _, date := range dates {
+		// A more precise date in the same period is always greater.
+		if date > latest {
+			latest = date
+		}
+	}
+
+	return
+}
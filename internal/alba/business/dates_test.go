@@ -0,0 +1,50 @@
+package business
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type DatesTestSuite struct {
+	suite.Suite
+}
+
+// Go testing framework entry point.
+func TestDatesTestSuite(t *testing.T) {
+	suite.Run(t, new(DatesTestSuite))
+}
+
+func (suite *DatesTestSuite) TestNormalizeDate() {
+	assert.Equal(suite.T(), "2001", NormalizeDate("2001"))
+	assert.Equal(suite.T(), "2001-05", NormalizeDate("2001-05"))
+	assert.Equal(suite.T(), "2001-05-14", NormalizeDate("2001-05-14"))
+	assert.Equal(suite.T(), "2001-05-14", NormalizeDate("2001/05/14"))
+	assert.Equal(suite.T(), "2001-05-14", NormalizeDate("20010514"))
+	assert.Equal(suite.T(), "2001-05-14", NormalizeDate(" 2001-05-14T12:30:00 "))
+	// Invalid months and days.
+	assert.Equal(suite.T(), "2001", NormalizeDate("2001-00-00"))
+	assert.Equal(suite.T(), "2001-05", NormalizeDate("2001-05-32"))
+	// Not dates.
+	assert.Equal(suite.T(), "", NormalizeDate(""))
+	assert.Equal(suite.T(), "", NormalizeDate("0000"))
+	assert.Equal(suite.T(), "", NormalizeDate("May 2001"))
+	// Anything after the date, like a time, is ignored.
+	assert.Equal(suite.T(), "2001-05-14", NormalizeDate("2001-05-14 12:30"))
+}
+
+func (suite *DatesTestSuite) TestYearSpan() {
+	assert.Equal(suite.T(), "", YearSpan(nil))
+	assert.Equal(suite.T(), "1996", YearSpan([]string{"1996", "", "1996-09-17"}))
+	assert.Equal(suite.T(), "1994–2001", YearSpan([]string{"2001-05-14", "1994", "1999-01"}))
+}
+
+func (suite *DatesTestSuite) TestEarliestAndLatestDates() {
+	dates := []string{"1999-01", "", "2001", "1994", "1994-03-02", "2001-05-14"}
+	assert.Equal(suite.T(), "1994-03-02", EarliestDate(dates))
+	assert.Equal(suite.T(), "2001-05-14", LatestDate(dates))
+
+	assert.Equal(suite.T(), "", EarliestDate([]string{""}))
+	assert.Equal(suite.T(), "", LatestDate(nil))
+}
@@ -0,0 +1,195 @@
+package business
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/spf13/viper"
+)
+
+/*
+This file exposes the content of the archives used to download the library for offline use.
+
+Archives hold the media files of the tracks, named "Artist/Album/NN - Title.ext", and the album covers, named
+"Artist/Album/cover.ext". Playlists are stored by the clients, so their archives are made from the ids of their tracks,
+named "Playlist/NN - Artist - Title.ext" after their position. Downloads can be disabled with Downloads.Enabled.
+*/
+
+var ErrDownloadsDisabled = errors.New("downloads are disabled")
+
+// Name of the playlists without name.
+const defaultPlaylistName = "Playlist"
+
+// Content of a download archive.
+type Download struct {
+	Name  string // Name of the archive, without extension.
+	Files []DownloadFile
+}
+
+// File of a download archive.
+type DownloadFile struct {
+	Name    string // Path in the archive.
+	Path    string // Path of the file on disk.
+	IsTrack bool
+	// Part of the media file, for the tracks of CUE sheets. EndMs is 0 for the end of the file.
+	StartMs int
+	EndMs   int
+}
+
+// Gets the content of the download archive of an album.
+func (interactor *LibraryInteractor) GetAlbumDownload(albumId int) (Download, error) {
+	if !viper.GetBool("Downloads.Enabled") {
+		return Download{}, ErrDownloadsDisabled
+	}
+
+	album, err := interactor.AlbumRepository.Get(albumId)
+	if err != nil {
+		return Download{}, err
+	}
+	artistName := interactor.getDownloadArtistName(album.ArtistId)
+
+	return Download{
+		Name:  SanitizeFileName(artistName + " - " + album.Title),
+		Files: interactor.getAlbumDownloadFiles(album, artistName),
+	}, nil
+}
+
+// Gets the content of the download archive of an artist, holding all of the artist albums.
+func (interactor *LibraryInteractor) GetArtistDownload(artistId int) (Download, error) {
+	if !viper.GetBool("Downloads.Enabled") {
+		return Download{}, ErrDownloadsDisabled
+	}
+
+	artist, err := interactor.ArtistRepository.Get(artistId)
+	if err != nil {
+		return Download{}, err
+	}
+	albums, err := interactor.AlbumRepository.GetAlbumsForArtist(artistId, true)
+	if err != nil {
+		return Download{}, err
+	}
+
+	download := Download{Name: SanitizeFileName(artist.Name)}
+	for _, album := range albums {
+		download.Files = append(download.Files, interactor.getAlbumDownloadFiles(album, artist.Name)...)
+	}
+
+	return download, nil
+}
+
+// Gets the content of the download archive of a playlist, from its name and the ids of its tracks in order.
+func (interactor *LibraryInteractor) GetPlaylistDownload(name string, trackIds []int) (Download, error) {
+	if !viper.GetBool("Downloads.Enabled") {
+		return Download{}, ErrDownloadsDisabled
+	}
+	if len(trackIds) == 0 {
+		return Download{}, errors.New("empty playlist")
+	}
+	if strings.TrimSpace(name) == "" {
+		name = defaultPlaylistName
+	}
+
+	download := Download{Name: SanitizeFileName(name)}
+	// The positions have the same number of digits, so the files are sorted by name.
+	digits := len(strconv.Itoa(len(trackIds)))
+	if digits < 2 {
+		digits = 2
+	}
+	for i, trackId := range trackIds {
+		track, err := interactor.TrackRepository.Get(trackId)
+		if err != nil {
+			return Download{}, err
+		}
+
+		number := strconv.Itoa(i + 1)
+		number = strings.Repeat("0", digits-len(number)) + number
+		artistName := interactor.getDownloadArtistName(track.ArtistId)
+		download.Files = append(download.Files, DownloadFile{
+			Name:    download.Name + "/" + number + " - " + SanitizeFileName(artistName+" - "+track.Title) + strings.ToLower(filepath.Ext(track.Path)),
+			Path:    track.Path,
+			IsTrack: true,
+			StartMs: track.StartMs,
+			EndMs:   track.EndMs,
+		})
+	}
+
+	return download, nil
+}
+
+// Gets the name of the artist of an album to use in the download archives.
+func (interactor *LibraryInteractor) getDownloadArtistName(artistId int) string {
+	if artist, err := interactor.ArtistRepository.Get(artistId); err == nil {
+		return artist.Name
+	}
+
+	return LibraryDefaultArtist
+}
+
+// Gets the files of an album in a download archive, which must have its tracks.
+func (interactor *LibraryInteractor) getAlbumDownloadFiles(album domain.Album, artistName string) (files []DownloadFile) {
+	directory := SanitizeFileName(artistName) + "/" + SanitizeFileName(album.Title) + "/"
+	names := make(map[string]bool)
+
+	for _, track := range album.Tracks {
+		name := SanitizeFileName(track.Title)
+		if track.Number > 0 {
+			number := strconv.Itoa(track.Number)
+			if track.Number < 10 {
+				number = "0" + number
+			}
+			name = number + " - " + name
+			// Prefix the disc number on multi-disc albums.
+			if parts := strings.Split(track.Disc, "/"); len(parts) == 2 && parts[1] != "1" && parts[0] != "" {
+				name = parts[0] + "-" + name
+			}
+		}
+
+		// Tracks with the same name are numbered.
+		extension := strings.ToLower(filepath.Ext(track.Path))
+		unique := name
+		for i := 2; names[unique]; i++ {
+			unique = name + " (" + strconv.Itoa(i) + ")"
+		}
+		names[unique] = true
+
+		files = append(files, DownloadFile{
+			Name:    directory + unique + extension,
+			Path:    track.Path,
+			IsTrack: true,
+			StartMs: track.StartMs,
+			EndMs:   track.EndMs,
+		})
+	}
+
+	if album.CoverId != 0 {
+		if cover, err := interactor.CoverRepository.Get(album.CoverId); err == nil {
+			files = append(files, DownloadFile{
+				Name: directory + "cover" + strings.ToLower(filepath.Ext(cover.Path)),
+				Path: filepath.Join(viper.GetString("Covers.Directory"), cover.Path),
+			})
+		}
+	}
+
+	return
+}
+
+// Replaces the characters which can't be used in file names on most systems.
+func SanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+
+	// Windows doesn't allow names ending with dots or spaces.
+	name = strings.TrimRight(strings.TrimSpace(name), ".")
+	if name == "" {
+		return "_"
+	}
+
+	return name
+}
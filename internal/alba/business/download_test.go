@@ -0,0 +1,130 @@
+package business
+
+import (
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type DownloadTestSuite struct {
+	suite.Suite
+	Library *LibraryInteractor
+}
+
+// Go testing framework entry point.
+func TestDownloadTestSuite(t *testing.T) {
+	suite.Run(t, new(DownloadTestSuite))
+}
+
+func (suite *DownloadTestSuite) SetupTest() {
+	suite.Library = createMockLibraryInteractor()
+	viper.Set("Downloads.Enabled", true)
+	viper.Set("Covers.Directory", "/covers")
+}
+
+func (suite *DownloadTestSuite) TearDownTest() {
+	viper.Set("Downloads.Enabled", false)
+	viper.Set("Covers.Directory", "")
+}
+
+func (suite *DownloadTestSuite) TestGetAlbumDownload() {
+	download, err := suite.Library.GetAlbumDownload(2)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Artist #0 - Album #2", download.Name)
+	assert.Len(suite.T(), download.Files, 3)
+	assert.Equal(suite.T(), DownloadFile{
+		Name:    "Artist #0/Album #2/Track #1 for album #2.mp3",
+		Path:    "/music/Album 2/Track 1.mp3",
+		IsTrack: true,
+	}, download.Files[0])
+
+	_, err = suite.Library.GetAlbumDownload(42)
+	assert.NotNil(suite.T(), err)
+
+	viper.Set("Downloads.Enabled", false)
+	_, err = suite.Library.GetAlbumDownload(2)
+	assert.Equal(suite.T(), ErrDownloadsDisabled, err)
+}
+
+func (suite *DownloadTestSuite) TestGetArtistDownload() {
+	download, err := suite.Library.GetArtistDownload(1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Artist #1", download.Name)
+	for _, file := range download.Files {
+		assert.Regexp(suite.T(), "^Artist #1/", file.Name)
+	}
+
+	_, err = suite.Library.GetArtistDownload(42)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *DownloadTestSuite) TestGetPlaylistDownload() {
+	download, err := suite.Library.GetPlaylistDownload("Road trip: 2020", []int{3, 10, 3})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Road trip_ 2020", download.Name)
+	assert.Len(suite.T(), download.Files, 3)
+	assert.Equal(suite.T(), DownloadFile{
+		Name:    "Road trip_ 2020/01 - Artist #0 - Track #3.mp3",
+		Path:    "/music/Track 3.mp3",
+		IsTrack: true,
+	}, download.Files[0])
+	// Tracks of CUE sheets are cut from their media file.
+	assert.Equal(suite.T(), "Road trip_ 2020/02 - Artist #0 - Track #10.flac", download.Files[1].Name)
+	assert.Equal(suite.T(), 180000, download.Files[1].StartMs)
+	assert.Equal(suite.T(), "Road trip_ 2020/03 - Artist #0 - Track #3.mp3", download.Files[2].Name)
+
+	// The positions have as many digits as needed.
+	trackIds := make([]int, 100)
+	for i := range trackIds {
+		trackIds[i] = 1
+	}
+	download, err = suite.Library.GetPlaylistDownload("", trackIds)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Playlist/001 - Artist #0 - Track #1.mp3", download.Files[0].Name)
+	assert.Equal(suite.T(), "Playlist/100 - Artist #0 - Track #1.mp3", download.Files[99].Name)
+
+	_, err = suite.Library.GetPlaylistDownload("Playlist", []int{1, 42})
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.GetPlaylistDownload("Playlist", nil)
+	assert.NotNil(suite.T(), err)
+
+	viper.Set("Downloads.Enabled", false)
+	_, err = suite.Library.GetPlaylistDownload("Playlist", []int{1})
+	assert.Equal(suite.T(), ErrDownloadsDisabled, err)
+}
+
+func (suite *DownloadTestSuite) TestGetAlbumDownloadFiles() {
+	album := domain.Album{
+		Title:   "Greatest Hits: 1970/1980",
+		CoverId: 1,
+		Tracks: domain.Tracks{
+			{Title: "Intro", Number: 1, Disc: "1/2", Path: "/music/Hits/CD1/01.FLAC"},
+			{Title: "What?", Number: 12, Disc: "2/2", Path: "/music/Hits/CD2/12.flac"},
+			{Title: "Intro", Number: 1, Disc: "1/2", Path: "/music/Hits/CD1/01b.flac"},
+			{Title: "Hidden", Path: "/music/Hits/Album.flac", StartMs: 1000, EndMs: 2000},
+		},
+	}
+
+	files := suite.Library.getAlbumDownloadFiles(album, "AC/DC")
+	assert.Len(suite.T(), files, 5)
+	assert.Equal(suite.T(), "AC_DC/Greatest Hits_ 1970_1980/1-01 - Intro.flac", files[0].Name)
+	assert.Equal(suite.T(), "AC_DC/Greatest Hits_ 1970_1980/2-12 - What_.flac", files[1].Name)
+	assert.Equal(suite.T(), "AC_DC/Greatest Hits_ 1970_1980/1-01 - Intro (2).flac", files[2].Name)
+	assert.Equal(suite.T(), "AC_DC/Greatest Hits_ 1970_1980/Hidden.flac", files[3].Name)
+	assert.Equal(suite.T(), 1000, files[3].StartMs)
+	assert.Equal(suite.T(), 2000, files[3].EndMs)
+	assert.Equal(suite.T(), DownloadFile{
+		Name: "AC_DC/Greatest Hits_ 1970_1980/cover.jpg",
+		Path: "/covers/path/to/cover.jpg",
+	}, files[4])
+}
+
+func (suite *DownloadTestSuite) TestSanitizeFileName() {
+	assert.Equal(suite.T(), "Who_ What_ _Why_", SanitizeFileName("Who? What: \"Why\""))
+	assert.Equal(suite.T(), "Tabs_and_lines", SanitizeFileName("Tabs\tand\nlines"))
+	assert.Equal(suite.T(), "St", SanitizeFileName(" St. "))
+	assert.Equal(suite.T(), "_", SanitizeFileName("..."))
+}
@@ -16,6 +16,11 @@ type ArtistRepository interface {
 	// Gets an entity based on its name.
 	GetByName(name string) (entity domain.Artist, err error)
 
+	// Gets all artists having at least one track of a given genre.
+	//
+	// If no artist found, returns an empty collection without error.
+	GetArtistsForGenre(genreId int) (entities domain.Artists, err error)
+
 	// Saves an entity to a datasource.
 	Save(entity *domain.Artist) (err error)
 
@@ -27,7 +32,7 @@ type ArtistRepository interface {
 	// Tests if an entity exists in datasource.
 	Exists(id int) bool
 
-	// Removes artists without tracks drom DB.
+	// Removes artists without tracks, credits nor albums drom DB.
 	CleanUp() error
 }
 
@@ -50,6 +55,16 @@ type AlbumRepository interface {
 	// If hydrate == true, hydrate the sub objects. If no album found, returns an empty collection without error.
 	GetAlbumsForArtist(artistId int, hydrate bool) (entities domain.Albums, err error)
 
+	// Gets all albums having at least one track of a given genre.
+	//
+	// If no album found, returns an empty collection without error.
+	GetAlbumsForGenre(genreId int) (entities domain.Albums, err error)
+
+	// Gets all albums of other artists having at least one track crediting a given artist.
+	//
+	// If no album found, returns an empty collection without error.
+	GetAlbumsArtistAppearsOn(artistId int) (entities domain.Albums, err error)
+
 	// Saves an entity to a datasource.
 	Save(entity *domain.Album) (err error)
 
@@ -65,6 +80,22 @@ type AlbumRepository interface {
 	CleanUp() error
 }
 
+// Criteria of a track search. Zero values match any track, text values are compared without case.
+type TrackFilter struct {
+	GenreId             int
+	MusicBrainzTrackId  string
+	MusicBrainzAlbumId  string
+	MusicBrainzArtistId string
+	Isrc                string
+	Label               string
+	CatalogNumber       string
+	OriginalYear        string
+	Composer            string
+	Conductor           string
+	BpmMin              int
+	BpmMax              int
+}
+
 type TrackRepository interface {
 	// Gets an entity from a datasource.
 	//
@@ -84,9 +115,32 @@ type TrackRepository interface {
 	// If hydrate == true, hydrate the sub objects. If no track found, returns an empty collection without error.
 	GetTracksForAlbum(albumId int) (entities domain.Tracks, err error)
 
+	// Gets all tracks of a given genre.
+	//
+	// If no track found, returns an empty collection without error.
+	GetTracksForGenre(genreId int) (entities domain.Tracks, err error)
+
+	// Gets all tracks matching every criteria of a filter, ordered by album then disc and track number.
+	//
+	// If no track found, returns an empty collection without error.
+	FindTracks(filter TrackFilter) (entities domain.Tracks, err error)
+
+	// Gets all tracks crediting a given artist with a given role, or with any role if role is empty.
+	//
+	// If no track found, returns an empty collection without error.
+	GetTracksForArtist(artistId int, role string) (entities domain.Tracks, err error)
+
+	// Gets the artists credited on a given track, main artists first.
+	GetTrackArtists(trackId int) (entities domain.TrackArtists, err error)
+
 	// Saves an entity to a datasource.
 	Save(entity *domain.Track) (err error)
 
+	// Saves the loudness analysis results of a track, leaving its other fields as they are in the datasource.
+	//
+	// Does not return an error if the entity doesn't exist anymore on the datasource.
+	SaveLoudness(entity *domain.Track) (err error)
+
 	// Deletes an entity from a datasource.
 	//
 	// Does not return an error if the entity doesn't exists on the datasource or no entity id is given.
@@ -96,6 +150,40 @@ type TrackRepository interface {
 	Exists(id int) bool
 }
 
+type GenreRepository interface {
+	// Gets an entity from a datasource, with its tracks and albums count.
+	//
+	// Returns an error if no entity found.
+	Get(id int) (entity domain.Genre, err error)
+
+	// Gets all entities from the datasource, with their tracks and albums count.
+	//
+	// If no entities found, returns an empty collection without error.
+	GetAll() (entities domain.Genres, err error)
+
+	// Gets an entity based on its name (case insensitive).
+	GetByName(name string) (entity domain.Genre, err error)
+
+	// Gets all genres of a given track.
+	//
+	// If no genre found, returns an empty collection without error.
+	GetGenresForTrack(trackId int) (entities domain.Genres, err error)
+
+	// Saves an entity to a datasource.
+	Save(entity *domain.Genre) (err error)
+
+	// Deletes an entity from a datasource.
+	//
+	// Does not return an error if the entity doesn't exists on the datasource or no entity id is given.
+	Delete(entity *domain.Genre) (err error)
+
+	// Tests if an entity exists in datasource.
+	Exists(id int) bool
+
+	// Removes genres without tracks from DB.
+	CleanUp() error
+}
+
 type CoverRepository interface {
 	// Gets an entity from a datasource.
 	//
@@ -119,6 +207,194 @@ type CoverRepository interface {
 	ExistsByHash(hash string) int
 }
 
+type OverrideRepository interface {
+	// Gets an entity from a datasource.
+	//
+	// Returns an hydrated entity if entity is fund, else an error.
+	Get(id int) (entity domain.Override, err error)
+
+	// Gets all the overrides, or all the overrides of an entity type if entityType is not empty.
+	//
+	// If no entities found, returns an empty collection without error.
+	GetAll(entityType string) (entities domain.Overrides, err error)
+
+	// Gets all the overrides of an entity.
+	//
+	// If no entities found, returns an empty collection without error.
+	GetForEntity(entityType string, entityId int) (entities domain.Overrides, err error)
+
+	// Saves an entity to a datasource.
+	Save(entity *domain.Override) (err error)
+
+	// Deletes an entity from a datasource.
+	//
+	// Does not return an error if the entity doesn't exists on the datasource or no entity id is given.
+	Delete(entity *domain.Override) (err error)
+
+	// Removes the overrides of entities which don't exist anymore.
+	CleanUp() error
+}
+
+type ArtistAliasRepository interface {
+	// Gets an entity from a datasource.
+	//
+	// Returns an hydrated entity if entity is fund, else an error.
+	Get(id int) (entity domain.ArtistAlias, err error)
+
+	// Gets all the aliases, or all the aliases of an artist if artistId is not 0.
+	//
+	// If no entities found, returns an empty collection without error.
+	GetAll(artistId int) (entities domain.ArtistAliases, err error)
+
+	// Merges artists into a target artist, recording their names as aliases of the target artist.
+	//
+	// Returns the created aliases. Nothing is merged if one of the artists cannot be.
+	Merge(sourceIds []int, targetId int) (entities domain.ArtistAliases, err error)
+
+	// Undoes the merge which created an alias.
+	//
+	// Returns the restored artist.
+	Unmerge(entity *domain.ArtistAlias) (artist domain.Artist, err error)
+
+	// Removes the aliases of artists which don't exist anymore.
+	CleanUp() error
+}
+
+type LyricsRepository interface {
+	// Gets the lyrics of a track.
+	//
+	// Returns an error if the track has no lyrics.
+	GetForTrack(trackId int) (entity domain.Lyrics, err error)
+
+	// Removes the lyrics of tracks which don't exist anymore.
+	CleanUp() error
+}
+
+type ShareRepository interface {
+	// Gets an entity from a datasource.
+	//
+	// Returns an entity if found, else an error.
+	Get(id int) (entity domain.Share, err error)
+
+	// Gets a share from its slug.
+	//
+	// Returns an entity if found, else an error.
+	GetBySlug(slug string) (entity domain.Share, err error)
+
+	// Gets all the shares, the most recent first.
+	//
+	// If no entities found, returns an empty collection without error.
+	GetAll() (entities domain.Shares, err error)
+
+	// Saves an entity to a datasource, with the tracks of a playlist.
+	Save(entity *domain.Share) (err error)
+
+	// Deletes an entity from a datasource.
+	//
+	// Does not return an error if the entity doesn't exists on the datasource or no entity id is given.
+	Delete(entity *domain.Share) (err error)
+
+	// Counts a play of a share, unless its maximum number of plays is reached.
+	//
+	// Returns false if the play hasn't been counted.
+	AddPlay(id int) (bool, error)
+
+	// Removes the shares of albums and tracks which don't exist anymore, and the tracks which don't exist anymore from
+	// the shared playlists.
+	CleanUp() error
+}
+
+type ScrobbleAccountRepository interface {
+	// Gets an entity from a datasource.
+	//
+	// Returns an entity if found, else an error.
+	Get(id int) (entity domain.ScrobbleAccount, err error)
+
+	// Gets the account of a user of a scrobbling service.
+	//
+	// Returns an entity if found, else an error.
+	GetByName(service string, userName string) (entity domain.ScrobbleAccount, err error)
+
+	// Gets all the accounts.
+	//
+	// If no entities found, returns an empty collection without error.
+	GetAll() (entities domain.ScrobbleAccounts, err error)
+
+	// Saves an entity to a datasource.
+	Save(entity *domain.ScrobbleAccount) (err error)
+
+	// Deletes an entity from a datasource.
+	//
+	// Does not return an error if the entity doesn't exists on the datasource or no entity id is given.
+	Delete(entity *domain.ScrobbleAccount) (err error)
+}
+
+type ScrobbleRepository interface {
+	// Gets all the scrobbles waiting to be sent, the oldest first.
+	//
+	// If no entities found, returns an empty collection without error.
+	GetAll() (entities domain.Scrobbles, err error)
+
+	// Saves an entity to a datasource.
+	Save(entity *domain.Scrobble) (err error)
+
+	// Deletes an entity from a datasource.
+	//
+	// Does not return an error if the entity doesn't exists on the datasource or no entity id is given.
+	Delete(entity *domain.Scrobble) (err error)
+
+	// Removes the scrobbles of accounts which don't exist anymore.
+	CleanUp() error
+}
+
+type PlayQueueRepository interface {
+	// Gets the play queue of a user, with its tracks.
+	//
+	// Returns an entity if found, else an error.
+	Get(user string) (entity domain.PlayQueue, err error)
+
+	// Saves an entity to a datasource, replacing its tracks.
+	Save(entity *domain.PlayQueue) (err error)
+}
+
+type BookmarkRepository interface {
+	// Gets the bookmark of a user for a track.
+	//
+	// Returns an entity if found, else an error.
+	Get(user string, trackId int) (entity domain.Bookmark, err error)
+
+	// Gets all the bookmarks of a user, the most recently modified first.
+	//
+	// If no entities found, returns an empty collection without error.
+	GetAll(user string) (entities domain.Bookmarks, err error)
+
+	// Saves an entity to a datasource.
+	Save(entity *domain.Bookmark) (err error)
+
+	// Deletes an entity from a datasource.
+	//
+	// Does not return an error if the entity doesn't exists on the datasource or no entity id is given.
+	Delete(entity *domain.Bookmark) (err error)
+
+	// Removes the bookmarks of tracks which don't exist anymore.
+	CleanUp() error
+}
+
+// Client of a scrobbling service.
+type Scrobbler interface {
+	// Links an account from a token given by the service: a Last.fm authentication token or a ListenBrainz user
+	// token. Returns the account with its name and credentials.
+	LinkAccount(token string) (domain.ScrobbleAccount, error)
+
+	// Sends the track being played.
+	NowPlaying(account domain.ScrobbleAccount, scrobble domain.Scrobble) error
+
+	// Submits a play.
+	//
+	// Returns a ScrobbleRejectedError if the service refused the play, which must not be sent again.
+	Scrobble(account domain.ScrobbleAccount, scrobble domain.Scrobble) error
+}
+
 type InternalVariableRepository interface {
 	// Gets an entity from a datasource.
 	//
@@ -141,6 +417,17 @@ type LibraryRepository interface {
 	Erase()
 }
 
+// Interface describing the storage of the computed waveforms.
+type WaveformRepository interface {
+	// Gets the waveform of a track from a directory.
+	//
+	// Returns an error if the waveform isn't stored or if the media file of the track has changed since.
+	Get(track domain.Track, directory string) (Waveform, error)
+
+	// Stores the waveform of a track in a directory.
+	Save(track domain.Track, waveform Waveform, directory string) error
+}
+
 // Interface describing the storage mecanism for media.
 type MediaFileRepository interface {
 	// TODO Not abstract enough yet, we should not need a path but a reader or something.
@@ -149,4 +436,19 @@ type MediaFileRepository interface {
 	WriteCoverFile(file *domain.Cover, directory string) error
 	RemoveCoverFile(file *domain.Cover, directory string) error
 	DeleteCovers() error
+
+	// Writes tag values into a media file, values being indexed by the names listed in TrackTagFields. An empty value
+	// removes the tag.
+	//
+	// Returns the changes made to the file, or the changes that would be made if dryRun is true.
+	WriteTags(filepath string, values map[string]string, dryRun bool) ([]TagEdit, error)
+
+	// Imports the given media files again.
+	RescanMediaFiles(filepaths []string) error
+
+	// Decodes the audio of a media file between two times in milliseconds, the end being 0 for the end of the file.
+	//
+	// The audio is passed to the write callback by chunks, samples being indexed by channel and normalized to [-1, 1].
+	// Decoding stops at the first error returned by the callback.
+	DecodeAudio(filepath string, startMs int, endMs int, write func(sampleRate int, samples [][]float64) error) error
 }
@@ -19,13 +19,34 @@ type LibraryInteractor struct {
 	ArtistRepository  ArtistRepository
 	AlbumRepository AlbumRepository
 	TrackRepository TrackRepository
+	GenreRepository GenreRepository
 	CoverRepository CoverRepository
 	// TODO Check if the library repo should be an interface here.
 	LibraryRepository LibraryRepository
 	MediaFileRepository MediaFileRepository
+	OverrideRepository OverrideRepository
+	ArtistAliasRepository ArtistAliasRepository
+	LyricsRepository LyricsRepository
+	WaveformRepository WaveformRepository
+	ShareRepository ShareRepository
+	ScrobbleAccountRepository ScrobbleAccountRepository
+	ScrobbleRepository ScrobbleRepository
+	Scrobblers map[string]Scrobbler // Clients of the scrobbling services, by service.
+	PlayQueueRepository PlayQueueRepository
+	BookmarkRepository BookmarkRepository
 	InternalVariableRepository InternalVariableRepository
 	mutex sync.Mutex
 	LibraryIsUpdating bool
+	loudnessMutex sync.Mutex
+	loudnessProgressMutex sync.Mutex
+	loudnessProgress LoudnessAnalysisProgress
+	scrobbleMutex sync.Mutex
+	playQueueMutex sync.Mutex
+	playQueueListeners map[chan domain.PlayQueue]string // Users of the play queues listened to, by listener.
+	partyMutex sync.Mutex
+	parties map[string]*Party // Parties, by id.
+	partyListeners map[chan Party]string // Ids of the parties listened to, by listener.
+	partyClock func() time.Time // Current time for the parties, time.Now() if nil.
 }
 
 // Gets an artist by id.
@@ -57,10 +78,25 @@ func (interactor *LibraryInteractor) SaveArtist(artist *domain.Artist) error {
 	if artist.Name == "" {
 		return errors.New("cannot save artist: empty name")
 	}
+	if artist.SortName == "" {
+		artist.SortName = SortName(artist.Name, viper.GetStringSlice("Library.SortArticles"))
+	}
 
 	return interactor.ArtistRepository.Save(artist)
 }
 
+// Gets all artists grouped by the first letter of their sort name.
+//
+// If no artists found, returns an empty index.
+func (interactor *LibraryInteractor) GetArtistIndex() ([]ArtistIndexGroup, error) {
+	artists, err := interactor.ArtistRepository.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildArtistIndex(artists, viper.GetStringSlice("Library.SortArticles")), nil
+}
+
 // Deletes an artist.
 //
 // Returns an error if no artistId provided.
@@ -121,6 +157,9 @@ func (interactor *LibraryInteractor) SaveAlbum(album *domain.Album) error {
 	if invalid {
 		return errors.New(message)
 	}
+	if album.SortName == "" {
+		album.SortName = SortName(album.Title, viper.GetStringSlice("Library.SortArticles"))
+	}
 
 	return interactor.AlbumRepository.Save(album)
 }
@@ -166,6 +205,28 @@ func (interactor *LibraryInteractor) GetTracksForAlbum(albumId int) (domain.Trac
 	return interactor.TrackRepository.GetTracksForAlbum(albumId)
 }
 
+// Get all tracks crediting a given artist with a given role, or with any role if role is empty.
+//
+// If the artist doesn't exists, return an error
+func (interactor *LibraryInteractor) GetTracksForArtist(artistId int, role string) (domain.Tracks, error) {
+	if !interactor.ArtistExists(artistId) {
+		return domain.Tracks{}, errors.New("cannot get tracks: invalid artist ID")
+	}
+
+	return interactor.TrackRepository.GetTracksForArtist(artistId, role)
+}
+
+// Get all albums of other artists an artist appears on.
+//
+// If the artist doesn't exists, return an error
+func (interactor *LibraryInteractor) GetAlbumsArtistAppearsOn(artistId int) (domain.Albums, error) {
+	if !interactor.ArtistExists(artistId) {
+		return domain.Albums{}, errors.New("cannot get albums: invalid artist ID")
+	}
+
+	return interactor.AlbumRepository.GetAlbumsArtistAppearsOn(artistId)
+}
+
 // Saves a track.
 //
 // A track cannot be saved without a title or if the related artist or album, if any, doesn't exists.
@@ -216,6 +277,76 @@ func (interactor *LibraryInteractor) TrackExists(trackId int) bool {
 	return interactor.TrackRepository.Exists(trackId)
 }
 
+// Gets a genre from its id.
+//
+// If no genre found, returns an error.
+func (interactor *LibraryInteractor) GetGenre(genreId int) (domain.Genre, error) {
+	return interactor.GenreRepository.Get(genreId)
+}
+
+// Gets all genres.
+//
+// If no genres found, returns an empty collection.
+func (interactor *LibraryInteractor) GetAllGenres() (domain.Genres, error) {
+	return interactor.GenreRepository.GetAll()
+}
+
+// Get all genres for a given track.
+//
+// If the track doesn't exists, return an error
+func (interactor *LibraryInteractor) GetGenresForTrack(trackId int) (domain.Genres, error) {
+	if !interactor.TrackExists(trackId) {
+		return domain.Genres{}, errors.New("cannot get genres: invalid track ID")
+	}
+
+	return interactor.GenreRepository.GetGenresForTrack(trackId)
+}
+
+// Get all artists having tracks of a given genre.
+//
+// If the genre doesn't exists, return an error
+func (interactor *LibraryInteractor) GetArtistsForGenre(genreId int) (domain.Artists, error) {
+	if !interactor.GenreRepository.Exists(genreId) {
+		return domain.Artists{}, errors.New("cannot get artists: invalid genre ID")
+	}
+
+	return interactor.ArtistRepository.GetArtistsForGenre(genreId)
+}
+
+// Get all albums having tracks of a given genre.
+//
+// If the genre doesn't exists, return an error
+func (interactor *LibraryInteractor) GetAlbumsForGenre(genreId int) (domain.Albums, error) {
+	if !interactor.GenreRepository.Exists(genreId) {
+		return domain.Albums{}, errors.New("cannot get albums: invalid genre ID")
+	}
+
+	return interactor.AlbumRepository.GetAlbumsForGenre(genreId)
+}
+
+// Get all tracks of a given genre.
+//
+// If the genre doesn't exists, return an error
+func (interactor *LibraryInteractor) GetTracksForGenre(genreId int) (domain.Tracks, error) {
+	if !interactor.GenreRepository.Exists(genreId) {
+		return domain.Tracks{}, errors.New("cannot get tracks: invalid genre ID")
+	}
+
+	return interactor.TrackRepository.GetTracksForGenre(genreId)
+}
+
+// Gets the tracks matching a filter, see TrackFilter.
+func (interactor *LibraryInteractor) FindTracks(filter TrackFilter) (domain.Tracks, error) {
+	if filter.GenreId != 0 && !interactor.GenreRepository.Exists(filter.GenreId) {
+		return domain.Tracks{}, errors.New("cannot get tracks: invalid genre ID")
+	}
+	if filter.BpmMin < 0 || (filter.BpmMax != 0 && filter.BpmMax < filter.BpmMin) {
+		return domain.Tracks{}, errors.New("cannot get tracks: invalid BPM range")
+	}
+
+	return interactor.TrackRepository.FindTracks(filter)
+}
+
 // Saves a cover.
 func (interactor *LibraryInteractor) SaveCover(cover *domain.Cover) error {
 	invalid := false
@@ -295,6 +426,10 @@ func (interactor *LibraryInteractor) UpdateLibrary() {
 
 	interactor.LibraryIsUpdating = false
 	interactor.mutex.Unlock()
+
+	if viper.GetBool("Waveforms.Precompute") {
+		interactor.PrecomputeWaveforms()
+	}
 }
 
 // Removes all data from library.
@@ -327,6 +462,24 @@ func (interactor *LibraryInteractor) CleanUpLibrary() {
 
 	// Delete artists if no more tracks from them.
 	_ = interactor.ArtistRepository.CleanUp()
+
+	// Delete genres if no more tracks in them.
+	_ = interactor.GenreRepository.CleanUp()
+
+	// Delete the manual edits of deleted entities.
+	_ = interactor.OverrideRepository.CleanUp()
+
+	// Delete the aliases of deleted artists.
+	_ = interactor.ArtistAliasRepository.CleanUp()
+
+	// Delete the lyrics of deleted tracks.
+	_ = interactor.LyricsRepository.CleanUp()
+
+	// Delete the shares of deleted albums and tracks, and the deleted tracks of the shared playlists.
+	_ = interactor.ShareRepository.CleanUp()
+
+	// Delete the bookmarks of deleted tracks.
+	_ = interactor.BookmarkRepository.CleanUp()
 }
 
 // Create a common artist for compilations.
@@ -59,6 +59,14 @@ func (suite *ArtistInteractorTestSuite) TestGetAllArtists() {
 	}
 }
 
+func (suite *ArtistInteractorTestSuite) TestGetArtistIndex() {
+	index, err := suite.Library.GetArtistIndex()
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), index, 1)
+	assert.Equal(suite.T(), "A", index[0].Letter)
+	assert.Len(suite.T(), index[0].Artists, 3)
+}
+
 func (suite *ArtistInteractorTestSuite) TestSaveArtist() {
 	// Test to save a new artist.
 	newArtist := &domain.Artist{
@@ -192,6 +200,17 @@ func (suite *AlbumInteractorTestSuite) TestGetAlbumsForArtist() {
 	assert.NotNil(suite.T(), err)
 }
 
+func (suite *AlbumInteractorTestSuite) TestGetAlbumsArtistAppearsOn() {
+	// Test with valid artist id.
+	albums, err := suite.Library.GetAlbumsArtistAppearsOn(1)
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), albums)
+
+	// Test with invalid artist id.
+	_, err = suite.Library.GetAlbumsArtistAppearsOn(54)
+	assert.NotNil(suite.T(), err)
+}
+
 func (suite *AlbumInteractorTestSuite) TestSaveAlbum() {
 	// Test to save a new album.
 	newAlbum := &domain.Album{
@@ -313,6 +332,44 @@ func (suite *TrackInteractorTestSuite) TestGetTracksForAlbum() {
 	assert.NotNil(suite.T(), err)
 }
 
+func (suite *TrackInteractorTestSuite) TestGetTracksForGenre() {
+	// Test with valid genre id.
+	tracks, err := suite.Library.GetTracksForGenre(1)
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), tracks)
+
+	// Test with invalid genre id.
+	_, err = suite.Library.GetTracksForGenre(54)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *TrackInteractorTestSuite) TestFindTracks() {
+	tracks, err := suite.Library.FindTracks(TrackFilter{GenreId: 1, Label: "Warp", BpmMin: 100, BpmMax: 140})
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), tracks)
+
+	// Test with invalid genre id.
+	_, err = suite.Library.FindTracks(TrackFilter{GenreId: 54})
+	assert.NotNil(suite.T(), err)
+
+	// Test with invalid BPM ranges.
+	_, err = suite.Library.FindTracks(TrackFilter{BpmMin: -1})
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.FindTracks(TrackFilter{BpmMin: 140, BpmMax: 100})
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *TrackInteractorTestSuite) TestGetTracksForArtist() {
+	// Test with valid artist id.
+	tracks, err := suite.Library.GetTracksForArtist(1, domain.ArtistRoleMain)
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), tracks)
+
+	// Test with invalid artist id.
+	_, err = suite.Library.GetTracksForArtist(54, domain.ArtistRoleMain)
+	assert.NotNil(suite.T(), err)
+}
+
 func (suite *TrackInteractorTestSuite) TestSaveTrack() {
 	// Test to save a new track.
 	newTrack := &domain.Track{
@@ -0,0 +1,364 @@
+package business
+
+import (
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+/*
+This file exposes the loudness analysis of the tracks, for the tracks missing ReplayGain tags.
+
+Loudness is measured as described by EBU R128 / ITU-R BS.1770-4: the audio is K-weighted, its mean square is computed
+over 400ms blocks overlapping by 75%, and the integrated loudness is the mean of the blocks louder than -70 LUFS and
+not more than 10 LU below the mean loudness of those blocks. Album loudness is computed from the blocks of all the
+tracks of the album, not from the loudness of each track.
+*/
+
+// Loudness of the ReplayGain 2.0 reference level, in LUFS.
+const ReplayGainReferenceLoudness = -18.0
+
+// Absolute gating threshold, in LUFS.
+const loudnessAbsoluteGate = -70.0
+
+// Relative gating threshold, in LU below the loudness of the blocks above the absolute threshold.
+const loudnessRelativeGate = -10.0
+
+// Coefficients of the 4x oversampling FIR filter used to measure the true peak, one row per phase (ITU-R BS.1770-4).
+var truePeakFilter = [4][12]float64{
+	{0.0017089843750, 0.0109863281250, -0.0196533203125, 0.0332031250000, -0.0594482421875, 0.1373291015625, 0.9721679687500, -0.1022949218750, 0.0476074218750, -0.0266113281250, 0.0148925781250, -0.0083007812500},
+	{-0.0291748046875, 0.0292968750000, -0.0517578125000, 0.0891113281250, -0.1665039062500, 0.4650878906250, 0.7797851562500, -0.2003173828125, 0.1015625000000, -0.0582275390625, 0.0330810546875, -0.0189208984375},
+	{-0.0189208984375, 0.0330810546875, -0.0582275390625, 0.1015625000000, -0.2003173828125, 0.7797851562500, 0.4650878906250, -0.1665039062500, 0.0891113281250, -0.0517578125000, 0.0292968750000, -0.0291748046875},
+	{-0.0083007812500, 0.0148925781250, -0.0266113281250, 0.0476074218750, -0.1022949218750, 0.9721679687500, 0.1373291015625, -0.0594482421875, 0.0332031250000, -0.0196533203125, 0.0109863281250, 0.0017089843750},
+}
+
+// Second order IIR filter.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+
+	return y
+}
+
+/*
+Measures the loudness and the true peak of audio.
+
+Audio is written to the meter by chunks, samples being indexed by channel and normalized to [-1, 1].
+*/
+type LoudnessMeter struct {
+	sampleRate int
+	shelving   []biquad // High shelf filter of the K-weighting, per channel.
+	highPass   []biquad // High pass filter of the K-weighting, per channel.
+	weights    []float64
+	history    [][]float64 // Last samples of each channel, for the true peak filter.
+	// Sum of the weighted squares of the current 100ms sub-block, and number of samples in it.
+	subBlockSum   float64
+	subBlockCount int
+	subBlocks     []float64 // Mean squares of the 3 previous sub-blocks.
+	// Mean squares of the 400ms blocks.
+	Blocks   []float64
+	TruePeak float64 // Linear amplitude.
+}
+
+// Creates a loudness meter for audio with a given sample rate and number of channels.
+func NewLoudnessMeter(sampleRate int, channels int) *LoudnessMeter {
+	meter := &LoudnessMeter{
+		sampleRate: sampleRate,
+		shelving:   make([]biquad, channels),
+		highPass:   make([]biquad, channels),
+		weights:    make([]float64, channels),
+		history:    make([][]float64, channels),
+	}
+
+	// K-weighting filters coefficients for any sample rate.
+	k := math.Tan(math.Pi * 1681.974450955533 / float64(sampleRate))
+	q := 0.7071752369554196
+	vh := math.Pow(10, 3.999843853973347/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelving := biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	k = math.Tan(math.Pi * 38.13547087602444 / float64(sampleRate))
+	q = 0.5003270373238773
+	a0 = 1 + k/q + k*k
+	highPass := biquad{b0: 1, b1: -2, b2: 1, a1: 2 * (k*k - 1) / a0, a2: (1 - k/q + k*k) / a0}
+
+	for channel := 0; channel < channels; channel++ {
+		meter.shelving[channel] = shelving
+		meter.highPass[channel] = highPass
+		meter.history[channel] = make([]float64, len(truePeakFilter[0]))
+		// Surround channels of 5.1 audio are louder and the LFE channel is ignored.
+		meter.weights[channel] = 1
+		if channels == 6 {
+			meter.weights[channel] = []float64{1, 1, 1, 0, 1.41, 1.41}[channel]
+		}
+	}
+
+	return meter
+}
+
+// Adds audio to the measure.
+func (meter *LoudnessMeter) Write(samples [][]float64) {
+	if len(samples) == 0 {
+		return
+	}
+	subBlockSize := meter.sampleRate / 10
+
+	for i := range samples[0] {
+		for channel := range samples {
+			x := samples[channel][i]
+			meter.updateTruePeak(channel, x)
+			y := meter.highPass[channel].process(meter.shelving[channel].process(x))
+			meter.subBlockSum += meter.weights[channel] * y * y
+		}
+
+		meter.subBlockCount++
+		if meter.subBlockCount == subBlockSize {
+			meanSquare := meter.subBlockSum / float64(subBlockSize)
+			if len(meter.subBlocks) == 3 {
+				meter.Blocks = append(meter.Blocks, (meter.subBlocks[0]+meter.subBlocks[1]+meter.subBlocks[2]+meanSquare)/4)
+				meter.subBlocks = meter.subBlocks[1:]
+			}
+			meter.subBlocks = append(meter.subBlocks, meanSquare)
+			meter.subBlockSum = 0
+			meter.subBlockCount = 0
+		}
+	}
+}
+
+// Oversamples a channel 4 times to find the peaks between the samples.
+func (meter *LoudnessMeter) updateTruePeak(channel int, x float64) {
+	history := meter.history[channel]
+	copy(history, history[1:])
+	history[len(history)-1] = x
+
+	for _, phase := range truePeakFilter {
+		var y float64
+		for i, coefficient := range phase {
+			y += coefficient * history[len(history)-1-i]
+		}
+		if math.Abs(y) > meter.TruePeak {
+			meter.TruePeak = math.Abs(y)
+		}
+	}
+	if math.Abs(x) > meter.TruePeak {
+		meter.TruePeak = math.Abs(x)
+	}
+}
+
+// Gets the integrated loudness of the audio written so far, in LUFS.
+//
+// Returns false if the audio is too short or too quiet to be measured.
+func (meter *LoudnessMeter) Loudness() (float64, bool) {
+	return IntegratedLoudness(meter.Blocks)
+}
+
+// Gets the integrated loudness of the given blocks mean squares, in LUFS.
+//
+// Returns false if none of the blocks is above the gating thresholds.
+func IntegratedLoudness(blocks []float64) (float64, bool) {
+	gated := func(threshold float64) (sum float64, count int) {
+		for _, block := range blocks {
+			if blockLoudness(block) > threshold {
+				sum += block
+				count++
+			}
+		}
+		return
+	}
+
+	sum, count := gated(loudnessAbsoluteGate)
+	if count == 0 {
+		return 0, false
+	}
+	sum, count = gated(blockLoudness(sum/float64(count)) + loudnessRelativeGate)
+	if count == 0 {
+		return 0, false
+	}
+
+	return blockLoudness(sum / float64(count)), true
+}
+
+// Converts a mean square to a loudness in LUFS.
+func blockLoudness(meanSquare float64) float64 {
+	if meanSquare <= 0 {
+		return math.Inf(-1)
+	}
+
+	return -0.691 + 10*math.Log10(meanSquare)
+}
+
+// Progress of the loudness analysis of the library.
+type LoudnessAnalysisProgress struct {
+	Running     bool
+	TracksDone  int
+	TracksTotal int
+	AlbumsDone  int
+	AlbumsTotal int
+	Failed      int    // Number of tracks whose audio couldn't be decoded.
+	Error       string // Error which stopped the analysis, if any.
+}
+
+/*
+Analyzes the loudness of the tracks of the library which haven't been analyzed yet, or of all the tracks if force is
+true.
+
+Tracks are analyzed album by album, as the album loudness depends on all of its tracks, and each album is saved once
+done, so an interrupted analysis resumes from the first album not completely analyzed. Only the loudness of the tracks
+is saved, so the changes made to the library while the analysis runs are kept. Tracks whose audio cannot be decoded are
+marked as analyzed without values. The progress callback, which can be nil, is called after each track.
+*/
+func (interactor *LibraryInteractor) AnalyzeLoudness(force bool, progress func(LoudnessAnalysisProgress)) error {
+	interactor.loudnessMutex.Lock()
+	defer interactor.loudnessMutex.Unlock()
+
+	tracks, err := interactor.TrackRepository.GetAll()
+	if err != nil {
+		interactor.setLoudnessProgress(LoudnessAnalysisProgress{Error: err.Error()}, progress)
+		return err
+	}
+
+	// Albums to analyze, in the order of their first track.
+	var albumIds []int
+	albums := make(map[int]domain.Tracks)
+	for _, track := range tracks {
+		if _, ok := albums[track.AlbumId]; !ok {
+			albumIds = append(albumIds, track.AlbumId)
+		}
+		albums[track.AlbumId] = append(albums[track.AlbumId], track)
+	}
+	state := LoudnessAnalysisProgress{Running: true}
+	var todo []int
+	for _, albumId := range albumIds {
+		if force || !loudnessAnalyzed(albums[albumId]) {
+			todo = append(todo, albumId)
+			state.AlbumsTotal++
+			state.TracksTotal += len(albums[albumId])
+		}
+	}
+
+	interactor.setLoudnessProgress(state, progress)
+	for _, albumId := range todo {
+		albumTracks := albums[albumId]
+		var albumBlocks []float64
+		var albumPeak float64
+		for i := range albumTracks {
+			track := &albumTracks[i]
+			meter, errDecode := interactor.measureLoudness(track)
+			track.LoudnessTrackLufs, track.LoudnessTrackPeak = 0, 0
+			if errDecode != nil {
+				state.Failed++
+			} else {
+				if loudness, ok := meter.Loudness(); ok {
+					track.LoudnessTrackLufs = loudness
+				}
+				track.LoudnessTrackPeak = meter.TruePeak
+				albumBlocks = append(albumBlocks, meter.Blocks...)
+				albumPeak = math.Max(albumPeak, meter.TruePeak)
+			}
+
+			state.TracksDone++
+			interactor.setLoudnessProgress(state, progress)
+		}
+
+		albumLoudness, _ := IntegratedLoudness(albumBlocks)
+		now := time.Now().Unix()
+		for i := range albumTracks {
+			albumTracks[i].LoudnessAlbumLufs = albumLoudness
+			albumTracks[i].LoudnessAlbumPeak = albumPeak
+			albumTracks[i].LoudnessAnalyzedAt = now
+			if errSave := interactor.TrackRepository.SaveLoudness(&albumTracks[i]); errSave != nil {
+				// The next tracks can still be saved.
+				log.Println("ERROR - Can't save the loudness of track " + albumTracks[i].Path + ": " + errSave.Error())
+			}
+		}
+
+		state.AlbumsDone++
+		interactor.setLoudnessProgress(state, progress)
+	}
+
+	state.Running = false
+	interactor.setLoudnessProgress(state, progress)
+
+	return nil
+}
+
+/*
+Starts the loudness analysis of the library in the background.
+
+Returns an error if an analysis is already running.
+*/
+func (interactor *LibraryInteractor) StartLoudnessAnalysis(force bool) error {
+	interactor.loudnessProgressMutex.Lock()
+	if interactor.loudnessProgress.Running {
+		interactor.loudnessProgressMutex.Unlock()
+		return errors.New("loudness analysis already running")
+	}
+	interactor.loudnessProgress = LoudnessAnalysisProgress{Running: true}
+	interactor.loudnessProgressMutex.Unlock()
+
+	go func() {
+		_ = interactor.AnalyzeLoudness(force, nil)
+	}()
+
+	return nil
+}
+
+// Gets the progress of the current or last loudness analysis.
+func (interactor *LibraryInteractor) GetLoudnessAnalysisProgress() LoudnessAnalysisProgress {
+	interactor.loudnessProgressMutex.Lock()
+	defer interactor.loudnessProgressMutex.Unlock()
+
+	return interactor.loudnessProgress
+}
+
+func (interactor *LibraryInteractor) setLoudnessProgress(state LoudnessAnalysisProgress, progress func(LoudnessAnalysisProgress)) {
+	interactor.loudnessProgressMutex.Lock()
+	interactor.loudnessProgress = state
+	interactor.loudnessProgressMutex.Unlock()
+
+	if progress != nil {
+		progress(state)
+	}
+}
+
+// Decodes the audio of a track and measures it.
+func (interactor *LibraryInteractor) measureLoudness(track *domain.Track) (meter *LoudnessMeter, err error) {
+	err = interactor.MediaFileRepository.DecodeAudio(track.Path, track.StartMs, track.EndMs, func(sampleRate int, samples [][]float64) error {
+		if meter == nil {
+			meter = NewLoudnessMeter(sampleRate, len(samples))
+		}
+		meter.Write(samples)
+		return nil
+	})
+	if err == nil && meter == nil {
+		meter = NewLoudnessMeter(44100, 2)
+	}
+
+	return
+}
+
+// Checks if all the tracks of an album have been analyzed.
+func loudnessAnalyzed(tracks domain.Tracks) bool {
+	for _, track := range tracks {
+		if track.LoudnessAnalyzedAt == 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,133 @@
+package business
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type LoudnessTestSuite struct {
+	suite.Suite
+	Library *LibraryInteractor
+}
+
+// Go testing framework entry point.
+func TestLoudnessTestSuite(t *testing.T) {
+	suite.Run(t, new(LoudnessTestSuite))
+}
+
+func (suite *LoudnessTestSuite) SetupTest() {
+	suite.Library = createMockLibraryInteractor()
+}
+
+// Writes a 1 kHz sine to a meter, in the given channels, in chunks of 0.1 second.
+func writeSine(meter *LoudnessMeter, sampleRate int, channels int, amplitude float64, seconds int) {
+	for chunk := 0; chunk < seconds*10; chunk++ {
+		samples := make([][]float64, channels)
+		for channel := range samples {
+			samples[channel] = make([]float64, sampleRate/10)
+			for i := range samples[channel] {
+				samples[channel][i] = amplitude * math.Sin(2*math.Pi*1000*float64(chunk*sampleRate/10+i)/float64(sampleRate))
+			}
+		}
+		meter.Write(samples)
+	}
+}
+
+func (suite *LoudnessTestSuite) TestLoudnessMeter() {
+	// A full scale 1 kHz sine in one channel is at -3.01 LUFS (EBU Tech 3341).
+	meter := NewLoudnessMeter(48000, 1)
+	writeSine(meter, 48000, 1, 1, 5)
+	loudness, ok := meter.Loudness()
+	assert.True(suite.T(), ok)
+	assert.InDelta(suite.T(), -3.01, loudness, 0.05)
+	assert.InDelta(suite.T(), 1.0, meter.TruePeak, 0.01)
+	// 400ms blocks every 100ms.
+	assert.Len(suite.T(), meter.Blocks, 47)
+
+	// Both stereo channels count, at any sample rate.
+	meter = NewLoudnessMeter(44100, 2)
+	writeSine(meter, 44100, 2, 0.5, 5)
+	loudness, _ = meter.Loudness()
+	assert.InDelta(suite.T(), -6.02, loudness, 0.05)
+	assert.InDelta(suite.T(), 0.5, meter.TruePeak, 0.01)
+
+	// Too short or silent audio can't be measured.
+	meter = NewLoudnessMeter(48000, 2)
+	writeSine(meter, 48000, 2, 1, 0)
+	_, ok = meter.Loudness()
+	assert.False(suite.T(), ok)
+	meter.Write([][]float64{make([]float64, 48000), make([]float64, 48000)})
+	_, ok = meter.Loudness()
+	assert.False(suite.T(), ok)
+}
+
+func (suite *LoudnessTestSuite) TestIntegratedLoudness() {
+	// Blocks at -20.691 LUFS and -40.691 LUFS: the quietest ones are under the relative gate.
+	loud, quiet := math.Pow(10, -2), math.Pow(10, -4)
+	loudness, ok := IntegratedLoudness([]float64{loud, loud, loud, quiet})
+	assert.True(suite.T(), ok)
+	assert.InDelta(suite.T(), -20.691, loudness, 0.001)
+
+	// Blocks under -70 LUFS are ignored.
+	loudness, ok = IntegratedLoudness([]float64{loud, 1e-9, 0})
+	assert.True(suite.T(), ok)
+	assert.InDelta(suite.T(), -20.691, loudness, 0.001)
+	_, ok = IntegratedLoudness([]float64{1e-9})
+	assert.False(suite.T(), ok)
+	_, ok = IntegratedLoudness(nil)
+	assert.False(suite.T(), ok)
+}
+
+func (suite *LoudnessTestSuite) TestAnalyzeLoudness() {
+	var states []LoudnessAnalysisProgress
+	err := suite.Library.AnalyzeLoudness(false, func(state LoudnessAnalysisProgress) {
+		states = append(states, state)
+	})
+	assert.Nil(suite.T(), err)
+
+	// Progress is reported at the start, after each track and after each album.
+	assert.Len(suite.T(), states, 8)
+	assert.Equal(suite.T(), LoudnessAnalysisProgress{Running: true, TracksTotal: 3, AlbumsTotal: 3}, states[0])
+	last := LoudnessAnalysisProgress{TracksDone: 3, TracksTotal: 3, AlbumsDone: 3, AlbumsTotal: 3, Failed: 1}
+	assert.Equal(suite.T(), last, states[7])
+	assert.Equal(suite.T(), last, suite.Library.GetLoudnessAnalysisProgress())
+
+	// Each track is its own album in the mock.
+	// Only the loudness is saved.
+	assert.Empty(suite.T(), suite.Library.TrackRepository.(*TrackRepositoryMock).Saved)
+	saved := suite.Library.TrackRepository.(*TrackRepositoryMock).LoudnessSaved
+	assert.Len(suite.T(), saved, 3)
+	assert.InDelta(suite.T(), 0.0, saved[0].LoudnessTrackLufs, 0.05)
+	assert.InDelta(suite.T(), 1.0, saved[0].LoudnessTrackPeak, 0.01)
+	assert.Equal(suite.T(), saved[0].LoudnessTrackLufs, saved[0].LoudnessAlbumLufs)
+	assert.InDelta(suite.T(), -6.02, saved[1].LoudnessTrackLufs, 0.05)
+	assert.NotZero(suite.T(), saved[1].LoudnessAnalyzedAt)
+	// Tracks which can't be decoded are marked as analyzed, without values.
+	assert.Zero(suite.T(), saved[2].LoudnessTrackLufs)
+	assert.Zero(suite.T(), saved[2].LoudnessAlbumLufs)
+	assert.NotZero(suite.T(), saved[2].LoudnessAnalyzedAt)
+}
+
+func (suite *LoudnessTestSuite) TestLoudnessAnalyzed() {
+	assert.True(suite.T(), loudnessAnalyzed(domain.Tracks{{LoudnessAnalyzedAt: 1}, {LoudnessAnalyzedAt: 2}}))
+	assert.False(suite.T(), loudnessAnalyzed(domain.Tracks{{LoudnessAnalyzedAt: 1}, {}}))
+}
+
+func (suite *LoudnessTestSuite) TestStartLoudnessAnalysis() {
+	err := suite.Library.StartLoudnessAnalysis(false)
+	assert.Nil(suite.T(), err)
+	assert.Eventually(suite.T(), func() bool {
+		return !suite.Library.GetLoudnessAnalysisProgress().Running
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(suite.T(), 3, suite.Library.GetLoudnessAnalysisProgress().TracksDone)
+
+	// Only one analysis at a time.
+	suite.Library.loudnessProgress.Running = true
+	err = suite.Library.StartLoudnessAnalysis(true)
+	assert.NotNil(suite.T(), err)
+}
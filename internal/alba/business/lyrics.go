@@ -0,0 +1,108 @@
+package business
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+/*
+This file exposes the handling of the tracks lyrics.
+
+Time-synced lyrics are stored in the LRC format: each line starts with one or several timestamps like "[01:23.45]",
+and an "[offset:+/-<milliseconds>]" tag can shift all of them.
+*/
+
+// Matches the timestamps at the beginning of a LRC line: "[mm:ss]", "[mm:ss.xx]", "[mm:ss.xxx]" or "[mm:ss:xx]".
+var lrcTimestampRegexp = regexp.MustCompile(`^\[(\d{1,3}):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+
+// Matches the LRC offset tag.
+var lrcOffsetRegexp = regexp.MustCompile(`(?mi)^\s*\[offset:\s*([+-]?\d+)\s*\]`)
+
+// Checks if lyrics are in the LRC format, ie if at least one of their lines starts with a timestamp.
+func IsLrc(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if lrcTimestampRegexp.MatchString(strings.TrimSpace(line)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Parses LRC lyrics.
+//
+// Returns the lines ordered by time. Lines without timestamps and the LRC metadata tags are ignored.
+func ParseLrc(content string) (lines []domain.LyricsLine) {
+	offset := 0
+	if matches := lrcOffsetRegexp.FindStringSubmatch(content); matches != nil {
+		offset, _ = strconv.Atoi(matches[1])
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+
+		// A line can start with several timestamps when it is repeated.
+		var times []int
+		for {
+			matches := lrcTimestampRegexp.FindStringSubmatch(line)
+			if matches == nil {
+				break
+			}
+			line = line[len(matches[0]):]
+
+			minutes, _ := strconv.Atoi(matches[1])
+			seconds, _ := strconv.Atoi(matches[2])
+			// The fraction can be given in tenths, hundredths or thousandths of a second.
+			fraction := 0
+			if matches[3] != "" {
+				fraction, _ = strconv.Atoi((matches[3] + "00")[0:3])
+			}
+			// A positive offset makes the lyrics appear sooner.
+			time := (minutes*60+seconds)*1000 + fraction - offset
+			if time < 0 {
+				time = 0
+			}
+			times = append(times, time)
+		}
+
+		for _, time := range times {
+			lines = append(lines, domain.LyricsLine{TimeMs: time, Line: strings.TrimSpace(line)})
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].TimeMs < lines[j].TimeMs })
+
+	return
+}
+
+// Formats time-synced lyrics in the LRC format.
+func FormatLrc(lines []domain.LyricsLine) string {
+	var lrc []string
+	for _, line := range lines {
+		lrc = append(lrc, fmt.Sprintf("[%02d:%02d.%02d]%s", line.TimeMs/60000, line.TimeMs/1000%60, line.TimeMs%1000/10, line.Line))
+	}
+
+	return strings.Join(lrc, "\n")
+}
+
+// Gets the text of time-synced lyrics.
+func LyricsText(lines []domain.LyricsLine) string {
+	var text []string
+	for _, line := range lines {
+		text = append(text, line.Line)
+	}
+
+	return strings.TrimSpace(strings.Join(text, "\n"))
+}
+
+// Gets the lyrics of a track.
+//
+// If the track has no lyrics, returns an error.
+func (interactor *LibraryInteractor) GetTrackLyrics(trackId int) (domain.Lyrics, error) {
+	return interactor.LyricsRepository.GetForTrack(trackId)
+}
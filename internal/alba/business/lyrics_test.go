@@ -0,0 +1,89 @@
+package business
+
+import (
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type LyricsTestSuite struct {
+	suite.Suite
+	Library *LibraryInteractor
+}
+
+// Go testing framework entry point.
+func TestLyricsTestSuite(t *testing.T) {
+	suite.Run(t, new(LyricsTestSuite))
+}
+
+func (suite *LyricsTestSuite) SetupSuite() {
+	suite.Library = createMockLibraryInteractor()
+}
+
+func (suite *LyricsTestSuite) TestIsLrc() {
+	assert.True(suite.T(), IsLrc("[ti:Title]\n[00:12.00]First line"))
+	assert.True(suite.T(), IsLrc("  [1:02]First line"))
+	assert.False(suite.T(), IsLrc("First line\nSecond line"))
+	assert.False(suite.T(), IsLrc("[ar:Artist]\n[Chorus]"))
+	assert.False(suite.T(), IsLrc(""))
+}
+
+func (suite *LyricsTestSuite) TestParseLrc() {
+	lines := ParseLrc("[ar:Artist]\n[ti:Title]\n[00:12.00]First line\r\n[00:17.5]Second line\n\nNot synced\n[01:02.345]Third line")
+	assert.Equal(suite.T(), []domain.LyricsLine{
+		{TimeMs: 12000, Line: "First line"},
+		{TimeMs: 17500, Line: "Second line"},
+		{TimeMs: 62345, Line: "Third line"},
+	}, lines)
+
+	// Repeated lines and empty lines.
+	lines = ParseLrc("[00:10.00][00:30.00]Chorus\n[00:20:00]Verse\n[00:25.00]")
+	assert.Equal(suite.T(), []domain.LyricsLine{
+		{TimeMs: 10000, Line: "Chorus"},
+		{TimeMs: 20000, Line: "Verse"},
+		{TimeMs: 25000, Line: ""},
+		{TimeMs: 30000, Line: "Chorus"},
+	}, lines)
+
+	// Offset.
+	lines = ParseLrc("[offset:+500]\n[00:00.20]First line\n[00:01.00]Second line")
+	assert.Equal(suite.T(), []domain.LyricsLine{
+		{TimeMs: 0, Line: "First line"},
+		{TimeMs: 500, Line: "Second line"},
+	}, lines)
+	lines = ParseLrc("[offset:-250]\n[00:01.00]First line")
+	assert.Equal(suite.T(), 1250, lines[0].TimeMs)
+
+	assert.Empty(suite.T(), ParseLrc("First line\nSecond line"))
+}
+
+func (suite *LyricsTestSuite) TestFormatLrc() {
+	lines := []domain.LyricsLine{
+		{TimeMs: 12000, Line: "First line"},
+		{TimeMs: 62345, Line: "Second line"},
+	}
+	assert.Equal(suite.T(), "[00:12.00]First line\n[01:02.34]Second line", FormatLrc(lines))
+	assert.Equal(suite.T(), []domain.LyricsLine{{TimeMs: 12000, Line: "First line"}, {TimeMs: 62340, Line: "Second line"}}, ParseLrc(FormatLrc(lines)))
+	assert.Equal(suite.T(), "", FormatLrc(nil))
+}
+
+func (suite *LyricsTestSuite) TestLyricsText() {
+	lines := []domain.LyricsLine{
+		{TimeMs: 0, Line: ""},
+		{TimeMs: 12000, Line: "First line"},
+		{TimeMs: 62345, Line: "Second line"},
+	}
+	assert.Equal(suite.T(), "First line\nSecond line", LyricsText(lines))
+}
+
+func (suite *LyricsTestSuite) TestGetTrackLyrics() {
+	lyrics, err := suite.Library.GetTrackLyrics(1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, lyrics.TrackId)
+	assert.Equal(suite.T(), "First line\nSecond line", lyrics.Text)
+
+	_, err = suite.Library.GetTrackLyrics(99)
+	assert.NotNil(suite.T(), err)
+}
@@ -0,0 +1,20 @@
+package business
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+/*
+Computes the key used to match artists and albums names.
+
+Names are matched regardless of their case, their Unicode normalisation form and their whitespaces: "AC/DC" and
+"Ac/Dc", or "Björk" written with a combining diaeresis and "Björk", have the same key. Accents are kept, so "Bjork"
+has a different key.
+*/
+func MatchKey(name string) string {
+	key := cases.Fold().String(norm.NFC.String(name))
+	return norm.NFC.String(strings.Join(strings.Fields(key), " "))
+}
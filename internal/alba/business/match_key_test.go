@@ -0,0 +1,30 @@
+package business
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MatchKeyTestSuite struct {
+	suite.Suite
+}
+
+// Go testing framework entry point.
+func TestMatchKeyTestSuite(t *testing.T) {
+	suite.Run(t, new(MatchKeyTestSuite))
+}
+
+func (suite *MatchKeyTestSuite) TestMatchKey() {
+	assert.Equal(suite.T(), MatchKey("AC/DC"), MatchKey("Ac/Dc"))
+	assert.Equal(suite.T(), MatchKey("Guns N' Roses"), MatchKey("  guns  n'   roses "))
+	// NFC and NFD forms.
+	assert.Equal(suite.T(), MatchKey("Björk"), MatchKey("Björk"))
+	assert.Equal(suite.T(), "björk", MatchKey("BJÖRK"))
+	// Case folding goes further than lower casing.
+	assert.Equal(suite.T(), MatchKey("Die Ärzte Straße"), MatchKey("DIE ÄRZTE STRASSE"))
+
+	// Accents are kept.
+	assert.NotEqual(suite.T(), MatchKey("Björk"), MatchKey("Bjork"))
+}
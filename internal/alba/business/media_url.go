@@ -0,0 +1,39 @@
+package business
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/spf13/viper"
+)
+
+/*
+This file exposes the secret key signing the media URLs.
+
+The key is read from MediaUrls.Secret, or else generated once and kept in the internal variables, so the URLs given to
+the clients stay valid after a restart.
+*/
+
+// Internal variable holding the generated secret key, which must never be given to the clients.
+const MediaUrlSecretVariable = "media_url_secret"
+
+// Gets the secret key signing the media URLs, generating it if needed.
+func (interactor *LibraryInteractor) GetMediaUrlSecret() (string, error) {
+	if secret := viper.GetString("MediaUrls.Secret"); secret != "" {
+		return secret, nil
+	}
+	if variable, err := interactor.InternalVariableRepository.Get(MediaUrlSecretVariable); err == nil && variable.Value != "" {
+		return variable.Value, nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	variable := InternalVariable{Key: MediaUrlSecretVariable, Value: hex.EncodeToString(key)}
+	if err := interactor.InternalVariableRepository.Save(&variable); err != nil {
+		return "", err
+	}
+
+	return variable.Value, nil
+}
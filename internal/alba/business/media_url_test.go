@@ -0,0 +1,46 @@
+package business
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MediaUrlTestSuite struct {
+	suite.Suite
+	Library *LibraryInteractor
+}
+
+// Go testing framework entry point.
+func TestMediaUrlTestSuite(t *testing.T) {
+	suite.Run(t, new(MediaUrlTestSuite))
+}
+
+func (suite *MediaUrlTestSuite) SetupTest() {
+	suite.Library = createMockLibraryInteractor()
+}
+
+func (suite *MediaUrlTestSuite) TearDownTest() {
+	viper.Set("MediaUrls.Secret", "")
+}
+
+func (suite *MediaUrlTestSuite) TestGetMediaUrlSecret() {
+	// A secret is generated and kept.
+	secret, err := suite.Library.GetMediaUrlSecret()
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), secret, 64)
+	again, err := suite.Library.GetMediaUrlSecret()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), secret, again)
+	variable, err := suite.Library.InternalVariableRepository.Get(MediaUrlSecretVariable)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), secret, variable.Value)
+
+	// The configured secret comes first.
+	viper.Set("MediaUrls.Secret", "configured")
+	secret, err = suite.Library.GetMediaUrlSecret()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "configured", secret)
+}
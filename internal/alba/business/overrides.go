@@ -0,0 +1,377 @@
+package business
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+/*
+This file exposes the manual edits of the library metadata.
+
+Edits are stored as overrides, one per entity field, and applied over the metadata read from the media files each time
+the library is updated. Fields are named after the GraphQL API fields. If the tags writing is enabled, track and album
+edits are also written into the media files, see tags.go; overrides are then only kept if the files cannot be written.
+ */
+
+// Fields of each entity type that can be edited manually.
+var OverridableFields = map[string][]string{
+	domain.OverrideEntityArtist: {"name", "sortName"},
+	domain.OverrideEntityAlbum:  {"title", "sortName", "year"},
+	domain.OverrideEntityTrack: {
+		"title", "number", "disc", "composer", "conductor", "label", "catalogNumber", "isrc", "originalYear", "bpm",
+	},
+}
+
+// Gets the value of an artist field, formatted as a string.
+func GetArtistField(artist *domain.Artist, field string) (string, error) {
+	switch field {
+	case "name":
+		return artist.Name, nil
+	case "sortName":
+		return artist.SortName, nil
+	}
+
+	return "", errors.New("field " + field + " cannot be edited")
+}
+
+// Sets the value of an artist field.
+func SetArtistField(artist *domain.Artist, field string, value string) error {
+	switch field {
+	case "name":
+		if value == "" {
+			return errors.New("empty name")
+		}
+		artist.Name = value
+	case "sortName":
+		artist.SortName = value
+	default:
+		return errors.New("field " + field + " cannot be edited")
+	}
+
+	return nil
+}
+
+// Gets the value of an album field, formatted as a string.
+func GetAlbumField(album *domain.Album, field string) (string, error) {
+	switch field {
+	case "title":
+		return album.Title, nil
+	case "sortName":
+		return album.SortName, nil
+	case "year":
+		return album.Year, nil
+	}
+
+	return "", errors.New("field " + field + " cannot be edited")
+}
+
+// Sets the value of an album field.
+func SetAlbumField(album *domain.Album, field string, value string) error {
+	switch field {
+	case "title":
+		if value == "" {
+			return errors.New("empty title")
+		}
+		album.Title = value
+	case "sortName":
+		album.SortName = value
+	case "year":
+		album.Year = value
+	default:
+		return errors.New("field " + field + " cannot be edited")
+	}
+
+	return nil
+}
+
+// Gets the value of a track field, formatted as a string.
+func GetTrackField(track *domain.Track, field string) (string, error) {
+	switch field {
+	case "title":
+		return track.Title, nil
+	case "number":
+		return strconv.Itoa(track.Number), nil
+	case "disc":
+		return track.Disc, nil
+	case "composer":
+		return track.Composer, nil
+	case "conductor":
+		return track.Conductor, nil
+	case "label":
+		return track.Label, nil
+	case "catalogNumber":
+		return track.CatalogNumber, nil
+	case "isrc":
+		return track.Isrc, nil
+	case "originalYear":
+		return track.OriginalYear, nil
+	case "bpm":
+		return strconv.Itoa(track.Bpm), nil
+	}
+
+	return "", errors.New("field " + field + " cannot be edited")
+}
+
+// Sets the value of a track field.
+//
+// Returns an error if the value of a numeric field is not a number.
+func SetTrackField(track *domain.Track, field string, value string) (err error) {
+	switch field {
+	case "title":
+		if value == "" {
+			return errors.New("empty title")
+		}
+		track.Title = value
+	case "number":
+		track.Number, err = atoiOrZero(value)
+	case "disc":
+		track.Disc = value
+	case "composer":
+		track.Composer = value
+	case "conductor":
+		track.Conductor = value
+	case "label":
+		track.Label = value
+	case "catalogNumber":
+		track.CatalogNumber = value
+	case "isrc":
+		track.Isrc = value
+	case "originalYear":
+		track.OriginalYear = value
+	case "bpm":
+		track.Bpm, err = atoiOrZero(value)
+	default:
+		return errors.New("field " + field + " cannot be edited")
+	}
+
+	return
+}
+
+// Edits an artist and stores the edits so they survive the library updates.
+//
+// Returns an error if the artist doesn't exist or one of the fields cannot be edited.
+func (interactor *LibraryInteractor) UpdateArtist(artistId int, values map[string]string) (domain.Artist, error) {
+	artist, err := interactor.ArtistRepository.Get(artistId)
+	if err != nil {
+		return artist, errors.New("cannot update artist: invalid artist ID")
+	}
+
+	err = interactor.saveOverrides(
+		domain.OverrideEntityArtist,
+		artistId,
+		values,
+		func(field string) (string, error) { return GetArtistField(&artist, field) },
+		func(field string, value string) error { return SetArtistField(&artist, field, value) },
+	)
+	if err != nil {
+		return artist, err
+	}
+
+	return artist, interactor.SaveArtist(&artist)
+}
+
+// Edits an album and stores the edits so they survive the library updates.
+//
+// Returns an error if the album doesn't exist or one of the fields cannot be edited.
+func (interactor *LibraryInteractor) UpdateAlbum(albumId int, values map[string]string) (domain.Album, error) {
+	album, err := interactor.AlbumRepository.Get(albumId)
+	if err != nil {
+		return album, errors.New("cannot update album: invalid album ID")
+	}
+
+	err = interactor.saveOverrides(
+		domain.OverrideEntityAlbum,
+		albumId,
+		values,
+		func(field string) (string, error) { return GetAlbumField(&album, field) },
+		func(field string, value string) error { return SetAlbumField(&album, field, value) },
+	)
+	if err != nil {
+		return album, err
+	}
+
+	if err = interactor.SaveAlbum(&album); err != nil || !interactor.TagWritingEnabled() || hasCueTracks(album.Tracks) {
+		return album, err
+	}
+
+	return album, interactor.writeAlbumTags(&album, values)
+}
+
+// Edits a track and stores the edits so they survive the library updates.
+//
+// Returns an error if the track doesn't exist or one of the fields cannot be edited.
+func (interactor *LibraryInteractor) UpdateTrack(trackId int, values map[string]string) (domain.Track, error) {
+	track, err := interactor.TrackRepository.Get(trackId)
+	if err != nil {
+		return track, errors.New("cannot update track: invalid track ID")
+	}
+
+	err = interactor.saveOverrides(
+		domain.OverrideEntityTrack,
+		trackId,
+		values,
+		func(field string) (string, error) { return GetTrackField(&track, field) },
+		func(field string, value string) error { return SetTrackField(&track, field, value) },
+	)
+	if err != nil {
+		return track, err
+	}
+
+	if err = interactor.SaveTrack(&track); err != nil || !interactor.TagWritingEnabled() || track.CueTrack != 0 {
+		return track, err
+	}
+
+	return track, interactor.writeTrackTags(&track, values)
+}
+
+// Gets the overrides of an entity, or all the overrides of an entity type if entityId is 0, or all the overrides if
+// entityType is empty.
+func (interactor *LibraryInteractor) GetOverrides(entityType string, entityId int) (domain.Overrides, error) {
+	if entityType != "" {
+		if _, ok := OverridableFields[entityType]; !ok {
+			return domain.Overrides{}, errors.New("cannot get overrides: invalid entity type")
+		}
+	}
+
+	if entityType != "" && entityId != 0 {
+		return interactor.OverrideRepository.GetForEntity(entityType, entityId)
+	}
+
+	return interactor.OverrideRepository.GetAll(entityType)
+}
+
+// Reverts a manual edit: restores the value read from the media files and deletes the override.
+//
+// Returns the deleted override.
+func (interactor *LibraryInteractor) RevertOverride(overrideId int) (domain.Override, error) {
+	override, err := interactor.OverrideRepository.Get(overrideId)
+	if err != nil {
+		return override, errors.New("cannot revert override: invalid override ID")
+	}
+
+	// The entity may have been deleted since it has been edited, in which case there is nothing to restore.
+	switch override.EntityType {
+	case domain.OverrideEntityArtist:
+		if artist, errGet := interactor.ArtistRepository.Get(override.EntityId); errGet == nil {
+			if SetArtistField(&artist, override.Field, override.OriginalValue) == nil {
+				err = interactor.ArtistRepository.Save(&artist)
+			}
+		}
+	case domain.OverrideEntityAlbum:
+		if album, errGet := interactor.AlbumRepository.Get(override.EntityId); errGet == nil {
+			if SetAlbumField(&album, override.Field, override.OriginalValue) == nil {
+				err = interactor.AlbumRepository.Save(&album)
+			}
+		}
+	case domain.OverrideEntityTrack:
+		if track, errGet := interactor.TrackRepository.Get(override.EntityId); errGet == nil {
+			if SetTrackField(&track, override.Field, override.OriginalValue) == nil {
+				err = interactor.TrackRepository.Save(&track)
+			}
+		}
+	}
+	if err != nil {
+		return override, err
+	}
+
+	return override, interactor.OverrideRepository.Delete(&override)
+}
+
+/*
+Applies overrides over the metadata read from the media files.
+
+The original value of each override is refreshed with the current value of the field, so reverting an override restores
+the value of the latest library update. Overrides that cannot be applied are ignored.
+
+Returns the overrides whose original value has changed.
+*/
+func ApplyOverrides(overrides domain.Overrides, get func(field string) (string, error), set func(field string, value string) error) (changed domain.Overrides) {
+	for _, override := range overrides {
+		original, err := get(override.Field)
+		if err != nil {
+			continue
+		}
+		if set(override.Field, override.Value) != nil {
+			continue
+		}
+		// A field already holding the edited value has not been read from the media files, like the name of an
+		// existing artist.
+		if original != override.OriginalValue && original != override.Value {
+			override.OriginalValue = original
+			changed = append(changed, override)
+		}
+	}
+
+	return
+}
+
+// Sets the values of an entity and saves them as overrides.
+//
+// All the values are set before saving anything, so an invalid value doesn't leave the entity half edited.
+func (interactor *LibraryInteractor) saveOverrides(
+	entityType string,
+	entityId int,
+	values map[string]string,
+	get func(field string) (string, error),
+	set func(field string, value string) error,
+) error {
+	if len(values) == 0 {
+		return errors.New("cannot update " + entityType + ": nothing to update")
+	}
+
+	existing, err := interactor.OverrideRepository.GetForEntity(entityType, entityId)
+	if err != nil {
+		return err
+	}
+
+	var overrides domain.Overrides
+	for field, value := range values {
+		override := domain.Override{EntityType: entityType, EntityId: entityId, Field: field}
+		for _, previous := range existing {
+			if previous.Field == field {
+				override = previous
+			}
+		}
+
+		// Keep the value read from the media files if the field has already been edited.
+		original, err := get(field)
+		if err != nil {
+			return errors.New("cannot update " + entityType + ": " + err.Error())
+		}
+		if override.Id == 0 {
+			override.OriginalValue = original
+			override.DateAdded = time.Now().Unix()
+		}
+
+		if err = set(field, value); err != nil {
+			return errors.New("cannot update " + entityType + ": " + err.Error())
+		}
+		override.Value = value
+		overrides = append(overrides, override)
+	}
+
+	for i := range overrides {
+		if err := interactor.OverrideRepository.Save(&overrides[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Converts a numeric field value, an empty value meaning 0.
+func atoiOrZero(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New("value " + value + " is not a number")
+	}
+
+	return number, nil
+}
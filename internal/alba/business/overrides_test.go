@@ -0,0 +1,127 @@
+package business
+
+import (
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type OverridesTestSuite struct {
+	suite.Suite
+	Library *LibraryInteractor
+}
+
+// Go testing framework entry point.
+func TestOverridesTestSuite(t *testing.T) {
+	suite.Run(t, new(OverridesTestSuite))
+}
+
+func (suite *OverridesTestSuite) SetupSuite() {
+	suite.Library = createMockLibraryInteractor()
+}
+
+func (suite *OverridesTestSuite) TestTrackFields() {
+	track := domain.Track{Title: "Title", Number: 3}
+
+	value, err := GetTrackField(&track, "number")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "3", value)
+
+	assert.Nil(suite.T(), SetTrackField(&track, "bpm", "128"))
+	assert.Equal(suite.T(), 128, track.Bpm)
+	assert.Nil(suite.T(), SetTrackField(&track, "bpm", ""))
+	assert.Equal(suite.T(), 0, track.Bpm)
+	assert.Nil(suite.T(), SetTrackField(&track, "catalogNumber", "CAT-001"))
+	assert.Equal(suite.T(), "CAT-001", track.CatalogNumber)
+
+	assert.NotNil(suite.T(), SetTrackField(&track, "number", "three"))
+	assert.NotNil(suite.T(), SetTrackField(&track, "title", ""))
+	assert.NotNil(suite.T(), SetTrackField(&track, "path", "/tmp"))
+	_, err = GetTrackField(&track, "path")
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *OverridesTestSuite) TestUpdateArtist() {
+	artist, err := suite.Library.UpdateArtist(2, map[string]string{"name": "Edited artist", "sortName": "Artist, Edited"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Edited artist", artist.Name)
+	assert.Equal(suite.T(), "Artist, Edited", artist.SortName)
+
+	// Invalid values.
+	_, err = suite.Library.UpdateArtist(2, map[string]string{"name": ""})
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.UpdateArtist(2, map[string]string{"unknown": "value"})
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.UpdateArtist(2, map[string]string{})
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.UpdateArtist(99, map[string]string{"name": "Edited artist"})
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *OverridesTestSuite) TestUpdateAlbum() {
+	album, err := suite.Library.UpdateAlbum(1, map[string]string{"year": "1999"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "1999", album.Year)
+
+	_, err = suite.Library.UpdateAlbum(99, map[string]string{"year": "1999"})
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *OverridesTestSuite) TestUpdateTrack() {
+	track, err := suite.Library.UpdateTrack(1, map[string]string{"title": "Edited track", "number": "7"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Edited track", track.Title)
+	assert.Equal(suite.T(), 7, track.Number)
+
+	_, err = suite.Library.UpdateTrack(1, map[string]string{"number": "seven"})
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *OverridesTestSuite) TestGetOverrides() {
+	overrides, err := suite.Library.GetOverrides(domain.OverrideEntityArtist, 1)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), overrides, 1)
+
+	overrides, err = suite.Library.GetOverrides(domain.OverrideEntityTrack, 0)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), overrides)
+
+	overrides, err = suite.Library.GetOverrides("", 0)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), overrides, 1)
+
+	_, err = suite.Library.GetOverrides("playlist", 0)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *OverridesTestSuite) TestRevertOverride() {
+	override, err := suite.Library.RevertOverride(1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Artist #1", override.OriginalValue)
+
+	_, err = suite.Library.RevertOverride(99)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *OverridesTestSuite) TestApplyOverrides() {
+	track := domain.Track{Title: "New title from file", Number: 2}
+	overrides := domain.Overrides{
+		{Field: "title", Value: "Edited title", OriginalValue: "Old title from file"},
+		{Field: "number", Value: "5", OriginalValue: "2"},
+		{Field: "path", Value: "/tmp"},
+	}
+
+	changed := ApplyOverrides(
+		overrides,
+		func(field string) (string, error) { return GetTrackField(&track, field) },
+		func(field string, value string) error { return SetTrackField(&track, field, value) },
+	)
+	assert.Equal(suite.T(), "Edited title", track.Title)
+	assert.Equal(suite.T(), 5, track.Number)
+
+	// The original value follows the media files.
+	assert.Len(suite.T(), changed, 1)
+	assert.Equal(suite.T(), "New title from file", changed[0].OriginalValue)
+}
@@ -0,0 +1,521 @@
+package business
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+/*
+This file exposes the parties: rooms where several people share a queue of tracks.
+
+Participants add tracks to the queue and vote them up or down. The queue is sorted by score, and a track gets skipped
+or removed once enough participants vote it down, see Party.SkipRatio. A party can have a host, the only participant
+allowed to control the playback; otherwise every participant can. Parties are kept in memory: they are closed when
+their host or their last participant leaves, or once idle for Party.IdleTimeout hours, see CloseIdleParties().
+*/
+
+// Playback actions of a party.
+const (
+	PartyActionPlay  = "play"
+	PartyActionPause = "pause"
+	PartyActionNext  = "next"
+)
+
+var ErrPartyNotFound = errors.New("party not found")
+var ErrPartyNotParticipant = errors.New("not a participant of the party")
+var ErrPartyNotHost = errors.New("only the host can control the playback")
+
+// Alphabet of the party codes, without the characters easily mistaken for others.
+const partyCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+const partyCodeLength = 6
+
+type Party struct {
+	Id           string // Code given to the participants to join the party.
+	Name         string
+	HostId       string // Participant controlling the playback, empty if every participant can.
+	Participants []PartyParticipant
+	Current      *PartyTrack  // Track being played, nil if none.
+	Queue        []PartyTrack // Tracks to play, the best voted first.
+	Playing      bool
+	Closed       bool // Only set on the last state sent to the listeners of a closed party.
+	DateModified int64
+	lastTrackId  int
+}
+
+type PartyParticipant struct {
+	Id    string
+	Name  string
+	Token string // Secret identifying the participant in the requests, never given to the other participants.
+}
+
+// Track added to the queue of a party.
+type PartyTrack struct {
+	Id        int // Unique in the party, as a track can be added several times.
+	TrackId   int
+	AddedBy   string // Id of the participant who added the track.
+	DateAdded int64
+	Votes     map[string]int // 1 or -1, by participant id.
+}
+
+// Gets the sum of the votes of a track.
+func (track PartyTrack) Score() (score int) {
+	for _, vote := range track.Votes {
+		score += vote
+	}
+
+	return
+}
+
+// Gets the number of participants who voted a track down.
+func (track PartyTrack) DownVotes() (count int) {
+	for _, vote := range track.Votes {
+		if vote < 0 {
+			count++
+		}
+	}
+
+	return
+}
+
+// Gets the number of down votes skipping a track, from the number of participants.
+func (party Party) SkipVotes() int {
+	votes := int(math.Ceil(float64(len(party.Participants)) * viper.GetFloat64("Party.SkipRatio")))
+	if votes < 1 {
+		return 1
+	}
+
+	return votes
+}
+
+/*
+Creates a party, whose first participant is its creator.
+
+The creator is the host of the party if hosted is true. Returns the party and the creator.
+*/
+func (interactor *LibraryInteractor) CreateParty(name string, participantName string, hosted bool) (Party, PartyParticipant, error) {
+	if name == "" || participantName == "" {
+		return Party{}, PartyParticipant{}, errors.New("empty name")
+	}
+	participant, err := newPartyParticipant(participantName)
+	if err != nil {
+		return Party{}, PartyParticipant{}, err
+	}
+
+	interactor.partyMutex.Lock()
+	defer interactor.partyMutex.Unlock()
+	interactor.closeIdleParties()
+
+	var id string
+	for id == "" || interactor.parties[id] != nil {
+		if id, err = generatePartyCode(); err != nil {
+			return Party{}, PartyParticipant{}, err
+		}
+	}
+	party := &Party{Id: id, Name: name, Participants: []PartyParticipant{participant}}
+	if hosted {
+		party.HostId = participant.Id
+	}
+	if interactor.parties == nil {
+		interactor.parties = make(map[string]*Party)
+	}
+	interactor.parties[id] = party
+	interactor.updateParty(party)
+
+	return party.copy(), participant, nil
+}
+
+// Joins a party. Returns the party and the new participant.
+func (interactor *LibraryInteractor) JoinParty(id string, participantName string) (Party, PartyParticipant, error) {
+	if participantName == "" {
+		return Party{}, PartyParticipant{}, errors.New("empty name")
+	}
+	participant, err := newPartyParticipant(participantName)
+	if err != nil {
+		return Party{}, PartyParticipant{}, err
+	}
+
+	interactor.partyMutex.Lock()
+	defer interactor.partyMutex.Unlock()
+	party, ok := interactor.getParty(id)
+	if !ok {
+		return Party{}, PartyParticipant{}, ErrPartyNotFound
+	}
+	party.Participants = append(party.Participants, participant)
+	interactor.updateParty(party)
+
+	return party.copy(), participant, nil
+}
+
+// Leaves a party, which is closed if the participant is its host or its last participant.
+func (interactor *LibraryInteractor) LeaveParty(id string, token string) error {
+	interactor.partyMutex.Lock()
+	defer interactor.partyMutex.Unlock()
+	party, participant, err := interactor.getPartyParticipant(id, token)
+	if err != nil {
+		return err
+	}
+
+	for i := range party.Participants {
+		if party.Participants[i].Id == participant.Id {
+			party.Participants = append(party.Participants[:i], party.Participants[i+1:]...)
+			break
+		}
+	}
+	if participant.Id == party.HostId || len(party.Participants) == 0 {
+		interactor.closeParty(party)
+		return nil
+	}
+	// The votes of the participant are kept, but the number of down votes needed to skip may have decreased.
+	party.applySkipVotes()
+	interactor.updateParty(party)
+
+	return nil
+}
+
+// Gets a party.
+func (interactor *LibraryInteractor) GetParty(id string) (Party, error) {
+	interactor.partyMutex.Lock()
+	defer interactor.partyMutex.Unlock()
+	party, ok := interactor.getParty(id)
+	if !ok {
+		return Party{}, ErrPartyNotFound
+	}
+
+	return party.copy(), nil
+}
+
+// Adds a track to the queue of a party. The track is played straight away if none is.
+func (interactor *LibraryInteractor) AddPartyTrack(id string, token string, trackId int) (Party, error) {
+	if _, err := interactor.TrackRepository.Get(trackId); err != nil {
+		return Party{}, err
+	}
+
+	interactor.partyMutex.Lock()
+	defer interactor.partyMutex.Unlock()
+	party, participant, err := interactor.getPartyParticipant(id, token)
+	if err != nil {
+		return Party{}, err
+	}
+
+	party.lastTrackId++
+	track := PartyTrack{
+		Id:        party.lastTrackId,
+		TrackId:   trackId,
+		AddedBy:   participant.Id,
+		DateAdded: interactor.partyNow().Unix(),
+		Votes:     make(map[string]int),
+	}
+	if party.Current == nil {
+		party.Current = &track
+	} else {
+		party.Queue = append(party.Queue, track)
+		party.sortQueue()
+	}
+	interactor.updateParty(party)
+
+	return party.copy(), nil
+}
+
+/*
+Votes for a track of a party, the current one or a queued one.
+
+vote is 1 to vote the track up, -1 to vote it down, or 0 to withdraw the vote. The track is skipped or removed from
+the queue once enough participants voted it down.
+*/
+func (interactor *LibraryInteractor) VotePartyTrack(id string, token string, partyTrackId int, vote int) (Party, error) {
+	if vote < -1 || vote > 1 {
+		return Party{}, errors.New("invalid vote")
+	}
+
+	interactor.partyMutex.Lock()
+	defer interactor.partyMutex.Unlock()
+	party, participant, err := interactor.getPartyParticipant(id, token)
+	if err != nil {
+		return Party{}, err
+	}
+
+	track := party.getTrack(partyTrackId)
+	if track == nil {
+		return Party{}, errors.New("track not in the party")
+	}
+	if vote == 0 {
+		delete(track.Votes, participant.Id)
+	} else {
+		track.Votes[participant.Id] = vote
+	}
+	party.sortQueue()
+	party.applySkipVotes()
+	interactor.updateParty(party)
+
+	return party.copy(), nil
+}
+
+// Controls the playback of a party: play, pause, or go to the next track.
+func (interactor *LibraryInteractor) ControlParty(id string, token string, action string) (Party, error) {
+	interactor.partyMutex.Lock()
+	defer interactor.partyMutex.Unlock()
+	party, participant, err := interactor.getPartyParticipant(id, token)
+	if err != nil {
+		return Party{}, err
+	}
+	if party.HostId != "" && party.HostId != participant.Id {
+		return Party{}, ErrPartyNotHost
+	}
+
+	switch action {
+	case PartyActionPlay:
+		party.Playing = true
+	case PartyActionPause:
+		party.Playing = false
+	case PartyActionNext:
+		party.next()
+	default:
+		return Party{}, errors.New("unknown action " + action)
+	}
+	interactor.updateParty(party)
+
+	return party.copy(), nil
+}
+
+/*
+Listens to the changes of a party.
+
+Returns a channel receiving the states of the party, and a function to call to stop listening. The channel is closed
+after the closing of the party, whose last state is sent with Closed set. Slow listeners only receive the latest
+state.
+*/
+func (interactor *LibraryInteractor) ListenParty(id string) (<-chan Party, func(), error) {
+	interactor.partyMutex.Lock()
+	defer interactor.partyMutex.Unlock()
+	if _, ok := interactor.getParty(id); !ok {
+		return nil, nil, ErrPartyNotFound
+	}
+
+	listener := make(chan Party, 1)
+	if interactor.partyListeners == nil {
+		interactor.partyListeners = make(map[chan Party]string)
+	}
+	interactor.partyListeners[listener] = id
+
+	return listener, func() {
+		interactor.partyMutex.Lock()
+		defer interactor.partyMutex.Unlock()
+		if _, ok := interactor.partyListeners[listener]; ok {
+			delete(interactor.partyListeners, listener)
+			close(listener)
+		}
+	}, nil
+}
+
+// Gets a party and one of its participants from a token. Must be called with the party mutex held.
+func (interactor *LibraryInteractor) getPartyParticipant(id string, token string) (*Party, PartyParticipant, error) {
+	party, ok := interactor.getParty(id)
+	if !ok {
+		return nil, PartyParticipant{}, ErrPartyNotFound
+	}
+	for _, participant := range party.Participants {
+		if participant.Token == token {
+			return party, participant, nil
+		}
+	}
+
+	return nil, PartyParticipant{}, ErrPartyNotParticipant
+}
+
+// Sends the new state of a party to its listeners. Must be called with the party mutex held.
+func (interactor *LibraryInteractor) updateParty(party *Party) {
+	party.DateModified = interactor.partyNow().Unix()
+	state := party.copy()
+
+	for listener, id := range interactor.partyListeners {
+		if id != party.Id {
+			continue
+		}
+		select {
+		case listener <- state:
+		default:
+			// The previous state hasn't been read yet and is outdated.
+			select {
+			case <-listener:
+			default:
+			}
+			listener <- state
+		}
+	}
+}
+
+// Closes a party and its listeners. Must be called with the party mutex held.
+func (interactor *LibraryInteractor) closeParty(party *Party) {
+	delete(interactor.parties, party.Id)
+	party.Closed = true
+	interactor.updateParty(party)
+
+	for listener, id := range interactor.partyListeners {
+		if id == party.Id {
+			delete(interactor.partyListeners, listener)
+			close(listener)
+		}
+	}
+}
+
+/*
+Closes the parties which haven't changed for Party.IdleTimeout hours, so their listeners are released.
+
+Called every minute by the server. The idle parties not closed yet can't be joined or used anymore.
+*/
+func (interactor *LibraryInteractor) CloseIdleParties() {
+	interactor.partyMutex.Lock()
+	defer interactor.partyMutex.Unlock()
+	interactor.closeIdleParties()
+}
+
+// Closes the parties which haven't changed for Party.IdleTimeout hours. Must be called with the party mutex held.
+func (interactor *LibraryInteractor) closeIdleParties() {
+	for _, party := range interactor.parties {
+		if interactor.isPartyIdle(party) {
+			interactor.closeParty(party)
+		}
+	}
+}
+
+// Gets a party, closing it if it's idle. Must be called with the party mutex held.
+func (interactor *LibraryInteractor) getParty(id string) (*Party, bool) {
+	party, ok := interactor.parties[id]
+	if ok && interactor.isPartyIdle(party) {
+		interactor.closeParty(party)
+		return nil, false
+	}
+
+	return party, ok
+}
+
+// Checks whether a party hasn't changed for Party.IdleTimeout hours.
+func (interactor *LibraryInteractor) isPartyIdle(party *Party) bool {
+	limit := interactor.partyNow().Add(-time.Duration(viper.GetInt("Party.IdleTimeout")) * time.Hour).Unix()
+
+	return party.DateModified < limit
+}
+
+// Gets the current time of the parties.
+func (interactor *LibraryInteractor) partyNow() time.Time {
+	if interactor.partyClock != nil {
+		return interactor.partyClock()
+	}
+
+	return time.Now()
+}
+
+// Gets the current or a queued track.
+func (party *Party) getTrack(partyTrackId int) *PartyTrack {
+	if party.Current != nil && party.Current.Id == partyTrackId {
+		return party.Current
+	}
+	for i := range party.Queue {
+		if party.Queue[i].Id == partyTrackId {
+			return &party.Queue[i]
+		}
+	}
+
+	return nil
+}
+
+// Plays the first track of the queue, or stops if the queue is empty.
+func (party *Party) next() {
+	if len(party.Queue) == 0 {
+		party.Current = nil
+		party.Playing = false
+		return
+	}
+	track := party.Queue[0]
+	party.Current = &track
+	party.Queue = party.Queue[1:]
+}
+
+// Sorts the queue by score, then in the order of addition.
+func (party *Party) sortQueue() {
+	sort.SliceStable(party.Queue, func(i, j int) bool {
+		scoreI, scoreJ := party.Queue[i].Score(), party.Queue[j].Score()
+		if scoreI != scoreJ {
+			return scoreI > scoreJ
+		}
+		return party.Queue[i].Id < party.Queue[j].Id
+	})
+}
+
+// Removes the queued tracks voted down by enough participants, and skips the current one if it is.
+func (party *Party) applySkipVotes() {
+	skipVotes := party.SkipVotes()
+
+	queue := party.Queue[:0]
+	for _, track := range party.Queue {
+		if track.DownVotes() < skipVotes {
+			queue = append(queue, track)
+		}
+	}
+	party.Queue = queue
+
+	if party.Current != nil && party.Current.DownVotes() >= skipVotes {
+		party.next()
+	}
+}
+
+// Copies a party, so its state can be read while it changes.
+func (party *Party) copy() Party {
+	state := *party
+	state.Participants = append([]PartyParticipant{}, party.Participants...)
+	state.Queue = make([]PartyTrack, len(party.Queue))
+	for i, track := range party.Queue {
+		state.Queue[i] = track.copy()
+	}
+	if party.Current != nil {
+		current := party.Current.copy()
+		state.Current = &current
+	}
+
+	return state
+}
+
+func (track PartyTrack) copy() PartyTrack {
+	votes := make(map[string]int, len(track.Votes))
+	for participantId, vote := range track.Votes {
+		votes[participantId] = vote
+	}
+	track.Votes = votes
+
+	return track
+}
+
+func newPartyParticipant(name string) (PartyParticipant, error) {
+	id := make([]byte, 6)
+	token := make([]byte, 18)
+	if _, err := rand.Read(id); err != nil {
+		return PartyParticipant{}, err
+	}
+	if _, err := rand.Read(token); err != nil {
+		return PartyParticipant{}, err
+	}
+
+	return PartyParticipant{
+		Id:    base64.RawURLEncoding.EncodeToString(id),
+		Name:  name,
+		Token: base64.RawURLEncoding.EncodeToString(token),
+	}, nil
+}
+
+func generatePartyCode() (string, error) {
+	bytes := make([]byte, partyCodeLength)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	for i := range bytes {
+		bytes[i] = partyCodeAlphabet[int(bytes[i])%len(partyCodeAlphabet)]
+	}
+
+	return string(bytes), nil
+}
@@ -0,0 +1,288 @@
+package business
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PartyTestSuite struct {
+	suite.Suite
+	Library *LibraryInteractor
+}
+
+// Go testing framework entry point.
+func TestPartyTestSuite(t *testing.T) {
+	suite.Run(t, new(PartyTestSuite))
+}
+
+func (suite *PartyTestSuite) SetupTest() {
+	suite.Library = createMockLibraryInteractor()
+	viper.Set("Party.SkipRatio", 0.5)
+	viper.Set("Party.IdleTimeout", 12)
+}
+
+func (suite *PartyTestSuite) TearDownTest() {
+	viper.Set("Party.SkipRatio", nil)
+	viper.Set("Party.IdleTimeout", nil)
+}
+
+// Creates a party with participants, the first one being the host if hosted is true.
+func (suite *PartyTestSuite) createParty(hosted bool, participants int) (Party, []PartyParticipant) {
+	party, host, err := suite.Library.CreateParty("Office", "Host", hosted)
+	assert.Nil(suite.T(), err)
+	members := []PartyParticipant{host}
+	for i := 1; i < participants; i++ {
+		var participant PartyParticipant
+		party, participant, err = suite.Library.JoinParty(party.Id, "Guest")
+		assert.Nil(suite.T(), err)
+		members = append(members, participant)
+	}
+
+	return party, members
+}
+
+func (suite *PartyTestSuite) TestCreateAndJoinParty() {
+	party, host, err := suite.Library.CreateParty("Office", "Alice", true)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), party.Id, partyCodeLength)
+	assert.Equal(suite.T(), "Office", party.Name)
+	assert.Equal(suite.T(), host.Id, party.HostId)
+	assert.NotEmpty(suite.T(), host.Token)
+	assert.Len(suite.T(), party.Participants, 1)
+
+	party, guest, err := suite.Library.JoinParty(party.Id, "Bob")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Bob", guest.Name)
+	assert.NotEqual(suite.T(), host.Token, guest.Token)
+	assert.Len(suite.T(), party.Participants, 2)
+
+	_, _, err = suite.Library.JoinParty("UNKNOWN", "Carol")
+	assert.Equal(suite.T(), ErrPartyNotFound, err)
+	_, _, err = suite.Library.CreateParty("", "Alice", false)
+	assert.NotNil(suite.T(), err)
+
+	// Parties without host.
+	other, _, err := suite.Library.CreateParty("Other", "Alice", false)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), other.HostId)
+	assert.NotEqual(suite.T(), party.Id, other.Id)
+}
+
+func (suite *PartyTestSuite) TestAddPartyTrack() {
+	party, members := suite.createParty(false, 2)
+
+	// The first track is played straight away.
+	party, err := suite.Library.AddPartyTrack(party.Id, members[0].Token, 1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, party.Current.TrackId)
+	assert.Equal(suite.T(), members[0].Id, party.Current.AddedBy)
+	assert.Empty(suite.T(), party.Queue)
+
+	party, err = suite.Library.AddPartyTrack(party.Id, members[1].Token, 2)
+	assert.Nil(suite.T(), err)
+	party, err = suite.Library.AddPartyTrack(party.Id, members[1].Token, 2)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), party.Queue, 2)
+	assert.NotEqual(suite.T(), party.Queue[0].Id, party.Queue[1].Id)
+
+	// Unknown tracks and participants.
+	_, err = suite.Library.AddPartyTrack(party.Id, members[0].Token, 99)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.AddPartyTrack(party.Id, "unknown", 1)
+	assert.Equal(suite.T(), ErrPartyNotParticipant, err)
+	_, err = suite.Library.AddPartyTrack("UNKNOWN", members[0].Token, 1)
+	assert.Equal(suite.T(), ErrPartyNotFound, err)
+}
+
+func (suite *PartyTestSuite) TestVotePartyTrack() {
+	party, members := suite.createParty(false, 4)
+	for trackId := 1; trackId <= 4; trackId++ {
+		party, _ = suite.Library.AddPartyTrack(party.Id, members[0].Token, trackId)
+	}
+	assert.Equal(suite.T(), 2, party.Queue[0].TrackId)
+
+	// The queue is sorted by score.
+	party, err := suite.Library.VotePartyTrack(party.Id, members[1].Token, party.Queue[2].Id, 1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []int{4, 2, 3}, getPartyTrackIds(party.Queue))
+	assert.Equal(suite.T(), 1, party.Queue[0].Score())
+
+	// Votes can be changed and withdrawn.
+	party, _ = suite.Library.VotePartyTrack(party.Id, members[1].Token, party.Queue[0].Id, -1)
+	assert.Equal(suite.T(), []int{2, 3, 4}, getPartyTrackIds(party.Queue))
+	party, _ = suite.Library.VotePartyTrack(party.Id, members[1].Token, party.Queue[2].Id, 0)
+	assert.Equal(suite.T(), []int{2, 3, 4}, getPartyTrackIds(party.Queue))
+	assert.Empty(suite.T(), party.Queue[2].Votes)
+
+	// Queued tracks voted down by half of the participants are removed.
+	assert.Equal(suite.T(), 2, party.SkipVotes())
+	party, _ = suite.Library.VotePartyTrack(party.Id, members[1].Token, party.Queue[1].Id, -1)
+	assert.Len(suite.T(), party.Queue, 3)
+	party, _ = suite.Library.VotePartyTrack(party.Id, members[2].Token, party.Queue[2].Id, -1)
+	assert.Equal(suite.T(), []int{2, 4}, getPartyTrackIds(party.Queue))
+
+	// And the current track is skipped.
+	party, _ = suite.Library.VotePartyTrack(party.Id, members[1].Token, party.Current.Id, -1)
+	assert.Equal(suite.T(), 1, party.Current.TrackId)
+	party, _ = suite.Library.VotePartyTrack(party.Id, members[2].Token, party.Current.Id, -1)
+	assert.Equal(suite.T(), 2, party.Current.TrackId)
+	assert.Equal(suite.T(), []int{4}, getPartyTrackIds(party.Queue))
+
+	// Invalid votes.
+	_, err = suite.Library.VotePartyTrack(party.Id, members[1].Token, party.Current.Id, 2)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.VotePartyTrack(party.Id, members[1].Token, 99, 1)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *PartyTestSuite) TestControlParty() {
+	party, members := suite.createParty(true, 2)
+	party, _ = suite.Library.AddPartyTrack(party.Id, members[1].Token, 1)
+	party, _ = suite.Library.AddPartyTrack(party.Id, members[1].Token, 2)
+
+	// Only the host controls the playback of a hosted party.
+	_, err := suite.Library.ControlParty(party.Id, members[1].Token, PartyActionPlay)
+	assert.Equal(suite.T(), ErrPartyNotHost, err)
+	party, err = suite.Library.ControlParty(party.Id, members[0].Token, PartyActionPlay)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), party.Playing)
+	party, _ = suite.Library.ControlParty(party.Id, members[0].Token, PartyActionNext)
+	assert.Equal(suite.T(), 2, party.Current.TrackId)
+	assert.Empty(suite.T(), party.Queue)
+	party, _ = suite.Library.ControlParty(party.Id, members[0].Token, PartyActionPause)
+	assert.False(suite.T(), party.Playing)
+
+	// The playback stops at the end of the queue.
+	party, _ = suite.Library.ControlParty(party.Id, members[0].Token, PartyActionPlay)
+	party, _ = suite.Library.ControlParty(party.Id, members[0].Token, PartyActionNext)
+	assert.Nil(suite.T(), party.Current)
+	assert.False(suite.T(), party.Playing)
+
+	_, err = suite.Library.ControlParty(party.Id, members[0].Token, "rewind")
+	assert.NotNil(suite.T(), err)
+
+	// Every participant controls the playback of a party without host.
+	party, members = suite.createParty(false, 2)
+	party, err = suite.Library.ControlParty(party.Id, members[1].Token, PartyActionPlay)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), party.Playing)
+}
+
+func (suite *PartyTestSuite) TestLeaveParty() {
+	party, members := suite.createParty(false, 3)
+	err := suite.Library.LeaveParty(party.Id, members[0].Token)
+	assert.Nil(suite.T(), err)
+	party, _ = suite.Library.GetParty(party.Id)
+	assert.Len(suite.T(), party.Participants, 2)
+	err = suite.Library.LeaveParty(party.Id, members[0].Token)
+	assert.Equal(suite.T(), ErrPartyNotParticipant, err)
+
+	// The party is closed once the last participant leaves.
+	_ = suite.Library.LeaveParty(party.Id, members[1].Token)
+	_ = suite.Library.LeaveParty(party.Id, members[2].Token)
+	_, err = suite.Library.GetParty(party.Id)
+	assert.Equal(suite.T(), ErrPartyNotFound, err)
+
+	// Or when its host leaves.
+	party, members = suite.createParty(true, 2)
+	_ = suite.Library.LeaveParty(party.Id, members[0].Token)
+	_, err = suite.Library.GetParty(party.Id)
+	assert.Equal(suite.T(), ErrPartyNotFound, err)
+}
+
+func (suite *PartyTestSuite) TestLeavePartySkips() {
+	party, members := suite.createParty(false, 4)
+	party, _ = suite.Library.AddPartyTrack(party.Id, members[0].Token, 1)
+	party, _ = suite.Library.VotePartyTrack(party.Id, members[1].Token, party.Current.Id, -1)
+	assert.NotNil(suite.T(), party.Current)
+
+	// With fewer participants, fewer down votes are needed.
+	_ = suite.Library.LeaveParty(party.Id, members[2].Token)
+	_ = suite.Library.LeaveParty(party.Id, members[3].Token)
+	party, _ = suite.Library.GetParty(party.Id)
+	assert.Nil(suite.T(), party.Current)
+}
+
+func (suite *PartyTestSuite) TestCloseIdleParties() {
+	now := time.Now()
+	suite.Library.partyClock = func() time.Time { return now }
+	party, members := suite.createParty(false, 1)
+	other, _ := suite.createParty(false, 1)
+	changes, stop, _ := suite.Library.ListenParty(party.Id)
+	defer stop()
+
+	// Changes keep a party open.
+	now = now.Add(11 * time.Hour)
+	_, err := suite.Library.AddPartyTrack(party.Id, members[0].Token, 1)
+	assert.Nil(suite.T(), err)
+	<-changes
+	now = now.Add(11 * time.Hour)
+	suite.Library.CloseIdleParties()
+	_, err = suite.Library.GetParty(party.Id)
+	assert.Nil(suite.T(), err)
+	_, err = suite.Library.GetParty(other.Id)
+	assert.Equal(suite.T(), ErrPartyNotFound, err)
+
+	// The listeners of the closed parties are released.
+	now = now.Add(2 * time.Hour)
+	suite.Library.CloseIdleParties()
+	state := <-changes
+	assert.True(suite.T(), state.Closed)
+	_, open := <-changes
+	assert.False(suite.T(), open)
+	assert.Empty(suite.T(), suite.Library.parties)
+}
+
+func (suite *PartyTestSuite) TestIdlePartiesCannotBeUsed() {
+	now := time.Now()
+	suite.Library.partyClock = func() time.Time { return now }
+	party, members := suite.createParty(false, 1)
+
+	// Before they are closed.
+	now = now.Add(13 * time.Hour)
+	_, _, err := suite.Library.JoinParty(party.Id, "Guest")
+	assert.Equal(suite.T(), ErrPartyNotFound, err)
+	_, _, err = suite.Library.ListenParty(party.Id)
+	assert.Equal(suite.T(), ErrPartyNotFound, err)
+	_, err = suite.Library.AddPartyTrack(party.Id, members[0].Token, 1)
+	assert.Equal(suite.T(), ErrPartyNotFound, err)
+}
+
+func (suite *PartyTestSuite) TestListenParty() {
+	party, members := suite.createParty(true, 2)
+	changes, stop, err := suite.Library.ListenParty(party.Id)
+	assert.Nil(suite.T(), err)
+	defer stop()
+
+	_, _ = suite.Library.AddPartyTrack(party.Id, members[1].Token, 1)
+	state := <-changes
+	assert.Equal(suite.T(), 1, state.Current.TrackId)
+
+	// States are copies.
+	_, _ = suite.Library.VotePartyTrack(party.Id, members[1].Token, state.Current.Id, 1)
+	assert.Empty(suite.T(), state.Current.Votes)
+	state = <-changes
+	assert.Len(suite.T(), state.Current.Votes, 1)
+
+	// The last state of a closed party is sent before the channel is closed.
+	_ = suite.Library.LeaveParty(party.Id, members[0].Token)
+	state = <-changes
+	assert.True(suite.T(), state.Closed)
+	_, open := <-changes
+	assert.False(suite.T(), open)
+
+	_, _, err = suite.Library.ListenParty(party.Id)
+	assert.Equal(suite.T(), ErrPartyNotFound, err)
+}
+
+func getPartyTrackIds(tracks []PartyTrack) (ids []int) {
+	for _, track := range tracks {
+		ids = append(ids, track.TrackId)
+	}
+
+	return
+}
@@ -0,0 +1,108 @@
+package business
+
+import (
+	"errors"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+/*
+This file exposes the play queues saved by the clients, so the playback can be resumed from another device.
+
+Every user has a single play queue. The clients listening to the queue of a user are notified when it's saved. Saving
+the queue also updates the bookmark of the track being played if it's resumable, see IsResumable().
+*/
+
+// Gets the play queue of a user, or an empty queue if none has been saved.
+func (interactor *LibraryInteractor) GetPlayQueue(user string) (domain.PlayQueue, error) {
+	queue, err := interactor.PlayQueueRepository.Get(user)
+	if err != nil {
+		return domain.PlayQueue{User: user, TrackIds: []int{}}, nil
+	}
+
+	return queue, nil
+}
+
+/*
+Saves the play queue of a user and notifies the clients listening to it.
+
+currentIndex is the index of the track being played in trackIds, and position the playback position in this track in
+milliseconds. clientId identifies the client saving the queue, so it can ignore its own changes.
+*/
+func (interactor *LibraryInteractor) SavePlayQueue(user string, clientId string, trackIds []int, currentIndex int, position int) (domain.PlayQueue, error) {
+	if currentIndex < 0 || (currentIndex >= len(trackIds) && currentIndex != 0) {
+		return domain.PlayQueue{}, errors.New("current index out of the queue")
+	}
+	if position < 0 {
+		return domain.PlayQueue{}, errors.New("negative position")
+	}
+	tracks := make(domain.Tracks, len(trackIds))
+	for i, trackId := range trackIds {
+		track, err := interactor.TrackRepository.Get(trackId)
+		if err != nil {
+			return domain.PlayQueue{}, errors.New("invalid track ID")
+		}
+		tracks[i] = track
+	}
+
+	queue, _ := interactor.GetPlayQueue(user)
+	queue.TrackIds = trackIds
+	queue.CurrentIndex = currentIndex
+	queue.Position = position
+	queue.ClientId = clientId
+	if err := interactor.PlayQueueRepository.Save(&queue); err != nil {
+		return domain.PlayQueue{}, err
+	}
+	// Clients save the queue with no position when starting a track, before seeking to its bookmark.
+	if len(tracks) > 0 && position > 0 && interactor.IsResumable(tracks[currentIndex]) {
+		if _, err := interactor.saveBookmark(user, tracks[currentIndex], position); err != nil {
+			return domain.PlayQueue{}, err
+		}
+	}
+
+	interactor.playQueueMutex.Lock()
+	defer interactor.playQueueMutex.Unlock()
+	for listener, listenedUser := range interactor.playQueueListeners {
+		if listenedUser != user {
+			continue
+		}
+		select {
+		case listener <- queue:
+		default:
+			// The previous change hasn't been read yet and is outdated.
+			select {
+			case <-listener:
+			default:
+			}
+			listener <- queue
+		}
+	}
+
+	return queue, nil
+}
+
+/*
+Listens to the changes of the play queue of a user.
+
+Returns a channel receiving the saved queues, and a function to call to stop listening. Slow listeners only receive the
+latest change.
+*/
+func (interactor *LibraryInteractor) ListenPlayQueue(user string) (<-chan domain.PlayQueue, func()) {
+	listener := make(chan domain.PlayQueue, 1)
+
+	interactor.playQueueMutex.Lock()
+	if interactor.playQueueListeners == nil {
+		interactor.playQueueListeners = make(map[chan domain.PlayQueue]string)
+	}
+	interactor.playQueueListeners[listener] = user
+	interactor.playQueueMutex.Unlock()
+
+	return listener, func() {
+		interactor.playQueueMutex.Lock()
+		defer interactor.playQueueMutex.Unlock()
+		if _, ok := interactor.playQueueListeners[listener]; ok {
+			delete(interactor.playQueueListeners, listener)
+			close(listener)
+		}
+	}
+}
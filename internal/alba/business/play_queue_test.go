@@ -0,0 +1,108 @@
+package business
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PlayQueueTestSuite struct {
+	suite.Suite
+	Library *LibraryInteractor
+}
+
+// Go testing framework entry point.
+func TestPlayQueueTestSuite(t *testing.T) {
+	suite.Run(t, new(PlayQueueTestSuite))
+}
+
+func (suite *PlayQueueTestSuite) SetupTest() {
+	suite.Library = createMockLibraryInteractor()
+}
+
+func (suite *PlayQueueTestSuite) TestSavePlayQueue() {
+	// Users without a saved queue have an empty one.
+	queue, err := suite.Library.GetPlayQueue("")
+	assert.Nil(suite.T(), err)
+	assert.Zero(suite.T(), queue.Id)
+	assert.Empty(suite.T(), queue.TrackIds)
+
+	queue, err = suite.Library.SavePlayQueue("", "desktop", []int{3, 1, 2}, 1, 5000)
+	assert.Nil(suite.T(), err)
+	assert.NotZero(suite.T(), queue.Id)
+	assert.Equal(suite.T(), []int{3, 1, 2}, queue.TrackIds)
+	assert.Equal(suite.T(), 1, queue.CurrentIndex)
+	assert.Equal(suite.T(), 5000, queue.Position)
+	assert.Equal(suite.T(), "desktop", queue.ClientId)
+
+	// Saving again replaces the queue.
+	saved, err := suite.Library.SavePlayQueue("", "laptop", []int{4}, 0, 0)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), queue.Id, saved.Id)
+	queue, _ = suite.Library.GetPlayQueue("")
+	assert.Equal(suite.T(), []int{4}, queue.TrackIds)
+	assert.Equal(suite.T(), "laptop", queue.ClientId)
+
+	// Queues are saved per user.
+	other, _ := suite.Library.GetPlayQueue("other")
+	assert.Empty(suite.T(), other.TrackIds)
+
+	// Empty queues.
+	queue, err = suite.Library.SavePlayQueue("", "laptop", []int{}, 0, 0)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), queue.TrackIds)
+}
+
+func (suite *PlayQueueTestSuite) TestSavePlayQueueInvalid() {
+	_, err := suite.Library.SavePlayQueue("", "desktop", []int{1, 2}, 2, 0)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.SavePlayQueue("", "desktop", []int{1, 2}, -1, 0)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.SavePlayQueue("", "desktop", []int{1, 2}, 0, -1)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.SavePlayQueue("", "desktop", []int{1, 99}, 0, 0)
+	assert.NotNil(suite.T(), err)
+
+	queue, _ := suite.Library.GetPlayQueue("")
+	assert.Zero(suite.T(), queue.Id)
+}
+
+func (suite *PlayQueueTestSuite) TestListenPlayQueue() {
+	changes, stop := suite.Library.ListenPlayQueue("")
+	otherChanges, stopOther := suite.Library.ListenPlayQueue("other")
+	defer stopOther()
+
+	_, err := suite.Library.SavePlayQueue("", "desktop", []int{1}, 0, 0)
+	assert.Nil(suite.T(), err)
+	queue := <-changes
+	assert.Equal(suite.T(), []int{1}, queue.TrackIds)
+	assert.Equal(suite.T(), "desktop", queue.ClientId)
+
+	// Listeners which haven't read the changes only get the latest one.
+	_, _ = suite.Library.SavePlayQueue("", "desktop", []int{1, 2}, 1, 0)
+	_, _ = suite.Library.SavePlayQueue("", "desktop", []int{1, 2}, 1, 1000)
+	queue = <-changes
+	assert.Equal(suite.T(), 1000, queue.Position)
+	select {
+	case <-changes:
+		suite.T().Error("outdated change received")
+	default:
+	}
+
+	// Listeners of other users aren't notified.
+	select {
+	case <-otherChanges:
+		suite.T().Error("change of another user received")
+	default:
+	}
+
+	// Stopped listeners are closed.
+	stop()
+	stop()
+	_, open := <-changes
+	assert.False(suite.T(), open)
+	_, err = suite.Library.SavePlayQueue("", "desktop", []int{2}, 0, 0)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), suite.Library.playQueueListeners, 1)
+}
@@ -0,0 +1,62 @@
+package business
+
+import (
+	"math"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+/*
+This file exposes the handling of the ReplayGain values of the tracks.
+
+ReplayGain gains are the adjustments in dB making the tracks play at the same loudness. The album gain keeps the
+loudness differences between the tracks of an album, the track gain doesn't. Tracks without ReplayGain tags get gains
+from their loudness analysis, if any.
+*/
+
+const ReplayGainModeTrack = "track"
+const ReplayGainModeAlbum = "album"
+
+/*
+Gets the gain to apply to a track in a ReplayGain mode, in dB.
+
+Falls back to the other mode values if the track doesn't have the ones of the requested mode. The gain is lowered if
+needed so the peak doesn't clip. Returns false if the mode is invalid or the track has no ReplayGain values.
+*/
+func ReplayGainAdjustment(track domain.Track, mode string) (float64, bool) {
+	if track.ReplayGainTrackGain == 0 && track.ReplayGainTrackPeak == 0 && track.ReplayGainAlbumGain == 0 && track.ReplayGainAlbumPeak == 0 {
+		track.ReplayGainTrackGain, track.ReplayGainTrackPeak = loudnessReplayGain(track.LoudnessTrackLufs, track.LoudnessTrackPeak)
+		track.ReplayGainAlbumGain, track.ReplayGainAlbumPeak = loudnessReplayGain(track.LoudnessAlbumLufs, track.LoudnessAlbumPeak)
+	}
+
+	trackValues := track.ReplayGainTrackGain != 0 || track.ReplayGainTrackPeak != 0
+	albumValues := track.ReplayGainAlbumGain != 0 || track.ReplayGainAlbumPeak != 0
+
+	var gain, peak float64
+	switch {
+	case mode != ReplayGainModeTrack && mode != ReplayGainModeAlbum:
+		return 0, false
+	case albumValues && (mode == ReplayGainModeAlbum || !trackValues):
+		gain, peak = track.ReplayGainAlbumGain, track.ReplayGainAlbumPeak
+	case trackValues:
+		gain, peak = track.ReplayGainTrackGain, track.ReplayGainTrackPeak
+	default:
+		return 0, false
+	}
+
+	// Prevent clipping.
+	if peak > 0 && gain > -20*math.Log10(peak) {
+		gain = -20 * math.Log10(peak)
+	}
+
+	return gain, true
+}
+
+// Converts a loudness in LUFS and a true peak to a ReplayGain gain and peak, 0 if the loudness is unknown.
+func loudnessReplayGain(loudness float64, truePeak float64) (gain float64, peak float64) {
+	if loudness == 0 {
+		return 0, 0
+	}
+
+	return ReplayGainReferenceLoudness - loudness, truePeak
+}
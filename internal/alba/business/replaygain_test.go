@@ -0,0 +1,68 @@
+package business
+
+import (
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReplayGainTestSuite struct {
+	suite.Suite
+}
+
+// Go testing framework entry point.
+func TestReplayGainTestSuite(t *testing.T) {
+	suite.Run(t, new(ReplayGainTestSuite))
+}
+
+func (suite *ReplayGainTestSuite) TestReplayGainAdjustment() {
+	track := domain.Track{
+		ReplayGainTrackGain: -6.5,
+		ReplayGainTrackPeak: 0.9,
+		ReplayGainAlbumGain: -7.25,
+		ReplayGainAlbumPeak: 1.0,
+	}
+
+	gain, ok := ReplayGainAdjustment(track, ReplayGainModeTrack)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), -6.5, gain)
+	gain, ok = ReplayGainAdjustment(track, ReplayGainModeAlbum)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), -7.25, gain)
+
+	// Fallback to the values of the other mode.
+	gain, ok = ReplayGainAdjustment(domain.Track{ReplayGainTrackGain: -3}, ReplayGainModeAlbum)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), -3.0, gain)
+	gain, ok = ReplayGainAdjustment(domain.Track{ReplayGainAlbumGain: -4}, ReplayGainModeTrack)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), -4.0, gain)
+
+	// Gains are lowered to prevent clipping.
+	gain, ok = ReplayGainAdjustment(domain.Track{ReplayGainTrackGain: 8, ReplayGainTrackPeak: 0.5}, ReplayGainModeTrack)
+	assert.True(suite.T(), ok)
+	assert.InDelta(suite.T(), 6.0206, gain, 0.0001)
+	gain, ok = ReplayGainAdjustment(domain.Track{ReplayGainTrackGain: 2, ReplayGainTrackPeak: 0.9}, ReplayGainModeTrack)
+	assert.True(suite.T(), ok)
+	assert.InDelta(suite.T(), 0.9151, gain, 0.0001)
+
+	// Values from the loudness analysis, with tag values first.
+	analyzed := domain.Track{LoudnessTrackLufs: -10.5, LoudnessTrackPeak: 0.2, LoudnessAlbumLufs: -12, LoudnessAlbumPeak: 0.2}
+	gain, ok = ReplayGainAdjustment(analyzed, ReplayGainModeTrack)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), -7.5, gain)
+	gain, ok = ReplayGainAdjustment(analyzed, ReplayGainModeAlbum)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), -6.0, gain)
+	analyzed.ReplayGainTrackGain = -3
+	gain, _ = ReplayGainAdjustment(analyzed, ReplayGainModeTrack)
+	assert.Equal(suite.T(), -3.0, gain)
+
+	// No values or invalid mode.
+	_, ok = ReplayGainAdjustment(domain.Track{}, ReplayGainModeTrack)
+	assert.False(suite.T(), ok)
+	_, ok = ReplayGainAdjustment(track, "loud")
+	assert.False(suite.T(), ok)
+}
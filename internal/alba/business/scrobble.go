@@ -0,0 +1,236 @@
+package business
+
+import (
+	"errors"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+/*
+This file exposes the scrobbling of the plays to the linked Last.fm and ListenBrainz accounts.
+
+The clients report the plays, which are queued in the database for every linked account and sent by FlushScrobbles().
+Submissions failing because a service is unreachable stay in the queue and are sent again on the next flush, in the
+order of the plays. The track being played is sent as "now playing", without queuing.
+*/
+
+// A track is scrobbled once played for half its duration or for 4 minutes, whichever comes first.
+const scrobbleMinDuration = 30
+const scrobbleMaxPlayedSeconds = 240
+
+var ErrScrobbleServiceUnavailable = errors.New("scrobbling service not available")
+
+// Error of a scrobbling service refusing a submission, which must not be sent again.
+type ScrobbleRejectedError struct {
+	Message string
+}
+
+func (e ScrobbleRejectedError) Error() string {
+	return "scrobble rejected: " + e.Message
+}
+
+/*
+Links an account of a scrobbling service from a token given by the service.
+
+The account is updated if it was already linked.
+*/
+func (interactor *LibraryInteractor) LinkScrobbleAccount(service string, token string) (domain.ScrobbleAccount, error) {
+	scrobbler, ok := interactor.Scrobblers[service]
+	if !ok {
+		return domain.ScrobbleAccount{}, ErrScrobbleServiceUnavailable
+	}
+
+	account, err := scrobbler.LinkAccount(token)
+	if err != nil {
+		return domain.ScrobbleAccount{}, err
+	}
+	account.Service = service
+	if existing, err := interactor.ScrobbleAccountRepository.GetByName(service, account.UserName); err == nil {
+		account.Id = existing.Id
+		account.DateAdded = existing.DateAdded
+	}
+	err = interactor.ScrobbleAccountRepository.Save(&account)
+
+	return account, err
+}
+
+// Gets the linked scrobbling accounts.
+func (interactor *LibraryInteractor) GetScrobbleAccounts() (domain.ScrobbleAccounts, error) {
+	return interactor.ScrobbleAccountRepository.GetAll()
+}
+
+// Unlinks a scrobbling account, dropping its queued scrobbles.
+func (interactor *LibraryInteractor) UnlinkScrobbleAccount(id int) (domain.ScrobbleAccount, error) {
+	interactor.scrobbleMutex.Lock()
+	defer interactor.scrobbleMutex.Unlock()
+
+	account, err := interactor.ScrobbleAccountRepository.Get(id)
+	if err != nil {
+		return account, err
+	}
+	if err = interactor.ScrobbleAccountRepository.Delete(&account); err != nil {
+		return account, err
+	}
+
+	return account, interactor.ScrobbleRepository.CleanUp()
+}
+
+// Gets the scrobbles waiting to be sent.
+func (interactor *LibraryInteractor) GetPendingScrobbles() (domain.Scrobbles, error) {
+	return interactor.ScrobbleRepository.GetAll()
+}
+
+/*
+Reports the play of a track to the linked scrobbling accounts.
+
+A track starting to play is sent as "now playing" straight away; failures are ignored, as the information would be
+outdated once sent again. A finished play is queued for scrobbling if the track was played long enough: playedSeconds
+is the time the track was listened to, or a negative number if the whole track was played. startedAt is the Unix time
+the play started at, 0 to compute it from now.
+
+Returns true if the play was queued for scrobbling. Call FlushScrobbles() to send the queue.
+*/
+func (interactor *LibraryInteractor) ReportPlay(trackId int, nowPlaying bool, playedSeconds int, startedAt int64) (bool, error) {
+	track, err := interactor.TrackRepository.Get(trackId)
+	if err != nil {
+		return false, err
+	}
+	accounts, err := interactor.ScrobbleAccountRepository.GetAll()
+	if err != nil || len(accounts) == 0 {
+		return false, err
+	}
+	scrobble := interactor.getTrackScrobble(track)
+
+	if nowPlaying {
+		for _, account := range accounts {
+			if scrobbler, ok := interactor.Scrobblers[account.Service]; ok {
+				_ = scrobbler.NowPlaying(account, scrobble)
+			}
+		}
+		return false, nil
+	}
+
+	if playedSeconds < 0 {
+		playedSeconds = track.Duration
+	}
+	if !isScrobbleablePlay(track.Duration, playedSeconds) {
+		return false, nil
+	}
+	scrobble.ListenedAt = startedAt
+	if scrobble.ListenedAt == 0 {
+		scrobble.ListenedAt = time.Now().Unix() - int64(playedSeconds)
+	}
+
+	for _, account := range accounts {
+		queued := scrobble
+		queued.AccountId = account.Id
+		if err = interactor.ScrobbleRepository.Save(&queued); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+/*
+Sends the queued scrobbles.
+
+Scrobbles sent or rejected by their service are removed from the queue. After a failure, the following scrobbles of
+the account are kept for the next flush, so they are sent in order. Accounts whose service isn't available keep their
+scrobbles too.
+
+Returns the number of scrobbles sent.
+*/
+func (interactor *LibraryInteractor) FlushScrobbles() (sent int, err error) {
+	interactor.scrobbleMutex.Lock()
+	defer interactor.scrobbleMutex.Unlock()
+
+	scrobbles, err := interactor.ScrobbleRepository.GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	accounts := make(map[int]domain.ScrobbleAccount)
+	postponed := make(map[int]bool)
+	for i := range scrobbles {
+		scrobble := &scrobbles[i]
+		if postponed[scrobble.AccountId] {
+			continue
+		}
+
+		account, ok := accounts[scrobble.AccountId]
+		if !ok {
+			if account, err = interactor.ScrobbleAccountRepository.Get(scrobble.AccountId); err != nil {
+				// The account has been unlinked.
+				if err = interactor.ScrobbleRepository.Delete(scrobble); err != nil {
+					return sent, err
+				}
+				continue
+			}
+			accounts[account.Id] = account
+		}
+		scrobbler, ok := interactor.Scrobblers[account.Service]
+		if !ok {
+			postponed[account.Id] = true
+			continue
+		}
+
+		errScrobble := scrobbler.Scrobble(account, *scrobble)
+		if _, rejected := errScrobble.(ScrobbleRejectedError); errScrobble == nil || rejected {
+			if errScrobble == nil {
+				sent++
+			}
+			if err = interactor.ScrobbleRepository.Delete(scrobble); err != nil {
+				return sent, err
+			}
+			continue
+		}
+
+		postponed[account.Id] = true
+		scrobble.Attempts++
+		scrobble.LastError = errScrobble.Error()
+		if err = interactor.ScrobbleRepository.Save(scrobble); err != nil {
+			return sent, err
+		}
+	}
+
+	return sent, nil
+}
+
+// Checks if a play is long enough to be scrobbled, durations being in seconds.
+func isScrobbleablePlay(duration int, playedSeconds int) bool {
+	if duration <= scrobbleMinDuration {
+		return false
+	}
+	required := duration / 2
+	if required > scrobbleMaxPlayedSeconds {
+		required = scrobbleMaxPlayedSeconds
+	}
+
+	return playedSeconds >= required
+}
+
+// Gets the scrobble of a track, without account and play time.
+func (interactor *LibraryInteractor) getTrackScrobble(track domain.Track) domain.Scrobble {
+	scrobble := domain.Scrobble{
+		Title:              track.Title,
+		Number:             track.Number,
+		Duration:           track.Duration,
+		MusicBrainzTrackId: track.MusicBrainzTrackId,
+	}
+	if artist, err := interactor.ArtistRepository.Get(track.ArtistId); err == nil {
+		scrobble.Artist = artist.Name
+	}
+	if album, err := interactor.AlbumRepository.Get(track.AlbumId); err == nil {
+		scrobble.Album = album.Title
+		if artist, err := interactor.ArtistRepository.Get(album.ArtistId); err == nil {
+			scrobble.AlbumArtist = artist.Name
+		}
+	}
+	if scrobble.Artist == "" {
+		scrobble.Artist = LibraryDefaultArtist
+	}
+
+	return scrobble
+}
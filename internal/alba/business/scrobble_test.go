@@ -0,0 +1,188 @@
+package business
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ScrobbleTestSuite struct {
+	suite.Suite
+	Library      *LibraryInteractor
+	LastFm       *ScrobblerMock
+	ListenBrainz *ScrobblerMock
+}
+
+// Go testing framework entry point.
+func TestScrobbleTestSuite(t *testing.T) {
+	suite.Run(t, new(ScrobbleTestSuite))
+}
+
+func (suite *ScrobbleTestSuite) SetupTest() {
+	suite.Library = createMockLibraryInteractor()
+	suite.LastFm = new(ScrobblerMock)
+	suite.ListenBrainz = new(ScrobblerMock)
+	suite.Library.Scrobblers = map[string]Scrobbler{
+		domain.ScrobbleServiceLastFm:       suite.LastFm,
+		domain.ScrobbleServiceListenBrainz: suite.ListenBrainz,
+	}
+}
+
+// Links an account of each service.
+func (suite *ScrobbleTestSuite) linkAccounts() {
+	_, err := suite.Library.LinkScrobbleAccount(domain.ScrobbleServiceLastFm, "lastfm")
+	assert.Nil(suite.T(), err)
+	_, err = suite.Library.LinkScrobbleAccount(domain.ScrobbleServiceListenBrainz, "listenbrainz")
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *ScrobbleTestSuite) TestLinkScrobbleAccount() {
+	account, err := suite.Library.LinkScrobbleAccount(domain.ScrobbleServiceLastFm, "token")
+	assert.Nil(suite.T(), err)
+	assert.NotZero(suite.T(), account.Id)
+	assert.Equal(suite.T(), domain.ScrobbleServiceLastFm, account.Service)
+	assert.Equal(suite.T(), "User token", account.UserName)
+	assert.Equal(suite.T(), "session token", account.Token)
+
+	// Linking the same account again updates it.
+	again, err := suite.Library.LinkScrobbleAccount(domain.ScrobbleServiceLastFm, "token")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), account.Id, again.Id)
+	accounts, _ := suite.Library.GetScrobbleAccounts()
+	assert.Len(suite.T(), accounts, 1)
+
+	// Invalid tokens and unknown services.
+	_, err = suite.Library.LinkScrobbleAccount(domain.ScrobbleServiceLastFm, "")
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.LinkScrobbleAccount("unknown", "token")
+	assert.Equal(suite.T(), ErrScrobbleServiceUnavailable, err)
+
+	// Unlinking.
+	unlinked, err := suite.Library.UnlinkScrobbleAccount(account.Id)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), account.Id, unlinked.Id)
+	accounts, _ = suite.Library.GetScrobbleAccounts()
+	assert.Empty(suite.T(), accounts)
+	_, err = suite.Library.UnlinkScrobbleAccount(account.Id)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *ScrobbleTestSuite) TestReportPlay() {
+	// Nothing is queued without linked accounts.
+	queued, err := suite.Library.ReportPlay(1, false, -1, 0)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), queued)
+
+	suite.linkAccounts()
+
+	// Now playing is sent straight away.
+	queued, err = suite.Library.ReportPlay(1, true, 0, 0)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), queued)
+	assert.Len(suite.T(), suite.LastFm.Playing, 1)
+	assert.Len(suite.T(), suite.ListenBrainz.Playing, 1)
+	assert.Equal(suite.T(), "Track #1", suite.LastFm.Playing[0].Title)
+	assert.Equal(suite.T(), "Artist #0", suite.LastFm.Playing[0].Artist)
+	assert.Equal(suite.T(), "Album #0", suite.LastFm.Playing[0].Album)
+	assert.Equal(suite.T(), 200, suite.LastFm.Playing[0].Duration)
+	pending, _ := suite.Library.GetPendingScrobbles()
+	assert.Empty(suite.T(), pending)
+
+	// Plays are queued for every account.
+	queued, err = suite.Library.ReportPlay(1, false, 100, 1000)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), queued)
+	pending, _ = suite.Library.GetPendingScrobbles()
+	assert.Len(suite.T(), pending, 2)
+	assert.Equal(suite.T(), 1, pending[0].AccountId)
+	assert.Equal(suite.T(), 2, pending[1].AccountId)
+	assert.Equal(suite.T(), int64(1000), pending[0].ListenedAt)
+	assert.Equal(suite.T(), "Track #1", pending[0].Title)
+
+	// The start of the play defaults to now minus the time played.
+	before := time.Now().Unix()
+	queued, err = suite.Library.ReportPlay(2, false, -1, 0)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), queued)
+	pending, _ = suite.Library.GetPendingScrobbles()
+	assert.Len(suite.T(), pending, 4)
+	assert.True(suite.T(), pending[3].ListenedAt >= before-200 && pending[3].ListenedAt <= time.Now().Unix()-200)
+
+	// Plays too short aren't queued.
+	queued, err = suite.Library.ReportPlay(1, false, 99, 0)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), queued)
+
+	// Unknown tracks.
+	_, err = suite.Library.ReportPlay(99, false, -1, 0)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *ScrobbleTestSuite) TestFlushScrobbles() {
+	suite.linkAccounts()
+	for i := 1; i <= 3; i++ {
+		_, _ = suite.Library.ReportPlay(i, false, -1, int64(1000*i))
+	}
+
+	// The Last.fm service is unreachable on the second scrobble, and ListenBrainz rejects the first one.
+	suite.LastFm.Errors = []error{nil, errors.New("connection refused")}
+	suite.ListenBrainz.Errors = []error{ScrobbleRejectedError{Message: "invalid listen"}}
+	sent, err := suite.Library.FlushScrobbles()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 3, sent)
+	assert.Len(suite.T(), suite.LastFm.Scrobbles, 1)
+	assert.Len(suite.T(), suite.ListenBrainz.Scrobbles, 2)
+
+	// The failed scrobble and the following ones are kept, in order.
+	pending, _ := suite.Library.GetPendingScrobbles()
+	assert.Len(suite.T(), pending, 2)
+	assert.Equal(suite.T(), "Track #2", pending[0].Title)
+	assert.Equal(suite.T(), 1, pending[0].Attempts)
+	assert.Equal(suite.T(), "connection refused", pending[0].LastError)
+	assert.Equal(suite.T(), "Track #3", pending[1].Title)
+	assert.Equal(suite.T(), 0, pending[1].Attempts)
+
+	sent, err = suite.Library.FlushScrobbles()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, sent)
+	assert.Equal(suite.T(), "Track #2", suite.LastFm.Scrobbles[1].Title)
+	assert.Equal(suite.T(), "Track #3", suite.LastFm.Scrobbles[2].Title)
+	pending, _ = suite.Library.GetPendingScrobbles()
+	assert.Empty(suite.T(), pending)
+}
+
+func (suite *ScrobbleTestSuite) TestFlushScrobblesUnavailableService() {
+	suite.linkAccounts()
+	_, _ = suite.Library.ReportPlay(1, false, -1, 1000)
+
+	// Scrobbles are kept until the service is configured again.
+	delete(suite.Library.Scrobblers, domain.ScrobbleServiceLastFm)
+	sent, err := suite.Library.FlushScrobbles()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, sent)
+	pending, _ := suite.Library.GetPendingScrobbles()
+	assert.Len(suite.T(), pending, 1)
+	assert.Equal(suite.T(), 1, pending[0].AccountId)
+
+	// Scrobbles of unlinked accounts are dropped.
+	_, _ = suite.Library.UnlinkScrobbleAccount(1)
+	sent, err = suite.Library.FlushScrobbles()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 0, sent)
+	pending, _ = suite.Library.GetPendingScrobbles()
+	assert.Empty(suite.T(), pending)
+}
+
+func (suite *ScrobbleTestSuite) TestIsScrobbleablePlay() {
+	assert.False(suite.T(), isScrobbleablePlay(30, 30))
+	assert.True(suite.T(), isScrobbleablePlay(31, 15))
+	assert.False(suite.T(), isScrobbleablePlay(200, 99))
+	assert.True(suite.T(), isScrobbleablePlay(200, 100))
+	// Long tracks only need 4 minutes.
+	assert.True(suite.T(), isScrobbleablePlay(3600, 240))
+	assert.False(suite.T(), isScrobbleablePlay(3600, 239))
+}
@@ -0,0 +1,266 @@
+package business
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/spf13/viper"
+)
+
+/*
+This file exposes the public share links of albums, tracks and playlists, which let people without an account listen
+to them.
+
+Shares are found from a random slug, can expire, and can limit the number of track plays. Playlists are stored by the
+clients, so their shares hold the ids of their tracks. Shares can be disabled with Shares.Enabled.
+*/
+
+var ErrSharesDisabled = errors.New("shares are disabled")
+var ErrShareNotFound = errors.New("share not found")
+var ErrShareExpired = errors.New("share expired")
+var ErrSharePlaysExhausted = errors.New("maximum number of plays reached")
+
+// Content of a share, as shown on its public page.
+type SharedContent struct {
+	Title   string // Title of the album or of the track, or name of the playlist.
+	Artist  string // Name of the album or track artist, empty for playlists.
+	CoverId int
+	Tracks  domain.Tracks
+	Artists map[int]string // Names of the tracks artists, by id.
+}
+
+/*
+Shares an album or a track.
+
+expiresAt is a Unix time, 0 for a link that never expires, and maxPlays the number of track plays allowed, 0 for
+unlimited plays.
+*/
+func (interactor *LibraryInteractor) CreateShare(entityType string, entityId int, expiresAt int64, maxPlays int, allowDownload bool) (domain.Share, error) {
+	if !viper.GetBool("Shares.Enabled") {
+		return domain.Share{}, ErrSharesDisabled
+	}
+
+	var err error
+	switch entityType {
+	case domain.ShareEntityAlbum:
+		_, err = interactor.AlbumRepository.Get(entityId)
+	case domain.ShareEntityTrack:
+		_, err = interactor.TrackRepository.Get(entityId)
+	default:
+		return domain.Share{}, errors.New("entities of type " + entityType + " cannot be shared")
+	}
+	if err != nil {
+		return domain.Share{}, err
+	}
+
+	return interactor.saveShare(domain.Share{
+		EntityType:    entityType,
+		EntityId:      entityId,
+		ExpiresAt:     expiresAt,
+		MaxPlays:      maxPlays,
+		AllowDownload: allowDownload,
+	})
+}
+
+// Shares a playlist, from its name and the ids of its tracks in order. See CreateShare() for the other parameters.
+func (interactor *LibraryInteractor) CreatePlaylistShare(name string, trackIds []int, expiresAt int64, maxPlays int, allowDownload bool) (domain.Share, error) {
+	if !viper.GetBool("Shares.Enabled") {
+		return domain.Share{}, ErrSharesDisabled
+	}
+	if len(trackIds) == 0 {
+		return domain.Share{}, errors.New("empty playlist")
+	}
+	for _, trackId := range trackIds {
+		if _, err := interactor.TrackRepository.Get(trackId); err != nil {
+			return domain.Share{}, err
+		}
+	}
+	if strings.TrimSpace(name) == "" {
+		name = defaultPlaylistName
+	}
+
+	return interactor.saveShare(domain.Share{
+		EntityType:    domain.ShareEntityPlaylist,
+		Name:          name,
+		ExpiresAt:     expiresAt,
+		MaxPlays:      maxPlays,
+		AllowDownload: allowDownload,
+		TrackIds:      trackIds,
+	})
+}
+
+// Checks the limits of a new share, and saves it with a random slug.
+func (interactor *LibraryInteractor) saveShare(share domain.Share) (domain.Share, error) {
+	if share.ExpiresAt != 0 && share.ExpiresAt <= time.Now().Unix() {
+		return domain.Share{}, errors.New("expiry date in the past")
+	}
+	if share.MaxPlays < 0 {
+		return domain.Share{}, errors.New("negative number of plays")
+	}
+
+	slug, err := generateShareSlug()
+	if err != nil {
+		return domain.Share{}, err
+	}
+	share.Slug = slug
+	err = interactor.ShareRepository.Save(&share)
+
+	return share, err
+}
+
+// Gets all the shares, the most recent first, including the expired ones.
+func (interactor *LibraryInteractor) GetShares() (domain.Shares, error) {
+	return interactor.ShareRepository.GetAll()
+}
+
+// Deletes a share, so its link doesn't work anymore. Returns the deleted share.
+func (interactor *LibraryInteractor) RevokeShare(id int) (domain.Share, error) {
+	share, err := interactor.ShareRepository.Get(id)
+	if err != nil {
+		return domain.Share{}, err
+	}
+
+	return share, interactor.ShareRepository.Delete(&share)
+}
+
+/*
+Gets a share from its slug.
+
+Returns ErrShareNotFound if there is no such share or if shares are disabled, or ErrShareExpired.
+*/
+func (interactor *LibraryInteractor) GetShare(slug string) (domain.Share, error) {
+	if !viper.GetBool("Shares.Enabled") {
+		return domain.Share{}, ErrShareNotFound
+	}
+	share, err := interactor.ShareRepository.GetBySlug(slug)
+	if err != nil {
+		return domain.Share{}, ErrShareNotFound
+	}
+	if share.ExpiresAt != 0 && share.ExpiresAt <= time.Now().Unix() {
+		return share, ErrShareExpired
+	}
+
+	return share, nil
+}
+
+// Gets the content of a share.
+func (interactor *LibraryInteractor) GetSharedContent(share domain.Share) (SharedContent, error) {
+	content := SharedContent{Artists: make(map[int]string)}
+	artistId := 0
+
+	switch share.EntityType {
+	case domain.ShareEntityPlaylist:
+		content.Title = share.Name
+		// The tracks deleted since the playlist has been shared are skipped.
+		for _, trackId := range share.TrackIds {
+			track, err := interactor.TrackRepository.Get(trackId)
+			if err != nil {
+				continue
+			}
+			content.Tracks = append(content.Tracks, track)
+			interactor.getShareArtistName(track.ArtistId, content.Artists)
+			// The cover of the first track having one.
+			if content.CoverId == 0 {
+				content.CoverId = track.CoverId
+			}
+		}
+		if len(content.Tracks) == 0 {
+			return content, errors.New("empty playlist")
+		}
+
+		return content, nil
+	case domain.ShareEntityAlbum:
+		album, err := interactor.AlbumRepository.Get(share.EntityId)
+		if err != nil {
+			return content, err
+		}
+		content.Title = album.Title
+		content.CoverId = album.CoverId
+		content.Tracks = album.Tracks
+		artistId = album.ArtistId
+	default:
+		track, err := interactor.TrackRepository.Get(share.EntityId)
+		if err != nil {
+			return content, err
+		}
+		content.Title = track.Title
+		content.CoverId = track.CoverId
+		content.Tracks = domain.Tracks{track}
+		artistId = track.ArtistId
+	}
+
+	content.Artist = interactor.getShareArtistName(artistId, content.Artists)
+	for _, track := range content.Tracks {
+		interactor.getShareArtistName(track.ArtistId, content.Artists)
+	}
+
+	return content, nil
+}
+
+// Gets the name of an artist, keeping the names already found in a map.
+func (interactor *LibraryInteractor) getShareArtistName(artistId int, names map[int]string) string {
+	if name, ok := names[artistId]; ok {
+		return name
+	}
+
+	name := LibraryDefaultArtist
+	if artist, err := interactor.ArtistRepository.Get(artistId); err == nil {
+		name = artist.Name
+	}
+	names[artistId] = name
+
+	return name
+}
+
+// Gets a track of a share. Returns ErrShareNotFound if the track isn't shared.
+func (interactor *LibraryInteractor) GetSharedTrack(share domain.Share, trackId int) (domain.Track, error) {
+	track, err := interactor.TrackRepository.Get(trackId)
+	if err != nil {
+		return domain.Track{}, ErrShareNotFound
+	}
+	switch share.EntityType {
+	case domain.ShareEntityPlaylist:
+		for _, sharedId := range share.TrackIds {
+			if sharedId == track.Id {
+				return track, nil
+			}
+		}
+	case domain.ShareEntityAlbum:
+		if track.AlbumId == share.EntityId {
+			return track, nil
+		}
+	default:
+		if track.Id == share.EntityId {
+			return track, nil
+		}
+	}
+
+	return domain.Track{}, ErrShareNotFound
+}
+
+// Counts a play of a share. Returns ErrSharePlaysExhausted if its maximum number of plays is reached.
+func (interactor *LibraryInteractor) AddSharePlay(share domain.Share) error {
+	counted, err := interactor.ShareRepository.AddPlay(share.Id)
+	if err != nil {
+		return err
+	}
+	if !counted {
+		return ErrSharePlaysExhausted
+	}
+
+	return nil
+}
+
+// Generates the random slug of a share.
+func generateShareSlug() (string, error) {
+	bytes := make([]byte, 12)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
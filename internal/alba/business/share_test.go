@@ -0,0 +1,193 @@
+package business
+
+import (
+	"testing"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ShareTestSuite struct {
+	suite.Suite
+	Library *LibraryInteractor
+}
+
+// Go testing framework entry point.
+func TestShareTestSuite(t *testing.T) {
+	suite.Run(t, new(ShareTestSuite))
+}
+
+func (suite *ShareTestSuite) SetupTest() {
+	suite.Library = createMockLibraryInteractor()
+	viper.Set("Shares.Enabled", true)
+}
+
+func (suite *ShareTestSuite) TearDownTest() {
+	viper.Set("Shares.Enabled", false)
+}
+
+func (suite *ShareTestSuite) TestCreateShare() {
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	share, err := suite.Library.CreateShare(domain.ShareEntityAlbum, 1, expiresAt, 5, true)
+	assert.Nil(suite.T(), err)
+	assert.NotZero(suite.T(), share.Id)
+	assert.Len(suite.T(), share.Slug, 16)
+	assert.Equal(suite.T(), domain.ShareEntityAlbum, share.EntityType)
+	assert.Equal(suite.T(), 1, share.EntityId)
+	assert.Equal(suite.T(), expiresAt, share.ExpiresAt)
+	assert.Equal(suite.T(), 5, share.MaxPlays)
+	assert.True(suite.T(), share.AllowDownload)
+
+	// Slugs are random.
+	other, err := suite.Library.CreateShare(domain.ShareEntityTrack, 2, 0, 0, false)
+	assert.Nil(suite.T(), err)
+	assert.NotEqual(suite.T(), share.Slug, other.Slug)
+
+	shares, err := suite.Library.GetShares()
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), shares, 2)
+
+	// Invalid shares.
+	_, err = suite.Library.CreateShare("playlist", 1, 0, 0, false)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.CreateShare(domain.ShareEntityAlbum, 99, 0, 0, false)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.CreateShare(domain.ShareEntityAlbum, 1, time.Now().Add(-time.Hour).Unix(), 0, false)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.CreateShare(domain.ShareEntityAlbum, 1, 0, -1, false)
+	assert.NotNil(suite.T(), err)
+
+	viper.Set("Shares.Enabled", false)
+	_, err = suite.Library.CreateShare(domain.ShareEntityAlbum, 1, 0, 0, false)
+	assert.Equal(suite.T(), ErrSharesDisabled, err)
+}
+
+func (suite *ShareTestSuite) TestCreatePlaylistShare() {
+	share, err := suite.Library.CreatePlaylistShare("Road trip", []int{3, 1, 3}, 0, 2, false)
+	assert.Nil(suite.T(), err)
+	assert.NotZero(suite.T(), share.Id)
+	assert.NotEmpty(suite.T(), share.Slug)
+	assert.Equal(suite.T(), domain.ShareEntityPlaylist, share.EntityType)
+	assert.Equal(suite.T(), "Road trip", share.Name)
+	assert.Equal(suite.T(), []int{3, 1, 3}, share.TrackIds)
+	assert.Equal(suite.T(), 2, share.MaxPlays)
+
+	share, err = suite.Library.CreatePlaylistShare(" ", []int{1}, 0, 0, false)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Playlist", share.Name)
+
+	// Invalid shares.
+	_, err = suite.Library.CreatePlaylistShare("Empty", nil, 0, 0, false)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.CreatePlaylistShare("Unknown", []int{1, 99}, 0, 0, false)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.CreatePlaylistShare("Expired", []int{1}, time.Now().Add(-time.Hour).Unix(), 0, false)
+	assert.NotNil(suite.T(), err)
+
+	viper.Set("Shares.Enabled", false)
+	_, err = suite.Library.CreatePlaylistShare("Road trip", []int{1}, 0, 0, false)
+	assert.Equal(suite.T(), ErrSharesDisabled, err)
+}
+
+func (suite *ShareTestSuite) TestGetShare() {
+	share, _ := suite.Library.CreateShare(domain.ShareEntityAlbum, 1, 0, 0, false)
+	found, err := suite.Library.GetShare(share.Slug)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), share.Id, found.Id)
+
+	_, err = suite.Library.GetShare("unknown")
+	assert.Equal(suite.T(), ErrShareNotFound, err)
+
+	// Expired shares.
+	share.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	_ = suite.Library.ShareRepository.Save(&share)
+	_, err = suite.Library.GetShare(share.Slug)
+	assert.Equal(suite.T(), ErrShareExpired, err)
+
+	viper.Set("Shares.Enabled", false)
+	_, err = suite.Library.GetShare(share.Slug)
+	assert.Equal(suite.T(), ErrShareNotFound, err)
+}
+
+func (suite *ShareTestSuite) TestRevokeShare() {
+	share, _ := suite.Library.CreateShare(domain.ShareEntityTrack, 1, 0, 0, false)
+	revoked, err := suite.Library.RevokeShare(share.Id)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), share.Slug, revoked.Slug)
+
+	_, err = suite.Library.GetShare(share.Slug)
+	assert.Equal(suite.T(), ErrShareNotFound, err)
+	_, err = suite.Library.RevokeShare(share.Id)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *ShareTestSuite) TestGetSharedContent() {
+	album := domain.Share{EntityType: domain.ShareEntityAlbum, EntityId: 2}
+	content, err := suite.Library.GetSharedContent(album)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Album #2", content.Title)
+	assert.Len(suite.T(), content.Tracks, 3)
+	assert.NotEmpty(suite.T(), content.Artist)
+	assert.Contains(suite.T(), content.Artists, content.Tracks[0].ArtistId)
+
+	track := domain.Share{EntityType: domain.ShareEntityTrack, EntityId: 3}
+	content, err = suite.Library.GetSharedContent(track)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Track #3", content.Title)
+	assert.Len(suite.T(), content.Tracks, 1)
+
+	_, err = suite.Library.GetSharedContent(domain.Share{EntityType: domain.ShareEntityAlbum, EntityId: 99})
+	assert.NotNil(suite.T(), err)
+
+	// The deleted tracks of the playlists are skipped.
+	playlist := domain.Share{EntityType: domain.ShareEntityPlaylist, Name: "Road trip", TrackIds: []int{3, 99, 1}}
+	content, err = suite.Library.GetSharedContent(playlist)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Road trip", content.Title)
+	assert.Empty(suite.T(), content.Artist)
+	assert.Len(suite.T(), content.Tracks, 2)
+	assert.Equal(suite.T(), "Track #1", content.Tracks[1].Title)
+	assert.Contains(suite.T(), content.Artists, content.Tracks[0].ArtistId)
+
+	playlist.TrackIds = []int{99}
+	_, err = suite.Library.GetSharedContent(playlist)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *ShareTestSuite) TestGetSharedTrack() {
+	track := domain.Share{EntityType: domain.ShareEntityTrack, EntityId: 3}
+	found, err := suite.Library.GetSharedTrack(track, 3)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 3, found.Id)
+	_, err = suite.Library.GetSharedTrack(track, 4)
+	assert.Equal(suite.T(), ErrShareNotFound, err)
+	_, err = suite.Library.GetSharedTrack(track, 99)
+	assert.Equal(suite.T(), ErrShareNotFound, err)
+
+	// The tracks of the mocked repository don't belong to any album.
+	album := domain.Share{EntityType: domain.ShareEntityAlbum, EntityId: 1}
+	_, err = suite.Library.GetSharedTrack(album, 3)
+	assert.Equal(suite.T(), ErrShareNotFound, err)
+
+	playlist := domain.Share{EntityType: domain.ShareEntityPlaylist, TrackIds: []int{5, 3}}
+	found, err = suite.Library.GetSharedTrack(playlist, 3)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 3, found.Id)
+	_, err = suite.Library.GetSharedTrack(playlist, 4)
+	assert.Equal(suite.T(), ErrShareNotFound, err)
+}
+
+func (suite *ShareTestSuite) TestAddSharePlay() {
+	share, _ := suite.Library.CreateShare(domain.ShareEntityTrack, 1, 0, 2, false)
+	assert.Nil(suite.T(), suite.Library.AddSharePlay(share))
+	assert.Nil(suite.T(), suite.Library.AddSharePlay(share))
+	assert.Equal(suite.T(), ErrSharePlaysExhausted, suite.Library.AddSharePlay(share))
+
+	unlimited, _ := suite.Library.CreateShare(domain.ShareEntityTrack, 1, 0, 0, false)
+	for i := 0; i < 5; i++ {
+		assert.Nil(suite.T(), suite.Library.AddSharePlay(unlimited))
+	}
+}
@@ -0,0 +1,187 @@
+package business
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/spf13/viper"
+)
+
+/*
+This file exposes the writing of the manual edits back into the media files tags.
+
+When Library.WriteTags is enabled, track and album edits are written into the files of the tracks instead of being
+stored as overrides, then the files are scanned again. Artist edits are always stored as overrides, as artist tags
+often credit several artists. The edits of the tracks of CUE sheets are also always stored as overrides, as their media
+file holds the tags of the whole album.
+*/
+
+// Change of a tag value in a media file.
+type TagEdit struct {
+	Path          string
+	Field         string // Name of the tag, see TrackTagFields.
+	PreviousValue string
+	Value         string
+}
+
+// Tags that can be written into the media files, named after the track fields.
+var TrackTagFields = []string{
+	"title", "number", "disc", "composer", "conductor", "label", "catalogNumber", "isrc", "originalYear", "bpm",
+	"album", "albumSort", "year",
+}
+
+// Tags of the tracks corresponding to the album fields.
+var albumTagFields = map[string]string{
+	"title":    "album",
+	"sortName": "albumSort",
+	"year":     "year",
+}
+
+// Checks if the edits must be written into the media files.
+func (interactor *LibraryInteractor) TagWritingEnabled() bool {
+	return viper.GetBool("Library.WriteTags")
+}
+
+// Gets the changes editing a track would make to its media file, without writing anything.
+func (interactor *LibraryInteractor) PreviewTrackTags(trackId int, values map[string]string) ([]TagEdit, error) {
+	track, err := interactor.TrackRepository.Get(trackId)
+	if err != nil {
+		return nil, errors.New("cannot preview track tags: invalid track ID")
+	}
+	if track.CueTrack != 0 {
+		return nil, errors.New("cannot preview track tags: the track is part of a CUE sheet")
+	}
+	for field, value := range values {
+		if err := SetTrackField(&track, field, value); err != nil {
+			return nil, errors.New("cannot preview track tags: " + err.Error())
+		}
+	}
+
+	return interactor.writeTags([]string{track.Path}, values, true)
+}
+
+// Gets the changes editing an album would make to the media files of its tracks, without writing anything.
+func (interactor *LibraryInteractor) PreviewAlbumTags(albumId int, values map[string]string) ([]TagEdit, error) {
+	album, err := interactor.AlbumRepository.Get(albumId)
+	if err != nil {
+		return nil, errors.New("cannot preview album tags: invalid album ID")
+	}
+	if hasCueTracks(album.Tracks) {
+		return nil, errors.New("cannot preview album tags: the album has tracks from a CUE sheet")
+	}
+	for field, value := range values {
+		if err := SetAlbumField(&album, field, value); err != nil {
+			return nil, errors.New("cannot preview album tags: " + err.Error())
+		}
+	}
+
+	return interactor.writeTags(getTrackPaths(album.Tracks), getAlbumTagValues(values), true)
+}
+
+// Writes a track edits into its media file and scans it again.
+//
+// The overrides of the written fields are deleted, as the file now holds their values.
+func (interactor *LibraryInteractor) writeTrackTags(track *domain.Track, values map[string]string) error {
+	if _, err := interactor.writeTags([]string{track.Path}, values, false); err != nil {
+		return err
+	}
+	if err := interactor.deleteOverrides(domain.OverrideEntityTrack, track.Id, values); err != nil {
+		return err
+	}
+
+	return interactor.MediaFileRepository.RescanMediaFiles([]string{track.Path})
+}
+
+// Writes an album edits into the media files of its tracks and scans them again.
+//
+// The overrides of the written fields are deleted, as the files now hold their values.
+func (interactor *LibraryInteractor) writeAlbumTags(album *domain.Album, values map[string]string) error {
+	paths := getTrackPaths(album.Tracks)
+	if _, err := interactor.writeTags(paths, getAlbumTagValues(values), false); err != nil {
+		return err
+	}
+	if err := interactor.deleteOverrides(domain.OverrideEntityAlbum, album.Id, values); err != nil {
+		return err
+	}
+
+	// The files now hold the new title, so the album must be found by it.
+	if _, ok := values["title"]; ok {
+		album.MatchKey = ""
+		if err := interactor.AlbumRepository.Save(album); err != nil {
+			return err
+		}
+	}
+
+	return interactor.MediaFileRepository.RescanMediaFiles(paths)
+}
+
+// Writes tags into media files, or only gets the changes if dryRun is true.
+//
+// Stops at the first file that cannot be written.
+func (interactor *LibraryInteractor) writeTags(paths []string, values map[string]string, dryRun bool) (edits []TagEdit, err error) {
+	for _, path := range paths {
+		fileEdits, errWrite := interactor.MediaFileRepository.WriteTags(path, values, dryRun)
+		if errWrite != nil {
+			return edits, errors.New("cannot write tags of " + path + ": " + errWrite.Error())
+		}
+		edits = append(edits, fileEdits...)
+	}
+
+	return
+}
+
+// Deletes the overrides of the given fields of an entity.
+func (interactor *LibraryInteractor) deleteOverrides(entityType string, entityId int, values map[string]string) error {
+	overrides, err := interactor.OverrideRepository.GetForEntity(entityType, entityId)
+	if err != nil {
+		return err
+	}
+
+	for _, override := range overrides {
+		if _, ok := values[override.Field]; ok {
+			if err := interactor.OverrideRepository.Delete(&override); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Converts album field values to track tag values.
+func getAlbumTagValues(values map[string]string) map[string]string {
+	tagValues := make(map[string]string)
+	for field, value := range values {
+		if tagField, ok := albumTagFields[field]; ok {
+			tagValues[tagField] = value
+		}
+	}
+
+	return tagValues
+}
+
+// Checks if some tracks are parts of a media file described by a CUE sheet.
+func hasCueTracks(tracks domain.Tracks) bool {
+	for _, track := range tracks {
+		if track.CueTrack != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Gets the paths of the media files of tracks, sorted and without duplicates.
+func getTrackPaths(tracks domain.Tracks) (paths []string) {
+	known := make(map[string]bool)
+	for _, track := range tracks {
+		if !known[track.Path] {
+			known[track.Path] = true
+			paths = append(paths, track.Path)
+		}
+	}
+	sort.Strings(paths)
+
+	return
+}
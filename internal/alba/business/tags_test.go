@@ -0,0 +1,96 @@
+package business
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TagsTestSuite struct {
+	suite.Suite
+	Library *LibraryInteractor
+}
+
+// Go testing framework entry point.
+func TestTagsTestSuite(t *testing.T) {
+	suite.Run(t, new(TagsTestSuite))
+}
+
+func (suite *TagsTestSuite) SetupTest() {
+	suite.Library = createMockLibraryInteractor()
+}
+
+func (suite *TagsTestSuite) TearDownTest() {
+	viper.Set("Library.WriteTags", false)
+}
+
+func (suite *TagsTestSuite) TestPreviewTrackTags() {
+	edits, err := suite.Library.PreviewTrackTags(1, map[string]string{"title": "Edited title", "bpm": "128"})
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), edits, 2)
+	assert.Equal(suite.T(), "/music/Track 1.mp3", edits[0].Path)
+	assert.Equal(suite.T(), "title", edits[0].Field)
+	assert.Equal(suite.T(), "Edited title", edits[0].Value)
+	assert.Equal(suite.T(), "bpm", edits[1].Field)
+	assert.Empty(suite.T(), suite.Library.MediaFileRepository.(*MediaFileRepositoryMock).Written)
+
+	// Invalid values.
+	_, err = suite.Library.PreviewTrackTags(1, map[string]string{"bpm": "fast"})
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.PreviewTrackTags(99, map[string]string{"title": "Edited title"})
+	assert.NotNil(suite.T(), err)
+
+	// The media files of CUE sheets hold the tags of the whole album.
+	_, err = suite.Library.PreviewTrackTags(10, map[string]string{"title": "Edited title"})
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *TagsTestSuite) TestPreviewAlbumTags() {
+	edits, err := suite.Library.PreviewAlbumTags(1, map[string]string{"title": "Edited album", "year": "2001"})
+	assert.Nil(suite.T(), err)
+	// One edit per field for each of the 3 tracks.
+	assert.Len(suite.T(), edits, 6)
+	assert.Equal(suite.T(), "/music/Album 1/Track 1.mp3", edits[0].Path)
+	assert.Equal(suite.T(), "album", edits[0].Field)
+	assert.Equal(suite.T(), "Edited album", edits[0].Value)
+	assert.Equal(suite.T(), "year", edits[1].Field)
+	assert.Empty(suite.T(), suite.Library.MediaFileRepository.(*MediaFileRepositoryMock).Written)
+
+	// Invalid values.
+	_, err = suite.Library.PreviewAlbumTags(1, map[string]string{"title": ""})
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.PreviewAlbumTags(99, map[string]string{"title": "Edited album"})
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *TagsTestSuite) TestWriteTags() {
+	// Edits are only stored as overrides by default.
+	_, err := suite.Library.UpdateTrack(1, map[string]string{"title": "Edited title"})
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), suite.Library.MediaFileRepository.(*MediaFileRepositoryMock).Written)
+
+	viper.Set("Library.WriteTags", true)
+
+	track, err := suite.Library.UpdateTrack(1, map[string]string{"title": "Edited title"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Edited title", track.Title)
+	assert.Equal(suite.T(), []string{"/music/Track 1.mp3"}, suite.Library.MediaFileRepository.(*MediaFileRepositoryMock).Written)
+
+	album, err := suite.Library.UpdateAlbum(2, map[string]string{"title": "Edited album"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Edited album", album.Title)
+	assert.Len(suite.T(), suite.Library.MediaFileRepository.(*MediaFileRepositoryMock).Written, 4)
+
+	// Edits of the tracks of CUE sheets are not written either.
+	track, err = suite.Library.UpdateTrack(10, map[string]string{"title": "Edited title"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Edited title", track.Title)
+	assert.Len(suite.T(), suite.Library.MediaFileRepository.(*MediaFileRepositoryMock).Written, 4)
+
+	// Artist edits are never written.
+	_, err = suite.Library.UpdateArtist(2, map[string]string{"name": "Edited artist"})
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), suite.Library.MediaFileRepository.(*MediaFileRepositoryMock).Written, 4)
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"strconv"
 	"fmt"
+	"math"
 	"math/rand"
 )
 
@@ -19,10 +20,20 @@ func createMockLibraryInteractor() (*LibraryInteractor) {
 	interactor.ArtistRepository = new(ArtistRepositoryMock)
 	interactor.AlbumRepository = new(AlbumRepositoryMock)
 	interactor.TrackRepository = new(TrackRepositoryMock)
+	interactor.GenreRepository = new(GenreRepositoryMock)
 	interactor.CoverRepository = new(CoverRepositoryMock)
 	interactor.MediaFileRepository = new(MediaFileRepositoryMock)
 	interactor.LibraryRepository = new(LibraryRepositoryMock)
 	interactor.InternalVariableRepository = new(InternalVariableRepositoryMock)
+	interactor.OverrideRepository = new(OverrideRepositoryMock)
+	interactor.ArtistAliasRepository = new(ArtistAliasRepositoryMock)
+	interactor.LyricsRepository = new(LyricsRepositoryMock)
+	interactor.WaveformRepository = new(WaveformRepositoryMock)
+	interactor.ShareRepository = new(ShareRepositoryMock)
+	interactor.ScrobbleAccountRepository = new(ScrobbleAccountRepositoryMock)
+	interactor.ScrobbleRepository = new(ScrobbleRepositoryMock)
+	interactor.PlayQueueRepository = new(PlayQueueRepositoryMock)
+	interactor.BookmarkRepository = new(BookmarkRepositoryMock)
 
 	return interactor
 }
@@ -133,6 +144,15 @@ func (m ArtistRepositoryMock) Exists(id int) bool {
 
 func (m ArtistRepositoryMock) CleanUp() error {return nil}
 
+// Returns artists for genreId 1, else no artists.
+func (m *ArtistRepositoryMock) GetArtistsForGenre(genreId int) (entities domain.Artists, err error) {
+	if genreId == 1 {
+		entities, _ = m.GetAll(false)
+	}
+
+	return
+}
+
 
 /* Mock for album repository. */
 
@@ -251,11 +271,31 @@ func (m AlbumRepositoryMock) Exists(id int) bool {
 
 func (m AlbumRepositoryMock) CleanUp() error {return nil}
 
+// Returns albums for artistId 1, else no albums.
+func (m *AlbumRepositoryMock) GetAlbumsArtistAppearsOn(artistId int) (entities domain.Albums, err error) {
+	if artistId == 1 {
+		entities, _ = m.GetAll(false)
+	}
+
+	return
+}
+
+// Returns albums for genreId 1, else no albums.
+func (m *AlbumRepositoryMock) GetAlbumsForGenre(genreId int) (entities domain.Albums, err error) {
+	if genreId == 1 {
+		entities, _ = m.GetAll(false)
+	}
+
+	return
+}
+
 
 /* Mock for track repository. */
 
 type TrackRepositoryMock struct{
 	mock.Mock
+	Saved domain.Tracks
+	LoudnessSaved domain.Tracks
 }
 
 // Returns a valid response for any id inferior or equals to 10, else an error.
@@ -265,6 +305,13 @@ func (m *TrackRepositoryMock) Get(id int) (entity domain.Track, err error) {
 		entity.Id = id
 		entity.Title = "Track #" + strconv.Itoa(id)
 		entity.Path = fmt.Sprintf("/music/Track %v.mp3", id)
+		entity.Duration = 200
+		// Track 10 is the second track of a CUE sheet.
+		if id == 10 {
+			entity.Path = "/music/Album.flac"
+			entity.CueTrack = 2
+			entity.StartMs = 180000
+		}
 
 		return
 	}
@@ -322,7 +369,8 @@ func (m *TrackRepositoryMock) GetTracksForAlbum(albumId int) (entities domain.Tr
 // Never fails.
 func (m *TrackRepositoryMock) Save(entity *domain.Track) (err error) {
 	if entity.Id != 0 {
-		// This is an update, do nothing.
+		// This is an update, keep the saved track.
+		m.Saved = append(m.Saved, *entity)
 		return
 	}
 
@@ -331,6 +379,12 @@ func (m *TrackRepositoryMock) Save(entity *domain.Track) (err error) {
 	return
 }
 
+// Never fails, keeps the saved track.
+func (m *TrackRepositoryMock) SaveLoudness(entity *domain.Track) (err error) {
+	m.LoudnessSaved = append(m.LoudnessSaved, *entity)
+	return
+}
+
 // Never fails.
 func (m *TrackRepositoryMock) Delete(entity *domain.Track) (err error) {
 	return
@@ -341,6 +395,119 @@ func (m TrackRepositoryMock) Exists(id int) bool {
 	return id == 1
 }
 
+// Returns tracks for artistId 1, else no tracks.
+func (m *TrackRepositoryMock) GetTracksForArtist(artistId int, role string) (entities domain.Tracks, err error) {
+	if artistId == 1 {
+		entities, _ = m.GetAll()
+
+		for idx := range entities {
+			entities[idx].ArtistId = 1
+		}
+	}
+
+	return
+}
+
+// Returns the main artist for trackId 1, else no artists.
+func (m *TrackRepositoryMock) GetTrackArtists(trackId int) (entities domain.TrackArtists, err error) {
+	if trackId == 1 {
+		entities = append(entities, domain.TrackArtist{TrackId: 1, ArtistId: 1, Role: domain.ArtistRoleMain})
+	}
+
+	return
+}
+
+// Returns tracks for genreId 1, else no tracks.
+func (m *TrackRepositoryMock) GetTracksForGenre(genreId int) (entities domain.Tracks, err error) {
+	if genreId == 1 {
+		entities, _ = m.GetAll()
+	}
+
+	return
+}
+
+// Returns tracks for genreId 1 whatever the other criteria, else no tracks.
+func (m *TrackRepositoryMock) FindTracks(filter TrackFilter) (entities domain.Tracks, err error) {
+	return m.GetTracksForGenre(filter.GenreId)
+}
+
+
+/* Mock for genre repository. */
+
+type GenreRepositoryMock struct{
+	mock.Mock
+}
+
+// Returns a valid response for any id inferior or equals to 10, else an error.
+func (m *GenreRepositoryMock) Get(id int) (entity domain.Genre, err error) {
+	if id <= 10 {
+		entity.Id = id
+		entity.Name = "Genre #" + strconv.Itoa(id)
+
+		return
+	}
+
+	// Else return an error.
+	err = errors.New("not found")
+	return
+}
+
+// Returns 3 genres.
+func (m *GenreRepositoryMock) GetAll() (entities domain.Genres, err error) {
+	for i := 1; i < 4; i++ {
+		entities = append(entities, domain.Genre{Id: i, Name: "Genre #" + strconv.Itoa(i)})
+	}
+
+	return
+}
+
+// Returns a valid respones only for name "Genre #1".
+func (m *GenreRepositoryMock) GetByName(name string) (entity domain.Genre, err error) {
+	if name == "Genre #1" {
+		entity.Id = 1
+		entity.Name = name
+
+		return
+	}
+
+	// Else return an error.
+	err = errors.New("not found")
+	return
+}
+
+// Returns genres for trackId 1, else no genres.
+func (m *GenreRepositoryMock) GetGenresForTrack(trackId int) (entities domain.Genres, err error) {
+	if trackId == 1 {
+		entities, _ = m.GetAll()
+	}
+
+	return
+}
+
+// Never fails.
+func (m *GenreRepositoryMock) Save(entity *domain.Genre) (err error) {
+	if entity.Id != 0 {
+		// This is an update, do nothing.
+		return
+	}
+
+	// Else this is a new entity, fill the Id.
+	entity.Id = rand.Intn(50)
+	return
+}
+
+// Never fails.
+func (m *GenreRepositoryMock) Delete(entity *domain.Genre) (err error) {
+	return
+}
+
+// Returns true if id == 1, else false.
+func (m *GenreRepositoryMock) Exists(id int) bool {
+	return id == 1
+}
+
+func (m *GenreRepositoryMock) CleanUp() error {return nil}
+
 
 /*
 Mock for cover repository.
@@ -401,12 +568,55 @@ Mock for media file repository.
 */
 type MediaFileRepositoryMock struct{
 	mock.Mock
+	Written []string
 }
 
 func (m *MediaFileRepositoryMock) ScanMediaFiles(path string) (int, int, error) { return 0, 0, nil }
 func (m *MediaFileRepositoryMock) WriteCoverFile(file *domain.Cover, directory string) error { return nil }
 func (m *MediaFileRepositoryMock) RemoveCoverFile(file *domain.Cover, directory string) error { return nil }
 func (m *MediaFileRepositoryMock) DeleteCovers() error { return nil }
+func (m *MediaFileRepositoryMock) RescanMediaFiles(filepaths []string) error { return nil }
+
+// Returns one edit per value, in the order of TrackTagFields, and keeps the written files paths.
+func (m *MediaFileRepositoryMock) WriteTags(filepath string, values map[string]string, dryRun bool) (edits []TagEdit, err error) {
+	for _, field := range TrackTagFields {
+		if value, ok := values[field]; ok {
+			edits = append(edits, TagEdit{Path: filepath, Field: field, Value: value})
+		}
+	}
+	if !dryRun {
+		m.Written = append(m.Written, filepath)
+	}
+
+	return
+}
+
+// Decodes 2 seconds of a 1 kHz stereo sine at 48 kHz for the 2 first tracks returned by trackRepoMock getAll(), at
+// full scale for the first one and at half scale for the second one, else returns an error.
+func (m *MediaFileRepositoryMock) DecodeAudio(filepath string, startMs int, endMs int, write func(sampleRate int, samples [][]float64) error) error {
+	amplitude := 0.0
+	switch filepath {
+	case "/music/Track 1.mp3":
+		amplitude = 1
+	case "/music/Track 2.mp3":
+		amplitude = 0.5
+	default:
+		return errors.New("cannot decode")
+	}
+
+	for chunk := 0; chunk < 20; chunk++ {
+		samples := [][]float64{make([]float64, 4800), make([]float64, 4800)}
+		for i := range samples[0] {
+			samples[0][i] = amplitude * math.Sin(2*math.Pi*1000*float64(chunk*4800+i)/48000)
+			samples[1][i] = samples[0][i]
+		}
+		if err := write(48000, samples); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
 // Returns false except for paths of the 2 first tracks returned by trackRepoMock getAll().
 func (m *MediaFileRepositoryMock) MediaFileExists(filepath string) bool {
@@ -425,10 +635,463 @@ Mock for internal variable repository.
 
 type InternalVariableRepositoryMock struct{
 	mock.Mock
+	Variables map[string]InternalVariable // Saved variables.
+}
+
+// Returns the saved variables, else an error.
+func (m *InternalVariableRepositoryMock) Get(key string) (variable InternalVariable, err error) {
+	variable, ok := m.Variables[key]
+	if !ok {
+		err = errors.New("no variable found for this key")
+	}
+	return
+}
+
+func (m *InternalVariableRepositoryMock) Save(variable *InternalVariable) (err error) {
+	if m.Variables == nil {
+		m.Variables = make(map[string]InternalVariable)
+	}
+	m.Variables[variable.Key] = *variable
+	return
 }
 
 // Not needed.
-func (m *InternalVariableRepositoryMock) Get(key string) (variable InternalVariable, err error) {return}
-func (m *InternalVariableRepositoryMock) Save(variable *InternalVariable) (err error) {return}
 func (m *InternalVariableRepositoryMock) Delete(variable *InternalVariable) (err error) {return}
 func (m *InternalVariableRepositoryMock) Exists(key string) bool {return true}
+
+/*
+Mock for override repository.
+*/
+type OverrideRepositoryMock struct{
+	mock.Mock
+}
+
+// Returns an override of the name of artist #1 for id 1, else an error.
+func (m *OverrideRepositoryMock) Get(id int) (entity domain.Override, err error) {
+	if id == 1 {
+		entity = domain.Override{
+			Id: 1,
+			EntityType: domain.OverrideEntityArtist,
+			EntityId: 1,
+			Field: "name",
+			Value: "Edited artist",
+			OriginalValue: "Artist #1",
+		}
+		return
+	}
+
+	err = errors.New("not found")
+	return
+}
+
+func (m *OverrideRepositoryMock) GetAll(entityType string) (entities domain.Overrides, err error) {
+	override, _ := m.Get(1)
+	if entityType == "" || entityType == override.EntityType {
+		entities = append(entities, override)
+	}
+
+	return
+}
+
+// Artist #1 has an edited name.
+func (m *OverrideRepositoryMock) GetForEntity(entityType string, entityId int) (entities domain.Overrides, err error) {
+	if entityType == domain.OverrideEntityArtist && entityId == 1 {
+		override, _ := m.Get(1)
+		entities = append(entities, override)
+	}
+
+	return
+}
+
+func (m *OverrideRepositoryMock) Save(entity *domain.Override) (err error) {
+	if entity.Id == 0 {
+		entity.Id = rand.Intn(50) + 2
+	}
+
+	return
+}
+
+func (m *OverrideRepositoryMock) Delete(entity *domain.Override) (err error) {return}
+func (m *OverrideRepositoryMock) CleanUp() error {return nil}
+
+/*
+Mock for artist alias repository.
+*/
+type ArtistAliasRepositoryMock struct{
+	mock.Mock
+	Merged []int
+}
+
+// Returns an alias of artist #2 for id 1, else an error.
+func (m *ArtistAliasRepositoryMock) Get(id int) (entity domain.ArtistAlias, err error) {
+	if id == 1 {
+		entity = domain.ArtistAlias{
+			Id: 1,
+			ArtistId: 2,
+			Name: "Artist #3",
+			MatchKey: MatchKey("Artist #3"),
+			SourceArtistId: 3,
+		}
+		return
+	}
+
+	err = errors.New("not found")
+	return
+}
+
+func (m *ArtistAliasRepositoryMock) GetAll(artistId int) (entities domain.ArtistAliases, err error) {
+	alias, _ := m.Get(1)
+	if artistId == 0 || artistId == alias.ArtistId {
+		entities = append(entities, alias)
+	}
+
+	return
+}
+
+// Keeps the merged artists ids.
+func (m *ArtistAliasRepositoryMock) Merge(sourceIds []int, targetId int) (entities domain.ArtistAliases, err error) {
+	for _, sourceId := range sourceIds {
+		m.Merged = append(m.Merged, sourceId)
+		entities = append(entities, domain.ArtistAlias{ArtistId: targetId, SourceArtistId: sourceId})
+	}
+
+	return
+}
+
+func (m *ArtistAliasRepositoryMock) Unmerge(entity *domain.ArtistAlias) (artist domain.Artist, err error) {
+	artist = domain.Artist{Id: entity.SourceArtistId, Name: entity.Name}
+	return
+}
+
+func (m *ArtistAliasRepositoryMock) CleanUp() error {return nil}
+
+/*
+Mock for lyrics repository.
+*/
+type LyricsRepositoryMock struct{
+	mock.Mock
+}
+
+// Returns lyrics for track #1, else an error.
+func (m *LyricsRepositoryMock) GetForTrack(trackId int) (entity domain.Lyrics, err error) {
+	if trackId == 1 {
+		entity = domain.Lyrics{
+			Id: 1,
+			TrackId: 1,
+			Text: "First line\nSecond line",
+			Synced: "[00:01.00]First line\n[00:02.50]Second line",
+		}
+		return
+	}
+
+	err = errors.New("not found")
+	return
+}
+
+func (m *LyricsRepositoryMock) CleanUp() error {return nil}
+
+/*
+Mock for waveform repository.
+*/
+type WaveformRepositoryMock struct{
+	mock.Mock
+	Saved map[string]Waveform
+}
+
+// Returns the waveforms saved for the track path, else an error.
+func (m *WaveformRepositoryMock) Get(track domain.Track, directory string) (waveform Waveform, err error) {
+	waveform, ok := m.Saved[track.Path]
+	if !ok {
+		err = errors.New("not found")
+	}
+	return
+}
+
+func (m *WaveformRepositoryMock) Save(track domain.Track, waveform Waveform, directory string) error {
+	if m.Saved == nil {
+		m.Saved = make(map[string]Waveform)
+	}
+	m.Saved[track.Path] = waveform
+	return nil
+}
+
+/*
+Mock for share repository.
+*/
+type ShareRepositoryMock struct{
+	mock.Mock
+	Shares domain.Shares // Saved shares.
+}
+
+// Returns the saved shares, else an error.
+func (m *ShareRepositoryMock) Get(id int) (entity domain.Share, err error) {
+	for _, share := range m.Shares {
+		if share.Id == id {
+			return share, nil
+		}
+	}
+	err = errors.New("not found")
+	return
+}
+
+// Returns the saved shares, else an error.
+func (m *ShareRepositoryMock) GetBySlug(slug string) (entity domain.Share, err error) {
+	for _, share := range m.Shares {
+		if share.Slug == slug {
+			return share, nil
+		}
+	}
+	err = errors.New("not found")
+	return
+}
+
+func (m *ShareRepositoryMock) GetAll() (entities domain.Shares, err error) {return m.Shares, nil}
+
+// Never fails.
+func (m *ShareRepositoryMock) Save(entity *domain.Share) (err error) {
+	if entity.Id == 0 {
+		entity.Id = len(m.Shares) + 1
+		m.Shares = append(m.Shares, *entity)
+		return
+	}
+	for i := range m.Shares {
+		if m.Shares[i].Id == entity.Id {
+			m.Shares[i] = *entity
+		}
+	}
+	return
+}
+
+func (m *ShareRepositoryMock) Delete(entity *domain.Share) (err error) {
+	for i := range m.Shares {
+		if m.Shares[i].Id == entity.Id {
+			m.Shares = append(m.Shares[:i], m.Shares[i+1:]...)
+			return
+		}
+	}
+	return
+}
+
+// Counts the plays of the saved shares.
+func (m *ShareRepositoryMock) AddPlay(id int) (bool, error) {
+	for i := range m.Shares {
+		if m.Shares[i].Id == id {
+			if m.Shares[i].MaxPlays != 0 && m.Shares[i].Plays >= m.Shares[i].MaxPlays {
+				return false, nil
+			}
+			m.Shares[i].Plays++
+			return true, nil
+		}
+	}
+	return false, errors.New("not found")
+}
+
+func (m *ShareRepositoryMock) CleanUp() error {return nil}
+
+type ScrobbleAccountRepositoryMock struct{
+	mock.Mock
+	Accounts domain.ScrobbleAccounts // Saved accounts.
+}
+
+// Returns the saved accounts, else an error.
+func (m *ScrobbleAccountRepositoryMock) Get(id int) (entity domain.ScrobbleAccount, err error) {
+	for _, account := range m.Accounts {
+		if account.Id == id {
+			return account, nil
+		}
+	}
+	err = errors.New("not found")
+	return
+}
+
+// Returns the saved accounts, else an error.
+func (m *ScrobbleAccountRepositoryMock) GetByName(service string, userName string) (entity domain.ScrobbleAccount, err error) {
+	for _, account := range m.Accounts {
+		if account.Service == service && account.UserName == userName {
+			return account, nil
+		}
+	}
+	err = errors.New("not found")
+	return
+}
+
+func (m *ScrobbleAccountRepositoryMock) GetAll() (entities domain.ScrobbleAccounts, err error) {return m.Accounts, nil}
+
+// Never fails.
+func (m *ScrobbleAccountRepositoryMock) Save(entity *domain.ScrobbleAccount) (err error) {
+	if entity.Id == 0 {
+		entity.Id = len(m.Accounts) + 1
+		m.Accounts = append(m.Accounts, *entity)
+		return
+	}
+	for i := range m.Accounts {
+		if m.Accounts[i].Id == entity.Id {
+			m.Accounts[i] = *entity
+		}
+	}
+	return
+}
+
+func (m *ScrobbleAccountRepositoryMock) Delete(entity *domain.ScrobbleAccount) (err error) {
+	for i := range m.Accounts {
+		if m.Accounts[i].Id == entity.Id {
+			m.Accounts = append(m.Accounts[:i], m.Accounts[i+1:]...)
+			return
+		}
+	}
+	return
+}
+
+type ScrobbleRepositoryMock struct{
+	mock.Mock
+	Scrobbles domain.Scrobbles // Queued scrobbles.
+	lastId int
+}
+
+func (m *ScrobbleRepositoryMock) GetAll() (entities domain.Scrobbles, err error) {
+	return append(domain.Scrobbles{}, m.Scrobbles...), nil
+}
+
+// Never fails.
+func (m *ScrobbleRepositoryMock) Save(entity *domain.Scrobble) (err error) {
+	if entity.Id == 0 {
+		m.lastId++
+		entity.Id = m.lastId
+		m.Scrobbles = append(m.Scrobbles, *entity)
+		return
+	}
+	for i := range m.Scrobbles {
+		if m.Scrobbles[i].Id == entity.Id {
+			m.Scrobbles[i] = *entity
+		}
+	}
+	return
+}
+
+func (m *ScrobbleRepositoryMock) Delete(entity *domain.Scrobble) (err error) {
+	for i := range m.Scrobbles {
+		if m.Scrobbles[i].Id == entity.Id {
+			m.Scrobbles = append(m.Scrobbles[:i], m.Scrobbles[i+1:]...)
+			return
+		}
+	}
+	return
+}
+
+// Does nothing: Delete() of ScrobbleAccountRepositoryMock doesn't know about the scrobbles.
+func (m *ScrobbleRepositoryMock) CleanUp() error {return nil}
+
+// Scrobbler recording the submissions.
+type ScrobblerMock struct{
+	Errors    []error // Errors returned by the next submissions, nil for success.
+	Playing   domain.Scrobbles // Tracks sent as now playing.
+	Scrobbles domain.Scrobbles // Successful submissions.
+}
+
+// Links an account named after the token, or fails for an empty token.
+func (m *ScrobblerMock) LinkAccount(token string) (domain.ScrobbleAccount, error) {
+	if token == "" {
+		return domain.ScrobbleAccount{}, errors.New("invalid token")
+	}
+	return domain.ScrobbleAccount{UserName: "User " + token, Token: "session " + token}, nil
+}
+
+func (m *ScrobblerMock) NowPlaying(account domain.ScrobbleAccount, scrobble domain.Scrobble) error {
+	m.Playing = append(m.Playing, scrobble)
+	return nil
+}
+
+func (m *ScrobblerMock) Scrobble(account domain.ScrobbleAccount, scrobble domain.Scrobble) error {
+	if len(m.Errors) > 0 {
+		err := m.Errors[0]
+		m.Errors = m.Errors[1:]
+		if err != nil {
+			return err
+		}
+	}
+	m.Scrobbles = append(m.Scrobbles, scrobble)
+	return nil
+}
+
+type PlayQueueRepositoryMock struct{
+	mock.Mock
+	Queues map[string]domain.PlayQueue // Saved queues, by user.
+}
+
+// Returns the saved queues, else an error.
+func (m *PlayQueueRepositoryMock) Get(user string) (entity domain.PlayQueue, err error) {
+	if queue, ok := m.Queues[user]; ok {
+		return queue, nil
+	}
+	err = errors.New("not found")
+	return
+}
+
+// Never fails.
+func (m *PlayQueueRepositoryMock) Save(entity *domain.PlayQueue) (err error) {
+	if m.Queues == nil {
+		m.Queues = make(map[string]domain.PlayQueue)
+	}
+	if entity.Id == 0 {
+		entity.Id = len(m.Queues) + 1
+	}
+	m.Queues[entity.User] = *entity
+	return
+}
+
+/*
+Mock for bookmark repository.
+*/
+type BookmarkRepositoryMock struct{
+	mock.Mock
+	Saved domain.Bookmarks
+	lastId int
+}
+
+// Returns the saved bookmarks, else an error.
+func (m *BookmarkRepositoryMock) Get(user string, trackId int) (entity domain.Bookmark, err error) {
+	for _, bookmark := range m.Saved {
+		if bookmark.User == user && bookmark.TrackId == trackId {
+			return bookmark, nil
+		}
+	}
+	err = errors.New("not found")
+	return
+}
+
+// Returns the saved bookmarks of the user.
+func (m *BookmarkRepositoryMock) GetAll(user string) (entities domain.Bookmarks, err error) {
+	for _, bookmark := range m.Saved {
+		if bookmark.User == user {
+			entities = append(entities, bookmark)
+		}
+	}
+	return
+}
+
+// Never fails.
+func (m *BookmarkRepositoryMock) Save(entity *domain.Bookmark) (err error) {
+	for i, bookmark := range m.Saved {
+		if bookmark.Id == entity.Id {
+			m.Saved[i] = *entity
+			return
+		}
+	}
+	m.lastId++
+	entity.Id = m.lastId
+	m.Saved = append(m.Saved, *entity)
+	return
+}
+
+// Never fails.
+func (m *BookmarkRepositoryMock) Delete(entity *domain.Bookmark) (err error) {
+	for i, bookmark := range m.Saved {
+		if bookmark.Id == entity.Id {
+			m.Saved = append(m.Saved[:i], m.Saved[i+1:]...)
+			return
+		}
+	}
+	return
+}
+
+func (m *BookmarkRepositoryMock) CleanUp() error {return nil}
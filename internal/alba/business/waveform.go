@@ -0,0 +1,156 @@
+package business
+
+import (
+	"math"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/spf13/viper"
+)
+
+/*
+This file exposes the waveforms of the tracks, used by the clients to draw seekbars.
+
+A waveform is made of the minimum and maximum sample values of each group of samples of a track, all channels mixed.
+Waveforms are computed at a high resolution and cached, then resized to the number of points requested.
+*/
+
+// Number of samples per point of the computed waveforms.
+const WaveformSamplesPerPoint = 256
+
+// Waveform of a track, samples being 16 bits values.
+type Waveform struct {
+	SampleRate      int
+	SamplesPerPoint int
+	Min             []int16
+	Max             []int16
+}
+
+/*
+Resizes a waveform to a number of points at most, keeping the peaks.
+
+Points are merged by whole groups, so the resized waveform can have a few points less than requested. Waveforms
+having less points than requested are returned as is.
+*/
+func (waveform Waveform) Resize(points int) Waveform {
+	if points <= 0 || len(waveform.Min) <= points {
+		return waveform
+	}
+
+	group := (len(waveform.Min) + points - 1) / points
+	length := (len(waveform.Min) + group - 1) / group
+	resized := Waveform{
+		SampleRate:      waveform.SampleRate,
+		SamplesPerPoint: waveform.SamplesPerPoint * group,
+		Min:             make([]int16, length),
+		Max:             make([]int16, length),
+	}
+	for i := 0; i < length; i++ {
+		resized.Min[i], resized.Max[i] = math.MaxInt16, math.MinInt16
+		for j := i * group; j < (i+1)*group && j < len(waveform.Min); j++ {
+			if waveform.Min[j] < resized.Min[i] {
+				resized.Min[i] = waveform.Min[j]
+			}
+			if waveform.Max[j] > resized.Max[i] {
+				resized.Max[i] = waveform.Max[j]
+			}
+		}
+	}
+
+	return resized
+}
+
+/*
+Gets the waveform of a track with a number of points at most.
+
+The waveform is computed if it isn't cached yet, which requires decoding the whole track.
+*/
+func (interactor *LibraryInteractor) GetWaveform(trackId int, points int) (Waveform, error) {
+	track, err := interactor.TrackRepository.Get(trackId)
+	if err != nil {
+		return Waveform{}, err
+	}
+
+	waveform, err := interactor.getTrackWaveform(track)
+	if err != nil {
+		return Waveform{}, err
+	}
+
+	return waveform.Resize(points), nil
+}
+
+/*
+Computes the waveforms of the tracks which aren't cached yet.
+
+Done after the library updates if Waveforms.Precompute is enabled. Tracks which cannot be decoded are skipped.
+*/
+func (interactor *LibraryInteractor) PrecomputeWaveforms() {
+	tracks, err := interactor.TrackRepository.GetAll()
+	if err != nil {
+		return
+	}
+
+	for _, track := range tracks {
+		_, _ = interactor.getTrackWaveform(track)
+	}
+}
+
+// Gets the waveform of a track from the cache, or computes and caches it.
+func (interactor *LibraryInteractor) getTrackWaveform(track domain.Track) (Waveform, error) {
+	directory := viper.GetString("Waveforms.Directory")
+	if waveform, err := interactor.WaveformRepository.Get(track, directory); err == nil {
+		return waveform, nil
+	}
+
+	waveform, err := interactor.computeWaveform(track)
+	if err != nil {
+		return Waveform{}, err
+	}
+	// The waveform can still be used if it can't be cached.
+	_ = interactor.WaveformRepository.Save(track, waveform, directory)
+
+	return waveform, nil
+}
+
+// Decodes the audio of a track to compute its waveform.
+func (interactor *LibraryInteractor) computeWaveform(track domain.Track) (Waveform, error) {
+	waveform := Waveform{SamplesPerPoint: WaveformSamplesPerPoint}
+	count := 0
+	var min, max float64
+	err := interactor.MediaFileRepository.DecodeAudio(track.Path, track.StartMs, track.EndMs, func(sampleRate int, samples [][]float64) error {
+		waveform.SampleRate = sampleRate
+		for i := range samples[0] {
+			for channel := range samples {
+				if count == 0 && channel == 0 {
+					min, max = samples[channel][i], samples[channel][i]
+				}
+				min = math.Min(min, samples[channel][i])
+				max = math.Max(max, samples[channel][i])
+			}
+
+			count++
+			if count == WaveformSamplesPerPoint {
+				waveform.Min = append(waveform.Min, waveformSample(min))
+				waveform.Max = append(waveform.Max, waveformSample(max))
+				count = 0
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return Waveform{}, err
+	}
+
+	// Last incomplete group.
+	if count > 0 {
+		waveform.Min = append(waveform.Min, waveformSample(min))
+		waveform.Max = append(waveform.Max, waveformSample(max))
+	}
+
+	return waveform, nil
+}
+
+// Converts a sample normalized to [-1, 1] to a 16 bits value.
+func waveformSample(sample float64) int16 {
+	return int16(math.Round(math.Max(-1, math.Min(1, sample)) * math.MaxInt16))
+}
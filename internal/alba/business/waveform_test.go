@@ -0,0 +1,83 @@
+package business
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WaveformTestSuite struct {
+	suite.Suite
+	Library *LibraryInteractor
+}
+
+// Go testing framework entry point.
+func TestWaveformTestSuite(t *testing.T) {
+	suite.Run(t, new(WaveformTestSuite))
+}
+
+func (suite *WaveformTestSuite) SetupTest() {
+	suite.Library = createMockLibraryInteractor()
+}
+
+func (suite *WaveformTestSuite) TestResize() {
+	waveform := Waveform{
+		SampleRate:      44100,
+		SamplesPerPoint: 256,
+		Min:             []int16{-1, -5, -2, -3, -4},
+		Max:             []int16{1, 2, 6, 3, 4},
+	}
+
+	resized := waveform.Resize(2)
+	assert.Equal(suite.T(), Waveform{SampleRate: 44100, SamplesPerPoint: 768, Min: []int16{-5, -4}, Max: []int16{6, 4}}, resized)
+
+	// Groups of 2 points leave 3 points.
+	resized = waveform.Resize(4)
+	assert.Equal(suite.T(), 512, resized.SamplesPerPoint)
+	assert.Equal(suite.T(), []int16{-5, -3, -4}, resized.Min)
+	assert.Equal(suite.T(), []int16{2, 6, 4}, resized.Max)
+
+	// Nothing to resize.
+	assert.Equal(suite.T(), waveform, waveform.Resize(5))
+	assert.Equal(suite.T(), waveform, waveform.Resize(0))
+}
+
+func (suite *WaveformTestSuite) TestGetWaveform() {
+	// 2 seconds at 48 kHz make 375 points of 256 samples.
+	waveform, err := suite.Library.GetWaveform(1, 10000)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 48000, waveform.SampleRate)
+	assert.Equal(suite.T(), WaveformSamplesPerPoint, waveform.SamplesPerPoint)
+	assert.Len(suite.T(), waveform.Min, 375)
+	assert.Len(suite.T(), waveform.Max, 375)
+	// Full scale sine.
+	assert.Equal(suite.T(), int16(math.MaxInt16), waveform.Max[10])
+	assert.Equal(suite.T(), int16(-math.MaxInt16), waveform.Min[10])
+
+	// The waveform has been cached.
+	cache := suite.Library.WaveformRepository.(*WaveformRepositoryMock)
+	assert.Contains(suite.T(), cache.Saved, "/music/Track 1.mp3")
+	waveform, err = suite.Library.GetWaveform(1, 100)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), waveform.Min, 94)
+	assert.Equal(suite.T(), 4*WaveformSamplesPerPoint, waveform.SamplesPerPoint)
+	cache.Saved["/music/Track 1.mp3"] = Waveform{SampleRate: 44100, SamplesPerPoint: 256, Min: []int16{-1}, Max: []int16{1}}
+	waveform, _ = suite.Library.GetWaveform(1, 100)
+	assert.Equal(suite.T(), []int16{1}, waveform.Max)
+
+	// Tracks which can't be decoded.
+	_, err = suite.Library.GetWaveform(3, 100)
+	assert.NotNil(suite.T(), err)
+	_, err = suite.Library.GetWaveform(42, 100)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *WaveformTestSuite) TestPrecomputeWaveforms() {
+	suite.Library.PrecomputeWaveforms()
+
+	cache := suite.Library.WaveformRepository.(*WaveformRepositoryMock)
+	assert.Len(suite.T(), cache.Saved, 2)
+	assert.Contains(suite.T(), cache.Saved, "/music/Track 2.mp3")
+}
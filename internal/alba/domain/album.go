@@ -1,13 +1,18 @@
 package domain
 
 type Album struct {
-	Id        int    `db:"id"`
-	Title     string `db:"title"` // Mandatory.
-	Year      string `db:"year"`
-	ArtistId  int    `db:"artist_id"`
-	CoverId   int    `db:"cover_id"`
-	DateAdded int64  `db:"created_at"`
-	Tracks    Tracks `db:"-"`
+	Id                 int    `db:"id"`
+	Title              string `db:"title"`         // Mandatory.
+	SortName           string `db:"sort_name"`     // Title used to sort the albums.
+	MatchKey           string `db:"match_key"`     // Normalised title used to find the album, see business.MatchKey().
+	Year               string `db:"year"`          // "1994", or "1994–2001" if the tracks have been released in different years.
+	Date               string `db:"date"`          // Latest release date of the tracks, see business.NormalizeDate().
+	OriginalDate       string `db:"original_date"` // Earliest original release date of the tracks.
+	ArtistId           int    `db:"artist_id"`
+	CoverId            int    `db:"cover_id"`
+	MusicBrainzAlbumId string `db:"musicbrainz_album_id"`
+	DateAdded          int64  `db:"created_at"`
+	Tracks             Tracks `db:"-"`
 }
 
 type Albums []Album
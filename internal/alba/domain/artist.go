@@ -3,6 +3,8 @@ package domain
 type Artist struct {
 	Id   	  int     `db:"id"`
 	Name 	  string  `db:"name"` // Mandatory.
+	SortName  string  `db:"sort_name"` // Name used to sort and index the artists, like "Beatles, The".
+	MatchKey  string  `db:"match_key"` // Normalised name used to find the artist, see business.MatchKey().
 	DateAdded int64   `db:"created_at"`
 	Albums 	  Albums  `db:"-"`
 }
@@ -0,0 +1,14 @@
+package domain
+
+// Other name of an artist, recorded when an artist is merged into another one.
+type ArtistAlias struct {
+	Id             int    `db:"id"`
+	ArtistId       int    `db:"artist_id"` // Canonical artist.
+	Name           string `db:"name"`
+	SortName       string `db:"sort_name"`
+	MatchKey       string `db:"match_key"`        // Normalised name used to find the canonical artist, see business.MatchKey().
+	SourceArtistId int    `db:"source_artist_id"` // ID of the merged artist, restored when the merge is undone.
+	DateAdded      int64  `db:"created_at"`
+}
+
+type ArtistAliases []ArtistAlias
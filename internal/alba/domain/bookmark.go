@@ -0,0 +1,13 @@
+package domain
+
+// Playback position of a user in a long track, so its playback can be resumed.
+type Bookmark struct {
+	Id           int    `db:"id"`
+	User         string `db:"user_name"` // Empty for the anonymous user.
+	TrackId      int    `db:"track_id"`
+	Position     int    `db:"position"` // Playback position in the track, in milliseconds.
+	DateAdded    int64  `db:"created_at"`
+	DateModified int64  `db:"updated_at"`
+}
+
+type Bookmarks []Bookmark
@@ -0,0 +1,11 @@
+package domain
+
+type Genre struct {
+	Id          int    `db:"id"`
+	Name        string `db:"name"` // Mandatory.
+	DateAdded   int64  `db:"created_at"`
+	TracksCount int    `db:"-"`
+	AlbumsCount int    `db:"-"`
+}
+
+type Genres []Genre
@@ -0,0 +1,16 @@
+package domain
+
+// Lyrics of a track, read from its tags or from the .lrc / .txt files next to it.
+type Lyrics struct {
+	Id        int    `db:"id"`
+	TrackId   int    `db:"track_id"`
+	Text      string `db:"text"`
+	Synced    string `db:"synced"` // Time-synced lyrics in the LRC format, see business.ParseLrc().
+	DateAdded int64  `db:"created_at"`
+}
+
+// Line of time-synced lyrics.
+type LyricsLine struct {
+	TimeMs int // Time the line starts at, in milliseconds from the beginning of the track.
+	Line   string
+}
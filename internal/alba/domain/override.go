@@ -0,0 +1,21 @@
+package domain
+
+// Types of the entities that can be edited manually.
+const (
+	OverrideEntityArtist = "artist"
+	OverrideEntityAlbum  = "album"
+	OverrideEntityTrack  = "track"
+)
+
+// Manual edit of an entity field, applied over the metadata read from the media files.
+type Override struct {
+	Id            int    `db:"id"`
+	EntityType    string `db:"entity_type"`
+	EntityId      int    `db:"entity_id"`
+	Field         string `db:"field"` // Name of the field in the GraphQL API, like "sortName".
+	Value         string `db:"value"`
+	OriginalValue string `db:"original_value"` // Value of the field before it has been edited for the first time.
+	DateAdded     int64  `db:"created_at"`
+}
+
+type Overrides []Override
@@ -0,0 +1,12 @@
+package domain
+
+// Play queue of a user, saved by a client so the playback can be resumed on another one.
+type PlayQueue struct {
+	Id           int    `db:"id"`
+	User         string `db:"user_name"`     // Empty for the anonymous user.
+	CurrentIndex int    `db:"current_index"` // Index of the track being played in TrackIds.
+	Position     int    `db:"position"`      // Playback position in the current track, in milliseconds.
+	ClientId     string `db:"client_id"`     // Client which saved the queue.
+	DateModified int64  `db:"updated_at"`
+	TrackIds     []int  `db:"-"`
+}
@@ -0,0 +1,37 @@
+package domain
+
+// Scrobbling services.
+const (
+	ScrobbleServiceLastFm       = "lastfm"
+	ScrobbleServiceListenBrainz = "listenbrainz"
+)
+
+// Account of a scrobbling service, to which the plays are sent.
+type ScrobbleAccount struct {
+	Id        int    `db:"id"`
+	Service   string `db:"service"`
+	UserName  string `db:"user_name"`
+	Token     string `db:"token"` // Last.fm session key or ListenBrainz user token, never given to the clients.
+	DateAdded int64  `db:"created_at"`
+}
+
+type ScrobbleAccounts []ScrobbleAccount
+
+// Play of a track waiting to be sent to an account. The track metadata is copied, as the track can change meanwhile.
+type Scrobble struct {
+	Id                 int    `db:"id"`
+	AccountId          int    `db:"account_id"`
+	Artist             string `db:"artist"`
+	Title              string `db:"title"`
+	Album              string `db:"album"`
+	AlbumArtist        string `db:"album_artist"`
+	Number             int    `db:"number"`
+	Duration           int    `db:"duration"` // In seconds.
+	MusicBrainzTrackId string `db:"musicbrainz_track_id"`
+	ListenedAt         int64  `db:"listened_at"` // Unix time the play started at.
+	Attempts           int    `db:"attempts"`
+	LastError          string `db:"last_error"`
+	DateAdded          int64  `db:"created_at"`
+}
+
+type Scrobbles []Scrobble
@@ -0,0 +1,25 @@
+package domain
+
+// Types of the entities that can be shared.
+const (
+	ShareEntityAlbum    = "album"
+	ShareEntityTrack    = "track"
+	ShareEntityPlaylist = "playlist"
+)
+
+// Public link to an album, a track or a playlist, usable without an account.
+type Share struct {
+	Id            int    `db:"id"`
+	Slug          string `db:"slug"` // Random part of the link, which can't be guessed.
+	EntityType    string `db:"entity_type"`
+	EntityId      int    `db:"entity_id"`  // 0 for playlists.
+	Name          string `db:"name"`       // Name of a playlist.
+	ExpiresAt     int64  `db:"expires_at"` // Unix time, 0 if the link never expires.
+	MaxPlays      int    `db:"max_plays"`  // Maximum number of track plays, 0 if unlimited.
+	Plays         int    `db:"plays"`
+	AllowDownload bool   `db:"allow_download"`
+	DateAdded     int64  `db:"created_at"`
+	TrackIds      []int  `db:"-"` // Tracks of a playlist, in order.
+}
+
+type Shares []Share
@@ -9,9 +9,38 @@ type Track struct {
 	Disc      string `db:"disc"`
 	Number    int    `db:"number"`
 	Duration  int    `db:"duration"` // Duration in seconds.
-	Genre	  string `db:"genre"` // TODO externalize this in another table.
+	Genre	  string `db:"genre"` // All the track genres, for display. See Genres.
 	Path      string `db:"path"` // Mandatory.
 	DateAdded int64  `db:"created_at"`
+	// Extended tags.
+	MusicBrainzTrackId  string `db:"musicbrainz_track_id"` // MusicBrainz recording id.
+	MusicBrainzAlbumId  string `db:"musicbrainz_album_id"` // MusicBrainz release id.
+	MusicBrainzArtistId string `db:"musicbrainz_artist_id"`
+	Isrc                string `db:"isrc"`
+	Label               string `db:"label"`
+	CatalogNumber       string `db:"catalog_number"`
+	Date                string `db:"date"` // Release date, see business.NormalizeDate().
+	OriginalDate        string `db:"original_date"`
+	OriginalYear        string `db:"original_year"`
+	Composer            string `db:"composer"`
+	Conductor           string `db:"conductor"`
+	Bpm                 int    `db:"bpm"`
+	// Tracks of a CUE sheet are segments of a media file.
+	StartMs  int `db:"start_ms"` // Start of the track in the media file, in milliseconds.
+	EndMs    int `db:"end_ms"`   // End of the track in the media file, in milliseconds, or 0 for the end of the file.
+	CueTrack int `db:"cue_track"` // Number of the track in its CUE sheet, or 0 if the track is a whole media file.
+	// ReplayGain, see business.ReplayGainAdjustment(). Gains are in dB and peaks are linear amplitudes, 0 if unknown.
+	ReplayGainTrackGain float64 `db:"replaygain_track_gain"`
+	ReplayGainTrackPeak float64 `db:"replaygain_track_peak"`
+	ReplayGainAlbumGain float64 `db:"replaygain_album_gain"`
+	ReplayGainAlbumPeak float64 `db:"replaygain_album_peak"`
+	// Loudness analysis, see business.AnalyzeLoudness(). Loudness is in LUFS and true peaks are linear amplitudes, 0
+	// if unknown.
+	LoudnessTrackLufs  float64 `db:"loudness_track_lufs"`
+	LoudnessTrackPeak  float64 `db:"loudness_track_peak"`
+	LoudnessAlbumLufs  float64 `db:"loudness_album_lufs"`
+	LoudnessAlbumPeak  float64 `db:"loudness_album_peak"`
+	LoudnessAnalyzedAt int64   `db:"loudness_analyzed_at"` // 0 if the track hasn't been analyzed yet.
 }
 
 type Tracks []Track
@@ -0,0 +1,19 @@
+package domain
+
+// Roles an artist can have on a track.
+const (
+	ArtistRoleMain     = "main"
+	ArtistRoleFeatured = "featured"
+	ArtistRoleRemixer  = "remixer"
+	ArtistRoleComposer = "composer"
+)
+
+// Credit of an artist on a track.
+type TrackArtist struct {
+	TrackId  int    `db:"track_id"`
+	ArtistId int    `db:"artist_id"`
+	Role     string `db:"role"`
+	Position int    `db:"position"` // Order of the artist in the tags.
+}
+
+type TrackArtists []TrackArtist
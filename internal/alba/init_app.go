@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
 	"github.com/humbkr/albaplayer-server/internal/alba/interfaces"
 	"github.com/natefinch/lumberjack"
 	"github.com/spf13/viper"
@@ -29,6 +30,48 @@ func InitApp() business.LibraryInteractor {
 	viper.SetDefault("Server.Https.KeyFile", "")
 	// Library.
 	viper.SetDefault("Library.Path", "")
+	viper.SetDefault("Library.Genres.Separators", []string{";", "/", ","})
+	viper.SetDefault("Library.Artists.Separators", interfaces.DefaultArtistSeparators)
+	viper.SetDefault("Library.Artists.FeaturingSeparators", []string{"feat.", "ft.", "featuring"})
+	viper.SetDefault("Library.SortArticles", []string{"The", "A", "An", "Le", "La", "Les", "L'", "Die", "Der", "Das", "El", "Los", "Las"})
+	viper.SetDefault("Library.WriteTags", false)
+
+	// Transcoding.
+	viper.SetDefault("Transcoding.FfmpegPath", "")
+	viper.SetDefault("Transcoding.Format", "mp3")
+	viper.SetDefault("Transcoding.Bitrate", 192)
+	// HLS.
+	viper.SetDefault("Hls.Bitrates", []string{"64", "128", "256"})
+	viper.SetDefault("Hls.SegmentDuration", 6)
+	viper.SetDefault("Hls.Directory", "./hls")
+	viper.SetDefault("Hls.CacheDuration", 10)
+	// Waveforms.
+	viper.SetDefault("Waveforms.Directory", "./waveforms")
+	viper.SetDefault("Waveforms.Precompute", false)
+	// Downloads.
+	viper.SetDefault("Downloads.Enabled", true)
+	// Media URLs.
+	viper.SetDefault("MediaUrls.Signed", true)
+	viper.SetDefault("MediaUrls.Expiry", 24)
+	viper.SetDefault("MediaUrls.Secret", "")
+	// Shares.
+	viper.SetDefault("Shares.Enabled", true)
+	// Scrobbling.
+	viper.SetDefault("Scrobbling.RetryInterval", 5)
+	viper.SetDefault("Scrobbling.ListenBrainz.Enabled", true)
+	viper.SetDefault("Scrobbling.ListenBrainz.ApiUrl", "https://api.listenbrainz.org")
+	viper.SetDefault("Scrobbling.LastFm.ApiKey", "")
+	viper.SetDefault("Scrobbling.LastFm.ApiSecret", "")
+	viper.SetDefault("Scrobbling.LastFm.ApiUrl", "https://ws.audioscrobbler.com/2.0/")
+	viper.SetDefault("Scrobbling.LastFm.AuthUrl", "https://www.last.fm/api/auth/")
+	// Parties.
+	viper.SetDefault("Party.SkipRatio", 0.5)
+	viper.SetDefault("Party.IdleTimeout", 12)
+	// Bookmarks.
+	viper.SetDefault("Bookmarks.MinDuration", 20)
+	viper.SetDefault("Bookmarks.Genres", []string{})
+	viper.SetDefault("Bookmarks.Folders", []string{})
+
 	// Dev mode.
 	viper.SetDefault("DevMode.Enabled", false)
 
@@ -62,10 +105,39 @@ func InitApp() business.LibraryInteractor {
 	libraryInteractor.ArtistRepository = interfaces.ArtistDbRepository{AppContext: &appContext}
 	libraryInteractor.AlbumRepository = interfaces.AlbumDbRepository{AppContext: &appContext}
 	libraryInteractor.TrackRepository = interfaces.TrackDbRepository{AppContext: &appContext}
+	libraryInteractor.GenreRepository = interfaces.GenreDbRepository{AppContext: &appContext}
 	libraryInteractor.CoverRepository = interfaces.CoverDbRepository{AppContext: &appContext}
 	libraryInteractor.LibraryRepository = interfaces.LibraryDbRepository{AppContext: &appContext}
 	libraryInteractor.MediaFileRepository = interfaces.LocalFilesystemRepository{AppContext: &appContext}
 	libraryInteractor.InternalVariableRepository = interfaces.InternalVariableDbRepository{AppContext: &appContext}
+	libraryInteractor.OverrideRepository = interfaces.OverrideDbRepository{AppContext: &appContext}
+	libraryInteractor.ArtistAliasRepository = interfaces.ArtistAliasDbRepository{AppContext: &appContext}
+	libraryInteractor.LyricsRepository = interfaces.LyricsDbRepository{AppContext: &appContext}
+	libraryInteractor.WaveformRepository = interfaces.WaveformFileRepository{}
+	libraryInteractor.ShareRepository = interfaces.ShareDbRepository{AppContext: &appContext}
+	libraryInteractor.ScrobbleAccountRepository = interfaces.ScrobbleAccountDbRepository{AppContext: &appContext}
+	libraryInteractor.ScrobbleRepository = interfaces.ScrobbleDbRepository{AppContext: &appContext}
+	libraryInteractor.PlayQueueRepository = interfaces.PlayQueueDbRepository{AppContext: &appContext}
+	libraryInteractor.BookmarkRepository = interfaces.BookmarkDbRepository{AppContext: &appContext}
+
+	// Clients of the enabled scrobbling services.
+	libraryInteractor.Scrobblers = make(map[string]business.Scrobbler)
+	if viper.GetBool("Scrobbling.ListenBrainz.Enabled") {
+		libraryInteractor.Scrobblers[domain.ScrobbleServiceListenBrainz] = interfaces.ListenBrainzScrobbler{}
+	}
+	// Last.fm needs the key of an API account.
+	if viper.GetString("Scrobbling.LastFm.ApiKey") != "" && viper.GetString("Scrobbling.LastFm.ApiSecret") != "" {
+		libraryInteractor.Scrobblers[domain.ScrobbleServiceLastFm] = interfaces.LastFmScrobbler{}
+	}
+
+	// Media URLs are signed with a secret kept in the database if none is configured.
+	if viper.GetBool("MediaUrls.Signed") {
+		secret, err := libraryInteractor.GetMediaUrlSecret()
+		if err != nil {
+			panic(fmt.Errorf("Error during the media URLs secret creation: %s \n", err))
+		}
+		viper.Set("MediaUrls.Secret", secret)
+	}
 
 	return libraryInteractor
 }
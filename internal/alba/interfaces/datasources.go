@@ -56,7 +56,16 @@ func InitAlbaDatasource(dbDriver string, dbFile string) (ds Datasource, err erro
 	// Bind tables to objects.
 	dbmap.AddTableWithName(domain.Artist{}, "artists").SetKeys(true, "Id").AddIndex("ArtistNameIndex", "nil", []string{"name"})
 	dbmap.AddTableWithName(domain.Album{}, "albums").SetKeys(true, "Id").AddIndex("AlbumTitleIndex", "nil", []string{"title"})
+	dbmap.AddTableWithName(domain.Genre{}, "genres").SetKeys(true, "Id").AddIndex("GenreNameIndex", "nil", []string{"name"})
 	dbmap.AddTableWithName(domain.Cover{}, "covers").SetKeys(true, "Id").AddIndex("CoverHashIndex", "nil", []string{"hash"})
+	dbmap.AddTableWithName(domain.Override{}, "overrides").SetKeys(true, "Id")
+	dbmap.AddTableWithName(domain.ArtistAlias{}, "artist_aliases").SetKeys(true, "Id")
+	dbmap.AddTableWithName(domain.Lyrics{}, "lyrics").SetKeys(true, "Id")
+	dbmap.AddTableWithName(domain.Share{}, "shares").SetKeys(true, "Id")
+	dbmap.AddTableWithName(domain.ScrobbleAccount{}, "scrobble_accounts").SetKeys(true, "Id")
+	dbmap.AddTableWithName(domain.Scrobble{}, "scrobbles").SetKeys(true, "Id")
+	dbmap.AddTableWithName(domain.PlayQueue{}, "play_queues").SetKeys(true, "Id")
+	dbmap.AddTableWithName(domain.Bookmark{}, "bookmarks").SetKeys(true, "Id")
 	dbmap.AddTableWithName(business.InternalVariable{}, "variables").SetKeys(false, "Key")
 
 	tracksTable := dbmap.AddTableWithName(domain.Track{}, "tracks")
@@ -70,5 +79,111 @@ func InitAlbaDatasource(dbDriver string, dbFile string) (ds Datasource, err erro
 		log.Fatalln("Create tables failed", err)
 	}
 
+	// Match keys cannot be computed by SQLite, so the migration adding them is completed here.
+	if err = mergeDuplicatesByMatchKey(dbmap); err != nil {
+		return
+	}
+
 	return dbmap, nil
 }
+
+/**
+Computes the missing artists and albums match keys, then merges the artists and albums having the same key.
+
+Duplicates are merged into the oldest entity. Albums are only merged if they belong to the same artist and don't have
+different MusicBrainz release ids. Does nothing if all the match keys are already known.
+*/
+func mergeDuplicatesByMatchKey(dbmap *gorp.DbMap) (err error) {
+	var artists domain.Artists
+	if _, err = dbmap.Select(&artists, "SELECT id, name FROM artists WHERE match_key = ''"); err != nil {
+		return
+	}
+	var albums domain.Albums
+	if _, err = dbmap.Select(&albums, "SELECT id, title FROM albums WHERE match_key = ''"); err != nil {
+		return
+	}
+	if len(artists) == 0 && len(albums) == 0 {
+		return
+	}
+
+	tx, err := dbmap.Begin()
+	if err != nil {
+		return
+	}
+	if err = mergeDuplicatesByMatchKeyInTransaction(tx, artists, albums); err != nil {
+		_ = tx.Rollback()
+		return
+	}
+
+	return tx.Commit()
+}
+
+// See mergeDuplicatesByMatchKey().
+func mergeDuplicatesByMatchKeyInTransaction(tx *gorp.Transaction, artists domain.Artists, albums domain.Albums) (err error) {
+	for _, artist := range artists {
+		if _, err = tx.Exec("UPDATE artists SET match_key = ? WHERE id = ?", business.MatchKey(artist.Name), artist.Id); err != nil {
+			return
+		}
+	}
+	for _, album := range albums {
+		if _, err = tx.Exec("UPDATE albums SET match_key = ? WHERE id = ?", business.MatchKey(album.Title), album.Id); err != nil {
+			return
+		}
+	}
+
+	type duplicate struct {
+		Id       int `db:"id"`
+		TargetId int `db:"target_id"`
+	}
+
+	// Merge artists.
+	var duplicateArtists []duplicate
+	_, err = tx.Select(
+		&duplicateArtists,
+		"SELECT art.id, (SELECT MIN(id) FROM artists WHERE match_key = art.match_key) target_id FROM artists art " +
+		"WHERE art.id != (SELECT MIN(id) FROM artists WHERE match_key = art.match_key)",
+	)
+	if err != nil {
+		return
+	}
+	for _, artist := range duplicateArtists {
+		queries := []string{
+			"UPDATE albums SET artist_id = ?1 WHERE artist_id = ?2",
+			"UPDATE tracks SET artist_id = ?1 WHERE artist_id = ?2",
+			"INSERT OR IGNORE INTO track_artists (track_id, artist_id, role, position) SELECT track_id, ?1, role, position FROM track_artists WHERE artist_id = ?2",
+			"DELETE FROM track_artists WHERE artist_id = ?2",
+			"DELETE FROM artists WHERE id = ?2",
+		}
+		for _, query := range queries {
+			if _, err = tx.Exec(query, artist.TargetId, artist.Id); err != nil {
+				return
+			}
+		}
+	}
+
+	// Merge albums, now that their artists have been merged.
+	var duplicateAlbums []duplicate
+	_, err = tx.Select(
+		&duplicateAlbums,
+		"SELECT alb.id, (SELECT MIN(id) FROM albums WHERE match_key = alb.match_key AND artist_id = alb.artist_id AND musicbrainz_album_id = alb.musicbrainz_album_id) target_id FROM albums alb " +
+		"WHERE alb.id != (SELECT MIN(id) FROM albums WHERE match_key = alb.match_key AND artist_id = alb.artist_id AND musicbrainz_album_id = alb.musicbrainz_album_id)",
+	)
+	if err != nil {
+		return
+	}
+	for _, album := range duplicateAlbums {
+		queries := []string{
+			"UPDATE tracks SET album_id = ?1 WHERE album_id = ?2",
+			// Don't lose the cover of a duplicate.
+			"UPDATE albums SET cover_id = (SELECT cover_id FROM albums WHERE id = ?2) WHERE id = ?1 AND cover_id = 0",
+			"DELETE FROM albums WHERE id = ?2",
+		}
+		for _, query := range queries {
+			if _, err = tx.Exec(query, album.TargetId, album.Id); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
@@ -54,3 +54,46 @@ func (suite *DatasourcesTestSuite) TestInitAlbaDatasource() {
 		_ = dbmap.Db.Close()
 	}
 }
+
+func (suite *DatasourcesTestSuite) TestMergeDuplicatesByMatchKey() {
+	testDataSourceFile := os.TempDir() + "testMatchKeys.db"
+	_ = os.Remove(testDataSourceFile)
+	defer os.Remove(testDataSourceFile)
+
+	ds, err := InitAlbaDatasource("sqlite3", testDataSourceFile)
+	assert.Nil(suite.T(), err)
+	dbmap := ds.(*gorp.DbMap)
+	defer dbmap.Db.Close()
+
+	// Data inserted before the match keys were introduced.
+	queries := []string{
+		"INSERT INTO artists(id, name, created_at) VALUES(1, 'AC/DC', 0), (2, 'Ac/Dc', 0), (3, 'Björk', 0), (4, 'Björk', 0)",
+		"INSERT INTO albums(id, artist_id, title, year, cover_id, created_at) VALUES(1, 1, 'Back in Black', '1980', 0, 0), (2, 2, 'back in  black', '1980', 5, 0), (3, 4, 'Post', '1995', 0, 0)",
+		"INSERT INTO tracks(id, album_id, artist_id, cover_id, title, disc, number, duration, genre, path, created_at) VALUES(1, 1, 1, 0, 'Hells Bells', '', 1, 0, '', '/1.mp3', 0), (2, 2, 2, 0, 'Shoot to Thrill', '', 2, 0, '', '/2.mp3', 0), (3, 3, 4, 0, 'Army of Me', '', 1, 0, '', '/3.mp3', 0)",
+		"INSERT INTO track_artists(track_id, artist_id, role, position) VALUES(1, 1, 'main', 0), (2, 2, 'main', 0), (3, 4, 'main', 0)",
+	}
+	for _, query := range queries {
+		_, err = dbmap.Exec(query)
+		assert.Nil(suite.T(), err)
+	}
+
+	err = mergeDuplicatesByMatchKey(dbmap)
+	assert.Nil(suite.T(), err)
+
+	artistsCount, _ := dbmap.SelectInt("SELECT COUNT(*) FROM artists")
+	assert.Equal(suite.T(), int64(2), artistsCount)
+	albumsCount, _ := dbmap.SelectInt("SELECT COUNT(*) FROM albums")
+	assert.Equal(suite.T(), int64(2), albumsCount)
+
+	// Duplicates have been merged into the oldest entities.
+	tracksCount, _ := dbmap.SelectInt("SELECT COUNT(*) FROM tracks WHERE artist_id = 1 AND album_id = 1")
+	assert.Equal(suite.T(), int64(2), tracksCount)
+	tracksCount, _ = dbmap.SelectInt("SELECT COUNT(*) FROM tracks WHERE artist_id = 3 AND album_id = 3")
+	assert.Equal(suite.T(), int64(1), tracksCount)
+	creditsCount, _ := dbmap.SelectInt("SELECT COUNT(*) FROM track_artists WHERE artist_id IN (1, 3)")
+	assert.Equal(suite.T(), int64(3), creditsCount)
+	coverId, _ := dbmap.SelectInt("SELECT cover_id FROM albums WHERE id = 1")
+	assert.Equal(suite.T(), int64(5), coverId)
+	matchKey, _ := dbmap.SelectStr("SELECT match_key FROM artists WHERE id = 1")
+	assert.Equal(suite.T(), "ac/dc", matchKey)
+}
@@ -0,0 +1,96 @@
+package interfaces
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/spf13/viper"
+)
+
+/*
+This file exposes the writing of the download archives.
+
+Archives are ZIP files streamed while they are written, so nothing is buffered on disk. Media files are already
+compressed, so they are stored without compression.
+*/
+
+/*
+Writes the files of a download to a ZIP archive.
+
+The tracks are transcoded if transcoding options are given. Files which can't be read are left out of the archive, as
+it may already be partly sent.
+*/
+func writeDownloadArchive(ctx context.Context, w io.Writer, download business.Download, options *transcodingOptions) error {
+	archive := zip.NewWriter(w)
+
+	for _, file := range download.Files {
+		err := writeDownloadFile(ctx, archive, file, options)
+		if err == nil {
+			continue
+		}
+		// The client has gone.
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Println("ERROR - Can't add file " + file.Path + " to download: " + err.Error())
+	}
+
+	return archive.Close()
+}
+
+// Writes a file to a ZIP archive.
+func writeDownloadFile(ctx context.Context, archive *zip.Writer, file business.DownloadFile, options *transcodingOptions) error {
+	source, err := os.Open(file.Path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	stat, err := source.Stat()
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader = source
+	transcoding := options
+	if file.IsTrack && transcoding == nil && (file.StartMs > 0 || file.EndMs > 0) {
+		// Tracks of CUE sheets are cut from their media file, or else transcoded.
+		if segment, errSegment := getMediaSegment(source, file.StartMs, file.EndMs); errSegment == nil {
+			reader = segment
+		} else {
+			defaultOptions := getTranscodingOptions()
+			transcoding = &defaultOptions
+		}
+	}
+
+	header := &zip.FileHeader{Name: file.Name, Method: zip.Store, Modified: stat.ModTime()}
+	if file.IsTrack && transcoding != nil {
+		if viper.GetString("Transcoding.FfmpegPath") == "" {
+			return errTranscodingDisabled
+		}
+		header.Name = strings.TrimSuffix(file.Name, filepath.Ext(file.Name)) + transcodingFormats[transcoding.Format].Extension
+		entry, errCreate := archive.CreateHeader(header)
+		if errCreate != nil {
+			return errCreate
+		}
+		segmentOptions := *transcoding
+		segmentOptions.StartMs = file.StartMs
+		segmentOptions.EndMs = file.EndMs
+
+		return transcodeTo(ctx, entry, file.Path, segmentOptions)
+	}
+
+	entry, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, reader)
+
+	return err
+}
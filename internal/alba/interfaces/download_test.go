@@ -0,0 +1,103 @@
+package interfaces
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type DownloadTestSuite struct {
+	suite.Suite
+	Directory string
+}
+
+// Go testing framework entry point.
+func TestDownloadTestSuite(t *testing.T) {
+	suite.Run(t, new(DownloadTestSuite))
+}
+
+func (suite *DownloadTestSuite) SetupSuite() {
+	directory, err := ioutil.TempDir("", "alba-download")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.Directory = directory
+}
+
+func (suite *DownloadTestSuite) TearDownSuite() {
+	_ = os.RemoveAll(suite.Directory)
+}
+
+// Writes a file in the test directory and returns its path.
+func (suite *DownloadTestSuite) createFile(name string, content []byte) string {
+	path := filepath.Join(suite.Directory, name)
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		suite.T().Fatal(err)
+	}
+
+	return path
+}
+
+func (suite *DownloadTestSuite) TestWriteDownloadArchive() {
+	flacContent := buildTestFlacFile(100, 2000)
+	download := business.Download{
+		Name: "Artist - Album",
+		Files: []business.DownloadFile{
+			{Name: "Artist/Album/01 - Track.mp3", Path: suite.createFile("track.mp3", []byte("mp3 content")), IsTrack: true},
+			{Name: "Artist/Album/02 - Missing.mp3", Path: filepath.Join(suite.Directory, "missing.mp3"), IsTrack: true},
+			// Tracks of a CUE sheet.
+			{Name: "Artist/Album/03 - Cut.flac", Path: suite.createFile("album.flac", flacContent), IsTrack: true, StartMs: 1000, EndMs: 2000},
+			{Name: "Artist/Album/04 - Transcoded.ape", Path: suite.createFile("album.ape", []byte("MAC ")), IsTrack: true, StartMs: 1000},
+			{Name: "Artist/Album/cover.jpg", Path: suite.createFile("cover.jpg", []byte("jpeg content"))},
+		},
+	}
+
+	var buffer bytes.Buffer
+	err := writeDownloadArchive(context.Background(), &buffer, download, nil)
+	assert.Nil(suite.T(), err)
+
+	// Missing files and tracks which would need transcoding are left out.
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), archive.File, 3)
+	assert.Equal(suite.T(), "Artist/Album/01 - Track.mp3", archive.File[0].Name)
+	assert.Equal(suite.T(), zip.Store, archive.File[0].Method)
+	assert.Equal(suite.T(), "Artist/Album/03 - Cut.flac", archive.File[1].Name)
+	assert.Equal(suite.T(), "Artist/Album/cover.jpg", archive.File[2].Name)
+
+	file, err := archive.File[0].Open()
+	assert.Nil(suite.T(), err)
+	content, _ := ioutil.ReadAll(file)
+	assert.Equal(suite.T(), "mp3 content", string(content))
+
+	// The cut track is a valid FLAC stream, made of the frames 10 to 21.
+	file, err = archive.File[1].Open()
+	assert.Nil(suite.T(), err)
+	content, _ = ioutil.ReadAll(file)
+	assert.Len(suite.T(), content, 42+12*2006)
+	assert.Equal(suite.T(), "fLaC", string(content[0:4]))
+	assert.Equal(suite.T(), flacContent[42+10*2006:42+22*2006], content[42:])
+}
+
+func (suite *DownloadTestSuite) TestWriteDownloadArchiveTranscodingDisabled() {
+	download := business.Download{
+		Name:  "Artist - Album",
+		Files: []business.DownloadFile{{Name: "Artist/Album/01 - Track.flac", Path: suite.createFile("track.flac", []byte("fLaC")), IsTrack: true}},
+	}
+	options := getTranscodingOptions()
+
+	var buffer bytes.Buffer
+	err := writeDownloadArchive(context.Background(), &buffer, download, &options)
+	assert.Nil(suite.T(), err)
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), archive.File)
+}
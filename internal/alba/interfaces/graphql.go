@@ -6,7 +6,10 @@ package interfaces
 
 import (
 	"errors"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/graphql-go/graphql"
 	"github.com/humbkr/albaplayer-server/internal/alba/version"
@@ -46,6 +49,17 @@ var artistType = graphql.NewObject(graphql.ObjectConfig{
 				return nil, nil
 			},
 		},
+		"sortName": &graphql.Field{
+			Name: "Artist sort name",
+			Description: "Name used to sort and index the artist, like \"Beatles, The\".",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if artist, ok := p.Source.(domain.Artist); ok == true {
+					return artist.SortName, nil
+				}
+				return nil, nil
+			},
+		},
 		"albums": &graphql.Field{
 			Name: "Artist albums",
 			Description: "Albums of the artist.",
@@ -57,6 +71,17 @@ var artistType = graphql.NewObject(graphql.ObjectConfig{
 				return nil, nil
 			},
 		},
+		"download": &graphql.Field{
+			Name: "Artist download",
+			Description: "Signed url of the ZIP archive of the artist, valid for a limited time.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if artist, ok := p.Source.(domain.Artist); ok == true {
+					return signMediaUrl("/download/artist/"+strconv.Itoa(artist.Id), anonymousUser, time.Now()), nil
+				}
+				return nil, nil
+			},
+		},
 		"dateAdded": &graphql.Field{
 			Name: "Date added",
 			Description: "Date at which the artist has been added to the library.",
@@ -99,6 +124,18 @@ var albumType = graphql.NewObject(graphql.ObjectConfig{
 				return nil, nil
 			},
 		},
+		"sortName": &graphql.Field{
+			Name: "Album sort name",
+			Description: "Title used to sort the album.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if album, ok := p.Source.(domain.Album); ok == true {
+					return album.SortName, nil
+				}
+
+				return nil, nil
+			},
+		},
 		"year": &graphql.Field{
 			Name: "Album year",
 			Description: "Year the album was released in, or the year-span in case of a compilation of tracks from released in different years.",
@@ -111,13 +148,59 @@ var albumType = graphql.NewObject(graphql.ObjectConfig{
 				return nil, nil
 			},
 		},
+		"date": &graphql.Field{
+			Name: "Album release date",
+			Description: "Latest release date of the album tracks, formatted as YYYY, YYYY-MM or YYYY-MM-DD depending on what the tags provide.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if album, ok := p.Source.(domain.Album); ok == true {
+					return album.Date, nil
+				}
+
+				return nil, nil
+			},
+		},
+		"originalDate": &graphql.Field{
+			Name: "Album original release date",
+			Description: "Earliest original release date of the album tracks, formatted as YYYY, YYYY-MM or YYYY-MM-DD.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if album, ok := p.Source.(domain.Album); ok == true {
+					return album.OriginalDate, nil
+				}
+
+				return nil, nil
+			},
+		},
+		"musicBrainzAlbumId": &graphql.Field{
+			Name: "MusicBrainz album ID",
+			Description: "MusicBrainz release identifier.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if album, ok := p.Source.(domain.Album); ok == true {
+					return album.MusicBrainzAlbumId, nil
+				}
+				return nil, nil
+			},
+		},
 		"cover": &graphql.Field{
 			Name: "Album cover",
-			Description: "Url of the cover file.",
+			Description: "Signed url of the cover file, valid for a limited time.",
 			Type: graphql.String,
 			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
 				if album, ok := p.Source.(domain.Album); ok == true && album.CoverId != 0 {
-					return "/covers/" + strconv.Itoa(album.CoverId), nil
+					return signMediaUrl("/covers/"+strconv.Itoa(album.CoverId), anonymousUser, time.Now()), nil
+				}
+				return nil, nil
+			},
+		},
+		"download": &graphql.Field{
+			Name: "Album download",
+			Description: "Signed url of the ZIP archive of the album, valid for a limited time.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if album, ok := p.Source.(domain.Album); ok == true {
+					return signMediaUrl("/download/album/"+strconv.Itoa(album.Id), anonymousUser, time.Now()), nil
 				}
 				return nil, nil
 			},
@@ -229,24 +312,266 @@ var trackType = graphql.NewObject(graphql.ObjectConfig{
 				return nil, nil
 			},
 		},
+		"musicBrainzTrackId": &graphql.Field{
+			Name: "MusicBrainz track ID",
+			Description: "MusicBrainz recording identifier.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true {
+					return track.MusicBrainzTrackId, nil
+				}
+				return nil, nil
+			},
+		},
+		"musicBrainzAlbumId": &graphql.Field{
+			Name: "MusicBrainz album ID",
+			Description: "MusicBrainz release identifier.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true {
+					return track.MusicBrainzAlbumId, nil
+				}
+				return nil, nil
+			},
+		},
+		"musicBrainzArtistId": &graphql.Field{
+			Name: "MusicBrainz artist ID",
+			Description: "MusicBrainz artist identifier.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true {
+					return track.MusicBrainzArtistId, nil
+				}
+				return nil, nil
+			},
+		},
+		"isrc": &graphql.Field{
+			Name: "ISRC",
+			Description: "International Standard Recording Code.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true {
+					return track.Isrc, nil
+				}
+				return nil, nil
+			},
+		},
+		"label": &graphql.Field{
+			Name: "Label",
+			Description: "Record label.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true {
+					return track.Label, nil
+				}
+				return nil, nil
+			},
+		},
+		"catalogNumber": &graphql.Field{
+			Name: "Catalog number",
+			Description: "Catalog number of the release.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true {
+					return track.CatalogNumber, nil
+				}
+				return nil, nil
+			},
+		},
+		"date": &graphql.Field{
+			Name: "Release date",
+			Description: "Date the track was released on, formatted as YYYY, YYYY-MM or YYYY-MM-DD depending on what the tags provide.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true {
+					return track.Date, nil
+				}
+				return nil, nil
+			},
+		},
+		"originalDate": &graphql.Field{
+			Name: "Original release date",
+			Description: "Date the track was originally released on, formatted as YYYY, YYYY-MM or YYYY-MM-DD.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true {
+					return track.OriginalDate, nil
+				}
+				return nil, nil
+			},
+		},
+		"originalYear": &graphql.Field{
+			Name: "Original year",
+			Description: "Year the track was originally released in.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true {
+					return track.OriginalYear, nil
+				}
+				return nil, nil
+			},
+		},
+		"composer": &graphql.Field{
+			Name: "Composer",
+			Description: "Composer of the track.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true {
+					return track.Composer, nil
+				}
+				return nil, nil
+			},
+		},
+		"conductor": &graphql.Field{
+			Name: "Conductor",
+			Description: "Conductor of the track.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true {
+					return track.Conductor, nil
+				}
+				return nil, nil
+			},
+		},
+		"bpm": &graphql.Field{
+			Name: "BPM",
+			Description: "Beats per minute.",
+			Type: graphql.Int,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true {
+					return track.Bpm, nil
+				}
+				return nil, nil
+			},
+		},
+		"replayGainTrackGain": &graphql.Field{
+			Name: "Track gain",
+			Description: "ReplayGain track gain in dB, null if unknown.",
+			Type: graphql.Float,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true && (track.ReplayGainTrackGain != 0 || track.ReplayGainTrackPeak != 0) {
+					return track.ReplayGainTrackGain, nil
+				}
+				return nil, nil
+			},
+		},
+		"replayGainTrackPeak": &graphql.Field{
+			Name: "Track peak",
+			Description: "ReplayGain track peak as a linear amplitude, null if unknown.",
+			Type: graphql.Float,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true && (track.ReplayGainTrackPeak != 0) {
+					return track.ReplayGainTrackPeak, nil
+				}
+				return nil, nil
+			},
+		},
+		"replayGainAlbumGain": &graphql.Field{
+			Name: "Album gain",
+			Description: "ReplayGain album gain in dB, null if unknown.",
+			Type: graphql.Float,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true && (track.ReplayGainAlbumGain != 0 || track.ReplayGainAlbumPeak != 0) {
+					return track.ReplayGainAlbumGain, nil
+				}
+				return nil, nil
+			},
+		},
+		"replayGainAlbumPeak": &graphql.Field{
+			Name: "Album peak",
+			Description: "ReplayGain album peak as a linear amplitude, null if unknown.",
+			Type: graphql.Float,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true && (track.ReplayGainAlbumPeak != 0) {
+					return track.ReplayGainAlbumPeak, nil
+				}
+				return nil, nil
+			},
+		},
+		"loudnessTrack": &graphql.Field{
+			Name: "Track loudness",
+			Description: "Integrated loudness of the track in LUFS measured by the loudness analysis, null if unknown.",
+			Type: graphql.Float,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true && track.LoudnessTrackLufs != 0 {
+					return track.LoudnessTrackLufs, nil
+				}
+				return nil, nil
+			},
+		},
+		"truePeakTrack": &graphql.Field{
+			Name: "Track true peak",
+			Description: "True peak of the track as a linear amplitude measured by the loudness analysis, null if unknown.",
+			Type: graphql.Float,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true && track.LoudnessTrackPeak != 0 {
+					return track.LoudnessTrackPeak, nil
+				}
+				return nil, nil
+			},
+		},
+		"loudnessAlbum": &graphql.Field{
+			Name: "Album loudness",
+			Description: "Integrated loudness of the album in LUFS measured by the loudness analysis, null if unknown.",
+			Type: graphql.Float,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true && track.LoudnessAlbumLufs != 0 {
+					return track.LoudnessAlbumLufs, nil
+				}
+				return nil, nil
+			},
+		},
+		"truePeakAlbum": &graphql.Field{
+			Name: "Album true peak",
+			Description: "True peak of the album as a linear amplitude measured by the loudness analysis, null if unknown.",
+			Type: graphql.Float,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true && track.LoudnessAlbumPeak != 0 {
+					return track.LoudnessAlbumPeak, nil
+				}
+				return nil, nil
+			},
+		},
 		"src": &graphql.Field{
 			Name: "Track path",
-			Description: "Url of the media file.",
+			Description: "Signed url of the media file, valid for a limited time.",
 			Type: graphql.NewNonNull(graphql.String),
 			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
 				if track, ok := p.Source.(domain.Track); ok == true {
-					return "/stream/" + strconv.Itoa(track.Id), nil
+					return signMediaUrl("/stream/"+strconv.Itoa(track.Id), anonymousUser, time.Now()), nil
 				}
 				return nil, nil
 			},
 		},
 		"cover": &graphql.Field{
 			Name: "Track cover",
-			Description: "Url of the cover file.",
+			Description: "Signed url of the cover file, valid for a limited time.",
 			Type: graphql.String,
 			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
 				if track, ok := p.Source.(domain.Track); ok == true && track.CoverId != 0 {
-					return "/covers/" + strconv.Itoa(track.CoverId), nil
+					return signMediaUrl("/covers/"+strconv.Itoa(track.CoverId), anonymousUser, time.Now()), nil
+				}
+				return nil, nil
+			},
+		},
+		"waveform": &graphql.Field{
+			Name: "Track waveform",
+			Description: "Signed url of the waveform, valid for a limited time.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true {
+					return signMediaUrl("/waveform/"+strconv.Itoa(track.Id), anonymousUser, time.Now()), nil
+				}
+				return nil, nil
+			},
+		},
+		"hls": &graphql.Field{
+			Name: "Track HLS playlist",
+			Description: "Signed url of the HLS master playlist, valid for a limited time.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(domain.Track); ok == true {
+					return signMediaUrl("/hls/"+strconv.Itoa(track.Id)+"/"+hlsPlaylistName, anonymousUser, time.Now()), nil
 				}
 				return nil, nil
 			},
@@ -287,57 +612,59 @@ var trackType = graphql.NewObject(graphql.ObjectConfig{
 	},
 })
 
-var libraryUpdateStateType = graphql.NewObject(graphql.ObjectConfig{
-	Name: "LibraryUpdateState",
+// Defines artist index group type.
+var artistIndexGroupType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ArtistIndexGroup",
+	Description: "Artists whose sort name starts with the same letter.",
 	Fields: graphql.Fields{
-		"tracksNumber": &graphql.Field{
-			Name:        "Number of tracks",
-			Description: "Number of tracks in the library.",
-			Type:        graphql.Int,
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				return nil, nil
-			},
-		},
-		"albumsNumber": &graphql.Field{
-			Name:        "Number of albums",
-			Description: "Number of albums in the library.",
-			Type:        graphql.Int,
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		"letter": &graphql.Field{
+			Name: "Index letter",
+			Description: "Upper case letter from A to Z, or # for the artists whose sort name doesn't start with a letter.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if group, ok := p.Source.(business.ArtistIndexGroup); ok == true {
+					return group.Letter, nil
+				}
 				return nil, nil
 			},
 		},
-		"artistsNumber": &graphql.Field{
-			Name:        "Number of artists",
-			Description: "Number of artists in the library.",
-			Type:        graphql.Int,
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		"artists": &graphql.Field{
+			Name: "Artists",
+			Description: "Artists of the group, ordered by sort name.",
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(artistType))),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if group, ok := p.Source.(business.ArtistIndexGroup); ok == true {
+					return group.Artists, nil
+				}
 				return nil, nil
 			},
 		},
 	},
 })
 
-var internalVariableType = graphql.NewObject(graphql.ObjectConfig{
-	Name: "Variable",
+// Defines static parts of track artist type.
+var trackArtistType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TrackArtist",
+	Description: "Credit of an artist on a track.",
 	Fields: graphql.Fields{
-		"key": &graphql.Field{
-			Name:        "Key",
-			Description: "Unique key of the variable.",
-			Type:        graphql.String,
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				if settings, ok := p.Source.(business.InternalVariable); ok == true {
-					return settings.Key, nil
+		"artistId": &graphql.Field{
+			Name: "Artist ID",
+			Description: "Shorthand property for performance, avoid loading an artist for each credit.",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if credit, ok := p.Source.(domain.TrackArtist); ok == true {
+					return credit.ArtistId, nil
 				}
 				return nil, nil
 			},
 		},
-		"value": &graphql.Field{
-			Name:        "Value",
-			Description: "Value of the variable",
-			Type:        graphql.String,
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				if settings, ok := p.Source.(business.InternalVariable); ok == true {
-					return settings.Value, nil
+		"role": &graphql.Field{
+			Name: "Artist role",
+			Description: "Role of the artist on the track: main, featured, remixer or composer.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if credit, ok := p.Source.(domain.TrackArtist); ok == true {
+					return credit.Role, nil
 				}
 				return nil, nil
 			},
@@ -345,230 +672,2356 @@ var internalVariableType = graphql.NewObject(graphql.ObjectConfig{
 	},
 })
 
-var settingsType = graphql.NewObject(graphql.ObjectConfig{
-	Name: "Settings",
+// Defines static parts of genre type.
+var genreType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Genre",
 	Fields: graphql.Fields{
-		"libraryPath": &graphql.Field{
-			Name:        "Music library path",
-			Description: "Absolute path of the music collection on disk.",
-			Type:        graphql.String,
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				if settings, ok := p.Source.(business.ClientSettings); ok == true {
-					return settings.LibraryPath, nil
+		"id": &graphql.Field{
+			Name: "Genre ID",
+			Description: "Genre unique Identifier.",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if genre, ok := p.Source.(domain.Genre); ok == true {
+					return genre.Id, nil
 				}
 				return nil, nil
 			},
 		},
-		"coversPreferredSource": &graphql.Field{
-			Name:        "Covers preferred source",
-			Description: "Tracks and album covers preferred source when scanning the library. 'folder' or 'file'",
-			Type:        graphql.String,
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				if settings, ok := p.Source.(business.ClientSettings); ok == true {
-					return settings.CoversPreferredSource, nil
+		"name": &graphql.Field{
+			Name: "Genre name",
+			Description: "Name of the genre.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if genre, ok := p.Source.(domain.Genre); ok == true {
+					return genre.Name, nil
 				}
 				return nil, nil
 			},
 		},
-		"disableLibrarySettings": &graphql.Field{
-			Name:        "Disable library settings",
-			Description: "Whether the library settings are editable on the client side or not.",
-			Type:        graphql.Boolean,
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				if settings, ok := p.Source.(business.ClientSettings); ok == true {
-					return settings.DisableLibraryConfiguration, nil
+		"tracksCount": &graphql.Field{
+			Name: "Number of tracks",
+			Description: "Number of tracks of this genre. Only available from the genres and genre queries.",
+			Type: graphql.Int,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if genre, ok := p.Source.(domain.Genre); ok == true {
+					return genre.TracksCount, nil
 				}
 				return nil, nil
 			},
 		},
-		"version": &graphql.Field{
-			Name:        "Server version",
-			Description: "Server version.",
-			Type:        graphql.String,
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				return version.Version, nil
+		"albumsCount": &graphql.Field{
+			Name: "Number of albums",
+			Description: "Number of albums having tracks of this genre. Only available from the genres and genre queries.",
+			Type: graphql.Int,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if genre, ok := p.Source.(domain.Genre); ok == true {
+					return genre.AlbumsCount, nil
+				}
+				return nil, nil
+			},
+		},
+		"dateAdded": &graphql.Field{
+			Name: "Date added",
+			Description: "Date at which the genre has been added to the library.",
+			Type: graphql.Int,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if genre, ok := p.Source.(domain.Genre); ok == true {
+					return genre.DateAdded, nil
+				}
+				return nil, nil
 			},
 		},
 	},
 })
 
-/*
-Creates a new GraphQL interactor.
+var overrideType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Override",
+	Description: "Manual edit of an artist, album or track field, kept when the library is updated.",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Name: "Override ID",
+			Description: "Override unique identifier.",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if override, ok := p.Source.(domain.Override); ok == true {
+					return override.Id, nil
+				}
+				return nil, nil
+			},
+		},
+		"entityType": &graphql.Field{
+			Name: "Entity type",
+			Description: "Type of the edited entity: artist, album or track.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if override, ok := p.Source.(domain.Override); ok == true {
+					return override.EntityType, nil
+				}
+				return nil, nil
+			},
+		},
+		"entityId": &graphql.Field{
+			Name: "Entity ID",
+			Description: "ID of the edited entity.",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if override, ok := p.Source.(domain.Override); ok == true {
+					return override.EntityId, nil
+				}
+				return nil, nil
+			},
+		},
+		"field": &graphql.Field{
+			Name: "Field",
+			Description: "Edited field, like sortName.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if override, ok := p.Source.(domain.Override); ok == true {
+					return override.Field, nil
+				}
+				return nil, nil
+			},
+		},
+		"value": &graphql.Field{
+			Name: "Value",
+			Description: "Value set manually.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if override, ok := p.Source.(domain.Override); ok == true {
+					return override.Value, nil
+				}
+				return nil, nil
+			},
+		},
+		"originalValue": &graphql.Field{
+			Name: "Original value",
+			Description: "Value read from the media files.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if override, ok := p.Source.(domain.Override); ok == true {
+					return override.OriginalValue, nil
+				}
+				return nil, nil
+			},
+		},
+		"dateAdded": &graphql.Field{
+			Name: "Date added",
+			Description: "Date at which the field has been edited.",
+			Type: graphql.Int,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if override, ok := p.Source.(domain.Override); ok == true {
+					return override.DateAdded, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
 
-Builds GraphQL Schema, initialise dynamic fields on types.
- */
-func NewGraphQLInteractor(ci *business.LibraryInteractor) *graphQLInteractor {
-	interactor := &graphQLInteractor{Library:ci}
+var artistAliasType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ArtistAlias",
+	Description: "Name of an artist merged into another artist.",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Name: "Alias ID",
+			Description: "Alias unique identifier.",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if alias, ok := p.Source.(domain.ArtistAlias); ok == true {
+					return alias.Id, nil
+				}
+				return nil, nil
+			},
+		},
+		"artistId": &graphql.Field{
+			Name: "Artist ID",
+			Description: "ID of the artist the name is mapped onto.",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if alias, ok := p.Source.(domain.ArtistAlias); ok == true {
+					return alias.ArtistId, nil
+				}
+				return nil, nil
+			},
+		},
+		"name": &graphql.Field{
+			Name: "Name",
+			Description: "Name of the merged artist.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if alias, ok := p.Source.(domain.ArtistAlias); ok == true {
+					return alias.Name, nil
+				}
+				return nil, nil
+			},
+		},
+		"sortName": &graphql.Field{
+			Name: "Sort name",
+			Description: "Sort name of the merged artist.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if alias, ok := p.Source.(domain.ArtistAlias); ok == true {
+					return alias.SortName, nil
+				}
+				return nil, nil
+			},
+		},
+		"dateAdded": &graphql.Field{
+			Name: "Date added",
+			Description: "Date at which the artists have been merged.",
+			Type: graphql.Int,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if alias, ok := p.Source.(domain.ArtistAlias); ok == true {
+					return alias.DateAdded, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
 
-	// Define dynamic fields on types.
-	albumType.AddFieldConfig("artist", &graphql.Field{
-		Type: artistType,
-		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			if album, ok := p.Source.(domain.Album); ok == true && album.ArtistId != 0 {
-				return interactor.Library.ArtistRepository.Get(album.ArtistId)
-			}
+var lyricsLineType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "LyricsLine",
+	Description: "Line of time-synced lyrics.",
+	Fields: graphql.Fields{
+		"timeMs": &graphql.Field{
+			Name: "Time",
+			Description: "Time the line starts at, in milliseconds from the beginning of the track.",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if line, ok := p.Source.(domain.LyricsLine); ok == true {
+					return line.TimeMs, nil
+				}
+				return nil, nil
+			},
+		},
+		"line": &graphql.Field{
+			Name: "Line",
+			Description: "Text of the line, empty for the instrumental parts.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if line, ok := p.Source.(domain.LyricsLine); ok == true {
+					return line.Line, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
 
-			return nil, nil
+var lyricsType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Lyrics",
+	Description: "Lyrics of a track, read from its tags or from the .lrc / .txt files next to it.",
+	Fields: graphql.Fields{
+		"text": &graphql.Field{
+			Name: "Text",
+			Description: "Text of the lyrics.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if lyrics, ok := p.Source.(domain.Lyrics); ok == true {
+					return lyrics.Text, nil
+				}
+				return nil, nil
+			},
 		},
-	})
-	trackType.AddFieldConfig("artist", &graphql.Field{
-		Type: artistType,
-		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			if track, ok := p.Source.(domain.Track); ok == true && track.ArtistId != 0 {
-				return interactor.Library.ArtistRepository.Get(track.ArtistId)
-			}
+		"synced": &graphql.Field{
+			Name: "Time-synced lyrics",
+			Description: "Lines of the lyrics ordered by time, empty if the lyrics are not time-synced.",
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(lyricsLineType))),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if lyrics, ok := p.Source.(domain.Lyrics); ok == true {
+					lines := business.ParseLrc(lyrics.Synced)
+					if lines == nil {
+						lines = []domain.LyricsLine{}
+					}
+					return lines, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
 
-			return nil, nil
+var tagEditType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TagEdit",
+	Description: "Change of a tag value in a media file.",
+	Fields: graphql.Fields{
+		"path": &graphql.Field{
+			Name: "Path",
+			Description: "Path of the media file.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if edit, ok := p.Source.(business.TagEdit); ok == true {
+					return edit.Path, nil
+				}
+				return nil, nil
+			},
 		},
-	})
-	trackType.AddFieldConfig("album", &graphql.Field{
-		Type: albumType,
-		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			if track, ok := p.Source.(domain.Track); ok == true && track.AlbumId != 0 {
-				return interactor.Library.AlbumRepository.Get(track.AlbumId)
-			}
+		"field": &graphql.Field{
+			Name: "Field",
+			Description: "Name of the tag, like catalogNumber.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if edit, ok := p.Source.(business.TagEdit); ok == true {
+					return edit.Field, nil
+				}
+				return nil, nil
+			},
+		},
+		"previousValue": &graphql.Field{
+			Name: "Previous value",
+			Description: "Value currently stored in the media file.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if edit, ok := p.Source.(business.TagEdit); ok == true {
+					return edit.PreviousValue, nil
+				}
+				return nil, nil
+			},
+		},
+		"value": &graphql.Field{
+			Name: "Value",
+			Description: "Value to write.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if edit, ok := p.Source.(business.TagEdit); ok == true {
+					return edit.Value, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var libraryUpdateStateType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "LibraryUpdateState",
+	Fields: graphql.Fields{
+		"tracksNumber": &graphql.Field{
+			Name:        "Number of tracks",
+			Description: "Number of tracks in the library.",
+			Type:        graphql.Int,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return nil, nil
+			},
+		},
+		"albumsNumber": &graphql.Field{
+			Name:        "Number of albums",
+			Description: "Number of albums in the library.",
+			Type:        graphql.Int,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return nil, nil
+			},
+		},
+		"artistsNumber": &graphql.Field{
+			Name:        "Number of artists",
+			Description: "Number of artists in the library.",
+			Type:        graphql.Int,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return nil, nil
+			},
+		},
+	},
+})
+
+var loudnessAnalysisType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "LoudnessAnalysis",
+	Description: "Progress of the loudness analysis of the library.",
+	Fields: graphql.Fields{
+		"running": &graphql.Field{
+			Name:        "Running",
+			Description: "Whether an analysis is running.",
+			Type:        graphql.NewNonNull(graphql.Boolean),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if progress, ok := p.Source.(business.LoudnessAnalysisProgress); ok == true {
+					return progress.Running, nil
+				}
+				return nil, nil
+			},
+		},
+		"tracksDone": &graphql.Field{
+			Name:        "Tracks done",
+			Description: "Number of tracks analyzed.",
+			Type:        graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if progress, ok := p.Source.(business.LoudnessAnalysisProgress); ok == true {
+					return progress.TracksDone, nil
+				}
+				return nil, nil
+			},
+		},
+		"tracksTotal": &graphql.Field{
+			Name:        "Tracks total",
+			Description: "Number of tracks to analyze.",
+			Type:        graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if progress, ok := p.Source.(business.LoudnessAnalysisProgress); ok == true {
+					return progress.TracksTotal, nil
+				}
+				return nil, nil
+			},
+		},
+		"albumsDone": &graphql.Field{
+			Name:        "Albums done",
+			Description: "Number of albums analyzed.",
+			Type:        graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if progress, ok := p.Source.(business.LoudnessAnalysisProgress); ok == true {
+					return progress.AlbumsDone, nil
+				}
+				return nil, nil
+			},
+		},
+		"albumsTotal": &graphql.Field{
+			Name:        "Albums total",
+			Description: "Number of albums to analyze.",
+			Type:        graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if progress, ok := p.Source.(business.LoudnessAnalysisProgress); ok == true {
+					return progress.AlbumsTotal, nil
+				}
+				return nil, nil
+			},
+		},
+		"failed": &graphql.Field{
+			Name:        "Failed",
+			Description: "Number of tracks whose audio couldn't be decoded.",
+			Type:        graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if progress, ok := p.Source.(business.LoudnessAnalysisProgress); ok == true {
+					return progress.Failed, nil
+				}
+				return nil, nil
+			},
+		},
+		"error": &graphql.Field{
+			Name:        "Error",
+			Description: "Error which stopped the analysis, null if none.",
+			Type:        graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if progress, ok := p.Source.(business.LoudnessAnalysisProgress); ok == true && progress.Error != "" {
+					return progress.Error, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var shareType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Share",
+	Description: "Public link to an album or a track, usable without an account.",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Name: "Share ID",
+			Description: "Share unique identifier.",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if share, ok := p.Source.(domain.Share); ok == true {
+					return share.Id, nil
+				}
+				return nil, nil
+			},
+		},
+		"slug": &graphql.Field{
+			Name: "Slug",
+			Description: "Random part of the share link.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if share, ok := p.Source.(domain.Share); ok == true {
+					return share.Slug, nil
+				}
+				return nil, nil
+			},
+		},
+		"url": &graphql.Field{
+			Name: "Url",
+			Description: "Url of the public page of the share.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if share, ok := p.Source.(domain.Share); ok == true {
+					return "/share/" + share.Slug, nil
+				}
+				return nil, nil
+			},
+		},
+		"entityType": &graphql.Field{
+			Name: "Entity type",
+			Description: "Type of the shared entity: album, track or playlist.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if share, ok := p.Source.(domain.Share); ok == true {
+					return share.EntityType, nil
+				}
+				return nil, nil
+			},
+		},
+		"entityId": &graphql.Field{
+			Name: "Entity ID",
+			Description: "ID of the shared entity, 0 for playlists.",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if share, ok := p.Source.(domain.Share); ok == true {
+					return share.EntityId, nil
+				}
+				return nil, nil
+			},
+		},
+		"name": &graphql.Field{
+			Name: "Name",
+			Description: "Name of a shared playlist, null for the other shares.",
+			Type: graphql.String,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if share, ok := p.Source.(domain.Share); ok == true && share.EntityType == domain.ShareEntityPlaylist {
+					return share.Name, nil
+				}
+				return nil, nil
+			},
+		},
+		"trackIds": &graphql.Field{
+			Name: "Track IDs",
+			Description: "IDs of the tracks of a shared playlist, in order. Null for the other shares.",
+			Type: graphql.NewList(graphql.NewNonNull(graphql.ID)),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if share, ok := p.Source.(domain.Share); ok == true && share.EntityType == domain.ShareEntityPlaylist {
+					return share.TrackIds, nil
+				}
+				return nil, nil
+			},
+		},
+		"expiresAt": &graphql.Field{
+			Name: "Expires at",
+			Description: "Unix time at which the link expires, null if it never expires.",
+			Type: graphql.Int,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if share, ok := p.Source.(domain.Share); ok == true && share.ExpiresAt != 0 {
+					return share.ExpiresAt, nil
+				}
+				return nil, nil
+			},
+		},
+		"maxPlays": &graphql.Field{
+			Name: "Maximum plays",
+			Description: "Maximum number of track plays, null if unlimited.",
+			Type: graphql.Int,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if share, ok := p.Source.(domain.Share); ok == true && share.MaxPlays != 0 {
+					return share.MaxPlays, nil
+				}
+				return nil, nil
+			},
+		},
+		"plays": &graphql.Field{
+			Name: "Plays",
+			Description: "Number of track plays.",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if share, ok := p.Source.(domain.Share); ok == true {
+					return share.Plays, nil
+				}
+				return nil, nil
+			},
+		},
+		"allowDownload": &graphql.Field{
+			Name: "Allow download",
+			Description: "Whether the shared tracks can be downloaded.",
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if share, ok := p.Source.(domain.Share); ok == true {
+					return share.AllowDownload, nil
+				}
+				return nil, nil
+			},
+		},
+		"dateAdded": &graphql.Field{
+			Name: "Date added",
+			Description: "Date at which the share has been created.",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if share, ok := p.Source.(domain.Share); ok == true {
+					return share.DateAdded, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var scrobbleAccountType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ScrobbleAccount",
+	Description: "Account of a scrobbling service to which the plays are sent.",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Name: "Scrobble account ID",
+			Description: "Scrobble account unique identifier.",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if account, ok := p.Source.(domain.ScrobbleAccount); ok == true {
+					return account.Id, nil
+				}
+				return nil, nil
+			},
+		},
+		"service": &graphql.Field{
+			Name: "Service",
+			Description: "Scrobbling service: lastfm or listenbrainz.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if account, ok := p.Source.(domain.ScrobbleAccount); ok == true {
+					return account.Service, nil
+				}
+				return nil, nil
+			},
+		},
+		"userName": &graphql.Field{
+			Name: "User name",
+			Description: "Name of the user on the scrobbling service.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if account, ok := p.Source.(domain.ScrobbleAccount); ok == true {
+					return account.UserName, nil
+				}
+				return nil, nil
+			},
+		},
+		"dateAdded": &graphql.Field{
+			Name: "Date added",
+			Description: "Date at which the account has been linked.",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if account, ok := p.Source.(domain.ScrobbleAccount); ok == true {
+					return account.DateAdded, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var scrobbleType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Scrobble",
+	Description: "Play waiting to be sent to a scrobbling service.",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Name: "Scrobble ID",
+			Description: "Scrobble unique identifier.",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if scrobble, ok := p.Source.(domain.Scrobble); ok == true {
+					return scrobble.Id, nil
+				}
+				return nil, nil
+			},
+		},
+		"accountId": &graphql.Field{
+			Name: "Account ID",
+			Description: "ID of the account the play is sent to.",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if scrobble, ok := p.Source.(domain.Scrobble); ok == true {
+					return scrobble.AccountId, nil
+				}
+				return nil, nil
+			},
+		},
+		"artist": &graphql.Field{
+			Name: "Artist",
+			Description: "Name of the track artist.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if scrobble, ok := p.Source.(domain.Scrobble); ok == true {
+					return scrobble.Artist, nil
+				}
+				return nil, nil
+			},
+		},
+		"title": &graphql.Field{
+			Name: "Title",
+			Description: "Title of the track.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if scrobble, ok := p.Source.(domain.Scrobble); ok == true {
+					return scrobble.Title, nil
+				}
+				return nil, nil
+			},
+		},
+		"album": &graphql.Field{
+			Name: "Album",
+			Description: "Title of the track album.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if scrobble, ok := p.Source.(domain.Scrobble); ok == true {
+					return scrobble.Album, nil
+				}
+				return nil, nil
+			},
+		},
+		"listenedAt": &graphql.Field{
+			Name: "Listened at",
+			Description: "Unix time at which the play started.",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if scrobble, ok := p.Source.(domain.Scrobble); ok == true {
+					return scrobble.ListenedAt, nil
+				}
+				return nil, nil
+			},
+		},
+		"attempts": &graphql.Field{
+			Name: "Attempts",
+			Description: "Number of failed submissions.",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if scrobble, ok := p.Source.(domain.Scrobble); ok == true {
+					return scrobble.Attempts, nil
+				}
+				return nil, nil
+			},
+		},
+		"lastError": &graphql.Field{
+			Name: "Last error",
+			Description: "Error of the last failed submission.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if scrobble, ok := p.Source.(domain.Scrobble); ok == true {
+					return scrobble.LastError, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var playQueueType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PlayQueue",
+	Description: "Play queue of the user, saved by a client so the playback can be resumed on another one.",
+	Fields: graphql.Fields{
+		"trackIds": &graphql.Field{
+			Name: "Track IDs",
+			Description: "IDs of the tracks of the queue, in order.",
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID))),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if queue, ok := p.Source.(domain.PlayQueue); ok == true {
+					return queue.TrackIds, nil
+				}
+				return nil, nil
+			},
+		},
+		"currentIndex": &graphql.Field{
+			Name: "Current index",
+			Description: "Index of the track being played.",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if queue, ok := p.Source.(domain.PlayQueue); ok == true {
+					return queue.CurrentIndex, nil
+				}
+				return nil, nil
+			},
+		},
+		"position": &graphql.Field{
+			Name: "Position",
+			Description: "Playback position in the current track, in milliseconds.",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if queue, ok := p.Source.(domain.PlayQueue); ok == true {
+					return queue.Position, nil
+				}
+				return nil, nil
+			},
+		},
+		"clientId": &graphql.Field{
+			Name: "Client ID",
+			Description: "Client which saved the queue.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if queue, ok := p.Source.(domain.PlayQueue); ok == true {
+					return queue.ClientId, nil
+				}
+				return nil, nil
+			},
+		},
+		"dateModified": &graphql.Field{
+			Name: "Date modified",
+			Description: "Date at which the queue has been saved, 0 if it never has.",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if queue, ok := p.Source.(domain.PlayQueue); ok == true {
+					return queue.DateModified, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var bookmarkType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Bookmark",
+	Description: "Playback position of the user in a track, so its playback can be resumed.",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Name: "Bookmark ID",
+			Description: "Bookmark unique identifier.",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if bookmark, ok := p.Source.(domain.Bookmark); ok == true {
+					return bookmark.Id, nil
+				}
+				return nil, nil
+			},
+		},
+		"trackId": &graphql.Field{
+			Name: "Track ID",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if bookmark, ok := p.Source.(domain.Bookmark); ok == true {
+					return bookmark.TrackId, nil
+				}
+				return nil, nil
+			},
+		},
+		"position": &graphql.Field{
+			Name: "Position",
+			Description: "Playback position in the track, in milliseconds.",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if bookmark, ok := p.Source.(domain.Bookmark); ok == true {
+					return bookmark.Position, nil
+				}
+				return nil, nil
+			},
+		},
+		"dateAdded": &graphql.Field{
+			Name: "Date added",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if bookmark, ok := p.Source.(domain.Bookmark); ok == true {
+					return bookmark.DateAdded, nil
+				}
+				return nil, nil
+			},
+		},
+		"dateModified": &graphql.Field{
+			Name: "Date modified",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if bookmark, ok := p.Source.(domain.Bookmark); ok == true {
+					return bookmark.DateModified, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+// Participant of a party, with the secret token returned when creating or joining it.
+type partySession struct {
+	Token string
+	Participant business.PartyParticipant
+	Party business.Party
+}
+
+var partyParticipantType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PartyParticipant",
+	Description: "Participant of a party.",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Name: "ID",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if participant, ok := p.Source.(business.PartyParticipant); ok == true {
+					return participant.Id, nil
+				}
+				return nil, nil
+			},
+		},
+		"name": &graphql.Field{
+			Name: "Name",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if participant, ok := p.Source.(business.PartyParticipant); ok == true {
+					return participant.Name, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var partyVoteType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PartyVote",
+	Description: "Vote of a participant for a track of a party.",
+	Fields: graphql.Fields{
+		"participantId": &graphql.Field{
+			Name: "Participant ID",
+			Type: graphql.NewNonNull(graphql.ID),
+		},
+		"vote": &graphql.Field{
+			Name: "Vote",
+			Description: "1 or -1.",
+			Type: graphql.NewNonNull(graphql.Int),
+		},
+	},
+})
+
+var partyTrackType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PartyTrack",
+	Description: "Track added to the queue of a party.",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Name: "ID",
+			Description: "ID of the track in the party, as a track can be added several times.",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(business.PartyTrack); ok == true {
+					return track.Id, nil
+				}
+				return nil, nil
+			},
+		},
+		"trackId": &graphql.Field{
+			Name: "Track ID",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(business.PartyTrack); ok == true {
+					return track.TrackId, nil
+				}
+				return nil, nil
+			},
+		},
+		"addedBy": &graphql.Field{
+			Name: "Added by",
+			Description: "ID of the participant who added the track.",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(business.PartyTrack); ok == true {
+					return track.AddedBy, nil
+				}
+				return nil, nil
+			},
+		},
+		"score": &graphql.Field{
+			Name: "Score",
+			Description: "Sum of the votes.",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(business.PartyTrack); ok == true {
+					return track.Score(), nil
+				}
+				return nil, nil
+			},
+		},
+		"downVotes": &graphql.Field{
+			Name: "Down votes",
+			Description: "Number of participants who voted the track down.",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(business.PartyTrack); ok == true {
+					return track.DownVotes(), nil
+				}
+				return nil, nil
+			},
+		},
+		"votes": &graphql.Field{
+			Name: "Votes",
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(partyVoteType))),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(business.PartyTrack); ok == true {
+					votes := []map[string]interface{}{}
+					for participantId, vote := range track.Votes {
+						votes = append(votes, map[string]interface{}{"participantId": participantId, "vote": vote})
+					}
+					return votes, nil
+				}
+				return nil, nil
+			},
+		},
+		"dateAdded": &graphql.Field{
+			Name: "Date added",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if track, ok := p.Source.(business.PartyTrack); ok == true {
+					return track.DateAdded, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var partyType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Party",
+	Description: "Room where several participants share a queue of tracks.",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Name: "ID",
+			Description: "Code given to the participants to join the party.",
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if party, ok := p.Source.(business.Party); ok == true {
+					return party.Id, nil
+				}
+				return nil, nil
+			},
+		},
+		"name": &graphql.Field{
+			Name: "Name",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if party, ok := p.Source.(business.Party); ok == true {
+					return party.Name, nil
+				}
+				return nil, nil
+			},
+		},
+		"hostId": &graphql.Field{
+			Name: "Host ID",
+			Description: "Participant controlling the playback, null if every participant can.",
+			Type: graphql.ID,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if party, ok := p.Source.(business.Party); ok == true && party.HostId != "" {
+					return party.HostId, nil
+				}
+				return nil, nil
+			},
+		},
+		"participants": &graphql.Field{
+			Name: "Participants",
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(partyParticipantType))),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if party, ok := p.Source.(business.Party); ok == true {
+					return party.Participants, nil
+				}
+				return nil, nil
+			},
+		},
+		"current": &graphql.Field{
+			Name: "Current track",
+			Description: "Track being played, null if none.",
+			Type: partyTrackType,
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if party, ok := p.Source.(business.Party); ok == true && party.Current != nil {
+					return *party.Current, nil
+				}
+				return nil, nil
+			},
+		},
+		"queue": &graphql.Field{
+			Name: "Queue",
+			Description: "Tracks to play, the best voted first.",
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(partyTrackType))),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if party, ok := p.Source.(business.Party); ok == true {
+					return party.Queue, nil
+				}
+				return nil, nil
+			},
+		},
+		"playing": &graphql.Field{
+			Name: "Playing",
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if party, ok := p.Source.(business.Party); ok == true {
+					return party.Playing, nil
+				}
+				return nil, nil
+			},
+		},
+		"closed": &graphql.Field{
+			Name: "Closed",
+			Description: "Whether the party has been closed. Only sent once to the subscriptions, which then complete.",
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if party, ok := p.Source.(business.Party); ok == true {
+					return party.Closed, nil
+				}
+				return nil, nil
+			},
+		},
+		"skipVotes": &graphql.Field{
+			Name: "Skip votes",
+			Description: "Number of down votes skipping or removing a track.",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if party, ok := p.Source.(business.Party); ok == true {
+					return party.SkipVotes(), nil
+				}
+				return nil, nil
+			},
+		},
+		"dateModified": &graphql.Field{
+			Name: "Date modified",
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if party, ok := p.Source.(business.Party); ok == true {
+					return party.DateModified, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var partySessionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PartySession",
+	Description: "Participant who created or joined a party.",
+	Fields: graphql.Fields{
+		"token": &graphql.Field{
+			Name: "Token",
+			Description: "Secret identifying the participant in the party mutations.",
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if session, ok := p.Source.(partySession); ok == true {
+					return session.Token, nil
+				}
+				return nil, nil
+			},
+		},
+		"participant": &graphql.Field{
+			Name: "Participant",
+			Type: graphql.NewNonNull(partyParticipantType),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if session, ok := p.Source.(partySession); ok == true {
+					return session.Participant, nil
+				}
+				return nil, nil
+			},
+		},
+		"party": &graphql.Field{
+			Name: "Party",
+			Type: graphql.NewNonNull(partyType),
+			Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+				if session, ok := p.Source.(partySession); ok == true {
+					return session.Party, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var internalVariableType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Variable",
+	Fields: graphql.Fields{
+		"key": &graphql.Field{
+			Name:        "Key",
+			Description: "Unique key of the variable.",
+			Type:        graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if settings, ok := p.Source.(business.InternalVariable); ok == true {
+					return settings.Key, nil
+				}
+				return nil, nil
+			},
+		},
+		"value": &graphql.Field{
+			Name:        "Value",
+			Description: "Value of the variable",
+			Type:        graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if settings, ok := p.Source.(business.InternalVariable); ok == true {
+					return settings.Value, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var settingsType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Settings",
+	Fields: graphql.Fields{
+		"libraryPath": &graphql.Field{
+			Name:        "Music library path",
+			Description: "Absolute path of the music collection on disk.",
+			Type:        graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if settings, ok := p.Source.(business.ClientSettings); ok == true {
+					return settings.LibraryPath, nil
+				}
+				return nil, nil
+			},
+		},
+		"coversPreferredSource": &graphql.Field{
+			Name:        "Covers preferred source",
+			Description: "Tracks and album covers preferred source when scanning the library. 'folder' or 'file'",
+			Type:        graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if settings, ok := p.Source.(business.ClientSettings); ok == true {
+					return settings.CoversPreferredSource, nil
+				}
+				return nil, nil
+			},
+		},
+		"disableLibrarySettings": &graphql.Field{
+			Name:        "Disable library settings",
+			Description: "Whether the library settings are editable on the client side or not.",
+			Type:        graphql.Boolean,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if settings, ok := p.Source.(business.ClientSettings); ok == true {
+					return settings.DisableLibraryConfiguration, nil
+				}
+				return nil, nil
+			},
+		},
+		"writeTags": &graphql.Field{
+			Name:        "Write tags",
+			Description: "Whether the track and album edits are written into the media files or not.",
+			Type:        graphql.Boolean,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if settings, ok := p.Source.(business.ClientSettings); ok == true {
+					return settings.WriteTags, nil
+				}
+				return nil, nil
+			},
+		},
+		"version": &graphql.Field{
+			Name:        "Server version",
+			Description: "Server version.",
+			Type:        graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return version.Version, nil
+			},
+		},
+	},
+})
+
+/*
+Creates a new GraphQL interactor.
+
+Builds GraphQL Schema, initialise dynamic fields on types.
+ */
+func NewGraphQLInteractor(ci *business.LibraryInteractor) *graphQLInteractor {
+	interactor := &graphQLInteractor{Library:ci}
+
+	// Define dynamic fields on types.
+	albumType.AddFieldConfig("artist", &graphql.Field{
+		Type: artistType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if album, ok := p.Source.(domain.Album); ok == true && album.ArtistId != 0 {
+				return interactor.Library.ArtistRepository.Get(album.ArtistId)
+			}
+
+			return nil, nil
+		},
+	})
+	trackType.AddFieldConfig("artist", &graphql.Field{
+		Type: artistType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if track, ok := p.Source.(domain.Track); ok == true && track.ArtistId != 0 {
+				return interactor.Library.ArtistRepository.Get(track.ArtistId)
+			}
+
+			return nil, nil
+		},
+	})
+	trackType.AddFieldConfig("album", &graphql.Field{
+		Type: albumType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if track, ok := p.Source.(domain.Track); ok == true && track.AlbumId != 0 {
+				return interactor.Library.AlbumRepository.Get(track.AlbumId)
+			}
+
+			return nil, nil
+		},
+	})
+
+	trackType.AddFieldConfig("artists", &graphql.Field{
+		Type: graphql.NewList(graphql.NewNonNull(trackArtistType)),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if track, ok := p.Source.(domain.Track); ok == true {
+				return interactor.Library.TrackRepository.GetTrackArtists(track.Id)
+			}
+
+			return nil, nil
+		},
+	})
+	playQueueType.AddFieldConfig("tracks", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(trackType)),
+		Description: "Tracks of the queue, in order. Tracks removed from the library are null.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if queue, ok := p.Source.(domain.PlayQueue); ok == true {
+				tracks := make([]interface{}, len(queue.TrackIds))
+				for i, trackId := range queue.TrackIds {
+					if track, err := interactor.Library.TrackRepository.Get(trackId); err == nil {
+						tracks[i] = track
+					}
+				}
+				return tracks, nil
+			}
+
+			return nil, nil
+		},
+	})
+	partyTrackType.AddFieldConfig("track", &graphql.Field{
+		Type: trackType,
+		Description: "Track of the library, null if it has been removed.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if partyTrack, ok := p.Source.(business.PartyTrack); ok == true {
+				if track, err := interactor.Library.TrackRepository.Get(partyTrack.TrackId); err == nil {
+					return track, nil
+				}
+			}
+
+			return nil, nil
+		},
+	})
+	trackArtistType.AddFieldConfig("artist", &graphql.Field{
+		Type: artistType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if credit, ok := p.Source.(domain.TrackArtist); ok == true {
+				return interactor.Library.ArtistRepository.Get(credit.ArtistId)
+			}
+
+			return nil, nil
+		},
+	})
+	artistType.AddFieldConfig("tracks", &graphql.Field{
+		Type: graphql.NewList(graphql.NewNonNull(trackType)),
+		Args: graphql.FieldConfigArgument{
+			"role": &graphql.ArgumentConfig{
+				Description: "Only get tracks crediting the artist with this role: main, featured, remixer or composer. Defaults to main.",
+				Type: graphql.String,
+				DefaultValue: domain.ArtistRoleMain,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if artist, ok := p.Source.(domain.Artist); ok == true {
+				role, _ := p.Args["role"].(string)
+				return interactor.Library.TrackRepository.GetTracksForArtist(artist.Id, role)
+			}
+
+			return nil, nil
+		},
+	})
+	artistType.AddFieldConfig("appearsOn", &graphql.Field{
+		Type: graphql.NewList(graphql.NewNonNull(albumType)),
+		Description: "Albums of other artists having tracks crediting the artist.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if artist, ok := p.Source.(domain.Artist); ok == true {
+				return interactor.Library.AlbumRepository.GetAlbumsArtistAppearsOn(artist.Id)
+			}
+
+			return nil, nil
+		},
+	})
+
+	trackType.AddFieldConfig("genres", &graphql.Field{
+		Type: graphql.NewList(graphql.NewNonNull(genreType)),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if track, ok := p.Source.(domain.Track); ok == true {
+				return interactor.Library.GenreRepository.GetGenresForTrack(track.Id)
+			}
+
+			return nil, nil
+		},
+	})
+	trackType.AddFieldConfig("resumable", &graphql.Field{
+		Type: graphql.Boolean,
+		Description: "Whether the clients must save the playback position of the track and resume it.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if track, ok := p.Source.(domain.Track); ok == true {
+				return interactor.Library.IsResumable(track), nil
+			}
+
+			return nil, nil
+		},
+	})
+	trackType.AddFieldConfig("bookmark", &graphql.Field{
+		Type: bookmarkType,
+		Description: "Playback position of the user in the track, null if none has been saved.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if track, ok := p.Source.(domain.Track); ok == true {
+				if bookmark, err := interactor.Library.GetBookmark(anonymousUser, track.Id); err == nil {
+					return bookmark, nil
+				}
+			}
+
+			return nil, nil
+		},
+	})
+	bookmarkType.AddFieldConfig("track", &graphql.Field{
+		Type: trackType,
+		Description: "Bookmarked track.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if bookmark, ok := p.Source.(domain.Bookmark); ok == true {
+				if track, err := interactor.Library.TrackRepository.Get(bookmark.TrackId); err == nil {
+					return track, nil
+				}
+			}
+
+			return nil, nil
+		},
+	})
+	trackType.AddFieldConfig("lyrics", &graphql.Field{
+		Type: lyricsType,
+		Description: "Lyrics of the track, null if the track has no lyrics.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if track, ok := p.Source.(domain.Track); ok == true {
+				if lyrics, err := interactor.Library.GetTrackLyrics(track.Id); err == nil {
+					return lyrics, nil
+				}
+			}
+
+			return nil, nil
+		},
+	})
+	genreType.AddFieldConfig("albums", &graphql.Field{
+		Type: graphql.NewList(graphql.NewNonNull(albumType)),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if genre, ok := p.Source.(domain.Genre); ok == true {
+				return interactor.Library.AlbumRepository.GetAlbumsForGenre(genre.Id)
+			}
+
+			return nil, nil
+		},
+	})
+	genreType.AddFieldConfig("tracks", &graphql.Field{
+		Type: graphql.NewList(graphql.NewNonNull(trackType)),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if genre, ok := p.Source.(domain.Genre); ok == true {
+				return interactor.Library.TrackRepository.GetTracksForGenre(genre.Id)
+			}
+
+			return nil, nil
+		},
+	})
+
+	// This is the type that will be the root of our query,
+	// and the entry point into our schema.
+	rootQuery := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"artists": &graphql.Field{
+				Type: graphql.NewList(artistType),
+				Args: graphql.FieldConfigArgument{
+					"genre": &graphql.ArgumentConfig{
+						Description: "Only get artists having tracks of this genre.",
+						Type: graphql.ID,
+					},
+				},
+				Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+					if genreId, ok, err := getIdArgument(p, "genre"); ok {
+						if err != nil {
+							return nil, err
+						}
+						return interactor.Library.GetArtistsForGenre(genreId)
+					}
+
+					return interactor.Library.GetAllArtists(false)
+				},
+			},
+			"artistIndex": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(artistIndexGroupType)),
+				Description: "Artists grouped by the first letter of their sort name.",
+				Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+					return interactor.Library.GetArtistIndex()
+				},
+			},
+			"artist": &graphql.Field{
+				Type: artistType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Description: "Artist ID",
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					i := p.Args["id"].(string)
+					id, err := strconv.Atoi(i)
+					if err != nil {
+						return nil, err
+					}
+
+					return interactor.Library.ArtistRepository.Get(id)
+				},
+			},
+			"albums": &graphql.Field{
+				Type: graphql.NewList(albumType),
+				Args: graphql.FieldConfigArgument{
+					"hydrate": &graphql.ArgumentConfig{
+						Description: "Enable possibility to get tracks from albums list. Default to false.",
+						Type: graphql.Boolean,
+					},
+					"genre": &graphql.ArgumentConfig{
+						Description: "Only get albums having tracks of this genre.",
+						Type: graphql.ID,
+					},
+				},
+				Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+					if genreId, ok, err := getIdArgument(p, "genre"); ok {
+						if err != nil {
+							return nil, err
+						}
+						return interactor.Library.GetAlbumsForGenre(genreId)
+					}
+
+					if p.Args["hydrate"] != nil {
+						if hydrate, ok := p.Args["hydrate"].(bool); ok {
+							return interactor.Library.AlbumRepository.GetAll(hydrate)
+						}
+					}
+
+					return interactor.Library.AlbumRepository.GetAll(false)
+				},
+			},
+			"album": &graphql.Field{
+				Type: albumType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Description: "Album ID",
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					i := p.Args["id"].(string)
+					id, err := strconv.Atoi(i)
+					if err != nil {
+						return nil, err
+					}
+
+					return interactor.Library.AlbumRepository.Get(id)
+				},
+			},
+			"tracks": &graphql.Field{
+				Type: graphql.NewList(trackType),
+				Description: "Tracks matching every given filter, compared without case.",
+				Args: graphql.FieldConfigArgument{
+					"genre": &graphql.ArgumentConfig{
+						Description: "Only get tracks of this genre.",
+						Type: graphql.ID,
+					},
+					"musicBrainzTrackId": &graphql.ArgumentConfig{Type: graphql.String},
+					"musicBrainzAlbumId": &graphql.ArgumentConfig{Type: graphql.String},
+					"musicBrainzArtistId": &graphql.ArgumentConfig{Type: graphql.String},
+					"isrc": &graphql.ArgumentConfig{Type: graphql.String},
+					"label": &graphql.ArgumentConfig{Type: graphql.String},
+					"catalogNumber": &graphql.ArgumentConfig{Type: graphql.String},
+					"originalYear": &graphql.ArgumentConfig{Type: graphql.String},
+					"composer": &graphql.ArgumentConfig{Type: graphql.String},
+					"conductor": &graphql.ArgumentConfig{Type: graphql.String},
+					"bpmMin": &graphql.ArgumentConfig{
+						Description: "Minimum BPM, included.",
+						Type: graphql.Int,
+					},
+					"bpmMax": &graphql.ArgumentConfig{
+						Description: "Maximum BPM, included.",
+						Type: graphql.Int,
+					},
+				},
+				Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+					filter, err := getTrackFilter(p)
+					if err != nil {
+						return nil, err
+					}
+					if filter == (business.TrackFilter{}) {
+						return interactor.Library.TrackRepository.GetAll()
+					}
+
+					return interactor.Library.FindTracks(filter)
+				},
+			},
+			"track": &graphql.Field{
+				Type: trackType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Description: "Track ID",
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					i := p.Args["id"].(string)
+					id, err := strconv.Atoi(i)
+					if err != nil {
+						return nil, err
+					}
+
+					return interactor.Library.TrackRepository.Get(id)
+				},
+			},
+			"genres": &graphql.Field{
+				Type: graphql.NewList(genreType),
+				Resolve: func (p graphql.ResolveParams) (interface{}, error) {
+					return interactor.Library.GetAllGenres()
+				},
+			},
+			"genre": &graphql.Field{
+				Type: genreType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Description: "Genre ID",
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					i := p.Args["id"].(string)
+					id, err := strconv.Atoi(i)
+					if err != nil {
+						return nil, err
+					}
+
+					return interactor.Library.GetGenre(id)
+				},
+			},
+			"settings": &graphql.Field{
+				Type: settingsType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					settingsInteractor := business.ClientSettingsInteractor{}
+					return settingsInteractor.GetSettings(), nil
+				},
+			},
+			"variable": &graphql.Field{
+				Type: internalVariableType,
+				Args: graphql.FieldConfigArgument{
+					"key": &graphql.ArgumentConfig{
+						Description: "Key",
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					key := p.Args["key"].(string)
+					if key == business.MediaUrlSecretVariable {
+						return nil, nil
+					}
+					// Return nil if no variable found instead of an error.
+					variable, err := interactor.Library.InternalVariableRepository.Get(key)
+					if err == nil {
+						return variable, err
+					}
+
+					return nil, nil
+				},
+			},
+
+			"overrides": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(overrideType)),
+				Description: "Manual edits of the library metadata.",
+				Args: graphql.FieldConfigArgument{
+					"entityType": &graphql.ArgumentConfig{
+						Description: "Only get the edits of this entity type: artist, album or track.",
+						Type: graphql.String,
+					},
+					"entityId": &graphql.ArgumentConfig{
+						Description: "Only get the edits of this entity. Requires entityType.",
+						Type: graphql.ID,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					entityType, _ := p.Args["entityType"].(string)
+					entityId, _, err := getIdArgument(p, "entityId")
+					if err != nil {
+						return nil, err
+					}
+
+					return interactor.Library.GetOverrides(entityType, entityId)
+				},
+			},
+			"artistAliases": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(artistAliasType)),
+				Description: "Names of the merged artists.",
+				Args: graphql.FieldConfigArgument{
+					"artistId": &graphql.ArgumentConfig{
+						Description: "Only get the aliases of this artist.",
+						Type: graphql.ID,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					artistId, _, err := getIdArgument(p, "artistId")
+					if err != nil {
+						return nil, err
+					}
+
+					return interactor.Library.GetArtistAliases(artistId)
+				},
+			},
+			"previewTrackTags": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(tagEditType)),
+				Description: "Changes editing a track would make to its media file when tags writing is enabled.",
+				Args: getOverrideArguments(domain.OverrideEntityTrack),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _, err := getIdArgument(p, "id")
+					if err != nil {
+						return nil, err
+					}
+
+					return interactor.Library.PreviewTrackTags(id, getOverrideValues(p))
+				},
+			},
+			"previewAlbumTags": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(tagEditType)),
+				Description: "Changes editing an album would make to the media files of its tracks when tags writing is enabled.",
+				Args: getOverrideArguments(domain.OverrideEntityAlbum),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _, err := getIdArgument(p, "id")
+					if err != nil {
+						return nil, err
+					}
+
+					return interactor.Library.PreviewAlbumTags(id, getOverrideValues(p))
+				},
+			},
+
+			"loudnessAnalysis": &graphql.Field{
+				Type: loudnessAnalysisType,
+				Description: "Progress of the current or last loudness analysis.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return interactor.Library.GetLoudnessAnalysisProgress(), nil
+				},
+			},
+
+			"shares": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(shareType)),
+				Description: "Public links to albums and tracks, the most recent first.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return interactor.Library.GetShares()
+				},
+			},
+
+			"scrobbleAccounts": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(scrobbleAccountType)),
+				Description: "Linked accounts of the scrobbling services.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return interactor.Library.GetScrobbleAccounts()
+				},
+			},
+			"pendingScrobbles": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(scrobbleType)),
+				Description: "Plays waiting to be sent to the scrobbling services, the oldest first.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return interactor.Library.GetPendingScrobbles()
+				},
+			},
+			"lastFmAuthUrl": &graphql.Field{
+				Type: graphql.String,
+				Description: "URL of the Last.fm page where the user authorizes the scrobbling, null if Last.fm isn't configured. Last.fm redirects to the callback URL with a token to give to linkScrobbleAccount.",
+				Args: graphql.FieldConfigArgument{
+					"callback": &graphql.ArgumentConfig{
+						Description: "URL to which Last.fm redirects once the scrobbling is authorized.",
+						Type: graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if _, ok := interactor.Library.Scrobblers[domain.ScrobbleServiceLastFm]; !ok {
+						return nil, nil
+					}
+					callback, _ := p.Args["callback"].(string)
+
+					return getLastFmAuthUrl(callback), nil
+				},
+			},
+
+			"playQueue": &graphql.Field{
+				Type: playQueueType,
+				Description: "Play queue of the user, empty if none has been saved.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return interactor.Library.GetPlayQueue(anonymousUser)
+				},
+			},
+			"bookmarks": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(bookmarkType)),
+				Description: "Playback positions of the user, the most recently modified first.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return interactor.Library.GetBookmarks(anonymousUser)
+				},
+			},
+			"playlistDownload": &graphql.Field{
+				Type: graphql.String,
+				Description: "Signed url of the ZIP archive of a playlist, valid for a limited time.",
+				Args: graphql.FieldConfigArgument{
+					"trackIds": &graphql.ArgumentConfig{
+						Description: "IDs of the tracks of the playlist, in order.",
+						Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID))),
+					},
+					"name": &graphql.ArgumentConfig{
+						Description: "Name of the playlist, used for the archive.",
+						Type: graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					values, _ := p.Args["trackIds"].([]interface{})
+					trackIds := make([]string, len(values))
+					ids := make([]int, len(values))
+					for i, value := range values {
+						trackId, errId := strconv.Atoi(value.(string))
+						if errId != nil {
+							return nil, errId
+						}
+						trackIds[i] = strconv.Itoa(trackId)
+						ids[i] = trackId
+					}
+					name, _ := p.Args["name"].(string)
+					// Checks the tracks before giving the URL.
+					if _, err := interactor.Library.GetPlaylistDownload(name, ids); err != nil {
+						return nil, err
+					}
+
+					// The tracks are part of the signed path, not the name.
+					downloadUrl := signMediaUrl("/download/playlist/"+strings.Join(trackIds, ","), anonymousUser, time.Now())
+					if name != "" {
+						separator := "?"
+						if strings.Contains(downloadUrl, "?") {
+							separator = "&"
+						}
+						downloadUrl += separator + url.Values{"name": {name}}.Encode()
+					}
+
+					return downloadUrl, nil
+				},
+			},
+			"party": &graphql.Field{
+				Type: partyType,
+				Description: "Party of the given code.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Description: "Code of the party.",
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _ := p.Args["id"].(string)
+
+					return interactor.Library.GetParty(id)
+				},
+			},
+
+			// TODO: I don't think using queries here is okay.
+			"updateLibrary": &graphql.Field{
+				Type: libraryUpdateStateType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if interactor.Library.LibraryIsUpdating {
+						return nil, errors.New("library currently updating")
+					}
+					interactor.Library.UpdateLibrary()
+
+					return nil, nil
+				},
+			},
+			"eraseLibrary": &graphql.Field{
+				Type: libraryUpdateStateType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if interactor.Library.LibraryIsUpdating {
+						return nil, errors.New("library currently updating")
+					}
+					interactor.Library.EraseLibrary()
+
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	// Edits of the library.
+	rootMutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"updateArtist": &graphql.Field{
+				Type: artistType,
+				Description: "Edits an artist. Edits are kept when the library is updated.",
+				Args: getOverrideArguments(domain.OverrideEntityArtist),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _, err := getIdArgument(p, "id")
+					if err != nil {
+						return nil, err
+					}
+
+					return interactor.Library.UpdateArtist(id, getOverrideValues(p))
+				},
+			},
+			"updateAlbum": &graphql.Field{
+				Type: albumType,
+				Description: "Edits an album. Edits are kept when the library is updated, or written into the media files of its tracks when tags writing is enabled.",
+				Args: getOverrideArguments(domain.OverrideEntityAlbum),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _, err := getIdArgument(p, "id")
+					if err != nil {
+						return nil, err
+					}
+
+					return interactor.Library.UpdateAlbum(id, getOverrideValues(p))
+				},
+			},
+			"updateTrack": &graphql.Field{
+				Type: trackType,
+				Description: "Edits a track. Edits are kept when the library is updated, or written into its media file when tags writing is enabled.",
+				Args: getOverrideArguments(domain.OverrideEntityTrack),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _, err := getIdArgument(p, "id")
+					if err != nil {
+						return nil, err
+					}
+
+					return interactor.Library.UpdateTrack(id, getOverrideValues(p))
+				},
+			},
+			"mergeArtists": &graphql.Field{
+				Type: artistType,
+				Description: "Merges artists into a target artist. The names of the merged artists are kept as aliases of the target artist. Returns the target artist.",
+				Args: graphql.FieldConfigArgument{
+					"sourceIds": &graphql.ArgumentConfig{
+						Description: "IDs of the artists to merge.",
+						Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID))),
+					},
+					"targetId": &graphql.ArgumentConfig{
+						Description: "ID of the artist to merge into.",
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					targetId, _, err := getIdArgument(p, "targetId")
+					if err != nil {
+						return nil, err
+					}
+					var sourceIds []int
+					values, _ := p.Args["sourceIds"].([]interface{})
+					for _, value := range values {
+						sourceId, errId := strconv.Atoi(value.(string))
+						if errId != nil {
+							return nil, errId
+						}
+						sourceIds = append(sourceIds, sourceId)
+					}
+
+					return interactor.Library.MergeArtists(sourceIds, targetId)
+				},
+			},
+			"undoArtistMerge": &graphql.Field{
+				Type: artistType,
+				Description: "Undoes the merge which created an alias. Returns the restored artist.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Description: "Artist alias ID",
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _, err := getIdArgument(p, "id")
+					if err != nil {
+						return nil, err
+					}
 
-			return nil, nil
-		},
-	})
+					return interactor.Library.UndoArtistMerge(id)
+				},
+			},
+			"revertOverride": &graphql.Field{
+				Type: overrideType,
+				Description: "Restores the value read from the media files. Returns the deleted override.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Description: "Override ID",
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _, err := getIdArgument(p, "id")
+					if err != nil {
+						return nil, err
+					}
 
-	// This is the type that will be the root of our query,
-	// and the entry point into our schema.
-	rootQuery := graphql.NewObject(graphql.ObjectConfig{
-		Name: "Query",
-		Fields: graphql.Fields{
-			"artists": &graphql.Field{
-				Type: graphql.NewList(artistType),
-				Resolve: func (g graphql.ResolveParams) (interface{}, error) {
-					return interactor.Library.GetAllArtists(false)
+					return interactor.Library.RevertOverride(id)
 				},
 			},
-			"artist": &graphql.Field{
-				Type: artistType,
+			"createShare": &graphql.Field{
+				Type: shareType,
+				Description: "Creates a public link to an album or a track.",
 				Args: graphql.FieldConfigArgument{
+					"entityType": &graphql.ArgumentConfig{
+						Description: "Type of the entity to share: album or track.",
+						Type: graphql.NewNonNull(graphql.String),
+					},
 					"id": &graphql.ArgumentConfig{
-						Description: "Artist ID",
+						Description: "ID of the entity to share.",
 						Type: graphql.NewNonNull(graphql.ID),
 					},
+					"expiresAt": &graphql.ArgumentConfig{
+						Description: "Unix time at which the link expires. The link never expires if null.",
+						Type: graphql.Int,
+					},
+					"maxPlays": &graphql.ArgumentConfig{
+						Description: "Maximum number of track plays. Plays are unlimited if null.",
+						Type: graphql.Int,
+					},
+					"allowDownload": &graphql.ArgumentConfig{
+						Description: "Allow the shared tracks to be downloaded.",
+						Type: graphql.Boolean,
+					},
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					i := p.Args["id"].(string)
-					id, err := strconv.Atoi(i)
+					id, _, err := getIdArgument(p, "id")
 					if err != nil {
 						return nil, err
 					}
+					entityType, _ := p.Args["entityType"].(string)
+					expiresAt, _ := p.Args["expiresAt"].(int)
+					maxPlays, _ := p.Args["maxPlays"].(int)
+					allowDownload, _ := p.Args["allowDownload"].(bool)
 
-					return interactor.Library.ArtistRepository.Get(id)
+					return interactor.Library.CreateShare(entityType, id, int64(expiresAt), maxPlays, allowDownload)
 				},
 			},
-			"albums": &graphql.Field{
-				Type: graphql.NewList(albumType),
+			"createPlaylistShare": &graphql.Field{
+				Type: shareType,
+				Description: "Creates a public link to a playlist.",
 				Args: graphql.FieldConfigArgument{
-					"hydrate": &graphql.ArgumentConfig{
-						Description: "Enable possibility to get tracks from albums list. Default to false.",
+					"trackIds": &graphql.ArgumentConfig{
+						Description: "IDs of the tracks of the playlist, in order.",
+						Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID))),
+					},
+					"name": &graphql.ArgumentConfig{
+						Description: "Name of the playlist, shown on its page.",
+						Type: graphql.String,
+					},
+					"expiresAt": &graphql.ArgumentConfig{
+						Description: "Unix time at which the link expires. The link never expires if null.",
+						Type: graphql.Int,
+					},
+					"maxPlays": &graphql.ArgumentConfig{
+						Description: "Maximum number of track plays. Plays are unlimited if null.",
+						Type: graphql.Int,
+					},
+					"allowDownload": &graphql.ArgumentConfig{
+						Description: "Allow the shared tracks to be downloaded.",
 						Type: graphql.Boolean,
 					},
 				},
-				Resolve: func (p graphql.ResolveParams) (interface{}, error) {
-					if p.Args["hydrate"] != nil {
-						if hydrate, ok := p.Args["hydrate"].(bool); ok {
-							return interactor.Library.AlbumRepository.GetAll(hydrate)
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					values, _ := p.Args["trackIds"].([]interface{})
+					trackIds := make([]int, len(values))
+					for i, value := range values {
+						trackId, errId := strconv.Atoi(value.(string))
+						if errId != nil {
+							return nil, errId
 						}
+						trackIds[i] = trackId
 					}
+					name, _ := p.Args["name"].(string)
+					expiresAt, _ := p.Args["expiresAt"].(int)
+					maxPlays, _ := p.Args["maxPlays"].(int)
+					allowDownload, _ := p.Args["allowDownload"].(bool)
 
-					return interactor.Library.AlbumRepository.GetAll(false)
+					return interactor.Library.CreatePlaylistShare(name, trackIds, int64(expiresAt), maxPlays, allowDownload)
 				},
 			},
-			"album": &graphql.Field{
-				Type: albumType,
+			"revokeShare": &graphql.Field{
+				Type: shareType,
+				Description: "Deletes a public link, which stops working. Returns the deleted share.",
 				Args: graphql.FieldConfigArgument{
 					"id": &graphql.ArgumentConfig{
-						Description: "Album ID",
+						Description: "Share ID",
 						Type: graphql.NewNonNull(graphql.ID),
 					},
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					i := p.Args["id"].(string)
-					id, err := strconv.Atoi(i)
+					id, _, err := getIdArgument(p, "id")
 					if err != nil {
 						return nil, err
 					}
 
-					return interactor.Library.AlbumRepository.Get(id)
+					return interactor.Library.RevokeShare(id)
 				},
 			},
-			"tracks": &graphql.Field{
-				Type: graphql.NewList(trackType),
-				Resolve: func (g graphql.ResolveParams) (interface{}, error) {
-					return interactor.Library.TrackRepository.GetAll()
+			"linkScrobbleAccount": &graphql.Field{
+				Type: scrobbleAccountType,
+				Description: "Links an account of a scrobbling service, to which the plays are then sent.",
+				Args: graphql.FieldConfigArgument{
+					"service": &graphql.ArgumentConfig{
+						Description: "Scrobbling service: lastfm or listenbrainz.",
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"token": &graphql.ArgumentConfig{
+						Description: "Token given by Last.fm after the authorization, or ListenBrainz user token.",
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					service, _ := p.Args["service"].(string)
+					token, _ := p.Args["token"].(string)
+
+					return interactor.Library.LinkScrobbleAccount(service, token)
 				},
 			},
-			"track": &graphql.Field{
-				Type: trackType,
+			"unlinkScrobbleAccount": &graphql.Field{
+				Type: scrobbleAccountType,
+				Description: "Unlinks an account of a scrobbling service, dropping its pending scrobbles. Returns the unlinked account.",
 				Args: graphql.FieldConfigArgument{
 					"id": &graphql.ArgumentConfig{
+						Description: "Scrobble account ID",
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _, err := getIdArgument(p, "id")
+					if err != nil {
+						return nil, err
+					}
+
+					return interactor.Library.UnlinkScrobbleAccount(id)
+				},
+			},
+			"reportPlay": &graphql.Field{
+				Type: graphql.Boolean,
+				Description: "Reports the play of a track to the linked scrobbling accounts. Returns true if the play will be scrobbled.",
+				Args: graphql.FieldConfigArgument{
+					"trackId": &graphql.ArgumentConfig{
 						Description: "Track ID",
 						Type: graphql.NewNonNull(graphql.ID),
 					},
+					"nowPlaying": &graphql.ArgumentConfig{
+						Description: "True when the track starts playing, false once the play is over.",
+						Type: graphql.Boolean,
+					},
+					"playedSeconds": &graphql.ArgumentConfig{
+						Description: "Time the track was listened to. The whole track is considered played if null.",
+						Type: graphql.Int,
+					},
+					"startedAt": &graphql.ArgumentConfig{
+						Description: "Unix time at which the play started. Computed from the time played if null.",
+						Type: graphql.Int,
+					},
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					i := p.Args["id"].(string)
-					id, err := strconv.Atoi(i)
+					trackId, _, err := getIdArgument(p, "trackId")
 					if err != nil {
 						return nil, err
 					}
+					nowPlaying, _ := p.Args["nowPlaying"].(bool)
+					playedSeconds, ok := p.Args["playedSeconds"].(int)
+					if !ok {
+						playedSeconds = -1
+					}
+					startedAt, _ := p.Args["startedAt"].(int)
 
-					return interactor.Library.TrackRepository.Get(id)
+					queued, err := interactor.Library.ReportPlay(trackId, nowPlaying, playedSeconds, int64(startedAt))
+					if queued {
+						// Services may be slow to answer.
+						go flushScrobbles(interactor.Library)
+					}
+
+					return queued, err
 				},
 			},
-			"settings": &graphql.Field{
-				Type: settingsType,
+			"savePlayQueue": &graphql.Field{
+				Type: playQueueType,
+				Description: "Saves the play queue of the user, which is sent to the other clients subscribed to it.",
+				Args: graphql.FieldConfigArgument{
+					"trackIds": &graphql.ArgumentConfig{
+						Description: "IDs of the tracks of the queue, in order.",
+						Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID))),
+					},
+					"currentIndex": &graphql.ArgumentConfig{
+						Description: "Index of the track being played.",
+						Type: graphql.Int,
+					},
+					"position": &graphql.ArgumentConfig{
+						Description: "Playback position in the current track, in milliseconds.",
+						Type: graphql.Int,
+					},
+					"clientId": &graphql.ArgumentConfig{
+						Description: "Identifier of the client saving the queue, which doesn't receive its own changes.",
+						Type: graphql.String,
+					},
+				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					settingsInteractor := business.ClientSettingsInteractor{}
-					return settingsInteractor.GetSettings(), nil
+					values, _ := p.Args["trackIds"].([]interface{})
+					trackIds := make([]int, len(values))
+					for i, value := range values {
+						trackId, errId := strconv.Atoi(value.(string))
+						if errId != nil {
+							return nil, errId
+						}
+						trackIds[i] = trackId
+					}
+					currentIndex, _ := p.Args["currentIndex"].(int)
+					position, _ := p.Args["position"].(int)
+					clientId, _ := p.Args["clientId"].(string)
+
+					return interactor.Library.SavePlayQueue(anonymousUser, clientId, trackIds, currentIndex, position)
 				},
 			},
-			"variable": &graphql.Field{
-				Type: internalVariableType,
+			"saveBookmark": &graphql.Field{
+				Type: bookmarkType,
+				Description: "Saves the playback position of the user in a track. Returns null if the position is at the end of the track, which removes the bookmark.",
 				Args: graphql.FieldConfigArgument{
-					"key": &graphql.ArgumentConfig{
-						Description: "Key",
+					"trackId": &graphql.ArgumentConfig{
+						Description: "Track ID",
 						Type: graphql.NewNonNull(graphql.ID),
 					},
+					"position": &graphql.ArgumentConfig{
+						Description: "Playback position in the track, in milliseconds.",
+						Type: graphql.NewNonNull(graphql.Int),
+					},
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					key := p.Args["key"].(string)
-					// Return nil if no variable found instead of an error.
-					variable, err := interactor.Library.InternalVariableRepository.Get(key)
-					if err == nil {
-						return variable, err
+					trackId, _, err := getIdArgument(p, "trackId")
+					if err != nil {
+						return nil, err
+					}
+					position, _ := p.Args["position"].(int)
+					bookmark, err := interactor.Library.SaveBookmark(anonymousUser, trackId, position)
+					if err != nil || bookmark.Id == 0 {
+						return nil, err
 					}
 
-					return nil, nil
+					return bookmark, nil
+				},
+			},
+			"deleteBookmark": &graphql.Field{
+				Type: bookmarkType,
+				Description: "Deletes the playback position of the user in a track. Returns the deleted bookmark.",
+				Args: graphql.FieldConfigArgument{
+					"trackId": &graphql.ArgumentConfig{
+						Description: "Track ID",
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					trackId, _, err := getIdArgument(p, "trackId")
+					if err != nil {
+						return nil, err
+					}
+
+					return interactor.Library.DeleteBookmark(anonymousUser, trackId)
 				},
 			},
+			"createParty": &graphql.Field{
+				Type: partySessionType,
+				Description: "Creates a party, whose first participant is its creator.",
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{
+						Description: "Name of the party.",
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"participantName": &graphql.ArgumentConfig{
+						Description: "Name of the creator.",
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"hosted": &graphql.ArgumentConfig{
+						Description: "Makes the creator the only participant allowed to control the playback.",
+						Type: graphql.Boolean,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					name, _ := p.Args["name"].(string)
+					participantName, _ := p.Args["participantName"].(string)
+					hosted, _ := p.Args["hosted"].(bool)
+					party, participant, err := interactor.Library.CreateParty(name, participantName, hosted)
+					if err != nil {
+						return nil, err
+					}
 
-			// TODO: I don't think using queries here is okay.
-			"updateLibrary": &graphql.Field{
-				Type: libraryUpdateStateType,
+					return partySession{Token: participant.Token, Participant: participant, Party: party}, nil
+				},
+			},
+			"joinParty": &graphql.Field{
+				Type: partySessionType,
+				Description: "Joins a party.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Description: "Code of the party.",
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+					"participantName": &graphql.ArgumentConfig{
+						Description: "Name of the participant.",
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if interactor.Library.LibraryIsUpdating {
-						return nil, errors.New("library currently updating")
+					id, _ := p.Args["id"].(string)
+					participantName, _ := p.Args["participantName"].(string)
+					party, participant, err := interactor.Library.JoinParty(id, participantName)
+					if err != nil {
+						return nil, err
 					}
-					interactor.Library.UpdateLibrary()
 
-					return nil, nil
+					return partySession{Token: participant.Token, Participant: participant, Party: party}, nil
 				},
 			},
-			"eraseLibrary": &graphql.Field{
-				Type: libraryUpdateStateType,
+			"leaveParty": &graphql.Field{
+				Type: graphql.Boolean,
+				Description: "Leaves a party, which is closed if the participant is its host or its last participant.",
+				Args: getPartyArguments(nil),
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					if interactor.Library.LibraryIsUpdating {
-						return nil, errors.New("library currently updating")
+					id, token := getPartyCredentials(p)
+					if err := interactor.Library.LeaveParty(id, token); err != nil {
+						return nil, err
 					}
-					interactor.Library.EraseLibrary()
 
-					return nil, nil
+					return true, nil
+				},
+			},
+			"addPartyTrack": &graphql.Field{
+				Type: partyType,
+				Description: "Adds a track to the queue of a party.",
+				Args: getPartyArguments(graphql.FieldConfigArgument{
+					"trackId": &graphql.ArgumentConfig{
+						Description: "ID of the track to add.",
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				}),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, token := getPartyCredentials(p)
+					trackId, _, err := getIdArgument(p, "trackId")
+					if err != nil {
+						return nil, err
+					}
+
+					return interactor.Library.AddPartyTrack(id, token, trackId)
+				},
+			},
+			"votePartyTrack": &graphql.Field{
+				Type: partyType,
+				Description: "Votes a track of a party up (1) or down (-1), or withdraws the vote (0).",
+				Args: getPartyArguments(graphql.FieldConfigArgument{
+					"itemId": &graphql.ArgumentConfig{
+						Description: "ID of the track in the party.",
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+					"vote": &graphql.ArgumentConfig{
+						Description: "1, -1 or 0.",
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+				}),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, token := getPartyCredentials(p)
+					itemId, _, err := getIdArgument(p, "itemId")
+					if err != nil {
+						return nil, err
+					}
+					vote, _ := p.Args["vote"].(int)
+
+					return interactor.Library.VotePartyTrack(id, token, itemId, vote)
+				},
+			},
+			"controlParty": &graphql.Field{
+				Type: partyType,
+				Description: "Controls the playback of a party: play, pause or next.",
+				Args: getPartyArguments(graphql.FieldConfigArgument{
+					"action": &graphql.ArgumentConfig{
+						Description: "play, pause or next.",
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				}),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, token := getPartyCredentials(p)
+					action, _ := p.Args["action"].(string)
+
+					return interactor.Library.ControlParty(id, token, action)
+				},
+			},
+			"analyzeLoudness": &graphql.Field{
+				Type: loudnessAnalysisType,
+				Description: "Starts the loudness analysis of the tracks not analyzed yet, or of all the tracks if force is true.",
+				Args: graphql.FieldConfigArgument{
+					"force": &graphql.ArgumentConfig{
+						Description: "Analyze again the tracks already analyzed.",
+						Type: graphql.Boolean,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					force, _ := p.Args["force"].(bool)
+					if err := interactor.Library.StartLoudnessAnalysis(force); err != nil {
+						return nil, err
+					}
+
+					return interactor.Library.GetLoudnessAnalysisProgress(), nil
 				},
 			},
 		},
@@ -579,8 +3032,54 @@ func NewGraphQLInteractor(ci *business.LibraryInteractor) *graphQLInteractor {
 	 * type we defined above) and export it.
 	 */
 	var err error
+	rootSubscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"playQueue": &graphql.Field{
+				Type: playQueueType,
+				Description: "Play queue of the user, sent each time another client saves it.",
+				Args: graphql.FieldConfigArgument{
+					"clientId": &graphql.ArgumentConfig{
+						Description: "Identifier of the subscribing client, whose own changes aren't sent.",
+						Type: graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					queue, ok := getSubscriptionEvent(p).(domain.PlayQueue)
+					clientId, _ := p.Args["clientId"].(string)
+					if !ok || (clientId != "" && queue.ClientId == clientId) {
+						return nil, nil
+					}
+
+					return queue, nil
+				},
+			},
+			"party": &graphql.Field{
+				Type: partyType,
+				Description: "Party of the given code, sent each time it changes. The subscription completes once the party is closed.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Description: "Code of the party.",
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					party, ok := getSubscriptionEvent(p).(business.Party)
+					id, _ := p.Args["id"].(string)
+					if !ok || party.Id != id {
+						return nil, nil
+					}
+
+					return party, nil
+				},
+			},
+		},
+	})
+
 	interactor.Schema, err = graphql.NewSchema(graphql.SchemaConfig{
 		Query: rootQuery,
+		Mutation: rootMutation,
+		Subscription: rootSubscription,
 	})
 	if err != nil {
 		panic(err)
@@ -588,3 +3087,102 @@ func NewGraphQLInteractor(ci *business.LibraryInteractor) *graphQLInteractor {
 
 	return interactor
 }
+
+/*
+Gets an optional ID argument from a GraphQL query.
+
+Returns ok == false if the argument has not been provided.
+*/
+func getIdArgument(p graphql.ResolveParams, name string) (id int, ok bool, err error) {
+	value, ok := p.Args[name].(string)
+	if !ok {
+		return
+	}
+
+	id, err = strconv.Atoi(value)
+	return
+}
+
+/*
+Builds the arguments of a mutation editing an entity: its ID and one optional argument per editable field.
+*/
+func getOverrideArguments(entityType string) graphql.FieldConfigArgument {
+	args := graphql.FieldConfigArgument{
+		"id": &graphql.ArgumentConfig{
+			Description: "ID of the " + entityType + " to edit.",
+			Type: graphql.NewNonNull(graphql.ID),
+		},
+	}
+	for _, field := range business.OverridableFields[entityType] {
+		args[field] = &graphql.ArgumentConfig{Type: graphql.String}
+	}
+
+	return args
+}
+
+/*
+Gets the values of the fields to edit from a mutation arguments.
+*/
+func getOverrideValues(p graphql.ResolveParams) map[string]string {
+	values := make(map[string]string)
+	for name, value := range p.Args {
+		if name == "id" {
+			continue
+		}
+		if s, ok := value.(string); ok {
+			values[name] = s
+		}
+	}
+
+	return values
+}
+
+// Gets the arguments identifying a party and a participant, followed by the given ones.
+func getPartyArguments(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	partyArgs := graphql.FieldConfigArgument{
+		"id": &graphql.ArgumentConfig{
+			Description: "Code of the party.",
+			Type: graphql.NewNonNull(graphql.ID),
+		},
+		"token": &graphql.ArgumentConfig{
+			Description: "Token of the participant, returned by createParty or joinParty.",
+			Type: graphql.NewNonNull(graphql.String),
+		},
+	}
+	for name, arg := range args {
+		partyArgs[name] = arg
+	}
+
+	return partyArgs
+}
+
+// Gets the code of the party and the token of the participant.
+func getPartyCredentials(p graphql.ResolveParams) (id string, token string) {
+	id, _ = p.Args["id"].(string)
+	token, _ = p.Args["token"].(string)
+
+	return
+}
+
+// Gets the filter of a tracks query from its arguments.
+func getTrackFilter(p graphql.ResolveParams) (filter business.TrackFilter, err error) {
+	if genreId, ok, errId := getIdArgument(p, "genre"); ok {
+		if errId != nil {
+			return filter, errId
+		}
+		filter.GenreId = genreId
+	}
+	filter.MusicBrainzTrackId, _ = p.Args["musicBrainzTrackId"].(string)
+	filter.MusicBrainzAlbumId, _ = p.Args["musicBrainzAlbumId"].(string)
+	filter.MusicBrainzArtistId, _ = p.Args["musicBrainzArtistId"].(string)
+	filter.Isrc, _ = p.Args["isrc"].(string)
+	filter.Label, _ = p.Args["label"].(string)
+	filter.CatalogNumber, _ = p.Args["catalogNumber"].(string)
+	filter.OriginalYear, _ = p.Args["originalYear"].(string)
+	filter.Composer, _ = p.Args["composer"].(string)
+	filter.Conductor, _ = p.Args["conductor"].(string)
+	filter.BpmMin, _ = p.Args["bpmMin"].(int)
+	filter.BpmMax, _ = p.Args["bpmMax"].(int)
+
+	return
+}
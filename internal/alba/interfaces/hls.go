@@ -0,0 +1,228 @@
+package interfaces
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/spf13/viper"
+)
+
+/*
+This file exposes the HTTP Live Streaming (HLS) of the tracks.
+
+Each track is available in several bitrates, listed by a master playlist. The first request for a bitrate starts
+ffmpeg, which transcodes the track to AAC segments of a few seconds and writes them with their playlist in a directory
+of Hls.Directory. The playlist is an event playlist growing while the segments are written, so the playback can start
+with the first segment. Directories which haven't been requested for Hls.CacheDuration minutes are removed.
+
+Like the other media URLs, the URLs of the playlists and of the segments are signed, see signMediaUrl(). The playlists
+are served with the URLs of their variants or segments signed for the user of the request.
+*/
+
+// Name of the playlists written by ffmpeg.
+const hlsPlaylistName = "playlist.m3u8"
+
+// Transcoding of a track to HLS segments, for one bitrate.
+type hlsJob struct {
+	directory  string
+	cancel     context.CancelFunc
+	done       chan struct{} // Closed once ffmpeg has exited.
+	err        error         // Error of ffmpeg, set before done is closed.
+	lastAccess time.Time     // Guarded by the segmenter mutex.
+}
+
+// Manages the HLS transcoding jobs.
+type hlsSegmenter struct {
+	mutex sync.Mutex
+	jobs  map[string]*hlsJob
+}
+
+func newHlsSegmenter() *hlsSegmenter {
+	return &hlsSegmenter{jobs: make(map[string]*hlsJob)}
+}
+
+// Gets the bitrates of the HLS variants, in kbit/s.
+func getHlsBitrates() (bitrates []int) {
+	for _, value := range viper.GetStringSlice("Hls.Bitrates") {
+		if bitrate, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && bitrate > 0 {
+			bitrates = append(bitrates, bitrate)
+		}
+	}
+
+	return
+}
+
+// Checks if a file name is the name of a segment written by ffmpeg, like "12.ts".
+func isHlsSegmentName(name string) bool {
+	number := strings.TrimSuffix(name, ".ts")
+	_, err := strconv.ParseUint(number, 10, 32)
+
+	return number != name && err == nil
+}
+
+// Gets the HLS master playlist of a track, listing the playlists of the variants signed for a user.
+func getHlsMasterPlaylist(trackId int, bitrates []int, user string, now time.Time) string {
+	base := "/hls/" + strconv.Itoa(trackId) + "/"
+	playlist := "#EXTM3U\n#EXT-X-VERSION:3\n"
+	for _, bitrate := range bitrates {
+		// MPEG-TS adds about 10% to the audio bitrate.
+		bandwidth := bitrate * 1100
+		playlist += "#EXT-X-STREAM-INF:BANDWIDTH=" + strconv.Itoa(bandwidth) + ",CODECS=\"mp4a.40.2\"\n"
+		playlist += getHlsFileUrl(base, strconv.Itoa(bitrate)+"/"+hlsPlaylistName, user, now) + "\n"
+	}
+
+	return playlist
+}
+
+// Signs the segments of the playlist of a variant written by ffmpeg, which is served from base, for a user.
+func signHlsPlaylist(playlist string, base string, user string, now time.Time) string {
+	lines := strings.Split(playlist, "\n")
+	for i, line := range lines {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines[i] = getHlsFileUrl(base, line, user, now)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Gets the signed URL of a file of a HLS playlist served from base, relative to the playlist, see signMediaUrl().
+func getHlsFileUrl(base string, name string, user string, now time.Time) string {
+	return strings.TrimPrefix(signMediaUrl(base+name, user, now), base)
+}
+
+// Gets the ffmpeg command line arguments to transcode a track to HLS segments in a directory.
+func getHlsArgs(track domain.Track, bitrate int, segmentDuration int, directory string) []string {
+	args := getTranscodingInputArgs(track.Path, transcodingOptions{StartMs: track.StartMs, EndMs: track.EndMs})
+
+	return append(args,
+		"-c:a", "aac", "-b:a", strconv.Itoa(bitrate)+"k",
+		"-f", "hls", "-hls_time", strconv.Itoa(segmentDuration), "-hls_list_size", "0",
+		"-hls_playlist_type", "event", "-hls_flags", "temp_file",
+		"-hls_segment_filename", filepath.Join(directory, "%d.ts"),
+		filepath.Join(directory, hlsPlaylistName),
+	)
+}
+
+/*
+Gets the transcoding job of a track for a bitrate, starting it if needed.
+
+Returns errTranscodingDisabled if no ffmpeg executable is configured.
+*/
+func (s *hlsSegmenter) getJob(track domain.Track, bitrate int) (*hlsJob, error) {
+	key := strconv.Itoa(track.Id) + "-" + strconv.Itoa(bitrate)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if job, ok := s.jobs[key]; ok {
+		// Failed jobs are started again.
+		select {
+		case <-job.done:
+			if job.err == nil {
+				job.lastAccess = time.Now()
+				return job, nil
+			}
+		default:
+			job.lastAccess = time.Now()
+			return job, nil
+		}
+	}
+
+	ffmpegPath := viper.GetString("Transcoding.FfmpegPath")
+	if ffmpegPath == "" {
+		return nil, errTranscodingDisabled
+	}
+
+	directory := filepath.Join(viper.GetString("Hls.Directory"), key)
+	if err := os.RemoveAll(directory); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &hlsJob{directory: directory, cancel: cancel, done: make(chan struct{}), lastAccess: time.Now()}
+	cmd := exec.CommandContext(ctx, ffmpegPath, getHlsArgs(track, bitrate, viper.GetInt("Hls.SegmentDuration"), directory)...)
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+	go func() {
+		job.err = cmd.Wait()
+		close(job.done)
+	}()
+	s.jobs[key] = job
+
+	return job, nil
+}
+
+/*
+Removes the jobs which haven't been accessed for a duration, stopping them if they are still running, and their
+files.
+
+The directories of Hls.Directory which don't belong to a job, like the ones of a previous run, are removed too.
+*/
+func (s *hlsSegmenter) cleanUp(maxAge time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	directories := make(map[string]bool)
+	for key, job := range s.jobs {
+		if time.Since(job.lastAccess) > maxAge {
+			job.cancel()
+			<-job.done
+			_ = os.RemoveAll(job.directory)
+			delete(s.jobs, key)
+		} else {
+			directories[filepath.Base(job.directory)] = true
+		}
+	}
+
+	files, err := ioutil.ReadDir(viper.GetString("Hls.Directory"))
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if file.IsDir() && !directories[file.Name()] {
+			_ = os.RemoveAll(filepath.Join(viper.GetString("Hls.Directory"), file.Name()))
+		}
+	}
+}
+
+/*
+Waits for a file written by a job, until it exists, the job ends or the context is done.
+
+Returns the path of the file, or an error if it will never exist.
+*/
+func (job *hlsJob) waitForFile(ctx context.Context, name string) (string, error) {
+	path := filepath.Join(job.directory, name)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+
+		select {
+		case <-job.done:
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+			if job.err != nil {
+				return "", job.err
+			}
+			return "", os.ErrNotExist
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package interfaces
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type HlsTestSuite struct {
+	suite.Suite
+	Directory string
+}
+
+// Go testing framework entry point.
+func TestHlsTestSuite(t *testing.T) {
+	suite.Run(t, new(HlsTestSuite))
+}
+
+func (suite *HlsTestSuite) SetupTest() {
+	directory, err := ioutil.TempDir("", "alba-hls")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.Directory = directory
+	viper.Set("Hls.Directory", filepath.Join(directory, "hls"))
+	viper.Set("Hls.SegmentDuration", 6)
+}
+
+func (suite *HlsTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.Directory)
+	viper.Set("Transcoding.FfmpegPath", "")
+	viper.Set("Hls.Directory", "")
+}
+
+// Uses a fake ffmpeg executable, writing a segment and the playlist to the directory of the output, or failing.
+func (suite *HlsTestSuite) setFfmpegScript(fail bool) {
+	script := "#!/bin/sh\nfor last; do :; done\nprintf 'segment' > \"$(dirname \"$last\")/0.ts\"\nprintf '#EXTM3U\\n' > \"$last\"\n"
+	if fail {
+		script = "#!/bin/sh\nexit 1\n"
+	}
+	path := filepath.Join(suite.Directory, "ffmpeg")
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		suite.T().Fatal(err)
+	}
+	viper.Set("Transcoding.FfmpegPath", path)
+}
+
+func (suite *HlsTestSuite) TestGetHlsMasterPlaylist() {
+	viper.Set("Hls.Bitrates", []interface{}{64, "128", "wrong"})
+	bitrates := getHlsBitrates()
+	assert.Equal(suite.T(), []int{64, 128}, bitrates)
+
+	assert.Equal(suite.T(), "#EXTM3U\n#EXT-X-VERSION:3\n"+
+		"#EXT-X-STREAM-INF:BANDWIDTH=70400,CODECS=\"mp4a.40.2\"\n64/playlist.m3u8\n"+
+		"#EXT-X-STREAM-INF:BANDWIDTH=140800,CODECS=\"mp4a.40.2\"\n128/playlist.m3u8\n", getHlsMasterPlaylist(1, bitrates, anonymousUser, time.Now()))
+}
+
+func (suite *HlsTestSuite) TestSignHlsPlaylists() {
+	viper.Set("MediaUrls.Signed", true)
+	viper.Set("MediaUrls.Secret", "secret")
+	viper.Set("MediaUrls.Expiry", 24)
+	defer viper.Set("MediaUrls.Signed", false)
+	defer viper.Set("MediaUrls.Expiry", 0)
+	defer viper.Set("MediaUrls.Secret", "")
+	now := time.Now()
+
+	// The URLs of the variants stay relative to the master playlist, and are signed for the user.
+	lines := strings.Split(getHlsMasterPlaylist(12, []int{64}, "john", now), "\n")
+	assert.Len(suite.T(), lines, 5)
+	assert.Regexp(suite.T(), `^64/playlist\.m3u8\?expires=\d+&signature=[\w-]+&user=john$`, lines[3])
+	request := httptest.NewRequest("GET", "/hls/12/"+lines[3], nil)
+	assert.Nil(suite.T(), verifyMediaUrl(request, "/hls/12/64/playlist.m3u8", now))
+
+	// The segments of the playlists of the variants.
+	playlist := signHlsPlaylist("#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.0,\n0.ts\n#EXTINF:6.0,\n1.ts\n", "/hls/12/64/", anonymousUser, now)
+	lines = strings.Split(playlist, "\n")
+	assert.Equal(suite.T(), "#EXTINF:6.0,", lines[2])
+	assert.Regexp(suite.T(), `^0\.ts\?expires=\d+&signature=[\w-]+$`, lines[3])
+	assert.Regexp(suite.T(), `^1\.ts\?`, lines[5])
+	request = httptest.NewRequest("GET", "/hls/12/64/"+lines[5], nil)
+	assert.Nil(suite.T(), verifyMediaUrl(request, "/hls/12/64/1.ts", now))
+	assert.Equal(suite.T(), errMediaUrlInvalid, verifyMediaUrl(request, "/hls/12/64/0.ts", now))
+
+	// Without signing, the playlists are left as is.
+	viper.Set("MediaUrls.Signed", false)
+	assert.Equal(suite.T(), "#EXTM3U\n0.ts\n", signHlsPlaylist("#EXTM3U\n0.ts\n", "/hls/12/64/", anonymousUser, now))
+}
+
+func (suite *HlsTestSuite) TestGetHlsArgs() {
+	track := domain.Track{Path: "/music/Album.flac", StartMs: 1500, EndMs: 90000}
+	assert.Equal(suite.T(), []string{
+		"-v", "error", "-nostdin",
+		"-ss", "1.500", "-to", "90.000",
+		"-i", "/music/Album.flac", "-map", "0:a:0", "-map_metadata", "-1",
+		"-c:a", "aac", "-b:a", "128k",
+		"-f", "hls", "-hls_time", "6", "-hls_list_size", "0",
+		"-hls_playlist_type", "event", "-hls_flags", "temp_file",
+		"-hls_segment_filename", "/hls/1-128/%d.ts",
+		"/hls/1-128/playlist.m3u8",
+	}, getHlsArgs(track, 128, 6, "/hls/1-128"))
+}
+
+func (suite *HlsTestSuite) TestIsHlsSegmentName() {
+	assert.True(suite.T(), isHlsSegmentName("0.ts"))
+	assert.True(suite.T(), isHlsSegmentName("124.ts"))
+	assert.False(suite.T(), isHlsSegmentName("124"))
+	assert.False(suite.T(), isHlsSegmentName(".ts"))
+	assert.False(suite.T(), isHlsSegmentName("../1.ts"))
+	assert.False(suite.T(), isHlsSegmentName("-1.ts"))
+}
+
+func (suite *HlsTestSuite) TestJobs() {
+	segmenter := newHlsSegmenter()
+	track := domain.Track{Id: 1, Path: "/music/Track.mp3"}
+
+	_, err := segmenter.getJob(track, 128)
+	assert.Equal(suite.T(), errTranscodingDisabled, err)
+
+	suite.setFfmpegScript(false)
+	job, err := segmenter.getJob(track, 128)
+	assert.Nil(suite.T(), err)
+	path, err := job.waitForFile(context.Background(), hlsPlaylistName)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), filepath.Join(suite.Directory, "hls", "1-128", hlsPlaylistName), path)
+	_, err = job.waitForFile(context.Background(), "0.ts")
+	assert.Nil(suite.T(), err)
+	_, err = job.waitForFile(context.Background(), "1.ts")
+	assert.Equal(suite.T(), os.ErrNotExist, err)
+
+	// Jobs are reused.
+	other, _ := segmenter.getJob(track, 128)
+	assert.Equal(suite.T(), job, other)
+
+	// Old jobs are removed, with the directories not belonging to a job.
+	stray := filepath.Join(suite.Directory, "hls", "2-64")
+	_ = os.MkdirAll(stray, 0755)
+	segmenter.cleanUp(time.Hour)
+	assert.DirExists(suite.T(), job.directory)
+	_, err = os.Stat(stray)
+	assert.True(suite.T(), os.IsNotExist(err))
+	segmenter.cleanUp(0)
+	assert.Empty(suite.T(), segmenter.jobs)
+	_, err = os.Stat(job.directory)
+	assert.True(suite.T(), os.IsNotExist(err))
+}
+
+func (suite *HlsTestSuite) TestFailedJob() {
+	segmenter := newHlsSegmenter()
+	track := domain.Track{Id: 1, Path: "/music/Track.mp3"}
+
+	suite.setFfmpegScript(true)
+	job, err := segmenter.getJob(track, 64)
+	assert.Nil(suite.T(), err)
+	_, err = job.waitForFile(context.Background(), hlsPlaylistName)
+	assert.NotNil(suite.T(), err)
+
+	// Failed jobs are started again.
+	suite.setFfmpegScript(false)
+	other, err := segmenter.getJob(track, 64)
+	assert.Nil(suite.T(), err)
+	assert.NotEqual(suite.T(), job, other)
+	_, err = other.waitForFile(context.Background(), hlsPlaylistName)
+	assert.Nil(suite.T(), err)
+}
@@ -0,0 +1,250 @@
+package interfaces
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+This file exposes the reading of the duration of the media files, from their headers without decoding the audio.
+
+MP3 files without a Xing, Info or VBRI frame are considered to have a constant bitrate. Ogg files must hold a Vorbis or
+an Opus stream.
+*/
+
+var errUnknownDuration = errors.New("unknown media file duration")
+
+// Size of the end of an Ogg file searched for its last page, which is usually way smaller.
+const oggLastPageSearchSize = 64 * 1024
+
+// Gets the duration of a media file in milliseconds.
+func getMediaDuration(file *os.File) (int, error) {
+	switch strings.ToLower(filepath.Ext(file.Name())) {
+	case ".flac":
+		return getFlacDuration(file)
+	case ".mp3":
+		return getMp3Duration(file)
+	case ".ogg", ".oga", ".opus":
+		return getOggDuration(file)
+	case ".m4a":
+		return getMp4Duration(file)
+	}
+
+	return 0, errUnknownDuration
+}
+
+// Gets the duration of a FLAC file from its STREAMINFO block.
+func getFlacDuration(file *os.File) (int, error) {
+	info, err := readFlacStreamInfo(file)
+	if err != nil {
+		return 0, err
+	}
+	if info.TotalSamples == 0 {
+		return 0, errUnknownDuration
+	}
+
+	return int(info.TotalSamples * 1000 / int64(info.SampleRate)), nil
+}
+
+/*
+Gets the duration of a MP3 file from the number of frames of its Xing, Info or VBRI frame, or from its bitrate and its
+size if it has none.
+*/
+func getMp3Duration(file *os.File) (int, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	offset, err := getId3v2TagSize(file)
+	if err != nil {
+		return 0, err
+	}
+
+	// Find the first frame, skipping the garbage before it.
+	buffer := make([]byte, 64*1024)
+	n, err := file.ReadAt(buffer, offset)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	buffer = buffer[:n]
+	var frame mp3Frame
+	start := -1
+	for i := 0; i+4 <= len(buffer); i++ {
+		if header, ok := parseMp3FrameHeader(buffer[i:]); ok {
+			frame, start = header, i
+			break
+		}
+	}
+	if start < 0 {
+		return 0, errors.New("no MP3 frame found")
+	}
+
+	content := buffer[start:]
+	if len(content) > frame.Size {
+		content = content[:frame.Size]
+	}
+	if frames := getMp3InfoFrameCount(content); frames > 0 {
+		return int(int64(frames) * int64(frame.Samples) * 1000 / int64(frame.SampleRate)), nil
+	}
+
+	// Constant bitrate, without the Info frame and the ID3v1 tag at the end of the file.
+	size := stat.Size() - offset - int64(start)
+	if isMp3InfoFrame(content) {
+		size -= int64(frame.Size)
+	}
+	tag := make([]byte, 3)
+	if _, errTag := file.ReadAt(tag, stat.Size()-128); errTag == nil && string(tag) == "TAG" {
+		size -= 128
+	}
+
+	return int(size * 8 * 1000 / int64(frame.Bitrate)), nil
+}
+
+// Gets the number of frames of a MP3 file from its Xing, Info or VBRI frame, 0 if unknown.
+func getMp3InfoFrameCount(frame []byte) int {
+	// Like in isMp3InfoFrame(), the tags are right after the side information.
+	header := frame
+	if len(header) > 64 {
+		header = header[0:64]
+	}
+	for _, name := range []string{"Xing", "Info"} {
+		index := bytes.Index(header, []byte(name))
+		// The frames count is the first optional field, after the flags.
+		if index >= 0 && index+12 <= len(frame) && frame[index+7]&0x01 != 0 {
+			return int(binary.BigEndian.Uint32(frame[index+8 : index+12]))
+		}
+	}
+	if index := bytes.Index(header, []byte("VBRI")); index >= 0 && index+18 <= len(frame) {
+		return int(binary.BigEndian.Uint32(frame[index+14 : index+18]))
+	}
+
+	return 0
+}
+
+// Gets the duration of an Ogg Vorbis or Opus file from the granule position of its last page.
+func getOggDuration(file *os.File) (int, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	// The identification header of the stream is the first packet of the first page.
+	page := make([]byte, 27+255)
+	n, err := file.ReadAt(page, 0)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if n < 27 || string(page[0:4]) != "OggS" {
+		return 0, errors.New("not an Ogg file")
+	}
+	packetOffset := int64(27) + int64(page[26])
+	packet := make([]byte, 20)
+	if _, err = file.ReadAt(packet, packetOffset); err != nil {
+		return 0, err
+	}
+	var sampleRate, preSkip int64
+	switch {
+	case string(packet[0:7]) == "\x01vorbis":
+		sampleRate = int64(binary.LittleEndian.Uint32(packet[12:16]))
+	case string(packet[0:8]) == "OpusHead":
+		// Opus granule positions are always counted at 48 kHz.
+		sampleRate = 48000
+		preSkip = int64(binary.LittleEndian.Uint16(packet[10:12]))
+	default:
+		return 0, errUnknownDuration
+	}
+	if sampleRate == 0 {
+		return 0, errUnknownDuration
+	}
+
+	searchSize := int64(oggLastPageSearchSize)
+	if searchSize > stat.Size() {
+		searchSize = stat.Size()
+	}
+	end := make([]byte, searchSize)
+	if _, err = file.ReadAt(end, stat.Size()-searchSize); err != nil && err != io.EOF {
+		return 0, err
+	}
+	index := bytes.LastIndex(end, []byte("OggS"))
+	if index < 0 || index+14 > len(end) {
+		return 0, errUnknownDuration
+	}
+	granule := int64(binary.LittleEndian.Uint64(end[index+6 : index+14]))
+	if granule <= preSkip {
+		return 0, errUnknownDuration
+	}
+
+	return int((granule - preSkip) * 1000 / sampleRate), nil
+}
+
+// Gets the duration of a MP4 file from the header of its movie.
+func getMp4Duration(file *os.File) (int, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	moov, moovSize, err := findMp4Atom(file, 0, stat.Size(), "moov")
+	if err != nil {
+		return 0, err
+	}
+	mvhd, _, err := findMp4Atom(file, moov, moov+moovSize, "mvhd")
+	if err != nil {
+		return 0, err
+	}
+
+	// Version and flags, then the creation and modification times, the time scale and the duration, on 64 bits in
+	// version 1.
+	header := make([]byte, 32)
+	if _, err = file.ReadAt(header, mvhd); err != nil {
+		return 0, err
+	}
+	var timeScale, duration int64
+	if header[0] == 1 {
+		timeScale = int64(binary.BigEndian.Uint32(header[20:24]))
+		duration = int64(binary.BigEndian.Uint64(header[24:32]))
+	} else {
+		timeScale = int64(binary.BigEndian.Uint32(header[12:16]))
+		duration = int64(binary.BigEndian.Uint32(header[16:20]))
+	}
+	if timeScale == 0 || duration == 0 {
+		return 0, errUnknownDuration
+	}
+
+	return int(duration * 1000 / timeScale), nil
+}
+
+// Finds an atom of a MP4 file between two offsets. Returns the offset and the size of its content.
+func findMp4Atom(file *os.File, offset int64, end int64, name string) (int64, int64, error) {
+	header := make([]byte, 16)
+	for offset+8 <= end {
+		if _, err := file.ReadAt(header[0:8], offset); err != nil {
+			return 0, 0, err
+		}
+		size, headerSize := int64(binary.BigEndian.Uint32(header[0:4])), int64(8)
+		switch size {
+		case 0:
+			// The atom lasts until the end.
+			size = end - offset
+		case 1:
+			if _, err := file.ReadAt(header[8:16], offset+8); err != nil {
+				return 0, 0, err
+			}
+			size, headerSize = int64(binary.BigEndian.Uint64(header[8:16])), 16
+		}
+		if size < headerSize {
+			return 0, 0, errors.New("invalid MP4 atom")
+		}
+		if string(header[4:8]) == name {
+			return offset + headerSize, size - headerSize, nil
+		}
+		offset += size
+	}
+
+	return 0, 0, errors.New("MP4 atom " + name + " not found")
+}
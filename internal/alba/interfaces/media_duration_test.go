@@ -0,0 +1,201 @@
+package interfaces
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MediaDurationTestSuite struct {
+	suite.Suite
+	Directory string
+}
+
+// Go testing framework entry point.
+func TestMediaDurationTestSuite(t *testing.T) {
+	suite.Run(t, new(MediaDurationTestSuite))
+}
+
+func (suite *MediaDurationTestSuite) SetupSuite() {
+	directory, err := ioutil.TempDir("", "alba-duration")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.Directory = directory
+}
+
+func (suite *MediaDurationTestSuite) TearDownSuite() {
+	_ = os.RemoveAll(suite.Directory)
+}
+
+// Writes a file in the test directory and opens it.
+func (suite *MediaDurationTestSuite) createFile(name string, content []byte) *os.File {
+	path := filepath.Join(suite.Directory, name)
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		suite.T().Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	return file
+}
+
+// Builds an Ogg page holding one packet.
+func buildTestOggPage(granule int64, packet []byte) []byte {
+	var page bytes.Buffer
+	page.WriteString("OggS")
+	page.Write([]byte{0x00, 0x00})
+	position := make([]byte, 8)
+	binary.LittleEndian.PutUint64(position, uint64(granule))
+	page.Write(position)
+	// Serial number, sequence number and checksum, which aren't checked.
+	page.Write(make([]byte, 12))
+	page.Write([]byte{0x01, byte(len(packet))})
+	page.Write(packet)
+
+	return page.Bytes()
+}
+
+// Builds a MP4 atom.
+func buildTestMp4Atom(name string, content []byte) []byte {
+	atom := make([]byte, 8)
+	binary.BigEndian.PutUint32(atom[0:4], uint32(8+len(content)))
+	copy(atom[4:8], name)
+
+	return append(atom, content...)
+}
+
+func (suite *MediaDurationTestSuite) TestGetFlacDuration() {
+	// 100 frames of 4096 samples at 44.1 kHz.
+	file := suite.createFile("track.flac", buildTestFlacFile(100, 10))
+	defer file.Close()
+	duration, err := getMediaDuration(file)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 9287, duration)
+
+	// Unknown number of samples.
+	content := buildTestFlacFile(10, 10)
+	copy(content[8+14:8+18], make([]byte, 4))
+	file = suite.createFile("unknown.flac", content)
+	defer file.Close()
+	_, err = getMediaDuration(file)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *MediaDurationTestSuite) TestGetMp3Duration() {
+	// Constant bitrate: 1000 frames of 417 bytes at 128 kbit/s, without the Info frame.
+	file := suite.createFile("cbr.mp3", buildTestMp3File(1000))
+	defer file.Close()
+	duration, err := getMediaDuration(file)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 26062, duration)
+
+	// ID3v1 tag at the end of the file.
+	content := append(buildTestMp3File(1000), append([]byte("TAG"), make([]byte, 125)...)...)
+	file = suite.createFile("id3v1.mp3", content)
+	defer file.Close()
+	duration, err = getMediaDuration(file)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 26062, duration)
+
+	// Variable bitrate: the Xing frame holds the number of frames, whatever the file size.
+	content = buildTestMp3File(10)
+	copy(content[30+36:], "Xing")
+	content[30+36+7] = 0x01
+	binary.BigEndian.PutUint32(content[30+36+8:], 5000)
+	file = suite.createFile("vbr.mp3", content)
+	defer file.Close()
+	duration, err = getMediaDuration(file)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 130612, duration)
+
+	// No frame.
+	file = suite.createFile("empty.mp3", []byte("ID3"))
+	defer file.Close()
+	_, err = getMediaDuration(file)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *MediaDurationTestSuite) TestGetOggDuration() {
+	// Vorbis at 44.1 kHz, the last page ending after 441000 samples.
+	identification := make([]byte, 30)
+	copy(identification, "\x01vorbis")
+	binary.LittleEndian.PutUint32(identification[12:16], 44100)
+	content := buildTestOggPage(0, identification)
+	content = append(content, buildTestOggPage(220500, make([]byte, 200))...)
+	content = append(content, buildTestOggPage(441000, make([]byte, 200))...)
+	file := suite.createFile("track.ogg", content)
+	defer file.Close()
+	duration, err := getMediaDuration(file)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 10000, duration)
+
+	// Opus, always at 48 kHz, without the pre-skip samples.
+	identification = make([]byte, 19)
+	copy(identification, "OpusHead")
+	binary.LittleEndian.PutUint16(identification[10:12], 312)
+	content = buildTestOggPage(0, identification)
+	content = append(content, buildTestOggPage(312+96000, make([]byte, 200))...)
+	file = suite.createFile("track.opus", content)
+	defer file.Close()
+	duration, err = getMediaDuration(file)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2000, duration)
+
+	// Other codecs.
+	content = buildTestOggPage(0, []byte("\x80theora and some more bytes"))
+	file = suite.createFile("video.ogg", content)
+	defer file.Close()
+	_, err = getMediaDuration(file)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *MediaDurationTestSuite) TestGetMp4Duration() {
+	// Version 0 movie header: 2 minutes with a time scale of 600, after some other atoms.
+	header := make([]byte, 100)
+	binary.BigEndian.PutUint32(header[12:16], 600)
+	binary.BigEndian.PutUint32(header[16:20], 72000)
+	content := buildTestMp4Atom("ftyp", []byte("M4A isom"))
+	content = append(content, buildTestMp4Atom("free", make([]byte, 50))...)
+	content = append(content, buildTestMp4Atom("moov", append(buildTestMp4Atom("udta", make([]byte, 20)), buildTestMp4Atom("mvhd", header)...))...)
+	content = append(content, buildTestMp4Atom("mdat", make([]byte, 100))...)
+	file := suite.createFile("v0.m4a", content)
+	defer file.Close()
+	duration, err := getMediaDuration(file)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 120000, duration)
+
+	// Version 1 movie header, with 64 bits fields.
+	header = make([]byte, 112)
+	header[0] = 1
+	binary.BigEndian.PutUint32(header[20:24], 44100)
+	binary.BigEndian.PutUint64(header[24:32], 441000)
+	content = buildTestMp4Atom("ftyp", []byte("M4A isom"))
+	content = append(content, buildTestMp4Atom("moov", buildTestMp4Atom("mvhd", header))...)
+	file = suite.createFile("v1.m4a", content)
+	defer file.Close()
+	duration, err = getMediaDuration(file)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 10000, duration)
+
+	// No movie.
+	file = suite.createFile("empty.m4a", buildTestMp4Atom("ftyp", []byte("M4A isom")))
+	defer file.Close()
+	_, err = getMediaDuration(file)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *MediaDurationTestSuite) TestGetMediaDurationUnknownFormat() {
+	file := suite.createFile("track.wav", buildTestFlacFile(10, 10))
+	defer file.Close()
+	_, err := getMediaDuration(file)
+	assert.Equal(suite.T(), errUnknownDuration, err)
+}
@@ -0,0 +1,567 @@
+package interfaces
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+This file exposes the extraction of a segment of a media file, used to stream the tracks of CUE sheets.
+
+Segments are cut on the boundaries of the audio frames without decoding the audio, so they start and end a few
+milliseconds around the requested times. Only FLAC and MP3 files can be cut, the other formats must be transcoded.
+*/
+
+var errUnsupportedSegmentFormat = errors.New("media file format cannot be cut")
+
+/*
+Gets a reader on the segment of a media file between two times, the end being 0 for the end of the file.
+
+The reader can seek, so the segment can be served with byte ranges. Returns errUnsupportedSegmentFormat if the format
+of the file cannot be cut.
+*/
+func getMediaSegment(file *os.File, startMs int, endMs int) (*io.SectionReader, error) {
+	switch strings.ToLower(filepath.Ext(file.Name())) {
+	case ".flac":
+		return getFlacSegment(file, startMs, endMs)
+	case ".mp3":
+		return getMp3Segment(file, startMs, endMs)
+	}
+
+	return nil, errUnsupportedSegmentFormat
+}
+
+/*
+Concatenation of readers, used to prepend a header to a part of a file.
+*/
+type multiReaderAt struct {
+	readers []io.ReaderAt
+	sizes   []int64
+}
+
+func newMultiReaderAt() *multiReaderAt {
+	return &multiReaderAt{}
+}
+
+func (m *multiReaderAt) add(reader io.ReaderAt, size int64) *multiReaderAt {
+	m.readers = append(m.readers, reader)
+	m.sizes = append(m.sizes, size)
+	return m
+}
+
+func (m *multiReaderAt) size() (size int64) {
+	for _, readerSize := range m.sizes {
+		size += readerSize
+	}
+	return
+}
+
+func (m *multiReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	for i := 0; i < len(m.readers) && n < len(p); i++ {
+		if off >= m.sizes[i] {
+			off -= m.sizes[i]
+			continue
+		}
+
+		toRead := p[n:]
+		if int64(len(toRead)) > m.sizes[i]-off {
+			toRead = toRead[0 : m.sizes[i]-off]
+		}
+		read, errRead := m.readers[i].ReadAt(toRead, off)
+		n += read
+		if read < len(toRead) {
+			if errRead == nil || errRead == io.EOF {
+				errRead = io.ErrUnexpectedEOF
+			}
+			return n, errRead
+		}
+		off = 0
+	}
+
+	if n < len(p) {
+		err = io.EOF
+	}
+
+	return
+}
+
+/*
+FLAC.
+*/
+
+// Stream properties read from the FLAC STREAMINFO block.
+type flacStreamInfo struct {
+	Block         []byte // Raw STREAMINFO block content.
+	SampleRate    int
+	BitsPerSample int
+	BlockSize     int   // Maximum number of samples per frame.
+	TotalSamples  int64 // 0 if unknown.
+	AudioOffset   int64 // Offset of the first frame.
+	FileSize      int64
+	Variable      bool // Frames hold their first sample number instead of their frame number.
+}
+
+// FLAC frame header, as needed to find the frames.
+type flacFrame struct {
+	Offset int64
+	Sample int64 // Number of the first sample of the frame.
+}
+
+// Sample rates of the frames headers, 0 meaning the one of the stream.
+var flacSampleRates = []int{0, 88200, 176400, 192000, 8000, 16000, 22050, 24000, 32000, 44100, 48000, 96000}
+
+// Sample sizes of the frames headers, 0 meaning the one of the stream.
+var flacSampleSizes = []int{0, 8, 12, 0, 16, 20, 24, 32}
+
+// Gets the segment of a FLAC file, as a FLAC stream holding the frames of the segment.
+func getFlacSegment(file *os.File, startMs int, endMs int) (*io.SectionReader, error) {
+	info, err := readFlacStreamInfo(file)
+	if err != nil {
+		return nil, err
+	}
+
+	firstFrame, err := info.findFrame(file, int64(startMs)*int64(info.SampleRate)/1000)
+	if err != nil {
+		return nil, err
+	}
+	endOffset, endSample := info.FileSize, info.TotalSamples
+	if endMs > 0 {
+		lastFrame, errEnd := info.findFrame(file, int64(endMs)*int64(info.SampleRate)/1000-1)
+		if errEnd != nil {
+			return nil, errEnd
+		}
+		if next, errNext := info.nextFrame(file, lastFrame.Offset+1); errNext == nil {
+			endOffset, endSample = next.Offset, next.Sample
+		}
+	}
+
+	// The segment is a stream of its own, with only a STREAMINFO block.
+	streamInfo := make([]byte, len(info.Block))
+	copy(streamInfo, info.Block)
+	totalSamples := uint64(0)
+	if endSample > firstFrame.Sample {
+		totalSamples = uint64(endSample - firstFrame.Sample)
+	}
+	// Total samples are stored on 36 bits after the sample rate, channels and bits per sample.
+	streamInfo[13] = streamInfo[13]&0xf0 | byte(totalSamples>>32&0x0f)
+	binary.BigEndian.PutUint32(streamInfo[14:18], uint32(totalSamples))
+	// The MD5 signature of the audio is unknown.
+	for i := 18; i < 34; i++ {
+		streamInfo[i] = 0
+	}
+
+	header := append([]byte("fLaC"), 0x80, byte(len(streamInfo)>>16), byte(len(streamInfo)>>8), byte(len(streamInfo)))
+	header = append(header, streamInfo...)
+
+	segment := newMultiReaderAt().
+		add(bytes.NewReader(header), int64(len(header))).
+		add(io.NewSectionReader(file, firstFrame.Offset, endOffset-firstFrame.Offset), endOffset-firstFrame.Offset)
+
+	return io.NewSectionReader(segment, 0, segment.size()), nil
+}
+
+// Reads the STREAMINFO block of a FLAC file and finds its first frame.
+func readFlacStreamInfo(file *os.File) (info flacStreamInfo, err error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return
+	}
+	info.FileSize = stat.Size()
+
+	marker := make([]byte, 4)
+	if _, err = file.ReadAt(marker, 0); err != nil {
+		return
+	}
+	if string(marker) != "fLaC" {
+		return info, errors.New("not a FLAC file")
+	}
+
+	offset := int64(4)
+	for {
+		blockHeader := make([]byte, 4)
+		if _, err = file.ReadAt(blockHeader, offset); err != nil {
+			return
+		}
+		size := int64(blockHeader[1])<<16 | int64(blockHeader[2])<<8 | int64(blockHeader[3])
+		if blockHeader[0]&0x7f == 0 {
+			if size < 34 {
+				return info, errors.New("invalid FLAC STREAMINFO block")
+			}
+			info.Block = make([]byte, size)
+			if _, err = file.ReadAt(info.Block, offset+4); err != nil {
+				return
+			}
+		}
+		offset += 4 + size
+		if blockHeader[0]&0x80 != 0 {
+			break
+		}
+	}
+	if info.Block == nil {
+		return info, errors.New("no FLAC STREAMINFO block found")
+	}
+
+	b := info.Block
+	info.BlockSize = int(binary.BigEndian.Uint16(b[2:4]))
+	info.SampleRate = int(b[10])<<12 | int(b[11])<<4 | int(b[12])>>4
+	info.BitsPerSample = int(b[12]&0x01)<<4 | int(b[13])>>4 + 1
+	info.TotalSamples = int64(b[13]&0x0f)<<32 | int64(binary.BigEndian.Uint32(b[14:18]))
+	if info.SampleRate == 0 || info.BlockSize == 0 {
+		return info, errors.New("invalid FLAC STREAMINFO block")
+	}
+	info.AudioOffset = offset
+
+	first, err := info.nextFrame(file, offset)
+	if err != nil {
+		return
+	}
+	if first.Offset != offset {
+		return info, errors.New("invalid FLAC first frame")
+	}
+	header := make([]byte, 2)
+	if _, err = file.ReadAt(header, offset); err != nil {
+		return
+	}
+	info.Variable = header[1]&0x01 != 0
+
+	return
+}
+
+/*
+Finds the frame holding a sample, or the last frame if the sample is after the end of the stream.
+
+Frames are located by bisection, as their headers hold the number of their first sample.
+*/
+func (info flacStreamInfo) findFrame(file io.ReaderAt, sample int64) (frame flacFrame, err error) {
+	if frame, err = info.nextFrame(file, info.AudioOffset); err != nil {
+		return
+	}
+
+	low, high := info.AudioOffset, info.FileSize
+	for high-low > 64*1024 {
+		middle := low + (high-low)/2
+		candidate, errNext := info.nextFrame(file, middle)
+		if errNext != nil || candidate.Offset >= high || candidate.Sample > sample {
+			high = middle
+			continue
+		}
+		low, frame = candidate.Offset, candidate
+	}
+
+	for {
+		next, errNext := info.nextFrame(file, frame.Offset+1)
+		if errNext != nil || next.Sample > sample {
+			return frame, nil
+		}
+		frame = next
+	}
+}
+
+// Finds the first frame starting at or after an offset.
+func (info flacStreamInfo) nextFrame(file io.ReaderAt, offset int64) (frame flacFrame, err error) {
+	buffer := make([]byte, 32*1024)
+	for offset < info.FileSize {
+		n, errRead := file.ReadAt(buffer, offset)
+		if n < 2 {
+			break
+		}
+		for i := 0; i+1 < n; i++ {
+			if buffer[i] != 0xff || buffer[i+1]&0xfe != 0xf8 {
+				continue
+			}
+			// Make sure the whole header is in the buffer.
+			if i+16 > n && errRead == nil {
+				break
+			}
+			if sample, ok := info.parseFrameHeader(buffer[i:n]); ok {
+				return flacFrame{Offset: offset + int64(i), Sample: sample}, nil
+			}
+		}
+		if errRead != nil {
+			break
+		}
+		// Frames headers are at most 16 bytes long.
+		offset += int64(n) - 16
+	}
+
+	return frame, io.EOF
+}
+
+// Parses a frame header, checking it is consistent with the stream.
+//
+// Returns the number of the first sample of the frame.
+func (info flacStreamInfo) parseFrameHeader(b []byte) (sample int64, ok bool) {
+	if len(b) < 6 {
+		return 0, false
+	}
+
+	blockSizeCode := b[2] >> 4
+	sampleRateCode := int(b[2] & 0x0f)
+	channels := b[3] >> 4
+	sampleSizeCode := int(b[3] >> 1 & 0x07)
+	if blockSizeCode == 0 || sampleRateCode == 15 || channels > 10 || sampleSizeCode == 3 || b[3]&0x01 != 0 {
+		return 0, false
+	}
+	if sampleRateCode < len(flacSampleRates) && flacSampleRates[sampleRateCode] != 0 && flacSampleRates[sampleRateCode] != info.SampleRate {
+		return 0, false
+	}
+	if flacSampleSizes[sampleSizeCode] != 0 && flacSampleSizes[sampleSizeCode] != info.BitsPerSample {
+		return 0, false
+	}
+
+	// Frame or sample number, coded like UTF-8 characters on up to 7 bytes.
+	// The number of leading ones of the first byte is the length of the number.
+	length := 0
+	for length < 8 && b[4]&(0x80>>uint(length)) != 0 {
+		length++
+	}
+	var number int64
+	switch {
+	case length == 0:
+		length = 1
+		number = int64(b[4])
+	case length == 1 || length > 7:
+		return 0, false
+	default:
+		number = int64(b[4] & (0xff >> uint(length+1)))
+	}
+	pos := 5
+	for i := 1; i < length; i++ {
+		if pos >= len(b) || b[pos]&0xc0 != 0x80 {
+			return 0, false
+		}
+		number = number<<6 | int64(b[pos]&0x3f)
+		pos++
+	}
+
+	switch {
+	case blockSizeCode == 6:
+		pos++
+	case blockSizeCode == 7:
+		pos += 2
+	}
+	switch {
+	case sampleRateCode == 12:
+		pos++
+	case sampleRateCode == 13 || sampleRateCode == 14:
+		pos += 2
+	}
+	if pos >= len(b) || crc8(b[0:pos]) != b[pos] {
+		return 0, false
+	}
+
+	if b[1]&0x01 != 0 {
+		return number, true
+	}
+
+	return number * int64(info.BlockSize), true
+}
+
+// Computes the CRC-8 of a FLAC frame header (polynomial x^8 + x^2 + x + 1).
+func crc8(b []byte) (crc byte) {
+	for _, c := range b {
+		crc ^= c
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return
+}
+
+/*
+MP3.
+*/
+
+// Bitrates in kbit/s, indexed by MPEG version (1, 2 and 2.5), layer and bitrate index.
+var mp3Bitrates = [2][3][16]int{
+	// MPEG 1.
+	{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	},
+	// MPEG 2 and 2.5.
+	{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	},
+}
+
+// Sample rates indexed by MPEG version (1, 2 and 2.5) and sample rate index.
+var mp3SampleRates = [3][3]int{
+	{44100, 48000, 32000},
+	{22050, 24000, 16000},
+	{11025, 12000, 8000},
+}
+
+// MPEG audio frame header.
+type mp3Frame struct {
+	Size       int
+	Samples    int
+	SampleRate int
+	Channels   int
+	// In bit/s.
+	Bitrate int
+}
+
+/*
+Gets the segment of a MP3 file, as the MP3 frames of the segment.
+
+The frames are read one by one from the beginning of the file, as their duration can vary. The Xing / Info frame is
+left out, as it describes the whole file.
+*/
+func getMp3Segment(file *os.File, startMs int, endMs int) (*io.SectionReader, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := getId3v2TagSize(file)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReaderSize(io.NewSectionReader(file, offset, stat.Size()-offset), 64*1024)
+	startOffset, endOffset := int64(-1), int64(-1)
+	var samples int64
+	first := true
+	for {
+		header, errPeek := reader.Peek(4)
+		if errPeek != nil {
+			break
+		}
+		frame, ok := parseMp3FrameHeader(header)
+		if !ok {
+			// Skip garbage between the frames.
+			if _, errDiscard := reader.Discard(1); errDiscard != nil {
+				break
+			}
+			offset++
+			continue
+		}
+
+		if first {
+			first = false
+			if content, errContent := reader.Peek(frame.Size); errContent == nil && isMp3InfoFrame(content) {
+				if _, err = reader.Discard(frame.Size); err != nil {
+					break
+				}
+				offset += int64(frame.Size)
+				continue
+			}
+		}
+
+		timeMs := samples * 1000 / int64(frame.SampleRate)
+		samples += int64(frame.Samples)
+		endTimeMs := samples * 1000 / int64(frame.SampleRate)
+		if startOffset < 0 && endTimeMs > int64(startMs) {
+			startOffset = offset
+		}
+		if endMs > 0 && timeMs >= int64(endMs) {
+			endOffset = offset
+			break
+		}
+
+		discarded, _ := reader.Discard(frame.Size)
+		offset += int64(discarded)
+		if discarded < frame.Size {
+			break
+		}
+		endOffset = offset
+	}
+
+	if startOffset < 0 || endOffset <= startOffset {
+		return nil, errors.New("segment not found in MP3 file")
+	}
+
+	return io.NewSectionReader(file, startOffset, endOffset-startOffset), nil
+}
+
+// Parses a MPEG audio frame header.
+func parseMp3FrameHeader(b []byte) (frame mp3Frame, ok bool) {
+	if len(b) < 4 || b[0] != 0xff || b[1]&0xe0 != 0xe0 {
+		return frame, false
+	}
+
+	versionBits := b[1] >> 3 & 0x03
+	layerBits := b[1] >> 1 & 0x03
+	bitrateIndex := b[2] >> 4
+	sampleRateIndex := b[2] >> 2 & 0x03
+	padding := int(b[2] >> 1 & 0x01)
+	if versionBits == 1 || layerBits == 0 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return frame, false
+	}
+
+	// Version index: 0 for MPEG 1, 1 for MPEG 2, 2 for MPEG 2.5.
+	version := map[byte]int{3: 0, 2: 1, 0: 2}[versionBits]
+	// Layer index: 0 for layer I, 1 for layer II, 2 for layer III.
+	layer := 3 - int(layerBits)
+	bitrateVersion := 0
+	if version > 0 {
+		bitrateVersion = 1
+	}
+	bitrate := mp3Bitrates[bitrateVersion][layer][bitrateIndex] * 1000
+	frame.Bitrate = bitrate
+	frame.SampleRate = mp3SampleRates[version][sampleRateIndex]
+	frame.Channels = 2
+	if b[3]>>6 == 3 {
+		frame.Channels = 1
+	}
+
+	switch {
+	case layer == 0:
+		frame.Samples = 384
+		frame.Size = (12*bitrate/frame.SampleRate + padding) * 4
+	case layer == 2 && version > 0:
+		frame.Samples = 576
+		frame.Size = 72*bitrate/frame.SampleRate + padding
+	default:
+		frame.Samples = 1152
+		frame.Size = 144*bitrate/frame.SampleRate + padding
+	}
+
+	return frame, frame.Size > 4
+}
+
+// Checks if a MP3 frame is a Xing, Info or VBRI frame, describing the whole file.
+func isMp3InfoFrame(frame []byte) bool {
+	if len(frame) > 64 {
+		frame = frame[0:64]
+	}
+
+	return bytes.Contains(frame, []byte("Xing")) || bytes.Contains(frame, []byte("Info")) || bytes.Contains(frame, []byte("VBRI"))
+}
+
+// Gets the size of the ID3v2 tag at the beginning of a file, 0 if there is none.
+func getId3v2TagSize(file io.ReaderAt) (int64, error) {
+	header := make([]byte, 10)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		if err == io.EOF {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if string(header[0:3]) != "ID3" {
+		return 0, nil
+	}
+
+	size := int64(syncsafeInt(header[6:10])) + 10
+	// Footer.
+	if header[5]&0x10 != 0 {
+		size += 10
+	}
+
+	return size, nil
+}
@@ -0,0 +1,224 @@
+package interfaces
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MediaSegmentTestSuite struct {
+	suite.Suite
+	Directory string
+}
+
+// Go testing framework entry point.
+func TestMediaSegmentTestSuite(t *testing.T) {
+	suite.Run(t, new(MediaSegmentTestSuite))
+}
+
+func (suite *MediaSegmentTestSuite) SetupSuite() {
+	directory, err := ioutil.TempDir("", "alba-segment")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.Directory = directory
+}
+
+func (suite *MediaSegmentTestSuite) TearDownSuite() {
+	_ = os.RemoveAll(suite.Directory)
+}
+
+// Writes a file in the test directory and opens it.
+func (suite *MediaSegmentTestSuite) createFile(name string, content []byte) *os.File {
+	path := filepath.Join(suite.Directory, name)
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		suite.T().Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	return file
+}
+
+/*
+Builds a mono 16 bits 44.1 kHz FLAC stream with frames of 4096 samples and silent payloads.
+
+The payloads are not valid audio, but they are enough to locate the frames.
+*/
+func buildTestFlacFile(frames int, payloadSize int) []byte {
+	var content bytes.Buffer
+	content.WriteString("fLaC")
+	content.Write([]byte{0x80, 0x00, 0x00, 0x22})
+	streamInfo := make([]byte, 34)
+	streamInfo[0], streamInfo[1], streamInfo[2], streamInfo[3] = 0x10, 0x00, 0x10, 0x00
+	totalSamples := frames * 4096
+	// 44100 Hz on 20 bits, 1 channel on 3 bits, 16 bits per sample on 5 bits, total samples on 36 bits.
+	streamInfo[10], streamInfo[11], streamInfo[12] = 0x0a, 0xc4, 0x40
+	streamInfo[13] = 0xf0
+	streamInfo[14], streamInfo[15], streamInfo[16], streamInfo[17] = byte(totalSamples>>24), byte(totalSamples>>16), byte(totalSamples>>8), byte(totalSamples)
+	for i := 18; i < 34; i++ {
+		streamInfo[i] = 0xaa
+	}
+	content.Write(streamInfo)
+
+	for i := 0; i < frames; i++ {
+		// Block size of 4096, sample rate of 44.1 kHz, mono, 16 bits, frame number on one byte (so less than 128 frames).
+		header := []byte{0xff, 0xf8, 0xc9, 0x08, byte(i)}
+		content.Write(header)
+		content.WriteByte(crc8(header))
+		content.Write(make([]byte, payloadSize))
+	}
+
+	return content.Bytes()
+}
+
+/*
+Builds a MP3 file made of an ID3v2 tag, an Info frame and 128 kbit/s 44.1 kHz MPEG 1 layer III frames with silent
+payloads.
+*/
+func buildTestMp3File(frames int) []byte {
+	var content bytes.Buffer
+	content.Write([]byte{'I', 'D', '3', 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x14})
+	content.Write(make([]byte, 20))
+
+	frame := make([]byte, 417)
+	frame[0], frame[1], frame[2], frame[3] = 0xff, 0xfb, 0x90, 0x00
+	infoFrame := make([]byte, 417)
+	copy(infoFrame, frame)
+	copy(infoFrame[36:], "Info")
+	content.Write(infoFrame)
+	for i := 0; i < frames; i++ {
+		content.Write(frame)
+	}
+
+	return content.Bytes()
+}
+
+func (suite *MediaSegmentTestSuite) TestGetFlacSegment() {
+	// Large enough for the frames to be found by bisection.
+	content := buildTestFlacFile(100, 2000)
+	file := suite.createFile("album.flac", content)
+	defer file.Close()
+	frameSize := int64(2006)
+	audioOffset := int64(42)
+
+	// From 1s to 2s: frames 10 (samples 40960 to 45055) to 21 (samples 86016 to 90111).
+	segment, err := getMediaSegment(file, 1000, 2000)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(42+12*2006), segment.Size())
+
+	segmentContent, err := ioutil.ReadAll(segment)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "fLaC", string(segmentContent[0:4]))
+	assert.Equal(suite.T(), content[audioOffset+10*frameSize:audioOffset+22*frameSize], segmentContent[42:])
+
+	segmentFile := suite.createFile("segment.flac", segmentContent)
+	defer segmentFile.Close()
+	info, err := readFlacStreamInfo(segmentFile)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 44100, info.SampleRate)
+	assert.Equal(suite.T(), 16, info.BitsPerSample)
+	assert.Equal(suite.T(), int64(12*4096), info.TotalSamples)
+	// The MD5 signature of the whole file doesn't match the segment.
+	assert.Equal(suite.T(), make([]byte, 16), info.Block[18:34])
+
+	// Until the end of the file.
+	segment, err = getMediaSegment(file, 8000, 0)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(42+14*2006), segment.Size())
+
+	// Byte ranges.
+	part := make([]byte, 10)
+	_, err = segment.ReadAt(part, 38)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), append(segmentContent[38:42], content[audioOffset+86*frameSize:audioOffset+86*frameSize+6]...), part)
+
+	// Not a FLAC file.
+	mp3File := suite.createFile("fake.flac", buildTestMp3File(10))
+	defer mp3File.Close()
+	_, err = getMediaSegment(mp3File, 1000, 2000)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *MediaSegmentTestSuite) TestGetMp3Segment() {
+	content := buildTestMp3File(200)
+	file := suite.createFile("album.mp3", content)
+	defer file.Close()
+	audioOffset := int64(30 + 417)
+
+	// From 1s to 2s: frames 38 (from 992ms to 1018ms) to 76 (from 1985ms to 2011ms).
+	segment, err := getMediaSegment(file, 1000, 2000)
+	assert.Nil(suite.T(), err)
+	segmentContent, err := ioutil.ReadAll(segment)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), content[audioOffset+38*417:audioOffset+77*417], segmentContent)
+
+	// Until the end of the file.
+	segment, err = getMediaSegment(file, 5000, 0)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(len(content))-audioOffset-191*417, segment.Size())
+
+	// After the end of the file.
+	_, err = getMediaSegment(file, 60000, 0)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *MediaSegmentTestSuite) TestUnsupportedFormat() {
+	file := suite.createFile("album.ogg", []byte("OggS"))
+	defer file.Close()
+
+	_, err := getMediaSegment(file, 1000, 2000)
+	assert.Equal(suite.T(), errUnsupportedSegmentFormat, err)
+}
+
+func (suite *MediaSegmentTestSuite) TestMultiReaderAt() {
+	reader := newMultiReaderAt().
+		add(strings.NewReader("header"), 6).
+		add(io.NewSectionReader(strings.NewReader("0123456789"), 2, 5), 5)
+	assert.Equal(suite.T(), int64(11), reader.size())
+
+	part := make([]byte, 4)
+	n, err := reader.ReadAt(part, 4)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 4, n)
+	assert.Equal(suite.T(), "er23", string(part))
+
+	n, err = reader.ReadAt(part, 9)
+	assert.Equal(suite.T(), io.EOF, err)
+	assert.Equal(suite.T(), "56", string(part[0:n]))
+}
+
+func (suite *MediaSegmentTestSuite) TestParseMp3FrameHeader() {
+	frame, ok := parseMp3FrameHeader([]byte{0xff, 0xfb, 0x90, 0x00})
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), mp3Frame{Size: 417, Samples: 1152, SampleRate: 44100, Channels: 2, Bitrate: 128000}, frame)
+
+	// Padding.
+	frame, ok = parseMp3FrameHeader([]byte{0xff, 0xfb, 0x92, 0x00})
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), 418, frame.Size)
+
+	// MPEG 2 layer III, 64 kbit/s, 22.05 kHz, mono.
+	frame, ok = parseMp3FrameHeader([]byte{0xff, 0xf3, 0x80, 0xc0})
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), mp3Frame{Size: 208, Samples: 576, SampleRate: 22050, Channels: 1, Bitrate: 64000}, frame)
+
+	// Free or bad bitrates, bad sample rate, reserved version.
+	_, ok = parseMp3FrameHeader([]byte{0xff, 0xfb, 0x00, 0x00})
+	assert.False(suite.T(), ok)
+	_, ok = parseMp3FrameHeader([]byte{0xff, 0xfb, 0xf0, 0x00})
+	assert.False(suite.T(), ok)
+	_, ok = parseMp3FrameHeader([]byte{0xff, 0xfb, 0x9c, 0x00})
+	assert.False(suite.T(), ok)
+	_, ok = parseMp3FrameHeader([]byte{0xff, 0xeb, 0x90, 0x00})
+	assert.False(suite.T(), ok)
+}
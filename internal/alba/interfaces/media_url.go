@@ -0,0 +1,98 @@
+package interfaces
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+/*
+This file exposes the signing of the media URLs.
+
+The URLs of the media files given to the clients carry an expiry time, the user they were given to and a HMAC-SHA256
+signature of both and of the path, so they work without cookies but can't be made up from the ids. The server has no
+user accounts yet, so the URLs are given to the anonymous user. Signing can be disabled with MediaUrls.Signed.
+*/
+
+var errMediaUrlInvalid = errors.New("invalid signature")
+var errMediaUrlExpired = errors.New("expired url")
+
+// Name of the user the media URLs are given to when there is no user account.
+const anonymousUser = ""
+
+/*
+Signs the path of a media URL, like "/stream/12", for a user.
+
+Expiry times are rounded to the hour, so the URL of a file stays the same for an hour and can be cached by the
+clients. URLs are valid for MediaUrls.Expiry hours, an hour less at most.
+*/
+func signMediaUrl(path string, user string, now time.Time) string {
+	if !viper.GetBool("MediaUrls.Signed") {
+		return path
+	}
+
+	expires := now.Truncate(time.Hour).Add(time.Duration(viper.GetInt("MediaUrls.Expiry")) * time.Hour).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	if user != anonymousUser {
+		query.Set("user", user)
+	}
+	query.Set("signature", getMediaUrlSignature(path, expires, user))
+
+	return path + "?" + query.Encode()
+}
+
+/*
+Verifies the signature of a media URL, from its request and its path before the handler prefix was stripped.
+
+Returns errMediaUrlInvalid if the URL hasn't been signed by the server, or errMediaUrlExpired.
+*/
+func verifyMediaUrl(r *http.Request, path string, now time.Time) error {
+	if !viper.GetBool("MediaUrls.Signed") {
+		return nil
+	}
+
+	query := r.URL.Query()
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || viper.GetString("MediaUrls.Secret") == "" {
+		return errMediaUrlInvalid
+	}
+	signature := getMediaUrlSignature(path, expires, query.Get("user"))
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(signature)) {
+		return errMediaUrlInvalid
+	}
+	if now.Unix() > expires {
+		return errMediaUrlExpired
+	}
+
+	return nil
+}
+
+// Gets the signature of a media URL.
+func getMediaUrlSignature(path string, expires int64, user string) string {
+	mac := hmac.New(sha256.New, []byte(viper.GetString("MediaUrls.Secret")))
+	mac.Write([]byte(path + "\n" + strconv.FormatInt(expires, 10) + "\n" + user))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Answers 403 Forbidden if a media URL isn't signed or has expired, and returns false.
+func checkMediaUrl(w http.ResponseWriter, r *http.Request, path string) bool {
+	switch verifyMediaUrl(r, path, time.Now()) {
+	case nil:
+		return true
+	case errMediaUrlExpired:
+		http.Error(w, "Expired URL", http.StatusForbidden)
+	default:
+		http.Error(w, "Invalid signature", http.StatusForbidden)
+	}
+
+	return false
+}
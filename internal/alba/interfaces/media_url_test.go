@@ -0,0 +1,98 @@
+package interfaces
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MediaUrlTestSuite struct {
+	suite.Suite
+}
+
+// Go testing framework entry point.
+func TestMediaUrlTestSuite(t *testing.T) {
+	suite.Run(t, new(MediaUrlTestSuite))
+}
+
+func (suite *MediaUrlTestSuite) SetupTest() {
+	viper.Set("MediaUrls.Signed", true)
+	viper.Set("MediaUrls.Expiry", 24)
+	viper.Set("MediaUrls.Secret", "secret")
+}
+
+func (suite *MediaUrlTestSuite) TearDownTest() {
+	viper.Set("MediaUrls.Signed", false)
+	viper.Set("MediaUrls.Expiry", 0)
+	viper.Set("MediaUrls.Secret", "")
+}
+
+// Verifies a media URL as requested to a handler registered at a prefix.
+func verifyTestMediaUrl(mediaUrl string, path string, now time.Time) error {
+	return verifyMediaUrl(httptest.NewRequest("GET", mediaUrl, nil), path, now)
+}
+
+func (suite *MediaUrlTestSuite) TestSignMediaUrl() {
+	now := time.Date(2020, 5, 1, 10, 30, 0, 0, time.UTC)
+	signed := signMediaUrl("/stream/12", anonymousUser, now)
+	parsed, err := url.Parse(signed)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "/stream/12", parsed.Path)
+	assert.Equal(suite.T(), "1588413600", parsed.Query().Get("expires"))
+	assert.Empty(suite.T(), parsed.Query().Get("user"))
+	assert.NotEmpty(suite.T(), parsed.Query().Get("signature"))
+
+	// URLs stay the same during an hour.
+	assert.Equal(suite.T(), signed, signMediaUrl("/stream/12", anonymousUser, now.Add(20*time.Minute)))
+	assert.NotEqual(suite.T(), signed, signMediaUrl("/stream/12", anonymousUser, now.Add(40*time.Minute)))
+	assert.Contains(suite.T(), signMediaUrl("/stream/12", "bob", now), "user=bob")
+
+	viper.Set("MediaUrls.Signed", false)
+	assert.Equal(suite.T(), "/stream/12", signMediaUrl("/stream/12", anonymousUser, now))
+}
+
+func (suite *MediaUrlTestSuite) TestVerifyMediaUrl() {
+	now := time.Date(2020, 5, 1, 10, 30, 0, 0, time.UTC)
+	signed := signMediaUrl("/stream/12", anonymousUser, now)
+	assert.Nil(suite.T(), verifyTestMediaUrl(signed, "/stream/12", now))
+	assert.Nil(suite.T(), verifyTestMediaUrl(signed, "/stream/12", now.Add(23*time.Hour)))
+	assert.Equal(suite.T(), errMediaUrlExpired, verifyTestMediaUrl(signed, "/stream/12", now.Add(24*time.Hour)))
+
+	// Other paths, users and tampered URLs.
+	assert.Equal(suite.T(), errMediaUrlInvalid, verifyTestMediaUrl(signed, "/stream/13", now))
+	assert.Equal(suite.T(), errMediaUrlInvalid, verifyTestMediaUrl(signed, "/covers/12", now))
+	assert.Equal(suite.T(), errMediaUrlInvalid, verifyTestMediaUrl(signed+"&user=bob", "/stream/12", now))
+	assert.Equal(suite.T(), errMediaUrlInvalid, verifyTestMediaUrl("/stream/12", "/stream/12", now))
+	signedBob := signMediaUrl("/stream/12", "bob", now)
+	assert.Nil(suite.T(), verifyTestMediaUrl(signedBob, "/stream/12", now))
+	parsed, _ := url.Parse(signedBob)
+	query := parsed.Query()
+	query.Set("expires", "1999999999")
+	assert.Equal(suite.T(), errMediaUrlInvalid, verifyTestMediaUrl("/stream/12?"+query.Encode(), "/stream/12", now))
+
+	// Another secret.
+	viper.Set("MediaUrls.Secret", "other")
+	assert.Equal(suite.T(), errMediaUrlInvalid, verifyTestMediaUrl(signed, "/stream/12", now))
+	viper.Set("MediaUrls.Secret", "")
+	assert.Equal(suite.T(), errMediaUrlInvalid, verifyTestMediaUrl(signed, "/stream/12", now))
+
+	viper.Set("MediaUrls.Signed", false)
+	assert.Nil(suite.T(), verifyTestMediaUrl("/stream/12", "/stream/12", now))
+}
+
+func (suite *MediaUrlTestSuite) TestCheckMediaUrl() {
+	recorder := httptest.NewRecorder()
+	assert.False(suite.T(), checkMediaUrl(recorder, httptest.NewRequest("GET", "/stream/12", nil), "/stream/12"))
+	assert.Equal(suite.T(), http.StatusForbidden, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	signed := signMediaUrl("/stream/12", anonymousUser, time.Now())
+	assert.True(suite.T(), checkMediaUrl(recorder, httptest.NewRequest("GET", signed, nil), "/stream/12"))
+	assert.Equal(suite.T(), http.StatusOK, recorder.Code)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
 	"github.com/humbkr/albaplayer-server/internal/alba/domain"
 )
 
@@ -34,15 +35,21 @@ Fetches all albums from the database.
 */
 func (ar AlbumDbRepository) GetAll(hydrate bool) (entities domain.Albums, err error) {
 	if !hydrate {
-		query := "SELECT id, title, year, artist_id, cover_id, created_at FROM albums"
+		query := "SELECT id, title, sort_name, match_key, year, date, original_date, artist_id, cover_id, musicbrainz_album_id, created_at FROM albums"
 		_, err = ar.AppContext.DB.Select(&entities, query)
 
 	} else {
 		type gorpResult struct {
 			AlbumId int
 			AlbumTitle string
+			AlbumSortName string
+			AlbumMatchKey string
 			AlbumYear string
+			AlbumDate string
+			AlbumOriginalDate string
 			AlbumArtistId int
+			AlbumCoverId int
+			AlbumMusicBrainzAlbumId string
 			AlbumCreatedAt int64
 			domain.Track
 			// Cannot select domain.album.ArtistId or domain.track.AlbumId because of a Gorp error...
@@ -52,49 +59,41 @@ func (ar AlbumDbRepository) GetAll(hydrate bool) (entities domain.Albums, err er
 		}
 		var results []gorpResult
 
-		query := "SELECT alb.Id AlbumId, alb.Title AlbumTitle, alb.Year AlbumYear, alb.artist_id AlbumArtistId, alb.created_at AlbumCreatedAt, trk.* " +
-			     "FROM albums alb, tracks trk WHERE alb.id = trk.album_id"
+		query := "SELECT alb.Id AlbumId, alb.Title AlbumTitle, alb.sort_name AlbumSortName, alb.match_key AlbumMatchKey, alb.Year AlbumYear, alb.date AlbumDate, alb.original_date AlbumOriginalDate, alb.artist_id AlbumArtistId, alb.cover_id AlbumCoverId, alb.musicbrainz_album_id AlbumMusicBrainzAlbumId, alb.created_at AlbumCreatedAt, trk.* " +
+			     "FROM albums alb, tracks trk WHERE alb.id = trk.album_id ORDER BY alb.id, trk.id"
 
 		_, err = ar.AppContext.DB.Select(&results, query)
 		if err == nil {
-			// Deduplicate stuff.
+			// Deduplicate stuff, the rows of an album being consecutive.
 			var current domain.Album
 			for _, r := range results {
-				track := domain.Track{
-					Id: r.Id,
-					Title: r.Title,
-					AlbumId: r.AlbumId,
-					ArtistId: r.ArtistId,
-					CoverId: r.CoverId,
-					Disc: r.Disc,
-					Number: r.Number,
-					Duration: r.Duration,
-					Genre: r.Genre,
-					Path: r.Path,
-					DateAdded: r.DateAdded,
-				}
+				// All the columns of the tracks are scanned, only the album id is shadowed.
+				track := r.Track
+				track.AlbumId = r.AlbumId
 
-				if current.Id == 0 {
-					current = domain.Album{
-						Id: r.AlbumId,
-						Title: r.AlbumTitle,
-						Year: r.AlbumYear,
-						ArtistId: r.AlbumArtistId,
-						DateAdded: r.AlbumCreatedAt,
+				if r.AlbumId != current.Id {
+					if current.Id != 0 {
+						entities = append(entities, current)
 					}
-				} else if r.Id != current.Id {
-					entities = append(entities, current)
-					// Then change the current album
 					current = domain.Album{
 						Id: r.AlbumId,
 						Title: r.AlbumTitle,
+						SortName: r.AlbumSortName,
+						MatchKey: r.AlbumMatchKey,
 						Year: r.AlbumYear,
+						Date: r.AlbumDate,
+						OriginalDate: r.AlbumOriginalDate,
 						ArtistId: r.AlbumArtistId,
+						CoverId: r.AlbumCoverId,
+						MusicBrainzAlbumId: r.AlbumMusicBrainzAlbumId,
 						DateAdded: r.AlbumCreatedAt,
 					}
 				}
 				current.Tracks = append(current.Tracks, track)
 			}
+			if current.Id != 0 {
+				entities = append(entities, current)
+			}
 		}
 	}
 
@@ -102,11 +101,11 @@ func (ar AlbumDbRepository) GetAll(hydrate bool) (entities domain.Albums, err er
 }
 
 /*
-Fetches an album from database.
+Fetches an album from database based on its title (case and Unicode normalisation insensitive) and artist.
 */
 func (ar AlbumDbRepository) GetByName(name string, artistId int) (entity domain.Album, err error) {
 	var entities domain.Albums
-	_, err = ar.AppContext.DB.Select(&entities, "SELECT * FROM albums WHERE title = ? AND artist_id = ?", name, artistId)
+	_, err = ar.AppContext.DB.Select(&entities, "SELECT * FROM albums WHERE match_key = ? AND artist_id = ? ORDER BY id", business.MatchKey(name), artistId)
 
 	if err == nil {
 		if len(entities) > 0 {
@@ -133,10 +132,41 @@ func (ar AlbumDbRepository) GetAlbumsForArtist(artistId int, hydrate bool) (enti
 	return
 }
 
+/*
+Fetches albums having at least one track of the specified genre from database.
+*/
+func (ar AlbumDbRepository) GetAlbumsForGenre(genreId int) (entities domain.Albums, err error) {
+	_, err = ar.AppContext.DB.Select(
+		&entities,
+		"SELECT * FROM albums WHERE id IN (SELECT trk.album_id FROM tracks trk, track_genres tg WHERE tg.track_id = trk.id AND tg.genre_id = ?)",
+		genreId,
+	)
+
+	return
+}
+
+/*
+Fetches albums of other artists having at least one track crediting the specified artist from database.
+*/
+func (ar AlbumDbRepository) GetAlbumsArtistAppearsOn(artistId int) (entities domain.Albums, err error) {
+	_, err = ar.AppContext.DB.Select(
+		&entities,
+		"SELECT * FROM albums WHERE artist_id != ? AND id IN (SELECT trk.album_id FROM tracks trk, track_artists ta WHERE ta.track_id = trk.id AND ta.artist_id = ?) ORDER BY year, title",
+		artistId,
+		artistId,
+	)
+
+	return
+}
+
 /*
 Create or update an album in the Database.
 */
 func (ar AlbumDbRepository) Save(entity *domain.Album) (err error) {
+	// The key is kept when the entity is renamed, so manual edits don't break the matching of the media files.
+	if entity.MatchKey == "" {
+		entity.MatchKey = business.MatchKey(entity.Title)
+	}
 	if entity.Id != 0 {
 		// Update.
 		_, err = ar.AppContext.DB.Update(entity)
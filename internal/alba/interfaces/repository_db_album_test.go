@@ -75,6 +75,42 @@ func (suite *AlbumRepoTestSuite) TestGetAll() {
 	}
 }
 
+func (suite *AlbumRepoTestSuite) TestGetAllDates() {
+	db := suite.AlbumRepository.AppContext.DB
+	_, _ = db.Exec("UPDATE albums SET date = '1996-10-01', original_date = '1996-09-17' WHERE id = 1")
+	_, _ = db.Exec("UPDATE tracks SET date = '1996-10-01', original_date = '1996-09-17', start_ms = 1000, end_ms = 2000, cue_track = 1, replaygain_track_gain = -6.5, loudness_track_lufs = -9.5 WHERE id = 1")
+
+	albums, err := suite.AlbumRepository.GetAll(false)
+	assert.Nil(suite.T(), err)
+	for _, album := range albums {
+		if album.Id == 1 {
+			assert.Equal(suite.T(), "1996-10-01", album.Date)
+			assert.Equal(suite.T(), "1996-09-17", album.OriginalDate)
+		}
+	}
+
+	// The tracks are fetched with all their fields.
+	albums, err = suite.AlbumRepository.GetAll(true)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), albums, 2)
+	album := albums[0]
+	assert.Equal(suite.T(), 1, album.Id)
+	assert.Equal(suite.T(), "1996-10-01", album.Date)
+	assert.Equal(suite.T(), "1996-09-17", album.OriginalDate)
+	assert.Len(suite.T(), album.Tracks, 15)
+	track := album.Tracks[0]
+	assert.Equal(suite.T(), 1, track.Id)
+	assert.Equal(suite.T(), 1, track.AlbumId)
+	assert.Equal(suite.T(), "Stinkfist", track.Title)
+	assert.Equal(suite.T(), "1996-10-01", track.Date)
+	assert.Equal(suite.T(), "1996-09-17", track.OriginalDate)
+	assert.Equal(suite.T(), 1000, track.StartMs)
+	assert.Equal(suite.T(), 2000, track.EndMs)
+	assert.Equal(suite.T(), 1, track.CueTrack)
+	assert.Equal(suite.T(), -6.5, track.ReplayGainTrackGain)
+	assert.Equal(suite.T(), -9.5, track.LoudnessTrackLufs)
+}
+
 func (suite *AlbumRepoTestSuite) TestGetByName() {
 	// Test album retrieval.
 	album, err := suite.AlbumRepository.GetByName("Ænima", 2)
@@ -85,6 +121,11 @@ func (suite *AlbumRepoTestSuite) TestGetByName() {
 	assert.Equal(suite.T(), "1996", album.Year)
 	assert.Empty(suite.T(), album.Tracks)
 
+	// Test that titles are matched regardless of their case.
+	album, err = suite.AlbumRepository.GetByName("æNIMA", 2)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, album.Id)
+
 	// Test to get an album with non existant name.
 	_, err = suite.AlbumRepository.GetByName("Bogus", 2)
 	assert.NotNil(suite.T(), err)
@@ -125,6 +166,29 @@ func (suite *AlbumRepoTestSuite) TestGetAlbumsForArtist() {
 	}
 }
 
+func (suite *AlbumRepoTestSuite) TestGetAlbumsForGenre() {
+	albums, err := suite.AlbumRepository.GetAlbumsForGenre(1)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), albums, 1)
+	assert.Equal(suite.T(), 1, albums[0].Id)
+
+	albums, err = suite.AlbumRepository.GetAlbumsForGenre(2)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), albums)
+}
+
+func (suite *AlbumRepoTestSuite) TestGetAlbumsArtistAppearsOn() {
+	albums, err := suite.AlbumRepository.GetAlbumsArtistAppearsOn(3)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), albums, 1)
+	assert.Equal(suite.T(), 1, albums[0].Id)
+
+	// The artist own albums are not returned.
+	albums, err = suite.AlbumRepository.GetAlbumsArtistAppearsOn(2)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), albums)
+}
+
 func (suite *AlbumRepoTestSuite) TestSave() {
 	// Note: we do not save embedded objects for the time being.
 	// Test to save a new album.
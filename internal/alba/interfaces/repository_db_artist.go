@@ -45,11 +45,11 @@ func (ar ArtistDbRepository) GetAll(hydrate bool) (entities domain.Artists, err
 }
 
 /**
-Fetches an artist from database based on its name (case insensitive).
+Fetches an artist from database based on its name (case and Unicode normalisation insensitive).
 */
 func (ar ArtistDbRepository) GetByName(name string) (entity domain.Artist, err error) {
 	var entities domain.Artists
-	_, err = ar.AppContext.DB.Select(&entities, "SELECT * FROM artists WHERE name = ?", name)
+	_, err = ar.AppContext.DB.Select(&entities, "SELECT * FROM artists WHERE match_key = ? ORDER BY id", business.MatchKey(name))
 
 	if err == nil {
 		if len(entities) > 0 {
@@ -62,10 +62,27 @@ func (ar ArtistDbRepository) GetByName(name string) (entity domain.Artist, err e
 	return
 }
 
+/**
+Fetches artists having at least one track of the specified genre from database.
+*/
+func (ar ArtistDbRepository) GetArtistsForGenre(genreId int) (entities domain.Artists, err error) {
+	_, err = ar.AppContext.DB.Select(
+		&entities,
+		"SELECT * FROM artists WHERE id IN (SELECT trk.artist_id FROM tracks trk, track_genres tg WHERE tg.track_id = trk.id AND tg.genre_id = ?)",
+		genreId,
+	)
+
+	return
+}
+
 /**
 Create or update an artist in the Database.
 */
 func (ar ArtistDbRepository) Save(entity *domain.Artist) (err error) {
+	// The key is kept when the entity is renamed, so manual edits don't break the matching of the media files.
+	if entity.MatchKey == "" {
+		entity.MatchKey = business.MatchKey(entity.Name)
+	}
 	if entity.Id != 0 {
 		// Update.
 		_, err = ar.AppContext.DB.Update(entity)
@@ -91,6 +108,12 @@ func (ar ArtistDbRepository) Delete(entity *domain.Artist) (err error) {
 		_ = albumRepo.Delete(&entity.Albums[i])
 	}
 
+	// Remove the artist credits on other artists tracks.
+	_, err = ar.AppContext.DB.Exec("DELETE FROM track_artists WHERE artist_id = ?", entity.Id)
+	if err != nil {
+		return
+	}
+
 	// Then delete album.
 	_, err = ar.AppContext.DB.Delete(entity)
 
@@ -103,9 +126,9 @@ func (ar ArtistDbRepository) Exists(id int) bool {
 	return err == nil
 }
 
-// Removes artists without tracks drom DB.
+// Removes artists without tracks, credits nor albums drom DB.
 func (ar ArtistDbRepository) CleanUp() error {
-	_, err := ar.AppContext.DB.Exec("DELETE FROM artists WHERE NOT EXISTS (SELECT id FROM tracks WHERE tracks.artist_id = artists.id) AND artists.name != ?", business.LibraryDefaultCompilationArtist)
+	_, err := ar.AppContext.DB.Exec("DELETE FROM artists WHERE NOT EXISTS (SELECT id FROM tracks WHERE tracks.artist_id = artists.id) AND NOT EXISTS (SELECT track_id FROM track_artists WHERE track_artists.artist_id = artists.id) AND NOT EXISTS (SELECT id FROM albums WHERE albums.artist_id = artists.id) AND artists.name != ?", business.LibraryDefaultCompilationArtist)
 	return err
 }
 
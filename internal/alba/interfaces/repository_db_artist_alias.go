@@ -0,0 +1,199 @@
+package interfaces
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-gorp/gorp"
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+// Types of the entities moved by a merge.
+const (
+	artistAliasMoveAlbum  = "album"
+	artistAliasMoveTrack  = "track"
+	artistAliasMoveCredit = "credit"
+	artistAliasMoveAlias  = "alias"
+)
+
+type ArtistAliasDbRepository struct {
+	AppContext *AppContext
+}
+
+/*
+Fetches an artist alias from the database.
+*/
+func (ar ArtistAliasDbRepository) Get(id int) (entity domain.ArtistAlias, err error) {
+	object, err := ar.AppContext.DB.Get(domain.ArtistAlias{}, id)
+	if err == nil && object != nil {
+		entity = *object.(*domain.ArtistAlias)
+	} else {
+		err = errors.New("no artist alias found")
+	}
+
+	return
+}
+
+/*
+Fetches all artist aliases from the database, or the aliases of an artist if artistId is not 0.
+*/
+func (ar ArtistAliasDbRepository) GetAll(artistId int) (entities domain.ArtistAliases, err error) {
+	_, err = ar.AppContext.DB.Select(
+		&entities,
+		"SELECT * FROM artist_aliases WHERE artist_id = ? OR ? = 0 ORDER BY artist_id, name",
+		artistId,
+		artistId,
+	)
+
+	return
+}
+
+/*
+Merges artists into a target artist.
+
+Albums, tracks and credits of the merged artists are given to the target artist, and the names of the merged artists
+are recorded as aliases of the target artist. What has been moved is recorded too, so the merge can be undone.
+*/
+func (ar ArtistAliasDbRepository) Merge(sourceIds []int, targetId int) (aliases domain.ArtistAliases, err error) {
+	tx, err := ar.begin()
+	if err != nil {
+		return
+	}
+
+	for _, sourceId := range sourceIds {
+		alias, errMerge := mergeArtist(tx, sourceId, targetId)
+		if errMerge != nil {
+			_ = tx.Rollback()
+			return nil, errMerge
+		}
+		aliases = append(aliases, alias)
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// See ArtistAliasDbRepository.Merge().
+func mergeArtist(tx *gorp.Transaction, sourceId int, targetId int) (alias domain.ArtistAlias, err error) {
+	object, err := tx.Get(domain.Artist{}, sourceId)
+	if err != nil || object == nil {
+		return alias, errors.New("no artist found")
+	}
+	source := object.(*domain.Artist)
+
+	alias = domain.ArtistAlias{
+		ArtistId:       targetId,
+		Name:           source.Name,
+		SortName:       source.SortName,
+		MatchKey:       source.MatchKey,
+		SourceArtistId: source.Id,
+		DateAdded:      time.Now().Unix(),
+	}
+	if err = tx.Insert(&alias); err != nil {
+		return
+	}
+
+	queries := []string{
+		// Record what is about to be moved.
+		"INSERT INTO artist_alias_moves (alias_id, entity_type, entity_id) SELECT ?3, '" + artistAliasMoveAlbum + "', id FROM albums WHERE artist_id = ?2",
+		"INSERT INTO artist_alias_moves (alias_id, entity_type, entity_id) SELECT ?3, '" + artistAliasMoveTrack + "', id FROM tracks WHERE artist_id = ?2",
+		// Credits the target artist already has are not moved.
+		"INSERT INTO artist_alias_moves (alias_id, entity_type, entity_id, role, position) SELECT ?3, '" + artistAliasMoveCredit + "', track_id, role, position FROM track_artists ta " +
+			"WHERE artist_id = ?2 AND NOT EXISTS (SELECT track_id FROM track_artists WHERE track_id = ta.track_id AND artist_id = ?1 AND role = ta.role)",
+		"INSERT INTO artist_alias_moves (alias_id, entity_type, entity_id) SELECT ?3, '" + artistAliasMoveAlias + "', id FROM artist_aliases WHERE artist_id = ?2",
+		// Then move.
+		"UPDATE albums SET artist_id = ?1 WHERE artist_id = ?2",
+		"UPDATE tracks SET artist_id = ?1 WHERE artist_id = ?2",
+		"INSERT OR IGNORE INTO track_artists (track_id, artist_id, role, position) SELECT track_id, ?1, role, position FROM track_artists WHERE artist_id = ?2",
+		"DELETE FROM track_artists WHERE artist_id = ?2",
+		"UPDATE artist_aliases SET artist_id = ?1 WHERE artist_id = ?2",
+		"DELETE FROM artists WHERE id = ?2",
+	}
+	for _, query := range queries {
+		if _, err = tx.Exec(query, targetId, sourceId, alias.Id); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+/*
+Undoes the merge which created an alias.
+
+The merged artist is created again with its original ID, gets back what has been moved to the canonical artist, and the
+alias is deleted. Returns the restored artist.
+*/
+func (ar ArtistAliasDbRepository) Unmerge(alias *domain.ArtistAlias) (artist domain.Artist, err error) {
+	artist = domain.Artist{
+		Id:        alias.SourceArtistId,
+		Name:      alias.Name,
+		SortName:  alias.SortName,
+		MatchKey:  alias.MatchKey,
+		DateAdded: time.Now().Unix(),
+	}
+
+	tx, err := ar.begin()
+	if err != nil {
+		return
+	}
+
+	// The ID is kept so the manual edits of the artist apply again.
+	_, err = tx.Exec(
+		"INSERT INTO artists (id, name, sort_name, match_key, created_at) VALUES (?, ?, ?, ?, ?)",
+		artist.Id,
+		artist.Name,
+		artist.SortName,
+		artist.MatchKey,
+		artist.DateAdded,
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		return
+	}
+
+	queries := []string{
+		"UPDATE albums SET artist_id = ?2 WHERE artist_id = ?1 AND id IN (SELECT entity_id FROM artist_alias_moves WHERE alias_id = ?3 AND entity_type = '" + artistAliasMoveAlbum + "')",
+		"UPDATE tracks SET artist_id = ?2 WHERE artist_id = ?1 AND id IN (SELECT entity_id FROM artist_alias_moves WHERE alias_id = ?3 AND entity_type = '" + artistAliasMoveTrack + "')",
+		"INSERT OR IGNORE INTO track_artists (track_id, artist_id, role, position) SELECT entity_id, ?2, role, position FROM artist_alias_moves WHERE alias_id = ?3 AND entity_type = '" + artistAliasMoveCredit + "'",
+		"DELETE FROM track_artists WHERE artist_id = ?1 AND EXISTS (SELECT entity_id FROM artist_alias_moves WHERE alias_id = ?3 AND entity_type = '" + artistAliasMoveCredit + "' AND entity_id = track_artists.track_id AND role = track_artists.role)",
+		"UPDATE artist_aliases SET artist_id = ?2 WHERE artist_id = ?1 AND id IN (SELECT entity_id FROM artist_alias_moves WHERE alias_id = ?3 AND entity_type = '" + artistAliasMoveAlias + "')",
+		"DELETE FROM artist_alias_moves WHERE alias_id = ?3",
+		"DELETE FROM artist_aliases WHERE id = ?3",
+	}
+	for _, query := range queries {
+		if _, err = tx.Exec(query, alias.ArtistId, alias.SourceArtistId, alias.Id); err != nil {
+			_ = tx.Rollback()
+			return
+		}
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// Starts a transaction on the datasource.
+func (ar ArtistAliasDbRepository) begin() (*gorp.Transaction, error) {
+	// TODO Find a way to not have to get the datasource implementation.
+	gorpDbMap, ok := ar.AppContext.DB.(*gorp.DbMap)
+	if !ok {
+		return nil, errors.New("cannot get underlying gorp dbmap")
+	}
+
+	return gorpDbMap.Begin()
+}
+
+// Removes the aliases of deleted artists from DB.
+func (ar ArtistAliasDbRepository) CleanUp() error {
+	queries := []string{
+		"DELETE FROM artist_aliases WHERE NOT EXISTS (SELECT id FROM artists WHERE artists.id = artist_aliases.artist_id)",
+		"DELETE FROM artist_alias_moves WHERE NOT EXISTS (SELECT id FROM artist_aliases WHERE artist_aliases.id = artist_alias_moves.alias_id)",
+	}
+	for _, query := range queries {
+		if _, err := ar.AppContext.DB.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package interfaces
+
+import (
+	"log"
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ArtistAliasRepoTestSuite struct {
+	suite.Suite
+	ArtistAliasRepository ArtistAliasDbRepository
+}
+
+/**
+Go testing framework entry point.
+ */
+func TestArtistAliasRepoTestSuite(t *testing.T) {
+	suite.Run(t, new(ArtistAliasRepoTestSuite))
+}
+
+func (suite *ArtistAliasRepoTestSuite) SetupSuite() {
+	ds, err := createTestDatasource()
+	if err != nil {
+		log.Fatal(err)
+	}
+	appContext := AppContext{DB: ds}
+	suite.ArtistAliasRepository = ArtistAliasDbRepository{AppContext: &appContext}
+}
+
+func (suite *ArtistAliasRepoTestSuite) TearDownSuite() {
+	if err := closeTestDataSource(suite.ArtistAliasRepository.AppContext.DB); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (suite *ArtistAliasRepoTestSuite) SetupTest() {
+	resetTestDataSource(suite.ArtistAliasRepository.AppContext.DB)
+}
+
+func (suite *ArtistAliasRepoTestSuite) TestGet() {
+	alias, err := suite.ArtistAliasRepository.Get(1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, alias.ArtistId)
+	assert.Equal(suite.T(), "Tool (band)", alias.Name)
+	assert.Equal(suite.T(), business.MatchKey("Tool (band)"), alias.MatchKey)
+	assert.Equal(suite.T(), 4, alias.SourceArtistId)
+
+	// Test to get a non existing alias.
+	_, err = suite.ArtistAliasRepository.Get(99)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *ArtistAliasRepoTestSuite) TestGetAll() {
+	aliases, err := suite.ArtistAliasRepository.GetAll(0)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), aliases, 2)
+
+	aliases, err = suite.ArtistAliasRepository.GetAll(2)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), aliases, 1)
+	assert.Equal(suite.T(), "Tool (band)", aliases[0].Name)
+
+	aliases, err = suite.ArtistAliasRepository.GetAll(3)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), aliases)
+}
+
+func (suite *ArtistAliasRepoTestSuite) TestMerge() {
+	db := suite.ArtistAliasRepository.AppContext.DB
+
+	aliases, err := suite.ArtistAliasRepository.Merge([]int{3}, 2)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), aliases, 1)
+	assert.NotEmpty(suite.T(), aliases[0].Id)
+	assert.Equal(suite.T(), 2, aliases[0].ArtistId)
+	assert.Equal(suite.T(), "Artist Test", aliases[0].Name)
+	assert.Equal(suite.T(), 3, aliases[0].SourceArtistId)
+
+	// The merged artist is deleted and its albums, tracks and credits moved.
+	object, err := db.Get(domain.Artist{}, 3)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), object)
+
+	var album domain.Album
+	assert.Nil(suite.T(), db.SelectOne(&album, "SELECT * FROM albums WHERE id = 2"))
+	assert.Equal(suite.T(), 2, album.ArtistId)
+
+	var track domain.Track
+	assert.Nil(suite.T(), db.SelectOne(&track, "SELECT * FROM tracks WHERE id = 16"))
+	assert.Equal(suite.T(), 2, track.ArtistId)
+
+	var credits domain.TrackArtists
+	_, err = db.Select(&credits, "SELECT * FROM track_artists WHERE track_id = 2 ORDER BY position")
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), credits, 2)
+	assert.Equal(suite.T(), 2, credits[1].ArtistId)
+	assert.Equal(suite.T(), domain.ArtistRoleFeatured, credits[1].Role)
+
+	// Test to merge a non existing artist: nothing is merged.
+	_, err = suite.ArtistAliasRepository.Merge([]int{1, 99}, 2)
+	assert.NotNil(suite.T(), err)
+	object, _ = db.Get(domain.Artist{}, 1)
+	assert.NotNil(suite.T(), object)
+	aliases, _ = suite.ArtistAliasRepository.GetAll(2)
+	assert.Len(suite.T(), aliases, 2)
+}
+
+func (suite *ArtistAliasRepoTestSuite) TestUnmerge() {
+	db := suite.ArtistAliasRepository.AppContext.DB
+
+	aliases, err := suite.ArtistAliasRepository.Merge([]int{3}, 2)
+	assert.Nil(suite.T(), err)
+
+	artist, err := suite.ArtistAliasRepository.Unmerge(&aliases[0])
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 3, artist.Id)
+	assert.Equal(suite.T(), "Artist Test", artist.Name)
+
+	var restored domain.Artist
+	assert.Nil(suite.T(), db.SelectOne(&restored, "SELECT * FROM artists WHERE id = 3"))
+	assert.Equal(suite.T(), business.MatchKey("Artist Test"), restored.MatchKey)
+
+	var album domain.Album
+	assert.Nil(suite.T(), db.SelectOne(&album, "SELECT * FROM albums WHERE id = 2"))
+	assert.Equal(suite.T(), 3, album.ArtistId)
+
+	var track domain.Track
+	assert.Nil(suite.T(), db.SelectOne(&track, "SELECT * FROM tracks WHERE id = 16"))
+	assert.Equal(suite.T(), 3, track.ArtistId)
+
+	// The credits the target artist had before the merge are kept.
+	var credits domain.TrackArtists
+	_, err = db.Select(&credits, "SELECT * FROM track_artists WHERE track_id = 2 ORDER BY position")
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), credits, 2)
+	assert.Equal(suite.T(), 2, credits[0].ArtistId)
+	assert.Equal(suite.T(), domain.ArtistRoleMain, credits[0].Role)
+	assert.Equal(suite.T(), 3, credits[1].ArtistId)
+	assert.Equal(suite.T(), domain.ArtistRoleFeatured, credits[1].Role)
+
+	_, err = suite.ArtistAliasRepository.Get(aliases[0].Id)
+	assert.NotNil(suite.T(), err)
+
+	// Test to undo a merge twice.
+	_, err = suite.ArtistAliasRepository.Unmerge(&aliases[0])
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *ArtistAliasRepoTestSuite) TestMergeChain() {
+	db := suite.ArtistAliasRepository.AppContext.DB
+
+	// Merge artist 3 into 2, then 2 into a new artist.
+	artist := domain.Artist{Name: "Canonical artist", MatchKey: business.MatchKey("Canonical artist")}
+	assert.Nil(suite.T(), db.Insert(&artist))
+	first, err := suite.ArtistAliasRepository.Merge([]int{3}, 2)
+	assert.Nil(suite.T(), err)
+	second, err := suite.ArtistAliasRepository.Merge([]int{2}, artist.Id)
+	assert.Nil(suite.T(), err)
+
+	aliases, _ := suite.ArtistAliasRepository.GetAll(artist.Id)
+	assert.Len(suite.T(), aliases, 3)
+
+	// Undoing the first merge gives artist 3 back its entities.
+	alias, _ := suite.ArtistAliasRepository.Get(first[0].Id)
+	assert.Equal(suite.T(), artist.Id, alias.ArtistId)
+	_, err = suite.ArtistAliasRepository.Unmerge(&alias)
+	assert.Nil(suite.T(), err)
+
+	var track domain.Track
+	assert.Nil(suite.T(), db.SelectOne(&track, "SELECT * FROM tracks WHERE id = 16"))
+	assert.Equal(suite.T(), 3, track.ArtistId)
+
+	// Undoing the second merge gives artist 2 back its entities and aliases.
+	_, err = suite.ArtistAliasRepository.Unmerge(&second[0])
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), db.SelectOne(&track, "SELECT * FROM tracks WHERE id = 1"))
+	assert.Equal(suite.T(), 2, track.ArtistId)
+	aliases, _ = suite.ArtistAliasRepository.GetAll(2)
+	assert.Len(suite.T(), aliases, 1)
+	assert.Equal(suite.T(), "Tool (band)", aliases[0].Name)
+}
+
+func (suite *ArtistAliasRepoTestSuite) TestCleanUp() {
+	err := suite.ArtistAliasRepository.CleanUp()
+	assert.Nil(suite.T(), err)
+
+	aliases, err := suite.ArtistAliasRepository.GetAll(0)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), aliases, 1)
+	assert.Equal(suite.T(), 2, aliases[0].ArtistId)
+}
@@ -88,11 +88,27 @@ func (suite *ArtistRepoTestSuite) TestGetByName() {
 	assert.Equal(suite.T(), "Tool", artist.Name)
 	assert.Empty(suite.T(), artist.Albums)
 
+	// Test that names are matched regardless of their case and whitespaces.
+	artist, err = suite.ArtistRepository.GetByName(" TOOL ")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, artist.Id)
+
 	// Test to get an artist with non existant name.
 	_, err = suite.ArtistRepository.GetByName("Bogus")
 	assert.NotNil(suite.T(), err)
 }
 
+func (suite *ArtistRepoTestSuite) TestGetArtistsForGenre() {
+	artists, err := suite.ArtistRepository.GetArtistsForGenre(1)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), artists, 1)
+	assert.Equal(suite.T(), "Tool", artists[0].Name)
+
+	artists, err = suite.ArtistRepository.GetArtistsForGenre(2)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), artists)
+}
+
 func (suite *ArtistRepoTestSuite) TestSave() {
 	// Note: we do not save embedded objects for the time being.
 	// Test to save a new artist.
@@ -209,6 +225,14 @@ func (suite *ArtistRepoTestSuite) TestCleanUp() {
 	errGetVarious := suite.ArtistRepository.AppContext.DB.SelectOne(&variousArtists, "SELECT * FROM artists WHERE name = ?", business.LibraryDefaultCompilationArtist)
 	assert.Nil(suite.T(), errGetVarious)
 
+	featuredArtist := domain.Artist{
+		Name: "Artist only featured on tracks",
+	}
+	err = suite.ArtistRepository.Save(&featuredArtist)
+	assert.Nil(suite.T(), err)
+	_, err = suite.ArtistRepository.AppContext.DB.Exec("INSERT INTO track_artists(track_id, artist_id, role, position) VALUES(1, ?, ?, 1)", featuredArtist.Id, domain.ArtistRoleFeatured)
+	assert.Nil(suite.T(), err)
+
 	errCleanUp := suite.ArtistRepository.CleanUp()
 	assert.Nil(suite.T(), errCleanUp)
 
@@ -221,4 +245,7 @@ func (suite *ArtistRepoTestSuite) TestCleanUp() {
 	variousArtists = domain.Artist{}
 	errGetVarious = suite.ArtistRepository.AppContext.DB.SelectOne(&variousArtists, "SELECT * FROM artists WHERE name = ?", business.LibraryDefaultCompilationArtist)
 	assert.Nil(suite.T(), errGetVarious)
+
+	// Check artists credited on tracks have not been deleted.
+	assert.True(suite.T(), suite.ArtistRepository.Exists(featuredArtist.Id))
 }
@@ -0,0 +1,67 @@
+package interfaces
+
+import (
+	"errors"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+type BookmarkDbRepository struct {
+	AppContext *AppContext
+}
+
+/*
+Fetches the bookmark of a user for a track from the database.
+*/
+func (br BookmarkDbRepository) Get(user string, trackId int) (entity domain.Bookmark, err error) {
+	err = br.AppContext.DB.SelectOne(&entity, "SELECT * FROM bookmarks WHERE user_name = ? AND track_id = ?", user, trackId)
+	if err != nil {
+		err = errors.New("no bookmark found")
+	}
+
+	return
+}
+
+/*
+Fetches all the bookmarks of a user from the database, the most recently modified first.
+*/
+func (br BookmarkDbRepository) GetAll(user string) (entities domain.Bookmarks, err error) {
+	_, err = br.AppContext.DB.Select(&entities, "SELECT * FROM bookmarks WHERE user_name = ? ORDER BY updated_at DESC, id DESC", user)
+
+	return
+}
+
+/*
+Create or update a bookmark in the Database.
+*/
+func (br BookmarkDbRepository) Save(entity *domain.Bookmark) (err error) {
+	entity.DateModified = time.Now().Unix()
+	if entity.Id != 0 {
+		// Update.
+		_, err = br.AppContext.DB.Update(entity)
+		return
+	} else {
+		// Insert new entity.
+		if entity.DateAdded == 0 {
+			entity.DateAdded = entity.DateModified
+		}
+		err = br.AppContext.DB.Insert(entity)
+		return
+	}
+}
+
+/*
+Delete a bookmark from the Database.
+*/
+func (br BookmarkDbRepository) Delete(entity *domain.Bookmark) (err error) {
+	_, err = br.AppContext.DB.Delete(entity)
+	return
+}
+
+// Removes the bookmarks of deleted tracks from DB.
+func (br BookmarkDbRepository) CleanUp() error {
+	_, err := br.AppContext.DB.Exec("DELETE FROM bookmarks WHERE NOT EXISTS (SELECT id FROM tracks WHERE tracks.id = bookmarks.track_id)")
+
+	return err
+}
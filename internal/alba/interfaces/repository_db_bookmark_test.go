@@ -0,0 +1,113 @@
+package interfaces
+
+import (
+	"log"
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type BookmarkRepoTestSuite struct {
+	suite.Suite
+	BookmarkRepository BookmarkDbRepository
+}
+
+/**
+Go testing framework entry point.
+ */
+func TestBookmarkRepoTestSuite(t *testing.T) {
+	suite.Run(t, new(BookmarkRepoTestSuite))
+}
+
+func (suite *BookmarkRepoTestSuite) SetupSuite() {
+	ds, err := createTestDatasource()
+	if err != nil {
+		log.Fatal(err)
+	}
+	appContext := AppContext{DB: ds}
+	suite.BookmarkRepository = BookmarkDbRepository{AppContext: &appContext}
+}
+
+func (suite *BookmarkRepoTestSuite) TearDownSuite() {
+	if err := closeTestDataSource(suite.BookmarkRepository.AppContext.DB); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (suite *BookmarkRepoTestSuite) SetupTest() {
+	resetTestDataSource(suite.BookmarkRepository.AppContext.DB)
+}
+
+func (suite *BookmarkRepoTestSuite) TestGet() {
+	bookmark, err := suite.BookmarkRepository.Get("", 1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 60000, bookmark.Position)
+
+	// Bookmarks are per user.
+	bookmark, err = suite.BookmarkRepository.Get("other", 1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 30000, bookmark.Position)
+
+	_, err = suite.BookmarkRepository.Get("other", 2)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *BookmarkRepoTestSuite) TestGetAll() {
+	bookmarks, err := suite.BookmarkRepository.GetAll("")
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), bookmarks, 3)
+	// The most recently modified first.
+	assert.Equal(suite.T(), 99, bookmarks[0].TrackId)
+	assert.Equal(suite.T(), 2, bookmarks[1].TrackId)
+	assert.Equal(suite.T(), 1, bookmarks[2].TrackId)
+
+	bookmarks, err = suite.BookmarkRepository.GetAll("nobody")
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), bookmarks)
+}
+
+func (suite *BookmarkRepoTestSuite) TestSave() {
+	bookmark := domain.Bookmark{User: "other", TrackId: 2, Position: 1000}
+	err := suite.BookmarkRepository.Save(&bookmark)
+	assert.Nil(suite.T(), err)
+	assert.NotZero(suite.T(), bookmark.Id)
+	assert.NotZero(suite.T(), bookmark.DateAdded)
+	assert.Equal(suite.T(), bookmark.DateAdded, bookmark.DateModified)
+
+	// Update.
+	bookmark.Position = 2000
+	err = suite.BookmarkRepository.Save(&bookmark)
+	assert.Nil(suite.T(), err)
+	saved, err := suite.BookmarkRepository.Get("other", 2)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), bookmark.Id, saved.Id)
+	assert.Equal(suite.T(), 2000, saved.Position)
+
+	// A user has a single bookmark per track.
+	duplicate := domain.Bookmark{User: "other", TrackId: 2, Position: 3000}
+	err = suite.BookmarkRepository.Save(&duplicate)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *BookmarkRepoTestSuite) TestDelete() {
+	bookmark, _ := suite.BookmarkRepository.Get("", 1)
+	err := suite.BookmarkRepository.Delete(&bookmark)
+	assert.Nil(suite.T(), err)
+	_, err = suite.BookmarkRepository.Get("", 1)
+	assert.NotNil(suite.T(), err)
+
+	// The bookmarks of other users are kept.
+	_, err = suite.BookmarkRepository.Get("other", 1)
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *BookmarkRepoTestSuite) TestCleanUp() {
+	err := suite.BookmarkRepository.CleanUp()
+	assert.Nil(suite.T(), err)
+	bookmarks, _ := suite.BookmarkRepository.GetAll("")
+	assert.Len(suite.T(), bookmarks, 2)
+	_, err = suite.BookmarkRepository.Get("", 99)
+	assert.NotNil(suite.T(), err)
+}
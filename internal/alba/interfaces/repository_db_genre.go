@@ -0,0 +1,138 @@
+package interfaces
+
+import (
+	"errors"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+type GenreDbRepository struct {
+	AppContext *AppContext
+}
+
+// Genre with its tracks and albums count, as returned by the database.
+type genreWithCounts struct {
+	domain.Genre
+	TracksCount int `db:"tracks_count"`
+	AlbumsCount int `db:"albums_count"`
+}
+
+const genresWithCountsQuery = "SELECT gen.*, COUNT(DISTINCT tg.track_id) tracks_count, COUNT(DISTINCT trk.album_id) albums_count " +
+	"FROM genres gen " +
+	"LEFT JOIN track_genres tg ON tg.genre_id = gen.id " +
+	"LEFT JOIN tracks trk ON trk.id = tg.track_id "
+
+/*
+Fetches a genre from the database, with its tracks and albums count.
+*/
+func (gr GenreDbRepository) Get(id int) (entity domain.Genre, err error) {
+	var results []genreWithCounts
+	_, err = gr.AppContext.DB.Select(&results, genresWithCountsQuery+"WHERE gen.id = ? GROUP BY gen.id", id)
+	if err == nil && len(results) > 0 {
+		entity = results[0].toGenre()
+	} else {
+		err = errors.New("no genre found")
+	}
+
+	return
+}
+
+/*
+Fetches all genres from the database ordered by name, with their tracks and albums count.
+*/
+func (gr GenreDbRepository) GetAll() (entities domain.Genres, err error) {
+	var results []genreWithCounts
+	_, err = gr.AppContext.DB.Select(&results, genresWithCountsQuery+"GROUP BY gen.id ORDER BY gen.name COLLATE NOCASE")
+	for _, result := range results {
+		entities = append(entities, result.toGenre())
+	}
+
+	return
+}
+
+/*
+Fetches a genre from database based on its name (case insensitive).
+*/
+func (gr GenreDbRepository) GetByName(name string) (entity domain.Genre, err error) {
+	var entities domain.Genres
+	_, err = gr.AppContext.DB.Select(&entities, "SELECT * FROM genres WHERE name = ? COLLATE NOCASE", name)
+
+	if err == nil {
+		if len(entities) > 0 {
+			entity = entities[0]
+		} else {
+			err = errors.New("no result found")
+		}
+	}
+
+	return
+}
+
+/*
+Fetches the genres of a track ordered by name.
+*/
+func (gr GenreDbRepository) GetGenresForTrack(trackId int) (entities domain.Genres, err error) {
+	_, err = gr.AppContext.DB.Select(
+		&entities,
+		"SELECT gen.* FROM genres gen, track_genres tg WHERE tg.genre_id = gen.id AND tg.track_id = ? ORDER BY gen.name COLLATE NOCASE",
+		trackId,
+	)
+
+	return
+}
+
+/*
+Create or update a genre in the Database.
+*/
+func (gr GenreDbRepository) Save(entity *domain.Genre) (err error) {
+	if entity.Id != 0 {
+		// Update.
+		_, err = gr.AppContext.DB.Update(entity)
+		return
+	} else {
+		// Insert new entity.
+		entity.DateAdded = time.Now().Unix()
+		err = gr.AppContext.DB.Insert(entity)
+		return
+	}
+}
+
+/*
+Delete a genre from the Database.
+
+Tracks are not deleted, they just lose this genre.
+*/
+func (gr GenreDbRepository) Delete(entity *domain.Genre) (err error) {
+	_, err = gr.AppContext.DB.Exec("DELETE FROM track_genres WHERE genre_id = ?", entity.Id)
+	if err == nil {
+		_, err = gr.AppContext.DB.Delete(entity)
+	}
+
+	return
+}
+
+// Check if a genre exists for a given id.
+func (gr GenreDbRepository) Exists(id int) bool {
+	object, err := gr.AppContext.DB.Get(domain.Genre{}, id)
+	return err == nil && object != nil
+}
+
+// Removes links to deleted tracks and genres without tracks from DB.
+func (gr GenreDbRepository) CleanUp() error {
+	_, err := gr.AppContext.DB.Exec("DELETE FROM track_genres WHERE NOT EXISTS (SELECT id FROM tracks WHERE tracks.id = track_genres.track_id)")
+	if err != nil {
+		return err
+	}
+
+	_, err = gr.AppContext.DB.Exec("DELETE FROM genres WHERE NOT EXISTS (SELECT genre_id FROM track_genres WHERE track_genres.genre_id = genres.id)")
+	return err
+}
+
+func (g genreWithCounts) toGenre() domain.Genre {
+	genre := g.Genre
+	genre.TracksCount = g.TracksCount
+	genre.AlbumsCount = g.AlbumsCount
+
+	return genre
+}
@@ -0,0 +1,155 @@
+package interfaces
+
+import (
+	"log"
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type GenreRepoTestSuite struct {
+	suite.Suite
+	GenreRepository GenreDbRepository
+}
+
+/**
+Go testing framework entry point.
+ */
+func TestGenreRepoTestSuite(t *testing.T) {
+	suite.Run(t, new(GenreRepoTestSuite))
+}
+
+func (suite *GenreRepoTestSuite) SetupSuite() {
+	ds, err := createTestDatasource()
+	if err != nil {
+		log.Fatal(err)
+	}
+	appContext := AppContext{DB: ds}
+	suite.GenreRepository = GenreDbRepository{AppContext: &appContext}
+}
+
+func (suite *GenreRepoTestSuite) TearDownSuite() {
+	if err := closeTestDataSource(suite.GenreRepository.AppContext.DB); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (suite *GenreRepoTestSuite) SetupTest() {
+	resetTestDataSource(suite.GenreRepository.AppContext.DB)
+}
+
+func (suite *GenreRepoTestSuite) TestGet() {
+	genre, err := suite.GenreRepository.Get(1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, genre.Id)
+	assert.Equal(suite.T(), "Progressive Metal", genre.Name)
+	assert.Equal(suite.T(), 4, genre.TracksCount)
+	assert.Equal(suite.T(), 1, genre.AlbumsCount)
+
+	// Test to get a genre without tracks.
+	genre, err = suite.GenreRepository.Get(2)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Rock", genre.Name)
+	assert.Equal(suite.T(), 0, genre.TracksCount)
+	assert.Equal(suite.T(), 0, genre.AlbumsCount)
+
+	// Test to get a non existing genre.
+	_, err = suite.GenreRepository.Get(99)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *GenreRepoTestSuite) TestGetAll() {
+	genres, err := suite.GenreRepository.GetAll()
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), genres, 3)
+
+	// Genres are ordered by name.
+	assert.Equal(suite.T(), "Metal", genres[0].Name)
+	assert.Equal(suite.T(), 1, genres[0].TracksCount)
+	assert.Equal(suite.T(), "Progressive Metal", genres[1].Name)
+	assert.Equal(suite.T(), "Rock", genres[2].Name)
+}
+
+func (suite *GenreRepoTestSuite) TestGetByName() {
+	genre, err := suite.GenreRepository.GetByName("Rock")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, genre.Id)
+
+	// Matching is case insensitive.
+	genre, err = suite.GenreRepository.GetByName("progressive metal")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, genre.Id)
+
+	_, err = suite.GenreRepository.GetByName("Bogus")
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *GenreRepoTestSuite) TestGetGenresForTrack() {
+	genres, err := suite.GenreRepository.GetGenresForTrack(1)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), genres, 2)
+	assert.Equal(suite.T(), "Metal", genres[0].Name)
+	assert.Equal(suite.T(), "Progressive Metal", genres[1].Name)
+
+	// Track without genres.
+	genres, err = suite.GenreRepository.GetGenresForTrack(9)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), genres)
+}
+
+func (suite *GenreRepoTestSuite) TestSave() {
+	newGenre := &domain.Genre{
+		Name: "Insert new genre test",
+	}
+
+	err := suite.GenreRepository.Save(newGenre)
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), newGenre.Id)
+	assert.NotEmpty(suite.T(), newGenre.DateAdded)
+
+	insertedGenre, errInsert := suite.GenreRepository.Get(newGenre.Id)
+	assert.Nil(suite.T(), errInsert)
+	assert.Equal(suite.T(), "Insert new genre test", insertedGenre.Name)
+
+	// Test to update the genre.
+	insertedGenre.Name = "Update genre test"
+	errUpdate := suite.GenreRepository.Save(&insertedGenre)
+	assert.Nil(suite.T(), errUpdate)
+
+	updatedGenre, errGetMod := suite.GenreRepository.Get(newGenre.Id)
+	assert.Nil(suite.T(), errGetMod)
+	assert.Equal(suite.T(), "Update genre test", updatedGenre.Name)
+}
+
+func (suite *GenreRepoTestSuite) TestDelete() {
+	genre, err := suite.GenreRepository.Get(3)
+	assert.Nil(suite.T(), err)
+
+	err = suite.GenreRepository.Delete(&genre)
+	assert.Nil(suite.T(), err)
+
+	_, err = suite.GenreRepository.Get(3)
+	assert.NotNil(suite.T(), err)
+
+	// The track lost the genre.
+	genres, err := suite.GenreRepository.GetGenresForTrack(1)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), genres, 1)
+}
+
+func (suite *GenreRepoTestSuite) TestExists() {
+	assert.True(suite.T(), suite.GenreRepository.Exists(1))
+	assert.False(suite.T(), suite.GenreRepository.Exists(543))
+}
+
+func (suite *GenreRepoTestSuite) TestCleanUp() {
+	err := suite.GenreRepository.CleanUp()
+	assert.Nil(suite.T(), err)
+
+	// Genres without tracks have been removed.
+	assert.False(suite.T(), suite.GenreRepository.Exists(2))
+	assert.True(suite.T(), suite.GenreRepository.Exists(1))
+	assert.True(suite.T(), suite.GenreRepository.Exists(3))
+}
@@ -17,6 +17,29 @@ func (lr LibraryDbRepository) Erase() {
 	lr.AppContext.DB.Exec("DELETE FROM sqlite_sequence WHERE name = 'albums'")
 	lr.AppContext.DB.Exec("DELETE FROM artists")
 	lr.AppContext.DB.Exec("DELETE FROM sqlite_sequence WHERE name = 'artists'")
+	lr.AppContext.DB.Exec("DELETE FROM track_artists")
+	lr.AppContext.DB.Exec("DELETE FROM track_genres")
+	lr.AppContext.DB.Exec("DELETE FROM genres")
+	lr.AppContext.DB.Exec("DELETE FROM sqlite_sequence WHERE name = 'genres'")
+	lr.AppContext.DB.Exec("DELETE FROM overrides")
+	lr.AppContext.DB.Exec("DELETE FROM sqlite_sequence WHERE name = 'overrides'")
+	lr.AppContext.DB.Exec("DELETE FROM artist_aliases")
+	lr.AppContext.DB.Exec("DELETE FROM sqlite_sequence WHERE name = 'artist_aliases'")
+	lr.AppContext.DB.Exec("DELETE FROM artist_alias_moves")
+	lr.AppContext.DB.Exec("DELETE FROM lyrics")
+	lr.AppContext.DB.Exec("DELETE FROM sqlite_sequence WHERE name = 'lyrics'")
+	lr.AppContext.DB.Exec("DELETE FROM share_tracks")
+	lr.AppContext.DB.Exec("DELETE FROM shares")
+	lr.AppContext.DB.Exec("DELETE FROM sqlite_sequence WHERE name = 'shares'")
+	lr.AppContext.DB.Exec("DELETE FROM play_queue_tracks")
+	lr.AppContext.DB.Exec("DELETE FROM play_queues")
+	lr.AppContext.DB.Exec("DELETE FROM sqlite_sequence WHERE name = 'play_queues'")
+	lr.AppContext.DB.Exec("DELETE FROM bookmarks")
+	lr.AppContext.DB.Exec("DELETE FROM sqlite_sequence WHERE name = 'bookmarks'")
+	lr.AppContext.DB.Exec("DELETE FROM scrobbles")
+	lr.AppContext.DB.Exec("DELETE FROM sqlite_sequence WHERE name = 'scrobbles'")
+	lr.AppContext.DB.Exec("DELETE FROM scrobble_accounts")
+	lr.AppContext.DB.Exec("DELETE FROM sqlite_sequence WHERE name = 'scrobble_accounts'")
 	lr.AppContext.DB.Exec("DELETE FROM variables")
 	lr.AppContext.DB.Exec("DELETE FROM sqlite_sequence WHERE name = 'variables'")
 }
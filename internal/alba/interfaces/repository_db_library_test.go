@@ -64,6 +64,22 @@ func (suite *LibraryRepoTestSuite) TestErase() {
 	assert.Nil(suite.T(), err)
 	assert.Empty(suite.T(), entitiesCovers)
 
+	// Shares would link to other entities once the ids are reused.
+	entitiesShares := domain.Shares{}
+	_, err = suite.LibraryRepository.AppContext.DB.Select(&entitiesShares, "SELECT * FROM shares")
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), entitiesShares)
+
+	entitiesScrobbles := domain.Scrobbles{}
+	_, err = suite.LibraryRepository.AppContext.DB.Select(&entitiesScrobbles, "SELECT * FROM scrobbles")
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), entitiesScrobbles)
+
+	entitiesBookmarks := domain.Bookmarks{}
+	_, err = suite.LibraryRepository.AppContext.DB.Select(&entitiesBookmarks, "SELECT * FROM bookmarks")
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), entitiesBookmarks)
+
 	// Test sequences.
 	type sequence struct {
 		name string
@@ -0,0 +1,29 @@
+package interfaces
+
+import (
+	"errors"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+type LyricsDbRepository struct {
+	AppContext *AppContext
+}
+
+/*
+Fetches the lyrics of a track from the database.
+*/
+func (lr LyricsDbRepository) GetForTrack(trackId int) (entity domain.Lyrics, err error) {
+	err = lr.AppContext.DB.SelectOne(&entity, "SELECT * FROM lyrics WHERE track_id = ?", trackId)
+	if err != nil {
+		err = errors.New("no lyrics found")
+	}
+
+	return
+}
+
+// Removes the lyrics of deleted tracks from DB.
+func (lr LyricsDbRepository) CleanUp() error {
+	_, err := lr.AppContext.DB.Exec("DELETE FROM lyrics WHERE NOT EXISTS (SELECT id FROM tracks WHERE tracks.id = lyrics.track_id)")
+	return err
+}
@@ -0,0 +1,62 @@
+package interfaces
+
+import (
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type LyricsRepoTestSuite struct {
+	suite.Suite
+	LyricsRepository LyricsDbRepository
+}
+
+/**
+Go testing framework entry point.
+ */
+func TestLyricsRepoTestSuite(t *testing.T) {
+	suite.Run(t, new(LyricsRepoTestSuite))
+}
+
+func (suite *LyricsRepoTestSuite) SetupSuite() {
+	ds, err := createTestDatasource()
+	if err != nil {
+		log.Fatal(err)
+	}
+	appContext := AppContext{DB: ds}
+	suite.LyricsRepository = LyricsDbRepository{AppContext: &appContext}
+}
+
+func (suite *LyricsRepoTestSuite) TearDownSuite() {
+	if err := closeTestDataSource(suite.LyricsRepository.AppContext.DB); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (suite *LyricsRepoTestSuite) SetupTest() {
+	resetTestDataSource(suite.LyricsRepository.AppContext.DB)
+}
+
+func (suite *LyricsRepoTestSuite) TestGetForTrack() {
+	lyrics, err := suite.LyricsRepository.GetForTrack(1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, lyrics.Id)
+	assert.Equal(suite.T(), "First line\nSecond line", lyrics.Text)
+	assert.Equal(suite.T(), "[00:12.00]First line\n[00:15.50]Second line", lyrics.Synced)
+
+	// Test to get the lyrics of a track without lyrics.
+	_, err = suite.LyricsRepository.GetForTrack(2)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *LyricsRepoTestSuite) TestCleanUp() {
+	err := suite.LyricsRepository.CleanUp()
+	assert.Nil(suite.T(), err)
+
+	_, err = suite.LyricsRepository.GetForTrack(1)
+	assert.Nil(suite.T(), err)
+	_, err = suite.LyricsRepository.GetForTrack(99)
+	assert.NotNil(suite.T(), err)
+}
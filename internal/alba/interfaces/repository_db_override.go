@@ -0,0 +1,101 @@
+package interfaces
+
+import (
+	"errors"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+type OverrideDbRepository struct {
+	AppContext *AppContext
+}
+
+/*
+Fetches an override from the database.
+*/
+func (or OverrideDbRepository) Get(id int) (entity domain.Override, err error) {
+	object, err := or.AppContext.DB.Get(domain.Override{}, id)
+	if err == nil && object != nil {
+		entity = *object.(*domain.Override)
+	} else {
+		err = errors.New("no override found")
+	}
+
+	return
+}
+
+/*
+Fetches all overrides from the database, or all the overrides of an entity type if entityType is not empty.
+*/
+func (or OverrideDbRepository) GetAll(entityType string) (entities domain.Overrides, err error) {
+	_, err = or.AppContext.DB.Select(
+		&entities,
+		"SELECT * FROM overrides WHERE entity_type = ? OR ? = '' ORDER BY entity_type, entity_id, field",
+		entityType,
+		entityType,
+	)
+
+	return
+}
+
+/*
+Fetches the overrides of an entity ordered by field.
+*/
+func (or OverrideDbRepository) GetForEntity(entityType string, entityId int) (entities domain.Overrides, err error) {
+	_, err = or.AppContext.DB.Select(
+		&entities,
+		"SELECT * FROM overrides WHERE entity_type = ? AND entity_id = ? ORDER BY field",
+		entityType,
+		entityId,
+	)
+
+	return
+}
+
+/*
+Create or update an override in the Database.
+*/
+func (or OverrideDbRepository) Save(entity *domain.Override) (err error) {
+	if entity.Id != 0 {
+		// Update.
+		_, err = or.AppContext.DB.Update(entity)
+		return
+	} else {
+		// Insert new entity.
+		if entity.DateAdded == 0 {
+			entity.DateAdded = time.Now().Unix()
+		}
+		err = or.AppContext.DB.Insert(entity)
+		return
+	}
+}
+
+/*
+Delete an override from the Database.
+*/
+func (or OverrideDbRepository) Delete(entity *domain.Override) (err error) {
+	_, err = or.AppContext.DB.Delete(entity)
+	return
+}
+
+// Removes the overrides of deleted artists, albums and tracks from DB.
+func (or OverrideDbRepository) CleanUp() error {
+	tables := map[string]string{
+		domain.OverrideEntityArtist: "artists",
+		domain.OverrideEntityAlbum:  "albums",
+		domain.OverrideEntityTrack:  "tracks",
+	}
+
+	for entityType, table := range tables {
+		_, err := or.AppContext.DB.Exec(
+			"DELETE FROM overrides WHERE entity_type = ? AND NOT EXISTS (SELECT id FROM "+table+" WHERE "+table+".id = overrides.entity_id)",
+			entityType,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
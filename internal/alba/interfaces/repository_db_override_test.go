@@ -0,0 +1,133 @@
+package interfaces
+
+import (
+	"log"
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type OverrideRepoTestSuite struct {
+	suite.Suite
+	OverrideRepository OverrideDbRepository
+}
+
+/**
+Go testing framework entry point.
+ */
+func TestOverrideRepoTestSuite(t *testing.T) {
+	suite.Run(t, new(OverrideRepoTestSuite))
+}
+
+func (suite *OverrideRepoTestSuite) SetupSuite() {
+	ds, err := createTestDatasource()
+	if err != nil {
+		log.Fatal(err)
+	}
+	appContext := AppContext{DB: ds}
+	suite.OverrideRepository = OverrideDbRepository{AppContext: &appContext}
+}
+
+func (suite *OverrideRepoTestSuite) TearDownSuite() {
+	if err := closeTestDataSource(suite.OverrideRepository.AppContext.DB); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (suite *OverrideRepoTestSuite) SetupTest() {
+	resetTestDataSource(suite.OverrideRepository.AppContext.DB)
+}
+
+func (suite *OverrideRepoTestSuite) TestGet() {
+	override, err := suite.OverrideRepository.Get(1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), domain.OverrideEntityArtist, override.EntityType)
+	assert.Equal(suite.T(), 3, override.EntityId)
+	assert.Equal(suite.T(), "sortName", override.Field)
+	assert.Equal(suite.T(), "Test, Artist", override.Value)
+	assert.Equal(suite.T(), "Artist Test", override.OriginalValue)
+
+	// Test to get a non existing override.
+	_, err = suite.OverrideRepository.Get(99)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *OverrideRepoTestSuite) TestGetAll() {
+	overrides, err := suite.OverrideRepository.GetAll("")
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), overrides, 4)
+
+	overrides, err = suite.OverrideRepository.GetAll(domain.OverrideEntityTrack)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), overrides, 3)
+
+	overrides, err = suite.OverrideRepository.GetAll(domain.OverrideEntityAlbum)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), overrides)
+}
+
+func (suite *OverrideRepoTestSuite) TestGetForEntity() {
+	overrides, err := suite.OverrideRepository.GetForEntity(domain.OverrideEntityTrack, 1)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), overrides, 2)
+	assert.Equal(suite.T(), "bpm", overrides[0].Field)
+	assert.Equal(suite.T(), "title", overrides[1].Field)
+
+	overrides, err = suite.OverrideRepository.GetForEntity(domain.OverrideEntityArtist, 1)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), overrides)
+}
+
+func (suite *OverrideRepoTestSuite) TestSave() {
+	// Test insert.
+	override := domain.Override{
+		EntityType: domain.OverrideEntityAlbum,
+		EntityId: 1,
+		Field: "year",
+		Value: "1997",
+		OriginalValue: "1996",
+	}
+	err := suite.OverrideRepository.Save(&override)
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), override.Id)
+	assert.NotEmpty(suite.T(), override.DateAdded)
+
+	// Test update.
+	override.Value = "1998"
+	err = suite.OverrideRepository.Save(&override)
+	assert.Nil(suite.T(), err)
+
+	saved, err := suite.OverrideRepository.Get(override.Id)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "1998", saved.Value)
+
+	// A field can only be overridden once.
+	duplicate := domain.Override{EntityType: domain.OverrideEntityAlbum, EntityId: 1, Field: "year", Value: "2000"}
+	err = suite.OverrideRepository.Save(&duplicate)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *OverrideRepoTestSuite) TestDelete() {
+	override, err := suite.OverrideRepository.Get(1)
+	assert.Nil(suite.T(), err)
+
+	err = suite.OverrideRepository.Delete(&override)
+	assert.Nil(suite.T(), err)
+
+	_, err = suite.OverrideRepository.Get(1)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *OverrideRepoTestSuite) TestCleanUp() {
+	err := suite.OverrideRepository.CleanUp()
+	assert.Nil(suite.T(), err)
+
+	overrides, err := suite.OverrideRepository.GetAll("")
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), overrides, 3)
+
+	_, err = suite.OverrideRepository.Get(4)
+	assert.NotNil(suite.T(), err)
+}
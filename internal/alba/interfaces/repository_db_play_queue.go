@@ -0,0 +1,72 @@
+package interfaces
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-gorp/gorp"
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+type PlayQueueDbRepository struct {
+	AppContext *AppContext
+}
+
+/*
+Fetches the play queue of a user from the database, with its tracks.
+*/
+func (pr PlayQueueDbRepository) Get(user string) (entity domain.PlayQueue, err error) {
+	err = pr.AppContext.DB.SelectOne(&entity, "SELECT * FROM play_queues WHERE user_name = ?", user)
+	if err != nil {
+		return entity, errors.New("no play queue found")
+	}
+
+	var trackIds []int64
+	_, err = pr.AppContext.DB.Select(&trackIds, "SELECT track_id FROM play_queue_tracks WHERE queue_id = ? ORDER BY number", entity.Id)
+	entity.TrackIds = make([]int, len(trackIds))
+	for i, trackId := range trackIds {
+		entity.TrackIds[i] = int(trackId)
+	}
+
+	return
+}
+
+/*
+Create or replace the play queue of a user in the database, with its tracks.
+*/
+func (pr PlayQueueDbRepository) Save(entity *domain.PlayQueue) (err error) {
+	tx, err := pr.begin()
+	if err != nil {
+		return
+	}
+
+	entity.DateModified = time.Now().Unix()
+	if entity.Id != 0 {
+		_, err = tx.Update(entity)
+	} else {
+		err = tx.Insert(entity)
+	}
+	if err == nil {
+		_, err = tx.Exec("DELETE FROM play_queue_tracks WHERE queue_id = ?", entity.Id)
+	}
+	for i := 0; err == nil && i < len(entity.TrackIds); i++ {
+		_, err = tx.Exec("INSERT INTO play_queue_tracks (queue_id, number, track_id) VALUES (?, ?, ?)", entity.Id, i, entity.TrackIds[i])
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// Starts a transaction on the datasource.
+func (pr PlayQueueDbRepository) begin() (*gorp.Transaction, error) {
+	gorpDbMap, ok := pr.AppContext.DB.(*gorp.DbMap)
+	if !ok {
+		return nil, errors.New("cannot get underlying gorp dbmap")
+	}
+
+	return gorpDbMap.Begin()
+}
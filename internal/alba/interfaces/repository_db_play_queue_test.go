@@ -0,0 +1,84 @@
+package interfaces
+
+import (
+	"log"
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PlayQueueRepoTestSuite struct {
+	suite.Suite
+	PlayQueueRepository PlayQueueDbRepository
+}
+
+/**
+Go testing framework entry point.
+ */
+func TestPlayQueueRepoTestSuite(t *testing.T) {
+	suite.Run(t, new(PlayQueueRepoTestSuite))
+}
+
+func (suite *PlayQueueRepoTestSuite) SetupSuite() {
+	ds, err := createTestDatasource()
+	if err != nil {
+		log.Fatal(err)
+	}
+	appContext := AppContext{DB: ds}
+	suite.PlayQueueRepository = PlayQueueDbRepository{AppContext: &appContext}
+}
+
+func (suite *PlayQueueRepoTestSuite) TearDownSuite() {
+	if err := closeTestDataSource(suite.PlayQueueRepository.AppContext.DB); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (suite *PlayQueueRepoTestSuite) SetupTest() {
+	resetTestDataSource(suite.PlayQueueRepository.AppContext.DB)
+}
+
+func (suite *PlayQueueRepoTestSuite) TestSaveAndGet() {
+	_, err := suite.PlayQueueRepository.Get("")
+	assert.NotNil(suite.T(), err)
+
+	queue := domain.PlayQueue{User: "", CurrentIndex: 1, Position: 5000, ClientId: "desktop", TrackIds: []int{3, 1, 2}}
+	err = suite.PlayQueueRepository.Save(&queue)
+	assert.Nil(suite.T(), err)
+	assert.NotZero(suite.T(), queue.Id)
+	assert.NotZero(suite.T(), queue.DateModified)
+
+	saved, err := suite.PlayQueueRepository.Get("")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), queue.Id, saved.Id)
+	assert.Equal(suite.T(), []int{3, 1, 2}, saved.TrackIds)
+	assert.Equal(suite.T(), 1, saved.CurrentIndex)
+	assert.Equal(suite.T(), 5000, saved.Position)
+	assert.Equal(suite.T(), "desktop", saved.ClientId)
+
+	// The tracks are replaced.
+	saved.TrackIds = []int{4, 5}
+	saved.CurrentIndex = 0
+	err = suite.PlayQueueRepository.Save(&saved)
+	assert.Nil(suite.T(), err)
+	saved, err = suite.PlayQueueRepository.Get("")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []int{4, 5}, saved.TrackIds)
+
+	saved.TrackIds = []int{}
+	err = suite.PlayQueueRepository.Save(&saved)
+	assert.Nil(suite.T(), err)
+	saved, err = suite.PlayQueueRepository.Get("")
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), saved.TrackIds)
+
+	// Queues are saved per user.
+	other := domain.PlayQueue{User: "other", TrackIds: []int{1}}
+	err = suite.PlayQueueRepository.Save(&other)
+	assert.Nil(suite.T(), err)
+	assert.NotEqual(suite.T(), queue.Id, other.Id)
+	other, _ = suite.PlayQueueRepository.Get("other")
+	assert.Equal(suite.T(), []int{1}, other.TrackIds)
+}
@@ -0,0 +1,126 @@
+package interfaces
+
+import (
+	"errors"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+type ScrobbleAccountDbRepository struct {
+	AppContext *AppContext
+}
+
+/*
+Fetches a scrobbling account from the database.
+*/
+func (sr ScrobbleAccountDbRepository) Get(id int) (entity domain.ScrobbleAccount, err error) {
+	object, err := sr.AppContext.DB.Get(domain.ScrobbleAccount{}, id)
+	if err == nil && object != nil {
+		entity = *object.(*domain.ScrobbleAccount)
+	} else {
+		err = errors.New("no scrobble account found")
+	}
+
+	return
+}
+
+/*
+Fetches the account of a user of a scrobbling service.
+*/
+func (sr ScrobbleAccountDbRepository) GetByName(service string, userName string) (entity domain.ScrobbleAccount, err error) {
+	err = sr.AppContext.DB.SelectOne(
+		&entity,
+		"SELECT * FROM scrobble_accounts WHERE service = ? AND user_name = ?",
+		service,
+		userName,
+	)
+	if err != nil {
+		err = errors.New("no scrobble account found")
+	}
+
+	return
+}
+
+/*
+Fetches all scrobbling accounts from the database.
+*/
+func (sr ScrobbleAccountDbRepository) GetAll() (entities domain.ScrobbleAccounts, err error) {
+	_, err = sr.AppContext.DB.Select(&entities, "SELECT * FROM scrobble_accounts ORDER BY service, user_name")
+
+	return
+}
+
+/*
+Create or update a scrobbling account in the Database.
+*/
+func (sr ScrobbleAccountDbRepository) Save(entity *domain.ScrobbleAccount) (err error) {
+	if entity.Id != 0 {
+		// Update.
+		_, err = sr.AppContext.DB.Update(entity)
+		return
+	} else {
+		// Insert new entity.
+		if entity.DateAdded == 0 {
+			entity.DateAdded = time.Now().Unix()
+		}
+		err = sr.AppContext.DB.Insert(entity)
+		return
+	}
+}
+
+/*
+Delete a scrobbling account from the Database.
+*/
+func (sr ScrobbleAccountDbRepository) Delete(entity *domain.ScrobbleAccount) (err error) {
+	_, err = sr.AppContext.DB.Delete(entity)
+	return
+}
+
+type ScrobbleDbRepository struct {
+	AppContext *AppContext
+}
+
+/*
+Fetches all scrobbles waiting to be sent, the oldest first.
+*/
+func (sr ScrobbleDbRepository) GetAll() (entities domain.Scrobbles, err error) {
+	_, err = sr.AppContext.DB.Select(&entities, "SELECT * FROM scrobbles ORDER BY listened_at, id")
+
+	return
+}
+
+/*
+Create or update a scrobble in the Database.
+*/
+func (sr ScrobbleDbRepository) Save(entity *domain.Scrobble) (err error) {
+	if entity.Id != 0 {
+		// Update.
+		_, err = sr.AppContext.DB.Update(entity)
+		return
+	} else {
+		// Insert new entity.
+		if entity.DateAdded == 0 {
+			entity.DateAdded = time.Now().Unix()
+		}
+		err = sr.AppContext.DB.Insert(entity)
+		return
+	}
+}
+
+/*
+Delete a scrobble from the Database.
+*/
+func (sr ScrobbleDbRepository) Delete(entity *domain.Scrobble) (err error) {
+	_, err = sr.AppContext.DB.Delete(entity)
+	return
+}
+
+// Removes the scrobbles of unlinked accounts from DB.
+func (sr ScrobbleDbRepository) CleanUp() error {
+	_, err := sr.AppContext.DB.Exec(
+		"DELETE FROM scrobbles WHERE NOT EXISTS (SELECT id FROM scrobble_accounts WHERE scrobble_accounts.id = scrobbles.account_id)",
+	)
+
+	return err
+}
@@ -0,0 +1,138 @@
+package interfaces
+
+import (
+	"log"
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ScrobbleRepoTestSuite struct {
+	suite.Suite
+	ScrobbleAccountRepository ScrobbleAccountDbRepository
+	ScrobbleRepository        ScrobbleDbRepository
+}
+
+/**
+Go testing framework entry point.
+ */
+func TestScrobbleRepoTestSuite(t *testing.T) {
+	suite.Run(t, new(ScrobbleRepoTestSuite))
+}
+
+func (suite *ScrobbleRepoTestSuite) SetupSuite() {
+	ds, err := createTestDatasource()
+	if err != nil {
+		log.Fatal(err)
+	}
+	appContext := AppContext{DB: ds}
+	suite.ScrobbleAccountRepository = ScrobbleAccountDbRepository{AppContext: &appContext}
+	suite.ScrobbleRepository = ScrobbleDbRepository{AppContext: &appContext}
+}
+
+func (suite *ScrobbleRepoTestSuite) TearDownSuite() {
+	if err := closeTestDataSource(suite.ScrobbleRepository.AppContext.DB); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (suite *ScrobbleRepoTestSuite) SetupTest() {
+	resetTestDataSource(suite.ScrobbleRepository.AppContext.DB)
+}
+
+func (suite *ScrobbleRepoTestSuite) TestGetAccount() {
+	account, err := suite.ScrobbleAccountRepository.Get(1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), domain.ScrobbleServiceLastFm, account.Service)
+	assert.Equal(suite.T(), "Listener", account.UserName)
+	assert.Equal(suite.T(), "session key", account.Token)
+	assert.Equal(suite.T(), int64(100), account.DateAdded)
+
+	// Test to get a non existing account.
+	_, err = suite.ScrobbleAccountRepository.Get(99)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *ScrobbleRepoTestSuite) TestGetAccountByName() {
+	account, err := suite.ScrobbleAccountRepository.GetByName(domain.ScrobbleServiceListenBrainz, "Listener")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, account.Id)
+
+	_, err = suite.ScrobbleAccountRepository.GetByName(domain.ScrobbleServiceListenBrainz, "Unknown")
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *ScrobbleRepoTestSuite) TestGetAllAccounts() {
+	accounts, err := suite.ScrobbleAccountRepository.GetAll()
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), accounts, 2)
+	assert.Equal(suite.T(), domain.ScrobbleServiceLastFm, accounts[0].Service)
+	assert.Equal(suite.T(), domain.ScrobbleServiceListenBrainz, accounts[1].Service)
+}
+
+func (suite *ScrobbleRepoTestSuite) TestSaveAccount() {
+	account := domain.ScrobbleAccount{Service: domain.ScrobbleServiceListenBrainz, UserName: "Other", Token: "token"}
+	err := suite.ScrobbleAccountRepository.Save(&account)
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), account.Id)
+	assert.NotEmpty(suite.T(), account.DateAdded)
+
+	account.Token = "new token"
+	err = suite.ScrobbleAccountRepository.Save(&account)
+	assert.Nil(suite.T(), err)
+	saved, err := suite.ScrobbleAccountRepository.Get(account.Id)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "new token", saved.Token)
+}
+
+func (suite *ScrobbleRepoTestSuite) TestDeleteAccount() {
+	account, _ := suite.ScrobbleAccountRepository.Get(2)
+	err := suite.ScrobbleAccountRepository.Delete(&account)
+	assert.Nil(suite.T(), err)
+	_, err = suite.ScrobbleAccountRepository.Get(2)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *ScrobbleRepoTestSuite) TestGetAll() {
+	scrobbles, err := suite.ScrobbleRepository.GetAll()
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), scrobbles, 3)
+	assert.Equal(suite.T(), "First", scrobbles[0].Title)
+	assert.Equal(suite.T(), 1, scrobbles[0].Attempts)
+	assert.Equal(suite.T(), "timeout", scrobbles[0].LastError)
+	assert.Equal(suite.T(), "Second", scrobbles[1].Title)
+	assert.Equal(suite.T(), "Unlinked", scrobbles[2].Title)
+}
+
+func (suite *ScrobbleRepoTestSuite) TestSaveAndDelete() {
+	scrobble := domain.Scrobble{AccountId: 2, Artist: "Artist", Title: "New", Duration: 100, ListenedAt: 4000}
+	err := suite.ScrobbleRepository.Save(&scrobble)
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), scrobble.Id)
+	assert.NotEmpty(suite.T(), scrobble.DateAdded)
+
+	scrobble.Attempts = 2
+	err = suite.ScrobbleRepository.Save(&scrobble)
+	assert.Nil(suite.T(), err)
+	scrobbles, _ := suite.ScrobbleRepository.GetAll()
+	assert.Len(suite.T(), scrobbles, 4)
+	assert.Equal(suite.T(), 2, scrobbles[3].Attempts)
+
+	err = suite.ScrobbleRepository.Delete(&scrobble)
+	assert.Nil(suite.T(), err)
+	scrobbles, _ = suite.ScrobbleRepository.GetAll()
+	assert.Len(suite.T(), scrobbles, 3)
+}
+
+func (suite *ScrobbleRepoTestSuite) TestCleanUp() {
+	err := suite.ScrobbleRepository.CleanUp()
+	assert.Nil(suite.T(), err)
+
+	scrobbles, _ := suite.ScrobbleRepository.GetAll()
+	assert.Len(suite.T(), scrobbles, 2)
+	for _, scrobble := range scrobbles {
+		assert.Equal(suite.T(), 1, scrobble.AccountId)
+	}
+}
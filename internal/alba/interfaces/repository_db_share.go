@@ -0,0 +1,172 @@
+package interfaces
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-gorp/gorp"
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+type ShareDbRepository struct {
+	AppContext *AppContext
+}
+
+/*
+Fetches a share from the database, with the tracks of a playlist.
+*/
+func (sr ShareDbRepository) Get(id int) (entity domain.Share, err error) {
+	object, err := sr.AppContext.DB.Get(domain.Share{}, id)
+	if err == nil && object != nil {
+		entity = *object.(*domain.Share)
+		err = sr.getTracks(&entity)
+	} else {
+		err = errors.New("no share found")
+	}
+
+	return
+}
+
+/*
+Fetches a share from its slug, with the tracks of a playlist.
+*/
+func (sr ShareDbRepository) GetBySlug(slug string) (entity domain.Share, err error) {
+	err = sr.AppContext.DB.SelectOne(&entity, "SELECT * FROM shares WHERE slug = ?", slug)
+	if err != nil {
+		return entity, errors.New("no share found")
+	}
+	err = sr.getTracks(&entity)
+
+	return
+}
+
+/*
+Fetches all shares from the database, the most recent first, with the tracks of the playlists.
+*/
+func (sr ShareDbRepository) GetAll() (entities domain.Shares, err error) {
+	_, err = sr.AppContext.DB.Select(&entities, "SELECT * FROM shares ORDER BY created_at DESC, id DESC")
+	for i := 0; err == nil && i < len(entities); i++ {
+		err = sr.getTracks(&entities[i])
+	}
+
+	return
+}
+
+// Fetches the tracks of a shared playlist.
+func (sr ShareDbRepository) getTracks(entity *domain.Share) error {
+	if entity.EntityType != domain.ShareEntityPlaylist {
+		return nil
+	}
+
+	var trackIds []int64
+	_, err := sr.AppContext.DB.Select(&trackIds, "SELECT track_id FROM share_tracks WHERE share_id = ? ORDER BY number", entity.Id)
+	entity.TrackIds = make([]int, len(trackIds))
+	for i, trackId := range trackIds {
+		entity.TrackIds[i] = int(trackId)
+	}
+
+	return err
+}
+
+/*
+Create or update a share in the Database, with the tracks of a playlist.
+*/
+func (sr ShareDbRepository) Save(entity *domain.Share) (err error) {
+	tx, err := sr.begin()
+	if err != nil {
+		return
+	}
+
+	if entity.Id != 0 {
+		// Update.
+		_, err = tx.Update(entity)
+	} else {
+		// Insert new entity.
+		if entity.DateAdded == 0 {
+			entity.DateAdded = time.Now().Unix()
+		}
+		err = tx.Insert(entity)
+	}
+	if err == nil {
+		_, err = tx.Exec("DELETE FROM share_tracks WHERE share_id = ?", entity.Id)
+	}
+	for i := 0; err == nil && i < len(entity.TrackIds); i++ {
+		_, err = tx.Exec("INSERT INTO share_tracks (share_id, number, track_id) VALUES (?, ?, ?)", entity.Id, i, entity.TrackIds[i])
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+/*
+Delete a share from the Database.
+*/
+func (sr ShareDbRepository) Delete(entity *domain.Share) (err error) {
+	_, err = sr.AppContext.DB.Delete(entity)
+	if err == nil {
+		_, err = sr.AppContext.DB.Exec("DELETE FROM share_tracks WHERE share_id = ?", entity.Id)
+	}
+
+	return
+}
+
+// Counts a play of a share in a single statement, so concurrent plays can't go over the maximum.
+func (sr ShareDbRepository) AddPlay(id int) (bool, error) {
+	result, err := sr.AppContext.DB.Exec(
+		"UPDATE shares SET plays = plays + 1 WHERE id = ? AND (max_plays = 0 OR plays < max_plays)",
+		id,
+	)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+
+	return count > 0, err
+}
+
+/*
+Removes the shares of deleted albums and tracks from DB.
+
+Deleted tracks are removed from the shared playlists, and the playlists without tracks left are removed.
+*/
+func (sr ShareDbRepository) CleanUp() error {
+	tables := map[string]string{
+		domain.ShareEntityAlbum: "albums",
+		domain.ShareEntityTrack: "tracks",
+	}
+
+	for entityType, table := range tables {
+		_, err := sr.AppContext.DB.Exec(
+			"DELETE FROM shares WHERE entity_type = ? AND NOT EXISTS (SELECT id FROM "+table+" WHERE "+table+".id = shares.entity_id)",
+			entityType,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := sr.AppContext.DB.Exec("DELETE FROM share_tracks WHERE NOT EXISTS (SELECT id FROM tracks WHERE tracks.id = share_tracks.track_id)")
+	if err != nil {
+		return err
+	}
+	_, err = sr.AppContext.DB.Exec(
+		"DELETE FROM shares WHERE entity_type = ? AND NOT EXISTS (SELECT share_id FROM share_tracks WHERE share_tracks.share_id = shares.id)",
+		domain.ShareEntityPlaylist,
+	)
+
+	return err
+}
+
+// Starts a transaction on the datasource.
+func (sr ShareDbRepository) begin() (*gorp.Transaction, error) {
+	gorpDbMap, ok := sr.AppContext.DB.(*gorp.DbMap)
+	if !ok {
+		return nil, errors.New("cannot get underlying gorp dbmap")
+	}
+
+	return gorpDbMap.Begin()
+}
@@ -0,0 +1,166 @@
+package interfaces
+
+import (
+	"log"
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ShareRepoTestSuite struct {
+	suite.Suite
+	ShareRepository ShareDbRepository
+}
+
+/**
+Go testing framework entry point.
+ */
+func TestShareRepoTestSuite(t *testing.T) {
+	suite.Run(t, new(ShareRepoTestSuite))
+}
+
+func (suite *ShareRepoTestSuite) SetupSuite() {
+	ds, err := createTestDatasource()
+	if err != nil {
+		log.Fatal(err)
+	}
+	appContext := AppContext{DB: ds}
+	suite.ShareRepository = ShareDbRepository{AppContext: &appContext}
+}
+
+func (suite *ShareRepoTestSuite) TearDownSuite() {
+	if err := closeTestDataSource(suite.ShareRepository.AppContext.DB); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (suite *ShareRepoTestSuite) SetupTest() {
+	resetTestDataSource(suite.ShareRepository.AppContext.DB)
+}
+
+func (suite *ShareRepoTestSuite) TestGet() {
+	share, err := suite.ShareRepository.Get(1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "album-slug", share.Slug)
+	assert.Equal(suite.T(), domain.ShareEntityAlbum, share.EntityType)
+	assert.Equal(suite.T(), 1, share.EntityId)
+	assert.True(suite.T(), share.AllowDownload)
+	assert.Equal(suite.T(), int64(100), share.DateAdded)
+
+	// Test to get a non existing share.
+	_, err = suite.ShareRepository.Get(99)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *ShareRepoTestSuite) TestGetBySlug() {
+	share, err := suite.ShareRepository.GetBySlug("track-slug")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, share.Id)
+	assert.Equal(suite.T(), 2, share.MaxPlays)
+	assert.Equal(suite.T(), 1, share.Plays)
+	assert.False(suite.T(), share.AllowDownload)
+
+	_, err = suite.ShareRepository.GetBySlug("unknown")
+	assert.NotNil(suite.T(), err)
+
+	// Playlists are fetched with their tracks, in order.
+	share, err = suite.ShareRepository.GetBySlug("playlist-slug")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), domain.ShareEntityPlaylist, share.EntityType)
+	assert.Equal(suite.T(), "Mix", share.Name)
+	assert.Equal(suite.T(), []int{3, 99, 1}, share.TrackIds)
+}
+
+func (suite *ShareRepoTestSuite) TestGetAll() {
+	shares, err := suite.ShareRepository.GetAll()
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), shares, 4)
+	// Most recent first.
+	assert.Equal(suite.T(), "track-slug", shares[0].Slug)
+	assert.Equal(suite.T(), "deleted-slug", shares[1].Slug)
+	assert.Len(suite.T(), shares[3].TrackIds, 3)
+}
+
+func (suite *ShareRepoTestSuite) TestSave() {
+	share := &domain.Share{Slug: "new-slug", EntityType: domain.ShareEntityTrack, EntityId: 3, ExpiresAt: 1000}
+	err := suite.ShareRepository.Save(share)
+	assert.Nil(suite.T(), err)
+	assert.NotZero(suite.T(), share.Id)
+	assert.NotZero(suite.T(), share.DateAdded)
+
+	share.AllowDownload = true
+	err = suite.ShareRepository.Save(share)
+	assert.Nil(suite.T(), err)
+	saved, err := suite.ShareRepository.Get(share.Id)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), saved.AllowDownload)
+	assert.Equal(suite.T(), int64(1000), saved.ExpiresAt)
+
+	// Slugs are unique.
+	err = suite.ShareRepository.Save(&domain.Share{Slug: "new-slug", EntityType: domain.ShareEntityTrack, EntityId: 1})
+	assert.NotNil(suite.T(), err)
+
+	// The tracks of the playlists are replaced.
+	playlist, _ := suite.ShareRepository.Get(4)
+	playlist.TrackIds = []int{2, 1}
+	err = suite.ShareRepository.Save(&playlist)
+	assert.Nil(suite.T(), err)
+	saved, _ = suite.ShareRepository.Get(4)
+	assert.Equal(suite.T(), []int{2, 1}, saved.TrackIds)
+}
+
+func (suite *ShareRepoTestSuite) TestDelete() {
+	share, _ := suite.ShareRepository.Get(1)
+	err := suite.ShareRepository.Delete(&share)
+	assert.Nil(suite.T(), err)
+	_, err = suite.ShareRepository.Get(1)
+	assert.NotNil(suite.T(), err)
+
+	// With the tracks of the playlists.
+	share, _ = suite.ShareRepository.Get(4)
+	err = suite.ShareRepository.Delete(&share)
+	assert.Nil(suite.T(), err)
+	var trackIds []int64
+	_, err = suite.ShareRepository.AppContext.DB.Select(&trackIds, "SELECT track_id FROM share_tracks WHERE share_id = 4")
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), trackIds)
+}
+
+func (suite *ShareRepoTestSuite) TestAddPlay() {
+	// The track share allows one more play.
+	counted, err := suite.ShareRepository.AddPlay(2)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), counted)
+	counted, err = suite.ShareRepository.AddPlay(2)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), counted)
+	share, _ := suite.ShareRepository.Get(2)
+	assert.Equal(suite.T(), 2, share.Plays)
+
+	// Unlimited plays.
+	for i := 0; i < 3; i++ {
+		counted, _ = suite.ShareRepository.AddPlay(1)
+		assert.True(suite.T(), counted)
+	}
+}
+
+func (suite *ShareRepoTestSuite) TestCleanUp() {
+	err := suite.ShareRepository.CleanUp()
+	assert.Nil(suite.T(), err)
+	shares, _ := suite.ShareRepository.GetAll()
+	assert.Len(suite.T(), shares, 3)
+	_, err = suite.ShareRepository.GetBySlug("deleted-slug")
+	assert.NotNil(suite.T(), err)
+
+	// Deleted tracks are removed from the playlists, and the playlists without tracks left are removed.
+	playlist, _ := suite.ShareRepository.GetBySlug("playlist-slug")
+	assert.Equal(suite.T(), []int{3, 1}, playlist.TrackIds)
+	empty := &domain.Share{Slug: "empty-slug", EntityType: domain.ShareEntityPlaylist, TrackIds: []int{98, 99}}
+	_ = suite.ShareRepository.Save(empty)
+	err = suite.ShareRepository.CleanUp()
+	assert.Nil(suite.T(), err)
+	_, err = suite.ShareRepository.GetBySlug("empty-slug")
+	assert.NotNil(suite.T(), err)
+}
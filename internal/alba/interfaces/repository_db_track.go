@@ -2,8 +2,11 @@ package interfaces
 
 import (
 	"errors"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
 	"github.com/humbkr/albaplayer-server/internal/alba/domain"
 )
 
@@ -63,6 +66,102 @@ func (tr TrackDbRepository) GetTracksForAlbum(albumId int) (entities domain.Trac
 	return
 }
 
+/**
+Fetches tracks having the specified genre from database.
+*/
+func (tr TrackDbRepository) GetTracksForGenre(genreId int) (entities domain.Tracks, err error) {
+	_, err = tr.AppContext.DB.Select(
+		&entities,
+		"SELECT trk.* FROM tracks trk, track_genres tg WHERE tg.track_id = trk.id AND tg.genre_id = ?",
+		genreId,
+	)
+
+	return
+}
+
+/**
+Fetches tracks matching a filter from database.
+*/
+func (tr TrackDbRepository) FindTracks(filter business.TrackFilter) (entities domain.Tracks, err error) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+	if filter.GenreId != 0 {
+		conditions = append(conditions, "id IN (SELECT track_id FROM track_genres WHERE genre_id = ?)")
+		args = append(args, filter.GenreId)
+	}
+	values := map[string]string{
+		"musicbrainz_track_id":  filter.MusicBrainzTrackId,
+		"musicbrainz_album_id":  filter.MusicBrainzAlbumId,
+		"musicbrainz_artist_id": filter.MusicBrainzArtistId,
+		"isrc":                  filter.Isrc,
+		"label":                 filter.Label,
+		"catalog_number":        filter.CatalogNumber,
+		"original_year":         filter.OriginalYear,
+		"composer":              filter.Composer,
+		"conductor":             filter.Conductor,
+	}
+	// Sorted so the query is always the same.
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		if values[column] != "" {
+			conditions = append(conditions, column+" = ? COLLATE NOCASE")
+			args = append(args, values[column])
+		}
+	}
+	if filter.BpmMin != 0 {
+		conditions = append(conditions, "bpm >= ?")
+		args = append(args, filter.BpmMin)
+	}
+	if filter.BpmMax != 0 {
+		// 0 means unknown.
+		conditions = append(conditions, "bpm > 0 AND bpm <= ?")
+		args = append(args, filter.BpmMax)
+	}
+
+	_, err = tr.AppContext.DB.Select(
+		&entities,
+		"SELECT * FROM tracks WHERE "+strings.Join(conditions, " AND ")+" ORDER BY album_id, disc, number",
+		args...,
+	)
+
+	return
+}
+
+/**
+Fetches tracks crediting the specified artist with the given role from database.
+
+If role is empty, fetches the tracks crediting the artist with any role.
+*/
+func (tr TrackDbRepository) GetTracksForArtist(artistId int, role string) (entities domain.Tracks, err error) {
+	_, err = tr.AppContext.DB.Select(
+		&entities,
+		"SELECT * FROM tracks WHERE id IN (SELECT track_id FROM track_artists WHERE artist_id = ? AND (role = ? OR ? = '')) ORDER BY album_id, disc, number",
+		artistId,
+		role,
+		role,
+	)
+
+	return
+}
+
+/**
+Fetches the artists credits of a track from database, main artists first.
+*/
+func (tr TrackDbRepository) GetTrackArtists(trackId int) (entities domain.TrackArtists, err error) {
+	_, err = tr.AppContext.DB.Select(
+		&entities,
+		"SELECT * FROM track_artists WHERE track_id = ? ORDER BY CASE role WHEN ? THEN 0 ELSE 1 END, position",
+		trackId,
+		domain.ArtistRoleMain,
+	)
+
+	return
+}
+
 /**
 Create or update a track in the Database.
 */
@@ -79,6 +178,25 @@ func (tr TrackDbRepository) Save(entity *domain.Track) (err error) {
 	}
 }
 
+/*
+Update the loudness analysis results of a track in the Database.
+
+The other columns are left as they are, as they may have changed since the analysis read the track.
+*/
+func (tr TrackDbRepository) SaveLoudness(entity *domain.Track) (err error) {
+	_, err = tr.AppContext.DB.Exec(
+		"UPDATE tracks SET loudness_track_lufs = ?, loudness_track_peak = ?, loudness_album_lufs = ?, loudness_album_peak = ?, loudness_analyzed_at = ? WHERE id = ?",
+		entity.LoudnessTrackLufs,
+		entity.LoudnessTrackPeak,
+		entity.LoudnessAlbumLufs,
+		entity.LoudnessAlbumPeak,
+		entity.LoudnessAnalyzedAt,
+		entity.Id,
+	)
+
+	return
+}
+
 // Check if a track exists for a given id.
 func (tr TrackDbRepository) Exists(id int) bool {
 	_, err := tr.Get(id)
@@ -89,6 +207,15 @@ func (tr TrackDbRepository) Exists(id int) bool {
 Delete a track from the Database.
 */
 func (tr TrackDbRepository) Delete(entity *domain.Track) (err error) {
-	_, err = tr.AppContext.DB.Delete(entity)
+	_, err = tr.AppContext.DB.Exec("DELETE FROM track_genres WHERE track_id = ?", entity.Id)
+	if err == nil {
+		_, err = tr.AppContext.DB.Exec("DELETE FROM track_artists WHERE track_id = ?", entity.Id)
+	}
+	if err == nil {
+		_, err = tr.AppContext.DB.Exec("DELETE FROM lyrics WHERE track_id = ?", entity.Id)
+	}
+	if err == nil {
+		_, err = tr.AppContext.DB.Delete(entity)
+	}
 	return
 }
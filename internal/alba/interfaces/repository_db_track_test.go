@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"log"
 	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
 )
 
 type TrackRepoTestSuite struct {
@@ -113,6 +114,86 @@ func (suite *TrackRepoTestSuite) TestGetTracksForAlbum() {
 	}
 }
 
+func (suite *TrackRepoTestSuite) TestGetTracksForGenre() {
+	tracks, err := suite.TrackRepository.GetTracksForGenre(1)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), tracks, 4)
+
+	tracks, err = suite.TrackRepository.GetTracksForGenre(2)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), tracks)
+}
+
+func (suite *TrackRepoTestSuite) TestFindTracks() {
+	genreTracks, _ := suite.TrackRepository.GetTracksForGenre(1)
+	db := suite.TrackRepository.AppContext.DB
+	_, _ = db.Exec("UPDATE tracks SET label = 'Warp', composer = 'Richard D. James', bpm = 120, isrc = 'GBAAA0000001' WHERE id = ?", genreTracks[0].Id)
+	_, _ = db.Exec("UPDATE tracks SET label = 'Warp', bpm = 140 WHERE id = ?", genreTracks[1].Id)
+	_, _ = db.Exec("UPDATE tracks SET label = 'Warp', bpm = 90, musicbrainz_track_id = 'mbid' WHERE id = 16")
+
+	// Text values are compared without case.
+	tracks, err := suite.TrackRepository.FindTracks(business.TrackFilter{Label: "warp"})
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), tracks, 3)
+
+	// Every criteria must match.
+	tracks, err = suite.TrackRepository.FindTracks(business.TrackFilter{Label: "Warp", GenreId: 1})
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), tracks, 2)
+	tracks, err = suite.TrackRepository.FindTracks(business.TrackFilter{Label: "Warp", Composer: "richard d. james"})
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), tracks, 1)
+	assert.Equal(suite.T(), genreTracks[0].Id, tracks[0].Id)
+	tracks, _ = suite.TrackRepository.FindTracks(business.TrackFilter{Isrc: "GBAAA0000001"})
+	assert.Len(suite.T(), tracks, 1)
+	tracks, _ = suite.TrackRepository.FindTracks(business.TrackFilter{MusicBrainzTrackId: "mbid"})
+	assert.Len(suite.T(), tracks, 1)
+	assert.Equal(suite.T(), 16, tracks[0].Id)
+
+	// BPM bounds are included.
+	tracks, _ = suite.TrackRepository.FindTracks(business.TrackFilter{BpmMin: 100, BpmMax: 140})
+	assert.Len(suite.T(), tracks, 2)
+	tracks, _ = suite.TrackRepository.FindTracks(business.TrackFilter{BpmMin: 121})
+	assert.Len(suite.T(), tracks, 1)
+	tracks, _ = suite.TrackRepository.FindTracks(business.TrackFilter{BpmMax: 90})
+	assert.Len(suite.T(), tracks, 1)
+
+	tracks, err = suite.TrackRepository.FindTracks(business.TrackFilter{Label: "Unknown"})
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), tracks)
+}
+
+func (suite *TrackRepoTestSuite) TestGetTracksForArtist() {
+	tracks, err := suite.TrackRepository.GetTracksForArtist(2, domain.ArtistRoleMain)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), tracks, 3)
+
+	// Test with another role.
+	tracks, err = suite.TrackRepository.GetTracksForArtist(3, domain.ArtistRoleFeatured)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), tracks, 1)
+	assert.Equal(suite.T(), 2, tracks[0].Id)
+
+	// Test with any role.
+	tracks, err = suite.TrackRepository.GetTracksForArtist(3, "")
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), tracks, 2)
+
+	tracks, err = suite.TrackRepository.GetTracksForArtist(99, "")
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), tracks)
+}
+
+func (suite *TrackRepoTestSuite) TestGetTrackArtists() {
+	credits, err := suite.TrackRepository.GetTrackArtists(2)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), credits, 2)
+	assert.Equal(suite.T(), 2, credits[0].ArtistId)
+	assert.Equal(suite.T(), domain.ArtistRoleMain, credits[0].Role)
+	assert.Equal(suite.T(), 3, credits[1].ArtistId)
+	assert.Equal(suite.T(), domain.ArtistRoleFeatured, credits[1].Role)
+}
+
 func (suite *TrackRepoTestSuite) TestSave() {
 	// Test to save a new track.
 	newTrack := &domain.Track{
@@ -179,6 +260,73 @@ func (suite *TrackRepoTestSuite) TestSave() {
 	assert.Nil(suite.T(), errBogusId)
 }
 
+func (suite *TrackRepoTestSuite) TestSaveLoudness() {
+	track, _ := suite.TrackRepository.Get(1)
+	// The track is changed after having been read.
+	_, _ = suite.TrackRepository.AppContext.DB.Exec("UPDATE tracks SET title = 'Renamed' WHERE id = 1")
+
+	track.LoudnessTrackLufs = -9.5
+	track.LoudnessTrackPeak = 0.9
+	track.LoudnessAlbumLufs = -10
+	track.LoudnessAlbumPeak = 1
+	track.LoudnessAnalyzedAt = 1000
+	err := suite.TrackRepository.SaveLoudness(&track)
+	assert.Nil(suite.T(), err)
+	saved, _ := suite.TrackRepository.Get(1)
+	assert.Equal(suite.T(), "Renamed", saved.Title)
+	assert.Equal(suite.T(), -9.5, saved.LoudnessTrackLufs)
+	assert.Equal(suite.T(), 0.9, saved.LoudnessTrackPeak)
+	assert.Equal(suite.T(), -10.0, saved.LoudnessAlbumLufs)
+	assert.Equal(suite.T(), 1.0, saved.LoudnessAlbumPeak)
+	assert.Equal(suite.T(), int64(1000), saved.LoudnessAnalyzedAt)
+
+	// Deleted tracks are ignored.
+	err = suite.TrackRepository.SaveLoudness(&domain.Track{Id: 99, LoudnessAnalyzedAt: 1000})
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), suite.TrackRepository.Exists(99))
+}
+
+// Media repository changing the library while the audio is decoded.
+type editingMediaRepositoryMock struct {
+	mediaRepositoryMock
+	edit func()
+}
+
+func (m *editingMediaRepositoryMock) DecodeAudio(filepath string, startMs int, endMs int, write func(sampleRate int, samples [][]float64) error) error {
+	if m.edit != nil {
+		m.edit()
+		m.edit = nil
+	}
+
+	return nil
+}
+
+func (suite *TrackRepoTestSuite) TestAnalyzeLoudnessKeepsChanges() {
+	db := suite.TrackRepository.AppContext.DB
+	interactor := business.LibraryInteractor{
+		TrackRepository: suite.TrackRepository,
+		MediaFileRepository: &editingMediaRepositoryMock{edit: func() {
+			// The tracks have been read by the analysis.
+			_, _ = db.Exec("UPDATE tracks SET title = 'Renamed', path = '/moved/01 - Renamed.mp3' WHERE id = 1")
+			_, _ = db.Exec("DELETE FROM tracks WHERE id = 2")
+		}},
+	}
+
+	tracks, _ := suite.TrackRepository.GetAll()
+	err := interactor.AnalyzeLoudness(true, nil)
+	assert.Nil(suite.T(), err)
+	track, err := suite.TrackRepository.Get(1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Renamed", track.Title)
+	assert.Equal(suite.T(), "/moved/01 - Renamed.mp3", track.Path)
+	assert.NotZero(suite.T(), track.LoudnessAnalyzedAt)
+	assert.False(suite.T(), suite.TrackRepository.Exists(2))
+	// The tracks after the deleted one are analyzed.
+	track, _ = suite.TrackRepository.Get(3)
+	assert.NotZero(suite.T(), track.LoudnessAnalyzedAt)
+	assert.Equal(suite.T(), len(tracks), interactor.GetLoudnessAnalysisProgress().TracksDone)
+}
+
 func (suite *TrackRepoTestSuite) TestDelete() {
 	var trackId = 1
 
@@ -193,6 +341,10 @@ func (suite *TrackRepoTestSuite) TestDelete() {
 	// Check track has been removed from the database.
 	_, err = suite.TrackRepository.Get(trackId)
 	assert.NotNil(suite.T(), err)
+
+	// Check its lyrics have been removed too.
+	_, err = LyricsDbRepository{AppContext: suite.TrackRepository.AppContext}.GetForTrack(trackId)
+	assert.NotNil(suite.T(), err)
 }
 
 func (suite *TrackRepoTestSuite) TestExists() {
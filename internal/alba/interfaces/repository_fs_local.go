@@ -11,6 +11,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -22,6 +23,15 @@ import (
 	"github.com/spf13/viper"
 )
 
+var validMediaExtensions = []string{
+	".mp3",
+	".flac",
+	".ogg",
+	".oga",
+	".opus",
+	".m4a",
+}
+
 var validCoverExtensions = []string{
 	".png",
 	".jpg",
@@ -29,6 +39,9 @@ var validCoverExtensions = []string{
 	".gif",
 }
 
+// Matches subdirectories holding one disc of a multi-disc album, like "CD1", "Disc 2" or "disk_3".
+var discDirectoryRegexp = regexp.MustCompile(`(?i)^(cd|disc|disk)[\s_\-.]*(\d+)\b`)
+
 var validCoverNames = []string{
 	"cover",
 	"artwork",
@@ -44,14 +57,42 @@ type mediaMetadata struct {
 	Format  	string
 	Title   	string
 	Album   	string
-	Artist  	string
+	Artist  	string // Main artist.
+	ArtistSort 	string
+	Artists 	[]artistCredit // All the credited artists, main artist first.
 	AlbumArtist string
+	AlbumArtistSort string
+	AlbumSort 	string
+	Compilation bool
 	Genre   	string
+	Genres  	[]string
 	Year    	string
+	Date    	string // Format: YYYY[-MM[-DD]]
+	OriginalDate string
 	Track   	int
 	Disc    	string // Format: <number>/<total>
+	MusicBrainzTrackId string
+	MusicBrainzAlbumId string
+	MusicBrainzArtistId string
+	Isrc 		string
+	Label 		string
+	CatalogNumber string
+	OriginalYear string
+	Composer 	string
+	Conductor 	string
+	Bpm 		int
+	ReplayGainTrackGain float64
+	ReplayGainTrackPeak float64
+	ReplayGainAlbumGain float64
+	ReplayGainAlbumPeak float64
+	Lyrics 		string
+	SyncedLyrics []domain.LyricsLine
 	Picture 	*tag.Picture
-	Duration 	int
+	Duration 	int // In seconds.
+	DurationMs 	int // Length of the media file.
+	StartMs 	int // Tracks of a CUE sheet are segments of the media file.
+	EndMs 		int
+	CueTrack 	int
 	Path 		string
 }
 
@@ -97,12 +138,73 @@ func scanDirectory(path string, variousArtistsId int, dbTransaction *gorp.Transa
 		return
 	}
 
-	currentDir := filepath.Clean(path) + string(os.PathSeparator)
+	tracks, potentialAlbumCover, subDirectories, err := readDirectory(path)
+	if err != nil {
+		return
+	}
+
+	var discDirectories []string
+	for _, subDirectory := range subDirectories {
+		if discDirectoryRegexp.MatchString(filepath.Base(subDirectory)) {
+			discDirectories = append(discDirectories, subDirectory)
+		} else {
+			// Recursion.
+			scanDirectory(subDirectory, variousArtistsId, dbTransaction)
+		}
+	}
+
+	// Tracks located in disc subdirectories are part of the albums of the current directory.
+	for i, discDirectory := range discDirectories {
+		discTracks, discCover, discSubDirectories, errDisc := readDirectory(discDirectory)
+		if errDisc != nil {
+			log.Println(errDisc)
+			continue
+		}
+
+		discNumber := i + 1
+		if matches := discDirectoryRegexp.FindStringSubmatch(filepath.Base(discDirectory)); matches != nil {
+			discNumber, _ = strconv.Atoi(matches[2])
+		}
+		for j := range discTracks {
+			if discTracks[j].Disc == "" && len(discDirectories) > 1 {
+				discTracks[j].Disc = strconv.Itoa(discNumber) + "/" + strconv.Itoa(len(discDirectories))
+			}
+		}
+		tracks = append(tracks, discTracks...)
+
+		// The cover in the parent directory has priority.
+		if len(potentialAlbumCover) == 0 {
+			potentialAlbumCover = discCover
+		}
+
+		for _, discSubDirectory := range discSubDirectories {
+			scanDirectory(discSubDirectory, variousArtistsId, dbTransaction)
+		}
+	}
 
 	// Collection of tracks found in the directory indexed by album.
 	mediaFiles := make(map[string][]mediaMetadata)
+	for _, metadata := range tracks {
+		// Add metadata info to the list of media files, sorting by albums.
+		if len(metadata.Album) > 0 {
+			albumKey := business.MatchKey(metadata.Album)
+			mediaFiles[albumKey] = append(mediaFiles[albumKey], metadata)
+		} else {
+			mediaFiles[business.LibraryDefaultAlbum] = append(mediaFiles[business.LibraryDefaultAlbum], metadata)
+		}
+	}
 
-	potentialAlbumCover := ""
+	processMediaFiles(mediaFiles, potentialAlbumCover, variousArtistsId, dbTransaction)
+
+	return
+}
+
+// Gets the media files metadata, the potential album cover and the subdirectories of a directory.
+//
+// The media files described by a CUE sheet are replaced by the tracks of the sheet.
+func readDirectory(path string) (tracks []mediaMetadata, potentialAlbumCover string, subDirectories []string, err error) {
+	currentDir := filepath.Clean(path) + string(os.PathSeparator)
+	var cueSheets []string
 
 	// Get all the entries in the current directory.
 	files, err := ioutil.ReadDir(path)
@@ -114,46 +216,42 @@ func scanDirectory(path string, variousArtistsId int, dbTransaction *gorp.Transa
 		filePath := currentDir + file.Name()
 
 		if file.IsDir() {
-			// Recursion.
-			scanDirectory(filePath, variousArtistsId, dbTransaction)
-		} else if matched, _ := filepath.Match("*.mp3", strings.ToLower(file.Name())); matched {
-			// Get ID3 metadata and add it to an array.
+			subDirectories = append(subDirectories, filePath)
+		} else if isValidMediaFile(file.Name()) {
+			// Get metadata and add it to an array.
 			metadata, err := getMetadataFromFile(filePath)
 			if err == nil {
-				// Add metadata info to the list of media files, sorting by albums.
-				if len(metadata.Album) > 0 {
-					mediaFiles[metadata.Album] = append(mediaFiles[metadata.Album], metadata)
-				} else {
-					mediaFiles[business.LibraryDefaultAlbum] = append(mediaFiles[business.LibraryDefaultAlbum], metadata)
-				}
+				tracks = append(tracks, metadata)
 			}
 		} else if len(potentialAlbumCover) == 0 && isValidCoverFile(file.Name()) {
 			// It's a good candidate for an album cover, so keep it.
 			potentialAlbumCover = filePath
+		} else if strings.ToLower(filepath.Ext(file.Name())) == ".cue" {
+			cueSheets = append(cueSheets, filePath)
 		}
 	}
 
-	processMediaFiles(mediaFiles, potentialAlbumCover, variousArtistsId, dbTransaction)
+	tracks = applyCueSheets(tracks, cueSheets)
 
 	return
 }
 
 func processMediaFiles(mediaFiles map[string][]mediaMetadata, cover string, variousArtistsId int, dbTransaction *gorp.Transaction) {
-	// MediaFiles is a map of albums found in one directory.
-	uniqueAlbum := len(mediaFiles) < 2
+	// Tracks sharing the same album title in a directory can still belong to different albums if they have been
+	// given different album artists, different MusicBrainz release ids or different numbers of discs.
+	var albums [][]mediaMetadata
+	for _, tracks := range mediaFiles {
+		albums = append(albums, splitAlbums(tracks)...)
+	}
+
+	// Albums is the list of albums found in one directory.
+	uniqueAlbum := len(albums) < 2
+	// Ids of the tracks saved for each media file.
+	processedTracks := make(map[string][]int)
 
 	// Process the media files per album.
-	for _, album := range mediaFiles {
-
-		// Here we try to figure out if the album is a compilation or not.
-		// If at least 2 of the tracks have different artists, this must be a compilation.
-		compilation := false
-		currentArtist := album[0].Artist
-		for i := 1; i < len(album) && !compilation; i++ {
-			if album[i].Artist != currentArtist {
-				compilation = true
-			}
-		}
+	for _, album := range albums {
+		albumArtist, compilation := resolveAlbumArtist(album)
 
 		// If there is only one album in the directory and we found a valid cover file, in this same directory,
 		// we can directly add the cover to the database.
@@ -173,22 +271,20 @@ func processMediaFiles(mediaFiles map[string][]mediaMetadata, cover string, vari
 			}
 		}
 
+		// The album belongs to its album artist, which is not necessarily one of the tracks artists.
+		albumArtistId := variousArtistsId
+		if !compilation {
+			albumArtistId, _ = processArtist(dbTransaction, albumArtist, resolveAlbumArtistSort(album, albumArtist))
+		}
+
 		// Now we process the metadata to populate the library.
 		for _, metadataTrack := range album {
-			if compilation {
-				metadataTrack.AlbumArtist = business.LibraryDefaultCompilationArtist
-			}
+			metadataTrack.AlbumArtist = albumArtist
 
 			var artistId int
 			var albumId int
 
-			artistId, _ = processArtist(dbTransaction, &metadataTrack)
-
-			albumArtistId := artistId
-			if compilation {
-				albumArtistId = variousArtistsId
-			}
-
+			artistId, _ = processArtist(dbTransaction, metadataTrack.Artist, metadataTrack.ArtistSort)
 			albumId, _ = processAlbum(dbTransaction, &metadataTrack, albumArtistId, albumCoverId)
 
 			// Find out what cover we can set for the track based on config preferences.
@@ -210,9 +306,143 @@ func processMediaFiles(mediaFiles map[string][]mediaMetadata, cover string, vari
 				}
 			}
 
-			processTrack(dbTransaction, &metadataTrack, artistId, albumId, trackCoverId)
+			trackId, errTrack := processTrack(dbTransaction, &metadataTrack, artistId, albumId, trackCoverId)
+			if errTrack == nil {
+				processedTracks[metadataTrack.Path] = append(processedTracks[metadataTrack.Path], trackId)
+			}
+		}
+	}
+
+	// A media file is either a track or split into the tracks of a CUE sheet: remove what it was before.
+	for filePath, trackIds := range processedTracks {
+		if err := removeOtherTracks(dbTransaction, filePath, trackIds); err != nil {
+			// TODO devise a decent logging system.
+			log.Println(err)
+		}
+	}
+}
+
+// Splits tracks having the same album title into several albums.
+//
+// Album identity is resolved by album artist, then MusicBrainz release id, then disc structure.
+func splitAlbums(tracks []mediaMetadata) (albums [][]mediaMetadata) {
+	albums = [][]mediaMetadata{tracks}
+	keyFunctions := []func(track mediaMetadata) string{
+		func(track mediaMetadata) string { return business.MatchKey(track.AlbumArtist) },
+		func(track mediaMetadata) string { return strings.ToLower(track.MusicBrainzAlbumId) },
+		func(track mediaMetadata) string {
+			if total := discTotal(track.Disc); total > 0 {
+				return strconv.Itoa(total)
+			}
+			return ""
+		},
+	}
+
+	for _, keyFunction := range keyFunctions {
+		var result [][]mediaMetadata
+		for _, album := range albums {
+			result = append(result, splitTracksBy(album, keyFunction)...)
+		}
+		albums = result
+	}
+
+	return
+}
+
+// Splits tracks in groups sharing the same key.
+//
+// Tracks with an empty key are kept with the others if all the remaining tracks share the same key.
+func splitTracksBy(tracks []mediaMetadata, keyFunction func(track mediaMetadata) string) (groups [][]mediaMetadata) {
+	var keys []string
+	tracksByKey := make(map[string][]mediaMetadata)
+	for _, track := range tracks {
+		key := keyFunction(track)
+		if _, ok := tracksByKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		tracksByKey[key] = append(tracksByKey[key], track)
+	}
+
+	if untagged, ok := tracksByKey[""]; ok && len(tracksByKey) == 2 {
+		for _, key := range keys {
+			if key != "" {
+				tracksByKey[key] = append(tracksByKey[key], untagged...)
+			}
+		}
+		delete(tracksByKey, "")
+	}
+
+	for _, key := range keys {
+		if group, ok := tracksByKey[key]; ok {
+			groups = append(groups, group)
 		}
 	}
+
+	return
+}
+
+// Gets the total number of discs from a "<number>/<total>" disc string.
+//
+// Returns 0 if unknown.
+func discTotal(disc string) int {
+	parts := strings.Split(disc, "/")
+	if len(parts) != 2 {
+		return 0
+	}
+
+	total, _ := strconv.Atoi(parts[1])
+	return total
+}
+
+// Figures out who the album artist is from the album tracks metadata.
+//
+// The album artist tag has priority, then the compilation flag. If none of the tracks carry this information, we
+// try to guess: if at least 2 of the tracks have different artists, this must be a compilation.
+func resolveAlbumArtist(album []mediaMetadata) (albumArtist string, compilation bool) {
+	// Use the most common album artist tag, in case the album has been partially tagged.
+	counts := make(map[string]int)
+	for _, track := range album {
+		if track.AlbumArtist != "" {
+			counts[track.AlbumArtist]++
+			if counts[track.AlbumArtist] > counts[albumArtist] {
+				albumArtist = track.AlbumArtist
+			}
+		}
+	}
+	if albumArtist != "" {
+		return albumArtist, strings.EqualFold(albumArtist, business.LibraryDefaultCompilationArtist)
+	}
+
+	for _, track := range album {
+		if track.Compilation {
+			return business.LibraryDefaultCompilationArtist, true
+		}
+	}
+
+	currentArtist := album[0].Artist
+	for i := 1; i < len(album); i++ {
+		if album[i].Artist != currentArtist {
+			return business.LibraryDefaultCompilationArtist, true
+		}
+	}
+
+	return currentArtist, false
+}
+
+// Finds the sort name of an album artist in the tracks tags, if any.
+func resolveAlbumArtistSort(album []mediaMetadata, albumArtist string) string {
+	for _, track := range album {
+		if track.AlbumArtistSort != "" && track.AlbumArtist == albumArtist {
+			return track.AlbumArtistSort
+		}
+	}
+	for _, track := range album {
+		if track.ArtistSort != "" && track.Artist == albumArtist {
+			return track.ArtistSort
+		}
+	}
+
+	return ""
 }
 
 // Checks if a media file physically exists.
@@ -239,24 +469,55 @@ func (r LocalFilesystemRepository) DeleteCovers() error {
 // Saves an artist info in the database.
 //
 // Returns a artist id.
-func processArtist(dbTransaction *gorp.Transaction, metadata *mediaMetadata) (id int, err error) {
+func processArtist(dbTransaction *gorp.Transaction, name string, sortName string) (id int, err error) {
 	// Process artist if any.
-	if metadata.Artist != "" {
+	if name != "" {
 		artist := domain.Artist{}
 
 		// See if the artist exists and if so instanciate it with existing data.
 		var entities domain.Artists
 		// TODO Bad! Persistance layer should be abstracted!
-		_, transErr := dbTransaction.Select(&entities, "SELECT * FROM artists WHERE name = ?", metadata.Artist)
+		// The names of the artists merged into another one are mapped onto the canonical artist.
+		_, transErr := dbTransaction.Select(
+			&entities,
+			"SELECT art.* FROM artists art, artist_aliases ali WHERE ali.artist_id = art.id AND ali.match_key = ? ORDER BY ali.id",
+			business.MatchKey(name),
+		)
+		aliased := transErr == nil && len(entities) > 0
+		if !aliased {
+			_, transErr = dbTransaction.Select(&entities, "SELECT * FROM artists WHERE match_key = ? ORDER BY id", business.MatchKey(name))
+		}
 		if transErr == nil {
 			if len(entities) > 0 {
 				artist = entities[0]
 			}
 		}
 
-		artist.Name = metadata.Artist
+		// Keep the name of the first file the artist has been found in.
+		if artist.Id == 0 {
+			artist.Name = name
+			artist.MatchKey = business.MatchKey(name)
+		}
+		// Sort names from the tags have priority, but we don't want to lose one found in another file. The sort name
+		// of an alias doesn't apply to the canonical artist.
+		if sortName != "" && !aliased {
+			artist.SortName = business.FoldAccents(sortName)
+		} else if artist.SortName == "" {
+			artist.SortName = business.SortName(name, viper.GetStringSlice("Library.SortArticles"))
+		}
 
 		if artist.Id != 0 {
+			err = processOverrides(
+				dbTransaction,
+				domain.OverrideEntityArtist,
+				artist.Id,
+				func(field string) (string, error) { return business.GetArtistField(&artist, field) },
+				func(field string, value string) error { return business.SetArtistField(&artist, field, value) },
+			)
+			if err != nil {
+				return
+			}
+
 			// Update.
 			_, err = dbTransaction.Update(&artist)
 		} else {
@@ -284,20 +545,78 @@ func processAlbum(dbTransaction *gorp.Transaction, metadata *mediaMetadata, arti
 		// See if the album exists and if so instanciate it with existing data.
 		var entities domain.Albums
 		// TODO Bad! Persistance layer should be abstracted!
-		_, transErr := dbTransaction.Select(&entities, "SELECT * FROM albums WHERE title = ? AND artist_id = ?", metadata.Album, artistId)
+		// An album can be spread across several directories, so we don't want to match on the album path here.
+		// Albums without MusicBrainz release id will be merged with any album having the same title and artist.
+		_, transErr := dbTransaction.Select(
+			&entities,
+			"SELECT * FROM albums WHERE match_key = ? AND artist_id = ? AND (musicbrainz_album_id = ? OR musicbrainz_album_id = '' OR ? = '') ORDER BY musicbrainz_album_id DESC, id",
+			business.MatchKey(metadata.Album),
+			artistId,
+			metadata.MusicBrainzAlbumId,
+			metadata.MusicBrainzAlbumId,
+		)
 		if transErr == nil {
 			if len(entities) > 0 {
 				album = entities[0]
 			}
 		}
 
-		album.Title = metadata.Album
+		if album.Id == 0 {
+			album.Title = metadata.Album
+			album.MatchKey = business.MatchKey(metadata.Album)
+		}
 		album.ArtistId = artistId
-		// TODO Track all the years from an album tracks and compute the final value (improvement).
-		album.Year = metadata.Year
-		album.CoverId = coverId
+		if metadata.AlbumSort != "" {
+			album.SortName = business.FoldAccents(metadata.AlbumSort)
+		} else if album.SortName == "" {
+			album.SortName = business.SortName(metadata.Album, viper.GetStringSlice("Library.SortArticles"))
+		}
+		// Compute the album dates from all its tracks, the current one not being saved yet.
+		dates := []string{metadata.Date}
+		originalDates := []string{metadata.OriginalDate}
+		if album.Id != 0 {
+			var trackDates []struct {
+				Date         string `db:"date"`
+				OriginalDate string `db:"original_date"`
+			}
+			// TODO Bad! Persistance layer should be abstracted!
+			_, transErr = dbTransaction.Select(
+				&trackDates,
+				"SELECT date, original_date FROM tracks WHERE album_id = ? AND NOT (path = ? AND cue_track = ?)",
+				album.Id,
+				metadata.Path,
+				metadata.CueTrack,
+			)
+			if transErr == nil {
+				for _, trackDate := range trackDates {
+					dates = append(dates, trackDate.Date)
+					originalDates = append(originalDates, trackDate.OriginalDate)
+				}
+			}
+		}
+		album.Year = business.YearSpan(dates)
+		album.Date = business.LatestDate(dates)
+		album.OriginalDate = business.EarliestDate(originalDates)
+		if metadata.MusicBrainzAlbumId != "" {
+			album.MusicBrainzAlbumId = metadata.MusicBrainzAlbumId
+		}
+		// Don't remove a cover found in another directory of the album.
+		if coverId != 0 {
+			album.CoverId = coverId
+		}
 
 		if album.Id != 0 {
+			err = processOverrides(
+				dbTransaction,
+				domain.OverrideEntityAlbum,
+				album.Id,
+				func(field string) (string, error) { return business.GetAlbumField(&album, field) },
+				func(field string, value string) error { return business.SetAlbumField(&album, field, value) },
+			)
+			if err != nil {
+				return
+			}
+
 			// Update.
 			_, err = dbTransaction.Update(&album)
 		} else {
@@ -325,11 +644,16 @@ func processTrack(dbTransaction *gorp.Transaction, metadata *mediaMetadata, arti
 	// See if the track exists and if so instanciate it with existing data.
 	var entities domain.Tracks
 	// TODO Bad! Persistance layer should be abstracted!
-	_, transErr := dbTransaction.Select(&entities, "SELECT * FROM tracks WHERE path = ?", metadata.Path)
+	_, transErr := dbTransaction.Select(&entities, "SELECT * FROM tracks WHERE path = ? AND cue_track = ?", metadata.Path, metadata.CueTrack)
 	if transErr == nil && len(entities) > 0 {
 		track = entities[0]
 	}
 
+	// The audio has changed, analyze it again. Tracks scanned before durations were read had none.
+	if (track.Duration != metadata.Duration && track.Duration != 0) || track.StartMs != metadata.StartMs || track.EndMs != metadata.EndMs {
+		track.LoudnessAnalyzedAt = 0
+	}
+
 	track.ArtistId = artistId
 	track.AlbumId = albumId
 	track.CoverId = coverId
@@ -339,8 +663,38 @@ func processTrack(dbTransaction *gorp.Transaction, metadata *mediaMetadata, arti
 	track.Genre = metadata.Genre
 	track.Duration = metadata.Duration
 	track.Path = metadata.Path
+	track.MusicBrainzTrackId = metadata.MusicBrainzTrackId
+	track.MusicBrainzAlbumId = metadata.MusicBrainzAlbumId
+	track.MusicBrainzArtistId = metadata.MusicBrainzArtistId
+	track.Isrc = metadata.Isrc
+	track.Label = metadata.Label
+	track.CatalogNumber = metadata.CatalogNumber
+	track.Date = metadata.Date
+	track.OriginalDate = metadata.OriginalDate
+	track.OriginalYear = metadata.OriginalYear
+	track.Composer = metadata.Composer
+	track.Conductor = metadata.Conductor
+	track.Bpm = metadata.Bpm
+	track.ReplayGainTrackGain = metadata.ReplayGainTrackGain
+	track.ReplayGainTrackPeak = metadata.ReplayGainTrackPeak
+	track.ReplayGainAlbumGain = metadata.ReplayGainAlbumGain
+	track.ReplayGainAlbumPeak = metadata.ReplayGainAlbumPeak
+	track.StartMs = metadata.StartMs
+	track.EndMs = metadata.EndMs
+	track.CueTrack = metadata.CueTrack
 
 	if track.Id != 0 {
+		err = processOverrides(
+			dbTransaction,
+			domain.OverrideEntityTrack,
+			track.Id,
+			func(field string) (string, error) { return business.GetTrackField(&track, field) },
+			func(field string, value string) error { return business.SetTrackField(&track, field, value) },
+		)
+		if err != nil {
+			return
+		}
+
 		// Update.
 		_, err = dbTransaction.Update(&track)
 	} else {
@@ -349,9 +703,160 @@ func processTrack(dbTransaction *gorp.Transaction, metadata *mediaMetadata, arti
 		err = dbTransaction.Insert(&track)
 	}
 
+	if err == nil {
+		err = processGenres(dbTransaction, track.Id, metadata.Genres)
+	}
+	if err == nil {
+		err = processTrackArtists(dbTransaction, track.Id, artistId, metadata.Artists)
+	}
+	if err == nil {
+		err = processLyrics(dbTransaction, track.Id, metadata.Lyrics, metadata.SyncedLyrics)
+	}
+
 	return track.Id, err
 }
 
+// Applies the manual edits of an entity over the metadata read from the media files.
+func processOverrides(
+	dbTransaction *gorp.Transaction,
+	entityType string,
+	entityId int,
+	get func(field string) (string, error),
+	set func(field string, value string) error,
+) error {
+	var overrides domain.Overrides
+	// TODO Bad! Persistance layer should be abstracted!
+	_, err := dbTransaction.Select(&overrides, "SELECT * FROM overrides WHERE entity_type = ? AND entity_id = ?", entityType, entityId)
+	if err != nil {
+		return err
+	}
+
+	for _, override := range business.ApplyOverrides(overrides, get, set) {
+		if _, err = dbTransaction.Update(&override); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Saves the artists credited on a track in the database and links them to the track.
+func processTrackArtists(dbTransaction *gorp.Transaction, trackId int, artistId int, credits []artistCredit) (err error) {
+	// Credits may have been changed since last scan.
+	_, err = dbTransaction.Exec("DELETE FROM track_artists WHERE track_id = ?", trackId)
+	if err != nil {
+		return
+	}
+
+	// The track always belongs to its main artist.
+	_, err = dbTransaction.Exec(
+		"INSERT OR IGNORE INTO track_artists (track_id, artist_id, role, position) VALUES (?, ?, ?, ?)",
+		trackId,
+		artistId,
+		domain.ArtistRoleMain,
+		0,
+	)
+	if err != nil {
+		return
+	}
+
+	for position, credit := range credits {
+		creditArtistId, errArtist := processArtist(dbTransaction, credit.Name, "")
+		if errArtist != nil {
+			return errArtist
+		}
+
+		_, err = dbTransaction.Exec(
+			"INSERT OR IGNORE INTO track_artists (track_id, artist_id, role, position) VALUES (?, ?, ?, ?)",
+			trackId,
+			creditArtistId,
+			credit.Role,
+			position,
+		)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Removes the tracks of a media file other than the given ones, with their genres, credits and lyrics.
+func removeOtherTracks(dbTransaction *gorp.Transaction, filePath string, trackIds []int) (err error) {
+	var ids []string
+	for _, trackId := range trackIds {
+		ids = append(ids, strconv.Itoa(trackId))
+	}
+	condition := "path = ? AND id NOT IN (" + strings.Join(ids, ", ") + ")"
+
+	// TODO Bad! Persistance layer should be abstracted!
+	for _, table := range []string{"track_genres", "track_artists", "lyrics"} {
+		_, err = dbTransaction.Exec("DELETE FROM "+table+" WHERE track_id IN (SELECT id FROM tracks WHERE "+condition+")", filePath)
+		if err != nil {
+			return
+		}
+	}
+	_, err = dbTransaction.Exec("DELETE FROM tracks WHERE "+condition, filePath)
+
+	return
+}
+
+// Saves the lyrics of a track in the database.
+func processLyrics(dbTransaction *gorp.Transaction, trackId int, text string, synced []domain.LyricsLine) (err error) {
+	lyrics := domain.Lyrics{}
+	// TODO Bad! Persistance layer should be abstracted!
+	_ = dbTransaction.SelectOne(&lyrics, "SELECT * FROM lyrics WHERE track_id = ?", trackId)
+
+	// Lyrics may have been removed since last scan.
+	if text == "" && len(synced) == 0 {
+		if lyrics.Id != 0 {
+			_, err = dbTransaction.Delete(&lyrics)
+		}
+		return
+	}
+
+	lyrics.TrackId = trackId
+	lyrics.Text = text
+	lyrics.Synced = business.FormatLrc(synced)
+	if lyrics.Id != 0 {
+		_, err = dbTransaction.Update(&lyrics)
+	} else {
+		lyrics.DateAdded = time.Now().Unix()
+		err = dbTransaction.Insert(&lyrics)
+	}
+
+	return
+}
+
+// Saves the genres of a track in the database and links them to the track.
+func processGenres(dbTransaction *gorp.Transaction, trackId int, genres []string) (err error) {
+	// Genres may have been changed since last scan.
+	_, err = dbTransaction.Exec("DELETE FROM track_genres WHERE track_id = ?", trackId)
+	if err != nil {
+		return
+	}
+
+	for _, name := range genres {
+		genre := domain.Genre{}
+		// TODO Bad! Persistance layer should be abstracted!
+		_ = dbTransaction.SelectOne(&genre, "SELECT * FROM genres WHERE name = ? COLLATE NOCASE", name)
+		if genre.Id == 0 {
+			genre.Name = name
+			genre.DateAdded = time.Now().Unix()
+			if err = dbTransaction.Insert(&genre); err != nil {
+				return
+			}
+		}
+
+		_, err = dbTransaction.Exec("INSERT OR IGNORE INTO track_genres (track_id, genre_id) VALUES (?, ?)", trackId, genre.Id)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
 // Saves a cover info in the database and filesystem.
 //
 // Returns a cover id.
@@ -393,36 +898,92 @@ func getMetadataFromFile(filePath string) (info mediaMetadata, err error) {
 		return
 	}
 
-	tags, errTags := tag.ReadFrom(file)
+	metadata, errTags := tag.ReadFrom(file)
 	if errTags != nil {
 		log.Println("ERROR - Can't read id3 tags of " + filePath)
 	}
 
 	if errTags == nil {
-		var artist = sanitizeString(tags.Artist())
-		if len(artist) == 0 {
-			artist = business.LibraryDefaultArtist
+		tags := mediaTags{Metadata: metadata, MultiValues: readMultiValueTags(file, metadata)}
+
+		info.Artists = getArtistCredits(
+			tags,
+			viper.GetStringSlice("Library.Artists.Separators"),
+			viper.GetStringSlice("Library.Artists.FeaturingSeparators"),
+		)
+		// The first main artist is the one the track belongs to.
+		var artist = business.LibraryDefaultArtist
+		if len(info.Artists) > 0 && info.Artists[0].Role == domain.ArtistRoleMain {
+			artist = info.Artists[0].Name
+		} else {
+			info.Artists = append([]artistCredit{{Name: artist, Role: domain.ArtistRoleMain}}, info.Artists...)
 		}
 
-
 		// Get all we can from the common tags.
 		info.Format = string(tags.FileType())
 		info.Title = sanitizeString(tags.Title())
 		info.Album = sanitizeString(tags.Album())
 		info.AlbumArtist = sanitizeString(tags.AlbumArtist())
 		info.Artist = artist
-		info.Genre = sanitizeString(tags.Genre())
-		if tags.Year() != 0 {
-			info.Year = strconv.Itoa(tags.Year())
+		// The artist sort tag is only relevant if the artist tag holds one artist.
+		if artist == sanitizeString(tags.Artist()) {
+			info.ArtistSort = getRawTag(tags, tagArtistSort)
+		}
+		info.AlbumArtistSort = getRawTag(tags, tagAlbumArtistSort)
+		info.AlbumSort = getRawTag(tags, tagAlbumSort)
+		info.Genres = normalizeGenres(getGenres(tags), viper.GetStringSlice("Library.Genres.Separators"))
+		info.Genre = strings.Join(info.Genres, ", ")
+		info.Date = business.NormalizeDate(getRawTag(tags, tagDate))
+		// ID3v2.3 stores the day and month of the release date in a separate frame, as "DDMM".
+		if dayMonth := getRawTag(tags, tagDateDayMonth); len(info.Date) == 4 && len(dayMonth) == 4 {
+			info.Date = business.NormalizeDate(info.Date + "-" + dayMonth[2:4] + "-" + dayMonth[0:2])
+		}
+		if info.Date == "" && tags.Year() != 0 {
+			info.Date = strconv.Itoa(tags.Year())
 		}
+		info.Year = business.DateYear(info.Date)
 		info.Track, _ = tags.Track()
 		info.Picture = tags.Picture()
+		info.Compilation = getRawTag(tags, tagCompilation) == "1"
+
+		// Extended tags.
+		info.MusicBrainzTrackId = getRawTag(tags, tagMusicBrainzTrackId)
+		info.MusicBrainzAlbumId = getRawTag(tags, tagMusicBrainzAlbumId)
+		info.MusicBrainzArtistId = getRawTag(tags, tagMusicBrainzArtistId)
+		info.Isrc = getRawTag(tags, tagIsrc)
+		info.Label = getRawTag(tags, tagLabel)
+		info.CatalogNumber = getRawTag(tags, tagCatalogNumber)
+		info.Composer = getRawTag(tags, tagComposer)
+		info.Conductor = getRawTag(tags, tagConductor)
+		info.OriginalDate = business.NormalizeDate(getRawTag(tags, tagOriginalDate))
+		info.OriginalYear = business.DateYear(info.OriginalDate)
+		if bpm, errBpm := strconv.ParseFloat(getRawTag(tags, tagBpm), 64); errBpm == nil {
+			info.Bpm = int(bpm + 0.5)
+		}
 
 		number, total := tags.Disc()
 		// Don't store disc info if there's only one disc.
 		if total > 1 {
 			info.Disc = strconv.Itoa(number) + "/" + strconv.Itoa(total)
 		}
+
+		info.ReplayGainTrackGain, info.ReplayGainTrackPeak, info.ReplayGainAlbumGain, info.ReplayGainAlbumPeak = getReplayGain(tags)
+		info.Lyrics, info.SyncedLyrics = getLyrics(tags)
+	}
+
+	// The duration is read from the audio headers, tags can't be trusted for it.
+	if durationMs, errDuration := getMediaDuration(file); errDuration == nil {
+		info.DurationMs = durationMs
+		info.Duration = (durationMs + 500) / 1000
+	}
+
+	// Lyrics files next to the media file have priority over the tags.
+	text, synced := getLyricsFromFiles(filePath)
+	if text != "" {
+		info.Lyrics = text
+	}
+	if len(synced) > 0 {
+		info.SyncedLyrics = synced
 	}
 
 	// If the track has no title, fallback to the filename.
@@ -439,6 +1000,35 @@ func getMetadataFromFile(filePath string) (info mediaMetadata, err error) {
 	return
 }
 
+/*
+Gets the lyrics of a media file from the .lrc and .txt files having the same name.
+
+The text of the lyrics falls back to the text of the time-synced lyrics.
+*/
+func getLyricsFromFiles(mediaFilePath string) (text string, synced []domain.LyricsLine) {
+	basePath := strings.TrimSuffix(mediaFilePath, filepath.Ext(mediaFilePath))
+
+	if content, err := ioutil.ReadFile(basePath + ".lrc"); err == nil {
+		synced = business.ParseLrc(normalizeLyrics(string(content)))
+	}
+	if content, err := ioutil.ReadFile(basePath + ".txt"); err == nil {
+		text = normalizeLyrics(string(content))
+		// Some files hold time-synced lyrics despite their extension.
+		if business.IsLrc(text) {
+			if len(synced) == 0 {
+				synced = business.ParseLrc(text)
+			}
+			text = ""
+		}
+	}
+
+	if text == "" {
+		text = business.LyricsText(synced)
+	}
+
+	return
+}
+
 // Get media cover from file.
 //
 // Returns the info for the first image file that matches.
@@ -463,6 +1053,17 @@ func getMediaCoverFromImageFile(coverFilepath string) (cover domain.Cover, err e
 	return cover, errors.New("invalid cover image file")
 }
 
+func isValidMediaFile(filename string) bool {
+	extension := strings.ToLower(filepath.Ext(filename))
+	for _, validExtension := range validMediaExtensions {
+		if extension == validExtension {
+			return true
+		}
+	}
+
+	return false
+}
+
 func isValidCoverFile(filename string) bool {
 	for _, name := range validCoverNames {
 		for _, ext := range validCoverExtensions {
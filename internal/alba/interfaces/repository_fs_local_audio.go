@@ -0,0 +1,189 @@
+package interfaces
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/go-mp3"
+	"github.com/mewkiz/flac"
+)
+
+/*
+This file exposes the decoding of the media files audio, used to analyze it.
+
+Only FLAC and MP3 files can be decoded.
+*/
+
+// Number of samples per channel passed at once to the decoding callback.
+const decodingChunkSize = 4096
+
+// Implements business.MediaFileRepository.
+func (r LocalFilesystemRepository) DecodeAudio(filePath string, startMs int, endMs int, write func(sampleRate int, samples [][]float64) error) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".flac":
+		return decodeFlac(file, startMs, endMs, write)
+	case ".mp3":
+		return decodeMp3(file, startMs, endMs, write)
+	}
+
+	return errors.New("cannot decode audio of " + filePath + ": unsupported format")
+}
+
+/*
+Passes the decoded audio between two times to a callback.
+
+Decoders can start before the start time, as they usually decode whole frames.
+*/
+type audioSegmentWriter struct {
+	sampleRate int
+	position   int64 // Number of the next decoded sample.
+	startMs    int
+	endMs      int // 0 for the end of the file.
+	write      func(sampleRate int, samples [][]float64) error
+}
+
+// Writes decoded samples.
+//
+// Returns true once the end of the segment has been reached.
+func (w *audioSegmentWriter) writeSamples(samples [][]float64) (done bool, err error) {
+	if len(samples) == 0 || len(samples[0]) == 0 {
+		return false, nil
+	}
+
+	length := int64(len(samples[0]))
+	from := int64(w.startMs)*int64(w.sampleRate)/1000 - w.position
+	to := length
+	if w.endMs > 0 {
+		to = int64(w.endMs)*int64(w.sampleRate)/1000 - w.position
+	}
+	w.position += length
+
+	if from < 0 {
+		from = 0
+	}
+	if to > length {
+		to = length
+	}
+	if from < to {
+		segment := make([][]float64, len(samples))
+		for channel := range samples {
+			segment[channel] = samples[channel][from:to]
+		}
+		if err = w.write(w.sampleRate, segment); err != nil {
+			return true, err
+		}
+	}
+
+	return w.endMs > 0 && to < length, nil
+}
+
+/*
+Decodes a FLAC file.
+
+Files are cut with getFlacSegment() to start decoding at the frame holding the start time. The frames keep their
+numbers, so the position of the decoded samples is still known.
+*/
+func decodeFlac(file *os.File, startMs int, endMs int, write func(sampleRate int, samples [][]float64) error) error {
+	var reader io.Reader = file
+	if startMs > 0 {
+		segment, err := getFlacSegment(file, startMs, endMs)
+		if err != nil {
+			return err
+		}
+		reader = segment
+	}
+
+	stream, err := flac.New(reader)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	writer := audioSegmentWriter{sampleRate: int(stream.Info.SampleRate), startMs: startMs, endMs: endMs, write: write}
+	scale := float64(int64(1) << (stream.Info.BitsPerSample - 1))
+	for {
+		frame, errFrame := stream.ParseNext()
+		if errFrame == io.EOF {
+			return nil
+		} else if errFrame != nil {
+			return errFrame
+		}
+
+		samples := make([][]float64, len(frame.Subframes))
+		for channel, subframe := range frame.Subframes {
+			samples[channel] = make([]float64, len(subframe.Samples))
+			for i, sample := range subframe.Samples {
+				samples[channel][i] = float64(sample) / scale
+			}
+		}
+
+		writer.position = int64(frame.SampleNumber())
+		if done, errWrite := writer.writeSamples(samples); done || errWrite != nil {
+			return errWrite
+		}
+	}
+}
+
+/*
+Decodes a MP3 file.
+
+The decoder always outputs 16 bits stereo samples, so mono files are detected from their first frame header.
+*/
+func decodeMp3(file *os.File, startMs int, endMs int, write func(sampleRate int, samples [][]float64) error) error {
+	channels := 2
+	if offset, err := getId3v2TagSize(file); err == nil {
+		header := make([]byte, 4)
+		if _, err = file.ReadAt(header, offset); err == nil {
+			if frame, ok := parseMp3FrameHeader(header); ok {
+				channels = frame.Channels
+			}
+		}
+	}
+
+	decoder, err := mp3.NewDecoder(file)
+	if err != nil {
+		return err
+	}
+
+	writer := audioSegmentWriter{sampleRate: decoder.SampleRate(), startMs: startMs, endMs: endMs, write: write}
+	if startMs > 0 {
+		// Decoded samples are 4 bytes long.
+		if writer.position, err = decoder.Seek(int64(startMs)*int64(writer.sampleRate)/1000*4, io.SeekStart); err != nil {
+			return err
+		}
+		writer.position /= 4
+	}
+
+	buffer := make([]byte, decodingChunkSize*4)
+	for {
+		n, errRead := io.ReadFull(decoder, buffer)
+		if n >= 4 {
+			samples := make([][]float64, channels)
+			for channel := range samples {
+				samples[channel] = make([]float64, n/4)
+				for i := range samples[channel] {
+					sample := int16(uint16(buffer[i*4+channel*2]) | uint16(buffer[i*4+channel*2+1])<<8)
+					samples[channel][i] = float64(sample) / 32768
+				}
+			}
+			if done, errWrite := writer.writeSamples(samples); done || errWrite != nil {
+				return errWrite
+			}
+		}
+
+		if errRead == io.EOF || errRead == io.ErrUnexpectedEOF {
+			return nil
+		} else if errRead != nil {
+			return errRead
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package interfaces
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type AudioDecodingTestSuite struct {
+	suite.Suite
+	Directory string
+}
+
+// Go testing framework entry point.
+func TestAudioDecodingTestSuite(t *testing.T) {
+	suite.Run(t, new(AudioDecodingTestSuite))
+}
+
+func (suite *AudioDecodingTestSuite) SetupSuite() {
+	directory, err := ioutil.TempDir("", "alba-audio")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.Directory = directory
+}
+
+func (suite *AudioDecodingTestSuite) TearDownSuite() {
+	_ = os.RemoveAll(suite.Directory)
+}
+
+/*
+Encodes a mono 16 bits 44.1 kHz FLAC file with frames of 4410 samples.
+
+The value of each sample is its number modulo 10000, so the position of the decoded samples can be checked.
+*/
+func (suite *AudioDecodingTestSuite) createFlacFile(name string, frames int) string {
+	path := filepath.Join(suite.Directory, name)
+	file, err := os.Create(path)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	info := &meta.StreamInfo{BlockSizeMin: 4410, BlockSizeMax: 4410, SampleRate: 44100, NChannels: 1, BitsPerSample: 16}
+	encoder, err := flac.NewEncoder(file, info)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	for i := 0; i < frames; i++ {
+		samples := make([]int32, 4410)
+		for j := range samples {
+			samples[j] = int32((i*4410 + j) % 10000)
+		}
+		audioFrame := &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         4410,
+				SampleRate:        44100,
+				Channels:          frame.ChannelsMono,
+				BitsPerSample:     16,
+			},
+			Subframes: []*frame.Subframe{{SubHeader: frame.SubHeader{Pred: frame.PredVerbatim}, Samples: samples, NSamples: 4410}},
+		}
+		if err = encoder.WriteFrame(audioFrame); err != nil {
+			suite.T().Fatal(err)
+		}
+	}
+	if err = encoder.Close(); err != nil {
+		suite.T().Fatal(err)
+	}
+
+	return path
+}
+
+// Decodes a file and returns its sample rate and samples.
+func decodeTestFile(path string, startMs int, endMs int) (sampleRate int, samples [][]float64, err error) {
+	err = LocalFilesystemRepository{}.DecodeAudio(path, startMs, endMs, func(rate int, chunk [][]float64) error {
+		sampleRate = rate
+		if samples == nil {
+			samples = make([][]float64, len(chunk))
+		}
+		for channel := range chunk {
+			samples[channel] = append(samples[channel], chunk[channel]...)
+		}
+		return nil
+	})
+
+	return
+}
+
+func (suite *AudioDecodingTestSuite) TestDecodeFlac() {
+	path := suite.createFlacFile("track.flac", 20)
+
+	sampleRate, samples, err := decodeTestFile(path, 0, 0)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 44100, sampleRate)
+	assert.Len(suite.T(), samples, 1)
+	assert.Len(suite.T(), samples[0], 20*4410)
+	assert.Equal(suite.T(), 1234.0/32768, samples[0][1234])
+
+	// From 250ms to 1s: samples 11025 to 44099.
+	_, samples, err = decodeTestFile(path, 250, 1000)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), samples[0], 33075)
+	assert.Equal(suite.T(), 1025.0/32768, samples[0][0])
+	assert.Equal(suite.T(), 4099.0/32768, samples[0][33074])
+}
+
+func (suite *AudioDecodingTestSuite) TestDecodeMp3() {
+	path := filepath.Join(suite.Directory, "track.mp3")
+	if err := ioutil.WriteFile(path, buildTestMp3File(40), 0644); err != nil {
+		suite.T().Fatal(err)
+	}
+
+	sampleRate, samples, err := decodeTestFile(path, 0, 0)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 44100, sampleRate)
+	assert.Len(suite.T(), samples, 2)
+	assert.True(suite.T(), len(samples[0]) >= 40*1152)
+	for _, sample := range samples[0] {
+		if sample != 0 {
+			assert.Fail(suite.T(), "silent frames decoded to sound")
+			break
+		}
+	}
+
+	// From 100ms to 600ms.
+	_, samples, err = decodeTestFile(path, 100, 600)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), samples[0], 22050)
+}
+
+func (suite *AudioDecodingTestSuite) TestDecodeUnsupportedFormat() {
+	path := filepath.Join(suite.Directory, "track.ogg")
+	if err := ioutil.WriteFile(path, []byte("OggS"), 0644); err != nil {
+		suite.T().Fatal(err)
+	}
+
+	_, _, err := decodeTestFile(path, 0, 0)
+	assert.NotNil(suite.T(), err)
+	_, _, err = decodeTestFile(filepath.Join(suite.Directory, "missing.flac"), 0, 0)
+	assert.NotNil(suite.T(), err)
+}
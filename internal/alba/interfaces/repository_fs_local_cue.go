@@ -0,0 +1,308 @@
+package interfaces
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/spf13/viper"
+)
+
+/*
+CUE sheet describing the tracks of one or several media files, usually a whole album ripped as a single file.
+*/
+type cueSheet struct {
+	Title     string
+	Performer string
+	Genre     string
+	Date      string
+	Files     []cueFile
+	// ReplayGain values of the whole sheet, written as comments by some rippers.
+	ReplayGainAlbumGain float64
+	ReplayGainAlbumPeak float64
+}
+
+type cueFile struct {
+	Name   string // Name of the media file, relative to the CUE sheet.
+	Tracks []cueTrack
+}
+
+type cueTrack struct {
+	Number         int
+	Title          string
+	Performer      string
+	Songwriter     string
+	Isrc           string
+	StartMs        int // Position of the track index 01 in the media file.
+	ReplayGainGain float64
+	ReplayGainPeak float64
+}
+
+/*
+Parses a CUE sheet.
+
+Only the audio tracks are kept. A track belongs to the file in which its index 01 is: the pregap of a track can be at
+the end of the previous file.
+*/
+func parseCueSheet(content string) (sheet cueSheet, err error) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	// CUE sheets written by old rippers are usually encoded in ISO-8859-1.
+	if !utf8.ValidString(content) {
+		runes := make([]rune, len(content))
+		for i := 0; i < len(content); i++ {
+			runes[i] = rune(content[i])
+		}
+		content = string(runes)
+	}
+
+	var track *cueTrack
+	for _, line := range strings.Split(strings.Replace(content, "\r", "\n", -1), "\n") {
+		fields := splitCueLine(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		command := strings.ToUpper(fields[0])
+		value := ""
+		if len(fields) > 1 {
+			value = fields[1]
+		}
+
+		switch command {
+		case "FILE":
+			sheet.Files = append(sheet.Files, cueFile{Name: value})
+		case "TRACK":
+			track = nil
+			if len(fields) > 2 && strings.ToUpper(fields[2]) == "AUDIO" {
+				number, _ := strconv.Atoi(value)
+				track = &cueTrack{Number: number, StartMs: -1}
+			}
+		case "INDEX":
+			if track != nil && len(fields) > 2 && value == "01" && track.StartMs < 0 {
+				if len(sheet.Files) == 0 {
+					return sheet, errors.New("track without file in CUE sheet")
+				}
+				if track.StartMs, err = parseCueTime(fields[2]); err != nil {
+					return
+				}
+				file := &sheet.Files[len(sheet.Files)-1]
+				file.Tracks = append(file.Tracks, *track)
+				track = &file.Tracks[len(file.Tracks)-1]
+			}
+		case "TITLE", "PERFORMER", "SONGWRITER", "ISRC":
+			setCueValue(&sheet, track, command, value)
+		case "REM":
+			// Common comments, like "REM GENRE Rock", "REM DATE 1994" or "REM REPLAYGAIN_TRACK_GAIN -6.48 dB".
+			if len(fields) > 2 && track == nil {
+				switch strings.ToUpper(value) {
+				case "GENRE":
+					sheet.Genre = fields[2]
+				case "DATE":
+					sheet.Date = fields[2]
+				case "REPLAYGAIN_ALBUM_GAIN":
+					sheet.ReplayGainAlbumGain = parseReplayGainValue(fields[2])
+				case "REPLAYGAIN_ALBUM_PEAK":
+					sheet.ReplayGainAlbumPeak = parseReplayGainValue(fields[2])
+				}
+			} else if len(fields) > 2 {
+				switch strings.ToUpper(value) {
+				case "REPLAYGAIN_TRACK_GAIN":
+					track.ReplayGainGain = parseReplayGainValue(fields[2])
+				case "REPLAYGAIN_TRACK_PEAK":
+					track.ReplayGainPeak = parseReplayGainValue(fields[2])
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// Sets the value of a sheet or track command.
+func setCueValue(sheet *cueSheet, track *cueTrack, command string, value string) {
+	if track == nil {
+		switch command {
+		case "TITLE":
+			sheet.Title = value
+		case "PERFORMER":
+			sheet.Performer = value
+		}
+		return
+	}
+
+	switch command {
+	case "TITLE":
+		track.Title = value
+	case "PERFORMER":
+		track.Performer = value
+	case "SONGWRITER":
+		track.Songwriter = value
+	case "ISRC":
+		track.Isrc = value
+	}
+}
+
+// Splits a CUE sheet line into its command and arguments, arguments possibly being quoted.
+func splitCueLine(line string) (fields []string) {
+	line = strings.TrimSpace(line)
+	for line != "" {
+		var field string
+		if line[0] == '"' {
+			end := strings.Index(line[1:], "\"")
+			if end < 0 {
+				field, line = line[1:], ""
+			} else {
+				field, line = line[1:end+1], line[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				field, line = line, ""
+			} else {
+				field, line = line[0:end], line[end:]
+			}
+		}
+		fields = append(fields, sanitizeString(field))
+		line = strings.TrimSpace(line)
+	}
+
+	return
+}
+
+// Converts a CUE sheet time, formatted as "mm:ss:ff" with 75 frames per second, to milliseconds.
+func parseCueTime(value string) (int, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, errors.New("invalid CUE sheet time " + value)
+	}
+
+	var numbers [3]int
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return 0, errors.New("invalid CUE sheet time " + value)
+		}
+		numbers[i] = number
+	}
+
+	return (numbers[0]*60+numbers[1])*1000 + numbers[2]*1000/75, nil
+}
+
+/*
+Replaces the media files described by CUE sheets with the tracks of the sheets.
+
+Media files are matched by name, ignoring the extension as the sheets often still reference the WAV file the album
+has been ripped to. A media file is only split by the first sheet referencing it.
+*/
+func applyCueSheets(tracks []mediaMetadata, cueSheetPaths []string) []mediaMetadata {
+	for _, cueSheetPath := range cueSheetPaths {
+		content, err := ioutil.ReadFile(cueSheetPath)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		sheet, err := parseCueSheet(string(content))
+		if err != nil {
+			log.Println("ERROR - Can't read CUE sheet " + cueSheetPath + ": " + err.Error())
+			continue
+		}
+
+		for _, file := range sheet.Files {
+			for i, metadata := range tracks {
+				if metadata.CueTrack == 0 && len(file.Tracks) > 0 && isCueFile(metadata.Path, file.Name) {
+					tracks = append(tracks[0:i], append(getCueTracksMetadata(metadata, sheet, file), tracks[i+1:]...)...)
+					break
+				}
+			}
+		}
+	}
+
+	return tracks
+}
+
+// Checks if a CUE sheet file entry references a media file.
+func isCueFile(mediaFilePath string, name string) bool {
+	name = filepath.Base(strings.Replace(name, "\\", "/", -1))
+	base := filepath.Base(mediaFilePath)
+
+	return strings.EqualFold(strings.TrimSuffix(name, filepath.Ext(name)), strings.TrimSuffix(base, filepath.Ext(base)))
+}
+
+// Builds the metadata of the tracks of a CUE sheet file from the metadata of the media file.
+func getCueTracksMetadata(metadata mediaMetadata, sheet cueSheet, file cueFile) (tracks []mediaMetadata) {
+	for i, track := range file.Tracks {
+		info := metadata
+		info.CueTrack = track.Number
+		info.Track = track.Number
+		info.StartMs = track.StartMs
+		info.EndMs = 0
+		info.Duration = 0
+		if i+1 < len(file.Tracks) {
+			info.EndMs = file.Tracks[i+1].StartMs
+		} else if metadata.DurationMs > info.StartMs {
+			// The last track lasts until the end of the media file, 0 if its length is unknown.
+			info.EndMs = metadata.DurationMs
+		}
+		if info.EndMs > 0 {
+			info.Duration = (info.EndMs - info.StartMs) / 1000
+		}
+
+		info.Title = track.Title
+		if info.Title == "" {
+			info.Title = "Track " + strconv.Itoa(track.Number)
+		}
+		if sheet.Title != "" {
+			info.Album = sheet.Title
+			info.AlbumSort = ""
+		}
+		if sheet.Performer != "" {
+			info.AlbumArtist = sheet.Performer
+			info.AlbumArtistSort = ""
+		}
+		performer := track.Performer
+		if performer == "" {
+			performer = sheet.Performer
+		}
+		if performer != "" {
+			info.Artist = performer
+			info.ArtistSort = ""
+			info.Artists = []artistCredit{{Name: performer, Role: domain.ArtistRoleMain}}
+		}
+		if track.Songwriter != "" {
+			info.Composer = track.Songwriter
+		}
+		if sheet.Genre != "" {
+			info.Genres = normalizeGenres([]string{sheet.Genre}, viper.GetStringSlice("Library.Genres.Separators"))
+			info.Genre = strings.Join(info.Genres, ", ")
+		}
+		if date := business.NormalizeDate(sheet.Date); date != "" {
+			info.Date = date
+			info.Year = business.DateYear(date)
+		}
+
+		// The track gain of the media file is the gain of the whole album.
+		if info.ReplayGainAlbumGain == 0 && info.ReplayGainAlbumPeak == 0 {
+			info.ReplayGainAlbumGain, info.ReplayGainAlbumPeak = metadata.ReplayGainTrackGain, metadata.ReplayGainTrackPeak
+		}
+		if sheet.ReplayGainAlbumGain != 0 || sheet.ReplayGainAlbumPeak != 0 {
+			info.ReplayGainAlbumGain, info.ReplayGainAlbumPeak = sheet.ReplayGainAlbumGain, sheet.ReplayGainAlbumPeak
+		}
+		info.ReplayGainTrackGain, info.ReplayGainTrackPeak = track.ReplayGainGain, track.ReplayGainPeak
+
+		// The identifiers and lyrics of the media file don't apply to its parts.
+		info.Isrc = track.Isrc
+		info.MusicBrainzTrackId = ""
+		info.Lyrics = ""
+		info.SyncedLyrics = nil
+
+		tracks = append(tracks, info)
+	}
+
+	return
+}
@@ -0,0 +1,173 @@
+package interfaces
+
+import (
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CueSheetTestSuite struct {
+	suite.Suite
+}
+
+// Go testing framework entry point.
+func TestCueSheetTestSuite(t *testing.T) {
+	suite.Run(t, new(CueSheetTestSuite))
+}
+
+func (suite *CueSheetTestSuite) SetupSuite() {
+	viper.Set("Library.Genres.Separators", []string{";", "/", ","})
+}
+
+const testCueSheet = "\ufeffREM GENRE Classical\r\n" +
+	"REM DATE 1994\r\n" +
+	"REM REPLAYGAIN_ALBUM_GAIN -7.25 dB\r\n" +
+	"PERFORMER \"Some Orchestra\"\r\n" +
+	"TITLE \"Symphony No. 1\"\r\n" +
+	"FILE \"Symphony.wav\" WAVE\r\n" +
+	"  TRACK 01 AUDIO\r\n" +
+	"    TITLE \"I. Allegro\"\r\n" +
+	"    SONGWRITER \"Some Composer\"\r\n" +
+	"    ISRC ABC123456789\r\n" +
+	"    REM REPLAYGAIN_TRACK_GAIN -6.50 dB\r\n" +
+	"    REM REPLAYGAIN_TRACK_PEAK 0.912\r\n" +
+	"    INDEX 01 00:00:00\r\n" +
+	"  TRACK 02 AUDIO\r\n" +
+	"    TITLE \"II. Adagio\"\r\n" +
+	"    PERFORMER \"Some Soloist\"\r\n" +
+	"    INDEX 00 09:58:00\r\n" +
+	"    INDEX 01 10:00:37\r\n" +
+	"  TRACK 03 AUDIO\r\n" +
+	"    INDEX 01 20:30:74\r\n"
+
+func (suite *CueSheetTestSuite) TestParseCueSheet() {
+	sheet, err := parseCueSheet(testCueSheet)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Symphony No. 1", sheet.Title)
+	assert.Equal(suite.T(), "Some Orchestra", sheet.Performer)
+	assert.Equal(suite.T(), "Classical", sheet.Genre)
+	assert.Equal(suite.T(), "1994", sheet.Date)
+	assert.Equal(suite.T(), -7.25, sheet.ReplayGainAlbumGain)
+	assert.Len(suite.T(), sheet.Files, 1)
+	assert.Equal(suite.T(), "Symphony.wav", sheet.Files[0].Name)
+	assert.Equal(suite.T(), []cueTrack{
+		{Number: 1, Title: "I. Allegro", Songwriter: "Some Composer", Isrc: "ABC123456789", StartMs: 0, ReplayGainGain: -6.5, ReplayGainPeak: 0.912},
+		{Number: 2, Title: "II. Adagio", Performer: "Some Soloist", StartMs: 600493},
+		{Number: 3, StartMs: 1230986},
+	}, sheet.Files[0].Tracks)
+
+	// Several files, and a pregap at the end of the previous file.
+	sheet, err = parseCueSheet("FILE \"CD1.flac\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00:00\nTRACK 02 AUDIO\nTITLE \"Second\"\nINDEX 00 04:00:00\nFILE \"CD2.flac\" WAVE\nINDEX 01 00:00:00\nTRACK 03 DATA\nINDEX 01 00:00:00")
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), sheet.Files, 2)
+	assert.Len(suite.T(), sheet.Files[0].Tracks, 1)
+	assert.Equal(suite.T(), []cueTrack{{Number: 2, Title: "Second", StartMs: 0}}, sheet.Files[1].Tracks)
+
+	// ISO-8859-1 encoding.
+	sheet, err = parseCueSheet("TITLE \"Premi\xe8re\"\n")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Première", sheet.Title)
+
+	// Invalid sheets.
+	_, err = parseCueSheet("TRACK 01 AUDIO\nINDEX 01 00:00:00")
+	assert.NotNil(suite.T(), err)
+	_, err = parseCueSheet("FILE \"Album.flac\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00")
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *CueSheetTestSuite) TestSplitCueLine() {
+	assert.Equal(suite.T(), []string{"FILE", "My Album.flac", "WAVE"}, splitCueLine("  FILE \"My Album.flac\" WAVE"))
+	assert.Equal(suite.T(), []string{"TITLE", "Unclosed quote"}, splitCueLine("TITLE \"Unclosed quote"))
+	assert.Equal(suite.T(), []string{"INDEX", "01", "00:00:00"}, splitCueLine("\tINDEX\t01   00:00:00 "))
+	assert.Empty(suite.T(), splitCueLine("   "))
+}
+
+func (suite *CueSheetTestSuite) TestParseCueTime() {
+	timeMs, err := parseCueTime("01:02:75")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 63000, timeMs)
+	timeMs, err = parseCueTime("120:00:15")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 7200200, timeMs)
+
+	_, err = parseCueTime("01:02")
+	assert.NotNil(suite.T(), err)
+	_, err = parseCueTime("01:-2:00")
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *CueSheetTestSuite) TestIsCueFile() {
+	assert.True(suite.T(), isCueFile("/music/Album/Symphony.flac", "Symphony.wav"))
+	assert.True(suite.T(), isCueFile("/music/Album/Symphony.flac", "C:\\Rips\\symphony.flac"))
+	assert.False(suite.T(), isCueFile("/music/Album/Symphony.flac", "Concerto.flac"))
+}
+
+func (suite *CueSheetTestSuite) TestGetCueTracksMetadata() {
+	metadata := mediaMetadata{
+		Path:                "/music/Album/Symphony.flac",
+		Title:               "Symphony No. 1 (complete)",
+		Album:               "Symphony",
+		Artist:              "Unknown",
+		Duration:            1500,
+		Track:               1,
+		MusicBrainzTrackId:  "9b5e1e5a-1d3c-4f1b-8f5c-0a4d6f0d0d0d",
+		Lyrics:              "First line",
+		ReplayGainTrackGain: -8,
+		ReplayGainTrackPeak: 1,
+	}
+	sheet, _ := parseCueSheet(testCueSheet)
+
+	tracks := getCueTracksMetadata(metadata, sheet, sheet.Files[0])
+	assert.Len(suite.T(), tracks, 3)
+	for _, track := range tracks {
+		assert.Equal(suite.T(), "/music/Album/Symphony.flac", track.Path)
+		assert.Equal(suite.T(), "Symphony No. 1", track.Album)
+		assert.Equal(suite.T(), "Some Orchestra", track.AlbumArtist)
+		assert.Equal(suite.T(), "Classical", track.Genre)
+		assert.Equal(suite.T(), "1994", track.Year)
+		assert.Empty(suite.T(), track.MusicBrainzTrackId)
+		assert.Empty(suite.T(), track.Lyrics)
+		assert.Equal(suite.T(), -7.25, track.ReplayGainAlbumGain)
+	}
+
+	assert.Equal(suite.T(), 1, tracks[0].CueTrack)
+	assert.Equal(suite.T(), 1, tracks[0].Track)
+	assert.Equal(suite.T(), "I. Allegro", tracks[0].Title)
+	assert.Equal(suite.T(), "Some Orchestra", tracks[0].Artist)
+	assert.Equal(suite.T(), []artistCredit{{Name: "Some Orchestra", Role: domain.ArtistRoleMain}}, tracks[0].Artists)
+	assert.Equal(suite.T(), "Some Composer", tracks[0].Composer)
+	assert.Equal(suite.T(), "ABC123456789", tracks[0].Isrc)
+	assert.Equal(suite.T(), 0, tracks[0].StartMs)
+	assert.Equal(suite.T(), 600493, tracks[0].EndMs)
+	assert.Equal(suite.T(), 600, tracks[0].Duration)
+	assert.Equal(suite.T(), -6.5, tracks[0].ReplayGainTrackGain)
+	assert.Equal(suite.T(), 0.912, tracks[0].ReplayGainTrackPeak)
+
+	assert.Equal(suite.T(), "Some Soloist", tracks[1].Artist)
+	assert.Equal(suite.T(), 1230986, tracks[1].EndMs)
+	assert.Equal(suite.T(), 0.0, tracks[1].ReplayGainTrackGain)
+
+	// The last track lasts until the end of the file, whose length is unknown.
+	assert.Equal(suite.T(), 3, tracks[2].CueTrack)
+	assert.Equal(suite.T(), "Track 3", tracks[2].Title)
+	assert.Equal(suite.T(), 1230986, tracks[2].StartMs)
+	assert.Equal(suite.T(), 0, tracks[2].EndMs)
+	assert.Equal(suite.T(), 0, tracks[2].Duration)
+
+	// With the length of the media file.
+	metadata.DurationMs = 1500250
+	tracks = getCueTracksMetadata(metadata, sheet, sheet.Files[0])
+	assert.Equal(suite.T(), 1230986, tracks[1].EndMs)
+	assert.Equal(suite.T(), 1500250, tracks[2].EndMs)
+	assert.Equal(suite.T(), 269, tracks[2].Duration)
+	metadata.DurationMs = 0
+
+	// Without album values in the sheet, the track values of the media file are the album values.
+	sheet.ReplayGainAlbumGain = 0
+	tracks = getCueTracksMetadata(metadata, sheet, sheet.Files[0])
+	assert.Equal(suite.T(), -8.0, tracks[0].ReplayGainAlbumGain)
+	assert.Equal(suite.T(), 1.0, tracks[0].ReplayGainAlbumPeak)
+}
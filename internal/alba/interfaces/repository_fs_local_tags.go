@@ -0,0 +1,837 @@
+package interfaces
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/dhowden/tag"
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+/**
+Tags of a media file.
+
+The tag library concatenates the values of ID3v2.4 multi-valued text frames and only keeps the last value of repeated
+Vorbis comments, so we read those ourselves and keep them alongside.
+*/
+type mediaTags struct {
+	tag.Metadata
+	// Values of the text frames / comments, indexed by ID3v2 frame name, "TXXX:<DESCRIPTION>" or Vorbis comment name.
+	MultiValues map[string][]string
+}
+
+/**
+Describes where to find a tag value in the raw tags of the different tag formats.
+*/
+type rawTagKeys struct {
+	// ID3v2 frames names, including the 3 letters ID3v2.2 names.
+	Id3Frames []string
+	// Descriptions of the ID3v2 user defined text frames (TXXX).
+	Id3Descriptions []string
+	// Vorbis comments names, lower case.
+	Vorbis []string
+	// MP4 atoms names, including the iTunes custom atoms names ("----").
+	Mp4 []string
+}
+
+var tagCompilation = rawTagKeys{
+	Id3Frames: []string{"TCMP", "TCP"},
+	Vorbis:    []string{"compilation"},
+	Mp4:       []string{"cpil"},
+}
+
+var tagArtist = rawTagKeys{
+	Id3Frames: []string{"TPE1", "TP1"},
+	Vorbis:    []string{"artist"},
+	Mp4:       []string{"\xa9ART"},
+}
+
+var tagRemixer = rawTagKeys{
+	Id3Frames: []string{"TPE4", "TP4"},
+	Vorbis:    []string{"remixer"},
+	Mp4:       []string{"REMIXER"},
+}
+
+var tagGenre = rawTagKeys{
+	Id3Frames: []string{"TCON", "TCO"},
+	Vorbis:    []string{"genre"},
+	Mp4:       []string{"\xa9gen"},
+}
+
+var tagMusicBrainzTrackId = rawTagKeys{
+	Id3Descriptions: []string{"MusicBrainz Track Id"},
+	Vorbis:          []string{"musicbrainz_trackid"},
+	Mp4:             []string{"MusicBrainz Track Id"},
+}
+
+var tagMusicBrainzAlbumId = rawTagKeys{
+	Id3Descriptions: []string{"MusicBrainz Album Id"},
+	Vorbis:          []string{"musicbrainz_albumid"},
+	Mp4:             []string{"MusicBrainz Album Id"},
+}
+
+var tagMusicBrainzArtistId = rawTagKeys{
+	Id3Descriptions: []string{"MusicBrainz Artist Id"},
+	Vorbis:          []string{"musicbrainz_artistid"},
+	Mp4:             []string{"MusicBrainz Artist Id"},
+}
+
+var tagIsrc = rawTagKeys{
+	Id3Frames:       []string{"TSRC", "TRC"},
+	Id3Descriptions: []string{"ISRC"},
+	Vorbis:          []string{"isrc"},
+	Mp4:             []string{"ISRC"},
+}
+
+var tagLabel = rawTagKeys{
+	Id3Frames:       []string{"TPUB", "TPB"},
+	Id3Descriptions: []string{"LABEL"},
+	Vorbis:          []string{"label", "organization", "publisher"},
+	Mp4:             []string{"LABEL"},
+}
+
+var tagCatalogNumber = rawTagKeys{
+	Id3Descriptions: []string{"CATALOGNUMBER"},
+	Vorbis:          []string{"catalognumber"},
+	Mp4:             []string{"CATALOGNUMBER"},
+}
+
+var tagDate = rawTagKeys{
+	Id3Frames: []string{"TDRC", "TYER", "TYE"},
+	Vorbis:    []string{"date", "year"},
+	Mp4:       []string{"\xa9day"},
+}
+
+// Day and month of the release date in ID3v2.3 tags.
+var tagDateDayMonth = rawTagKeys{
+	Id3Frames: []string{"TDAT", "TDA"},
+}
+
+var tagOriginalDate = rawTagKeys{
+	Id3Frames:       []string{"TDOR", "TORY", "TOR"},
+	Id3Descriptions: []string{"ORIGINALDATE", "originalyear"},
+	Vorbis:          []string{"originaldate", "originalyear"},
+	Mp4:             []string{"ORIGINALDATE", "originalyear"},
+}
+
+var tagArtistSort = rawTagKeys{
+	Id3Frames: []string{"TSOP", "TSP", "XSOP"},
+	Vorbis:    []string{"artistsort"},
+	Mp4:       []string{"soar"},
+}
+
+var tagAlbumArtistSort = rawTagKeys{
+	Id3Frames:       []string{"TSO2", "TS2"},
+	Id3Descriptions: []string{"ALBUMARTISTSORT"},
+	Vorbis:          []string{"albumartistsort"},
+	Mp4:             []string{"soaa"},
+}
+
+var tagAlbumSort = rawTagKeys{
+	Id3Frames: []string{"TSOA", "TSA", "XSOA"},
+	Vorbis:    []string{"albumsort"},
+	Mp4:       []string{"soal"},
+}
+
+var tagComposer = rawTagKeys{
+	Id3Frames: []string{"TCOM", "TCM"},
+	Vorbis:    []string{"composer"},
+	Mp4:       []string{"\xa9wrt"},
+}
+
+var tagConductor = rawTagKeys{
+	Id3Frames: []string{"TPE3", "TP3"},
+	Vorbis:    []string{"conductor"},
+	Mp4:       []string{"CONDUCTOR"},
+}
+
+var tagBpm = rawTagKeys{
+	Id3Frames: []string{"TBPM", "TBP"},
+	Vorbis:    []string{"bpm"},
+	Mp4:       []string{"tmpo"},
+}
+
+var tagLyrics = rawTagKeys{
+	Id3Frames: []string{"USLT", "ULT"},
+	Vorbis:    []string{"lyrics", "unsyncedlyrics"},
+	Mp4:       []string{"\xa9lyr"},
+}
+
+var tagReplayGainTrackGain = rawTagKeys{
+	Id3Descriptions: []string{"REPLAYGAIN_TRACK_GAIN"},
+	Vorbis:          []string{"replaygain_track_gain"},
+	Mp4:             []string{"replaygain_track_gain", "REPLAYGAIN_TRACK_GAIN"},
+}
+
+var tagReplayGainTrackPeak = rawTagKeys{
+	Id3Descriptions: []string{"REPLAYGAIN_TRACK_PEAK"},
+	Vorbis:          []string{"replaygain_track_peak"},
+	Mp4:             []string{"replaygain_track_peak", "REPLAYGAIN_TRACK_PEAK"},
+}
+
+var tagReplayGainAlbumGain = rawTagKeys{
+	Id3Descriptions: []string{"REPLAYGAIN_ALBUM_GAIN"},
+	Vorbis:          []string{"replaygain_album_gain"},
+	Mp4:             []string{"replaygain_album_gain", "REPLAYGAIN_ALBUM_GAIN"},
+}
+
+var tagReplayGainAlbumPeak = rawTagKeys{
+	Id3Descriptions: []string{"REPLAYGAIN_ALBUM_PEAK"},
+	Vorbis:          []string{"replaygain_album_peak"},
+	Mp4:             []string{"replaygain_album_peak", "REPLAYGAIN_ALBUM_PEAK"},
+}
+
+// Opus files gains, see getReplayGain().
+var tagR128TrackGain = rawTagKeys{
+	Vorbis: []string{"r128_track_gain"},
+}
+
+var tagR128AlbumGain = rawTagKeys{
+	Vorbis: []string{"r128_album_gain"},
+}
+
+/*
+Gets a tag value from the raw tags of a media file.
+
+Returns the first value found for the given keys, or an empty string.
+*/
+func getRawTag(tags mediaTags, keys rawTagKeys) string {
+	values := getRawTagValues(tags, keys)
+	if len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+/*
+Gets all the values of a tag from the raw tags of a media file.
+
+Values are returned in the order of the given keys. Some formats allow a tag to be repeated: ID3v2 frames are stored
+in the raw map as <name>, <name>_0, <name>_1, etc.
+*/
+func getRawTagValues(tags mediaTags, keys rawTagKeys) (values []string) {
+	raw := tags.Raw()
+
+	switch tags.Format() {
+	case tag.ID3v2_2, tag.ID3v2_3, tag.ID3v2_4:
+		for _, frame := range keys.Id3Frames {
+			if multiValues, ok := tags.MultiValues[frame]; ok {
+				values = append(values, multiValues...)
+				continue
+			}
+			for _, value := range getId3Frames(raw, frame) {
+				if text := rawValueToString(value); text != "" {
+					values = append(values, text)
+				}
+			}
+		}
+		for _, description := range keys.Id3Descriptions {
+			if multiValues, ok := tags.MultiValues["TXXX:"+strings.ToUpper(description)]; ok {
+				values = append(values, multiValues...)
+				continue
+			}
+			for _, frame := range []string{"TXXX", "TXX"} {
+				for _, value := range getId3Frames(raw, frame) {
+					if comm, ok := value.(*tag.Comm); ok && strings.EqualFold(comm.Description, description) {
+						if text := sanitizeString(comm.Text); text != "" {
+							values = append(values, text)
+						}
+					}
+				}
+			}
+		}
+		// MusicBrainz recording ids are stored in a dedicated frame.
+		if len(values) == 0 && containsString(keys.Id3Descriptions, "MusicBrainz Track Id") {
+			for _, frame := range []string{"UFID", "UFI"} {
+				for _, value := range getId3Frames(raw, frame) {
+					if ufid, ok := value.(*tag.UFID); ok && ufid.Provider == "http://musicbrainz.org" {
+						values = append(values, sanitizeString(string(ufid.Identifier)))
+					}
+				}
+			}
+		}
+
+	case tag.VORBIS:
+		for _, key := range keys.Vorbis {
+			if multiValues, ok := tags.MultiValues[key]; ok {
+				values = append(values, multiValues...)
+				continue
+			}
+			if text := rawValueToString(raw[key]); text != "" {
+				values = append(values, text)
+			}
+		}
+
+	case tag.MP4:
+		for _, key := range keys.Mp4 {
+			if text := rawValueToString(raw[key]); text != "" {
+				values = append(values, text)
+			}
+		}
+	}
+
+	return
+}
+
+/*
+Gets all the genres of a media file.
+
+Falls back to the tag library genre if the file has only one genre, as it knows how to deal with ID3v1 genres ids.
+*/
+func getGenres(tags mediaTags) []string {
+	values := getRawTagValues(tags, tagGenre)
+	if len(values) < 2 {
+		if genre := sanitizeString(tags.Genre()); genre != "" {
+			return []string{genre}
+		}
+	}
+
+	return values
+}
+
+/*
+Splits genres on the given separators and normalises them.
+
+Whitespaces are collapsed, and duplicates are removed regardless of their case.
+*/
+func normalizeGenres(values []string, separators []string) (genres []string) {
+	for _, separator := range separators {
+		var split []string
+		for _, value := range values {
+			split = append(split, strings.Split(value, separator)...)
+		}
+		values = split
+	}
+
+	known := make(map[string]bool)
+	for _, value := range values {
+		genre := strings.Join(strings.Fields(value), " ")
+		key := strings.ToLower(genre)
+		if genre != "" && !known[key] {
+			known[key] = true
+			genres = append(genres, genre)
+		}
+	}
+
+	return
+}
+
+/**
+Credit of an artist on a media file.
+*/
+type artistCredit struct {
+	Name string
+	Role string
+}
+
+// Default strings separating the artists of a single artist tag, see Library.Artists.Separators. "&" is left out as
+// it's part of many band names like "Simon & Garfunkel", so it has to be opted in.
+var DefaultArtistSeparators = []string{";"}
+
+/*
+Gets all the artists credited on a media file with their role.
+
+Main artists come first, in the order of the tags. Featured artists are extracted from the artist tags, for example
+"Artist A feat. Artist B".
+*/
+func getArtistCredits(tags mediaTags, separators []string, featuringSeparators []string) (credits []artistCredit) {
+	artists := getRawTagValues(tags, tagArtist)
+	if len(artists) < 2 {
+		artists = []string{tags.Artist()}
+	}
+
+	var featured []string
+	for _, value := range artists {
+		main, featuring := splitFeaturing(value, featuringSeparators)
+		credits = appendArtistCredits(credits, splitArtists([]string{main}, separators), domain.ArtistRoleMain)
+		featured = append(featured, featuring...)
+	}
+	credits = appendArtistCredits(credits, splitArtists(featured, separators), domain.ArtistRoleFeatured)
+	credits = appendArtistCredits(credits, splitArtists(getRawTagValues(tags, tagRemixer), separators), domain.ArtistRoleRemixer)
+	credits = appendArtistCredits(credits, splitArtists(getRawTagValues(tags, tagComposer), separators), domain.ArtistRoleComposer)
+
+	return
+}
+
+/*
+Splits the featured artists from an artist tag value.
+
+"Artist A feat. Artist B & Artist C" returns "Artist A" and ["Artist B & Artist C"]. Featuring separators are case
+insensitive and must be preceded by a whitespace or an opening bracket.
+*/
+func splitFeaturing(value string, featuringSeparators []string) (main string, featured []string) {
+	if len(featuringSeparators) == 0 {
+		return value, nil
+	}
+
+	var quoted []string
+	for _, separator := range featuringSeparators {
+		quoted = append(quoted, regexp.QuoteMeta(separator))
+	}
+	featuringRegexp := regexp.MustCompile(`(?i)[\s(\[]+(?:` + strings.Join(quoted, "|") + `)\s*`)
+
+	parts := featuringRegexp.Split(value, -1)
+	for i := 1; i < len(parts); i++ {
+		featured = append(featured, strings.Trim(parts[i], " )]"))
+	}
+
+	return parts[0], featured
+}
+
+/*
+Splits artists names on the given separators.
+
+Whitespaces are collapsed and empty names are ignored.
+*/
+func splitArtists(values []string, separators []string) (artists []string) {
+	for _, separator := range separators {
+		var split []string
+		for _, value := range values {
+			split = append(split, strings.Split(value, separator)...)
+		}
+		values = split
+	}
+
+	for _, value := range values {
+		if artist := strings.Join(strings.Fields(sanitizeString(value)), " "); artist != "" {
+			artists = append(artists, artist)
+		}
+	}
+
+	return
+}
+
+// Appends artists to credits with the given role, ignoring the artists already credited with this role.
+func appendArtistCredits(credits []artistCredit, artists []string, role string) []artistCredit {
+	for _, artist := range artists {
+		known := false
+		for _, credit := range credits {
+			if credit.Role == role && strings.EqualFold(credit.Name, artist) {
+				known = true
+				break
+			}
+		}
+
+		if !known {
+			credits = append(credits, artistCredit{Name: artist, Role: role})
+		}
+	}
+
+	return credits
+}
+
+/*
+Gets the ReplayGain values of a media file.
+
+Opus files can have R128 gains instead, stored as Q7.8 fixed-point numbers and relative to -23 LUFS, while ReplayGain
+gains are relative to -18 LUFS. They don't have peaks.
+*/
+func getReplayGain(tags mediaTags) (trackGain float64, trackPeak float64, albumGain float64, albumPeak float64) {
+	trackGain = parseReplayGainValue(getRawTag(tags, tagReplayGainTrackGain))
+	trackPeak = parseReplayGainValue(getRawTag(tags, tagReplayGainTrackPeak))
+	albumGain = parseReplayGainValue(getRawTag(tags, tagReplayGainAlbumGain))
+	albumPeak = parseReplayGainValue(getRawTag(tags, tagReplayGainAlbumPeak))
+
+	if trackGain == 0 {
+		if r128, err := strconv.Atoi(strings.TrimSpace(getRawTag(tags, tagR128TrackGain))); err == nil {
+			trackGain = float64(r128)/256 + 5
+		}
+	}
+	if albumGain == 0 {
+		if r128, err := strconv.Atoi(strings.TrimSpace(getRawTag(tags, tagR128AlbumGain))); err == nil {
+			albumGain = float64(r128)/256 + 5
+		}
+	}
+
+	return
+}
+
+// Parses a ReplayGain gain like "-6.48 dB" or peak like "0.988235", returning 0 if the value is invalid.
+func parseReplayGainValue(value string) float64 {
+	value = strings.TrimSpace(value)
+	if len(value) > 2 && strings.EqualFold(value[len(value)-2:], "dB") {
+		value = strings.TrimSpace(value[0 : len(value)-2])
+	}
+	number, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0
+	}
+
+	return number
+}
+
+/*
+Gets the lyrics of a media file.
+
+Time-synced lyrics are read from the ID3v2 SYLT frames, or from the lyrics tag if it is in the LRC format. The text of
+the lyrics falls back to the text of the time-synced lyrics.
+*/
+func getLyrics(tags mediaTags) (text string, synced []domain.LyricsLine) {
+	text = normalizeLyrics(getRawTag(tags, tagLyrics))
+	if business.IsLrc(text) {
+		synced = business.ParseLrc(text)
+		text = ""
+	}
+
+	for _, frame := range []string{"SYLT", "SLT"} {
+		for _, value := range getId3Frames(tags.Raw(), frame) {
+			if content, ok := value.([]byte); ok && len(synced) == 0 {
+				synced, _ = parseSyltFrame(content)
+			}
+		}
+	}
+
+	if text == "" {
+		text = business.LyricsText(synced)
+	}
+
+	return
+}
+
+// Normalises the line endings of lyrics and removes the byte order mark of lyrics files.
+func normalizeLyrics(lyrics string) string {
+	lyrics = strings.TrimPrefix(lyrics, "\ufeff")
+	lyrics = strings.Replace(lyrics, "\r\n", "\n", -1)
+	lyrics = strings.Replace(lyrics, "\r", "\n", -1)
+
+	return strings.TrimSpace(lyrics)
+}
+
+/*
+Parses the content of an ID3v2 synchronised lyrics frame (SYLT).
+
+Only the timestamps expressed in milliseconds are supported. Returns the lines ordered by time.
+*/
+func parseSyltFrame(b []byte) (lines []domain.LyricsLine, err error) {
+	// Encoding, language, timestamp format and content type.
+	if len(b) < 6 {
+		return nil, errors.New("invalid SYLT frame")
+	}
+	encoding := b[0]
+	if b[4] != 2 {
+		return nil, errors.New("unsupported SYLT timestamp format")
+	}
+
+	// Skip the content descriptor.
+	_, data, ok := cutNullTerminatedText(encoding, b[6:])
+	if !ok {
+		return nil, errors.New("invalid SYLT frame")
+	}
+
+	for len(data) > 0 {
+		var text string
+		text, data, ok = cutNullTerminatedText(encoding, data)
+		if !ok || len(data) < 4 {
+			return nil, errors.New("invalid SYLT frame")
+		}
+		time := int(binary.BigEndian.Uint32(data[0:4]))
+		data = data[4:]
+
+		// Lines usually start with a line feed.
+		lines = append(lines, domain.LyricsLine{TimeMs: time, Line: strings.TrimSpace(text)})
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].TimeMs < lines[j].TimeMs })
+
+	return
+}
+
+// Reads a null terminated string from the content of an ID3v2 frame.
+//
+// Returns the decoded string and what follows it.
+func cutNullTerminatedText(encoding byte, b []byte) (text string, rest []byte, ok bool) {
+	// UTF-16 strings are terminated by two null bytes.
+	terminatorSize := 1
+	if encoding == 1 || encoding == 2 {
+		terminatorSize = 2
+	}
+
+	for i := 0; i+terminatorSize <= len(b); i += terminatorSize {
+		if b[i] == 0 && b[i+terminatorSize-1] == 0 {
+			return splitNullTerminatedText(encoding, b[0:i])[0], b[i+terminatorSize:], true
+		}
+	}
+
+	return "", nil, false
+}
+
+// Gets all the ID3v2 frames having the given name, in the order they appear in the file.
+func getId3Frames(raw map[string]interface{}, name string) (frames []interface{}) {
+	if value, ok := raw[name]; ok {
+		frames = append(frames, value)
+	}
+	for i := 0; ; i++ {
+		value, ok := raw[name+"_"+strconv.Itoa(i)]
+		if !ok {
+			break
+		}
+		frames = append(frames, value)
+	}
+
+	return
+}
+
+// Converts a raw tag value to a string if possible.
+func rawValueToString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return sanitizeString(v)
+	case int:
+		return strconv.Itoa(v)
+	case *tag.Comm:
+		return sanitizeString(v.Text)
+	}
+
+	return ""
+}
+
+// Checks if a string slice contains a string.
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Reads the multi-valued text tags of a media file.
+
+Supports ID3v2 tags and FLAC Vorbis comments. Returns nil if the format is not supported or the tags cannot be read, in
+which case the values from the tag library should be used.
+*/
+func readMultiValueTags(file io.ReadSeeker, tags tag.Metadata) map[string][]string {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil
+	}
+
+	var values map[string][]string
+	var err error
+	switch {
+	case tags.Format() == tag.ID3v2_2 || tags.Format() == tag.ID3v2_3 || tags.Format() == tag.ID3v2_4:
+		values, err = readId3v2TextFrames(file)
+	case tags.FileType() == tag.FLAC:
+		values, err = readFlacVorbisComments(file)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return values
+}
+
+// Reads all the text frames of an ID3v2 tag located at the beginning of a file.
+func readId3v2TextFrames(r io.Reader) (values map[string][]string, err error) {
+	header := make([]byte, 10)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return
+	}
+	if string(header[0:3]) != "ID3" {
+		return nil, errors.New("no ID3v2 tag found")
+	}
+
+	version := header[3]
+	flags := header[5]
+	// Unsynchronised tags are rare enough to let the tag library deal with them.
+	if flags&0x80 != 0 || version < 2 || version > 4 {
+		return nil, errors.New("unsupported ID3v2 tag")
+	}
+
+	data := make([]byte, syncsafeInt(header[6:10]))
+	if _, err = io.ReadFull(r, data); err != nil {
+		return
+	}
+
+	// Skip the extended header.
+	if flags&0x40 != 0 && version > 2 && len(data) >= 4 {
+		extendedHeaderSize := int(binary.BigEndian.Uint32(data[0:4])) + 4
+		if version == 4 {
+			extendedHeaderSize = syncsafeInt(data[0:4])
+		}
+		if extendedHeaderSize > len(data) {
+			return nil, errors.New("invalid ID3v2 extended header")
+		}
+		data = data[extendedHeaderSize:]
+	}
+
+	nameSize, headerSize := 4, 10
+	if version == 2 {
+		nameSize, headerSize = 3, 6
+	}
+
+	values = make(map[string][]string)
+	for len(data) > headerSize && data[0] != 0 {
+		name := string(data[0:nameSize])
+		var size int
+		var frameFlags uint16
+		switch version {
+		case 2:
+			size = int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+		case 3:
+			size = int(binary.BigEndian.Uint32(data[4:8]))
+			frameFlags = binary.BigEndian.Uint16(data[8:10])
+		case 4:
+			size = syncsafeInt(data[4:8])
+			frameFlags = binary.BigEndian.Uint16(data[8:10])
+		}
+		if size < 0 || headerSize+size > len(data) {
+			return nil, errors.New("invalid ID3v2 frame size")
+		}
+		content := data[headerSize : headerSize+size]
+		data = data[headerSize+size:]
+
+		// Ignore compressed, encrypted or unsynchronised frames.
+		if (version == 3 && frameFlags&0x00c0 != 0) || (version == 4 && frameFlags&0x000e != 0) {
+			continue
+		}
+		if name[0] != 'T' || len(content) < 1 {
+			continue
+		}
+
+		texts := splitNullTerminatedText(content[0], content[1:])
+		if name == "TXXX" || name == "TXX" {
+			if len(texts) < 2 {
+				continue
+			}
+			name = "TXXX:" + strings.ToUpper(texts[0])
+			texts = texts[1:]
+		}
+		for _, text := range texts {
+			if text = sanitizeString(text); text != "" {
+				values[name] = append(values[name], text)
+			}
+		}
+	}
+
+	return
+}
+
+// Decodes an ID3v2 text frame content and splits the null separated values.
+func splitNullTerminatedText(encoding byte, b []byte) []string {
+	var text string
+	switch encoding {
+	case 1, 2:
+		// UTF-16, with or without byte order mark.
+		var byteOrder binary.ByteOrder = binary.BigEndian
+		if len(b) >= 2 && b[0] == 0xff && b[1] == 0xfe {
+			byteOrder = binary.LittleEndian
+		}
+		if len(b) >= 2 && ((b[0] == 0xff && b[1] == 0xfe) || (b[0] == 0xfe && b[1] == 0xff)) {
+			b = b[2:]
+		}
+		units := make([]uint16, 0, len(b)/2)
+		for i := 0; i+1 < len(b); i += 2 {
+			unit := byteOrder.Uint16(b[i:])
+			// Each value can start with its own byte order mark.
+			if unit == 0xfeff || unit == 0xfffe {
+				continue
+			}
+			units = append(units, unit)
+		}
+		text = string(utf16.Decode(units))
+	case 3:
+		text = string(b)
+	default:
+		// ISO-8859-1.
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		text = string(runes)
+	}
+
+	return strings.Split(strings.TrimRight(text, "\x00"), "\x00")
+}
+
+// Reads the Vorbis comments of a FLAC file.
+func readFlacVorbisComments(r io.Reader) (values map[string][]string, err error) {
+	marker := make([]byte, 4)
+	if _, err = io.ReadFull(r, marker); err != nil {
+		return
+	}
+	if string(marker) != "fLaC" {
+		return nil, errors.New("not a FLAC file")
+	}
+
+	for {
+		blockHeader := make([]byte, 4)
+		if _, err = io.ReadFull(r, blockHeader); err != nil {
+			return
+		}
+		last := blockHeader[0]&0x80 != 0
+		blockType := blockHeader[0] & 0x7f
+		size := int(blockHeader[1])<<16 | int(blockHeader[2])<<8 | int(blockHeader[3])
+		block := make([]byte, size)
+		if _, err = io.ReadFull(r, block); err != nil {
+			return
+		}
+
+		if blockType == 4 {
+			return parseVorbisComments(block)
+		}
+		if last {
+			return nil, errors.New("no Vorbis comments found")
+		}
+	}
+}
+
+// Parses a Vorbis comments block.
+func parseVorbisComments(block []byte) (values map[string][]string, err error) {
+	reader := bytes.NewReader(block)
+	var vendorLength uint32
+	if err = binary.Read(reader, binary.LittleEndian, &vendorLength); err != nil {
+		return
+	}
+	if _, err = reader.Seek(int64(vendorLength), io.SeekCurrent); err != nil {
+		return
+	}
+
+	var count uint32
+	if err = binary.Read(reader, binary.LittleEndian, &count); err != nil {
+		return
+	}
+
+	values = make(map[string][]string)
+	for i := uint32(0); i < count; i++ {
+		var length uint32
+		if err = binary.Read(reader, binary.LittleEndian, &length); err != nil {
+			return
+		}
+		if int64(length) > int64(reader.Len()) {
+			return nil, errors.New("invalid Vorbis comment length")
+		}
+		comment := make([]byte, length)
+		if _, err = io.ReadFull(reader, comment); err != nil {
+			return
+		}
+
+		parts := strings.SplitN(string(comment), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToLower(parts[0])
+		if value := sanitizeString(parts[1]); value != "" {
+			values[key] = append(values[key], value)
+		}
+	}
+
+	return
+}
+
+// Decodes a 4 bytes syncsafe integer, as used by ID3v2.
+func syncsafeInt(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
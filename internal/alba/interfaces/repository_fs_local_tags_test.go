@@ -0,0 +1,324 @@
+package interfaces
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/dhowden/tag"
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RawTagsTestSuite struct {
+	suite.Suite
+}
+
+// Go testing framework entry point.
+func TestRawTagsTestSuite(t *testing.T) {
+	suite.Run(t, new(RawTagsTestSuite))
+}
+
+func (suite *RawTagsTestSuite) readTags(path string) mediaTags {
+	file, err := os.Open(path)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	defer file.Close()
+
+	metadata, err := tag.ReadFrom(file)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	return mediaTags{Metadata: metadata, MultiValues: readMultiValueTags(file, metadata)}
+}
+
+func (suite *RawTagsTestSuite) TestGetRawTagId3() {
+	tags := suite.readTags(TestFSLibDir + "/artist 4/artist 4 - album 1/Artist 4 - Album 1 - Track 1.mp3")
+
+	// UFID frame.
+	assert.Equal(suite.T(), "9a1b2c3d-0000-4000-8000-000000000001", getRawTag(tags, tagMusicBrainzTrackId))
+	// TXXX frames.
+	assert.Equal(suite.T(), "9a1b2c3d-0000-4000-8000-000000000002", getRawTag(tags, tagMusicBrainzAlbumId))
+	assert.Equal(suite.T(), "9a1b2c3d-0000-4000-8000-000000000003", getRawTag(tags, tagMusicBrainzArtistId))
+	assert.Equal(suite.T(), "CAT-001", getRawTag(tags, tagCatalogNumber))
+	// Text frames.
+	assert.Equal(suite.T(), "FRZ011200001", getRawTag(tags, tagIsrc))
+	assert.Equal(suite.T(), "Label #1", getRawTag(tags, tagLabel))
+	assert.Equal(suite.T(), "1999-03-01", getRawTag(tags, tagOriginalDate))
+	assert.Equal(suite.T(), "Composer #1", getRawTag(tags, tagComposer))
+	assert.Equal(suite.T(), "Conductor #1", getRawTag(tags, tagConductor))
+	assert.Equal(suite.T(), "120", getRawTag(tags, tagBpm))
+	// Missing tag.
+	assert.Empty(suite.T(), getRawTag(tags, tagCompilation))
+}
+
+func (suite *RawTagsTestSuite) TestGetRawTagVorbis() {
+	tags := suite.readTags(TestFSLibDir + "/artist 4/artist 4 - album 2/Artist 4 - Album 2 - Track 1.flac")
+
+	assert.Equal(suite.T(), "9a1b2c3d-0000-4000-8000-000000000011", getRawTag(tags, tagMusicBrainzTrackId))
+	assert.Equal(suite.T(), "9a1b2c3d-0000-4000-8000-000000000012", getRawTag(tags, tagMusicBrainzAlbumId))
+	assert.Equal(suite.T(), "9a1b2c3d-0000-4000-8000-000000000013", getRawTag(tags, tagMusicBrainzArtistId))
+	assert.Equal(suite.T(), "FRZ011300001", getRawTag(tags, tagIsrc))
+	assert.Equal(suite.T(), "Label #2", getRawTag(tags, tagLabel))
+	assert.Equal(suite.T(), "CAT-002", getRawTag(tags, tagCatalogNumber))
+	assert.Equal(suite.T(), "2001", getRawTag(tags, tagOriginalDate))
+	assert.Equal(suite.T(), "Composer #2", getRawTag(tags, tagComposer))
+	assert.Equal(suite.T(), "Conductor #2", getRawTag(tags, tagConductor))
+	assert.Equal(suite.T(), "98.6", getRawTag(tags, tagBpm))
+}
+
+func (suite *RawTagsTestSuite) TestGetGenres() {
+	// Null-separated ID3v2.4 values.
+	tags := suite.readTags(TestFSLibDir + "/artist 4/artist 4 - album 1/Artist 4 - Album 1 - Track 2.mp3")
+	assert.Equal(suite.T(), []string{"Rock", "alternative   rock; Indie"}, getGenres(tags))
+
+	// Repeated Vorbis comments.
+	tags = suite.readTags(TestFSLibDir + "/artist 4/artist 4 - album 2/Artist 4 - Album 2 - Track 2.flac")
+	assert.Equal(suite.T(), []string{"Rock", "Jazz"}, getGenres(tags))
+}
+
+func (suite *RawTagsTestSuite) TestNormalizeGenres() {
+	separators := []string{";", "/"}
+
+	genres := normalizeGenres([]string{"Rock", "alternative   rock; Indie"}, separators)
+	assert.Equal(suite.T(), []string{"Rock", "alternative rock", "Indie"}, genres)
+
+	// Duplicates are removed case-insensitively, empty values are ignored.
+	genres = normalizeGenres([]string{"Rock / rock", " ", "ROCK;Jazz;"}, separators)
+	assert.Equal(suite.T(), []string{"Rock", "Jazz"}, genres)
+
+	assert.Empty(suite.T(), normalizeGenres(nil, separators))
+}
+
+func (suite *RawTagsTestSuite) TestGetArtistCredits() {
+	tags := suite.readTags(TestFSLibDir + "/artist 5/Artist 5 - Album 1 - Track 1.mp3")
+	credits := getArtistCredits(tags, []string{";", "&"}, []string{"feat.", "ft."})
+	assert.Equal(suite.T(), []artistCredit{
+		{Name: "Artist #5", Role: domain.ArtistRoleMain},
+		{Name: "Artist #6", Role: domain.ArtistRoleFeatured},
+		{Name: "Artist #7", Role: domain.ArtistRoleFeatured},
+		{Name: "Artist #8", Role: domain.ArtistRoleRemixer},
+		{Name: "Artist #5", Role: domain.ArtistRoleComposer},
+	}, credits)
+}
+
+func (suite *RawTagsTestSuite) TestSplitFeaturing() {
+	separators := []string{"feat.", "ft.", "featuring"}
+
+	main, featured := splitFeaturing("Artist A feat. Artist B", separators)
+	assert.Equal(suite.T(), "Artist A", main)
+	assert.Equal(suite.T(), []string{"Artist B"}, featured)
+
+	// Separators are case insensitive and can be enclosed in brackets.
+	main, featured = splitFeaturing("Artist A (Ft. Artist B)", separators)
+	assert.Equal(suite.T(), "Artist A", main)
+	assert.Equal(suite.T(), []string{"Artist B"}, featured)
+
+	// Separators must not be part of a word.
+	main, featured = splitFeaturing("Daft.Punk", separators)
+	assert.Equal(suite.T(), "Daft.Punk", main)
+	assert.Empty(suite.T(), featured)
+}
+
+func (suite *RawTagsTestSuite) TestSplitArtists() {
+	artists := splitArtists([]string{"Artist A & Artist B;  Artist C", " "}, []string{";", "&"})
+	assert.Equal(suite.T(), []string{"Artist A", "Artist B", "Artist C"}, artists)
+
+	assert.Equal(suite.T(), []string{"AC/DC"}, splitArtists([]string{"AC/DC"}, []string{";", "&"}))
+}
+
+func (suite *RawTagsTestSuite) TestSplitArtistsDefaultSeparators() {
+	// Band names stay whole unless "&" is opted in.
+	artists := splitArtists([]string{"Simon & Garfunkel", "Earth, Wind & Fire; Artist C"}, DefaultArtistSeparators)
+	assert.Equal(suite.T(), []string{"Simon & Garfunkel", "Earth, Wind & Fire", "Artist C"}, artists)
+
+	credits := appendArtistCredits(nil, artists, domain.ArtistRoleMain)
+	assert.Len(suite.T(), credits, 3)
+	assert.Equal(suite.T(), "Simon & Garfunkel", credits[0].Name)
+}
+
+func (suite *RawTagsTestSuite) TestGetLyrics() {
+	directory, err := ioutil.TempDir("", "alba-lyrics")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	// Unsynchronised and synchronised lyrics frames.
+	path := filepath.Join(directory, "lyrics.mp3")
+	data := buildTestId3v24File([]id3v2Frame{
+		{Name: "TIT2", Content: []byte("\x03Title")},
+		{Name: "USLT", Content: []byte("\x03eng\x00First line\r\nSecond line")},
+		{Name: "SYLT", Content: buildTestSyltFrame(3, []string{"First line", "Second line"}, []uint32{1000, 2500})},
+	})
+	if err = ioutil.WriteFile(path, data, 0644); err != nil {
+		suite.T().Fatal(err)
+	}
+	text, synced := getLyrics(suite.readTags(path))
+	assert.Equal(suite.T(), "First line\nSecond line", text)
+	assert.Equal(suite.T(), []domain.LyricsLine{{TimeMs: 1000, Line: "First line"}, {TimeMs: 2500, Line: "Second line"}}, synced)
+
+	// LRC lyrics in the unsynchronised lyrics frame.
+	data = buildTestId3v24File([]id3v2Frame{
+		{Name: "USLT", Content: []byte("\x03eng\x00[00:01.00]First line\n[00:02.50]Second line")},
+	})
+	if err = ioutil.WriteFile(path, data, 0644); err != nil {
+		suite.T().Fatal(err)
+	}
+	text, synced = getLyrics(suite.readTags(path))
+	assert.Equal(suite.T(), "First line\nSecond line", text)
+	assert.Equal(suite.T(), []domain.LyricsLine{{TimeMs: 1000, Line: "First line"}, {TimeMs: 2500, Line: "Second line"}}, synced)
+
+	// No lyrics.
+	text, synced = getLyrics(suite.readTags(TestFSLibDir + "/artist 2/Artist 2 - Album 1 - Track 1.mp3"))
+	assert.Empty(suite.T(), text)
+	assert.Empty(suite.T(), synced)
+}
+
+func (suite *RawTagsTestSuite) TestParseSyltFrame() {
+	lines, err := parseSyltFrame(buildTestSyltFrame(3, []string{"Second line", "First line", ""}, []uint32{2500, 1000, 4000}))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []domain.LyricsLine{
+		{TimeMs: 1000, Line: "First line"},
+		{TimeMs: 2500, Line: "Second line"},
+		{TimeMs: 4000, Line: ""},
+	}, lines)
+
+	// UTF-16 text.
+	lines, err = parseSyltFrame(buildTestSyltFrame(1, []string{"Première ligne"}, []uint32{1000}))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []domain.LyricsLine{{TimeMs: 1000, Line: "Première ligne"}}, lines)
+
+	// Timestamps in MPEG frames.
+	content := buildTestSyltFrame(3, []string{"First line"}, []uint32{10})
+	content[4] = 1
+	_, err = parseSyltFrame(content)
+	assert.NotNil(suite.T(), err)
+
+	// Truncated frame.
+	content = buildTestSyltFrame(3, []string{"First line"}, []uint32{1000})
+	_, err = parseSyltFrame(content[0 : len(content)-2])
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *RawTagsTestSuite) TestGetReplayGain() {
+	directory, err := ioutil.TempDir("", "alba-replaygain")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	// ID3v2 user defined text frames, whatever their case.
+	path := filepath.Join(directory, "replaygain.mp3")
+	data := buildTestId3v24File([]id3v2Frame{
+		{Name: "TXXX", Content: []byte("\x03REPLAYGAIN_TRACK_GAIN\x00-6.48 dB")},
+		{Name: "TXXX", Content: []byte("\x03replaygain_track_peak\x00 0.988235")},
+		{Name: "TXXX", Content: []byte("\x03REPLAYGAIN_ALBUM_GAIN\x00+1,20 dB")},
+	})
+	if err = ioutil.WriteFile(path, data, 0644); err != nil {
+		suite.T().Fatal(err)
+	}
+	trackGain, trackPeak, albumGain, albumPeak := getReplayGain(suite.readTags(path))
+	assert.Equal(suite.T(), -6.48, trackGain)
+	assert.Equal(suite.T(), 0.988235, trackPeak)
+	assert.Equal(suite.T(), 1.2, albumGain)
+	assert.Equal(suite.T(), 0.0, albumPeak)
+
+	// Opus R128 gains.
+	path = filepath.Join(directory, "replaygain.flac")
+	if err = ioutil.WriteFile(path, buildTestFlacTagsFile([]string{"R128_TRACK_GAIN=-2560", "R128_ALBUM_GAIN=256"}), 0644); err != nil {
+		suite.T().Fatal(err)
+	}
+	trackGain, trackPeak, albumGain, albumPeak = getReplayGain(suite.readTags(path))
+	assert.Equal(suite.T(), -5.0, trackGain)
+	assert.Equal(suite.T(), 0.0, trackPeak)
+	assert.Equal(suite.T(), 6.0, albumGain)
+
+	// ReplayGain values have priority.
+	if err = ioutil.WriteFile(path, buildTestFlacTagsFile([]string{"R128_TRACK_GAIN=-2560", "REPLAYGAIN_TRACK_GAIN=-3.5 dB"}), 0644); err != nil {
+		suite.T().Fatal(err)
+	}
+	trackGain, _, _, _ = getReplayGain(suite.readTags(path))
+	assert.Equal(suite.T(), -3.5, trackGain)
+
+	// No values.
+	trackGain, trackPeak, albumGain, albumPeak = getReplayGain(suite.readTags(TestFSLibDir + "/artist 2/Artist 2 - Album 1 - Track 1.mp3"))
+	assert.Equal(suite.T(), []float64{0, 0, 0, 0}, []float64{trackGain, trackPeak, albumGain, albumPeak})
+}
+
+func (suite *RawTagsTestSuite) TestParseReplayGainValue() {
+	assert.Equal(suite.T(), -6.48, parseReplayGainValue(" -6.48 dB"))
+	assert.Equal(suite.T(), 2.0, parseReplayGainValue("+2.00DB"))
+	assert.Equal(suite.T(), 0.5, parseReplayGainValue("0,5"))
+	assert.Equal(suite.T(), 0.0, parseReplayGainValue("loud"))
+	assert.Equal(suite.T(), 0.0, parseReplayGainValue("NaN"))
+	assert.Equal(suite.T(), 0.0, parseReplayGainValue(""))
+}
+
+// Builds a media file holding only an ID3v2.4 tag.
+func buildTestId3v24File(frames []id3v2Frame) []byte {
+	var body bytes.Buffer
+	for _, frame := range frames {
+		body.WriteString(frame.Name)
+		body.Write(syncsafeBytes(len(frame.Content)))
+		_ = binary.Write(&body, binary.BigEndian, frame.Flags)
+		body.Write(frame.Content)
+	}
+
+	data := append([]byte("ID3\x04\x00\x00"), syncsafeBytes(body.Len())...)
+	return append(data, body.Bytes()...)
+}
+
+// Builds a FLAC file without audio holding only Vorbis comments.
+func buildTestFlacTagsFile(comments []string) []byte {
+	var block bytes.Buffer
+	vendor := "alba test"
+	_ = binary.Write(&block, binary.LittleEndian, uint32(len(vendor)))
+	block.WriteString(vendor)
+	_ = binary.Write(&block, binary.LittleEndian, uint32(len(comments)))
+	for _, comment := range comments {
+		_ = binary.Write(&block, binary.LittleEndian, uint32(len(comment)))
+		block.WriteString(comment)
+	}
+
+	data := []byte("fLaC")
+	data = append(data, 0x00, 0x00, 0x00, 0x22)
+	data = append(data, make([]byte, 34)...)
+	data = append(data, 0x84, byte(block.Len()>>16), byte(block.Len()>>8), byte(block.Len()))
+
+	return append(data, block.Bytes()...)
+}
+
+// Builds the content of a SYLT frame with millisecond timestamps, in UTF-8 or in UTF-16 with byte order mark.
+func buildTestSyltFrame(encoding byte, lines []string, times []uint32) []byte {
+	encode := func(text string) []byte {
+		if encoding != 1 {
+			return append([]byte(text), 0)
+		}
+		b := []byte{0xff, 0xfe}
+		for _, unit := range utf16.Encode([]rune(text)) {
+			b = append(b, byte(unit), byte(unit>>8))
+		}
+		return append(b, 0, 0)
+	}
+
+	var content bytes.Buffer
+	content.WriteByte(encoding)
+	content.WriteString("eng")
+	// Milliseconds timestamps, lyrics.
+	content.Write([]byte{2, 1})
+	content.Write(encode(""))
+	for i, line := range lines {
+		content.Write(encode("\n" + line))
+		_ = binary.Write(&content, binary.BigEndian, times[i])
+	}
+
+	return content.Bytes()
+}
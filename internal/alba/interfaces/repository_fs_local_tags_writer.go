@@ -0,0 +1,1148 @@
+package interfaces
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+)
+
+/**
+Describes where to write a tag in the different tag formats.
+*/
+type writableTagKeys struct {
+	// ID3v2.4 frame name, or "TXXX:<DESCRIPTION>" for a user defined text frame.
+	Id3Frame string
+	// Vorbis comment name, upper case.
+	Vorbis string
+	// MP4 atom name, or "----:<NAME>" for an iTunes custom atom.
+	Mp4 string
+}
+
+// Tags that can be written, indexed by the names of business.TrackTagFields.
+var writableTags = map[string]writableTagKeys{
+	"title":         {Id3Frame: "TIT2", Vorbis: "TITLE", Mp4: "\xa9nam"},
+	"album":         {Id3Frame: "TALB", Vorbis: "ALBUM", Mp4: "\xa9alb"},
+	"albumSort":     {Id3Frame: "TSOA", Vorbis: "ALBUMSORT", Mp4: "soal"},
+	"year":          {Id3Frame: "TDRC", Vorbis: "DATE", Mp4: "\xa9day"},
+	"number":        {Id3Frame: "TRCK", Vorbis: "TRACKNUMBER", Mp4: "trkn"},
+	"disc":          {Id3Frame: "TPOS", Vorbis: "DISCNUMBER", Mp4: "disk"},
+	"composer":      {Id3Frame: "TCOM", Vorbis: "COMPOSER", Mp4: "\xa9wrt"},
+	"conductor":     {Id3Frame: "TPE3", Vorbis: "CONDUCTOR", Mp4: "----:CONDUCTOR"},
+	"label":         {Id3Frame: "TPUB", Vorbis: "LABEL", Mp4: "----:LABEL"},
+	"catalogNumber": {Id3Frame: "TXXX:CATALOGNUMBER", Vorbis: "CATALOGNUMBER", Mp4: "----:CATALOGNUMBER"},
+	"isrc":          {Id3Frame: "TSRC", Vorbis: "ISRC", Mp4: "----:ISRC"},
+	"originalYear":  {Id3Frame: "TDOR", Vorbis: "ORIGINALDATE", Mp4: "----:ORIGINALDATE"},
+	"bpm":           {Id3Frame: "TBPM", Vorbis: "BPM", Mp4: "tmpo"},
+}
+
+// ID3v2.3 frames replaced by another frame in ID3v2.4.
+var id3v23ConvertedFrames = map[string]string{
+	"TYER": "TDRC",
+	"TORY": "TDOR",
+}
+
+// Size of the padding left after the ID3v2 tags, so they can be edited again without rewriting the whole file.
+const id3v2Padding = 1024
+
+/*
+Writes tag values into a media file.
+
+Tags are written in ID3v2.4 for MP3 files, in Vorbis comments for FLAC, Ogg Vorbis and Opus files and in iTunes atoms
+for MP4 files. The file is written to a temporary file which then replaces the original one, so it is never left half
+written.
+
+Returns the changes made to the file, or the changes that would be made if dryRun is true.
+*/
+func (r LocalFilesystemRepository) WriteTags(filePath string, values map[string]string, dryRun bool) (edits []business.TagEdit, err error) {
+	var writer func(src *os.File, dst io.Writer, tags map[string]string) error
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".mp3":
+		writer = writeId3v2Tags
+	case ".flac":
+		writer = writeFlacTags
+	case ".ogg", ".oga", ".opus":
+		writer = writeOggTags
+	case ".m4a":
+		writer = writeMp4Tags
+	default:
+		return nil, errors.New("unsupported file format")
+	}
+
+	metadata, err := getMetadataFromFile(filePath)
+	if err != nil {
+		return
+	}
+
+	var fields []string
+	for field := range values {
+		if _, ok := writableTags[field]; !ok {
+			return nil, errors.New("tag " + field + " cannot be written")
+		}
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		previous := getMetadataTagValue(metadata, field)
+		if previous != values[field] {
+			edits = append(edits, business.TagEdit{Path: filePath, Field: field, PreviousValue: previous, Value: values[field]})
+		}
+	}
+	if dryRun || len(edits) == 0 {
+		return
+	}
+
+	tags := make(map[string]string)
+	for _, edit := range edits {
+		tags[edit.Field] = edit.Value
+	}
+
+	return edits, replaceFile(filePath, func(src *os.File, dst io.Writer) error {
+		return writer(src, dst, tags)
+	})
+}
+
+/*
+Imports media files again.
+
+The directories of the files are scanned as a whole, so the files are processed with the rest of their album.
+*/
+func (r LocalFilesystemRepository) RescanMediaFiles(filePaths []string) error {
+	var directories []string
+	known := make(map[string]bool)
+	for _, filePath := range filePaths {
+		directory := filepath.Dir(filePath)
+		// Tracks located in disc subdirectories are part of the albums of the parent directory.
+		if discDirectoryRegexp.MatchString(filepath.Base(directory)) {
+			directory = filepath.Dir(directory)
+		}
+		if !known[directory] {
+			known[directory] = true
+			directories = append(directories, directory)
+		}
+	}
+
+	for _, directory := range directories {
+		if _, _, err := r.ScanMediaFiles(directory); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Gets the value of a tag from the metadata of a media file, formatted like the values to write.
+func getMetadataTagValue(metadata mediaMetadata, field string) string {
+	switch field {
+	case "title":
+		return metadata.Title
+	case "album":
+		return metadata.Album
+	case "albumSort":
+		return metadata.AlbumSort
+	case "year":
+		return metadata.Year
+	case "number":
+		if metadata.Track != 0 {
+			return strconv.Itoa(metadata.Track)
+		}
+	case "disc":
+		return metadata.Disc
+	case "composer":
+		return metadata.Composer
+	case "conductor":
+		return metadata.Conductor
+	case "label":
+		return metadata.Label
+	case "catalogNumber":
+		return metadata.CatalogNumber
+	case "isrc":
+		return metadata.Isrc
+	case "originalYear":
+		return metadata.OriginalYear
+	case "bpm":
+		if metadata.Bpm != 0 {
+			return strconv.Itoa(metadata.Bpm)
+		}
+	}
+
+	return ""
+}
+
+// Rewrites a file through a temporary file in the same directory, which replaces the original file once complete.
+func replaceFile(filePath string, write func(src *os.File, dst io.Writer) error) (err error) {
+	src, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	buffered := bufio.NewWriter(tmp)
+	if err = write(src, buffered); err != nil {
+		return
+	}
+	if err = buffered.Flush(); err != nil {
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		return
+	}
+	if err = tmp.Chmod(info.Mode()); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+
+	return os.Rename(tmp.Name(), filePath)
+}
+
+// Keeps the total of a "<number>/<total>" value if the new value has none.
+func keepTotal(previous string, value string) string {
+	if value == "" || strings.Contains(value, "/") {
+		return value
+	}
+	if parts := strings.SplitN(previous, "/", 2); len(parts) == 2 && parts[1] != "" {
+		return value + "/" + parts[1]
+	}
+
+	return value
+}
+
+/*
+ID3v2.
+*/
+
+/**
+Frame of an ID3v2 tag.
+*/
+type id3v2Frame struct {
+	Name    string
+	Flags   uint16
+	Content []byte
+}
+
+/*
+Writes tags into the ID3v2 tag of an MP3 file.
+
+Existing ID3v2.3 and ID3v2.4 tags are converted to ID3v2.4, keeping the frames which are not written. Text values are
+encoded in UTF-8.
+*/
+func writeId3v2Tags(src *os.File, dst io.Writer, tags map[string]string) error {
+	frames, audioOffset, err := readId3v2Frames(src)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range sortedKeys(tags) {
+		frameName := writableTags[field].Id3Frame
+		description := ""
+		if strings.HasPrefix(frameName, "TXXX:") {
+			description = strings.TrimPrefix(frameName, "TXXX:")
+			frameName = "TXXX"
+		}
+
+		// Remove the frames holding the previous value.
+		var kept []id3v2Frame
+		previous := ""
+		for _, frame := range frames {
+			if frame.Name != frameName {
+				kept = append(kept, frame)
+				continue
+			}
+			texts := splitNullTerminatedText(frame.Content[0], frame.Content[1:])
+			if description != "" && (len(texts) < 2 || !strings.EqualFold(texts[0], description)) {
+				kept = append(kept, frame)
+				continue
+			}
+			if previous == "" && len(texts) > 0 {
+				previous = texts[len(texts)-1]
+			}
+		}
+		frames = kept
+
+		value := tags[field]
+		if field == "number" || field == "disc" {
+			value = keepTotal(previous, value)
+		}
+		if value == "" {
+			continue
+		}
+
+		// Text encoding 3 is UTF-8.
+		content := []byte{3}
+		if description != "" {
+			content = append(append(content, description...), 0)
+		}
+		frames = append(frames, id3v2Frame{Name: frameName, Content: append(content, value...)})
+	}
+
+	var body bytes.Buffer
+	for _, frame := range frames {
+		body.WriteString(frame.Name)
+		body.Write(syncsafeBytes(len(frame.Content)))
+		_ = binary.Write(&body, binary.BigEndian, frame.Flags)
+		body.Write(frame.Content)
+	}
+	body.Write(make([]byte, id3v2Padding))
+
+	header := append([]byte{'I', 'D', '3', 4, 0, 0}, syncsafeBytes(body.Len())...)
+	if _, err = dst.Write(header); err != nil {
+		return err
+	}
+	if _, err = body.WriteTo(dst); err != nil {
+		return err
+	}
+
+	if _, err = src.Seek(audioOffset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+
+	return err
+}
+
+/*
+Reads the frames of the ID3v2 tag located at the beginning of a file, converted to ID3v2.4.
+
+Returns the offset of the audio data following the tag.
+*/
+func readId3v2Frames(src io.ReadSeeker) (frames []id3v2Frame, audioOffset int64, err error) {
+	header := make([]byte, 10)
+	if _, err = io.ReadFull(src, header); err != nil || string(header[0:3]) != "ID3" {
+		// No tag: the audio data starts at the beginning of the file.
+		return nil, 0, nil
+	}
+
+	version := header[3]
+	flags := header[5]
+	if version != 3 && version != 4 {
+		return nil, 0, errors.New("unsupported ID3v2." + strconv.Itoa(int(version)) + " tag")
+	}
+	if flags&0x80 != 0 {
+		return nil, 0, errors.New("unsupported unsynchronised ID3v2 tag")
+	}
+
+	data := make([]byte, syncsafeInt(header[6:10]))
+	if _, err = io.ReadFull(src, data); err != nil {
+		return
+	}
+	audioOffset = int64(10 + len(data))
+	// ID3v2.4 tags can end with a footer.
+	if version == 4 && flags&0x10 != 0 {
+		audioOffset += 10
+	}
+
+	// Skip the extended header.
+	if flags&0x40 != 0 && len(data) >= 4 {
+		extendedHeaderSize := int(binary.BigEndian.Uint32(data[0:4])) + 4
+		if version == 4 {
+			extendedHeaderSize = syncsafeInt(data[0:4])
+		}
+		if extendedHeaderSize > len(data) {
+			return nil, 0, errors.New("invalid ID3v2 extended header")
+		}
+		data = data[extendedHeaderSize:]
+	}
+
+	for len(data) > 10 && data[0] != 0 {
+		frame := id3v2Frame{Name: string(data[0:4]), Flags: binary.BigEndian.Uint16(data[8:10])}
+		size := syncsafeInt(data[4:8])
+		if version == 3 {
+			size = int(binary.BigEndian.Uint32(data[4:8]))
+		}
+		if size < 1 || 10+size > len(data) {
+			return nil, 0, errors.New("invalid ID3v2 frame size")
+		}
+		frame.Content = data[10 : 10+size]
+		data = data[10+size:]
+
+		if version == 3 {
+			// Compressed or encrypted frames cannot be converted.
+			if frame.Flags&0x00c0 != 0 {
+				continue
+			}
+			frame.Flags = 0
+			if name, ok := id3v23ConvertedFrames[frame.Name]; ok {
+				frame.Name = name
+			}
+		}
+		frames = append(frames, frame)
+	}
+
+	return
+}
+
+// Encodes a 4 bytes syncsafe integer, as used by ID3v2.4.
+func syncsafeBytes(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+}
+
+/*
+Vorbis comments.
+*/
+
+/*
+Writes tags into a Vorbis comments block.
+
+Comments having the names of the written tags are replaced, the other ones are kept in their order.
+*/
+func updateVorbisComments(block []byte, tags map[string]string) ([]byte, error) {
+	reader := bytes.NewReader(block)
+	var vendorLength uint32
+	if err := binary.Read(reader, binary.LittleEndian, &vendorLength); err != nil {
+		return nil, err
+	}
+	if int64(vendorLength) > int64(reader.Len()) {
+		return nil, errors.New("invalid Vorbis comments vendor")
+	}
+	vendor := make([]byte, vendorLength)
+	if _, err := io.ReadFull(reader, vendor); err != nil {
+		return nil, err
+	}
+
+	var count uint32
+	if err := binary.Read(reader, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for field := range tags {
+		names[writableTags[field].Vorbis] = true
+	}
+
+	var comments [][]byte
+	previousValues := make(map[string]string)
+	for i := uint32(0); i < count; i++ {
+		var length uint32
+		if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
+			return nil, err
+		}
+		if int64(length) > int64(reader.Len()) {
+			return nil, errors.New("invalid Vorbis comment length")
+		}
+		comment := make([]byte, length)
+		if _, err := io.ReadFull(reader, comment); err != nil {
+			return nil, err
+		}
+
+		parts := strings.SplitN(string(comment), "=", 2)
+		name := strings.ToUpper(parts[0])
+		if names[name] {
+			if len(parts) == 2 {
+				previousValues[name] = parts[1]
+			}
+			continue
+		}
+		comments = append(comments, comment)
+	}
+
+	for _, field := range sortedKeys(tags) {
+		name := writableTags[field].Vorbis
+		value := tags[field]
+		if field == "number" || field == "disc" {
+			value = keepTotal(previousValues[name], value)
+		}
+		if value != "" {
+			comments = append(comments, []byte(name+"="+value))
+		}
+	}
+
+	var updated bytes.Buffer
+	_ = binary.Write(&updated, binary.LittleEndian, vendorLength)
+	updated.Write(vendor)
+	_ = binary.Write(&updated, binary.LittleEndian, uint32(len(comments)))
+	for _, comment := range comments {
+		_ = binary.Write(&updated, binary.LittleEndian, uint32(len(comment)))
+		updated.Write(comment)
+	}
+
+	return updated.Bytes(), nil
+}
+
+/*
+FLAC.
+*/
+
+/*
+Writes tags into the Vorbis comments block of a FLAC file.
+
+The block is created after the STREAMINFO block if the file has none.
+*/
+func writeFlacTags(src *os.File, dst io.Writer, tags map[string]string) error {
+	marker := make([]byte, 4)
+	if _, err := io.ReadFull(src, marker); err != nil || string(marker) != "fLaC" {
+		return errors.New("not a FLAC file")
+	}
+
+	type flacBlock struct {
+		Type byte
+		Data []byte
+	}
+	var blocks []flacBlock
+	var comments []byte
+	for last := false; !last; {
+		blockHeader := make([]byte, 4)
+		if _, err := io.ReadFull(src, blockHeader); err != nil {
+			return err
+		}
+		last = blockHeader[0]&0x80 != 0
+		block := flacBlock{Type: blockHeader[0] & 0x7f}
+		block.Data = make([]byte, int(blockHeader[1])<<16|int(blockHeader[2])<<8|int(blockHeader[3]))
+		if _, err := io.ReadFull(src, block.Data); err != nil {
+			return err
+		}
+
+		if block.Type == 4 {
+			comments = block.Data
+		} else {
+			blocks = append(blocks, block)
+		}
+	}
+	if len(blocks) == 0 || blocks[0].Type != 0 {
+		return errors.New("invalid FLAC file: missing STREAMINFO block")
+	}
+
+	if comments == nil {
+		// Empty block with the vendor string of the FLAC reference library.
+		vendor := "reference libFLAC"
+		comments = append(append(make([]byte, 0), byte(len(vendor)), 0, 0, 0), vendor...)
+		comments = append(comments, 0, 0, 0, 0)
+	}
+	comments, err := updateVorbisComments(comments, tags)
+	if err != nil {
+		return err
+	}
+	if len(comments) >= 1<<24 {
+		return errors.New("Vorbis comments too large")
+	}
+	blocks = append(blocks[:1], append([]flacBlock{{Type: 4, Data: comments}}, blocks[1:]...)...)
+
+	if _, err = dst.Write(marker); err != nil {
+		return err
+	}
+	for i, block := range blocks {
+		blockType := block.Type
+		if i == len(blocks)-1 {
+			blockType |= 0x80
+		}
+		size := len(block.Data)
+		if _, err = dst.Write([]byte{blockType, byte(size >> 16), byte(size >> 8), byte(size)}); err != nil {
+			return err
+		}
+		if _, err = dst.Write(block.Data); err != nil {
+			return err
+		}
+	}
+
+	// The audio frames follow the metadata blocks.
+	_, err = io.Copy(dst, src)
+
+	return err
+}
+
+/*
+Ogg.
+*/
+
+/**
+Page of an Ogg bitstream.
+*/
+type oggPage struct {
+	HeaderType byte
+	Granule    uint64
+	Serial     uint32
+	Sequence   uint32
+	Segments   []byte
+	Data       []byte
+}
+
+var oggCrcTable = func() (table [256]uint32) {
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+
+	return
+}()
+
+/*
+Writes tags into the comment header of an Ogg Vorbis or Opus file.
+
+The header packets are paginated again, and the following pages are renumbered if the number of header pages has
+changed. Only files holding one logical bitstream are supported.
+*/
+func writeOggTags(src *os.File, dst io.Writer, tags map[string]string) error {
+	reader := bufio.NewReader(src)
+
+	// Read the header pages: the identification packet, the comment packet and, for Vorbis, the setup packet.
+	var headerPages []oggPage
+	var packets [][]byte
+	var packet []byte
+	headerPackets := 3
+	for len(packets) < headerPackets || len(packet) > 0 {
+		page, err := readOggPage(reader)
+		if err != nil {
+			return err
+		}
+		if len(headerPages) > 0 && page.Serial != headerPages[0].Serial {
+			return errors.New("unsupported multiplexed Ogg file")
+		}
+		headerPages = append(headerPages, page)
+
+		offset := 0
+		for _, lacing := range page.Segments {
+			if len(packets) == headerPackets {
+				return errors.New("unsupported Ogg file: audio data in header pages")
+			}
+			packet = append(packet, page.Data[offset:offset+int(lacing)]...)
+			offset += int(lacing)
+			if lacing < 255 {
+				packets = append(packets, packet)
+				packet = nil
+				if len(packets) == 1 && bytes.HasPrefix(packets[0], []byte("OpusHead")) {
+					headerPackets = 2
+				}
+			}
+		}
+	}
+
+	comments := packets[1]
+	var prefix, suffix []byte
+	switch {
+	case bytes.HasPrefix(comments, []byte("\x03vorbis")):
+		// Vorbis comments end with a framing bit.
+		prefix, suffix = comments[:7], []byte{1}
+	case bytes.HasPrefix(comments, []byte("OpusTags")):
+		prefix = comments[:8]
+	default:
+		return errors.New("unsupported Ogg codec")
+	}
+	block, err := updateVorbisComments(comments[len(prefix):], tags)
+	if err != nil {
+		return err
+	}
+	packets[1] = append(append(append([]byte{}, prefix...), block...), suffix...)
+
+	// The identification packet stays alone on the first page.
+	pages := []oggPage{headerPages[0]}
+	pages = append(pages, paginateOggPackets(packets[1:], headerPages[0].Serial, 1)...)
+	for _, page := range pages {
+		if _, err = dst.Write(encodeOggPage(page)); err != nil {
+			return err
+		}
+	}
+
+	// Copy the audio pages, renumbering them if needed.
+	delta := uint32(len(pages) - len(headerPages))
+	if delta == 0 {
+		_, err = io.Copy(dst, reader)
+		return err
+	}
+	for {
+		page, errPage := readOggPage(reader)
+		if errPage == io.EOF {
+			return nil
+		}
+		if errPage != nil {
+			return errPage
+		}
+		page.Sequence += delta
+		if _, err = dst.Write(encodeOggPage(page)); err != nil {
+			return err
+		}
+	}
+}
+
+// Reads an Ogg page. Returns io.EOF if there are no more pages.
+func readOggPage(r io.Reader) (page oggPage, err error) {
+	header := make([]byte, 27)
+	if _, err = io.ReadFull(r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = errors.New("truncated Ogg page")
+		}
+		return
+	}
+	if string(header[0:4]) != "OggS" {
+		return page, errors.New("invalid Ogg page")
+	}
+
+	page.HeaderType = header[5]
+	page.Granule = binary.LittleEndian.Uint64(header[6:14])
+	page.Serial = binary.LittleEndian.Uint32(header[14:18])
+	page.Sequence = binary.LittleEndian.Uint32(header[18:22])
+	page.Segments = make([]byte, header[26])
+	if _, err = io.ReadFull(r, page.Segments); err != nil {
+		return
+	}
+
+	size := 0
+	for _, lacing := range page.Segments {
+		size += int(lacing)
+	}
+	page.Data = make([]byte, size)
+	_, err = io.ReadFull(r, page.Data)
+
+	return
+}
+
+// Encodes an Ogg page, computing its checksum.
+func encodeOggPage(page oggPage) []byte {
+	encoded := make([]byte, 27, 27+len(page.Segments)+len(page.Data))
+	copy(encoded, "OggS")
+	encoded[5] = page.HeaderType
+	binary.LittleEndian.PutUint64(encoded[6:14], page.Granule)
+	binary.LittleEndian.PutUint32(encoded[14:18], page.Serial)
+	binary.LittleEndian.PutUint32(encoded[18:22], page.Sequence)
+	encoded[26] = byte(len(page.Segments))
+	encoded = append(append(encoded, page.Segments...), page.Data...)
+
+	var crc uint32
+	for _, b := range encoded {
+		crc = crc<<8 ^ oggCrcTable[byte(crc>>24)^b]
+	}
+	binary.LittleEndian.PutUint32(encoded[22:26], crc)
+
+	return encoded
+}
+
+// Splits packets into Ogg pages, the last packet ending its page.
+func paginateOggPackets(packets [][]byte, serial uint32, sequence uint32) (pages []oggPage) {
+	page := oggPage{Serial: serial, Sequence: sequence}
+	// Pages on which no packet ends have no granule position.
+	packetEnded := false
+	for _, packet := range packets {
+		for first := true; ; first = false {
+			if len(page.Segments) == 255 {
+				if !packetEnded {
+					page.Granule = ^uint64(0)
+				}
+				pages = append(pages, page)
+				page = oggPage{Serial: serial, Sequence: page.Sequence + 1}
+				if !first {
+					page.HeaderType = 0x01
+				}
+				packetEnded = false
+			}
+
+			lacing := len(packet)
+			if lacing > 255 {
+				lacing = 255
+			}
+			page.Segments = append(page.Segments, byte(lacing))
+			page.Data = append(page.Data, packet[:lacing]...)
+			packet = packet[lacing:]
+			if lacing < 255 {
+				packetEnded = true
+				break
+			}
+		}
+	}
+
+	return append(pages, page)
+}
+
+/*
+MP4.
+*/
+
+/**
+Atom of an MP4 file.
+*/
+type mp4Atom struct {
+	Name string
+	// Content of the leaf atoms.
+	Data []byte
+	// Bytes preceding the children of a container atom, like the version and flags of "meta".
+	Header   []byte
+	Children []*mp4Atom
+}
+
+// Atoms containing other atoms on the path to the tags and the chunk offsets.
+var mp4Containers = map[string]bool{
+	"moov": true, "udta": true, "meta": true, "ilst": true, "trak": true, "mdia": true, "minf": true, "stbl": true,
+}
+
+/*
+Writes tags into the iTunes metadata atoms of an MP4 file.
+
+The "moov" atom is rebuilt, and the chunk offsets are updated if the media data follows it.
+*/
+func writeMp4Tags(src *os.File, dst io.Writer, tags map[string]string) error {
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	// Find the top level atoms.
+	type atomPosition struct {
+		Name   string
+		Offset int64
+		Size   int64
+	}
+	var atoms []atomPosition
+	moovIndex := -1
+	for offset := int64(0); offset < info.Size(); {
+		header := make([]byte, 16)
+		if _, err = src.ReadAt(header[:8], offset); err != nil {
+			return err
+		}
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		switch size {
+		case 0:
+			size = info.Size() - offset
+		case 1:
+			if _, err = src.ReadAt(header[8:16], offset+8); err != nil {
+				return err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+		}
+		if size < 8 || offset+size > info.Size() {
+			return errors.New("invalid MP4 atom size")
+		}
+
+		name := string(header[4:8])
+		if name == "moov" {
+			moovIndex = len(atoms)
+		}
+		atoms = append(atoms, atomPosition{Name: name, Offset: offset, Size: size})
+		offset += size
+	}
+	if moovIndex < 0 {
+		return errors.New("invalid MP4 file: missing moov atom")
+	}
+
+	moovPosition := atoms[moovIndex]
+	moovData := make([]byte, moovPosition.Size)
+	if _, err = src.ReadAt(moovData, moovPosition.Offset); err != nil {
+		return err
+	}
+	moov, err := parseMp4Atom(moovData)
+	if err != nil {
+		return err
+	}
+
+	ilst := findOrCreateMp4Atom(moov, "udta", "meta", "ilst")
+	updateMp4Items(ilst, tags)
+
+	// Media data located after the moov atom moves with its size change.
+	delta := int64(len(encodeMp4Atom(moov))) - moovPosition.Size
+	if delta != 0 {
+		for _, atom := range atoms[moovIndex+1:] {
+			if atom.Name == "mdat" {
+				if err = shiftMp4ChunkOffsets(moov, moovPosition.Offset, delta); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	for i, atom := range atoms {
+		if i == moovIndex {
+			_, err = dst.Write(encodeMp4Atom(moov))
+		} else {
+			_, err = io.Copy(dst, io.NewSectionReader(src, atom.Offset, atom.Size))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Parses an atom, and its children if it is one of the containers we need to browse.
+func parseMp4Atom(data []byte) (*mp4Atom, error) {
+	if len(data) < 8 {
+		return nil, errors.New("invalid MP4 atom")
+	}
+	atom := &mp4Atom{Name: string(data[4:8])}
+	content := data[8:]
+	if binary.BigEndian.Uint32(data[0:4]) == 1 {
+		if len(data) < 16 {
+			return nil, errors.New("invalid MP4 atom")
+		}
+		content = data[16:]
+	}
+
+	if !mp4Containers[atom.Name] {
+		atom.Data = content
+		return atom, nil
+	}
+
+	// The iTunes "meta" atom is a full atom, starting with a version and flags, unlike the QuickTime one.
+	if atom.Name == "meta" && len(content) >= 4 && binary.BigEndian.Uint32(content[0:4]) == 0 {
+		atom.Header = content[0:4]
+		content = content[4:]
+	}
+
+	for len(content) > 0 {
+		if len(content) < 8 {
+			return nil, errors.New("invalid MP4 atom")
+		}
+		size := int(binary.BigEndian.Uint32(content[0:4]))
+		if size == 1 && len(content) >= 16 {
+			size = int(binary.BigEndian.Uint64(content[8:16]))
+		}
+		if size < 8 || size > len(content) {
+			return nil, errors.New("invalid MP4 atom size")
+		}
+		child, err := parseMp4Atom(content[:size])
+		if err != nil {
+			return nil, err
+		}
+		atom.Children = append(atom.Children, child)
+		content = content[size:]
+	}
+
+	return atom, nil
+}
+
+// Encodes an atom and its children.
+func encodeMp4Atom(atom *mp4Atom) []byte {
+	content := append([]byte{}, atom.Header...)
+	if atom.Children != nil || mp4Containers[atom.Name] {
+		for _, child := range atom.Children {
+			content = append(content, encodeMp4Atom(child)...)
+		}
+	} else {
+		content = append(content, atom.Data...)
+	}
+
+	if len(content)+8 > 0xffffffff {
+		encoded := make([]byte, 16, 16+len(content))
+		binary.BigEndian.PutUint32(encoded[0:4], 1)
+		copy(encoded[4:8], atom.Name)
+		binary.BigEndian.PutUint64(encoded[8:16], uint64(len(content)+16))
+		return append(encoded, content...)
+	}
+
+	encoded := make([]byte, 8, 8+len(content))
+	binary.BigEndian.PutUint32(encoded[0:4], uint32(len(content)+8))
+	copy(encoded[4:8], atom.Name)
+	return append(encoded, content...)
+}
+
+// Finds a descendant atom, creating the missing atoms on the path.
+func findOrCreateMp4Atom(parent *mp4Atom, path ...string) *mp4Atom {
+	for _, name := range path {
+		var found *mp4Atom
+		for _, child := range parent.Children {
+			if child.Name == name {
+				found = child
+				break
+			}
+		}
+
+		if found == nil {
+			found = &mp4Atom{Name: name}
+			if name == "meta" {
+				// iTunes metadata handler.
+				found.Header = []byte{0, 0, 0, 0}
+				hdlr := append(make([]byte, 8), "mdirappl"...)
+				found.Children = append(found.Children, &mp4Atom{Name: "hdlr", Data: append(hdlr, make([]byte, 9)...)})
+			}
+			parent.Children = append(parent.Children, found)
+		}
+		parent = found
+	}
+
+	return parent
+}
+
+// Replaces the items of an "ilst" atom having the names of the written tags.
+func updateMp4Items(ilst *mp4Atom, tags map[string]string) {
+	names := make(map[string]string)
+	for field := range tags {
+		names[writableTags[field].Mp4] = field
+	}
+
+	var kept []*mp4Atom
+	previousValues := make(map[string]string)
+	for _, item := range ilst.Children {
+		name := item.Name
+		if name == "----" {
+			name = "----:" + strings.ToUpper(getMp4FreeformName(item))
+		}
+		if field, ok := names[name]; ok {
+			previousValues[field] = getMp4ItemText(item)
+			continue
+		}
+		kept = append(kept, item)
+	}
+	ilst.Children = kept
+
+	for _, field := range sortedKeys(tags) {
+		value := tags[field]
+		if field == "number" || field == "disc" {
+			value = keepTotal(previousValues[field], value)
+		}
+		if value == "" {
+			continue
+		}
+
+		name := writableTags[field].Mp4
+		item := &mp4Atom{Name: name, Children: []*mp4Atom{}}
+		if strings.HasPrefix(name, "----:") {
+			item.Name = "----"
+			item.Children = append(
+				item.Children,
+				&mp4Atom{Name: "mean", Data: append([]byte{0, 0, 0, 0}, "com.apple.iTunes"...)},
+				&mp4Atom{Name: "name", Data: append([]byte{0, 0, 0, 0}, strings.TrimPrefix(name, "----:")...)},
+			)
+		}
+
+		// Data atoms start with a type indicator and a locale.
+		var data []byte
+		switch name {
+		case "trkn", "disk":
+			parts := strings.SplitN(value, "/", 2)
+			number, _ := strconv.Atoi(parts[0])
+			total := 0
+			if len(parts) == 2 {
+				total, _ = strconv.Atoi(parts[1])
+			}
+			data = []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, byte(number >> 8), byte(number), byte(total >> 8), byte(total)}
+			if name == "trkn" {
+				data = append(data, 0, 0)
+			}
+		case "tmpo":
+			bpm, _ := strconv.Atoi(value)
+			data = []byte{0, 0, 0, 21, 0, 0, 0, 0, byte(bpm >> 8), byte(bpm)}
+		default:
+			data = append([]byte{0, 0, 0, 1, 0, 0, 0, 0}, value...)
+		}
+		item.Children = append(item.Children, &mp4Atom{Name: "data", Data: data})
+		ilst.Children = append(ilst.Children, item)
+	}
+}
+
+// Gets the name of an iTunes custom atom.
+func getMp4FreeformName(item *mp4Atom) string {
+	for _, child := range getMp4ItemChildren(item) {
+		if child.Name == "name" && len(child.Data) >= 4 {
+			return string(child.Data[4:])
+		}
+	}
+
+	return ""
+}
+
+// Gets the value of an item as text, "<number>/<total>" for the track and disc numbers.
+func getMp4ItemText(item *mp4Atom) string {
+	for _, child := range getMp4ItemChildren(item) {
+		if child.Name != "data" || len(child.Data) < 8 {
+			continue
+		}
+		data := child.Data[8:]
+		if (item.Name == "trkn" || item.Name == "disk") && len(data) >= 6 {
+			return strconv.Itoa(int(binary.BigEndian.Uint16(data[2:4]))) + "/" + strconv.Itoa(int(binary.BigEndian.Uint16(data[4:6])))
+		}
+		return string(data)
+	}
+
+	return ""
+}
+
+// Gets the children of an "ilst" item, which is not parsed as a container.
+func getMp4ItemChildren(item *mp4Atom) (children []*mp4Atom) {
+	if item.Children != nil {
+		return item.Children
+	}
+
+	for content := item.Data; len(content) >= 8; {
+		size := int(binary.BigEndian.Uint32(content[0:4]))
+		if size < 8 || size > len(content) {
+			break
+		}
+		children = append(children, &mp4Atom{Name: string(content[4:8]), Data: content[8:size]})
+		content = content[size:]
+	}
+
+	return
+}
+
+// Shifts the chunk offsets of all the tracks pointing after the given position.
+func shiftMp4ChunkOffsets(atom *mp4Atom, after int64, delta int64) error {
+	for _, child := range atom.Children {
+		switch child.Name {
+		case "stco", "co64":
+			entrySize := 4
+			if child.Name == "co64" {
+				entrySize = 8
+			}
+			if len(child.Data) < 8 {
+				return errors.New("invalid MP4 chunk offsets")
+			}
+			count := int(binary.BigEndian.Uint32(child.Data[4:8]))
+			if len(child.Data) < 8+count*entrySize {
+				return errors.New("invalid MP4 chunk offsets")
+			}
+			data := append([]byte{}, child.Data...)
+			for i := 0; i < count; i++ {
+				entry := data[8+i*entrySize:]
+				if entrySize == 4 {
+					if offset := int64(binary.BigEndian.Uint32(entry)); offset > after {
+						if offset+delta > 0xffffffff {
+							return errors.New("MP4 chunk offsets overflow")
+						}
+						binary.BigEndian.PutUint32(entry, uint32(offset+delta))
+					}
+				} else if offset := int64(binary.BigEndian.Uint64(entry)); offset > after {
+					binary.BigEndian.PutUint64(entry, uint64(offset+delta))
+				}
+			}
+			child.Data = data
+		default:
+			if err := shiftMp4ChunkOffsets(child, after, delta); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Gets the keys of a map, sorted.
+func sortedKeys(values map[string]string) (keys []string) {
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return
+}
@@ -0,0 +1,289 @@
+package interfaces
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TagsWriterTestSuite struct {
+	suite.Suite
+	LocalFSRepository LocalFilesystemRepository
+	Directory         string
+}
+
+// Go testing framework entry point.
+func TestTagsWriterTestSuite(t *testing.T) {
+	suite.Run(t, new(TagsWriterTestSuite))
+}
+
+func (suite *TagsWriterTestSuite) SetupTest() {
+	directory, err := ioutil.TempDir("", "alba-tags")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.Directory = directory
+}
+
+func (suite *TagsWriterTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.Directory)
+}
+
+// Copies a test media file to the temporary directory.
+func (suite *TagsWriterTestSuite) copyFile(path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	return suite.writeFile(filepath.Base(path), data)
+}
+
+func (suite *TagsWriterTestSuite) writeFile(name string, data []byte) string {
+	path := filepath.Join(suite.Directory, name)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		suite.T().Fatal(err)
+	}
+
+	return path
+}
+
+func (suite *TagsWriterTestSuite) TestWriteTagsId3v23() {
+	path := suite.copyFile(TestFSLibDir + "/artist 2/Artist 2 - Album 1 - Track 1.mp3")
+	original, _ := ioutil.ReadFile(path)
+
+	values := map[string]string{"title": "Edited title", "catalogNumber": "CAT-002", "bpm": "128"}
+
+	// Dry run.
+	edits, err := suite.LocalFSRepository.WriteTags(path, values, true)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), edits, 3)
+	assert.Equal(suite.T(), "bpm", edits[0].Field)
+	assert.Equal(suite.T(), "", edits[0].PreviousValue)
+	assert.Equal(suite.T(), "128", edits[0].Value)
+	assert.Equal(suite.T(), "title", edits[2].Field)
+	assert.Equal(suite.T(), "Artist #2 - Album #1 - Track #1", edits[2].PreviousValue)
+	unchanged, _ := ioutil.ReadFile(path)
+	assert.Equal(suite.T(), original, unchanged)
+
+	edits, err = suite.LocalFSRepository.WriteTags(path, values, false)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), edits, 3)
+
+	metadata, err := getMetadataFromFile(path)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Edited title", metadata.Title)
+	assert.Equal(suite.T(), "CAT-002", metadata.CatalogNumber)
+	assert.Equal(suite.T(), 128, metadata.Bpm)
+	// Other tags are kept, the ID3v2.3 year being converted.
+	assert.Equal(suite.T(), "Artist #2", metadata.Artist)
+	assert.Equal(suite.T(), "Artist #2 - Album #1", metadata.Album)
+	assert.Equal(suite.T(), "2017", metadata.Year)
+	assert.Equal(suite.T(), "1/2", metadata.Disc)
+
+	written, _ := ioutil.ReadFile(path)
+	assert.Equal(suite.T(), byte(4), written[3])
+	// The audio data is kept.
+	assert.True(suite.T(), bytes.HasSuffix(written, original[10+syncsafeInt(original[6:10]):]))
+
+	// No temporary file left.
+	files, _ := ioutil.ReadDir(suite.Directory)
+	assert.Len(suite.T(), files, 1)
+
+	// Nothing to write.
+	edits, err = suite.LocalFSRepository.WriteTags(path, values, false)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), edits)
+}
+
+func (suite *TagsWriterTestSuite) TestWriteTagsId3v24() {
+	path := suite.copyFile(TestFSLibDir + "/artist 4/artist 4 - album 1/Artist 4 - Album 1 - Track 1.mp3")
+
+	_, err := suite.LocalFSRepository.WriteTags(path, map[string]string{"catalogNumber": "CAT-002", "composer": ""}, false)
+	assert.Nil(suite.T(), err)
+
+	metadata, err := getMetadataFromFile(path)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "CAT-002", metadata.CatalogNumber)
+	assert.Empty(suite.T(), metadata.Composer)
+	// Other user defined text frames are kept.
+	assert.Equal(suite.T(), "9a1b2c3d-0000-4000-8000-000000000002", metadata.MusicBrainzAlbumId)
+	assert.Equal(suite.T(), "Conductor #1", metadata.Conductor)
+}
+
+func (suite *TagsWriterTestSuite) TestWriteTagsFlac() {
+	path := suite.copyFile(TestFSLibDir + "/artist 4/artist 4 - album 2/Artist 4 - Album 2 - Track 1.flac")
+	before, err := getMetadataFromFile(path)
+	assert.Nil(suite.T(), err)
+
+	_, err = suite.LocalFSRepository.WriteTags(path, map[string]string{"title": "Edited title", "label": "Label #2"}, false)
+	assert.Nil(suite.T(), err)
+
+	metadata, err := getMetadataFromFile(path)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "FLAC", metadata.Format)
+	assert.Equal(suite.T(), "Edited title", metadata.Title)
+	assert.Equal(suite.T(), "Label #2", metadata.Label)
+	assert.Equal(suite.T(), before.Artist, metadata.Artist)
+	assert.Equal(suite.T(), before.MusicBrainzTrackId, metadata.MusicBrainzTrackId)
+}
+
+func (suite *TagsWriterTestSuite) TestWriteTagsOgg() {
+	audio := []byte("audio data")
+	path := suite.writeFile("track.ogg", buildTestOggVorbisFile([]string{"TITLE=Title", "ARTIST=Artist"}, audio))
+
+	_, err := suite.LocalFSRepository.WriteTags(path, map[string]string{"title": "Edited title", "number": "3"}, false)
+	assert.Nil(suite.T(), err)
+
+	metadata, err := getMetadataFromFile(path)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Edited title", metadata.Title)
+	assert.Equal(suite.T(), "Artist", metadata.Artist)
+	assert.Equal(suite.T(), 3, metadata.Track)
+
+	// A comment spanning several pages shifts the audio pages.
+	_, err = suite.LocalFSRepository.WriteTags(path, map[string]string{"label": strings.Repeat("x", 70000)}, false)
+	assert.Nil(suite.T(), err)
+
+	file, err := os.Open(path)
+	assert.Nil(suite.T(), err)
+	defer file.Close()
+
+	var pages []oggPage
+	for {
+		page, errPage := readOggPage(file)
+		if errPage == io.EOF {
+			break
+		}
+		assert.Nil(suite.T(), errPage)
+		pages = append(pages, page)
+	}
+	assert.Len(suite.T(), pages, 4)
+	for i, page := range pages {
+		assert.Equal(suite.T(), uint32(i), page.Sequence)
+	}
+	assert.Equal(suite.T(), byte(0x01), pages[2].HeaderType)
+	assert.Equal(suite.T(), audio, pages[3].Data)
+
+	// Checksums are valid.
+	written, _ := ioutil.ReadFile(path)
+	var encoded []byte
+	for _, page := range pages {
+		encoded = append(encoded, encodeOggPage(page)...)
+	}
+	assert.Equal(suite.T(), written, encoded)
+}
+
+func (suite *TagsWriterTestSuite) TestWriteTagsMp4() {
+	path := suite.writeFile("track.m4a", buildTestMp4File())
+
+	_, err := suite.LocalFSRepository.WriteTags(path, map[string]string{"title": "Edited title", "number": "2", "label": "Label #2", "bpm": "90"}, false)
+	assert.Nil(suite.T(), err)
+
+	metadata, err := getMetadataFromFile(path)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Edited title", metadata.Title)
+	assert.Equal(suite.T(), "Album", metadata.Album)
+	assert.Equal(suite.T(), 2, metadata.Track)
+	assert.Equal(suite.T(), "Label #2", metadata.Label)
+
+	// The chunk offset still points to the media data.
+	written, _ := ioutil.ReadFile(path)
+	stco := bytes.Index(written, []byte("stco"))
+	offset := binary.BigEndian.Uint32(written[stco+12:])
+	assert.Equal(suite.T(), "audio data", string(written[offset:offset+10]))
+
+	// The track total is kept.
+	trkn := bytes.Index(written, []byte("trkn"))
+	assert.Equal(suite.T(), []byte{0, 2, 0, 9}, written[trkn+22:trkn+26])
+	// The tag library only reads the first byte of integer items.
+	tmpo := bytes.Index(written, []byte("tmpo"))
+	assert.Equal(suite.T(), []byte{0, 0, 0, 21, 0, 0, 0, 0, 0, 90}, written[tmpo+12:tmpo+22])
+}
+
+func (suite *TagsWriterTestSuite) TestWriteTagsErrors() {
+	path := suite.writeFile("track.wav", []byte("RIFF"))
+	_, err := suite.LocalFSRepository.WriteTags(path, map[string]string{"title": "Edited title"}, false)
+	assert.NotNil(suite.T(), err)
+
+	path = suite.copyFile(TestFSLibDir + "/artist 2/Artist 2 - Album 1 - Track 1.mp3")
+	_, err = suite.LocalFSRepository.WriteTags(path, map[string]string{"genre": "Rock"}, false)
+	assert.NotNil(suite.T(), err)
+
+	_, err = suite.LocalFSRepository.WriteTags(suite.Directory+"/missing.mp3", map[string]string{"title": "Edited title"}, false)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *TagsWriterTestSuite) TestKeepTotal() {
+	assert.Equal(suite.T(), "3/12", keepTotal("1/12", "3"))
+	assert.Equal(suite.T(), "3/10", keepTotal("1/12", "3/10"))
+	assert.Equal(suite.T(), "3", keepTotal("1", "3"))
+	assert.Equal(suite.T(), "", keepTotal("1/12", ""))
+}
+
+// Builds an Ogg Vorbis file with the given comments and one audio page.
+func buildTestOggVorbisFile(comments []string, audio []byte) []byte {
+	identification := append([]byte("\x01vorbis"), make([]byte, 23)...)
+
+	comment := []byte("\x03vorbis")
+	comment = append(comment, 4, 0, 0, 0)
+	comment = append(comment, "test"...)
+	comment = append(comment, byte(len(comments)), 0, 0, 0)
+	for _, c := range comments {
+		comment = append(comment, byte(len(c)), 0, 0, 0)
+		comment = append(comment, c...)
+	}
+	comment = append(comment, 1)
+	setup := []byte("\x05vorbis setup")
+
+	var file []byte
+	file = append(file, encodeOggPage(oggPage{HeaderType: 0x02, Serial: 1, Segments: []byte{byte(len(identification))}, Data: identification})...)
+	for _, page := range paginateOggPackets([][]byte{comment, setup}, 1, 1) {
+		file = append(file, encodeOggPage(page)...)
+	}
+	file = append(file, encodeOggPage(oggPage{HeaderType: 0x04, Granule: 1, Serial: 1, Sequence: 2, Segments: []byte{byte(len(audio))}, Data: audio})...)
+
+	return file
+}
+
+// Builds an MP4 file with a title, an album and a track number, its media data following the moov atom.
+func buildTestMp4File() []byte {
+	atom := func(name string, content ...[]byte) []byte {
+		data := bytes.Join(content, nil)
+		header := make([]byte, 8)
+		binary.BigEndian.PutUint32(header, uint32(len(data)+8))
+		copy(header[4:], name)
+		return append(header, data...)
+	}
+	text := func(value string) []byte {
+		return atom("data", []byte{0, 0, 0, 1, 0, 0, 0, 0}, []byte(value))
+	}
+
+	ftyp := atom("ftyp", []byte("M4A \x00\x00\x00\x00M4A mp42isom"))
+	ilst := atom("ilst",
+		atom("\xa9nam", text("Title")),
+		atom("\xa9alb", text("Album")),
+		atom("trkn", atom("data", []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 9, 0, 0})),
+	)
+	hdlr := atom("hdlr", make([]byte, 8), []byte("mdirappl"), make([]byte, 9))
+	udta := atom("udta", atom("meta", []byte{0, 0, 0, 0}, hdlr, ilst))
+	stco := func(offset uint32) []byte {
+		entry := make([]byte, 4)
+		binary.BigEndian.PutUint32(entry, offset)
+		return atom("stco", []byte{0, 0, 0, 0, 0, 0, 0, 1}, entry)
+	}
+	moovSize := len(atom("moov", atom("trak", atom("mdia", atom("minf", atom("stbl", stco(0))))), udta))
+	// The media data starts after the mdat header.
+	moov := atom("moov", atom("trak", atom("mdia", atom("minf", atom("stbl", stco(uint32(len(ftyp)+moovSize+8)))))), udta)
+	mdat := atom("mdat", []byte("audio data"))
+
+	return bytes.Join([][]byte{ftyp, moov, mdat}, nil)
+}
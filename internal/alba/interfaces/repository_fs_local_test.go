@@ -9,6 +9,8 @@ import (
 	"github.com/spf13/viper"
 	"os"
 	"log"
+	"io/ioutil"
+	"path/filepath"
 )
 
 type LocalFSRepoTestSuite struct {
@@ -27,6 +29,10 @@ func (suite *LocalFSRepoTestSuite) SetupSuite() {
 		_ = os.Mkdir(coversDir, 0755)
 	}
 	viper.Set("Covers.Directory", coversDir)
+	viper.Set("Library.Genres.Separators", []string{";", "/", ","})
+	viper.Set("Library.Artists.Separators", []string{";", "&"})
+	viper.Set("Library.Artists.FeaturingSeparators", []string{"feat.", "ft.", "featuring"})
+	viper.Set("Library.SortArticles", []string{"The", "Le", "Les"})
 
 	ds, err := createTestDatasource()
 	if err != nil {
@@ -38,7 +44,7 @@ func (suite *LocalFSRepoTestSuite) SetupSuite() {
 		log.Fatal(err)
 	}
 
-	_, err = ds.Exec("INSERT INTO artists(id, name) VALUES(?, ?)", 1, business.LibraryDefaultCompilationArtist)
+	_, err = ds.Exec("INSERT INTO artists(id, name, match_key, created_at) VALUES(?, ?, ?, strftime('%s', 'now'))", 1, business.LibraryDefaultCompilationArtist, business.MatchKey(business.LibraryDefaultCompilationArtist))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -88,7 +94,7 @@ func (suite *LocalFSRepoTestSuite) TestScanMediaFiles() {
 	assert.Equal(suite.T(), 0, track.CoverId)
 	assert.Equal(suite.T(), "1/2", track.Disc)
 	assert.Equal(suite.T(), 1, track.Number)
-	// TODO Cannot test duration with the test file.
+	// The test file has no audio, see TestScanDuration().
 	assert.Equal(suite.T(), 0, track.Duration)
 	assert.Equal(suite.T(), "Genre #3", track.Genre)
 	assert.Equal(suite.T(), "../../../testdata/mp3/artist 2/Artist 2 - Album 1 - Track 1.mp3", track.Path)
@@ -127,10 +133,330 @@ func (suite *LocalFSRepoTestSuite) TestScanMediaFiles() {
 	assert.Nil(suite.T(), errCompilationAlbumArtist)
 	assert.Equal(suite.T(), business.LibraryDefaultCompilationArtist, compilationAlbumArtist.Name)
 
+	// Test multi-disc albums stored in disc subdirectories.
+	var discsAlbums domain.Albums
+	_, errDiscsAlbums := suite.LocalFSRepository.AppContext.DB.Select(&discsAlbums, "SELECT * FROM albums WHERE title = ?", "Artist #3 - Album #1")
+	assert.Nil(suite.T(), errDiscsAlbums)
+	assert.Len(suite.T(), discsAlbums, 1)
+	assert.NotEmpty(suite.T(), discsAlbums[0].CoverId)
+
+	var discsTracks domain.Tracks
+	_, errDiscsTracks := suite.LocalFSRepository.AppContext.DB.Select(&discsTracks, "SELECT * FROM tracks WHERE album_id = ? ORDER BY disc", discsAlbums[0].Id)
+	assert.Nil(suite.T(), errDiscsTracks)
+	assert.Len(suite.T(), discsTracks, 2)
+	assert.Equal(suite.T(), "1/2", discsTracks[0].Disc)
+	assert.Equal(suite.T(), "2/2", discsTracks[1].Disc)
+	assert.Equal(suite.T(), discsAlbums[0].CoverId, discsTracks[1].CoverId)
+
+	// Test albums with the same title in the same directory.
+	var sameTitleAlbums domain.Albums
+	_, errSameTitleAlbums := suite.LocalFSRepository.AppContext.DB.Select(&sameTitleAlbums, "SELECT * FROM albums WHERE title = ? ORDER BY year", "Greatest Hits")
+	assert.Nil(suite.T(), errSameTitleAlbums)
+	assert.Len(suite.T(), sameTitleAlbums, 2)
+	assert.Equal(suite.T(), "d1f1e5d2-0000-4000-8000-000000000001", sameTitleAlbums[0].MusicBrainzAlbumId)
+	assert.Equal(suite.T(), "d1f1e5d2-0000-4000-8000-000000000002", sameTitleAlbums[1].MusicBrainzAlbumId)
+	assert.Equal(suite.T(), "2010", sameTitleAlbums[0].Year)
+	assert.Equal(suite.T(), "2015", sameTitleAlbums[1].Year)
+
+	// Test the album dates computed from all its tracks.
+	var datesAlbum = domain.Album{}
+	errDatesAlbum := suite.LocalFSRepository.AppContext.DB.SelectOne(&datesAlbum, "SELECT * FROM albums WHERE title = ?", "Artist #4 - Album #1")
+	assert.Nil(suite.T(), errDatesAlbum)
+	assert.Equal(suite.T(), "2012", datesAlbum.Year)
+	assert.Equal(suite.T(), "2012", datesAlbum.Date)
+	assert.Equal(suite.T(), "1999-03-01", datesAlbum.OriginalDate)
+
+	// Test the lyrics read from a .lrc file.
+	var lyricsTrack = domain.Track{}
+	errLyricsTrack := suite.LocalFSRepository.AppContext.DB.SelectOne(&lyricsTrack, "SELECT * FROM tracks WHERE title = ?", "Artist #1 - Album #2 - Track #1")
+	assert.Nil(suite.T(), errLyricsTrack)
+	var lyrics = domain.Lyrics{}
+	errLyrics := suite.LocalFSRepository.AppContext.DB.SelectOne(&lyrics, "SELECT * FROM lyrics WHERE track_id = ?", lyricsTrack.Id)
+	assert.Nil(suite.T(), errLyrics)
+	assert.Equal(suite.T(), "First line\nSecond line", lyrics.Text)
+	assert.Equal(suite.T(), "[00:01.00]First line\n[00:02.50]Second line", lyrics.Synced)
+
+	// Test tracks with several artists.
+	var creditsTrack = domain.Track{}
+	errCreditsTrack := suite.LocalFSRepository.AppContext.DB.SelectOne(&creditsTrack, "SELECT * FROM tracks WHERE title = ?", "Artist #5 - Album #1 - Track #1")
+	assert.Nil(suite.T(), errCreditsTrack)
+
+	var creditsArtist = domain.Artist{}
+	errCreditsArtist := suite.LocalFSRepository.AppContext.DB.SelectOne(&creditsArtist, "SELECT * FROM artists WHERE id = ?", creditsTrack.ArtistId)
+	assert.Nil(suite.T(), errCreditsArtist)
+	assert.Equal(suite.T(), "Artist #5", creditsArtist.Name)
+
+	var credits []struct {
+		Name string `db:"name"`
+		Role string `db:"role"`
+	}
+	_, errCredits := suite.LocalFSRepository.AppContext.DB.Select(
+		&credits,
+		"SELECT art.name, ta.role FROM track_artists ta, artists art WHERE ta.artist_id = art.id AND ta.track_id = ? ORDER BY ta.position",
+		creditsTrack.Id,
+	)
+	assert.Nil(suite.T(), errCredits)
+	assert.Len(suite.T(), credits, 5)
+	assert.Equal(suite.T(), "Artist #5", credits[0].Name)
+	assert.Equal(suite.T(), domain.ArtistRoleMain, credits[0].Role)
+	assert.Equal(suite.T(), "Artist #6", credits[1].Name)
+	assert.Equal(suite.T(), domain.ArtistRoleFeatured, credits[1].Role)
+	assert.Equal(suite.T(), "Artist #7", credits[2].Name)
+	assert.Equal(suite.T(), domain.ArtistRoleFeatured, credits[2].Role)
+	assert.Equal(suite.T(), "Artist #8", credits[3].Name)
+	assert.Equal(suite.T(), domain.ArtistRoleRemixer, credits[3].Role)
+	assert.Equal(suite.T(), "Artist #5", credits[4].Name)
+	assert.Equal(suite.T(), domain.ArtistRoleComposer, credits[4].Role)
+
+	// No combined artist has been created.
+	var combinedArtists domain.Artists
+	_, errCombinedArtists := suite.LocalFSRepository.AppContext.DB.Select(&combinedArtists, "SELECT * FROM artists WHERE name LIKE '%feat.%'")
+	assert.Nil(suite.T(), errCombinedArtists)
+	assert.Empty(suite.T(), combinedArtists)
+
+	// Test sort names derived from the names.
+	var sortedArtist = domain.Artist{}
+	errSortedArtist := suite.LocalFSRepository.AppContext.DB.SelectOne(&sortedArtist, "SELECT * FROM artists WHERE name = ?", "The Artist #9")
+	assert.Nil(suite.T(), errSortedArtist)
+	assert.Equal(suite.T(), "Artist #9, The", sortedArtist.SortName)
+
+	var sortedAlbum = domain.Album{}
+	errSortedAlbum := suite.LocalFSRepository.AppContext.DB.SelectOne(&sortedAlbum, "SELECT * FROM albums WHERE title = ?", "The Album #9")
+	assert.Nil(suite.T(), errSortedAlbum)
+	assert.Equal(suite.T(), "Album #9, The", sortedAlbum.SortName)
+
+	// Test sort names from the tags.
+	sortedArtist = domain.Artist{}
+	errSortedArtist = suite.LocalFSRepository.AppContext.DB.SelectOne(&sortedArtist, "SELECT * FROM artists WHERE match_key = ?", business.MatchKey("Émile Artist #10"))
+	assert.Nil(suite.T(), errSortedArtist)
+	assert.Equal(suite.T(), "Artist #10, Emile", sortedArtist.SortName)
+
+	sortedAlbum = domain.Album{}
+	errSortedAlbum = suite.LocalFSRepository.AppContext.DB.SelectOne(&sortedAlbum, "SELECT * FROM albums WHERE match_key = ?", business.MatchKey("Album #10"))
+	assert.Nil(suite.T(), errSortedAlbum)
+	assert.Equal(suite.T(), "Album #10 (sorted)", sortedAlbum.SortName)
+
+	// Test that artists and albums are matched regardless of case and Unicode normalisation form.
+	var matchedTrack = domain.Track{}
+	errMatchedTrack := suite.LocalFSRepository.AppContext.DB.SelectOne(&matchedTrack, "SELECT * FROM tracks WHERE title = ?", "Artist #10 - Album #1 - Track #2")
+	assert.Nil(suite.T(), errMatchedTrack)
+	assert.Equal(suite.T(), sortedArtist.Id, matchedTrack.ArtistId)
+	assert.Equal(suite.T(), sortedAlbum.Id, matchedTrack.AlbumId)
+
+	// Test that manual edits survive a rescan.
+	artistOverride := domain.Override{EntityType: domain.OverrideEntityArtist, EntityId: artist.Id, Field: "name", Value: "Edited artist", OriginalValue: artist.Name}
+	trackOverride := domain.Override{EntityType: domain.OverrideEntityTrack, EntityId: track.Id, Field: "title", Value: "Edited track", OriginalValue: "Outdated title"}
+	assert.Nil(suite.T(), suite.LocalFSRepository.AppContext.DB.Insert(&artistOverride, &trackOverride))
+
+	_, _, err = suite.LocalFSRepository.ScanMediaFiles(TestFSLibDir)
+	assert.Nil(suite.T(), err)
+
+	var editedTrack = domain.Track{}
+	errEditedTrack := suite.LocalFSRepository.AppContext.DB.SelectOne(&editedTrack, "SELECT * FROM tracks WHERE id = ?", track.Id)
+	assert.Nil(suite.T(), errEditedTrack)
+	assert.Equal(suite.T(), "Edited track", editedTrack.Title)
+	assert.Equal(suite.T(), artist.Id, editedTrack.ArtistId)
+
+	var editedArtist = domain.Artist{}
+	errEditedArtist := suite.LocalFSRepository.AppContext.DB.SelectOne(&editedArtist, "SELECT * FROM artists WHERE id = ?", artist.Id)
+	assert.Nil(suite.T(), errEditedArtist)
+	assert.Equal(suite.T(), "Edited artist", editedArtist.Name)
+
+	// The original value follows the media files.
+	var refreshedOverride = domain.Override{}
+	errRefreshedOverride := suite.LocalFSRepository.AppContext.DB.SelectOne(&refreshedOverride, "SELECT * FROM overrides WHERE id = ?", trackOverride.Id)
+	assert.Nil(suite.T(), errRefreshedOverride)
+	assert.Equal(suite.T(), "Artist #2 - Album #1 - Track #1", refreshedOverride.OriginalValue)
+
+	// Test that the names of merged artists are mapped onto the canonical artist.
+	var mergedArtist = domain.Artist{}
+	errMergedArtist := suite.LocalFSRepository.AppContext.DB.SelectOne(&mergedArtist, "SELECT * FROM artists WHERE name = ?", "The Artist #9")
+	assert.Nil(suite.T(), errMergedArtist)
+	aliasRepository := ArtistAliasDbRepository{AppContext: suite.LocalFSRepository.AppContext}
+	_, errMerge := aliasRepository.Merge([]int{mergedArtist.Id}, artist.Id)
+	assert.Nil(suite.T(), errMerge)
+
+	_, _, err = suite.LocalFSRepository.ScanMediaFiles(TestFSLibDir)
+	assert.Nil(suite.T(), err)
+
+	var recreatedArtists domain.Artists
+	_, errRecreatedArtists := suite.LocalFSRepository.AppContext.DB.Select(&recreatedArtists, "SELECT * FROM artists WHERE name = ?", "The Artist #9")
+	assert.Nil(suite.T(), errRecreatedArtists)
+	assert.Empty(suite.T(), recreatedArtists)
+
+	var aliasedTrack = domain.Track{}
+	errAliasedTrack := suite.LocalFSRepository.AppContext.DB.SelectOne(&aliasedTrack, "SELECT * FROM tracks WHERE path = ?", TestFSLibDir + "/artist 9/The Artist 9 - Album 1 - Track 1.mp3")
+	assert.Nil(suite.T(), errAliasedTrack)
+	assert.Equal(suite.T(), artist.Id, aliasedTrack.ArtistId)
+
+	editedArtist = domain.Artist{}
+	errEditedArtist = suite.LocalFSRepository.AppContext.DB.SelectOne(&editedArtist, "SELECT * FROM artists WHERE id = ?", artist.Id)
+	assert.Nil(suite.T(), errEditedArtist)
+	assert.Equal(suite.T(), artist.SortName, editedArtist.SortName)
 
 	// TODO test more, this is not exhaustive.
 }
 
+func (suite *LocalFSRepoTestSuite) TestScanCueSheet() {
+	directory, err := ioutil.TempDir("", "alba-cue")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	content, _ := ioutil.ReadFile(TestFSLibDir + "/artist 4/artist 4 - album 2/Artist 4 - Album 2 - Track 1.flac")
+	mediaFilePath := filepath.Join(directory, "Concert.flac")
+	_ = ioutil.WriteFile(mediaFilePath, content, 0644)
+	cueSheet := "PERFORMER \"Cue Artist\"\nTITLE \"Cue Concert\"\nFILE \"Concert.wav\" WAVE\n" +
+		"TRACK 01 AUDIO\nTITLE \"Opening\"\nINDEX 01 00:00:00\n" +
+		"TRACK 02 AUDIO\nTITLE \"Encore\"\nINDEX 01 03:00:00\n"
+	_ = ioutil.WriteFile(filepath.Join(directory, "Concert.cue"), []byte(cueSheet+"TRACK 03 AUDIO\nTITLE \"Outro\"\nINDEX 01 05:30:00\n"), 0644)
+
+	_, _, err = suite.LocalFSRepository.ScanMediaFiles(directory)
+	assert.Nil(suite.T(), err)
+
+	var tracks domain.Tracks
+	_, err = suite.LocalFSRepository.AppContext.DB.Select(&tracks, "SELECT * FROM tracks WHERE path = ? ORDER BY cue_track", mediaFilePath)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), tracks, 3)
+	assert.Equal(suite.T(), "Opening", tracks[0].Title)
+	assert.Equal(suite.T(), 1, tracks[0].CueTrack)
+	assert.Equal(suite.T(), 1, tracks[0].Number)
+	assert.Equal(suite.T(), 0, tracks[0].StartMs)
+	assert.Equal(suite.T(), 180000, tracks[0].EndMs)
+	assert.Equal(suite.T(), 180, tracks[0].Duration)
+	assert.Equal(suite.T(), "Outro", tracks[2].Title)
+	assert.Equal(suite.T(), 330000, tracks[2].StartMs)
+	assert.Equal(suite.T(), 0, tracks[2].EndMs)
+	assert.Equal(suite.T(), tracks[0].AlbumId, tracks[2].AlbumId)
+
+	var album domain.Album
+	err = suite.LocalFSRepository.AppContext.DB.SelectOne(&album, "SELECT * FROM albums WHERE id = ?", tracks[0].AlbumId)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Cue Concert", album.Title)
+
+	// The tracks removed from the sheet are removed from the library.
+	_ = ioutil.WriteFile(filepath.Join(directory, "Concert.cue"), []byte(cueSheet), 0644)
+	_, _, err = suite.LocalFSRepository.ScanMediaFiles(directory)
+	assert.Nil(suite.T(), err)
+
+	var updatedTracks domain.Tracks
+	_, err = suite.LocalFSRepository.AppContext.DB.Select(&updatedTracks, "SELECT * FROM tracks WHERE path = ? ORDER BY cue_track", mediaFilePath)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), updatedTracks, 2)
+	assert.Equal(suite.T(), tracks[0].Id, updatedTracks[0].Id)
+	assert.Equal(suite.T(), tracks[1].Id, updatedTracks[1].Id)
+	assert.Equal(suite.T(), 0, updatedTracks[1].EndMs)
+
+	// Without a sheet, the media file is a single track again.
+	_ = os.Remove(filepath.Join(directory, "Concert.cue"))
+	_, _, err = suite.LocalFSRepository.ScanMediaFiles(directory)
+	assert.Nil(suite.T(), err)
+
+	updatedTracks = domain.Tracks{}
+	_, err = suite.LocalFSRepository.AppContext.DB.Select(&updatedTracks, "SELECT * FROM tracks WHERE path = ?", mediaFilePath)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), updatedTracks, 1)
+	assert.Equal(suite.T(), 0, updatedTracks[0].CueTrack)
+}
+
+func (suite *LocalFSRepoTestSuite) TestScanDuration() {
+	directory, err := ioutil.TempDir("", "alba-duration")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	// 2000 frames of 417 bytes at 128 kbit/s, and 1000 frames of 4096 samples at 44.1 kHz.
+	mp3FilePath := filepath.Join(directory, "Track.mp3")
+	_ = ioutil.WriteFile(mp3FilePath, buildTestMp3File(2000), 0644)
+	flacFilePath := filepath.Join(directory, "Track.flac")
+	_ = ioutil.WriteFile(flacFilePath, buildTestFlacFile(1000, 10), 0644)
+
+	meta, err := getMetadataFromFile(mp3FilePath)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 52125, meta.DurationMs)
+	assert.Equal(suite.T(), 52, meta.Duration)
+
+	_, _, err = suite.LocalFSRepository.ScanMediaFiles(directory)
+	assert.Nil(suite.T(), err)
+
+	var track domain.Track
+	err = suite.LocalFSRepository.AppContext.DB.SelectOne(&track, "SELECT * FROM tracks WHERE path = ?", mp3FilePath)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 52, track.Duration)
+	err = suite.LocalFSRepository.AppContext.DB.SelectOne(&track, "SELECT * FROM tracks WHERE path = ?", flacFilePath)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 93, track.Duration)
+
+	// The plays of the scanned tracks can be scrobbled.
+	interactor := business.LibraryInteractor{
+		TrackRepository:           TrackDbRepository{AppContext: suite.LocalFSRepository.AppContext},
+		ArtistRepository:          ArtistDbRepository{AppContext: suite.LocalFSRepository.AppContext},
+		AlbumRepository:           AlbumDbRepository{AppContext: suite.LocalFSRepository.AppContext},
+		ScrobbleAccountRepository: ScrobbleAccountDbRepository{AppContext: suite.LocalFSRepository.AppContext},
+		ScrobbleRepository:        ScrobbleDbRepository{AppContext: suite.LocalFSRepository.AppContext},
+	}
+	account := domain.ScrobbleAccount{Service: "listenbrainz", UserName: "duration"}
+	err = interactor.ScrobbleAccountRepository.Save(&account)
+	assert.Nil(suite.T(), err)
+	defer func() {
+		_, _ = suite.LocalFSRepository.AppContext.DB.Exec("DELETE FROM scrobbles")
+		_ = interactor.ScrobbleAccountRepository.Delete(&account)
+	}()
+
+	scrobbled, err := interactor.ReportPlay(track.Id, false, 30, 0)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), scrobbled)
+	scrobbled, err = interactor.ReportPlay(track.Id, false, 50, 0)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), scrobbled)
+
+	// The playback of the scanned tracks lasting long enough can be resumed, until the end of the track.
+	interactor.BookmarkRepository = BookmarkDbRepository{AppContext: suite.LocalFSRepository.AppContext}
+	viper.Set("Bookmarks.MinDuration", 1)
+	defer viper.Set("Bookmarks.MinDuration", nil)
+	assert.True(suite.T(), interactor.IsResumable(track))
+	var shortTrack domain.Track
+	_ = suite.LocalFSRepository.AppContext.DB.SelectOne(&shortTrack, "SELECT * FROM tracks WHERE path = ?", mp3FilePath)
+	assert.False(suite.T(), interactor.IsResumable(shortTrack))
+
+	bookmark, err := interactor.SaveBookmark("", track.Id, 80000)
+	assert.Nil(suite.T(), err)
+	assert.NotZero(suite.T(), bookmark.Id)
+	bookmark, err = interactor.SaveBookmark("", track.Id, 85000)
+	assert.Nil(suite.T(), err)
+	assert.Zero(suite.T(), bookmark.Id)
+	_, err = interactor.GetBookmark("", track.Id)
+	assert.NotNil(suite.T(), err)
+
+	// The last track of a CUE sheet lasts until the end of the media file.
+	cueSheet := "FILE \"Track.flac\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00:00\nTRACK 02 AUDIO\nINDEX 01 01:00:00\n"
+	_ = ioutil.WriteFile(filepath.Join(directory, "Track.cue"), []byte(cueSheet), 0644)
+	_, _, err = suite.LocalFSRepository.ScanMediaFiles(directory)
+	assert.Nil(suite.T(), err)
+
+	var tracks domain.Tracks
+	_, err = suite.LocalFSRepository.AppContext.DB.Select(&tracks, "SELECT * FROM tracks WHERE path = ? ORDER BY cue_track", flacFilePath)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), tracks, 2)
+	assert.Equal(suite.T(), 60000, tracks[0].EndMs)
+	assert.Equal(suite.T(), 60, tracks[0].Duration)
+	assert.Equal(suite.T(), 60000, tracks[1].StartMs)
+	assert.Equal(suite.T(), 92879, tracks[1].EndMs)
+	assert.Equal(suite.T(), 32, tracks[1].Duration)
+}
+
+func (suite *LocalFSRepoTestSuite) TestRescanMediaFiles() {
+	// Test with non existing directory.
+	err := suite.LocalFSRepository.RescanMediaFiles([]string{"/what/ever/track.mp3"})
+	assert.NotNil(suite.T(), err)
+
+	// Test with empty directory.
+	err = suite.LocalFSRepository.RescanMediaFiles([]string{TestFSEmptyLibDir + "/track 1.mp3", TestFSEmptyLibDir + "/track 2.mp3"})
+	assert.Nil(suite.T(), err)
+}
+
 func (suite *LocalFSRepoTestSuite) TestMediaFileExists() {
 	// Test with an existing media file.
 	exists := suite.LocalFSRepository.MediaFileExists(TestFSLibDir + "/no artist - no album - no title.mp3")
@@ -156,6 +482,98 @@ func (suite *LocalFSRepoTestSuite) TestProcessTrack() {}
 func (suite *LocalFSRepoTestSuite) TestProcessCover() {}
 func (suite *LocalFSRepoTestSuite) TestWriteCoverFileInternal() {}
 
+func (suite *LocalFSRepoTestSuite) TestResolveAlbumArtist() {
+	// Test with album artist tag, which has priority over track artists.
+	album := []mediaMetadata{
+		{Artist: "Artist #1", AlbumArtist: "Artist #1"},
+		{Artist: "Artist #1 feat. Artist #2", AlbumArtist: "Artist #1"},
+		{Artist: "Artist #1"},
+	}
+	albumArtist, compilation := resolveAlbumArtist(album)
+	assert.Equal(suite.T(), "Artist #1", albumArtist)
+	assert.False(suite.T(), compilation)
+
+	// Test with album artist tag set to the compilation artist.
+	album = []mediaMetadata{
+		{Artist: "Artist #1", AlbumArtist: "Various Artists"},
+		{Artist: "Artist #2", AlbumArtist: "Various Artists"},
+	}
+	albumArtist, compilation = resolveAlbumArtist(album)
+	assert.True(suite.T(), compilation)
+
+	// Test with compilation flag.
+	album = []mediaMetadata{
+		{Artist: "Artist #1", Compilation: true},
+		{Artist: "Artist #1"},
+	}
+	albumArtist, compilation = resolveAlbumArtist(album)
+	assert.Equal(suite.T(), business.LibraryDefaultCompilationArtist, albumArtist)
+	assert.True(suite.T(), compilation)
+
+	// Test fallback on track artists.
+	album = []mediaMetadata{
+		{Artist: "Artist #1"},
+		{Artist: "Artist #2"},
+	}
+	albumArtist, compilation = resolveAlbumArtist(album)
+	assert.Equal(suite.T(), business.LibraryDefaultCompilationArtist, albumArtist)
+	assert.True(suite.T(), compilation)
+
+	album = []mediaMetadata{
+		{Artist: "Artist #1"},
+		{Artist: "Artist #1"},
+	}
+	albumArtist, compilation = resolveAlbumArtist(album)
+	assert.Equal(suite.T(), "Artist #1", albumArtist)
+	assert.False(suite.T(), compilation)
+}
+
+func (suite *LocalFSRepoTestSuite) TestSplitAlbums() {
+	// Untagged tracks are kept with the tagged ones.
+	tracks := []mediaMetadata{
+		{Title: "Track #1", AlbumArtist: "Artist #1"},
+		{Title: "Track #2"},
+	}
+	albums := splitAlbums(tracks)
+	assert.Len(suite.T(), albums, 1)
+	assert.Len(suite.T(), albums[0], 2)
+
+	// Tracks with different album artists end up in different albums.
+	tracks = []mediaMetadata{
+		{Title: "Track #1", AlbumArtist: "Artist #1"},
+		{Title: "Track #2", AlbumArtist: "Artist #2"},
+		{Title: "Track #3", AlbumArtist: "artist #1"},
+	}
+	albums = splitAlbums(tracks)
+	assert.Len(suite.T(), albums, 2)
+	assert.Len(suite.T(), albums[0], 2)
+	assert.Len(suite.T(), albums[1], 1)
+
+	// Tracks with different MusicBrainz release ids end up in different albums.
+	tracks = []mediaMetadata{
+		{Title: "Track #1", AlbumArtist: "Artist #1", MusicBrainzAlbumId: "1"},
+		{Title: "Track #2", AlbumArtist: "Artist #1", MusicBrainzAlbumId: "2"},
+	}
+	albums = splitAlbums(tracks)
+	assert.Len(suite.T(), albums, 2)
+
+	// Tracks with different disc structures end up in different albums.
+	tracks = []mediaMetadata{
+		{Title: "Track #1", Disc: "1/2"},
+		{Title: "Track #2", Disc: "2/2"},
+		{Title: "Track #3", Disc: "1/3"},
+	}
+	albums = splitAlbums(tracks)
+	assert.Len(suite.T(), albums, 2)
+	assert.Len(suite.T(), albums[0], 2)
+}
+
+func (suite *LocalFSRepoTestSuite) TestDiscTotal() {
+	assert.Equal(suite.T(), 2, discTotal("1/2"))
+	assert.Equal(suite.T(), 0, discTotal("1"))
+	assert.Equal(suite.T(), 0, discTotal(""))
+}
+
 func (suite *LocalFSRepoTestSuite) TestGetMetadataFromFile() {
 	// Test with almost full metadata.
 	track := domain.Track{Path: TestFSLibDir + "/artist 1/artist 1 - album 1/Artist 1 - Album 1 - Track 1.mp3"}
@@ -170,7 +588,8 @@ func (suite *LocalFSRepoTestSuite) TestGetMetadataFromFile() {
 	assert.Equal(suite.T(), 1, meta.Track)
 	assert.Empty(suite.T(), meta.Disc)
 	assert.Empty(suite.T(), meta.Picture)
-	// TODO Cannot test duration with the test file.
+	assert.False(suite.T(), meta.Compilation)
+	// The test file has no audio, see TestScanDuration().
 	assert.Equal(suite.T(), 0, meta.Duration)
 	// Path will be different on each platform so we can only test it's not empty.
 	assert.NotEmpty(suite.T(), meta.Path)
@@ -189,7 +608,7 @@ func (suite *LocalFSRepoTestSuite) TestGetMetadataFromFile() {
 	assert.Equal(suite.T(), 1, meta.Track)
 	assert.Empty(suite.T(), meta.Disc)
 	assert.Empty(suite.T(), meta.Picture)
-	// TODO Cannot test duration with the test file.
+	// The test file has no audio, see TestScanDuration().
 	assert.Equal(suite.T(), 0, meta.Duration)
 	// Path will be different on each platform so we can only test it's not empty.
 	assert.NotEmpty(suite.T(), meta.Path)
@@ -207,7 +626,7 @@ func (suite *LocalFSRepoTestSuite) TestGetMetadataFromFile() {
 	assert.Empty(suite.T(), meta.Track)
 	assert.Empty(suite.T(), meta.Disc)
 	assert.Empty(suite.T(), meta.Picture)
-	// TODO Cannot test duration with the test file.
+	// The test file has no audio, see TestScanDuration().
 	assert.Equal(suite.T(), 0, meta.Duration)
 	// Path will be different on each platform so we can only test it's not empty.
 	assert.NotEmpty(suite.T(), meta.Path)
@@ -225,9 +644,88 @@ func (suite *LocalFSRepoTestSuite) TestGetMetadataFromFile() {
 	assert.Equal(suite.T(), "jpg", meta.Picture.Ext)
 	assert.NotEmpty(suite.T(), meta.Picture.Data)
 
+	// Test with extended tags.
+	track = domain.Track{Path: TestFSLibDir + "/artist 4/artist 4 - album 1/Artist 4 - Album 1 - Track 1.mp3"}
+	meta, err = getMetadataFromFile(track.Path)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "9a1b2c3d-0000-4000-8000-000000000001", meta.MusicBrainzTrackId)
+	assert.Equal(suite.T(), "9a1b2c3d-0000-4000-8000-000000000002", meta.MusicBrainzAlbumId)
+	assert.Equal(suite.T(), "9a1b2c3d-0000-4000-8000-000000000003", meta.MusicBrainzArtistId)
+	assert.Equal(suite.T(), "FRZ011200001", meta.Isrc)
+	assert.Equal(suite.T(), "Label #1", meta.Label)
+	assert.Equal(suite.T(), "CAT-001", meta.CatalogNumber)
+	assert.Equal(suite.T(), "2012", meta.Date)
+	assert.Equal(suite.T(), "1999-03-01", meta.OriginalDate)
+	assert.Equal(suite.T(), "1999", meta.OriginalYear)
+	assert.Equal(suite.T(), "Composer #1", meta.Composer)
+	assert.Equal(suite.T(), "Conductor #1", meta.Conductor)
+	assert.Equal(suite.T(), 120, meta.Bpm)
+
+	// Test with a flac file.
+	track = domain.Track{Path: TestFSLibDir + "/artist 4/artist 4 - album 2/Artist 4 - Album 2 - Track 1.flac"}
+	meta, err = getMetadataFromFile(track.Path)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "FLAC", meta.Format)
+	assert.Equal(suite.T(), "Artist #4 - Album #2 - Track #1", meta.Title)
+	assert.Equal(suite.T(), "2001", meta.OriginalDate)
+	assert.Equal(suite.T(), "2001", meta.OriginalYear)
+	assert.Equal(suite.T(), 99, meta.Bpm)
+
+	// Test with multiple genres.
+	track = domain.Track{Path: TestFSLibDir + "/artist 4/artist 4 - album 1/Artist 4 - Album 1 - Track 2.mp3"}
+	meta, err = getMetadataFromFile(track.Path)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"Rock", "alternative rock", "Indie"}, meta.Genres)
+	assert.Equal(suite.T(), "Rock, alternative rock, Indie", meta.Genre)
+
+	track = domain.Track{Path: TestFSLibDir + "/artist 4/artist 4 - album 2/Artist 4 - Album 2 - Track 2.flac"}
+	meta, err = getMetadataFromFile(track.Path)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"Rock", "Jazz"}, meta.Genres)
+
+	// Test with a lyrics file.
+	track = domain.Track{Path: TestFSLibDir + "/artist 1/artist 1 - album 2/Artist 1 - Album 2 - Track 1.mp3"}
+	meta, err = getMetadataFromFile(track.Path)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "First line\nSecond line", meta.Lyrics)
+	assert.Equal(suite.T(), []domain.LyricsLine{{TimeMs: 1000, Line: "First line"}, {TimeMs: 2500, Line: "Second line"}}, meta.SyncedLyrics)
+
 	// Test with non existant file.
 	meta, err = getMetadataFromFile("non/existant/file.mp3")
 	assert.NotNil(suite.T(), err)
 
 	return
 }
+
+func (suite *LocalFSRepoTestSuite) TestGetLyricsFromFiles() {
+	directory, err := ioutil.TempDir("", "alba-lyrics")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	mediaFilePath := filepath.Join(directory, "track.mp3")
+
+	// No lyrics files.
+	text, synced := getLyricsFromFiles(mediaFilePath)
+	assert.Empty(suite.T(), text)
+	assert.Empty(suite.T(), synced)
+
+	// Text file, with a byte order mark and Windows line endings.
+	_ = ioutil.WriteFile(filepath.Join(directory, "track.txt"), []byte("\ufeffFirst line\r\nSecond line\r\n"), 0644)
+	text, synced = getLyricsFromFiles(mediaFilePath)
+	assert.Equal(suite.T(), "First line\nSecond line", text)
+	assert.Empty(suite.T(), synced)
+
+	// Text and LRC files.
+	_ = ioutil.WriteFile(filepath.Join(directory, "track.lrc"), []byte("[00:01.00]Première ligne\n[00:02.50]Deuxième ligne"), 0644)
+	text, synced = getLyricsFromFiles(mediaFilePath)
+	assert.Equal(suite.T(), "First line\nSecond line", text)
+	assert.Equal(suite.T(), []domain.LyricsLine{{TimeMs: 1000, Line: "Première ligne"}, {TimeMs: 2500, Line: "Deuxième ligne"}}, synced)
+
+	// LRC lyrics in a text file.
+	_ = os.Remove(filepath.Join(directory, "track.lrc"))
+	_ = ioutil.WriteFile(filepath.Join(directory, "track.txt"), []byte("[00:01.00]First line"), 0644)
+	text, synced = getLyricsFromFiles(mediaFilePath)
+	assert.Equal(suite.T(), "First line", text)
+	assert.Equal(suite.T(), []domain.LyricsLine{{TimeMs: 1000, Line: "First line"}}, synced)
+}
@@ -0,0 +1,83 @@
+package interfaces
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+/*
+Implements business.WaveformRepository, storing the waveforms as audiowaveform binary files.
+
+The waveform files are named after the path of the media file and the part of it used by the track, and are given the
+modification time of the media file, so the waveforms of modified media files are computed again.
+*/
+type WaveformFileRepository struct{}
+
+func (r WaveformFileRepository) Get(track domain.Track, directory string) (waveform business.Waveform, err error) {
+	mediaStat, err := os.Stat(track.Path)
+	if err != nil {
+		return
+	}
+
+	file, err := os.Open(getWaveformFilePath(track, directory))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return
+	}
+	if stat.ModTime().Unix() != mediaStat.ModTime().Unix() {
+		return waveform, errors.New("waveform outdated")
+	}
+
+	return readWaveformDat(file)
+}
+
+func (r WaveformFileRepository) Save(track domain.Track, waveform business.Waveform, directory string) error {
+	mediaStat, err := os.Stat(track.Path)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(directory, 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a waveform being written is never read.
+	file, err := ioutil.TempFile(directory, "waveform")
+	if err != nil {
+		return err
+	}
+	err = writeWaveformDat(file, waveform, 16)
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Chtimes(file.Name(), mediaStat.ModTime(), mediaStat.ModTime())
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), getWaveformFilePath(track, directory))
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+	}
+
+	return err
+}
+
+// Gets the path of the waveform file of a track.
+func getWaveformFilePath(track domain.Track, directory string) string {
+	hash := md5.Sum([]byte(track.Path + "|" + strconv.Itoa(track.StartMs) + "|" + strconv.Itoa(track.EndMs)))
+
+	return filepath.Join(directory, hex.EncodeToString(hash[:])+".dat")
+}
@@ -0,0 +1,69 @@
+package interfaces
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WaveformFileRepositoryTestSuite struct {
+	suite.Suite
+	Directory string
+}
+
+// Go testing framework entry point.
+func TestWaveformFileRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(WaveformFileRepositoryTestSuite))
+}
+
+func (suite *WaveformFileRepositoryTestSuite) SetupTest() {
+	directory, err := ioutil.TempDir("", "alba-waveform")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.Directory = directory
+}
+
+func (suite *WaveformFileRepositoryTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.Directory)
+}
+
+func (suite *WaveformFileRepositoryTestSuite) TestSaveAndGet() {
+	repository := WaveformFileRepository{}
+	track := domain.Track{Path: filepath.Join(suite.Directory, "track.flac")}
+	if err := ioutil.WriteFile(track.Path, []byte("fLaC"), 0644); err != nil {
+		suite.T().Fatal(err)
+	}
+	cache := filepath.Join(suite.Directory, "waveforms")
+
+	_, err := repository.Get(track, cache)
+	assert.NotNil(suite.T(), err)
+
+	err = repository.Save(track, testWaveform, cache)
+	assert.Nil(suite.T(), err)
+	waveform, err := repository.Get(track, cache)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), testWaveform, waveform)
+
+	// Each track of a CUE sheet has its own waveform.
+	_, err = repository.Get(domain.Track{Path: track.Path, CueTrack: 2, StartMs: 1000}, cache)
+	assert.NotNil(suite.T(), err)
+
+	// Waveforms of modified media files are outdated.
+	modTime := time.Now().Add(time.Hour)
+	if err = os.Chtimes(track.Path, modTime, modTime); err != nil {
+		suite.T().Fatal(err)
+	}
+	_, err = repository.Get(track, cache)
+	assert.NotNil(suite.T(), err)
+
+	// Only the waveform file remains.
+	files, _ := ioutil.ReadDir(cache)
+	assert.Len(suite.T(), files, 1)
+}
@@ -0,0 +1,342 @@
+package interfaces
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/spf13/viper"
+)
+
+/*
+This file exposes the clients of the scrobbling services, ListenBrainz and Last.fm.
+
+The base URLs of the APIs are configurable, so self-hosted ListenBrainz instances or mock servers can be used.
+Submissions refused by a service are returned as business.ScrobbleRejectedError; any other failure, including
+authentication errors, keeps them queued until the service or the account works again.
+*/
+
+// Name of the client sent to the services.
+const scrobbleClientName = "Alba"
+
+var scrobbleHttpClient = &http.Client{Timeout: 10 * time.Second}
+
+/*
+Sends the queued scrobbles now, then every Scrobbling.RetryInterval minutes, so the plays reported while a service was
+unreachable are eventually sent. Never returns.
+*/
+func RunScrobbleQueue(interactor *business.LibraryInteractor) {
+	for {
+		flushScrobbles(interactor)
+		interval := viper.GetInt("Scrobbling.RetryInterval")
+		if interval <= 0 {
+			interval = 1
+		}
+		time.Sleep(time.Duration(interval) * time.Minute)
+	}
+}
+
+// Sends the queued scrobbles, logging the failures.
+func flushScrobbles(interactor *business.LibraryInteractor) {
+	if _, err := interactor.FlushScrobbles(); err != nil {
+		log.Println("ERROR - Can't send the scrobbles: " + err.Error())
+	}
+}
+
+// Client of the ListenBrainz API, see https://listenbrainz.readthedocs.io/en/latest/users/api/.
+type ListenBrainzScrobbler struct{}
+
+type listenBrainzListen struct {
+	ListenedAt    int64                     `json:"listened_at,omitempty"`
+	TrackMetadata listenBrainzTrackMetadata `json:"track_metadata"`
+}
+
+type listenBrainzTrackMetadata struct {
+	ArtistName     string                 `json:"artist_name"`
+	TrackName      string                 `json:"track_name"`
+	ReleaseName    string                 `json:"release_name,omitempty"`
+	AdditionalInfo map[string]interface{} `json:"additional_info"`
+}
+
+// Checks a ListenBrainz user token and gets the name of its user.
+func (s ListenBrainzScrobbler) LinkAccount(token string) (domain.ScrobbleAccount, error) {
+	var response struct {
+		Valid    bool   `json:"valid"`
+		UserName string `json:"user_name"`
+		Message  string `json:"message"`
+	}
+	err := s.request(http.MethodGet, "/1/validate-token", token, nil, &response)
+	if err != nil {
+		return domain.ScrobbleAccount{}, err
+	}
+	if !response.Valid {
+		return domain.ScrobbleAccount{}, errors.New("invalid ListenBrainz token: " + response.Message)
+	}
+
+	return domain.ScrobbleAccount{Service: domain.ScrobbleServiceListenBrainz, UserName: response.UserName, Token: token}, nil
+}
+
+func (s ListenBrainzScrobbler) NowPlaying(account domain.ScrobbleAccount, scrobble domain.Scrobble) error {
+	return s.submit(account, "playing_now", listenBrainzListen{TrackMetadata: getListenBrainzTrackMetadata(scrobble)})
+}
+
+func (s ListenBrainzScrobbler) Scrobble(account domain.ScrobbleAccount, scrobble domain.Scrobble) error {
+	return s.submit(account, "single", listenBrainzListen{
+		ListenedAt:    scrobble.ListenedAt,
+		TrackMetadata: getListenBrainzTrackMetadata(scrobble),
+	})
+}
+
+func (s ListenBrainzScrobbler) submit(account domain.ScrobbleAccount, listenType string, listen listenBrainzListen) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"listen_type": listenType,
+		"payload":     []listenBrainzListen{listen},
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.request(http.MethodPost, "/1/submit-listens", account.Token, body, nil)
+}
+
+// Sends a request to the ListenBrainz API, decoding the JSON response in result if given.
+func (s ListenBrainzScrobbler) request(method string, path string, token string, body []byte, result interface{}) error {
+	request, err := http.NewRequest(method, strings.TrimSuffix(viper.GetString("Scrobbling.ListenBrainz.ApiUrl"), "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Token "+token)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := scrobbleHttpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	content, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		var apiError struct {
+			Error string `json:"error"`
+		}
+		message := response.Status
+		if json.Unmarshal(content, &apiError) == nil && apiError.Error != "" {
+			message += ": " + apiError.Error
+		}
+		// Invalid listens. Other errors are about the token or the availability of the service.
+		if response.StatusCode == http.StatusBadRequest {
+			return business.ScrobbleRejectedError{Message: message}
+		}
+		return errors.New("ListenBrainz error " + message)
+	}
+	if result != nil {
+		return json.Unmarshal(content, result)
+	}
+
+	return nil
+}
+
+func getListenBrainzTrackMetadata(scrobble domain.Scrobble) listenBrainzTrackMetadata {
+	info := map[string]interface{}{
+		"media_player":      scrobbleClientName,
+		"submission_client": scrobbleClientName,
+	}
+	if scrobble.Number > 0 {
+		info["tracknumber"] = scrobble.Number
+	}
+	if scrobble.Duration > 0 {
+		info["duration_ms"] = scrobble.Duration * 1000
+	}
+	if scrobble.MusicBrainzTrackId != "" {
+		info["recording_mbid"] = scrobble.MusicBrainzTrackId
+	}
+
+	return listenBrainzTrackMetadata{
+		ArtistName:     scrobble.Artist,
+		TrackName:      scrobble.Title,
+		ReleaseName:    scrobble.Album,
+		AdditionalInfo: info,
+	}
+}
+
+/*
+Client of the Last.fm API, see https://www.last.fm/api/scrobbling.
+
+Accounts are linked with the web authentication: the user authorizes the application on the page given by
+getLastFmAuthUrl(), which redirects to the callback URL with a token exchanged for a session key.
+*/
+type LastFmScrobbler struct{}
+
+// Last.fm error codes of invalid parameters. Other errors are about the session, the API key or the availability of
+// the service.
+var lastFmRejectedErrors = map[int]bool{
+	6: true,
+}
+
+// Gets the session of the user who authorized a token.
+func (s LastFmScrobbler) LinkAccount(token string) (domain.ScrobbleAccount, error) {
+	var response struct {
+		Session struct {
+			Name string `json:"name"`
+			Key  string `json:"key"`
+		} `json:"session"`
+	}
+	err := s.call("auth.getSession", url.Values{"token": {token}}, &response)
+	if err != nil {
+		return domain.ScrobbleAccount{}, err
+	}
+	if response.Session.Key == "" {
+		return domain.ScrobbleAccount{}, errors.New("no Last.fm session")
+	}
+
+	return domain.ScrobbleAccount{
+		Service:  domain.ScrobbleServiceLastFm,
+		UserName: response.Session.Name,
+		Token:    response.Session.Key,
+	}, nil
+}
+
+func (s LastFmScrobbler) NowPlaying(account domain.ScrobbleAccount, scrobble domain.Scrobble) error {
+	params := getLastFmTrackParams(scrobble)
+	params.Set("sk", account.Token)
+
+	return s.call("track.updateNowPlaying", params, nil)
+}
+
+func (s LastFmScrobbler) Scrobble(account domain.ScrobbleAccount, scrobble domain.Scrobble) error {
+	params := getLastFmTrackParams(scrobble)
+	params.Set("sk", account.Token)
+	params.Set("timestamp", strconv.FormatInt(scrobble.ListenedAt, 10))
+
+	var response struct {
+		Scrobbles struct {
+			Attr struct {
+				Ignored int `json:"ignored"`
+			} `json:"@attr"`
+			Scrobble struct {
+				IgnoredMessage struct {
+					Code string `json:"code"`
+				} `json:"ignoredMessage"`
+			} `json:"scrobble"`
+		} `json:"scrobbles"`
+	}
+	if err := s.call("track.scrobble", params, &response); err != nil {
+		return err
+	}
+	if response.Scrobbles.Attr.Ignored > 0 {
+		return business.ScrobbleRejectedError{Message: "ignored by Last.fm, code " + response.Scrobbles.Scrobble.IgnoredMessage.Code}
+	}
+
+	return nil
+}
+
+// Calls a signed method of the Last.fm API, decoding the JSON response in result if given.
+func (s LastFmScrobbler) call(method string, params url.Values, result interface{}) error {
+	params.Set("method", method)
+	params.Set("api_key", viper.GetString("Scrobbling.LastFm.ApiKey"))
+	params.Set("api_sig", getLastFmSignature(params, viper.GetString("Scrobbling.LastFm.ApiSecret")))
+	params.Set("format", "json")
+
+	response, err := scrobbleHttpClient.PostForm(viper.GetString("Scrobbling.LastFm.ApiUrl"), params)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	content, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	// Errors are sent with various status codes.
+	var apiError struct {
+		Error   int    `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(content, &apiError) == nil && apiError.Error != 0 {
+		message := strconv.Itoa(apiError.Error) + " " + apiError.Message
+		if lastFmRejectedErrors[apiError.Error] {
+			return business.ScrobbleRejectedError{Message: message}
+		}
+		return errors.New("Last.fm error " + message)
+	}
+	if response.StatusCode != http.StatusOK {
+		return errors.New("Last.fm error " + response.Status)
+	}
+	if result != nil {
+		return json.Unmarshal(content, result)
+	}
+
+	return nil
+}
+
+func getLastFmTrackParams(scrobble domain.Scrobble) url.Values {
+	params := url.Values{
+		"artist": {scrobble.Artist},
+		"track":  {scrobble.Title},
+	}
+	if scrobble.Album != "" {
+		params.Set("album", scrobble.Album)
+	}
+	if scrobble.AlbumArtist != "" {
+		params.Set("albumArtist", scrobble.AlbumArtist)
+	}
+	if scrobble.Number > 0 {
+		params.Set("trackNumber", strconv.Itoa(scrobble.Number))
+	}
+	if scrobble.Duration > 0 {
+		params.Set("duration", strconv.Itoa(scrobble.Duration))
+	}
+	if scrobble.MusicBrainzTrackId != "" {
+		params.Set("mbid", scrobble.MusicBrainzTrackId)
+	}
+
+	return params
+}
+
+// Signs the parameters of a Last.fm API call: MD5 of the parameters sorted by name and of the secret.
+func getLastFmSignature(params url.Values, secret string) string {
+	var names []string
+	for name := range params {
+		if name != "format" && name != "callback" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var signed strings.Builder
+	for _, name := range names {
+		signed.WriteString(name)
+		signed.WriteString(params.Get(name))
+	}
+	signed.WriteString(secret)
+	sum := md5.Sum([]byte(signed.String()))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Gets the URL of the Last.fm page where users authorize the server to scrobble, redirecting to a callback URL.
+func getLastFmAuthUrl(callback string) string {
+	params := url.Values{"api_key": {viper.GetString("Scrobbling.LastFm.ApiKey")}}
+	if callback != "" {
+		params.Set("cb", callback)
+	}
+
+	return viper.GetString("Scrobbling.LastFm.AuthUrl") + "?" + params.Encode()
+}
@@ -0,0 +1,246 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ScrobblerTestSuite struct {
+	suite.Suite
+	Server   *httptest.Server
+	Requests []*http.Request // Requests received by the mock server, with their parsed form.
+	Bodies   []string
+	Response func(w http.ResponseWriter, r *http.Request)
+}
+
+// Go testing framework entry point.
+func TestScrobblerTestSuite(t *testing.T) {
+	suite.Run(t, new(ScrobblerTestSuite))
+}
+
+func (suite *ScrobblerTestSuite) SetupTest() {
+	suite.Requests = nil
+	suite.Bodies = nil
+	suite.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		suite.Bodies = append(suite.Bodies, string(body))
+		r.Form, _ = url.ParseQuery(string(body))
+		suite.Requests = append(suite.Requests, r)
+		suite.Response(w, r)
+	}))
+	viper.Set("Scrobbling.ListenBrainz.ApiUrl", suite.Server.URL+"/")
+	viper.Set("Scrobbling.LastFm.ApiUrl", suite.Server.URL+"/2.0/")
+	viper.Set("Scrobbling.LastFm.ApiKey", "key")
+	viper.Set("Scrobbling.LastFm.ApiSecret", "secret")
+}
+
+func (suite *ScrobblerTestSuite) TearDownTest() {
+	suite.Server.Close()
+	viper.Set("Scrobbling.ListenBrainz.ApiUrl", "")
+	viper.Set("Scrobbling.LastFm.ApiUrl", "")
+	viper.Set("Scrobbling.LastFm.ApiKey", "")
+	viper.Set("Scrobbling.LastFm.ApiSecret", "")
+}
+
+// Answers the requests with a status and a body.
+func (suite *ScrobblerTestSuite) respond(status int, body string) {
+	suite.Response = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func getTestScrobble() domain.Scrobble {
+	return domain.Scrobble{
+		Artist:             "Artist",
+		Title:              "Title",
+		Album:              "Album",
+		AlbumArtist:        "Album Artist",
+		Number:             3,
+		Duration:           200,
+		MusicBrainzTrackId: "mbid",
+		ListenedAt:         1000,
+	}
+}
+
+func (suite *ScrobblerTestSuite) TestListenBrainzLinkAccount() {
+	suite.respond(http.StatusOK, `{"code": 200, "message": "Token valid.", "valid": true, "user_name": "listener"}`)
+	account, err := ListenBrainzScrobbler{}.LinkAccount("token")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "listener", account.UserName)
+	assert.Equal(suite.T(), "token", account.Token)
+	assert.Equal(suite.T(), "/1/validate-token", suite.Requests[0].URL.Path)
+	assert.Equal(suite.T(), "Token token", suite.Requests[0].Header.Get("Authorization"))
+
+	suite.respond(http.StatusOK, `{"code": 200, "message": "Token invalid.", "valid": false}`)
+	_, err = ListenBrainzScrobbler{}.LinkAccount("invalid")
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *ScrobblerTestSuite) TestListenBrainzScrobble() {
+	account := domain.ScrobbleAccount{Token: "token"}
+	suite.respond(http.StatusOK, `{"status": "ok"}`)
+
+	err := ListenBrainzScrobbler{}.NowPlaying(account, getTestScrobble())
+	assert.Nil(suite.T(), err)
+	err = ListenBrainzScrobbler{}.Scrobble(account, getTestScrobble())
+	assert.Nil(suite.T(), err)
+
+	assert.Len(suite.T(), suite.Requests, 2)
+	assert.Equal(suite.T(), http.MethodPost, suite.Requests[1].Method)
+	assert.Equal(suite.T(), "/1/submit-listens", suite.Requests[1].URL.Path)
+	assert.Equal(suite.T(), "Token token", suite.Requests[1].Header.Get("Authorization"))
+
+	var submission struct {
+		ListenType string `json:"listen_type"`
+		Payload    []struct {
+			ListenedAt    int64 `json:"listened_at"`
+			TrackMetadata struct {
+				ArtistName     string                 `json:"artist_name"`
+				TrackName      string                 `json:"track_name"`
+				ReleaseName    string                 `json:"release_name"`
+				AdditionalInfo map[string]interface{} `json:"additional_info"`
+			} `json:"track_metadata"`
+		} `json:"payload"`
+	}
+	assert.Nil(suite.T(), json.Unmarshal([]byte(suite.Bodies[0]), &submission))
+	assert.Equal(suite.T(), "playing_now", submission.ListenType)
+	assert.Equal(suite.T(), int64(0), submission.Payload[0].ListenedAt)
+
+	assert.Nil(suite.T(), json.Unmarshal([]byte(suite.Bodies[1]), &submission))
+	assert.Equal(suite.T(), "single", submission.ListenType)
+	assert.Len(suite.T(), submission.Payload, 1)
+	assert.Equal(suite.T(), int64(1000), submission.Payload[0].ListenedAt)
+	assert.Equal(suite.T(), "Artist", submission.Payload[0].TrackMetadata.ArtistName)
+	assert.Equal(suite.T(), "Title", submission.Payload[0].TrackMetadata.TrackName)
+	assert.Equal(suite.T(), "Album", submission.Payload[0].TrackMetadata.ReleaseName)
+	assert.Equal(suite.T(), float64(200000), submission.Payload[0].TrackMetadata.AdditionalInfo["duration_ms"])
+	assert.Equal(suite.T(), "mbid", submission.Payload[0].TrackMetadata.AdditionalInfo["recording_mbid"])
+}
+
+func (suite *ScrobblerTestSuite) TestListenBrainzErrors() {
+	account := domain.ScrobbleAccount{Token: "token"}
+
+	// Invalid listens are rejected.
+	suite.respond(http.StatusBadRequest, `{"code": 400, "error": "Invalid listen"}`)
+	err := ListenBrainzScrobbler{}.Scrobble(account, getTestScrobble())
+	_, rejected := err.(business.ScrobbleRejectedError)
+	assert.True(suite.T(), rejected)
+	assert.Contains(suite.T(), err.Error(), "Invalid listen")
+
+	// Others are retried.
+	for _, status := range []int{http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		suite.respond(status, "")
+		err = ListenBrainzScrobbler{}.Scrobble(account, getTestScrobble())
+		assert.NotNil(suite.T(), err)
+		_, rejected = err.(business.ScrobbleRejectedError)
+		assert.False(suite.T(), rejected)
+	}
+
+	// Unreachable service.
+	suite.Server.Close()
+	err = ListenBrainzScrobbler{}.Scrobble(account, getTestScrobble())
+	assert.NotNil(suite.T(), err)
+	_, rejected = err.(business.ScrobbleRejectedError)
+	assert.False(suite.T(), rejected)
+}
+
+func (suite *ScrobblerTestSuite) TestLastFmLinkAccount() {
+	suite.respond(http.StatusOK, `{"session": {"name": "listener", "key": "session key", "subscriber": 0}}`)
+	account, err := LastFmScrobbler{}.LinkAccount("token")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "listener", account.UserName)
+	assert.Equal(suite.T(), "session key", account.Token)
+
+	form := suite.Requests[0].Form
+	assert.Equal(suite.T(), "/2.0/", suite.Requests[0].URL.Path)
+	assert.Equal(suite.T(), "auth.getSession", form.Get("method"))
+	assert.Equal(suite.T(), "token", form.Get("token"))
+	assert.Equal(suite.T(), "key", form.Get("api_key"))
+	assert.Equal(suite.T(), "json", form.Get("format"))
+	assert.Equal(suite.T(), getLastFmSignature(url.Values{"method": {"auth.getSession"}, "token": {"token"}, "api_key": {"key"}}, "secret"), form.Get("api_sig"))
+
+	suite.respond(http.StatusForbidden, `{"error": 14, "message": "Unauthorized Token - This token has not been authorized"}`)
+	_, err = LastFmScrobbler{}.LinkAccount("token")
+	assert.NotNil(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "Unauthorized Token")
+}
+
+func (suite *ScrobblerTestSuite) TestLastFmScrobble() {
+	account := domain.ScrobbleAccount{Token: "session key"}
+	suite.respond(http.StatusOK, `{"nowplaying": {}}`)
+	err := LastFmScrobbler{}.NowPlaying(account, getTestScrobble())
+	assert.Nil(suite.T(), err)
+	form := suite.Requests[0].Form
+	assert.Equal(suite.T(), "track.updateNowPlaying", form.Get("method"))
+	assert.Equal(suite.T(), "session key", form.Get("sk"))
+	assert.Empty(suite.T(), form.Get("timestamp"))
+
+	suite.respond(http.StatusOK, `{"scrobbles": {"@attr": {"accepted": 1, "ignored": 0}, "scrobble": {}}}`)
+	err = LastFmScrobbler{}.Scrobble(account, getTestScrobble())
+	assert.Nil(suite.T(), err)
+	form = suite.Requests[1].Form
+	assert.Equal(suite.T(), "track.scrobble", form.Get("method"))
+	assert.Equal(suite.T(), "1000", form.Get("timestamp"))
+	assert.Equal(suite.T(), "Artist", form.Get("artist"))
+	assert.Equal(suite.T(), "Title", form.Get("track"))
+	assert.Equal(suite.T(), "Album", form.Get("album"))
+	assert.Equal(suite.T(), "Album Artist", form.Get("albumArtist"))
+	assert.Equal(suite.T(), "3", form.Get("trackNumber"))
+	assert.Equal(suite.T(), "200", form.Get("duration"))
+	assert.Equal(suite.T(), "mbid", form.Get("mbid"))
+
+	// Ignored scrobbles are rejected.
+	suite.respond(http.StatusOK, `{"scrobbles": {"@attr": {"accepted": 0, "ignored": 1}, "scrobble": {"ignoredMessage": {"code": "3", "#text": "Timestamp too old"}}}}`)
+	err = LastFmScrobbler{}.Scrobble(account, getTestScrobble())
+	_, rejected := err.(business.ScrobbleRejectedError)
+	assert.True(suite.T(), rejected)
+}
+
+func (suite *ScrobblerTestSuite) TestLastFmErrors() {
+	account := domain.ScrobbleAccount{Token: "session key"}
+
+	suite.respond(http.StatusBadRequest, `{"error": 6, "message": "Invalid parameters"}`)
+	err := LastFmScrobbler{}.Scrobble(account, getTestScrobble())
+	_, rejected := err.(business.ScrobbleRejectedError)
+	assert.True(suite.T(), rejected)
+
+	// Invalid sessions, unavailable service and rate limits are retried.
+	for _, code := range []string{"9", "11", "16", "29"} {
+		suite.respond(http.StatusOK, `{"error": `+code+`, "message": "Error"}`)
+		err = LastFmScrobbler{}.Scrobble(account, getTestScrobble())
+		assert.NotNil(suite.T(), err)
+		_, rejected = err.(business.ScrobbleRejectedError)
+		assert.False(suite.T(), rejected)
+	}
+
+	suite.respond(http.StatusBadGateway, "Bad gateway")
+	err = LastFmScrobbler{}.Scrobble(account, getTestScrobble())
+	assert.NotNil(suite.T(), err)
+	_, rejected = err.(business.ScrobbleRejectedError)
+	assert.False(suite.T(), rejected)
+}
+
+func (suite *ScrobblerTestSuite) TestGetLastFmSignature() {
+	params := url.Values{"method": {"auth.getSession"}, "api_key": {"key"}, "token": {"token"}, "format": {"json"}}
+	// MD5 of "api_keykeymethodauth.getSessiontokentokensecret".
+	assert.Equal(suite.T(), "9ac306496295a8866c4a8673395540eb", getLastFmSignature(params, "secret"))
+}
+
+func (suite *ScrobblerTestSuite) TestGetLastFmAuthUrl() {
+	viper.Set("Scrobbling.LastFm.AuthUrl", "https://www.last.fm/api/auth/")
+	defer viper.Set("Scrobbling.LastFm.AuthUrl", "")
+
+	assert.Equal(suite.T(), "https://www.last.fm/api/auth/?api_key=key&cb=http%3A%2F%2Fclient%2Fcallback", getLastFmAuthUrl("http://client/callback"))
+	assert.Equal(suite.T(), "https://www.last.fm/api/auth/?api_key=key", getLastFmAuthUrl(""))
+}
@@ -1,11 +1,21 @@
 package interfaces
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"log"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
 	"github.com/spf13/viper"
 )
 
@@ -18,23 +28,525 @@ func NewMediaStreamHandler(ci *business.LibraryInteractor) *mediaStreamHandler {
 	return &mediaStreamHandler{Interactor: ci}
 }
 
-// Streams a file located on disk from a track id.
+/*
+Streams a file located on disk from a track id.
+
+The URL must be signed, see signMediaUrl(). Files are served with validators, so they can be cached and requested
+conditionally, and with byte ranges. The "replayGain" query parameter, "track" or "album", asks for the ReplayGain to be applied if transcoding is enabled. The
+"download" query parameter asks for the file to be saved instead of played.
+*/
 func (h mediaStreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !checkMediaUrl(w, r, "/stream/"+r.URL.Path) {
+		return
+	}
 	trackId, err := strconv.Atoi(r.URL.Path)
 	if err != nil {
-		fmt.Fprint(w, "Invalid id")
+		http.Error(w, "Invalid id", http.StatusBadRequest)
 		return
 	}
 
 	// Try to find a corresponding track.
 	track, err := h.Interactor.TrackRepository.Get(trackId)
 	if err != nil {
-		fmt.Fprint(w, "Track not found")
+		http.Error(w, "Track not found", http.StatusNotFound)
+		return
+	}
+
+	serveTrack(w, r, track)
+}
+
+// Streams the media file of a track, see mediaStreamHandler.ServeHTTP() for the query parameters.
+func serveTrack(w http.ResponseWriter, r *http.Request, track domain.Track) {
+	file, stat, ok := openServedFile(w, track.Path)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	// The ReplayGain can be applied server-side, which requires transcoding.
+	if mode := r.URL.Query().Get("replayGain"); mode != "" {
+		if gainDb, ok := business.ReplayGainAdjustment(track, mode); ok {
+			options := getTranscodingOptions()
+			options.StartMs = track.StartMs
+			options.EndMs = track.EndMs
+			options.GainDb = gainDb
+			if serveTranscodedTrack(w, r, track, options) {
+				return
+			}
+		}
+	}
+
+	// Tracks of CUE sheets are a part of a media file.
+	if track.CueTrack != 0 {
+		serveMediaSegment(w, r, file, stat, track)
+		return
+	}
+
+	w.Header().Set("Content-Type", getMediaContentType(track.Path))
+	w.Header().Set("ETag", getFileETag(stat, 0, 0))
+	setContentDisposition(w, r, getTrackFileName(track, filepath.Ext(track.Path)))
+	http.ServeContent(w, r, stat.Name(), stat.ModTime(), file)
+}
+
+// Streams the part of a media file used by a track, cutting it if possible or else transcoding it.
+func serveMediaSegment(w http.ResponseWriter, r *http.Request, file *os.File, stat os.FileInfo, track domain.Track) {
+	segment, err := getMediaSegment(file, track.StartMs, track.EndMs)
+	if err == nil {
+		w.Header().Set("Content-Type", getMediaContentType(track.Path))
+		w.Header().Set("ETag", getFileETag(stat, track.StartMs, track.EndMs))
+		setContentDisposition(w, r, getTrackFileName(track, filepath.Ext(track.Path)))
+		http.ServeContent(w, r, stat.Name(), stat.ModTime(), segment)
+		return
+	}
+	if err != errUnsupportedSegmentFormat {
+		log.Println("ERROR - Can't cut media file " + track.Path + ": " + err.Error())
+	}
+
+	options := getTranscodingOptions()
+	options.StartMs = track.StartMs
+	options.EndMs = track.EndMs
+	if !serveTranscodedTrack(w, r, track, options) {
+		http.Error(w, "Media file cannot be streamed without transcoding", http.StatusNotImplemented)
+	}
+}
+
+// Streams a transcoded track. Returns false if nothing was sent because transcoding is disabled.
+func serveTranscodedTrack(w http.ResponseWriter, r *http.Request, track domain.Track, options transcodingOptions) bool {
+	if viper.GetString("Transcoding.FfmpegPath") == "" {
+		return false
+	}
+
+	setContentDisposition(w, r, getTrackFileName(track, transcodingFormats[options.Format].Extension))
+	if err := transcode(w, r, track.Path, options); err != nil {
+		log.Println("ERROR - Can't transcode media file " + track.Path + ": " + err.Error())
+	}
+
+	return true
+}
+
+type waveformHandler struct {
+	Interactor *business.LibraryInteractor
+}
+
+func NewWaveformHandler(ci *business.LibraryInteractor) *waveformHandler {
+	return &waveformHandler{Interactor: ci}
+}
+
+// Serves the waveform of a track from a track id. The URL must be signed, see signMediaUrl().
+//
+// Query parameters: "points", the maximum number of points (1000 by default), "format", "json" (default) or "binary"
+// for the audiowaveform formats, and "bits", 8 (default) or 16 for the size of the values.
+func (h waveformHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !checkMediaUrl(w, r, "/waveform/"+r.URL.Path) {
+		return
+	}
+	trackId, err := strconv.Atoi(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	points := 1000
+	if query.Get("points") != "" {
+		if points, err = strconv.Atoi(query.Get("points")); err != nil || points < 1 {
+			http.Error(w, "Invalid number of points", http.StatusBadRequest)
+			return
+		}
+	}
+	bits := 8
+	if query.Get("bits") == "16" {
+		bits = 16
+	}
+
+	track, err := h.Interactor.TrackRepository.Get(trackId)
+	if err != nil {
+		http.Error(w, "Track not found", http.StatusNotFound)
+		return
+	}
+	if _, err = os.Stat(track.Path); os.IsNotExist(err) {
+		http.Error(w, "File not found", http.StatusGone)
+		return
+	}
+
+	// Waveforms can't be computed for the formats which can't be decoded.
+	waveform, err := h.Interactor.GetWaveform(trackId, points)
+	if err != nil {
+		http.Error(w, "Waveform not available", http.StatusUnprocessableEntity)
 		return
 	}
 
-	http.ServeFile(w, r, track.Path)
-	return
+	if query.Get("format") == "binary" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		err = writeWaveformDat(w, waveform, bits)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(newWaveformJson(waveform, bits))
+	}
+	if err != nil {
+		log.Println("ERROR - Can't write waveform of track " + strconv.Itoa(trackId) + ": " + err.Error())
+	}
+}
+
+type downloadHandler struct {
+	Interactor *business.LibraryInteractor
+}
+
+func NewDownloadHandler(ci *business.LibraryInteractor) *downloadHandler {
+	return &downloadHandler{Interactor: ci}
+}
+
+// Streams a ZIP archive of an album, an artist or a playlist, from a path like "album/<id>", "artist/<id>" or
+// "playlist/<track id>,<track id>...". The URL must be signed, see signMediaUrl(), so the tracks of the playlists
+// can't be changed.
+//
+// The "format" query parameter, one of the transcoding formats, and the optional "bitrate" one in kbit/s ask for the
+// tracks to be transcoded, if transcoding is enabled. The "name" query parameter is the name of a playlist.
+func (h downloadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !checkMediaUrl(w, r, "/download/"+r.URL.Path) {
+		return
+	}
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) != 2 || (parts[0] != "album" && parts[0] != "artist" && parts[0] != "playlist") {
+		http.NotFound(w, r)
+		return
+	}
+	values := strings.Split(parts[1], ",")
+	if parts[0] != "playlist" && len(values) > 1 {
+		http.NotFound(w, r)
+		return
+	}
+	ids := make([]int, len(values))
+	for i, value := range values {
+		id, errId := strconv.Atoi(value)
+		if errId != nil {
+			http.Error(w, "Invalid id", http.StatusBadRequest)
+			return
+		}
+		ids[i] = id
+	}
+	id := ids[0]
+
+	var options *transcodingOptions
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		if _, ok := transcodingFormats[format]; !ok {
+			http.Error(w, "Invalid format", http.StatusBadRequest)
+			return
+		}
+		if viper.GetString("Transcoding.FfmpegPath") == "" {
+			http.Error(w, "Transcoding is disabled", http.StatusNotImplemented)
+			return
+		}
+		profile := getTranscodingOptions()
+		profile.Format = format
+		if bitrate, errBitrate := strconv.Atoi(r.URL.Query().Get("bitrate")); errBitrate == nil && bitrate > 0 {
+			profile.Bitrate = bitrate
+		}
+		options = &profile
+	}
+
+	var download business.Download
+	var err error
+	switch parts[0] {
+	case "album":
+		download, err = h.Interactor.GetAlbumDownload(id)
+	case "artist":
+		download, err = h.Interactor.GetArtistDownload(id)
+	default:
+		download, err = h.Interactor.GetPlaylistDownload(r.URL.Query().Get("name"), ids)
+	}
+	if err == business.ErrDownloadsDisabled {
+		http.Error(w, "Downloads are disabled", http.StatusForbidden)
+		return
+	} else if err != nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	serveDownload(w, r, download, options)
+}
+
+// Streams a download archive, transcoding the tracks if transcoding options are given.
+func serveDownload(w http.ResponseWriter, r *http.Request, download business.Download, options *transcodingOptions) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": download.Name + ".zip"}))
+	if err := writeDownloadArchive(r.Context(), w, download, options); err != nil {
+		log.Println("ERROR - Can't write download " + download.Name + ": " + err.Error())
+	}
+}
+
+type shareHandler struct {
+	Interactor *business.LibraryInteractor
+	sessions   *shareSessions
+}
+
+func NewShareHandler(ci *business.LibraryInteractor) *shareHandler {
+	return &shareHandler{Interactor: ci, sessions: newShareSessions()}
+}
+
+/*
+Serves a share, from a path like "<slug>" for its page, "<slug>/items" for its content as JSON, "<slug>/stream/<track
+id>" for its tracks, "<slug>/cover" for its cover or "<slug>/download" for the archive of a shared album or playlist.
+*/
+func (h shareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	share, err := h.Interactor.GetShare(parts[0])
+	if err == business.ErrShareExpired {
+		http.Error(w, "Share expired", http.StatusGone)
+		return
+	} else if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 || (len(parts) == 2 && parts[1] == "items"):
+		h.servePage(w, r, share, len(parts) == 2)
+	case len(parts) == 2 && parts[1] == "cover":
+		h.serveCover(w, r, share)
+	case len(parts) == 2 && parts[1] == "download":
+		h.serveDownload(w, r, share)
+	case len(parts) == 3 && parts[1] == "stream":
+		h.serveTrack(w, r, share, parts[2])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Serves the page of a share, or its content as JSON.
+func (h shareHandler) servePage(w http.ResponseWriter, r *http.Request, share domain.Share, asJson bool) {
+	content, err := h.Interactor.GetSharedContent(share)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	session, err := h.sessions.start(share.Id, time.Now())
+	if err != nil {
+		log.Println("ERROR - Can't start session of share " + share.Slug + ": " + err.Error())
+		http.Error(w, "Share not available", http.StatusInternalServerError)
+		return
+	}
+	page := newSharePage(share, content, "/share/"+share.Slug, session)
+
+	// The number of plays left and the session change.
+	w.Header().Set("Cache-Control", "no-cache")
+	if asJson {
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(page)
+	} else {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err = sharePageTemplate.Execute(w, page)
+	}
+	if err != nil {
+		log.Println("ERROR - Can't write share " + share.Slug + ": " + err.Error())
+	}
+}
+
+// Streams the cover of a share.
+func (h shareHandler) serveCover(w http.ResponseWriter, r *http.Request, share domain.Share) {
+	content, err := h.Interactor.GetSharedContent(share)
+	if err != nil || content.CoverId == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	cover, err := h.Interactor.CoverRepository.Get(content.CoverId)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	serveCover(w, r, cover)
+}
+
+// Streams the archive of a shared album or playlist, which counts as a play.
+func (h shareHandler) serveDownload(w http.ResponseWriter, r *http.Request, share domain.Share) {
+	if share.EntityType == domain.ShareEntityTrack {
+		http.NotFound(w, r)
+		return
+	}
+	if !share.AllowDownload {
+		http.Error(w, "Downloads are not allowed", http.StatusForbidden)
+		return
+	}
+
+	var download business.Download
+	var err error
+	if share.EntityType == domain.ShareEntityPlaylist {
+		download, err = h.Interactor.GetPlaylistDownload(share.Name, share.TrackIds)
+	} else {
+		download, err = h.Interactor.GetAlbumDownload(share.EntityId)
+	}
+	if err == business.ErrDownloadsDisabled {
+		http.Error(w, "Downloads are disabled", http.StatusForbidden)
+		return
+	} else if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !h.addPlay(w, share) {
+		return
+	}
+
+	serveDownload(w, r, download, nil)
+}
+
+/*
+Streams a track of a share.
+
+The URL must hold the "session" query parameter of the page, see shareSessions. A play is counted the first time the
+track is requested in the session.
+*/
+func (h shareHandler) serveTrack(w http.ResponseWriter, r *http.Request, share domain.Share, id string) {
+	trackId, err := strconv.Atoi(id)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+	track, err := h.Interactor.GetSharedTrack(share, trackId)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if download, _ := strconv.ParseBool(r.URL.Query().Get("download")); download && !share.AllowDownload {
+		http.Error(w, "Downloads are not allowed", http.StatusForbidden)
+		return
+	}
+
+	session := r.URL.Query().Get("session")
+	count, ok := h.sessions.play(session, share.Id, track.Id, time.Now())
+	if !ok {
+		http.Error(w, "Expired session, reload the page", http.StatusForbidden)
+		return
+	}
+	if count && !h.addPlay(w, share) {
+		// The track can be requested again once plays are available.
+		h.sessions.cancelPlay(session, track.Id)
+		return
+	}
+
+	serveTrack(w, r, track)
+}
+
+// Counts a play of a share. Answers 403 Forbidden if there are no plays left, and returns false.
+func (h shareHandler) addPlay(w http.ResponseWriter, share domain.Share) bool {
+	err := h.Interactor.AddSharePlay(share)
+	if err == business.ErrSharePlaysExhausted {
+		http.Error(w, "Maximum number of plays reached", http.StatusForbidden)
+		return false
+	} else if err != nil {
+		log.Println("ERROR - Can't count play of share " + share.Slug + ": " + err.Error())
+		http.Error(w, "Share not available", http.StatusInternalServerError)
+		return false
+	}
+
+	return true
+}
+
+type hlsHandler struct {
+	Interactor *business.LibraryInteractor
+	segmenter  *hlsSegmenter
+}
+
+// Creates the HLS handler, which removes the old HLS files every minute.
+func NewHlsHandler(ci *business.LibraryInteractor) *hlsHandler {
+	handler := &hlsHandler{Interactor: ci, segmenter: newHlsSegmenter()}
+	go func() {
+		for range time.Tick(time.Minute) {
+			handler.segmenter.cleanUp(time.Duration(viper.GetInt("Hls.CacheDuration")) * time.Minute)
+		}
+	}()
+
+	return handler
+}
+
+// Serves the HLS playlists and segments of a track, from a path like "<track id>/playlist.m3u8" for the master
+// playlist, "<track id>/<bitrate>/playlist.m3u8" for the playlist of a variant or "<track id>/<bitrate>/<n>.ts" for
+// a segment. The URLs must be signed, see signMediaUrl().
+func (h hlsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !checkMediaUrl(w, r, "/hls/"+r.URL.Path) {
+		return
+	}
+	// The URLs in the playlists are signed for the same user.
+	user := r.URL.Query().Get("user")
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) != 2 && len(parts) != 3 {
+		http.NotFound(w, r)
+		return
+	}
+	trackId, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+	track, err := h.Interactor.TrackRepository.Get(trackId)
+	if err != nil {
+		http.Error(w, "Track not found", http.StatusNotFound)
+		return
+	}
+	if _, err = os.Stat(track.Path); os.IsNotExist(err) {
+		http.Error(w, "File not found", http.StatusGone)
+		return
+	}
+
+	bitrates := getHlsBitrates()
+	if len(parts) == 2 {
+		if parts[1] != hlsPlaylistName {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		fmt.Fprint(w, getHlsMasterPlaylist(trackId, bitrates, user, time.Now()))
+		return
+	}
+
+	bitrate, _ := strconv.Atoi(parts[1])
+	valid := false
+	for _, value := range bitrates {
+		valid = valid || value == bitrate
+	}
+	name := parts[2]
+	if !valid || (name != hlsPlaylistName && !isHlsSegmentName(name)) {
+		http.NotFound(w, r)
+		return
+	}
+
+	job, err := h.segmenter.getJob(track, bitrate)
+	if err == errTranscodingDisabled {
+		http.Error(w, "Media file cannot be streamed without transcoding", http.StatusNotImplemented)
+		return
+	} else if err != nil {
+		log.Println("ERROR - Can't transcode media file " + track.Path + ": " + err.Error())
+		http.Error(w, "Transcoding failed", http.StatusInternalServerError)
+		return
+	}
+
+	path, err := job.waitForFile(r.Context(), name)
+	if os.IsNotExist(err) {
+		// Segments past the end of the track.
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		// The client has gone, or ffmpeg failed.
+		if r.Context().Err() == nil {
+			http.Error(w, "Transcoding failed", http.StatusInternalServerError)
+		}
+		return
+	}
+	if name == hlsPlaylistName {
+		playlist, errRead := ioutil.ReadFile(path)
+		if errRead != nil {
+			http.Error(w, "Transcoding failed", http.StatusInternalServerError)
+			return
+		}
+		// The playlist grows while the segments are written.
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		w.Header().Set("Cache-Control", "no-cache")
+		base := "/hls/" + parts[0] + "/" + parts[1] + "/"
+		fmt.Fprint(w, signHlsPlaylist(string(playlist), base, user, time.Now()))
+		return
+	}
+	w.Header().Set("Content-Type", "video/mp2t")
+	http.ServeFile(w, r, path)
 }
 
 type coverStreamHandler struct {
@@ -45,23 +557,144 @@ func NewCoverStreamHandler(ci *business.LibraryInteractor) *coverStreamHandler {
 	return &coverStreamHandler{Interactor: ci}
 }
 
-// Streams a file located on disk from a track id.
+// Streams a file located on disk from a cover id, with validators so it can be cached and requested conditionally.
+// The URL must be signed, see signMediaUrl().
 func (h coverStreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !checkMediaUrl(w, r, "/covers/"+r.URL.Path) {
+		return
+	}
 	coverId, err := strconv.Atoi(r.URL.Path)
 	if err != nil {
-		fmt.Fprint(w, "Invalid id")
+		http.Error(w, "Invalid id", http.StatusBadRequest)
 		return
 	}
 
 	// Try to find a cover.
 	cover, err := h.Interactor.CoverRepository.Get(coverId)
 	if err != nil {
-		fmt.Fprint(w, "Cover not found")
+		http.Error(w, "Cover not found", http.StatusNotFound)
 		return
 	}
 
+	serveCover(w, r, cover)
+}
+
+// Streams the file of a cover.
+func serveCover(w http.ResponseWriter, r *http.Request, cover domain.Cover) {
 	filePath := viper.GetString("Covers.Directory") + "/" + cover.Path
+	file, stat, ok := openServedFile(w, filePath)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	// The content type is found from the name.
+	w.Header().Set("ETag", getFileETag(stat, 0, 0))
+	http.ServeContent(w, r, stat.Name(), stat.ModTime(), file)
+}
+
+// Content types of the media files, from their extension. Audio formats are often missing from the system MIME types.
+var mediaContentTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".flac": "audio/flac",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": "audio/ogg",
+	".m4a":  "audio/mp4",
+}
+
+// Gets the content type of a media file.
+func getMediaContentType(path string) string {
+	if contentType, ok := mediaContentTypes[strings.ToLower(filepath.Ext(path))]; ok {
+		return contentType
+	}
+
+	return "application/octet-stream"
+}
+
+/*
+Opens a file to serve it.
+
+Answers 410 Gone if the file doesn't exist, as it is still referenced by the library, or 500 if it can't be read.
+Returns false if the file can't be served.
+*/
+func openServedFile(w http.ResponseWriter, path string) (*os.File, os.FileInfo, bool) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		http.Error(w, "File not found", http.StatusGone)
+		return nil, nil, false
+	} else if err != nil {
+		log.Println("ERROR - Can't open file " + path + ": " + err.Error())
+		http.Error(w, "File not readable", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		file.Close()
+		http.Error(w, "File not readable", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	return file, stat, true
+}
+
+/*
+Gets the entity tag of a file, or of a part of it for the tracks of CUE sheets, from its modification time and size.
+
+The same bytes are always served for a tag, so it is a strong one usable with byte ranges.
+*/
+func getFileETag(stat os.FileInfo, startMs int, endMs int) string {
+	tag := strconv.FormatInt(stat.ModTime().UnixNano(), 16) + "-" + strconv.FormatInt(stat.Size(), 16)
+	if startMs > 0 || endMs > 0 {
+		tag += "-" + strconv.Itoa(startMs) + "-" + strconv.Itoa(endMs)
+	}
+
+	return `"` + tag + `"`
+}
+
+// Gets the file name of a track for the clients, with an extension.
+func getTrackFileName(track domain.Track, extension string) string {
+	return business.SanitizeFileName(track.Title) + strings.ToLower(extension)
+}
+
+// Sets the name of a served file, and asks for it to be saved instead of displayed if the "download" query parameter
+// is set.
+func setContentDisposition(w http.ResponseWriter, r *http.Request, name string) {
+	disposition := "inline"
+	if download, _ := strconv.ParseBool(r.URL.Query().Get("download")); download {
+		disposition = "attachment"
+	}
+
+	if value := mime.FormatMediaType(disposition, map[string]string{"filename": name}); value != "" {
+		w.Header().Set("Content-Disposition", value)
+	}
+}
+
+type subscriptionHandler struct {
+	GraphQL  *graphQLInteractor
+	upgrader websocket.Upgrader
+}
+
+func NewSubscriptionHandler(gi *graphQLInteractor) *subscriptionHandler {
+	return &subscriptionHandler{
+		GraphQL: gi,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{subscriptionProtocol},
+			// Cross-domain requests are allowed, like on the other endpoints.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// Serves the GraphQL subscriptions over a WebSocket connection.
+func (h subscriptionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The upgrader answers the failed handshakes.
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
 
-	http.ServeFile(w, r, filePath)
-	return
+	serveSubscriptions(conn, h.GraphQL, anonymousUser)
 }
@@ -0,0 +1,426 @@
+package interfaces
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ServerTestSuite struct {
+	suite.Suite
+	Interactor business.LibraryInteractor
+	Directory  string
+}
+
+// Go testing framework entry point.
+func TestServerTestSuite(t *testing.T) {
+	suite.Run(t, new(ServerTestSuite))
+}
+
+func (suite *ServerTestSuite) SetupSuite() {
+	ds, err := createTestDatasource()
+	if err != nil {
+		log.Fatal(err)
+	}
+	appContext := AppContext{DB: ds}
+	suite.Interactor = business.LibraryInteractor{
+		ArtistRepository: ArtistDbRepository{AppContext: &appContext},
+		AlbumRepository:  AlbumDbRepository{AppContext: &appContext},
+		TrackRepository:  TrackDbRepository{AppContext: &appContext},
+		CoverRepository:  CoverDbRepository{AppContext: &appContext},
+		ShareRepository:  ShareDbRepository{AppContext: &appContext},
+	}
+}
+
+func (suite *ServerTestSuite) TearDownSuite() {
+	if repository, ok := suite.Interactor.TrackRepository.(TrackDbRepository); ok == true {
+		if err := closeTestDataSource(repository.AppContext.DB); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func (suite *ServerTestSuite) SetupTest() {
+	if repository, ok := suite.Interactor.TrackRepository.(TrackDbRepository); ok == true {
+		resetTestDataSource(repository.AppContext.DB)
+	}
+
+	directory, err := ioutil.TempDir("", "alba-server")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.Directory = directory
+	viper.Set("Covers.Directory", directory)
+	viper.Set("Transcoding.FfmpegPath", "")
+	viper.Set("Shares.Enabled", true)
+	viper.Set("Downloads.Enabled", true)
+}
+
+func (suite *ServerTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.Directory)
+	viper.Set("Covers.Directory", nil)
+	viper.Set("Transcoding.FfmpegPath", nil)
+	viper.Set("Shares.Enabled", nil)
+	viper.Set("Downloads.Enabled", nil)
+}
+
+// Writes a file in the test directory and returns its path.
+func (suite *ServerTestSuite) createFile(name string, content []byte) string {
+	path := filepath.Join(suite.Directory, name)
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		suite.T().Fatal(err)
+	}
+
+	return path
+}
+
+// Sends a request to a handler, the path being relative to the handler prefix.
+func serveTestRequest(handler http.Handler, path string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("GET", "/"+path, nil)
+	request.URL.Path = strings.TrimPrefix(request.URL.Path, "/")
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func (suite *ServerTestSuite) TestMediaStreamErrors() {
+	handler := NewMediaStreamHandler(&suite.Interactor)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, serveTestRequest(handler, "abc", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, serveTestRequest(handler, "99", nil).Code)
+	// The media file of the test track doesn't exist.
+	assert.Equal(suite.T(), http.StatusGone, serveTestRequest(handler, "1", nil).Code)
+}
+
+func (suite *ServerTestSuite) TestMediaStream() {
+	track := domain.Track{Title: "Who? Me", Path: suite.createFile("track.MP3", []byte("mp3 content"))}
+	if err := suite.Interactor.TrackRepository.Save(&track); err != nil {
+		suite.T().Fatal(err)
+	}
+	handler := NewMediaStreamHandler(&suite.Interactor)
+	path := strconv.Itoa(track.Id)
+
+	response := serveTestRequest(handler, path, nil)
+	assert.Equal(suite.T(), http.StatusOK, response.Code)
+	assert.Equal(suite.T(), "mp3 content", response.Body.String())
+	assert.Equal(suite.T(), "audio/mpeg", response.Header().Get("Content-Type"))
+	assert.Equal(suite.T(), "inline; filename=\"Who_ Me.mp3\"", response.Header().Get("Content-Disposition"))
+	assert.NotEmpty(suite.T(), response.Header().Get("Last-Modified"))
+	etag := response.Header().Get("ETag")
+	assert.Regexp(suite.T(), `^"[0-9a-f]+-b"$`, etag)
+
+	response = serveTestRequest(handler, path+"?download=1", nil)
+	assert.Equal(suite.T(), "attachment; filename=\"Who_ Me.mp3\"", response.Header().Get("Content-Disposition"))
+
+	// Conditional requests.
+	response = serveTestRequest(handler, path, map[string]string{"If-None-Match": etag})
+	assert.Equal(suite.T(), http.StatusNotModified, response.Code)
+	assert.Empty(suite.T(), response.Body.String())
+	response = serveTestRequest(handler, path, map[string]string{"If-None-Match": `"other"`})
+	assert.Equal(suite.T(), http.StatusOK, response.Code)
+	response = serveTestRequest(handler, path, map[string]string{"If-Modified-Since": response.Header().Get("Last-Modified")})
+	assert.Equal(suite.T(), http.StatusNotModified, response.Code)
+
+	// Byte ranges, only if the file hasn't changed.
+	response = serveTestRequest(handler, path, map[string]string{"Range": "bytes=4-", "If-Range": etag})
+	assert.Equal(suite.T(), http.StatusPartialContent, response.Code)
+	assert.Equal(suite.T(), "content", response.Body.String())
+	response = serveTestRequest(handler, path, map[string]string{"Range": "bytes=4-", "If-Range": `"other"`})
+	assert.Equal(suite.T(), http.StatusOK, response.Code)
+}
+
+func (suite *ServerTestSuite) TestSignedUrls() {
+	viper.Set("MediaUrls.Signed", true)
+	viper.Set("MediaUrls.Secret", "secret")
+	viper.Set("MediaUrls.Expiry", 24)
+	defer viper.Set("MediaUrls.Signed", false)
+	defer viper.Set("MediaUrls.Expiry", 0)
+	defer viper.Set("MediaUrls.Secret", "")
+
+	// Unsigned URLs are rejected before looking for the track, so the ids can't be enumerated.
+	streamHandler := NewMediaStreamHandler(&suite.Interactor)
+	assert.Equal(suite.T(), http.StatusForbidden, serveTestRequest(streamHandler, "1", nil).Code)
+	assert.Equal(suite.T(), http.StatusForbidden, serveTestRequest(streamHandler, "99", nil).Code)
+	signed := strings.TrimPrefix(signMediaUrl("/stream/1", anonymousUser, time.Now()), "/stream/")
+	assert.Equal(suite.T(), http.StatusGone, serveTestRequest(streamHandler, signed, nil).Code)
+
+	coverHandler := NewCoverStreamHandler(&suite.Interactor)
+	assert.Equal(suite.T(), http.StatusForbidden, serveTestRequest(coverHandler, "1", nil).Code)
+	assert.Equal(suite.T(), http.StatusForbidden, serveTestRequest(coverHandler, signed, nil).Code)
+	signed = strings.TrimPrefix(signMediaUrl("/covers/1", anonymousUser, time.Now()), "/covers/")
+	assert.Equal(suite.T(), http.StatusGone, serveTestRequest(coverHandler, signed, nil).Code)
+
+	// The other media URLs.
+	routes := []struct {
+		prefix  string
+		handler http.Handler
+		path    string
+		status  int // Status of the signed URL.
+	}{
+		{"/waveform/", NewWaveformHandler(&suite.Interactor), "1", http.StatusGone},
+		{"/download/", NewDownloadHandler(&suite.Interactor), "album/99", http.StatusNotFound},
+		{"/download/", NewDownloadHandler(&suite.Interactor), "artist/99", http.StatusNotFound},
+		{"/download/", NewDownloadHandler(&suite.Interactor), "playlist/1,99", http.StatusNotFound},
+		{"/hls/", &hlsHandler{Interactor: &suite.Interactor, segmenter: newHlsSegmenter()}, "1/playlist.m3u8", http.StatusGone},
+		{"/hls/", &hlsHandler{Interactor: &suite.Interactor, segmenter: newHlsSegmenter()}, "1/128/0.ts", http.StatusGone},
+	}
+	for _, route := range routes {
+		assert.Equal(suite.T(), http.StatusForbidden, serveTestRequest(route.handler, route.path, nil).Code, route.path)
+		expired := strings.TrimPrefix(signMediaUrl(route.prefix+route.path, anonymousUser, time.Now().Add(-48*time.Hour)), route.prefix)
+		response := serveTestRequest(route.handler, expired, nil)
+		assert.Equal(suite.T(), http.StatusForbidden, response.Code, route.path)
+		assert.Equal(suite.T(), "Expired URL\n", response.Body.String())
+		signed = strings.TrimPrefix(signMediaUrl(route.prefix+route.path, anonymousUser, time.Now()), route.prefix)
+		assert.Equal(suite.T(), route.status, serveTestRequest(route.handler, signed, nil).Code, route.path)
+	}
+
+	// The tracks of a playlist can't be changed.
+	signed = strings.TrimPrefix(signMediaUrl("/download/playlist/1,2", anonymousUser, time.Now()), "/download/")
+	tampered := strings.Replace(signed, "playlist/1,2", "playlist/1,3", 1)
+	assert.Equal(suite.T(), http.StatusForbidden, serveTestRequest(NewDownloadHandler(&suite.Interactor), tampered, nil).Code)
+}
+
+func (suite *ServerTestSuite) TestMediaStreamSegment() {
+	track := domain.Track{
+		Title:    "Cut",
+		Path:     suite.createFile("album.flac", buildTestFlacFile(100, 2000)),
+		StartMs:  1000,
+		EndMs:    2000,
+		CueTrack: 2,
+	}
+	if err := suite.Interactor.TrackRepository.Save(&track); err != nil {
+		suite.T().Fatal(err)
+	}
+	handler := NewMediaStreamHandler(&suite.Interactor)
+
+	response := serveTestRequest(handler, strconv.Itoa(track.Id), nil)
+	assert.Equal(suite.T(), http.StatusOK, response.Code)
+	assert.Equal(suite.T(), "audio/flac", response.Header().Get("Content-Type"))
+	assert.Regexp(suite.T(), `^"[0-9a-f]+-[0-9a-f]+-1000-2000"$`, response.Header().Get("ETag"))
+
+	// Tracks which can't be cut require transcoding.
+	track.Id = 0
+	track.Path = suite.createFile("album.ape", []byte("MAC "))
+	if err := suite.Interactor.TrackRepository.Save(&track); err != nil {
+		suite.T().Fatal(err)
+	}
+	response = serveTestRequest(handler, strconv.Itoa(track.Id), nil)
+	assert.Equal(suite.T(), http.StatusNotImplemented, response.Code)
+}
+
+func (suite *ServerTestSuite) TestCoverStream() {
+	handler := NewCoverStreamHandler(&suite.Interactor)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, serveTestRequest(handler, "abc", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, serveTestRequest(handler, "99", nil).Code)
+	// The file of the test cover doesn't exist.
+	assert.Equal(suite.T(), http.StatusGone, serveTestRequest(handler, "1", nil).Code)
+
+	suite.createFile("cover.png", []byte("png content"))
+	cover := domain.Cover{Path: "cover.png", Hash: "hash"}
+	if err := suite.Interactor.CoverRepository.Save(&cover); err != nil {
+		suite.T().Fatal(err)
+	}
+	path := strconv.Itoa(cover.Id)
+
+	response := serveTestRequest(handler, path, nil)
+	assert.Equal(suite.T(), http.StatusOK, response.Code)
+	assert.Equal(suite.T(), "image/png", response.Header().Get("Content-Type"))
+	response = serveTestRequest(handler, path, map[string]string{"If-None-Match": response.Header().Get("ETag")})
+	assert.Equal(suite.T(), http.StatusNotModified, response.Code)
+}
+
+func (suite *ServerTestSuite) TestDownloadErrors() {
+	handler := NewDownloadHandler(&suite.Interactor)
+	viper.Set("Downloads.Enabled", false)
+
+	assert.Equal(suite.T(), http.StatusNotFound, serveTestRequest(handler, "genre/1", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, serveTestRequest(handler, "album/1,2", nil).Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, serveTestRequest(handler, "album/abc", nil).Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, serveTestRequest(handler, "playlist/1,abc", nil).Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, serveTestRequest(handler, "album/1?format=wav", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotImplemented, serveTestRequest(handler, "album/1?format=mp3", nil).Code)
+	assert.Equal(suite.T(), http.StatusForbidden, serveTestRequest(handler, "album/1", nil).Code)
+	assert.Equal(suite.T(), http.StatusForbidden, serveTestRequest(handler, "playlist/1,2", nil).Code)
+}
+
+func (suite *ServerTestSuite) TestPlaylistDownload() {
+	track := domain.Track{Title: "Who? Me", Path: suite.createFile("track.mp3", []byte("mp3 content"))}
+	if err := suite.Interactor.TrackRepository.Save(&track); err != nil {
+		suite.T().Fatal(err)
+	}
+	handler := NewDownloadHandler(&suite.Interactor)
+	id := strconv.Itoa(track.Id)
+
+	response := serveTestRequest(handler, "playlist/"+id+","+id+"?name=Road+trip", nil)
+	assert.Equal(suite.T(), http.StatusOK, response.Code)
+	assert.Equal(suite.T(), "attachment; filename=\"Road trip.zip\"", response.Header().Get("Content-Disposition"))
+	archive, err := zip.NewReader(bytes.NewReader(response.Body.Bytes()), int64(response.Body.Len()))
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), archive.File, 2)
+	assert.Equal(suite.T(), "Road trip/01 - Unknown artist - Who_ Me.mp3", archive.File[0].Name)
+	assert.Equal(suite.T(), "Road trip/02 - Unknown artist - Who_ Me.mp3", archive.File[1].Name)
+
+	assert.Equal(suite.T(), http.StatusNotFound, serveTestRequest(handler, "playlist/"+id+",999", nil).Code)
+}
+
+func (suite *ServerTestSuite) TestGetFileETag() {
+	path := suite.createFile("file", []byte("content"))
+	stat, _ := os.Stat(path)
+	etag := getFileETag(stat, 0, 0)
+	assert.Equal(suite.T(), etag, getFileETag(stat, 0, 0))
+	assert.NotEqual(suite.T(), etag, getFileETag(stat, 1000, 0))
+
+	// The tag changes with the modification time.
+	if err := os.Chtimes(path, stat.ModTime(), stat.ModTime().Add(1e9)); err != nil {
+		suite.T().Fatal(err)
+	}
+	modified, _ := os.Stat(path)
+	assert.NotEqual(suite.T(), etag, getFileETag(modified, 0, 0))
+}
+
+func (suite *ServerTestSuite) TestShare() {
+	track := domain.Track{Title: "Shared", Path: suite.createFile("track.mp3", []byte("mp3 content"))}
+	if err := suite.Interactor.TrackRepository.Save(&track); err != nil {
+		suite.T().Fatal(err)
+	}
+	share, err := suite.Interactor.CreateShare(domain.ShareEntityTrack, track.Id, 0, 1, false)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	handler := NewShareHandler(&suite.Interactor)
+	stream := share.Slug + "/stream/" + strconv.Itoa(track.Id)
+
+	response := serveTestRequest(handler, share.Slug, nil)
+	assert.Equal(suite.T(), http.StatusOK, response.Code)
+	assert.Equal(suite.T(), "text/html; charset=utf-8", response.Header().Get("Content-Type"))
+	assert.Contains(suite.T(), response.Body.String(), "/share/"+stream+"?session=")
+
+	response = serveTestRequest(handler, share.Slug+"/items", nil)
+	assert.Equal(suite.T(), http.StatusOK, response.Code)
+	var page sharePage
+	assert.Nil(suite.T(), json.Unmarshal(response.Body.Bytes(), &page))
+	assert.Equal(suite.T(), "Shared", page.Title)
+	assert.True(suite.T(), strings.HasPrefix(page.Tracks[0].Src, "/share/"+stream+"?session="))
+	assert.Equal(suite.T(), 1, *page.PlaysLeft)
+	assert.Empty(suite.T(), page.Download)
+
+	// Downloads aren't allowed, and only the shared tracks are served.
+	assert.Equal(suite.T(), http.StatusForbidden, serveTestRequest(handler, stream+"?download=1", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, serveTestRequest(handler, share.Slug+"/stream/1", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, serveTestRequest(handler, share.Slug+"/download", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, serveTestRequest(handler, share.Slug+"/cover", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, serveTestRequest(handler, share.Slug+"/other", nil).Code)
+
+	// The tracks are streamed in the session of a page, where their play is counted once.
+	assert.Equal(suite.T(), http.StatusForbidden, serveTestRequest(handler, stream, nil).Code)
+	assert.Equal(suite.T(), http.StatusForbidden, serveTestRequest(handler, stream+"?session=unknown", nil).Code)
+	src := strings.TrimPrefix(page.Tracks[0].Src, "/share/")
+	response = serveTestRequest(handler, src, nil)
+	assert.Equal(suite.T(), http.StatusOK, response.Code)
+	assert.Equal(suite.T(), "mp3 content", response.Body.String())
+	response = serveTestRequest(handler, src, map[string]string{"Range": "bytes=4-"})
+	assert.Equal(suite.T(), http.StatusPartialContent, response.Code)
+	response = serveTestRequest(handler, src, map[string]string{"Range": "bytes=0-"})
+	assert.Equal(suite.T(), http.StatusPartialContent, response.Code)
+	assert.Equal(suite.T(), http.StatusOK, serveTestRequest(handler, src, nil).Code)
+
+	// No plays are left for the next views of the page.
+	response = serveTestRequest(handler, share.Slug+"/items", nil)
+	assert.Nil(suite.T(), json.Unmarshal(response.Body.Bytes(), &page))
+	assert.Equal(suite.T(), 0, *page.PlaysLeft)
+	response = serveTestRequest(handler, strings.TrimPrefix(page.Tracks[0].Src, "/share/"), nil)
+	assert.Equal(suite.T(), http.StatusForbidden, response.Code)
+	assert.Equal(suite.T(), "Maximum number of plays reached\n", response.Body.String())
+	// The session of another share can't be used.
+	other, _ := suite.Interactor.CreateShare(domain.ShareEntityTrack, track.Id, 0, 0, false)
+	response = serveTestRequest(handler, other.Slug+"/stream/"+strconv.Itoa(track.Id)+"?"+strings.SplitN(src, "?", 2)[1], nil)
+	assert.Equal(suite.T(), http.StatusForbidden, response.Code)
+
+	// Unknown, expired and revoked shares.
+	assert.Equal(suite.T(), http.StatusNotFound, serveTestRequest(handler, "unknown", nil).Code)
+	share.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	_ = suite.Interactor.ShareRepository.Save(&share)
+	assert.Equal(suite.T(), http.StatusGone, serveTestRequest(handler, share.Slug, nil).Code)
+	_, _ = suite.Interactor.RevokeShare(share.Id)
+	assert.Equal(suite.T(), http.StatusNotFound, serveTestRequest(handler, share.Slug, nil).Code)
+}
+
+func (suite *ServerTestSuite) TestShareDownload() {
+	handler := NewShareHandler(&suite.Interactor)
+	share, err := suite.Interactor.CreateShare(domain.ShareEntityAlbum, 1, 0, 0, false)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	assert.Equal(suite.T(), http.StatusForbidden, serveTestRequest(handler, share.Slug+"/download", nil).Code)
+
+	share, _ = suite.Interactor.CreateShare(domain.ShareEntityAlbum, 1, 0, 0, true)
+	response := serveTestRequest(handler, share.Slug+"/download", nil)
+	assert.Equal(suite.T(), http.StatusOK, response.Code)
+	assert.Equal(suite.T(), "application/zip", response.Header().Get("Content-Type"))
+
+	viper.Set("Downloads.Enabled", false)
+	assert.Equal(suite.T(), http.StatusForbidden, serveTestRequest(handler, share.Slug+"/download", nil).Code)
+}
+
+func (suite *ServerTestSuite) TestSharePlaylist() {
+	first := domain.Track{Title: "First", Path: suite.createFile("first.mp3", []byte("first content"))}
+	second := domain.Track{Title: "Second", Path: suite.createFile("second.mp3", []byte("second content"))}
+	for _, track := range []*domain.Track{&first, &second} {
+		if err := suite.Interactor.TrackRepository.Save(track); err != nil {
+			suite.T().Fatal(err)
+		}
+	}
+	share, err := suite.Interactor.CreatePlaylistShare("Road trip", []int{second.Id, first.Id}, 0, 0, true)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	handler := NewShareHandler(&suite.Interactor)
+
+	response := serveTestRequest(handler, share.Slug+"/items", nil)
+	assert.Equal(suite.T(), http.StatusOK, response.Code)
+	var page sharePage
+	assert.Nil(suite.T(), json.Unmarshal(response.Body.Bytes(), &page))
+	assert.Equal(suite.T(), "playlist", page.EntityType)
+	assert.Equal(suite.T(), "Road trip", page.Title)
+	assert.Len(suite.T(), page.Tracks, 2)
+	assert.Equal(suite.T(), "Second", page.Tracks[0].Title)
+	assert.Equal(suite.T(), "/share/"+share.Slug+"/download", page.Download)
+
+	// Only the tracks of the playlist are served.
+	response = serveTestRequest(handler, strings.TrimPrefix(page.Tracks[1].Src, "/share/"), nil)
+	assert.Equal(suite.T(), http.StatusOK, response.Code)
+	assert.Equal(suite.T(), "first content", response.Body.String())
+	assert.Equal(suite.T(), http.StatusNotFound, serveTestRequest(handler, share.Slug+"/stream/1", nil).Code)
+
+	response = serveTestRequest(handler, share.Slug+"/download", nil)
+	assert.Equal(suite.T(), http.StatusOK, response.Code)
+	archive, err := zip.NewReader(bytes.NewReader(response.Body.Bytes()), int64(response.Body.Len()))
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), archive.File, 2)
+	assert.Equal(suite.T(), "Road trip/01 - Unknown artist - Second.mp3", archive.File[0].Name)
+
+	// A play of the streamed track, and one of the download.
+	share, _ = suite.Interactor.ShareRepository.Get(share.Id)
+	assert.Equal(suite.T(), 2, share.Plays)
+}
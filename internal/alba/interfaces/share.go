@@ -0,0 +1,205 @@
+package interfaces
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"html/template"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+)
+
+/*
+This file exposes the public pages of the shares.
+
+A share is served at /share/<slug> as a minimal HTML page with audio players, and its content at /share/<slug>/items as
+JSON. The tracks, the cover and the download of a share are served under its path, so they don't need signed URLs.
+
+Each view of a page starts a session, whose id is in the URLs of the tracks. A play is counted the first time a track
+is requested in a session, so the byte ranges and the seeking of the players don't use up the plays. Sessions are kept
+in memory for shareSessionDuration.
+*/
+
+// Duration of a share session, after which its page must be loaded again to play the tracks.
+const shareSessionDuration = 12 * time.Hour
+
+// View of a share page.
+type shareSession struct {
+	shareId   int
+	expiresAt time.Time
+	played    map[int]bool // Tracks whose play has been counted.
+}
+
+// Manages the share sessions.
+type shareSessions struct {
+	mutex    sync.Mutex
+	sessions map[string]*shareSession
+}
+
+func newShareSessions() *shareSessions {
+	return &shareSessions{sessions: make(map[string]*shareSession)}
+}
+
+// Starts a session for a view of a share page, and removes the expired ones. Returns the id of the session.
+func (s *shareSessions) start(shareId int, now time.Time) (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	id := base64.RawURLEncoding.EncodeToString(bytes)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, session := range s.sessions {
+		if !now.Before(session.expiresAt) {
+			delete(s.sessions, key)
+		}
+	}
+	s.sessions[id] = &shareSession{shareId: shareId, expiresAt: now.Add(shareSessionDuration), played: make(map[int]bool)}
+
+	return id, nil
+}
+
+/*
+Marks a track as played in a session.
+
+Returns whether the play must be counted, false if the track has already been played in the session, and false for ok if
+the session doesn't exist, has expired or belongs to another share.
+*/
+func (s *shareSessions) play(id string, shareId int, trackId int, now time.Time) (count bool, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, found := s.sessions[id]
+	if !found || session.shareId != shareId || !now.Before(session.expiresAt) {
+		return false, false
+	}
+	if session.played[trackId] {
+		return false, true
+	}
+	session.played[trackId] = true
+
+	return true, true
+}
+
+// Unmarks a track as played in a session, when its play couldn't be counted.
+func (s *shareSessions) cancelPlay(id string, trackId int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if session, found := s.sessions[id]; found {
+		delete(session.played, trackId)
+	}
+}
+
+// Content of a share, for its page and its JSON endpoint.
+type sharePage struct {
+	EntityType string           `json:"entityType"`
+	Title      string           `json:"title"`
+	Artist     string           `json:"artist"`
+	Cover      string           `json:"cover,omitempty"`
+	Download   string           `json:"download,omitempty"` // Only if downloads are allowed.
+	ExpiresAt  int64            `json:"expiresAt,omitempty"`
+	PlaysLeft  *int             `json:"playsLeft,omitempty"` // Only if the number of plays is limited.
+	Tracks     []sharePageTrack `json:"tracks"`
+}
+
+type sharePageTrack struct {
+	Id       int    `json:"id"`
+	Title    string `json:"title"`
+	Artist   string `json:"artist"`
+	Disc     string `json:"disc,omitempty"`
+	Number   int    `json:"number,omitempty"`
+	Duration int    `json:"duration"` // In seconds.
+	Src      string `json:"src"`
+}
+
+// Builds the content of a share page, with URLs under a path like "/share/<slug>" and the tracks URLs in a session.
+func newSharePage(share domain.Share, content business.SharedContent, path string, session string) sharePage {
+	page := sharePage{
+		EntityType: share.EntityType,
+		Title:      content.Title,
+		Artist:     content.Artist,
+		ExpiresAt:  share.ExpiresAt,
+		Tracks:     make([]sharePageTrack, 0, len(content.Tracks)),
+	}
+	if content.CoverId != 0 {
+		page.Cover = path + "/cover"
+	}
+	if share.MaxPlays != 0 {
+		playsLeft := share.MaxPlays - share.Plays
+		if playsLeft < 0 {
+			playsLeft = 0
+		}
+		page.PlaysLeft = &playsLeft
+	}
+
+	for _, track := range content.Tracks {
+		page.Tracks = append(page.Tracks, sharePageTrack{
+			Id:       track.Id,
+			Title:    track.Title,
+			Artist:   content.Artists[track.ArtistId],
+			Disc:     track.Disc,
+			Number:   track.Number,
+			Duration: track.Duration,
+			Src:      path + "/stream/" + strconv.Itoa(track.Id) + "?" + url.Values{"session": {session}}.Encode(),
+		})
+	}
+
+	if share.AllowDownload {
+		// Shared tracks are downloaded as is, albums and playlists as ZIP archives.
+		if share.EntityType == domain.ShareEntityTrack {
+			page.Download = path + "/stream/" + strconv.Itoa(share.EntityId) + "?" + url.Values{"download": {"1"}, "session": {session}}.Encode()
+		} else {
+			page.Download = path + "/download"
+		}
+	}
+
+	return page
+}
+
+// Formats a duration in seconds as "m:ss".
+func formatShareDuration(seconds int) string {
+	remainder := strconv.Itoa(seconds % 60)
+	if len(remainder) == 1 {
+		remainder = "0" + remainder
+	}
+
+	return strconv.Itoa(seconds/60) + ":" + remainder
+}
+
+var sharePageTemplate = template.Must(template.New("share").Funcs(template.FuncMap{
+	"duration": formatShareDuration,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}{{if .Artist}} - {{.Artist}}{{end}}</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 2em auto; padding: 0 1em; color: #222; }
+img { max-width: 100%; }
+ol { padding-left: 1.5em; }
+li { margin: 1em 0; }
+audio { display: block; width: 100%; margin-top: .3em; }
+.artist, .duration { color: #777; }
+</style>
+</head>
+<body>
+{{if .Cover}}<img src="{{.Cover}}" alt="">{{end}}
+<h1>{{.Title}}</h1>
+{{if .Artist}}<p class="artist">{{.Artist}}</p>{{end}}
+<ol>
+{{range .Tracks}}<li>{{.Title}}{{if ne .Artist $.Artist}} <span class="artist">{{.Artist}}</span>{{end}} <span class="duration">{{duration .Duration}}</span>
+<audio controls preload="none" src="{{.Src}}"></audio></li>
+{{end}}</ol>
+{{if .PlaysLeft}}<p>Plays left: {{.PlaysLeft}}</p>{{end}}
+{{if .Download}}<p><a href="{{.Download}}">Download</a></p>{{end}}
+</body>
+</html>
+`))
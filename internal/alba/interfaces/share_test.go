@@ -0,0 +1,127 @@
+package interfaces
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/humbkr/albaplayer-server/internal/alba/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SharePageTestSuite struct {
+	suite.Suite
+	Content business.SharedContent
+}
+
+// Go testing framework entry point.
+func TestSharePageTestSuite(t *testing.T) {
+	suite.Run(t, new(SharePageTestSuite))
+}
+
+func (suite *SharePageTestSuite) SetupTest() {
+	suite.Content = business.SharedContent{
+		Title:   "Album <1>",
+		Artist:  "Artist",
+		CoverId: 4,
+		Tracks: domain.Tracks{
+			{Id: 7, Title: "First", ArtistId: 1, Number: 1, Duration: 65},
+			{Id: 8, Title: "Second", ArtistId: 2, Number: 2, Duration: 600},
+		},
+		Artists: map[int]string{1: "Artist", 2: "Guest"},
+	}
+}
+
+func (suite *SharePageTestSuite) TestNewSharePage() {
+	share := domain.Share{Slug: "slug", EntityType: domain.ShareEntityAlbum, EntityId: 3, MaxPlays: 5, Plays: 2, AllowDownload: true}
+	page := newSharePage(share, suite.Content, "/share/slug", "abc")
+	assert.Equal(suite.T(), "album", page.EntityType)
+	assert.Equal(suite.T(), "Album <1>", page.Title)
+	assert.Equal(suite.T(), "/share/slug/cover", page.Cover)
+	assert.Equal(suite.T(), "/share/slug/download", page.Download)
+	assert.Equal(suite.T(), 3, *page.PlaysLeft)
+	assert.Len(suite.T(), page.Tracks, 2)
+	assert.Equal(suite.T(), "/share/slug/stream/8?session=abc", page.Tracks[1].Src)
+	assert.Equal(suite.T(), "Guest", page.Tracks[1].Artist)
+
+	// Shared tracks are downloaded from their stream.
+	share = domain.Share{Slug: "slug", EntityType: domain.ShareEntityTrack, EntityId: 7, AllowDownload: true}
+	suite.Content.CoverId = 0
+	page = newSharePage(share, suite.Content, "/share/slug", "abc")
+	assert.Equal(suite.T(), "/share/slug/stream/7?download=1&session=abc", page.Download)
+	assert.Empty(suite.T(), page.Cover)
+	assert.Nil(suite.T(), page.PlaysLeft)
+
+	share.AllowDownload = false
+	assert.Empty(suite.T(), newSharePage(share, suite.Content, "/share/slug", "abc").Download)
+
+	// Shared playlists are downloaded as archives.
+	share = domain.Share{Slug: "slug", EntityType: domain.ShareEntityPlaylist, Name: "Mix", TrackIds: []int{8, 7}, AllowDownload: true}
+	page = newSharePage(share, suite.Content, "/share/slug", "abc")
+	assert.Equal(suite.T(), "playlist", page.EntityType)
+	assert.Equal(suite.T(), "/share/slug/download", page.Download)
+}
+
+func (suite *SharePageTestSuite) TestSharePageTemplate() {
+	share := domain.Share{Slug: "slug", EntityType: domain.ShareEntityAlbum, MaxPlays: 5}
+	var html bytes.Buffer
+	err := sharePageTemplate.Execute(&html, newSharePage(share, suite.Content, "/share/slug", "abc"))
+	assert.Nil(suite.T(), err)
+	assert.Contains(suite.T(), html.String(), "<h1>Album &lt;1&gt;</h1>")
+	assert.Contains(suite.T(), html.String(), `<audio controls preload="none" src="/share/slug/stream/7?session=abc">`)
+	assert.Contains(suite.T(), html.String(), "1:05")
+	assert.Contains(suite.T(), html.String(), "10:00")
+	// Only the artists of the tracks which aren't the album artist are shown.
+	assert.Contains(suite.T(), html.String(), `Second <span class="artist">Guest</span>`)
+	assert.Contains(suite.T(), html.String(), "Plays left: 5")
+	assert.NotContains(suite.T(), html.String(), "Download")
+
+	// Playlists have no artist, so the artists of all the tracks are shown.
+	suite.Content.Artist = ""
+	html.Reset()
+	err = sharePageTemplate.Execute(&html, newSharePage(domain.Share{EntityType: domain.ShareEntityPlaylist}, suite.Content, "/share/slug", "abc"))
+	assert.Nil(suite.T(), err)
+	assert.Contains(suite.T(), html.String(), "<title>Album &lt;1&gt;</title>")
+	assert.Contains(suite.T(), html.String(), `First <span class="artist">Artist</span>`)
+	assert.NotContains(suite.T(), html.String(), `<p class="artist">`)
+}
+
+func (suite *SharePageTestSuite) TestShareSessions() {
+	sessions := newShareSessions()
+	now := time.Now()
+	id, err := sessions.start(1, now)
+	assert.Nil(suite.T(), err)
+	other, _ := sessions.start(1, now)
+	assert.NotEqual(suite.T(), id, other)
+
+	// The plays are counted once per track.
+	count, ok := sessions.play(id, 1, 7, now)
+	assert.True(suite.T(), ok)
+	assert.True(suite.T(), count)
+	count, ok = sessions.play(id, 1, 7, now.Add(time.Hour))
+	assert.True(suite.T(), ok)
+	assert.False(suite.T(), count)
+	count, _ = sessions.play(id, 1, 8, now)
+	assert.True(suite.T(), count)
+	count, _ = sessions.play(other, 1, 7, now)
+	assert.True(suite.T(), count)
+
+	// Unless the play couldn't be counted.
+	sessions.cancelPlay(id, 8)
+	count, _ = sessions.play(id, 1, 8, now)
+	assert.True(suite.T(), count)
+
+	// Unknown sessions, sessions of other shares and expired sessions.
+	_, ok = sessions.play("unknown", 1, 7, now)
+	assert.False(suite.T(), ok)
+	_, ok = sessions.play(id, 2, 7, now)
+	assert.False(suite.T(), ok)
+	_, ok = sessions.play(id, 1, 7, now.Add(shareSessionDuration))
+	assert.False(suite.T(), ok)
+
+	// Expired sessions are removed when a session starts.
+	_, _ = sessions.start(1, now.Add(shareSessionDuration))
+	assert.Len(suite.T(), sessions.sessions, 1)
+}
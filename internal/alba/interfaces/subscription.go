@@ -0,0 +1,329 @@
+package interfaces
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+)
+
+/*
+This file exposes the GraphQL subscriptions over WebSocket, with the "graphql-ws" protocol of
+subscriptions-transport-ws, see https://github.com/apollographql/subscriptions-transport-ws/blob/master/PROTOCOL.md.
+
+Every subscription field has a source of events. The subscription operation is executed for each event, which the
+field resolves with getSubscriptionEvent(), and the result is sent to the client unless the field resolves to null.
+Queries and mutations sent on the connection are executed once.
+*/
+
+const subscriptionProtocol = "graphql-ws"
+
+// Interval of the keep alive messages, so proxies don't close idle connections.
+const subscriptionKeepAlive = 30 * time.Second
+
+// Messages of the protocol.
+const (
+	subscriptionConnectionInit      = "connection_init"
+	subscriptionConnectionAck       = "connection_ack"
+	subscriptionConnectionError     = "connection_error"
+	subscriptionConnectionKeepAlive = "ka"
+	subscriptionConnectionTerminate = "connection_terminate"
+	subscriptionStart               = "start"
+	subscriptionStop                = "stop"
+	subscriptionData                = "data"
+	subscriptionError               = "error"
+	subscriptionComplete            = "complete"
+)
+
+/*
+Sources of the events of the subscription fields, by field.
+
+A source starts sending the events of a user to a function, and returns a function stopping it. It gets the arguments
+of the field, literal or from the variables, and calls complete if the events end before the subscription is stopped.
+*/
+var subscriptionSources = map[string]func(library *business.LibraryInteractor, user string, args map[string]interface{}, send func(interface{}), complete func()) (func(), error){
+	"playQueue": func(library *business.LibraryInteractor, user string, args map[string]interface{}, send func(interface{}), complete func()) (func(), error) {
+		changes, stop := library.ListenPlayQueue(user)
+		go func() {
+			for queue := range changes {
+				send(queue)
+			}
+		}()
+
+		return stop, nil
+	},
+	"party": func(library *business.LibraryInteractor, user string, args map[string]interface{}, send func(interface{}), complete func()) (func(), error) {
+		id, _ := args["id"].(string)
+		changes, stop, err := library.ListenParty(id)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			for party := range changes {
+				send(party)
+			}
+			// The party has been closed, or the subscription stopped.
+			complete()
+		}()
+
+		return stop, nil
+	},
+}
+
+type subscriptionMessage struct {
+	Id      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type subscriptionOperation struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+type subscriptionEventKey struct{}
+
+// Connection of a client, with its running subscriptions.
+type subscriptionConnection struct {
+	conn          *websocket.Conn
+	graphQL       *graphQLInteractor
+	user          string
+	writeMutex    sync.Mutex
+	mutex         sync.Mutex
+	subscriptions map[string]*runningSubscription // By operation id.
+}
+
+type runningSubscription struct {
+	stop func()
+}
+
+// Gets the event a subscription field is resolved for, nil if the operation isn't executed for an event.
+func getSubscriptionEvent(p graphql.ResolveParams) interface{} {
+	if p.Context == nil {
+		return nil
+	}
+
+	return p.Context.Value(subscriptionEventKey{})
+}
+
+// Serves the messages of a client until the connection is closed.
+func serveSubscriptions(conn *websocket.Conn, graphQL *graphQLInteractor, user string) {
+	connection := &subscriptionConnection{
+		conn:          conn,
+		graphQL:       graphQL,
+		user:          user,
+		subscriptions: make(map[string]*runningSubscription),
+	}
+	defer connection.stopAll()
+
+	done := make(chan struct{})
+	defer close(done)
+	go connection.keepAlive(done)
+
+	for {
+		var message subscriptionMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			return
+		}
+
+		switch message.Type {
+		case subscriptionConnectionInit:
+			connection.send(subscriptionMessage{Type: subscriptionConnectionAck})
+		case subscriptionStart:
+			var operation subscriptionOperation
+			if err := json.Unmarshal(message.Payload, &operation); err != nil {
+				connection.sendError(message.Id, err)
+				continue
+			}
+			connection.start(message.Id, operation)
+		case subscriptionStop:
+			connection.stop(message.Id)
+			connection.send(subscriptionMessage{Id: message.Id, Type: subscriptionComplete})
+		case subscriptionConnectionTerminate:
+			return
+		default:
+			connection.sendError(message.Id, errors.New("unknown message type "+message.Type))
+		}
+	}
+}
+
+// Starts an operation: subscriptions are started, queries and mutations are executed once.
+func (c *subscriptionConnection) start(id string, operation subscriptionOperation) {
+	field, err := getSubscriptionField(operation)
+	if err != nil {
+		c.sendError(id, err)
+		return
+	}
+	if field == nil {
+		c.sendResult(id, c.execute(operation, nil))
+		c.send(subscriptionMessage{Id: id, Type: subscriptionComplete})
+		return
+	}
+
+	name := field.Name.Value
+	source, ok := subscriptionSources[name]
+	if !ok {
+		c.sendError(id, errors.New("unknown subscription "+name))
+		return
+	}
+
+	// An operation id can be reused once stopped.
+	c.stop(id)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	subscription := &runningSubscription{}
+	send := func(event interface{}) {
+		result := c.execute(operation, event)
+		if data, ok := result.Data.(map[string]interface{}); ok && data[name] == nil && len(result.Errors) == 0 {
+			return
+		}
+		c.sendResult(id, result)
+	}
+	complete := func() {
+		c.mutex.Lock()
+		running := c.subscriptions[id] == subscription
+		if running {
+			delete(c.subscriptions, id)
+		}
+		c.mutex.Unlock()
+
+		// Stopped subscriptions have already been completed.
+		if running {
+			subscription.stop()
+			c.send(subscriptionMessage{Id: id, Type: subscriptionComplete})
+		}
+	}
+	subscription.stop, err = source(c.graphQL.Library, c.user, getSubscriptionArguments(field, operation.Variables), send, complete)
+	if err != nil {
+		c.sendError(id, err)
+		return
+	}
+	c.subscriptions[id] = subscription
+}
+
+// Stops a subscription.
+func (c *subscriptionConnection) stop(id string) {
+	c.mutex.Lock()
+	subscription, ok := c.subscriptions[id]
+	delete(c.subscriptions, id)
+	c.mutex.Unlock()
+
+	if ok {
+		subscription.stop()
+	}
+}
+
+// Stops all the subscriptions of the connection.
+func (c *subscriptionConnection) stopAll() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for id, subscription := range c.subscriptions {
+		subscription.stop()
+		delete(c.subscriptions, id)
+	}
+}
+
+// Executes an operation, for an event if not nil.
+func (c *subscriptionConnection) execute(operation subscriptionOperation, event interface{}) *graphql.Result {
+	ctx := context.Background()
+	if event != nil {
+		ctx = context.WithValue(ctx, subscriptionEventKey{}, event)
+	}
+
+	return graphql.Do(graphql.Params{
+		Schema:         c.graphQL.Schema,
+		RequestString:  operation.Query,
+		VariableValues: operation.Variables,
+		OperationName:  operation.OperationName,
+		Context:        ctx,
+	})
+}
+
+func (c *subscriptionConnection) sendResult(id string, result *graphql.Result) {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		c.sendError(id, err)
+		return
+	}
+	c.send(subscriptionMessage{Id: id, Type: subscriptionData, Payload: payload})
+}
+
+func (c *subscriptionConnection) sendError(id string, err error) {
+	payload, _ := json.Marshal(map[string]string{"message": err.Error()})
+	c.send(subscriptionMessage{Id: id, Type: subscriptionError, Payload: payload})
+}
+
+// Sends a message, failures being handled by the reading loop once the connection is closed.
+func (c *subscriptionConnection) send(message subscriptionMessage) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	_ = c.conn.WriteJSON(message)
+}
+
+func (c *subscriptionConnection) keepAlive(done chan struct{}) {
+	ticker := time.NewTicker(subscriptionKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.send(subscriptionMessage{Type: subscriptionConnectionKeepAlive})
+		case <-done:
+			return
+		}
+	}
+}
+
+/*
+Gets the field of a subscription operation.
+
+Returns nil if the operation is a query or a mutation, or an error if the operation can't be parsed.
+*/
+func getSubscriptionField(operation subscriptionOperation) (*ast.Field, error) {
+	document, err := parser.Parse(parser.ParseParams{Source: operation.Query})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, definition := range document.Definitions {
+		definition, ok := definition.(*ast.OperationDefinition)
+		if !ok || (operation.OperationName != "" && (definition.Name == nil || definition.Name.Value != operation.OperationName)) {
+			continue
+		}
+		if definition.Operation != ast.OperationTypeSubscription {
+			return nil, nil
+		}
+		if definition.SelectionSet == nil || len(definition.SelectionSet.Selections) != 1 {
+			return nil, errors.New("subscriptions must select a single field")
+		}
+		field, ok := definition.SelectionSet.Selections[0].(*ast.Field)
+		if !ok {
+			return nil, errors.New("subscriptions must select a single field")
+		}
+
+		return field, nil
+	}
+
+	return nil, errors.New("no operation found")
+}
+
+// Gets the arguments of a field, with the values of the variables. Values aren't coerced to their type.
+func getSubscriptionArguments(field *ast.Field, variables map[string]interface{}) map[string]interface{} {
+	args := make(map[string]interface{})
+	for _, argument := range field.Arguments {
+		if variable, ok := argument.Value.(*ast.Variable); ok {
+			args[argument.Name.Value] = variables[variable.Name.Value]
+		} else {
+			args[argument.Name.Value] = argument.Value.GetValue()
+		}
+	}
+
+	return args
+}
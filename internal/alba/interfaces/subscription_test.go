@@ -0,0 +1,215 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SubscriptionTestSuite struct {
+	suite.Suite
+	Interactor business.LibraryInteractor
+	Server     *httptest.Server
+	Conn       *websocket.Conn
+}
+
+// Go testing framework entry point.
+func TestSubscriptionTestSuite(t *testing.T) {
+	suite.Run(t, new(SubscriptionTestSuite))
+}
+
+func (suite *SubscriptionTestSuite) SetupSuite() {
+	ds, err := createTestDatasource()
+	if err != nil {
+		log.Fatal(err)
+	}
+	appContext := AppContext{DB: ds}
+	suite.Interactor = business.LibraryInteractor{
+		ArtistRepository:    ArtistDbRepository{AppContext: &appContext},
+		AlbumRepository:     AlbumDbRepository{AppContext: &appContext},
+		TrackRepository:     TrackDbRepository{AppContext: &appContext},
+		PlayQueueRepository: PlayQueueDbRepository{AppContext: &appContext},
+	}
+	suite.Server = httptest.NewServer(NewSubscriptionHandler(NewGraphQLInteractor(&suite.Interactor)))
+}
+
+func (suite *SubscriptionTestSuite) TearDownSuite() {
+	suite.Server.Close()
+	if repository, ok := suite.Interactor.TrackRepository.(TrackDbRepository); ok == true {
+		if err := closeTestDataSource(repository.AppContext.DB); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func (suite *SubscriptionTestSuite) SetupTest() {
+	if repository, ok := suite.Interactor.TrackRepository.(TrackDbRepository); ok == true {
+		resetTestDataSource(repository.AppContext.DB)
+	}
+
+	dialer := websocket.Dialer{Subprotocols: []string{subscriptionProtocol}}
+	conn, response, err := dialer.Dial("ws"+strings.TrimPrefix(suite.Server.URL, "http"), nil)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	assert.Equal(suite.T(), subscriptionProtocol, response.Header.Get("Sec-WebSocket-Protocol"))
+	suite.Conn = conn
+
+	suite.send("", subscriptionConnectionInit, nil)
+	assert.Equal(suite.T(), subscriptionConnectionAck, suite.receive().Type)
+}
+
+func (suite *SubscriptionTestSuite) TearDownTest() {
+	suite.send("", subscriptionConnectionTerminate, nil)
+	_ = suite.Conn.Close()
+}
+
+func (suite *SubscriptionTestSuite) send(id string, messageType string, operation *subscriptionOperation) {
+	message := subscriptionMessage{Id: id, Type: messageType}
+	if operation != nil {
+		message.Payload, _ = json.Marshal(operation)
+	}
+	if err := suite.Conn.WriteJSON(message); err != nil {
+		suite.T().Fatal(err)
+	}
+}
+
+func (suite *SubscriptionTestSuite) receive() subscriptionMessage {
+	var message subscriptionMessage
+	_ = suite.Conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := suite.Conn.ReadJSON(&message); err != nil {
+		suite.T().Fatal(err)
+	}
+
+	return message
+}
+
+// Receives the play queue sent for an operation.
+func (suite *SubscriptionTestSuite) receivePlayQueue(id string) map[string]interface{} {
+	message := suite.receive()
+	assert.Equal(suite.T(), id, message.Id)
+	assert.Equal(suite.T(), subscriptionData, message.Type)
+
+	var result struct {
+		Data struct {
+			PlayQueue map[string]interface{} `json:"playQueue"`
+		} `json:"data"`
+	}
+	assert.Nil(suite.T(), json.Unmarshal(message.Payload, &result))
+
+	return result.Data.PlayQueue
+}
+
+func (suite *SubscriptionTestSuite) TestPlayQueueSubscription() {
+	suite.send("1", subscriptionStart, &subscriptionOperation{
+		Query:     "subscription Queue($client: String) { playQueue(clientId: $client) { trackIds currentIndex position clientId tracks { title } } }",
+		Variables: map[string]interface{}{"client": "laptop"},
+	})
+
+	// Queries are executed once, after the previous messages.
+	suite.send("2", subscriptionStart, &subscriptionOperation{Query: "{ playQueue { trackIds } }"})
+	queue := suite.receivePlayQueue("2")
+	assert.Empty(suite.T(), queue["trackIds"])
+	assert.Equal(suite.T(), subscriptionMessage{Id: "2", Type: subscriptionComplete}, suite.receive())
+
+	// The changes of the subscribing client aren't sent.
+	_, err := suite.Interactor.SavePlayQueue(anonymousUser, "laptop", []int{1}, 0, 0)
+	assert.Nil(suite.T(), err)
+	_, err = suite.Interactor.SavePlayQueue(anonymousUser, "desktop", []int{2, 1}, 1, 5000)
+	assert.Nil(suite.T(), err)
+	queue = suite.receivePlayQueue("1")
+	assert.Equal(suite.T(), []interface{}{"2", "1"}, queue["trackIds"])
+	assert.Equal(suite.T(), float64(1), queue["currentIndex"])
+	assert.Equal(suite.T(), float64(5000), queue["position"])
+	assert.Equal(suite.T(), "desktop", queue["clientId"])
+	assert.Len(suite.T(), queue["tracks"], 2)
+
+	// Stopped subscriptions don't receive the changes anymore.
+	suite.send("1", subscriptionStop, nil)
+	assert.Equal(suite.T(), subscriptionMessage{Id: "1", Type: subscriptionComplete}, suite.receive())
+	_, err = suite.Interactor.SavePlayQueue(anonymousUser, "desktop", []int{1}, 0, 0)
+	assert.Nil(suite.T(), err)
+	suite.send("3", subscriptionStart, &subscriptionOperation{Query: "{ playQueue { trackIds } }"})
+	queue = suite.receivePlayQueue("3")
+	assert.Equal(suite.T(), []interface{}{"1"}, queue["trackIds"])
+}
+
+func (suite *SubscriptionTestSuite) TestPartySubscription() {
+	party, host, err := suite.Interactor.CreateParty("Office", "Alice", true)
+	assert.Nil(suite.T(), err)
+
+	suite.send("1", subscriptionStart, &subscriptionOperation{
+		Query:     "subscription Party($id: ID!) { party(id: $id) { name participants { name } current { track { title } } closed } }",
+		Variables: map[string]interface{}{"id": party.Id},
+	})
+	// The subscription is started once the query below is answered.
+	suite.send("2", subscriptionStart, &subscriptionOperation{Query: `{ party(id: "` + party.Id + `") { name } }`})
+	assert.Equal(suite.T(), "2", suite.receive().Id)
+	assert.Equal(suite.T(), subscriptionComplete, suite.receive().Type)
+
+	_, _, err = suite.Interactor.JoinParty(party.Id, "Bob")
+	assert.Nil(suite.T(), err)
+	message := suite.receive()
+	assert.Equal(suite.T(), "1", message.Id)
+	assert.Contains(suite.T(), string(message.Payload), `"participants":[{"name":"Alice"},{"name":"Bob"}]`)
+
+	_, err = suite.Interactor.AddPartyTrack(party.Id, host.Token, 1)
+	assert.Nil(suite.T(), err)
+	message = suite.receive()
+	assert.Contains(suite.T(), string(message.Payload), `"current":{"track":{"title":"Stinkfist"}}`)
+
+	// The subscription completes once the party is closed.
+	assert.Nil(suite.T(), suite.Interactor.LeaveParty(party.Id, host.Token))
+	message = suite.receive()
+	assert.Contains(suite.T(), string(message.Payload), `"closed":true`)
+	assert.Equal(suite.T(), subscriptionMessage{Id: "1", Type: subscriptionComplete}, suite.receive())
+
+	// Unknown parties can't be subscribed to.
+	suite.send("3", subscriptionStart, &subscriptionOperation{Query: `subscription { party(id: "` + party.Id + `") { name } }`})
+	assert.Equal(suite.T(), subscriptionError, suite.receive().Type)
+}
+
+func (suite *SubscriptionTestSuite) TestInvalidOperations() {
+	suite.send("1", subscriptionStart, &subscriptionOperation{Query: "subscription { unknown }"})
+	assert.Equal(suite.T(), subscriptionError, suite.receive().Type)
+
+	suite.send("2", subscriptionStart, &subscriptionOperation{Query: "subscription {"})
+	assert.Equal(suite.T(), subscriptionError, suite.receive().Type)
+
+	suite.send("3", "unknown", nil)
+	assert.Equal(suite.T(), subscriptionError, suite.receive().Type)
+}
+
+func (suite *SubscriptionTestSuite) TestGetSubscriptionField() {
+	field, err := getSubscriptionField(subscriptionOperation{Query: "subscription { playQueue { position } }"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "playQueue", field.Name.Value)
+
+	// The operation is found by name.
+	query := "query Queue { playQueue { position } } subscription Changes { playQueue { position } }"
+	field, err = getSubscriptionField(subscriptionOperation{Query: query, OperationName: "Queue"})
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), field)
+	field, err = getSubscriptionField(subscriptionOperation{Query: query, OperationName: "Changes"})
+	assert.Nil(suite.T(), err)
+	assert.NotNil(suite.T(), field)
+	_, err = getSubscriptionField(subscriptionOperation{Query: query, OperationName: "Unknown"})
+	assert.NotNil(suite.T(), err)
+
+	_, err = getSubscriptionField(subscriptionOperation{Query: "subscription { playQueue { position } other }"})
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *SubscriptionTestSuite) TestGetSubscriptionArguments() {
+	field, _ := getSubscriptionField(subscriptionOperation{Query: `subscription ($id: String!) { party(id: $id, other: "literal") { name } }`})
+	args := getSubscriptionArguments(field, map[string]interface{}{"id": "ABCDEF"})
+	assert.Equal(suite.T(), map[string]interface{}{"id": "ABCDEF", "other": "literal"}, args)
+}
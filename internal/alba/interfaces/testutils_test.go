@@ -28,6 +28,12 @@ const TestArtistsFile = TestDataDir + "artists.csv"
 const TestAlbumsFile = TestDataDir + "albums.csv"
 const TestTracksFile = TestDataDir + "tracks.csv"
 const TestCoversFile = TestDataDir + "covers.csv"
+const TestGenresFile = TestDataDir + "genres.csv"
+const TestTrackGenresFile = TestDataDir + "track_genres.csv"
+const TestOverridesFile = TestDataDir + "overrides.csv"
+const TestArtistAliasesFile = TestDataDir + "artist_aliases.csv"
+const TestLyricsFile = TestDataDir + "lyrics.csv"
+const TestTrackArtistsFile = TestDataDir + "track_artists.csv"
 const TestFSLibDir = TestDataDir + "mp3"
 const TestFSEmptyLibDir = TestDataDir + "empty_library"
 
@@ -46,6 +52,29 @@ func clearTestDataSource(ds Datasource) error {
 		dbmap.Exec("DELETE FROM sqlite_sequence WHERE name = 'albums'")
 		dbmap.Exec("DELETE FROM artists")
 		dbmap.Exec("DELETE FROM sqlite_sequence WHERE name = 'artists'")
+		dbmap.Exec("DELETE FROM track_artists")
+		dbmap.Exec("DELETE FROM track_genres")
+		dbmap.Exec("DELETE FROM genres")
+		dbmap.Exec("DELETE FROM sqlite_sequence WHERE name = 'genres'")
+		dbmap.Exec("DELETE FROM overrides")
+		dbmap.Exec("DELETE FROM sqlite_sequence WHERE name = 'overrides'")
+		dbmap.Exec("DELETE FROM artist_aliases")
+		dbmap.Exec("DELETE FROM sqlite_sequence WHERE name = 'artist_aliases'")
+		dbmap.Exec("DELETE FROM artist_alias_moves")
+		dbmap.Exec("DELETE FROM lyrics")
+		dbmap.Exec("DELETE FROM sqlite_sequence WHERE name = 'lyrics'")
+		dbmap.Exec("DELETE FROM share_tracks")
+		dbmap.Exec("DELETE FROM shares")
+		dbmap.Exec("DELETE FROM sqlite_sequence WHERE name = 'shares'")
+		dbmap.Exec("DELETE FROM scrobble_accounts")
+		dbmap.Exec("DELETE FROM sqlite_sequence WHERE name = 'scrobble_accounts'")
+		dbmap.Exec("DELETE FROM scrobbles")
+		dbmap.Exec("DELETE FROM sqlite_sequence WHERE name = 'scrobbles'")
+		dbmap.Exec("DELETE FROM play_queue_tracks")
+		dbmap.Exec("DELETE FROM play_queues")
+		dbmap.Exec("DELETE FROM sqlite_sequence WHERE name = 'play_queues'")
+		dbmap.Exec("DELETE FROM bookmarks")
+		dbmap.Exec("DELETE FROM sqlite_sequence WHERE name = 'bookmarks'")
 		dbmap.Exec("DELETE FROM variables")
 		dbmap.Exec("DELETE FROM sqlite_sequence WHERE name = 'variables'")
 	}
@@ -72,7 +101,7 @@ func closeTestDataSource(ds Datasource) error {
 func initTestDataSource(ds Datasource) (err error) {
 	if dbmap, ok := ds.(*gorp.DbMap); ok == true {
 		// Artists.
-		dbmap.Exec("INSERT INTO artists(id, name) VALUES(?, ?)", 1, business.LibraryDefaultCompilationArtist)
+		dbmap.Exec("INSERT INTO artists(id, name, match_key, created_at) VALUES(?, ?, ?, strftime('%s', 'now'))", 1, business.LibraryDefaultCompilationArtist, business.MatchKey(business.LibraryDefaultCompilationArtist))
 
 		file, errOpen := os.OpenFile(TestArtistsFile, os.O_RDONLY, 0666)
 		if errOpen != nil {
@@ -90,7 +119,7 @@ func initTestDataSource(ds Datasource) (err error) {
 			}
 
 			// Insert the row in database.
-			dbmap.Exec("INSERT INTO artists(id, name) VALUES(?, ?)", record[0], record[1])
+			dbmap.Exec("INSERT INTO artists(id, name, match_key, created_at) VALUES(?, ?, ?, strftime('%s', 'now'))", record[0], record[1], business.MatchKey(record[1]))
 		}
 		file.Close()
 
@@ -112,10 +141,11 @@ func initTestDataSource(ds Datasource) (err error) {
 
 			// Insert the row in database.
 			dbmap.Exec(
-				"INSERT INTO albums(id, artist_id, title, year, cover_id) VALUES(?, ?, ?, ?, ?)",
+				"INSERT INTO albums(id, artist_id, title, match_key, year, cover_id, created_at) VALUES(?, ?, ?, ?, ?, ?, strftime('%s', 'now'))",
 				record[0],
 				record[1],
 				record[2],
+				business.MatchKey(record[2]),
 				record[3],
 				record[4],
 			)
@@ -140,7 +170,7 @@ func initTestDataSource(ds Datasource) (err error) {
 
 			// Insert the row in database.
 			dbmap.Exec(
-				"INSERT INTO tracks(id, album_id, artist_id, cover_id, title, disc, number, duration, genre, path) VALUES(?, ?, ?, ?, ? ,? ,?, ?, ?, ?)",
+				"INSERT INTO tracks(id, album_id, artist_id, cover_id, title, disc, number, duration, genre, path, created_at) VALUES(?, ?, ?, ?, ? ,? ,?, ?, ?, ?, strftime('%s', 'now'))",
 				record[0],
 				record[1],
 				record[2],
@@ -176,8 +206,178 @@ func initTestDataSource(ds Datasource) (err error) {
 		}
 		file.Close()
 
+		// Genres.
+		file, errOpen = os.OpenFile(TestGenresFile, os.O_RDONLY, 0666)
+		if errOpen != nil {
+			fmt.Println(errOpen)
+		}
+
+		r = csv.NewReader(file)
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			// Insert the row in database.
+			dbmap.Exec("INSERT INTO genres(id, name, created_at) VALUES(?, ?, strftime('%s', 'now'))", record[0], record[1])
+		}
+		file.Close()
+
+		// Tracks genres.
+		file, errOpen = os.OpenFile(TestTrackGenresFile, os.O_RDONLY, 0666)
+		if errOpen != nil {
+			fmt.Println(errOpen)
+		}
+
+		r = csv.NewReader(file)
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			// Insert the row in database.
+			dbmap.Exec("INSERT INTO track_genres(track_id, genre_id) VALUES(?, ?)", record[0], record[1])
+		}
+		file.Close()
+
+		// Tracks artists.
+		file, errOpen = os.OpenFile(TestTrackArtistsFile, os.O_RDONLY, 0666)
+		if errOpen != nil {
+			fmt.Println(errOpen)
+		}
+
+		r = csv.NewReader(file)
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			// Insert the row in database.
+			dbmap.Exec("INSERT INTO track_artists(track_id, artist_id, role, position) VALUES(?, ?, ?, ?)", record[0], record[1], record[2], record[3])
+		}
+		file.Close()
+
+		// Overrides.
+		file, errOpen = os.OpenFile(TestOverridesFile, os.O_RDONLY, 0666)
+		if errOpen != nil {
+			fmt.Println(errOpen)
+		}
+
+		r = csv.NewReader(file)
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			// Insert the row in database.
+			dbmap.Exec(
+				"INSERT INTO overrides(id, entity_type, entity_id, field, value, original_value, created_at) VALUES(?, ?, ?, ?, ?, ?, strftime('%s', 'now'))",
+				record[0],
+				record[1],
+				record[2],
+				record[3],
+				record[4],
+				record[5],
+			)
+		}
+		file.Close()
+
+		// Artist aliases.
+		file, errOpen = os.OpenFile(TestArtistAliasesFile, os.O_RDONLY, 0666)
+		if errOpen != nil {
+			fmt.Println(errOpen)
+		}
+
+		r = csv.NewReader(file)
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			// Insert the row in database.
+			dbmap.Exec(
+				"INSERT INTO artist_aliases(id, artist_id, name, sort_name, match_key, source_artist_id, created_at) VALUES(?, ?, ?, ?, ?, ?, strftime('%s', 'now'))",
+				record[0],
+				record[1],
+				record[2],
+				record[2],
+				business.MatchKey(record[2]),
+				record[3],
+			)
+		}
+		file.Close()
+
+		// Lyrics.
+		file, errOpen = os.OpenFile(TestLyricsFile, os.O_RDONLY, 0666)
+		if errOpen != nil {
+			fmt.Println(errOpen)
+		}
+
+		r = csv.NewReader(file)
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			// Insert the row in database.
+			dbmap.Exec(
+				"INSERT INTO lyrics(id, track_id, text, synced, created_at) VALUES(?, ?, ?, ?, strftime('%s', 'now'))",
+				record[0],
+				record[1],
+				record[2],
+				record[3],
+			)
+		}
+		file.Close()
+
 		// Variables
 		dbmap.Exec("INSERT INTO variables(key, value) VALUES('var_key', 'var_value')")
+
+		// Shares.
+		dbmap.Exec("INSERT INTO shares(slug, entity_type, entity_id, expires_at, max_plays, plays, allow_download, created_at) VALUES('album-slug', 'album', 1, 0, 0, 0, 1, 100)")
+		dbmap.Exec("INSERT INTO shares(slug, entity_type, entity_id, expires_at, max_plays, plays, allow_download, created_at) VALUES('track-slug', 'track', 2, 0, 2, 1, 0, 300)")
+		dbmap.Exec("INSERT INTO shares(slug, entity_type, entity_id, expires_at, max_plays, plays, allow_download, created_at) VALUES('deleted-slug', 'album', 99, 0, 0, 0, 0, 200)")
+		dbmap.Exec("INSERT INTO shares(slug, entity_type, entity_id, name, expires_at, max_plays, plays, allow_download, created_at) VALUES('playlist-slug', 'playlist', 0, 'Mix', 0, 0, 0, 0, 50)")
+		dbmap.Exec("INSERT INTO share_tracks(share_id, number, track_id) VALUES(4, 0, 3)")
+		dbmap.Exec("INSERT INTO share_tracks(share_id, number, track_id) VALUES(4, 1, 99)")
+		dbmap.Exec("INSERT INTO share_tracks(share_id, number, track_id) VALUES(4, 2, 1)")
+
+		// Scrobbling.
+		dbmap.Exec("INSERT INTO scrobble_accounts(service, user_name, token, created_at) VALUES('lastfm', 'Listener', 'session key', 100)")
+		dbmap.Exec("INSERT INTO scrobble_accounts(service, user_name, token, created_at) VALUES('listenbrainz', 'Listener', 'user token', 200)")
+		dbmap.Exec("INSERT INTO scrobbles(account_id, artist, title, album, album_artist, number, duration, musicbrainz_track_id, listened_at, attempts, last_error, created_at) VALUES(1, 'Artist', 'Second', 'Album', 'Artist', 2, 200, '', 2000, 0, '', 2000)")
+		dbmap.Exec("INSERT INTO scrobbles(account_id, artist, title, album, album_artist, number, duration, musicbrainz_track_id, listened_at, attempts, last_error, created_at) VALUES(1, 'Artist', 'First', 'Album', 'Artist', 1, 180, '', 1000, 1, 'timeout', 1000)")
+		dbmap.Exec("INSERT INTO scrobbles(account_id, artist, title, album, album_artist, number, duration, musicbrainz_track_id, listened_at, attempts, last_error, created_at) VALUES(99, 'Artist', 'Unlinked', 'Album', 'Artist', 3, 240, '', 3000, 0, '', 3000)")
+
+		// Bookmarks.
+		dbmap.Exec("INSERT INTO bookmarks(user_name, track_id, position, created_at, updated_at) VALUES('', 1, 60000, 100, 100)")
+		dbmap.Exec("INSERT INTO bookmarks(user_name, track_id, position, created_at, updated_at) VALUES('', 2, 120000, 200, 300)")
+		dbmap.Exec("INSERT INTO bookmarks(user_name, track_id, position, created_at, updated_at) VALUES('other', 1, 30000, 200, 200)")
+		dbmap.Exec("INSERT INTO bookmarks(user_name, track_id, position, created_at, updated_at) VALUES('', 99, 5000, 400, 400)")
 	}
 
 	return nil
@@ -206,6 +406,7 @@ type artistRepositoryMock struct{
 // Not needed.
 func (m *artistRepositoryMock) Get(id int) (entity domain.Artist, err error) {return}
 func (m *artistRepositoryMock) GetAll(hydrate bool) (entities domain.Artists, err error) {return}
+func (m *artistRepositoryMock) GetArtistsForGenre(genreId int) (entities domain.Artists, err error) {return}
 func (m *artistRepositoryMock) Delete(entity *domain.Artist) (err error) {return}
 func (m artistRepositoryMock) Exists(id int) bool {return true}
 func (m artistRepositoryMock) CleanUp() error {return nil}
@@ -246,6 +447,8 @@ type albumRepositoryMock struct{
 func (m *albumRepositoryMock) Get(id int) (entity domain.Album, err error) {return}
 func (m *albumRepositoryMock) GetAll(hydrate bool) (entities domain.Albums, err error) {return}
 func (m *albumRepositoryMock) GetAlbumsForArtist(artistId int, hydrate bool) (entities domain.Albums, err error) {return}
+func (m *albumRepositoryMock) GetAlbumsForGenre(genreId int) (entities domain.Albums, err error) {return}
+func (m *albumRepositoryMock) GetAlbumsArtistAppearsOn(artistId int) (entities domain.Albums, err error) {return}
 func (m *albumRepositoryMock) Delete(entity *domain.Album) (err error) {return}
 func (m albumRepositoryMock) Exists(id int) bool {return false}
 func (m albumRepositoryMock) CleanUp() error {return nil}
@@ -295,6 +498,11 @@ type trackRepositoryMock struct{
 func (m *trackRepositoryMock) Get(id int) (entity domain.Track, err error) {return}
 func (m *trackRepositoryMock) GetAll() (entities domain.Tracks, err error) {return}
 func (m *trackRepositoryMock) GetTracksForAlbum(albumId int) (entities domain.Tracks, err error) {return}
+func (m *trackRepositoryMock) GetTracksForGenre(genreId int) (entities domain.Tracks, err error) {return}
+func (m *trackRepositoryMock) FindTracks(filter business.TrackFilter) (entities domain.Tracks, err error) {return}
+func (m *trackRepositoryMock) GetTracksForArtist(artistId int, role string) (entities domain.Tracks, err error) {return}
+func (m *trackRepositoryMock) GetTrackArtists(trackId int) (entities domain.TrackArtists, err error) {return}
+func (m *trackRepositoryMock) SaveLoudness(entity *domain.Track) (err error) {return}
 func (m *trackRepositoryMock) Delete(entity *domain.Track) (err error) {return}
 func (m trackRepositoryMock) Exists(id int) bool {return false}
 
@@ -368,6 +576,9 @@ func (m *mediaRepositoryMock) MediaFileExists(filepath string) bool {return true
 func (m *mediaRepositoryMock) WriteCoverFile(file *domain.Cover, directory string) error {return nil}
 func (m *mediaRepositoryMock) RemoveCoverFile(file *domain.Cover, directory string) error {return nil}
 func (m *mediaRepositoryMock) DeleteCovers() error {return nil}
+func (m *mediaRepositoryMock) WriteTags(filepath string, values map[string]string, dryRun bool) ([]business.TagEdit, error) {return nil, nil}
+func (m *mediaRepositoryMock) RescanMediaFiles(filepaths []string) error {return nil}
+func (m *mediaRepositoryMock) DecodeAudio(filepath string, startMs int, endMs int, write func(sampleRate int, samples [][]float64) error) error {return nil}
 
 /*
 Mock for internal variable repository.
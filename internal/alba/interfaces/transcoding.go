@@ -0,0 +1,130 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+/*
+This file exposes the transcoding of media files with ffmpeg, used to stream what can't be served as is.
+
+Transcoding is disabled if no ffmpeg executable is configured.
+*/
+
+var errTranscodingDisabled = errors.New("transcoding is disabled")
+
+// Output formats supported by the transcoder, with the ffmpeg arguments, the content type and the file extension of
+// each one.
+var transcodingFormats = map[string]struct {
+	Args        []string
+	ContentType string
+	Extension   string
+}{
+	"mp3":  {Args: []string{"-f", "mp3", "-c:a", "libmp3lame"}, ContentType: "audio/mpeg", Extension: ".mp3"},
+	"ogg":  {Args: []string{"-f", "ogg", "-c:a", "libvorbis"}, ContentType: "audio/ogg", Extension: ".ogg"},
+	"opus": {Args: []string{"-f", "ogg", "-c:a", "libopus"}, ContentType: "audio/ogg", Extension: ".opus"},
+	"flac": {Args: []string{"-f", "flac", "-c:a", "flac"}, ContentType: "audio/flac", Extension: ".flac"},
+}
+
+// Transcoding of a media file, or of a part of it.
+type transcodingOptions struct {
+	Format  string // One of the transcodingFormats.
+	Bitrate int    // In kbit/s, ignored by lossless formats.
+	StartMs int
+	EndMs   int     // 0 for the end of the file.
+	GainDb  float64 // Volume adjustment, like the ReplayGain gain.
+}
+
+// Gets the default transcoding options from the configuration.
+func getTranscodingOptions() transcodingOptions {
+	options := transcodingOptions{
+		Format:  strings.ToLower(viper.GetString("Transcoding.Format")),
+		Bitrate: viper.GetInt("Transcoding.Bitrate"),
+	}
+	if _, ok := transcodingFormats[options.Format]; !ok {
+		options.Format = "mp3"
+	}
+
+	return options
+}
+
+// Gets the ffmpeg command line arguments to transcode a media file to the standard output.
+func getTranscodingArgs(path string, options transcodingOptions) []string {
+	args := getTranscodingInputArgs(path, options)
+	args = append(args, transcodingFormats[options.Format].Args...)
+	if options.Bitrate > 0 && options.Format != "flac" {
+		args = append(args, "-b:a", strconv.Itoa(options.Bitrate)+"k")
+	}
+
+	return append(args, "pipe:1")
+}
+
+// Gets the ffmpeg command line arguments selecting the audio to transcode, the output format excepted.
+func getTranscodingInputArgs(path string, options transcodingOptions) []string {
+	args := []string{"-v", "error", "-nostdin"}
+	if options.StartMs > 0 {
+		args = append(args, "-ss", formatFfmpegTime(options.StartMs))
+	}
+	if options.EndMs > 0 {
+		args = append(args, "-to", formatFfmpegTime(options.EndMs))
+	}
+	args = append(args, "-i", path, "-map", "0:a:0", "-map_metadata", "-1")
+	if options.GainDb != 0 {
+		args = append(args, "-af", "volume="+strconv.FormatFloat(options.GainDb, 'f', 2, 64)+"dB")
+	}
+
+	return args
+}
+
+// Formats a time in milliseconds as seconds, as expected by ffmpeg.
+func formatFfmpegTime(timeMs int) string {
+	return strconv.FormatFloat(float64(timeMs)/1000, 'f', 3, 64)
+}
+
+/*
+Transcodes a media file and streams the result.
+
+The length of the result is unknown, so it can't be served with byte ranges. Returns errTranscodingDisabled if no
+ffmpeg executable is configured.
+*/
+func transcode(w http.ResponseWriter, r *http.Request, path string, options transcodingOptions) error {
+	if viper.GetString("Transcoding.FfmpegPath") == "" {
+		return errTranscodingDisabled
+	}
+
+	w.Header().Set("Content-Type", transcodingFormats[options.Format].ContentType)
+	return transcodeTo(r.Context(), w, path, options)
+}
+
+// Transcodes a media file to a writer, until the context is done.
+//
+// Returns errTranscodingDisabled if no ffmpeg executable is configured.
+func transcodeTo(ctx context.Context, w io.Writer, path string, options transcodingOptions) error {
+	ffmpegPath := viper.GetString("Transcoding.FfmpegPath")
+	if ffmpegPath == "" {
+		return errTranscodingDisabled
+	}
+
+	cmd := exec.CommandContext(ctx, ffmpegPath, getTranscodingArgs(path, options)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, stdout)
+	if errWait := cmd.Wait(); err == nil {
+		err = errWait
+	}
+
+	return err
+}
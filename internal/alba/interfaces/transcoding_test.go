@@ -0,0 +1,71 @@
+package interfaces
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TranscodingTestSuite struct {
+	suite.Suite
+}
+
+// Go testing framework entry point.
+func TestTranscodingTestSuite(t *testing.T) {
+	suite.Run(t, new(TranscodingTestSuite))
+}
+
+func (suite *TranscodingTestSuite) TearDownTest() {
+	viper.Set("Transcoding.FfmpegPath", "")
+	viper.Set("Transcoding.Format", "mp3")
+	viper.Set("Transcoding.Bitrate", 192)
+}
+
+func (suite *TranscodingTestSuite) TestGetTranscodingOptions() {
+	viper.Set("Transcoding.Format", "OPUS")
+	viper.Set("Transcoding.Bitrate", 96)
+	assert.Equal(suite.T(), transcodingOptions{Format: "opus", Bitrate: 96}, getTranscodingOptions())
+
+	// Unknown formats fall back to mp3.
+	viper.Set("Transcoding.Format", "wma")
+	assert.Equal(suite.T(), "mp3", getTranscodingOptions().Format)
+}
+
+func (suite *TranscodingTestSuite) TestGetTranscodingArgs() {
+	args := getTranscodingArgs("/music/Album.ape", transcodingOptions{Format: "mp3", Bitrate: 192, StartMs: 600493, EndMs: 1230986})
+	assert.Equal(suite.T(), []string{
+		"-v", "error", "-nostdin",
+		"-ss", "600.493", "-to", "1230.986",
+		"-i", "/music/Album.ape", "-map", "0:a:0", "-map_metadata", "-1",
+		"-f", "mp3", "-c:a", "libmp3lame", "-b:a", "192k",
+		"pipe:1",
+	}, args)
+
+	// Whole file, lossless.
+	args = getTranscodingArgs("/music/Album.ape", transcodingOptions{Format: "flac", Bitrate: 192})
+	assert.Equal(suite.T(), []string{
+		"-v", "error", "-nostdin",
+		"-i", "/music/Album.ape", "-map", "0:a:0", "-map_metadata", "-1",
+		"-f", "flac", "-c:a", "flac",
+		"pipe:1",
+	}, args)
+
+	// ReplayGain.
+	args = getTranscodingArgs("/music/Track.flac", transcodingOptions{Format: "opus", Bitrate: 96, GainDb: -6.478})
+	assert.Equal(suite.T(), []string{
+		"-v", "error", "-nostdin",
+		"-i", "/music/Track.flac", "-map", "0:a:0", "-map_metadata", "-1",
+		"-af", "volume=-6.48dB",
+		"-f", "ogg", "-c:a", "libopus", "-b:a", "96k",
+		"pipe:1",
+	}, args)
+}
+
+func (suite *TranscodingTestSuite) TestTranscodingDisabled() {
+	request := httptest.NewRequest("GET", "/stream/1", nil)
+	err := transcode(httptest.NewRecorder(), request, "/music/Album.ape", getTranscodingOptions())
+	assert.Equal(suite.T(), errTranscodingDisabled, err)
+}
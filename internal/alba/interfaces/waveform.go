@@ -0,0 +1,126 @@
+package interfaces
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+)
+
+/*
+This file exposes the serialization of the waveforms, in the binary and JSON formats of the BBC audiowaveform tool,
+which are understood by client libraries like peaks.js.
+*/
+
+// Header of the audiowaveform binary format, version 1. Values are little endian.
+type waveformDatHeader struct {
+	Version         int32
+	Flags           uint32 // 0 for 16 bits values, 1 for 8 bits values.
+	SampleRate      int32
+	SamplesPerPixel int32
+	Length          uint32 // Number of min / max pairs.
+}
+
+// Audiowaveform JSON format, version 2.
+type waveformJson struct {
+	Version         int   `json:"version"`
+	Channels        int   `json:"channels"`
+	SampleRate      int   `json:"sample_rate"`
+	SamplesPerPixel int   `json:"samples_per_pixel"`
+	Bits            int   `json:"bits"`
+	Length          int   `json:"length"`
+	Data            []int `json:"data"` // Min / max pairs.
+}
+
+// Converts a waveform to the audiowaveform JSON format, with 8 or 16 bits values.
+func newWaveformJson(waveform business.Waveform, bits int) waveformJson {
+	result := waveformJson{
+		Version:         2,
+		Channels:        1,
+		SampleRate:      waveform.SampleRate,
+		SamplesPerPixel: waveform.SamplesPerPoint,
+		Bits:            bits,
+		Length:          len(waveform.Min),
+		Data:            make([]int, 0, len(waveform.Min)*2),
+	}
+	for i := range waveform.Min {
+		result.Data = append(result.Data, waveformValue(waveform.Min[i], bits), waveformValue(waveform.Max[i], bits))
+	}
+
+	return result
+}
+
+// Writes a waveform in the audiowaveform binary format, with 8 or 16 bits values.
+func writeWaveformDat(w io.Writer, waveform business.Waveform, bits int) error {
+	header := waveformDatHeader{
+		Version:         1,
+		SampleRate:      int32(waveform.SampleRate),
+		SamplesPerPixel: int32(waveform.SamplesPerPoint),
+		Length:          uint32(len(waveform.Min)),
+	}
+	data := make([]int16, 0, len(waveform.Min)*2)
+	for i := range waveform.Min {
+		data = append(data, waveform.Min[i], waveform.Max[i])
+	}
+
+	if bits == 8 {
+		header.Flags = 1
+		if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+			return err
+		}
+		values := make([]int8, len(data))
+		for i, value := range data {
+			values[i] = int8(waveformValue(value, 8))
+		}
+		return binary.Write(w, binary.LittleEndian, values)
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, data)
+}
+
+// Reads a waveform in the audiowaveform binary format. 8 bits values are scaled to 16 bits.
+func readWaveformDat(r io.Reader) (waveform business.Waveform, err error) {
+	var header waveformDatHeader
+	if err = binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return
+	}
+	if header.Version != 1 {
+		return waveform, errors.New("unsupported waveform version")
+	}
+
+	data := make([]int16, header.Length*2)
+	if header.Flags&1 == 1 {
+		values := make([]int8, len(data))
+		if err = binary.Read(r, binary.LittleEndian, values); err != nil {
+			return
+		}
+		for i, value := range values {
+			data[i] = int16(value) << 8
+		}
+	} else if err = binary.Read(r, binary.LittleEndian, data); err != nil {
+		return
+	}
+
+	waveform.SampleRate = int(header.SampleRate)
+	waveform.SamplesPerPoint = int(header.SamplesPerPixel)
+	waveform.Min = make([]int16, header.Length)
+	waveform.Max = make([]int16, header.Length)
+	for i := range waveform.Min {
+		waveform.Min[i], waveform.Max[i] = data[i*2], data[i*2+1]
+	}
+
+	return
+}
+
+// Converts a 16 bits waveform value to the given number of bits.
+func waveformValue(value int16, bits int) int {
+	if bits == 8 {
+		return int(value >> 8)
+	}
+
+	return int(value)
+}
@@ -0,0 +1,70 @@
+package interfaces
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/humbkr/albaplayer-server/internal/alba/business"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WaveformTestSuite struct {
+	suite.Suite
+}
+
+// Go testing framework entry point.
+func TestWaveformTestSuite(t *testing.T) {
+	suite.Run(t, new(WaveformTestSuite))
+}
+
+var testWaveform = business.Waveform{
+	SampleRate:      44100,
+	SamplesPerPoint: 512,
+	Min:             []int16{-32767, -256},
+	Max:             []int16{32767, 511},
+}
+
+func (suite *WaveformTestSuite) TestNewWaveformJson() {
+	assert.Equal(suite.T(), waveformJson{
+		Version:         2,
+		Channels:        1,
+		SampleRate:      44100,
+		SamplesPerPixel: 512,
+		Bits:            8,
+		Length:          2,
+		Data:            []int{-128, 127, -1, 1},
+	}, newWaveformJson(testWaveform, 8))
+	assert.Equal(suite.T(), []int{-32767, 32767, -256, 511}, newWaveformJson(testWaveform, 16).Data)
+}
+
+func (suite *WaveformTestSuite) TestWaveformDat() {
+	var buffer bytes.Buffer
+	err := writeWaveformDat(&buffer, testWaveform, 16)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []byte{
+		1, 0, 0, 0, 0, 0, 0, 0, 0x44, 0xac, 0, 0, 0, 2, 0, 0, 2, 0, 0, 0,
+		0x01, 0x80, 0xff, 0x7f, 0x00, 0xff, 0xff, 0x01,
+	}, buffer.Bytes())
+
+	waveform, err := readWaveformDat(&buffer)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), testWaveform, waveform)
+
+	// 8 bits values.
+	buffer.Reset()
+	err = writeWaveformDat(&buffer, testWaveform, 8)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []byte{1, 0, 0, 0, 1, 0, 0, 0}, buffer.Bytes()[0:8])
+	assert.Equal(suite.T(), []byte{0x80, 0x7f, 0xff, 0x01}, buffer.Bytes()[20:])
+	waveform, err = readWaveformDat(&buffer)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []int16{-32768, -256}, waveform.Min)
+	assert.Equal(suite.T(), []int16{32512, 256}, waveform.Max)
+
+	// Truncated or unknown files.
+	_, err = readWaveformDat(bytes.NewReader([]byte{1, 0, 0, 0, 0, 0, 0, 0, 0x44, 0xac, 0, 0, 0, 2, 0, 0, 2, 0, 0, 0, 0x01}))
+	assert.NotNil(suite.T(), err)
+	_, err = readWaveformDat(bytes.NewReader([]byte{2, 0, 0, 0, 0, 0, 0, 0, 0x44, 0xac, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0}))
+	assert.NotNil(suite.T(), err)
+}
@@ -0,0 +1,23 @@
+-- +migrate Up
+ALTER TABLE albums ADD musicbrainz_album_id VARCHAR(255) NOT NULL DEFAULT '';
+
+-- +migrate Down
+PRAGMA foreign_keys=off;
+
+ALTER TABLE albums RENAME TO _albums_old;
+CREATE TABLE albums (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title VARCHAR(255),
+  year VARCHAR(255),
+  artist_id INTEGER,
+  cover_id INTEGER,
+  created_at INTEGER
+);
+
+INSERT INTO albums (id, title, year, artist_id, cover_id, created_at)
+SELECT id, title, year, artist_id, cover_id, created_at
+FROM _albums_old;
+
+DROP TABLE _albums_old;
+
+PRAGMA foreign_keys=on;
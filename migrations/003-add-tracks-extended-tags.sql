@@ -0,0 +1,37 @@
+-- +migrate Up
+ALTER TABLE tracks ADD musicbrainz_track_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE tracks ADD musicbrainz_album_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE tracks ADD musicbrainz_artist_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE tracks ADD isrc VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE tracks ADD label VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE tracks ADD catalog_number VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE tracks ADD original_year VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE tracks ADD composer VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE tracks ADD conductor VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE tracks ADD bpm INTEGER NOT NULL DEFAULT 0;
+
+-- +migrate Down
+PRAGMA foreign_keys=off;
+
+ALTER TABLE tracks RENAME TO _tracks_old;
+CREATE TABLE tracks (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title VARCHAR(255),
+  album_id INTEGER,
+  artist_id INTEGER,
+  cover_id INTEGER,
+  disc VARCHAR(255),
+  number INTEGER,
+  duration INTEGER,
+  genre VARCHAR(255),
+  path VARCHAR(255),
+  created_at INTEGER
+);
+
+INSERT INTO tracks (id, title, album_id, artist_id, cover_id, disc, number, duration, genre, path, created_at)
+SELECT id, title, album_id, artist_id, cover_id, disc, number, duration, genre, path, created_at
+FROM _tracks_old;
+
+DROP TABLE _tracks_old;
+
+PRAGMA foreign_keys=on;
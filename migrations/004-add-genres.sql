@@ -0,0 +1,30 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS genres (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name VARCHAR(255),
+  created_at INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS track_genres (
+  track_id INTEGER NOT NULL,
+  genre_id INTEGER NOT NULL,
+  PRIMARY KEY (track_id, genre_id)
+);
+
+CREATE INDEX IF NOT EXISTS GenreNameIndex ON genres (name);
+CREATE INDEX IF NOT EXISTS TrackGenreGenreIndex ON track_genres (genre_id);
+
+-- Existing genres will be split and normalised on the next scan.
+INSERT INTO genres (name, created_at)
+SELECT DISTINCT genre, strftime('%s', 'now')
+FROM tracks
+WHERE genre IS NOT NULL AND genre != '';
+
+INSERT INTO track_genres (track_id, genre_id)
+SELECT tracks.id, genres.id
+FROM tracks, genres
+WHERE tracks.genre = genres.name;
+
+-- +migrate Down
+DROP TABLE track_genres;
+DROP TABLE genres;
@@ -0,0 +1,19 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS track_artists (
+  track_id INTEGER NOT NULL,
+  artist_id INTEGER NOT NULL,
+  role VARCHAR(32) NOT NULL DEFAULT 'main',
+  position INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (track_id, artist_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS TrackArtistArtistIndex ON track_artists (artist_id, role);
+
+-- Combined artists like "Artist A feat. Artist B" will be split on the next scan.
+INSERT INTO track_artists (track_id, artist_id, role, position)
+SELECT id, artist_id, 'main', 0
+FROM tracks
+WHERE artist_id IS NOT NULL AND artist_id != 0;
+
+-- +migrate Down
+DROP TABLE track_artists;
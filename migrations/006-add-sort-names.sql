@@ -0,0 +1,43 @@
+-- +migrate Up
+-- Sort names will be filled from the tags or derived from the names on the next scan.
+ALTER TABLE artists ADD sort_name VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE albums ADD sort_name VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS ArtistSortNameIndex ON artists (sort_name);
+
+-- +migrate Down
+PRAGMA foreign_keys=off;
+
+DROP INDEX IF EXISTS ArtistSortNameIndex;
+
+ALTER TABLE artists RENAME TO _artists_old;
+CREATE TABLE artists (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name VARCHAR(255),
+  created_at INTEGER
+);
+
+INSERT INTO artists (id, name, created_at)
+SELECT id, name, created_at
+FROM _artists_old;
+
+DROP TABLE _artists_old;
+
+ALTER TABLE albums RENAME TO _albums_old;
+CREATE TABLE albums (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title VARCHAR(255),
+  year VARCHAR(255),
+  artist_id INTEGER,
+  cover_id INTEGER,
+  created_at INTEGER,
+  musicbrainz_album_id VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+INSERT INTO albums (id, title, year, artist_id, cover_id, created_at, musicbrainz_album_id)
+SELECT id, title, year, artist_id, cover_id, created_at, musicbrainz_album_id
+FROM _albums_old;
+
+DROP TABLE _albums_old;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,49 @@
+-- +migrate Up
+-- Match keys are computed, and the existing duplicates merged, when the database is opened.
+ALTER TABLE artists ADD match_key VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE albums ADD match_key VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS ArtistMatchKeyIndex ON artists (match_key);
+CREATE INDEX IF NOT EXISTS AlbumMatchKeyIndex ON albums (match_key, artist_id);
+
+-- +migrate Down
+PRAGMA foreign_keys=off;
+
+DROP INDEX IF EXISTS ArtistMatchKeyIndex;
+DROP INDEX IF EXISTS AlbumMatchKeyIndex;
+
+ALTER TABLE artists RENAME TO _artists_old;
+CREATE TABLE artists (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name VARCHAR(255),
+  created_at INTEGER,
+  sort_name VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+INSERT INTO artists (id, name, created_at, sort_name)
+SELECT id, name, created_at, sort_name
+FROM _artists_old;
+
+DROP TABLE _artists_old;
+
+CREATE INDEX IF NOT EXISTS ArtistSortNameIndex ON artists (sort_name);
+
+ALTER TABLE albums RENAME TO _albums_old;
+CREATE TABLE albums (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title VARCHAR(255),
+  year VARCHAR(255),
+  artist_id INTEGER,
+  cover_id INTEGER,
+  created_at INTEGER,
+  musicbrainz_album_id VARCHAR(255) NOT NULL DEFAULT '',
+  sort_name VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+INSERT INTO albums (id, title, year, artist_id, cover_id, created_at, musicbrainz_album_id, sort_name)
+SELECT id, title, year, artist_id, cover_id, created_at, musicbrainz_album_id, sort_name
+FROM _albums_old;
+
+DROP TABLE _albums_old;
+
+PRAGMA foreign_keys=on;
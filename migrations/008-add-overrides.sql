@@ -0,0 +1,14 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS overrides (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  entity_type VARCHAR(16) NOT NULL,
+  entity_id INTEGER NOT NULL,
+  field VARCHAR(64) NOT NULL,
+  value TEXT NOT NULL DEFAULT '',
+  original_value TEXT NOT NULL DEFAULT '',
+  created_at INTEGER NOT NULL DEFAULT 0,
+  UNIQUE (entity_type, entity_id, field)
+);
+
+-- +migrate Down
+DROP TABLE overrides;
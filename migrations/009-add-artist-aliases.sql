@@ -0,0 +1,30 @@
+-- +migrate Up
+-- Names of the artists merged into other artists, mapped onto the canonical artist when scanning the library.
+CREATE TABLE IF NOT EXISTS artist_aliases (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  artist_id INTEGER NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  sort_name VARCHAR(255) NOT NULL DEFAULT '',
+  match_key VARCHAR(255) NOT NULL DEFAULT '',
+  source_artist_id INTEGER NOT NULL DEFAULT 0,
+  created_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS ArtistAliasMatchKeyIndex ON artist_aliases (match_key);
+CREATE INDEX IF NOT EXISTS ArtistAliasArtistIndex ON artist_aliases (artist_id);
+
+-- Albums, tracks, credits and aliases moved by a merge, given back to the merged artist when the merge is undone.
+CREATE TABLE IF NOT EXISTS artist_alias_moves (
+  alias_id INTEGER NOT NULL,
+  entity_type VARCHAR(16) NOT NULL,
+  entity_id INTEGER NOT NULL,
+  role VARCHAR(32) NOT NULL DEFAULT '',
+  position INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (alias_id, entity_type, entity_id, role)
+);
+
+-- +migrate Down
+DROP TABLE artist_alias_moves;
+DROP INDEX IF EXISTS ArtistAliasArtistIndex;
+DROP INDEX IF EXISTS ArtistAliasMatchKeyIndex;
+DROP TABLE artist_aliases;
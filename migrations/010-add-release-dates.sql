@@ -0,0 +1,68 @@
+-- +migrate Up
+-- Dates will be read from the tags, and the albums years computed from all their tracks, on the next scan.
+ALTER TABLE tracks ADD date VARCHAR(10) NOT NULL DEFAULT '';
+ALTER TABLE tracks ADD original_date VARCHAR(10) NOT NULL DEFAULT '';
+ALTER TABLE albums ADD date VARCHAR(10) NOT NULL DEFAULT '';
+ALTER TABLE albums ADD original_date VARCHAR(10) NOT NULL DEFAULT '';
+
+-- +migrate Down
+PRAGMA foreign_keys=off;
+
+ALTER TABLE tracks RENAME TO _tracks_old;
+CREATE TABLE tracks (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title VARCHAR(255),
+  album_id INTEGER,
+  artist_id INTEGER,
+  cover_id INTEGER,
+  disc VARCHAR(255),
+  number INTEGER,
+  duration INTEGER,
+  genre VARCHAR(255),
+  path VARCHAR(255),
+  created_at INTEGER,
+  musicbrainz_track_id VARCHAR(255) NOT NULL DEFAULT '',
+  musicbrainz_album_id VARCHAR(255) NOT NULL DEFAULT '',
+  musicbrainz_artist_id VARCHAR(255) NOT NULL DEFAULT '',
+  isrc VARCHAR(255) NOT NULL DEFAULT '',
+  label VARCHAR(255) NOT NULL DEFAULT '',
+  catalog_number VARCHAR(255) NOT NULL DEFAULT '',
+  original_year VARCHAR(255) NOT NULL DEFAULT '',
+  composer VARCHAR(255) NOT NULL DEFAULT '',
+  conductor VARCHAR(255) NOT NULL DEFAULT '',
+  bpm INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO tracks (
+  id, title, album_id, artist_id, cover_id, disc, number, duration, genre, path, created_at, musicbrainz_track_id,
+  musicbrainz_album_id, musicbrainz_artist_id, isrc, label, catalog_number, original_year, composer, conductor, bpm
+)
+SELECT
+  id, title, album_id, artist_id, cover_id, disc, number, duration, genre, path, created_at, musicbrainz_track_id,
+  musicbrainz_album_id, musicbrainz_artist_id, isrc, label, catalog_number, original_year, composer, conductor, bpm
+FROM _tracks_old;
+
+DROP TABLE _tracks_old;
+
+ALTER TABLE albums RENAME TO _albums_old;
+CREATE TABLE albums (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title VARCHAR(255),
+  year VARCHAR(255),
+  artist_id INTEGER,
+  cover_id INTEGER,
+  created_at INTEGER,
+  musicbrainz_album_id VARCHAR(255) NOT NULL DEFAULT '',
+  sort_name VARCHAR(255) NOT NULL DEFAULT '',
+  match_key VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+INSERT INTO albums (id, title, year, artist_id, cover_id, created_at, musicbrainz_album_id, sort_name, match_key)
+SELECT id, title, year, artist_id, cover_id, created_at, musicbrainz_album_id, sort_name, match_key
+FROM _albums_old;
+
+DROP TABLE _albums_old;
+
+CREATE INDEX IF NOT EXISTS AlbumMatchKeyIndex ON albums (match_key, artist_id);
+
+PRAGMA foreign_keys=on;
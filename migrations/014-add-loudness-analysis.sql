@@ -0,0 +1,62 @@
+-- +migrate Up
+-- Loudness analysis results, EBU R128 integrated loudness in LUFS and true peaks as linear amplitudes. 0 if unknown.
+ALTER TABLE tracks ADD loudness_track_lufs REAL NOT NULL DEFAULT 0;
+ALTER TABLE tracks ADD loudness_track_peak REAL NOT NULL DEFAULT 0;
+ALTER TABLE tracks ADD loudness_album_lufs REAL NOT NULL DEFAULT 0;
+ALTER TABLE tracks ADD loudness_album_peak REAL NOT NULL DEFAULT 0;
+-- Timestamp of the analysis, 0 if the track hasn't been analyzed.
+ALTER TABLE tracks ADD loudness_analyzed_at INTEGER NOT NULL DEFAULT 0;
+
+-- +migrate Down
+PRAGMA foreign_keys=off;
+
+ALTER TABLE tracks RENAME TO _tracks_old;
+CREATE TABLE tracks (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title VARCHAR(255),
+  album_id INTEGER,
+  artist_id INTEGER,
+  cover_id INTEGER,
+  disc VARCHAR(255),
+  number INTEGER,
+  duration INTEGER,
+  genre VARCHAR(255),
+  path VARCHAR(255),
+  created_at INTEGER,
+  musicbrainz_track_id VARCHAR(255) NOT NULL DEFAULT '',
+  musicbrainz_album_id VARCHAR(255) NOT NULL DEFAULT '',
+  musicbrainz_artist_id VARCHAR(255) NOT NULL DEFAULT '',
+  isrc VARCHAR(255) NOT NULL DEFAULT '',
+  label VARCHAR(255) NOT NULL DEFAULT '',
+  catalog_number VARCHAR(255) NOT NULL DEFAULT '',
+  original_year VARCHAR(255) NOT NULL DEFAULT '',
+  composer VARCHAR(255) NOT NULL DEFAULT '',
+  conductor VARCHAR(255) NOT NULL DEFAULT '',
+  bpm INTEGER NOT NULL DEFAULT 0,
+  date VARCHAR(10) NOT NULL DEFAULT '',
+  original_date VARCHAR(10) NOT NULL DEFAULT '',
+  start_ms INTEGER NOT NULL DEFAULT 0,
+  end_ms INTEGER NOT NULL DEFAULT 0,
+  cue_track INTEGER NOT NULL DEFAULT 0,
+  replaygain_track_gain REAL NOT NULL DEFAULT 0,
+  replaygain_track_peak REAL NOT NULL DEFAULT 0,
+  replaygain_album_gain REAL NOT NULL DEFAULT 0,
+  replaygain_album_peak REAL NOT NULL DEFAULT 0
+);
+
+INSERT INTO tracks (
+  id, title, album_id, artist_id, cover_id, disc, number, duration, genre, path, created_at, musicbrainz_track_id,
+  musicbrainz_album_id, musicbrainz_artist_id, isrc, label, catalog_number, original_year, composer, conductor, bpm,
+  date, original_date, start_ms, end_ms, cue_track, replaygain_track_gain, replaygain_track_peak, replaygain_album_gain,
+  replaygain_album_peak
+)
+SELECT
+  id, title, album_id, artist_id, cover_id, disc, number, duration, genre, path, created_at, musicbrainz_track_id,
+  musicbrainz_album_id, musicbrainz_artist_id, isrc, label, catalog_number, original_year, composer, conductor, bpm,
+  date, original_date, start_ms, end_ms, cue_track, replaygain_track_gain, replaygain_track_peak, replaygain_album_gain,
+  replaygain_album_peak
+FROM _tracks_old;
+
+DROP TABLE _tracks_old;
+
+PRAGMA foreign_keys=on;
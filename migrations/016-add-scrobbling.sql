@@ -0,0 +1,29 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS scrobble_accounts (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  service TEXT NOT NULL,
+  user_name TEXT NOT NULL,
+  token TEXT NOT NULL,
+  created_at INTEGER NOT NULL DEFAULT 0,
+  UNIQUE (service, user_name)
+);
+
+CREATE TABLE IF NOT EXISTS scrobbles (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  account_id INTEGER NOT NULL,
+  artist TEXT NOT NULL DEFAULT '',
+  title TEXT NOT NULL DEFAULT '',
+  album TEXT NOT NULL DEFAULT '',
+  album_artist TEXT NOT NULL DEFAULT '',
+  number INTEGER NOT NULL DEFAULT 0,
+  duration INTEGER NOT NULL DEFAULT 0,
+  musicbrainz_track_id TEXT NOT NULL DEFAULT '',
+  listened_at INTEGER NOT NULL DEFAULT 0,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT NOT NULL DEFAULT '',
+  created_at INTEGER NOT NULL DEFAULT 0
+);
+
+-- +migrate Down
+DROP TABLE scrobbles;
+DROP TABLE scrobble_accounts;
@@ -0,0 +1,20 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS play_queues (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_name TEXT NOT NULL UNIQUE,
+  current_index INTEGER NOT NULL DEFAULT 0,
+  position INTEGER NOT NULL DEFAULT 0,
+  client_id TEXT NOT NULL DEFAULT '',
+  updated_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS play_queue_tracks (
+  queue_id INTEGER NOT NULL,
+  number INTEGER NOT NULL,
+  track_id INTEGER NOT NULL,
+  PRIMARY KEY (queue_id, number)
+);
+
+-- +migrate Down
+DROP TABLE play_queue_tracks;
+DROP TABLE play_queues;
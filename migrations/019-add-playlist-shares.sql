@@ -0,0 +1,37 @@
+-- +migrate Up
+-- Name of the shared playlists, empty for the other shares.
+ALTER TABLE shares ADD name TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS share_tracks (
+  share_id INTEGER NOT NULL,
+  number INTEGER NOT NULL,
+  track_id INTEGER NOT NULL,
+  PRIMARY KEY (share_id, number)
+);
+
+-- +migrate Down
+DROP TABLE share_tracks;
+
+PRAGMA foreign_keys=off;
+
+ALTER TABLE shares RENAME TO _shares_old;
+CREATE TABLE shares (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  slug TEXT NOT NULL UNIQUE,
+  entity_type TEXT NOT NULL,
+  entity_id INTEGER NOT NULL,
+  expires_at INTEGER NOT NULL DEFAULT 0,
+  max_plays INTEGER NOT NULL DEFAULT 0,
+  plays INTEGER NOT NULL DEFAULT 0,
+  allow_download INTEGER NOT NULL DEFAULT 0,
+  created_at INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO shares (id, slug, entity_type, entity_id, expires_at, max_plays, plays, allow_download, created_at)
+SELECT id, slug, entity_type, entity_id, expires_at, max_plays, plays, allow_download, created_at
+FROM _shares_old
+WHERE entity_type != 'playlist';
+
+DROP TABLE _shares_old;
+
+PRAGMA foreign_keys=on;